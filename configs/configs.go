@@ -15,6 +15,7 @@ import (
 	"os"
 	"path"
 	"runtime"
+	"slices"
 	"strings"
 	"time"
 
@@ -46,8 +47,11 @@ type config struct {
 	Email        configEmail     `json:"email"`
 	Extractor    configExtractor `json:"extractor"`
 	Bookmarks    configBookmarks `json:"bookmarks"`
+	Inbound      configInbound   `json:"inbound"`
+	Auth         configAuth      `json:"auth"`
 	Worker       configWorker    `json:"worker"`
 	Metrics      configMetrics   `json:"metrics"`
+	Groups       []configGroup   `json:"groups"`
 	Commissioned bool            `json:"-"`
 }
 
@@ -78,7 +82,146 @@ type configSession struct {
 }
 
 type configBookmarks struct {
-	PublicShareTTL int `json:"public_share_ttl" env:"PUBLIC_SHARE_TTL"`
+	PublicShareTTL   int `json:"public_share_ttl" env:"PUBLIC_SHARE_TTL"`
+	TrashRetention   int `json:"trash_retention" env:"TRASH_RETENTION"`       // in days
+	FeedPollInterval int `json:"feed_poll_interval" env:"FEED_POLL_INTERVAL"` // in minutes
+}
+
+type configInbound struct {
+	Maildir string `json:"maildir" env:"INBOUND_MAILDIR"`
+	Address string `json:"address" env:"INBOUND_ADDRESS"`
+}
+
+type configAuth struct {
+	PasswordLogin      bool               `json:"password_login" env:"AUTH_PASSWORD_LOGIN"`
+	TOTPRequiredGroups []string           `json:"totp_required_groups" env:"AUTH_TOTP_REQUIRED_GROUPS"`
+	OIDC               configOIDC         `json:"oidc"`
+	Proxy              configProxyAuth    `json:"proxy"`
+	LDAP               configLDAP         `json:"ldap"`
+	Lockout            configLockout      `json:"lockout"`
+	Registration       configRegistration `json:"registration"`
+}
+
+// configRegistration contains the self-service registration settings.
+// Invitations created in the admin area work even when the open
+// registration is disabled. With VerifyEmail, a new account is only
+// created once its email address is confirmed, unless the invitation
+// was sent to this address. MaxUsers limits the number of users that
+// can register on the instance; 0 means no limit.
+type configRegistration struct {
+	Open         bool   `json:"open" env:"AUTH_REGISTRATION_OPEN"`
+	DefaultGroup string `json:"default_group" env:"AUTH_REGISTRATION_DEFAULT_GROUP"`
+	VerifyEmail  bool   `json:"verify_email" env:"AUTH_REGISTRATION_VERIFY_EMAIL"`
+	MaxUsers     int    `json:"max_users" env:"AUTH_REGISTRATION_MAX_USERS"`
+	InviteTTL    int    `json:"invite_ttl" env:"AUTH_REGISTRATION_INVITE_TTL"` // in days
+}
+
+// configLockout contains the brute-force protection settings of the
+// sign in with a password. Failed attempts are counted per user and
+// per client address; after a few of them, each attempt must wait
+// longer than the previous one, until the limit locks the sign in
+// for the given duration.
+type configLockout struct {
+	Enabled       bool `json:"enabled" env:"AUTH_LOCKOUT_ENABLED"`
+	MaxAttempts   int  `json:"max_attempts" env:"AUTH_LOCKOUT_MAX_ATTEMPTS"`
+	MaxIPAttempts int  `json:"max_ip_attempts" env:"AUTH_LOCKOUT_MAX_IP_ATTEMPTS"`
+	Duration      int  `json:"duration" env:"AUTH_LOCKOUT_DURATION"` // in minutes
+}
+
+// configOIDC contains the OpenID Connect provider settings.
+// The provider is enabled when the issuer and client ID are set.
+type configOIDC struct {
+	Name         string              `json:"name" env:"OIDC_NAME"`
+	Issuer       string              `json:"issuer" env:"OIDC_ISSUER"`
+	ClientID     string              `json:"client_id" env:"OIDC_CLIENT_ID"`
+	ClientSecret string              `json:"client_secret" env:"OIDC_CLIENT_SECRET,unset"`
+	Scopes       []string            `json:"scopes" env:"OIDC_SCOPES"`
+	GroupsClaim  string              `json:"groups_claim" env:"OIDC_GROUPS_CLAIM"`
+	Groups       configGroupsMapping `json:"groups" envPrefix:"OIDC_GROUPS_"`
+	DefaultGroup string              `json:"default_group" env:"OIDC_DEFAULT_GROUP"`
+	AutoCreate   bool                `json:"auto_create" env:"OIDC_AUTO_CREATE"`
+	LinkByEmail  bool                `json:"link_by_email" env:"OIDC_LINK_BY_EMAIL"`
+}
+
+// configProxyAuth contains the settings of the authentication by
+// headers sent from a trusted reverse proxy.
+type configProxyAuth struct {
+	Enabled      bool                `json:"enabled" env:"PROXY_AUTH_ENABLED"`
+	UserHeader   string              `json:"user_header" env:"PROXY_AUTH_USER_HEADER"`
+	EmailHeader  string              `json:"email_header" env:"PROXY_AUTH_EMAIL_HEADER"`
+	GroupsHeader string              `json:"groups_header" env:"PROXY_AUTH_GROUPS_HEADER"`
+	Groups       configGroupsMapping `json:"groups" envPrefix:"PROXY_AUTH_GROUPS_"`
+	DefaultGroup string              `json:"default_group" env:"PROXY_AUTH_DEFAULT_GROUP"`
+	AutoCreate   bool                `json:"auto_create" env:"PROXY_AUTH_AUTO_CREATE"`
+}
+
+// configLDAP contains the settings of the sign in against an LDAP
+// directory. The directory is enabled when the URL is set.
+//
+// The user is searched with the service account, using the user
+// filter where "%s" is the username given on the sign in form, then
+// the password is checked with a bind as the user's entry. With
+// LocalFallback, users missing from the directory, or all the users
+// when it can't be reached, sign in with their Readeck password.
+type configLDAP struct {
+	URL           string              `json:"url" env:"LDAP_URL"`
+	StartTLS      bool                `json:"start_tls" env:"LDAP_START_TLS"`
+	Insecure      bool                `json:"insecure" env:"LDAP_INSECURE"`
+	BindDN        string              `json:"bind_dn" env:"LDAP_BIND_DN"`
+	BindPassword  string              `json:"bind_password" env:"LDAP_BIND_PASSWORD,unset"`
+	BaseDN        string              `json:"base_dn" env:"LDAP_BASE_DN"`
+	UserFilter    string              `json:"user_filter" env:"LDAP_USER_FILTER"`
+	UsernameAttr  string              `json:"username_attribute" env:"LDAP_USERNAME_ATTRIBUTE"`
+	EmailAttr     string              `json:"email_attribute" env:"LDAP_EMAIL_ATTRIBUTE"`
+	GroupAttr     string              `json:"group_attribute" env:"LDAP_GROUP_ATTRIBUTE"`
+	Groups        configGroupsMapping `json:"groups" envPrefix:"LDAP_GROUPS_"`
+	DefaultGroup  string              `json:"default_group" env:"LDAP_DEFAULT_GROUP"`
+	AutoCreate    bool                `json:"auto_create" env:"LDAP_AUTO_CREATE"`
+	LocalFallback bool                `json:"local_fallback" env:"LDAP_LOCAL_FALLBACK"`
+	Timeout       int                 `json:"timeout" env:"LDAP_TIMEOUT"` // in seconds
+}
+
+// configGroupsMapping lists, for each Readeck group, the groups
+// of an external provider granting it.
+type configGroupsMapping struct {
+	Admin []string `json:"admin" env:"ADMIN"`
+	Staff []string `json:"staff" env:"STAFF"`
+	User  []string `json:"user" env:"USER"`
+}
+
+// IsSet returns true when at least one group is mapped.
+func (m configGroupsMapping) IsSet() bool {
+	return len(m.Admin) > 0 || len(m.Staff) > 0 || len(m.User) > 0
+}
+
+// Map returns the group with the most permissions that lists one of
+// the given values. It returns an empty string when none matches.
+func (m configGroupsMapping) Map(values []string) string {
+	for _, x := range []struct {
+		group  string
+		values []string
+	}{
+		{"admin", m.Admin},
+		{"staff", m.Staff},
+		{"user", m.User},
+	} {
+		if slices.ContainsFunc(x.values, func(v string) bool {
+			return slices.Contains(values, v)
+		}) {
+			return x.group
+		}
+	}
+	return ""
+}
+
+// IsEnabled returns true when the OpenID Connect provider is configured.
+func (c configOIDC) IsEnabled() bool {
+	return c.Issuer != "" && c.ClientID != ""
+}
+
+// IsEnabled returns true when the LDAP directory is configured.
+func (c configLDAP) IsEnabled() bool {
+	return c.URL != ""
 }
 
 type configEmail struct {
@@ -94,9 +237,19 @@ type configEmail struct {
 }
 
 type configWorker struct {
-	DSN         string `json:"dsn" env:"WORKER_DSN,unset"`
-	NumWorkers  int    `json:"num_workers" env:"WORKER_NUMBER"`
-	StartWorker bool   `json:"start_worker" env:"WORKER_START"`
+	DSN         string             `json:"dsn" env:"WORKER_DSN,unset"`
+	NumWorkers  int                `json:"num_workers" env:"WORKER_NUMBER"`
+	StartWorker bool               `json:"start_worker" env:"WORKER_START"`
+	Queues      configWorkerQueues `json:"queues"`
+}
+
+// configWorkerQueues contains the number of workers of each queue.
+// When not set, the interactive queue uses the extractor's number
+// of workers.
+type configWorkerQueues struct {
+	Interactive int `json:"interactive" env:"WORKER_QUEUE_INTERACTIVE"`
+	Import      int `json:"import" env:"WORKER_QUEUE_IMPORT"`
+	Maintenance int `json:"maintenance" env:"WORKER_QUEUE_MAINTENANCE"`
 }
 
 type configExtractor struct {
@@ -111,6 +264,16 @@ type configMetrics struct {
 	Port int    `json:"port" env:"METRICS_PORT"`
 }
 
+// configGroup is a custom group of users. It receives the permissions
+// of the built-in group it inherits from, then the ones matching
+// its permission patterns.
+type configGroup struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Inherit     string   `json:"inherit"`
+	Permissions []string `json:"permissions"`
+}
+
 type configEmailAddr struct {
 	*mail.Address
 }
@@ -312,16 +475,59 @@ var Config = config{
 		},
 	},
 	Database: configDB{},
+	Auth: configAuth{
+		PasswordLogin: true,
+		OIDC: configOIDC{
+			Name:         "OpenID Connect",
+			Scopes:       []string{"openid", "profile", "email"},
+			GroupsClaim:  "groups",
+			DefaultGroup: "user",
+			AutoCreate:   true,
+			LinkByEmail:  true,
+		},
+		Proxy: configProxyAuth{
+			UserHeader:   "Remote-User",
+			EmailHeader:  "Remote-Email",
+			GroupsHeader: "Remote-Groups",
+			DefaultGroup: "user",
+		},
+		LDAP: configLDAP{
+			UserFilter:   "(&(objectClass=person)(uid=%s))",
+			UsernameAttr: "uid",
+			EmailAttr:    "mail",
+			GroupAttr:    "memberOf",
+			DefaultGroup: "user",
+			AutoCreate:   true,
+			Timeout:      10,
+		},
+		Lockout: configLockout{
+			Enabled:       true,
+			MaxAttempts:   10,
+			MaxIPAttempts: 50,
+			Duration:      15,
+		},
+		Registration: configRegistration{
+			DefaultGroup: "user",
+			VerifyEmail:  true,
+			InviteTTL:    7,
+		},
+	},
 	Email: configEmail{
 		Port: 25,
 	},
 	Bookmarks: configBookmarks{
-		PublicShareTTL: 24,
+		PublicShareTTL:   24,
+		TrashRetention:   30,
+		FeedPollInterval: 60,
 	},
 	Worker: configWorker{
 		DSN:         "memory://",
 		NumWorkers:  max(1, runtime.NumCPU()-1),
 		StartWorker: true,
+		Queues: configWorkerQueues{
+			Import:      1,
+			Maintenance: 1,
+		},
 	},
 	Extractor: configExtractor{
 		NumWorkers:     runtime.NumCPU(),
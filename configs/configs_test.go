@@ -7,6 +7,7 @@ package configs
 import (
 	"log/slog"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -130,6 +131,89 @@ func TestEnvVars(t *testing.T) {
 			assert.NoError(err)
 			assert.Equal(48, cf.Bookmarks.PublicShareTTL)
 		}},
+		{"READECK_TRASH_RETENTION", "7", func(assert *require.Assertions, cf config, err error) {
+			assert.NoError(err)
+			assert.Equal(7, cf.Bookmarks.TrashRetention)
+		}},
+		{"READECK_FEED_POLL_INTERVAL", "15", func(assert *require.Assertions, cf config, err error) {
+			assert.NoError(err)
+			assert.Equal(15, cf.Bookmarks.FeedPollInterval)
+		}},
+		{"READECK_INBOUND_MAILDIR", "/srv/mail/readeck", func(assert *require.Assertions, cf config, err error) {
+			assert.NoError(err)
+			assert.Equal("/srv/mail/readeck", cf.Inbound.Maildir)
+		}},
+		{"READECK_INBOUND_ADDRESS", "readeck@example.net", func(assert *require.Assertions, cf config, err error) {
+			assert.NoError(err)
+			assert.Equal("readeck@example.net", cf.Inbound.Address)
+		}},
+		{"READECK_AUTH_PASSWORD_LOGIN", "false", func(assert *require.Assertions, cf config, err error) {
+			assert.NoError(err)
+			assert.False(cf.Auth.PasswordLogin)
+		}},
+		{"READECK_AUTH_TOTP_REQUIRED_GROUPS", "admin,staff", func(assert *require.Assertions, cf config, err error) {
+			assert.NoError(err)
+			assert.Equal([]string{"admin", "staff"}, cf.Auth.TOTPRequiredGroups)
+		}},
+		{"READECK_AUTH_LOCKOUT_MAX_ATTEMPTS", "5", func(assert *require.Assertions, cf config, err error) {
+			assert.NoError(err)
+			assert.Equal(5, cf.Auth.Lockout.MaxAttempts)
+		}},
+		{"READECK_AUTH_REGISTRATION_OPEN", "true", func(assert *require.Assertions, cf config, err error) {
+			assert.NoError(err)
+			assert.True(cf.Auth.Registration.Open)
+		}},
+		{"READECK_AUTH_REGISTRATION_MAX_USERS", "20", func(assert *require.Assertions, cf config, err error) {
+			assert.NoError(err)
+			assert.Equal(20, cf.Auth.Registration.MaxUsers)
+		}},
+		{"READECK_OIDC_ISSUER", "https://auth.example.net/realms/readeck", func(assert *require.Assertions, cf config, err error) {
+			assert.NoError(err)
+			assert.Equal("https://auth.example.net/realms/readeck", cf.Auth.OIDC.Issuer)
+			assert.False(cf.Auth.OIDC.IsEnabled())
+		}},
+		{"READECK_OIDC_CLIENT_SECRET", "abcdef", func(assert *require.Assertions, cf config, err error) {
+			assert.NoError(err)
+			assert.Equal("abcdef", cf.Auth.OIDC.ClientSecret)
+
+			v, exists := os.LookupEnv("READECK_OIDC_CLIENT_SECRET")
+			assert.Empty(v)
+			assert.False(exists)
+		}},
+		{"READECK_OIDC_GROUPS_ADMIN", "readeck-admins,sysadmins", func(assert *require.Assertions, cf config, err error) {
+			assert.NoError(err)
+			assert.Equal([]string{"readeck-admins", "sysadmins"}, cf.Auth.OIDC.Groups.Admin)
+		}},
+		{"READECK_PROXY_AUTH_ENABLED", "true", func(assert *require.Assertions, cf config, err error) {
+			assert.NoError(err)
+			assert.True(cf.Auth.Proxy.Enabled)
+		}},
+		{"READECK_PROXY_AUTH_USER_HEADER", "X-Forwarded-User", func(assert *require.Assertions, cf config, err error) {
+			assert.NoError(err)
+			assert.Equal("X-Forwarded-User", cf.Auth.Proxy.UserHeader)
+		}},
+		{"READECK_PROXY_AUTH_GROUPS_STAFF", "editors", func(assert *require.Assertions, cf config, err error) {
+			assert.NoError(err)
+			assert.Equal([]string{"editors"}, cf.Auth.Proxy.Groups.Staff)
+			assert.Empty(cf.Auth.OIDC.Groups.Staff)
+		}},
+		{"READECK_LDAP_URL", "ldaps://ldap.example.net", func(assert *require.Assertions, cf config, err error) {
+			assert.NoError(err)
+			assert.Equal("ldaps://ldap.example.net", cf.Auth.LDAP.URL)
+			assert.True(cf.Auth.LDAP.IsEnabled())
+		}},
+		{"READECK_LDAP_BIND_PASSWORD", "secret", func(assert *require.Assertions, cf config, err error) {
+			assert.NoError(err)
+			assert.Equal("secret", cf.Auth.LDAP.BindPassword)
+
+			v, exists := os.LookupEnv("READECK_LDAP_BIND_PASSWORD")
+			assert.Empty(v)
+			assert.False(exists)
+		}},
+		{"READECK_LDAP_GROUPS_ADMIN", "admins,sysadmins", func(assert *require.Assertions, cf config, err error) {
+			assert.NoError(err)
+			assert.Equal([]string{"admins", "sysadmins"}, cf.Auth.LDAP.Groups.Admin)
+		}},
 		{"READECK_WORKER_DSN", "memory://", func(assert *require.Assertions, cf config, err error) {
 			assert.NoError(err)
 			assert.Equal("memory://", cf.Worker.DSN)
@@ -146,6 +230,10 @@ func TestEnvVars(t *testing.T) {
 			assert.NoError(err)
 			assert.True(cf.Worker.StartWorker)
 		}},
+		{"READECK_WORKER_QUEUE_IMPORT", "3", func(assert *require.Assertions, cf config, err error) {
+			assert.NoError(err)
+			assert.Equal(3, cf.Worker.Queues.Import)
+		}},
 		{"READECK_METRICS_HOST", "::1", func(assert *require.Assertions, cf config, err error) {
 			assert.NoError(err)
 			assert.Equal("::1", cf.Metrics.Host)
@@ -202,4 +290,54 @@ func TestEnvVars(t *testing.T) {
 			assert.False(exists)
 		}
 	})
+
+	t.Run("lockout settings", func(t *testing.T) {
+		// The lockout stays enabled, with its defaults,
+		// when only some settings are given.
+		t.Setenv("READECK_AUTH_LOCKOUT_MAX_ATTEMPTS", "5")
+		cf := config{Auth: configAuth{Lockout: Config.Auth.Lockout}}
+		err := cf.LoadEnv()
+
+		assert := require.New(t)
+		assert.NoError(err)
+		assert.Equal(5, cf.Auth.Lockout.MaxAttempts)
+		assert.True(cf.Auth.Lockout.Enabled)
+		assert.Equal(Config.Auth.Lockout.Duration, cf.Auth.Lockout.Duration)
+	})
+}
+
+func TestLoadFileGroups(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "config.toml")
+	err := os.WriteFile(filename, []byte(`
+[main]
+log_level = "info"
+
+[[groups]]
+name = "reader-only"
+description = "No import, no email, no export"
+inherit = "user"
+permissions = ["-*:import", "-email:send", "-*:export"]
+
+[[groups]]
+name = "auditor"
+permissions = ["api:bookmarks:read"]
+`), 0o600)
+	require.NoError(t, err)
+
+	cf := config{}
+	assert := require.New(t)
+	assert.NoError(cf.LoadFile(filename))
+	assert.NoError(cf.LoadEnv())
+	assert.Equal([]configGroup{
+		{
+			Name:        "reader-only",
+			Description: "No import, no email, no export",
+			Inherit:     "user",
+			Permissions: []string{"-*:import", "-email:send", "-*:export"},
+		},
+		{
+			Name:        "auditor",
+			Permissions: []string{"api:bookmarks:read"},
+		},
+	}, cf.Groups)
 }
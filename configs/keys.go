@@ -17,6 +17,8 @@ const (
 	keyToken   = "api_token"
 	keySession = "session"
 	keyCSRF    = "csrf"
+	keyLogin   = "login"
+	keyTOTP    = "totp"
 )
 
 // KeyMaterial contains the signing and encryption keys.
@@ -25,6 +27,8 @@ type KeyMaterial struct {
 	tokenKey   []byte
 	sessionKey []byte
 	csrfKey    []byte
+	loginKey   []byte
+	totpKey    []byte
 }
 
 func hkdfHashFunc() hash.Hash {
@@ -54,6 +58,16 @@ func (km KeyMaterial) CSRFKey() []byte {
 	return km.csrfKey
 }
 
+// LoginKey returns a 256-bit key used by the sign in process' secure cookies.
+func (km KeyMaterial) LoginKey() []byte {
+	return km.loginKey
+}
+
+// TOTPKey returns a 256-bit key used to encrypt the users' TOTP secrets.
+func (km KeyMaterial) TOTPKey() []byte {
+	return km.totpKey
+}
+
 func (km KeyMaterial) mustExpand(name string, keyLength int) []byte {
 	k, err := km.Expand(name, keyLength)
 	if err != nil {
@@ -77,4 +91,6 @@ func loadKeys() {
 	Keys.tokenKey = Keys.mustExpand(keyToken, 32)
 	Keys.sessionKey = Keys.mustExpand(keySession, 32)
 	Keys.csrfKey = Keys.mustExpand(keyCSRF, 32)
+	Keys.loginKey = Keys.mustExpand(keyLogin, 32)
+	Keys.totpKey = Keys.mustExpand(keyTOTP, 32)
 }
@@ -11,6 +11,7 @@ import (
 	"path"
 	"sort"
 	"strings"
+	"sync"
 
 	"github.com/casbin/casbin/v2"
 	"github.com/casbin/casbin/v2/model"
@@ -21,15 +22,25 @@ import (
 //go:embed config/*
 var confFiles embed.FS
 
-var enforcer *casbin.Enforcer
+var (
+	// mu protects the enforcer against concurrent changes
+	// of the custom groups.
+	mu       sync.RWMutex
+	enforcer *casbin.Enforcer
+)
 
 // Check performs the rule enforcment for a given user, path and action.
 func Check(group, path, act string) (bool, error) {
+	mu.RLock()
+	defer mu.RUnlock()
 	return enforcer.Enforce(group, path, act)
 }
 
 // GetPermissions returns the permissions for a list of groups.
 func GetPermissions(groups ...string) ([]string, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+
 	perms := map[string]struct{}{}
 
 	for _, group := range groups {
@@ -52,6 +63,9 @@ func GetPermissions(groups ...string) ([]string, error) {
 
 // InGroup returns true if permissions from "src" group are all in "dest" group.
 func InGroup(src, dest string) bool {
+	mu.RLock()
+	defer mu.RUnlock()
+
 	srcPermissions, _ := enforcer.GetImplicitPermissionsForUser(src)
 	dstPermissions, _ := enforcer.GetImplicitPermissionsForUser(dest)
 
@@ -72,7 +86,12 @@ func InGroup(src, dest string) bool {
 }
 
 // DeleteRole deletes a role. Returns false if a role does not exist.
+// The custom groups don't receive a deleted role either.
 func DeleteRole(name string) (bool, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	deletedRoles[name] = struct{}{}
 	return enforcer.DeleteRole(name)
 }
 
@@ -82,6 +101,16 @@ func init() {
 	if err != nil {
 		panic(err)
 	}
+
+	// Keep the subjects of the policy file, so custom groups
+	// can't use their names.
+	rules, err := enforcer.GetGroupingPolicy()
+	if err != nil {
+		panic(err)
+	}
+	for _, r := range rules {
+		reservedNames[r[0]] = struct{}{}
+	}
 }
 
 func newEnforcer() (*casbin.Enforcer, error) {
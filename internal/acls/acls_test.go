@@ -30,6 +30,25 @@ func TestCheckPermission(t *testing.T) {
 		{"user", "api:profile:tokens", "delete", true},
 		{"", "api:profile:tokens", "delete", false},
 
+		{"user", "api:profile:webhooks", "write", true},
+		{"user", "profile:webhooks", "read", true},
+		{"", "api:profile:webhooks", "read", false},
+
+		{"user", "api:profile:rules", "write", true},
+		{"user", "profile:rules", "read", true},
+		{"", "api:profile:rules", "read", false},
+		{"user", "api:profile:feeds", "write", true},
+		{"user", "profile:feeds", "read", true},
+		{"", "api:profile:feeds", "read", false},
+		{"user", "api:profile:inbound", "write", true},
+		{"user", "profile:inbound", "read", true},
+		{"", "api:profile:inbound", "read", false},
+
+		{"user", "api:wallabag", "read", true},
+		{"", "api:wallabag", "read", false},
+		{"user", "api:kosync", "read", true},
+		{"", "api:kosync", "read", false},
+
 		{"admin", "system", "read", true},
 		{"staff", "system", "read", true},
 		{"user", "system", "read", false},
@@ -40,6 +59,14 @@ func TestCheckPermission(t *testing.T) {
 		{"user", "api:admin:users", "read", false},
 		{"", "api:admin:users", "read", false},
 
+		{"admin", "api:admin:tasks", "write", true},
+		{"staff", "api:admin:tasks", "read", false},
+
+		{"admin", "api:scim", "write", true},
+		{"staff", "api:scim", "read", false},
+		{"scoped_scim", "api:scim", "write", true},
+		{"scoped_scim", "api:admin:users", "read", false},
+
 		{"admin", "admin:users", "read", true},
 		{"staff", "admin:users", "read", false},
 		{"user", "admin:users", "read", false},
@@ -72,24 +99,28 @@ func TestGetPermissions(t *testing.T) {
 	}{
 		{
 			[]string{"scoped_admin_r"},
-			[]string{"api:admin:users:read", "api:profile:read", "api:profile:tokens:delete", "system:read"},
+			[]string{"api:admin:tasks:read", "api:admin:users:read", "api:profile:read", "api:profile:tokens:delete", "system:read"},
 		},
 		{
 			[]string{"scoped_admin_w"},
-			[]string{"api:admin:users:write", "api:profile:read", "api:profile:tokens:delete"},
+			[]string{"api:admin:tasks:write", "api:admin:users:write", "api:profile:read", "api:profile:tokens:delete"},
 		},
 		{
 			[]string{"scoped_admin_r", "scoped_admin_w"},
-			[]string{"api:admin:users:read", "api:admin:users:write", "api:profile:read", "api:profile:tokens:delete", "system:read"},
+			[]string{"api:admin:tasks:read", "api:admin:tasks:write", "api:admin:users:read", "api:admin:users:write", "api:profile:read", "api:profile:tokens:delete", "system:read"},
 		},
 		{
 			[]string{"scoped_bookmarks_r"},
-			[]string{"api:bookmarks:collections:read", "api:bookmarks:export", "api:bookmarks:read", "api:opds:read", "api:profile:read", "api:profile:tokens:delete", "bookmarks:read"},
+			[]string{"api:bookmarks:collections:read", "api:bookmarks:export", "api:bookmarks:read", "api:kosync:read", "api:opds:read", "api:profile:read", "api:profile:tokens:delete", "api:wallabag:read", "bookmarks:read"},
 		},
 		{
 			[]string{"scoped_bookmarks_w"},
 			[]string{"api:bookmarks:collections:write", "api:bookmarks:write", "api:profile:read", "api:profile:tokens:delete"},
 		},
+		{
+			[]string{"scoped_scim"},
+			[]string{"api:scim:read", "api:scim:write"},
+		},
 		{
 			[]string{"unknown"},
 			[]string{},
@@ -116,6 +147,8 @@ func TestInGroup(t *testing.T) {
 		{"scoped_bookmarks_r", "user", true},
 		{"scoped_admin_r", "user", false},
 		{"scoped_admin_r", "admin", true},
+		{"scoped_scim", "staff", false},
+		{"scoped_scim", "admin", true},
 	}
 
 	for _, test := range tests {
@@ -125,3 +158,114 @@ func TestInGroup(t *testing.T) {
 		})
 	}
 }
+
+func TestGroups(t *testing.T) {
+	defer func() {
+		require.NoError(t, acls.SetGroups())
+	}()
+
+	t.Run("invalid", func(t *testing.T) {
+		tests := []struct {
+			Group acls.Group
+			Err   string
+		}{
+			{acls.Group{Name: "user"}, `invalid group name: "user"`},
+			{acls.Group{Name: "none"}, `invalid group name: "none"`},
+			{acls.Group{Name: "scoped_admin_r"}, `invalid group name: "scoped_admin_r"`},
+			{acls.Group{Name: "/web/*"}, `invalid group name: "/web/*"`},
+			{acls.Group{Name: "Readers"}, `invalid group name: "Readers"`},
+			{acls.Group{Name: "readers", Inherit: "scoped_bookmarks_r"}, `group "readers": can't inherit from "scoped_bookmarks_r"`},
+			{acls.Group{Name: "readers", Permissions: []string{"bookmarks:delete"}}, `group "readers": "bookmarks:delete" doesn't match any permission`},
+			{acls.Group{Name: "readers", Permissions: []string{"-[bookmarks"}}, `group "readers": invalid permission "[bookmarks"`},
+			{
+				acls.Group{Name: "readers", Permissions: []string{"api:admin:tasks:read"}},
+				`group "readers": "api:admin:tasks:read" only covers part of the permissions api:admin:users:read, api:admin:tasks:read`,
+			},
+			{
+				acls.Group{Name: "readers", Inherit: "admin", Permissions: []string{"-*admin:users:*"}},
+				`group "readers": "*admin:users:*" only covers part of the permissions api:admin:users:read, api:admin:tasks:read`,
+			},
+		}
+
+		for _, test := range tests {
+			t.Run(test.Group.Name, func(t *testing.T) {
+				require.EqualError(t, acls.ValidateGroups(test.Group), test.Err)
+				require.EqualError(t, acls.SetGroups(test.Group), test.Err)
+			})
+		}
+
+		require.EqualError(t,
+			acls.ValidateGroups(acls.Group{Name: "readers"}, acls.Group{Name: "readers"}),
+			`invalid group name: "readers" is defined twice`,
+		)
+	})
+
+	t.Run("custom groups", func(t *testing.T) {
+		require.NoError(t, acls.SetGroups(
+			acls.Group{
+				Name:        "reader-only",
+				Inherit:     "user",
+				Permissions: []string{"-*:import:*", "-email:send", "-*:export"},
+			},
+			acls.Group{
+				Name:        "auditor",
+				Permissions: []string{"api:profile:read", "*admin:*:read"},
+			},
+		))
+
+		require.True(t, acls.IsGroup("reader-only"))
+		require.True(t, acls.IsGroup("staff"))
+		require.False(t, acls.IsGroup("unknown"))
+		require.Len(t, acls.Groups(), 2)
+
+		tests := []struct {
+			Group    string
+			Obj      string
+			Act      string
+			Expected bool
+		}{
+			{"reader-only", "bookmarks", "read", true},
+			{"reader-only", "api:bookmarks", "write", true},
+			{"reader-only", "api:profile:tokens", "delete", true},
+			{"reader-only", "bookmarks", "export", false},
+			{"reader-only", "api:bookmarks", "export", false},
+			{"reader-only", "bookmarks:import", "write", false},
+			{"reader-only", "email", "send", false},
+			{"reader-only", "system", "read", false},
+			{"auditor", "admin:users", "read", true},
+			{"auditor", "api:admin:tasks", "read", true},
+			{"auditor", "admin:users", "write", false},
+			{"auditor", "bookmarks", "read", false},
+			{"user", "bookmarks", "export", true},
+		}
+
+		for _, test := range tests {
+			res, err := acls.Check(test.Group, test.Obj, test.Act)
+			require.NoError(t, err)
+			require.Equal(t, test.Expected, res, "%s-%s-%s", test.Group, test.Obj, test.Act)
+		}
+
+		require.True(t, acls.InGroup("reader-only", "user"))
+		require.False(t, acls.InGroup("user", "reader-only"))
+		require.True(t, acls.InGroup("scoped_bookmarks_w", "reader-only"))
+		require.False(t, acls.InGroup("scoped_bookmarks_r", "reader-only"))
+	})
+
+	t.Run("replace", func(t *testing.T) {
+		require.NoError(t, acls.SetGroups(acls.Group{
+			Name:        "reader-only",
+			Permissions: []string{"bookmarks:read"},
+		}))
+		res, err := acls.GetPermissions("reader-only")
+		require.NoError(t, err)
+		require.Equal(t, []string{"bookmarks:read"}, res)
+
+		ok, err := acls.Check("auditor", "admin:users", "read")
+		require.NoError(t, err)
+		require.False(t, ok)
+
+		// An invalid list doesn't change the groups.
+		require.Error(t, acls.SetGroups(acls.Group{Name: "admin"}))
+		require.True(t, acls.IsGroup("reader-only"))
+	})
+}
@@ -0,0 +1,209 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package acls
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// BuiltinGroups are the groups of the policy file that users can
+// belong to, from the one with the least permissions.
+var BuiltinGroups = []string{"user", "staff", "admin"}
+
+var (
+	// ErrInvalidGroupName is returned when a custom group's name
+	// is invalid or already used.
+	ErrInvalidGroupName = errors.New("invalid group name")
+
+	rxGroupName = regexp.MustCompile(`^[a-z][a-z0-9_-]*$`)
+
+	customGroups  = []Group{}
+	reservedNames = map[string]struct{}{"none": {}}
+	deletedRoles  = map[string]struct{}{}
+)
+
+// Group is a custom group. It receives the permissions of the
+// built-in group it inherits from, if any, then every permission
+// matching its patterns, in order. A pattern is a [path.Match]
+// pattern on the "object:action" permission names, like
+// "api:bookmarks:read" or "*:export". A pattern starting with "-"
+// removes the permissions it matches. Since permissions are granted
+// through the roles of the policy file, a pattern must match every
+// permission of the roles it touches.
+type Group struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Inherit     string   `json:"inherit"`
+	Permissions []string `json:"permissions"`
+}
+
+// Groups returns the custom groups.
+func Groups() []Group {
+	mu.RLock()
+	defer mu.RUnlock()
+	return slices.Clone(customGroups)
+}
+
+// IsGroup returns true when name is a built-in or a custom group.
+func IsGroup(name string) bool {
+	if slices.Contains(BuiltinGroups, name) {
+		return true
+	}
+
+	mu.RLock()
+	defer mu.RUnlock()
+	return slices.ContainsFunc(customGroups, func(g Group) bool {
+		return g.Name == name
+	})
+}
+
+// Permissions returns the names of all the permissions
+// a group can receive.
+func Permissions() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	policies, _ := enforcer.GetPolicy()
+	res := []string{}
+	for _, p := range policies {
+		res = append(res, p[1]+":"+p[2])
+	}
+	slices.Sort(res)
+	return slices.Compact(res)
+}
+
+// ValidateGroups checks a list of custom groups without applying it.
+func ValidateGroups(groups ...Group) error {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	_, err := groupRoles(groups)
+	return err
+}
+
+// SetGroups replaces the custom groups. When one of the groups is
+// invalid, the current groups don't change.
+func SetGroups(groups ...Group) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	roles, err := groupRoles(groups)
+	if err != nil {
+		return err
+	}
+
+	for _, g := range customGroups {
+		if _, err = enforcer.RemoveFilteredGroupingPolicy(0, g.Name); err != nil {
+			return err
+		}
+	}
+
+	for i, g := range groups {
+		for _, role := range roles[i] {
+			if _, err = enforcer.AddGroupingPolicy(g.Name, role); err != nil {
+				return err
+			}
+		}
+	}
+
+	customGroups = slices.Clone(groups)
+	return nil
+}
+
+// groupRoles returns, for each group, the roles of the
+// policy file that grant its permissions.
+func groupRoles(groups []Group) ([][]string, error) {
+	policies, err := enforcer.GetPolicy()
+	if err != nil {
+		return nil, err
+	}
+
+	// Number of permissions in each role
+	roleSizes := map[string]int{}
+	for _, p := range policies {
+		roleSizes[p[0]]++
+	}
+
+	res := make([][]string, len(groups))
+	names := map[string]struct{}{}
+	for i, g := range groups {
+		if _, ok := reservedNames[g.Name]; ok || !rxGroupName.MatchString(g.Name) {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidGroupName, g.Name)
+		}
+		if _, ok := names[g.Name]; ok {
+			return nil, fmt.Errorf("%w: %q is defined twice", ErrInvalidGroupName, g.Name)
+		}
+		names[g.Name] = struct{}{}
+
+		roles := map[string]bool{}
+		if g.Inherit != "" {
+			if !slices.Contains(BuiltinGroups, g.Inherit) {
+				return nil, fmt.Errorf("group %q: can't inherit from %q", g.Name, g.Inherit)
+			}
+			plist, err := enforcer.GetImplicitPermissionsForUser(g.Inherit)
+			if err != nil {
+				return nil, err
+			}
+			for _, p := range plist {
+				roles[p[0]] = true
+			}
+		}
+
+		for _, pattern := range g.Permissions {
+			grant := !strings.HasPrefix(pattern, "-")
+			pattern = strings.TrimPrefix(pattern, "-")
+
+			matched := map[string]int{}
+			for _, p := range policies {
+				ok, err := path.Match(pattern, p[1]+":"+p[2])
+				if err != nil {
+					return nil, fmt.Errorf("group %q: invalid permission %q", g.Name, pattern)
+				}
+				if ok {
+					matched[p[0]]++
+				}
+			}
+			if len(matched) == 0 {
+				return nil, fmt.Errorf("group %q: %q doesn't match any permission", g.Name, pattern)
+			}
+
+			// A role matching partially would grant, or remove,
+			// permissions that the pattern doesn't match.
+			for _, role := range slices.Sorted(maps.Keys(matched)) {
+				if matched[role] < roleSizes[role] {
+					return nil, fmt.Errorf("group %q: %q only covers part of the permissions %s",
+						g.Name, pattern, strings.Join(rolePermissions(policies, role), ", "))
+				}
+				roles[role] = grant
+			}
+		}
+
+		for role, ok := range roles {
+			if _, deleted := deletedRoles[role]; ok && !deleted {
+				res[i] = append(res[i], role)
+			}
+		}
+		slices.Sort(res[i])
+	}
+
+	return res, nil
+}
+
+// rolePermissions returns the "object:action" names of a role's permissions.
+func rolePermissions(policies [][]string, role string) []string {
+	res := []string{}
+	for _, p := range policies {
+		if p[0] == role {
+			res = append(res, p[1]+":"+p[2])
+		}
+	}
+	return res
+}
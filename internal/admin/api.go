@@ -14,6 +14,7 @@ import (
 	"github.com/go-chi/chi/v5"
 
 	"codeberg.org/readeck/readeck/internal/auth"
+	"codeberg.org/readeck/readeck/internal/auth/signin"
 	"codeberg.org/readeck/readeck/internal/auth/users"
 	"codeberg.org/readeck/readeck/internal/bookmarks"
 	"codeberg.org/readeck/readeck/internal/server"
@@ -39,12 +40,35 @@ func newAdminAPI(s *server.Server) *adminAPI {
 	r.With(api.srv.WithPermission("api:admin:users", "read")).Group(func(r chi.Router) {
 		r.With(api.withUserList).Get("/users", api.userList)
 		r.With(api.withUser).Get("/users/{uid:[a-zA-Z0-9]{18,22}}", api.userInfo)
+		r.With(api.withGroupList).Get("/groups", api.groupList)
+		r.With(api.withGroup).Get("/groups/{uid:[a-zA-Z0-9]{18,22}}", api.groupInfo)
+		r.With(api.withInviteList).Get("/invites", api.inviteList)
+		r.With(api.withInvite).Get("/invites/{uid:[a-zA-Z0-9]{18,22}}", api.inviteInfo)
 	})
 
 	r.With(api.srv.WithPermission("api:admin:users", "write")).Group(func(r chi.Router) {
 		r.With(api.withUserList).Post("/users", api.userCreate)
 		r.With(api.withUser).Patch("/users/{uid:[a-zA-Z0-9]{18,22}}", api.userUpdate)
 		r.With(api.withUser).Delete("/users/{uid:[a-zA-Z0-9]{18,22}}", api.userDelete)
+		r.With(api.withUser).Delete("/users/{uid:[a-zA-Z0-9]{18,22}}/totp", api.userTOTPReset)
+		r.With(api.withUser).Delete("/users/{uid:[a-zA-Z0-9]{18,22}}/lockout", api.userUnlock)
+		r.Post("/groups", api.groupCreate)
+		r.With(api.withGroup).Patch("/groups/{uid:[a-zA-Z0-9]{18,22}}", api.groupUpdate)
+		r.With(api.withGroup).Delete("/groups/{uid:[a-zA-Z0-9]{18,22}}", api.groupDelete)
+		r.Post("/invites", api.inviteCreate)
+		r.With(api.withInvite).Delete("/invites/{uid:[a-zA-Z0-9]{18,22}}", api.inviteDelete)
+	})
+
+	r.With(api.srv.WithPermission("api:admin:tasks", "read")).Group(func(r chi.Router) {
+		r.With(api.withDeadLetterList).Get("/tasks/failed", api.deadLetterList)
+		r.With(api.withDeadLetter).Get("/tasks/failed/{uid:[a-zA-Z0-9]{18,22}}", api.deadLetterInfo)
+		r.Get("/tasks/scheduled", api.scheduledTaskList)
+	})
+
+	r.With(api.srv.WithPermission("api:admin:tasks", "write")).Group(func(r chi.Router) {
+		r.Delete("/tasks/failed", api.deadLetterPurge)
+		r.With(api.withDeadLetter).Post("/tasks/failed/{uid:[a-zA-Z0-9]{18,22}}/requeue", api.deadLetterRequeue)
+		r.With(api.withDeadLetter).Delete("/tasks/failed/{uid:[a-zA-Z0-9]{18,22}}", api.deadLetterDelete)
 	})
 
 	return api
@@ -134,6 +158,11 @@ func (api *adminAPI) userInfo(w http.ResponseWriter, r *http.Request) {
 	u := r.Context().Value(ctxUserKey{}).(*users.User)
 	item := newUserItem(api.srv, r, u, "./..")
 	item.Settings = u.Settings
+	if err := item.setTwoFactor(u); err != nil {
+		api.srv.Error(w, r, err)
+		return
+	}
+	item.setLockout(u)
 
 	api.srv.Render(w, r, http.StatusOK, item)
 }
@@ -192,6 +221,26 @@ func (api *adminAPI) userDelete(w http.ResponseWriter, r *http.Request) {
 	api.srv.Error(w, r, err)
 }
 
+func (api *adminAPI) userTOTPReset(w http.ResponseWriter, r *http.Request) {
+	u := r.Context().Value(ctxUserKey{}).(*users.User)
+
+	if err := resetTOTP(u); err != nil {
+		api.srv.Error(w, r, err)
+		return
+	}
+	api.srv.Status(w, r, http.StatusNoContent)
+}
+
+func (api *adminAPI) userUnlock(w http.ResponseWriter, r *http.Request) {
+	u := r.Context().Value(ctxUserKey{}).(*users.User)
+
+	if err := signin.UserAttempts(u).Reset(); err != nil {
+		api.srv.Error(w, r, err)
+		return
+	}
+	api.srv.Status(w, r, http.StatusNoContent)
+}
+
 type userList struct {
 	items      []*users.User
 	Pagination server.Pagination
@@ -199,15 +248,17 @@ type userList struct {
 }
 
 type userItem struct {
-	ID        string              `json:"id"`
-	Href      string              `json:"href"`
-	Created   time.Time           `json:"created"`
-	Updated   time.Time           `json:"updated"`
-	Username  string              `json:"username"`
-	Email     string              `json:"email"`
-	Group     string              `json:"group"`
-	Settings  *users.UserSettings `json:"settings,omitempty"`
-	IsDeleted bool                `json:"is_deleted"`
+	ID          string              `json:"id"`
+	Href        string              `json:"href"`
+	Created     time.Time           `json:"created"`
+	Updated     time.Time           `json:"updated"`
+	Username    string              `json:"username"`
+	Email       string              `json:"email"`
+	Group       string              `json:"group"`
+	Settings    *users.UserSettings `json:"settings,omitempty"`
+	TwoFactor   *bool               `json:"two_factor,omitempty"`
+	LockedUntil *time.Time          `json:"locked_until,omitempty"`
+	IsDeleted   bool                `json:"is_deleted"`
 }
 
 func newUserItem(s *server.Server, r *http.Request, u *users.User, base string) userItem {
@@ -223,6 +274,39 @@ func newUserItem(s *server.Server, r *http.Request, u *users.User, base string)
 	}
 }
 
+// setTwoFactor sets whether the user has an enabled
+// two-factor authentication.
+func (item *userItem) setTwoFactor(u *users.User) error {
+	enabled, err := users.Authenticators.IsEnabled(u)
+	if err != nil {
+		return err
+	}
+	item.TwoFactor = &enabled
+	return nil
+}
+
+// setLockout sets the end of the user's sign in lockout,
+// when there are too many failed attempts.
+func (item *userItem) setLockout(u *users.User) {
+	if a := signin.UserAttempts(u); a.IsLocked() {
+		until := a.LockedUntil().UTC()
+		item.LockedUntil = &until
+	}
+}
+
+// resetTOTP removes the user's authenticator. The user must set up
+// two-factor authentication again when its group requires it.
+func resetTOTP(u *users.User) error {
+	a, err := users.Authenticators.ForUser(u)
+	if errors.Is(err, users.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return a.Delete()
+}
+
 func deleteUser(u *users.User) error {
 	// Remove user's bookmarks first
 	if err := bookmarks.Bookmarks.DeleteUserBookmakrs(u); err != nil {
@@ -0,0 +1,216 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package admin
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/go-chi/chi/v5"
+
+	"codeberg.org/readeck/readeck/internal/auth/users"
+	"codeberg.org/readeck/readeck/internal/server"
+	"codeberg.org/readeck/readeck/pkg/forms"
+)
+
+type (
+	ctxGroupListKey struct{}
+	ctxGroupKey     struct{}
+)
+
+var errGroupInUse = errors.New("the group has users")
+
+func (api *adminAPI) withGroupList(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The groups of the configuration file come first, they have no ID
+		// and can't be changed.
+		res := []*users.Group{}
+		for _, g := range users.ConfigGroups() {
+			res = append(res, &users.Group{
+				Name:        g.Name,
+				Description: g.Description,
+				Inherit:     g.Inherit,
+				Permissions: g.Permissions,
+			})
+		}
+
+		stored, err := users.Groups.All()
+		if err != nil {
+			api.srv.Error(w, r, err)
+			return
+		}
+		for _, g := range stored {
+			if !users.IsConfigGroup(g.Name) {
+				res = append(res, g)
+			}
+		}
+
+		ctx := context.WithValue(r.Context(), ctxGroupListKey{}, res)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (api *adminAPI) withGroup(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		g, err := users.Groups.GetOne(
+			goqu.C("uid").Eq(chi.URLParam(r, "uid")),
+		)
+		if err != nil || users.IsConfigGroup(g.Name) {
+			api.srv.Status(w, r, http.StatusNotFound)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), ctxGroupKey{}, g)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// deleteGroup removes a group that has no user anymore.
+func (api *adminAPI) deleteGroup(g *users.Group) error {
+	count, err := g.CountUsers()
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return errGroupInUse
+	}
+
+	if err = g.Delete(); err != nil {
+		return err
+	}
+	return users.LoadGroups()
+}
+
+func (api *adminAPI) groupList(w http.ResponseWriter, r *http.Request) {
+	items, err := newGroupItems(api.srv, r, r.Context().Value(ctxGroupListKey{}).([]*users.Group), ".")
+	if err != nil {
+		api.srv.Error(w, r, err)
+		return
+	}
+
+	api.srv.Render(w, r, http.StatusOK, items)
+}
+
+func (api *adminAPI) groupInfo(w http.ResponseWriter, r *http.Request) {
+	g := r.Context().Value(ctxGroupKey{}).(*users.Group)
+	item, err := newGroupItem(api.srv, r, g, "./..")
+	if err != nil {
+		api.srv.Error(w, r, err)
+		return
+	}
+
+	api.srv.Render(w, r, http.StatusOK, item)
+}
+
+func (api *adminAPI) groupCreate(w http.ResponseWriter, r *http.Request) {
+	f := newGroupForm(api.srv.Locale(r), nil)
+	forms.Bind(f, r)
+	if !f.IsValid() {
+		api.srv.Render(w, r, http.StatusUnprocessableEntity, f)
+		return
+	}
+
+	g, err := f.save()
+	if err != nil {
+		api.srv.Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Location", api.srv.AbsoluteURL(r, ".", g.UID).String())
+	api.srv.TextMessage(w, r, http.StatusCreated, "Group created")
+}
+
+func (api *adminAPI) groupUpdate(w http.ResponseWriter, r *http.Request) {
+	g := r.Context().Value(ctxGroupKey{}).(*users.Group)
+	f := newGroupForm(api.srv.Locale(r), g)
+	forms.Bind(f, r)
+	if !f.IsValid() {
+		api.srv.Render(w, r, http.StatusUnprocessableEntity, f)
+		return
+	}
+
+	g, err := f.save()
+	if err != nil {
+		api.srv.Error(w, r, err)
+		return
+	}
+
+	item, err := newGroupItem(api.srv, r, g, "./..")
+	if err != nil {
+		api.srv.Error(w, r, err)
+		return
+	}
+	api.srv.Render(w, r, http.StatusOK, item)
+}
+
+func (api *adminAPI) groupDelete(w http.ResponseWriter, r *http.Request) {
+	g := r.Context().Value(ctxGroupKey{}).(*users.Group)
+
+	err := api.deleteGroup(g)
+	if err == nil {
+		api.srv.Status(w, r, http.StatusNoContent)
+		return
+	}
+	if errors.Is(err, errGroupInUse) {
+		api.srv.TextMessage(w, r, http.StatusConflict, err.Error())
+		return
+	}
+
+	api.srv.Error(w, r, err)
+}
+
+type groupItem struct {
+	ID          string     `json:"id,omitempty"`
+	Href        string     `json:"href,omitempty"`
+	Created     *time.Time `json:"created,omitempty"`
+	Updated     *time.Time `json:"updated,omitempty"`
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Inherit     string     `json:"inherit"`
+	Permissions []string   `json:"permissions"`
+	Source      string     `json:"source"`
+	Users       int        `json:"users"`
+}
+
+// newGroupItem returns a group item. The groups of the
+// configuration file have no ID, no link and no dates.
+func newGroupItem(s *server.Server, r *http.Request, g *users.Group, base string) (groupItem, error) {
+	res := groupItem{
+		Name:        g.Name,
+		Description: g.Description,
+		Inherit:     g.Inherit,
+		Permissions: g.Permissions,
+		Source:      "config",
+	}
+	if res.Permissions == nil {
+		res.Permissions = []string{}
+	}
+
+	if g.ID > 0 {
+		res.ID = g.UID
+		res.Href = s.AbsoluteURL(r, base, g.UID).String()
+		res.Created = &g.Created
+		res.Updated = &g.Updated
+		res.Source = "database"
+	}
+
+	count, err := g.CountUsers()
+	res.Users = int(count)
+	return res, err
+}
+
+func newGroupItems(s *server.Server, r *http.Request, groups []*users.Group, base string) ([]groupItem, error) {
+	res := make([]groupItem, len(groups))
+	for i, g := range groups {
+		var err error
+		if res[i], err = newGroupItem(s, r, g, base); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,274 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package admin_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/stretchr/testify/require"
+
+	"codeberg.org/readeck/readeck/configs"
+	"codeberg.org/readeck/readeck/internal/acls"
+	"codeberg.org/readeck/readeck/internal/auth/users"
+	. "codeberg.org/readeck/readeck/internal/testing" //revive:disable:dot-imports
+)
+
+func TestGroupAPI(t *testing.T) {
+	app := NewTestApp(t)
+	defer func() {
+		app.Close(t)
+	}()
+
+	client := NewClient(t, app)
+	u1, err := NewTestUser("test1", "test1@localhost", "test1", "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("groups", func(t *testing.T) {
+		RunRequestSequence(t, client, "admin",
+			RequestTest{
+				JSON:         true,
+				Target:       "/api/admin/groups",
+				ExpectStatus: 200,
+				ExpectJSON:   `[]`,
+			},
+			RequestTest{
+				Method:       "POST",
+				Target:       "/api/admin/groups",
+				JSON:         map[string]any{},
+				ExpectStatus: 422,
+				ExpectJSON: `{
+					"is_valid": false,
+					"errors": null,
+					"fields": {
+						"name": {
+							"is_null": true,
+							"is_bound": false,
+							"value": "",
+							"errors": ["field is required"]
+						},
+						"description": "<<PRESENCE>>",
+						"inherit": "<<PRESENCE>>",
+						"permissions": "<<PRESENCE>>"
+					}
+				}`,
+			},
+			RequestTest{
+				Method: "POST",
+				Target: "/api/admin/groups",
+				JSON: map[string]any{
+					"name":        "admin",
+					"permissions": []string{"bookmarks:read"},
+				},
+				ExpectStatus: 422,
+				ExpectJQ: []any{
+					".fields.name.errors", []any{`invalid group name: "admin"`},
+				},
+			},
+			RequestTest{
+				Method: "POST",
+				Target: "/api/admin/groups",
+				JSON: map[string]any{
+					"name":        "reader-only",
+					"permissions": []string{"nope:*"},
+				},
+				ExpectStatus: 422,
+				ExpectJQ: []any{
+					".fields.permissions.errors", []any{`group "reader-only": "nope:*" doesn't match any permission`},
+				},
+			},
+			RequestTest{
+				Method: "POST",
+				Target: "/api/admin/groups",
+				JSON: map[string]any{
+					"name":        "reader-only",
+					"description": "No import, no email, no export",
+					"inherit":     "user",
+					"permissions": []string{"-*:import:*", "-email:send", "-*:export"},
+				},
+				ExpectStatus: 201,
+				Assert: func(t *testing.T, _ *Response) {
+					require.True(t, acls.IsGroup("reader-only"))
+				},
+			},
+			RequestTest{
+				JSON:         true,
+				Target:       "{{ (index .History 0).Redirect }}",
+				ExpectStatus: 200,
+				ExpectJSON: `{
+					"id": "<<PRESENCE>>",
+					"href": "<<PRESENCE>>",
+					"created": "<<PRESENCE>>",
+					"updated": "<<PRESENCE>>",
+					"name": "reader-only",
+					"description": "No import, no email, no export",
+					"inherit": "user",
+					"permissions": ["-*:import:*", "-email:send", "-*:export"],
+					"source": "database",
+					"users": 0
+				}`,
+			},
+			RequestTest{
+				Method: "POST",
+				Target: "/api/admin/groups",
+				JSON: map[string]any{
+					"name": "reader-only",
+				},
+				ExpectStatus: 422,
+				ExpectJQ: []any{
+					".fields.name.errors", []any{`invalid group name: "reader-only" is defined twice`},
+				},
+			},
+		)
+
+		g, err := users.Groups.GetOne(goqu.C("name").Eq("reader-only"))
+		require.NoError(t, err)
+
+		RunRequestSequence(t, client, "admin",
+			RequestTest{
+				Method: "PATCH",
+				Target: "/api/admin/groups/" + g.UID,
+				JSON: map[string]any{
+					"permissions": []string{"-*:import:*"},
+				},
+				ExpectStatus: 200,
+				ExpectJQ: []any{
+					".name", "reader-only",
+					".description", "No import, no email, no export",
+					".permissions", []any{"-*:import:*"},
+				},
+			},
+			RequestTest{
+				Method:       "PATCH",
+				Target:       "/api/admin/groups/" + g.UID,
+				JSON:         map[string]any{"inherit": "nope"},
+				ExpectStatus: 422,
+			},
+
+			// Assign the group to a user
+			RequestTest{
+				Method:       "PATCH",
+				Target:       "/api/admin/users/" + u1.User.UID,
+				JSON:         map[string]any{"group": "reader-only"},
+				ExpectStatus: 200,
+				Assert: func(t *testing.T, _ *Response) {
+					u, err := users.Users.GetOne(goqu.C("id").Eq(u1.User.ID))
+					require.NoError(t, err)
+					require.Equal(t, "reader-only", u.Group)
+					require.True(t, u.HasPermission("api:bookmarks", "read"))
+					require.True(t, u.HasPermission("api:bookmarks", "export"))
+					require.False(t, u.HasPermission("api:bookmarks:import", "write"))
+					require.False(t, u.HasPermission("admin:users", "read"))
+				},
+			},
+			RequestTest{
+				Method:       "PATCH",
+				Target:       "/api/admin/users/" + u1.User.UID,
+				JSON:         map[string]any{"group": "unknown"},
+				ExpectStatus: 422,
+			},
+
+			// A group with users can't be deleted
+			RequestTest{
+				JSON:         true,
+				Target:       "/api/admin/groups",
+				ExpectStatus: 200,
+				ExpectJQ: []any{
+					".[0].name", "reader-only",
+					".[0].users", 1,
+				},
+			},
+			RequestTest{
+				Method:       "DELETE",
+				Target:       "/api/admin/groups/" + g.UID,
+				JSON:         true,
+				ExpectStatus: 409,
+			},
+			RequestTest{
+				Method:       "PATCH",
+				Target:       "/api/admin/users/" + u1.User.UID,
+				JSON:         map[string]any{"group": "user"},
+				ExpectStatus: 200,
+			},
+			RequestTest{
+				Method:       "DELETE",
+				Target:       "/api/admin/groups/" + g.UID,
+				JSON:         true,
+				ExpectStatus: 204,
+				Assert: func(t *testing.T, _ *Response) {
+					require.False(t, acls.IsGroup("reader-only"))
+				},
+			},
+			RequestTest{
+				JSON:         true,
+				Target:       "/api/admin/groups/" + g.UID,
+				ExpectStatus: 404,
+			},
+		)
+	})
+
+	t.Run("config groups", func(t *testing.T) {
+		filename := filepath.Join(t.TempDir(), "config.toml")
+		require.NoError(t, os.WriteFile(filename, []byte(`
+[[groups]]
+name = "auditor"
+description = "Read only admin"
+permissions = ["*admin:*:read"]
+`), 0o600))
+		require.NoError(t, configs.Config.LoadFile(filename))
+		require.NoError(t, users.LoadGroups())
+		defer func() {
+			configs.Config.Groups = nil
+			require.NoError(t, users.LoadGroups())
+		}()
+
+		RunRequestSequence(t, client, "admin",
+			RequestTest{
+				JSON:         true,
+				Target:       "/api/admin/groups",
+				ExpectStatus: 200,
+				ExpectJSON: `[
+					{
+						"name": "auditor",
+						"description": "Read only admin",
+						"inherit": "",
+						"permissions": ["*admin:*:read"],
+						"source": "config",
+						"users": 0
+					}
+				]`,
+			},
+			RequestTest{
+				Method:       "POST",
+				Target:       "/api/admin/groups",
+				JSON:         map[string]any{"name": "auditor"},
+				ExpectStatus: 422,
+			},
+			RequestTest{
+				Method:       "PATCH",
+				Target:       "/api/admin/users/" + u1.User.UID,
+				JSON:         map[string]any{"group": "auditor"},
+				ExpectStatus: 200,
+				Assert: func(t *testing.T, _ *Response) {
+					u, err := users.Users.GetOne(goqu.C("id").Eq(u1.User.ID))
+					require.NoError(t, err)
+					require.True(t, u.HasPermission("admin:users", "read"))
+					require.False(t, u.HasPermission("admin:users", "write"))
+					require.False(t, u.HasPermission("bookmarks", "read"))
+				},
+			},
+			RequestTest{
+				Method:       "PATCH",
+				Target:       "/api/admin/users/" + u1.User.UID,
+				JSON:         map[string]any{"group": "user"},
+				ExpectStatus: 200,
+			},
+		)
+	})
+}
@@ -0,0 +1,167 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package admin
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/go-chi/chi/v5"
+
+	"codeberg.org/readeck/readeck/configs"
+	"codeberg.org/readeck/readeck/internal/auth/users"
+	"codeberg.org/readeck/readeck/internal/email"
+	"codeberg.org/readeck/readeck/internal/server"
+	"codeberg.org/readeck/readeck/pkg/forms"
+)
+
+type (
+	ctxInviteListKey struct{}
+	ctxInviteKey     struct{}
+)
+
+func (api *adminAPI) withInviteList(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		res := []*users.Invite{}
+		err := users.Invites.Query().
+			Order(goqu.C("created").Desc()).
+			ScanStructs(&res)
+		if err != nil {
+			api.srv.Error(w, r, err)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), ctxInviteListKey{}, res)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (api *adminAPI) withInvite(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		i, err := users.Invites.GetOne(
+			goqu.C("uid").Eq(chi.URLParam(r, "uid")),
+		)
+		if err != nil {
+			api.srv.Status(w, r, http.StatusNotFound)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), ctxInviteKey{}, i)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// createInvite creates an invitation and, when it's tied to an email
+// address, sends the invitation link to this address. It returns
+// true when the email was sent.
+func (api *adminAPI) createInvite(r *http.Request, f *inviteForm) (*users.Invite, bool, error) {
+	i, err := f.createInvite()
+	if err != nil {
+		return nil, false, err
+	}
+
+	if i.Email == "" || !email.CanSendEmail() {
+		return i, false, nil
+	}
+
+	msg, err := email.NewMsg(
+		configs.Config.Email.FromNoReply.String(),
+		i.Email,
+		"[Readeck] Invitation to Readeck",
+		email.WithMDTemplate(
+			"/emails/invite.jet.md",
+			api.srv.TemplateVars(r),
+			server.TC{
+				"SiteURL":    api.srv.AbsoluteURL(r, "/"),
+				"Expires":    i.Expires,
+				"InviteLink": api.srv.AbsoluteURL(r, "/register", i.UID),
+			},
+		),
+	)
+	if err == nil {
+		err = email.Sender.SendEmail(msg)
+	}
+	if err != nil {
+		// The invitation exists, its link can still be shared
+		// by other means.
+		api.srv.Log(r).Error("sending invitation", slog.Any("err", err))
+		return i, false, nil
+	}
+
+	return i, true, nil
+}
+
+func (api *adminAPI) inviteList(w http.ResponseWriter, r *http.Request) {
+	items := newInviteItems(api.srv, r, r.Context().Value(ctxInviteListKey{}).([]*users.Invite), ".")
+	api.srv.Render(w, r, http.StatusOK, items)
+}
+
+func (api *adminAPI) inviteInfo(w http.ResponseWriter, r *http.Request) {
+	i := r.Context().Value(ctxInviteKey{}).(*users.Invite)
+	api.srv.Render(w, r, http.StatusOK, newInviteItem(api.srv, r, i, "./.."))
+}
+
+func (api *adminAPI) inviteCreate(w http.ResponseWriter, r *http.Request) {
+	f := newInviteForm(api.srv.Locale(r))
+	forms.Bind(f, r)
+	if !f.IsValid() {
+		api.srv.Render(w, r, http.StatusUnprocessableEntity, f)
+		return
+	}
+
+	i, _, err := api.createInvite(r, f)
+	if err != nil {
+		api.srv.Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Location", api.srv.AbsoluteURL(r, ".", i.UID).String())
+	api.srv.Render(w, r, http.StatusCreated, newInviteItem(api.srv, r, i, "."))
+}
+
+func (api *adminAPI) inviteDelete(w http.ResponseWriter, r *http.Request) {
+	i := r.Context().Value(ctxInviteKey{}).(*users.Invite)
+	if err := i.Delete(); err != nil {
+		api.srv.Error(w, r, err)
+		return
+	}
+
+	api.srv.Status(w, r, http.StatusNoContent)
+}
+
+type inviteItem struct {
+	ID      string    `json:"id"`
+	Href    string    `json:"href"`
+	URL     string    `json:"url"`
+	Created time.Time `json:"created"`
+	Expires time.Time `json:"expires"`
+	Email   string    `json:"email"`
+	Group   string    `json:"group"`
+	Expired bool      `json:"expired"`
+}
+
+func newInviteItem(s *server.Server, r *http.Request, i *users.Invite, base string) inviteItem {
+	return inviteItem{
+		ID:      i.UID,
+		Href:    s.AbsoluteURL(r, base, i.UID).String(),
+		URL:     s.AbsoluteURL(r, "/register", i.UID).String(),
+		Created: i.Created,
+		Expires: i.Expires,
+		Email:   i.Email,
+		Group:   i.Group,
+		Expired: i.IsExpired(),
+	}
+}
+
+func newInviteItems(s *server.Server, r *http.Request, invites []*users.Invite, base string) []inviteItem {
+	res := make([]inviteItem, len(invites))
+	for i, item := range invites {
+		res[i] = newInviteItem(s, r, item, base)
+	}
+	return res
+}
@@ -0,0 +1,120 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package admin_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/stretchr/testify/require"
+
+	"codeberg.org/readeck/readeck/internal/auth/users"
+	. "codeberg.org/readeck/readeck/internal/testing" //revive:disable:dot-imports
+)
+
+func TestInviteAPI(t *testing.T) {
+	app := NewTestApp(t)
+	defer func() {
+		app.Close(t)
+	}()
+
+	client := NewClient(t, app)
+
+	RunRequestSequence(t, client, "admin",
+		RequestTest{
+			JSON:         true,
+			Target:       "/api/admin/invites",
+			ExpectStatus: 200,
+			ExpectJSON:   `[]`,
+		},
+		RequestTest{
+			Method: "POST",
+			Target: "/api/admin/invites",
+			JSON: map[string]any{
+				"email": "nope",
+				"group": "unknown",
+				"ttl":   0,
+			},
+			ExpectStatus: 422,
+			ExpectJQ: []any{
+				".fields.email.errors", []any{"not a valid email address"},
+				".fields.group.errors", []any{"unknown is not one of user, staff, admin"},
+				".fields.ttl.errors", []any{"must be greater or equal than 1"},
+			},
+		},
+		RequestTest{
+			Method:       "POST",
+			Target:       "/api/admin/invites",
+			JSON:         map[string]any{},
+			ExpectStatus: 201,
+			ExpectJQ: []any{
+				".email", "",
+				".group", "user",
+				".expired", false,
+			},
+			Assert: func(t *testing.T, r *Response) {
+				i, err := users.Invites.GetOne(goqu.C("uid").Eq(r.JSON.(map[string]any)["id"]))
+				require.NoError(t, err)
+				require.WithinDuration(t, time.Now().AddDate(0, 0, 7), i.Expires, time.Minute)
+			},
+		},
+		RequestTest{
+			Method: "POST",
+			Target: "/api/admin/invites",
+			JSON: map[string]any{
+				"email": "guest@localhost",
+				"group": "staff",
+				"ttl":   2,
+			},
+			ExpectStatus: 201,
+			Assert: func(t *testing.T, r *Response) {
+				require.Contains(t, app.LastEmail, r.JSON.(map[string]any)["url"])
+			},
+		},
+		RequestTest{
+			JSON:         true,
+			Target:       "{{ (index .History 0).Redirect }}",
+			ExpectStatus: 200,
+			ExpectJSON: `{
+				"id": "<<PRESENCE>>",
+				"href": "<<PRESENCE>>",
+				"url": "<<PRESENCE>>",
+				"created": "<<PRESENCE>>",
+				"expires": "<<PRESENCE>>",
+				"email": "guest@localhost",
+				"group": "staff",
+				"expired": false
+			}`,
+		},
+		RequestTest{
+			JSON:         true,
+			Target:       "/api/admin/invites",
+			ExpectStatus: 200,
+			ExpectJQ: []any{
+				"length", 2,
+			},
+		},
+		RequestTest{
+			Method:       "DELETE",
+			Target:       "{{ (index .History 1).Path }}",
+			JSON:         true,
+			ExpectStatus: 204,
+		},
+		RequestTest{
+			JSON:         true,
+			Target:       "{{ (index .History 2).Path }}",
+			ExpectStatus: 404,
+		},
+	)
+
+	RunRequestSequence(t, client, "staff",
+		RequestTest{
+			JSON:         true,
+			Target:       "/api/admin/invites",
+			ExpectStatus: 403,
+		},
+	)
+}
@@ -0,0 +1,186 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/go-chi/chi/v5"
+
+	"codeberg.org/readeck/readeck/internal/bus"
+	"codeberg.org/readeck/readeck/internal/server"
+)
+
+type (
+	ctxDeadLetterListKey struct{}
+	ctxDeadLetterKey     struct{}
+)
+
+func (api *adminAPI) withDeadLetterList(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		res := deadLetterList{}
+
+		pf := api.srv.GetPageParams(r, 50)
+		if pf == nil {
+			api.srv.Status(w, r, http.StatusNotFound)
+			return
+		}
+
+		ds := bus.DeadLetters.Query().
+			Order(goqu.I("created").Desc(), goqu.I("id").Desc()).
+			Limit(uint(pf.Limit())).
+			Offset(uint(pf.Offset()))
+
+		count, err := ds.ClearOrder().ClearLimit().ClearOffset().Count()
+		if err != nil {
+			api.srv.Error(w, r, err)
+			return
+		}
+
+		res.items = []*bus.DeadLetter{}
+		if err = ds.ScanStructs(&res.items); err != nil {
+			api.srv.Error(w, r, err)
+			return
+		}
+
+		res.Pagination = api.srv.NewPagination(r, int(count), pf.Limit(), pf.Offset())
+
+		ctx := context.WithValue(r.Context(), ctxDeadLetterListKey{}, res)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (api *adminAPI) withDeadLetter(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		d, err := bus.DeadLetters.GetOne(
+			goqu.C("uid").Eq(chi.URLParam(r, "uid")),
+		)
+		if err != nil {
+			api.srv.Status(w, r, http.StatusNotFound)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), ctxDeadLetterKey{}, d)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (api *adminAPI) deadLetterList(w http.ResponseWriter, r *http.Request) {
+	dl := r.Context().Value(ctxDeadLetterListKey{}).(deadLetterList)
+	dl.Items = make([]deadLetterItem, len(dl.items))
+	for i, item := range dl.items {
+		dl.Items[i] = newDeadLetterItem(api.srv, r, item, ".")
+	}
+
+	api.srv.SendPaginationHeaders(w, r, dl.Pagination)
+	api.srv.Render(w, r, http.StatusOK, dl.Items)
+}
+
+func (api *adminAPI) scheduledTaskList(w http.ResponseWriter, r *http.Request) {
+	api.srv.Render(w, r, http.StatusOK, newScheduledTaskList())
+}
+
+func (api *adminAPI) deadLetterInfo(w http.ResponseWriter, r *http.Request) {
+	d := r.Context().Value(ctxDeadLetterKey{}).(*bus.DeadLetter)
+	api.srv.Render(w, r, http.StatusOK, newDeadLetterItem(api.srv, r, d, "./.."))
+}
+
+func (api *adminAPI) deadLetterRequeue(w http.ResponseWriter, r *http.Request) {
+	d := r.Context().Value(ctxDeadLetterKey{}).(*bus.DeadLetter)
+	if err := d.Requeue(); err != nil {
+		api.srv.Error(w, r, err)
+		return
+	}
+
+	api.srv.TextMessage(w, r, http.StatusAccepted, "Task requeued")
+}
+
+func (api *adminAPI) deadLetterDelete(w http.ResponseWriter, r *http.Request) {
+	d := r.Context().Value(ctxDeadLetterKey{}).(*bus.DeadLetter)
+	if err := d.Delete(); err != nil {
+		api.srv.Error(w, r, err)
+		return
+	}
+
+	api.srv.Status(w, r, http.StatusNoContent)
+}
+
+func (api *adminAPI) deadLetterPurge(w http.ResponseWriter, r *http.Request) {
+	if _, err := bus.DeadLetters.Purge(); err != nil {
+		api.srv.Error(w, r, err)
+		return
+	}
+
+	api.srv.Status(w, r, http.StatusNoContent)
+}
+
+type scheduledTaskItem struct {
+	Name      string     `json:"name"`
+	Schedule  string     `json:"schedule"`
+	LastRun   *time.Time `json:"last_run"`
+	Duration  float64    `json:"duration"`
+	LastError string     `json:"last_error"`
+	NextRun   *time.Time `json:"next_run"`
+}
+
+func newScheduledTaskList() []scheduledTaskItem {
+	schedules := bus.Tasks().Schedules()
+	res := make([]scheduledTaskItem, len(schedules))
+	for i, s := range schedules {
+		res[i] = scheduledTaskItem{
+			Name:      s.Name,
+			Schedule:  s.Schedule,
+			Duration:  s.Duration.Seconds(),
+			LastError: s.LastError,
+		}
+		if !s.LastRun.IsZero() {
+			res[i].LastRun = &s.LastRun
+		}
+		if !s.NextRun.IsZero() {
+			res[i].NextRun = &s.NextRun
+		}
+	}
+
+	return res
+}
+
+type deadLetterList struct {
+	items      []*bus.DeadLetter
+	Pagination server.Pagination
+	Items      []deadLetterItem
+}
+
+type deadLetterItem struct {
+	ID       string          `json:"id"`
+	Href     string          `json:"href"`
+	Created  time.Time       `json:"created"`
+	Task     string          `json:"task"`
+	TaskID   string          `json:"task_id"`
+	Attempts int             `json:"attempts"`
+	Error    string          `json:"error"`
+	Data     json.RawMessage `json:"data"`
+}
+
+func newDeadLetterItem(s *server.Server, r *http.Request, d *bus.DeadLetter, base string) deadLetterItem {
+	res := deadLetterItem{
+		ID:       d.UID,
+		Href:     s.AbsoluteURL(r, base, d.UID).String(),
+		Created:  d.Created,
+		Task:     d.Name,
+		TaskID:   d.OpID,
+		Attempts: d.Attempts,
+		Error:    d.Error,
+		Data:     json.RawMessage(d.Data),
+	}
+	if !json.Valid(res.Data) {
+		res.Data = json.RawMessage("null")
+	}
+
+	return res
+}
@@ -0,0 +1,152 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package admin_test
+
+import (
+	"testing"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/stretchr/testify/require"
+
+	"codeberg.org/readeck/readeck/internal/bus"
+	. "codeberg.org/readeck/readeck/internal/testing" //revive:disable:dot-imports
+)
+
+func TestDeadLetterAPI(t *testing.T) {
+	app := NewTestApp(t)
+	defer func() {
+		app.Close(t)
+	}()
+
+	client := NewClient(t, app)
+
+	newDeadLetter := func() *bus.DeadLetter {
+		d := &bus.DeadLetter{
+			Name:     "bookmark.create",
+			OpID:     "12",
+			Attempts: 4,
+			Data:     `{"BookmarkID":12}`,
+			Error:    "Invalid status code (503)",
+		}
+		require.NoError(t, bus.DeadLetters.Create(d))
+		return d
+	}
+
+	d1 := newDeadLetter()
+	d2 := newDeadLetter()
+
+	RunRequestSequence(t, client, "user",
+		RequestTest{
+			JSON:         true,
+			Target:       "/api/admin/tasks/failed",
+			ExpectStatus: 403,
+		},
+	)
+
+	RunRequestSequence(t, client, "admin",
+		RequestTest{
+			JSON:         true,
+			Target:       "/api/admin/tasks/failed/" + d1.UID,
+			ExpectStatus: 200,
+			ExpectJSON: `{
+				"id": "` + d1.UID + `",
+				"href": "<<PRESENCE>>",
+				"created": "<<PRESENCE>>",
+				"task": "bookmark.create",
+				"task_id": "12",
+				"attempts": 4,
+				"error": "Invalid status code (503)",
+				"data": {"BookmarkID": 12}
+			}`,
+		},
+		RequestTest{
+			JSON:         true,
+			Target:       "/api/admin/tasks/failed",
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				require.Len(t, r.JSON, 2)
+			},
+		},
+		RequestTest{
+			Method:       "POST",
+			JSON:         true,
+			Target:       "/api/admin/tasks/failed/" + d1.UID + "/requeue",
+			ExpectStatus: 202,
+			Assert: func(t *testing.T, _ *Response) {
+				require.Len(t, Events().Records("task"), 1)
+				require.JSONEq(t,
+					`{"name":"bookmark.create","id":"12"}`,
+					string(Events().Records("task")[0]),
+				)
+				_, err := bus.DeadLetters.GetOne(goqu.C("id").Eq(d1.ID))
+				require.ErrorIs(t, err, bus.ErrDeadLetterNotFound)
+			},
+		},
+		RequestTest{
+			Method:       "DELETE",
+			JSON:         true,
+			Target:       "/api/admin/tasks/failed/" + d2.UID,
+			ExpectStatus: 204,
+		},
+		RequestTest{
+			JSON:         true,
+			Target:       "/api/admin/tasks/failed/" + d2.UID,
+			ExpectStatus: 404,
+		},
+	)
+
+	newDeadLetter()
+	RunRequestSequence(t, client, "admin",
+		RequestTest{
+			Method:       "DELETE",
+			JSON:         true,
+			Target:       "/api/admin/tasks/failed",
+			ExpectStatus: 204,
+		},
+		RequestTest{
+			JSON:         true,
+			Target:       "/api/admin/tasks/failed",
+			ExpectStatus: 200,
+			ExpectJSON:   `[]`,
+		},
+	)
+}
+
+func TestScheduledTaskAPI(t *testing.T) {
+	app := NewTestApp(t)
+	defer func() {
+		app.Close(t)
+	}()
+
+	client := NewClient(t, app)
+
+	RunRequestSequence(t, client, "user",
+		RequestTest{
+			JSON:         true,
+			Target:       "/api/admin/tasks/scheduled",
+			ExpectStatus: 403,
+		},
+	)
+
+	RunRequestSequence(t, client, "admin",
+		RequestTest{
+			JSON:         true,
+			Target:       "/api/admin/tasks/scheduled",
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				var purge map[string]any
+				for _, x := range r.JSON.([]any) {
+					if item := x.(map[string]any); item["name"] == "tombstone.purge" {
+						purge = item
+					}
+				}
+				require.NotNil(t, purge)
+				require.Equal(t, "@every 24h0m0s", purge["schedule"])
+				require.Nil(t, purge["last_run"])
+				require.NotEmpty(t, purge["next_run"])
+			},
+		},
+	)
+}
@@ -5,8 +5,13 @@
 package admin_test
 
 import (
+	"fmt"
 	"testing"
+	"time"
 
+	"github.com/stretchr/testify/require"
+
+	"codeberg.org/readeck/readeck/internal/bus"
 	. "codeberg.org/readeck/readeck/internal/testing" //revive:disable:dot-imports
 )
 
@@ -94,6 +99,7 @@ func TestAPI(t *testing.T) {
 					"email": "test1@localhost",
 					"group": "user",
 					"is_deleted": false,
+					"two_factor": false,
 					"settings": "<<PRESENCE>>"
 				}`,
 			},
@@ -422,4 +428,39 @@ func TestAPI(t *testing.T) {
 			},
 		)
 	})
+
+	t.Run("lockout", func(t *testing.T) {
+		u2, err := NewTestUser("locked", "locked@localhost", "locked", "user")
+		require.NoError(t, err)
+		key := fmt.Sprintf("login:user:%d", u2.User.ID)
+		require.NoError(t, bus.Store().Set(key, fmt.Sprintf(`{"c":10,"l":%q}`, time.Now().Format(time.RFC3339)), time.Hour))
+
+		RunRequestSequence(t, client, "admin",
+			RequestTest{
+				JSON:         true,
+				Target:       "/api/admin/users/" + u2.User.UID,
+				ExpectStatus: 200,
+				Assert: func(t *testing.T, rsp *Response) {
+					rsp.AssertJQ(t, ".locked_until | length > 0", true)
+				},
+			},
+			RequestTest{
+				Method:       "DELETE",
+				Target:       "/api/admin/users/" + u2.User.UID + "/lockout",
+				JSON:         true,
+				ExpectStatus: 204,
+				Assert: func(t *testing.T, _ *Response) {
+					require.Empty(t, bus.Store().Get(key))
+				},
+			},
+			RequestTest{
+				JSON:         true,
+				Target:       "/api/admin/users/" + u2.User.UID,
+				ExpectStatus: 200,
+				Assert: func(t *testing.T, rsp *Response) {
+					rsp.AssertJQ(t, "has(\"locked_until\")", false)
+				},
+			},
+		)
+	})
 }
@@ -6,7 +6,12 @@ package admin
 
 import (
 	"context"
+	"errors"
+	"strings"
+	"time"
 
+	"codeberg.org/readeck/readeck/configs"
+	"codeberg.org/readeck/readeck/internal/acls"
 	"codeberg.org/readeck/readeck/internal/auth/users"
 	"codeberg.org/readeck/readeck/pkg/forms"
 )
@@ -31,3 +36,158 @@ func (f *deleteForm) trigger(u *users.User) error {
 
 	return deleteUserTask.Run(u.ID, u.ID)
 }
+
+type groupForm struct {
+	*forms.Form
+	group *users.Group
+}
+
+// newGroupForm returns a form to create a new custom group, or to
+// update an existing one when g is not nil. A group can't be renamed.
+func newGroupForm(tr forms.Translator, g *users.Group) *groupForm {
+	inherit := [][2]string{{"", tr.Gettext("no group")}}
+	for _, name := range acls.BuiltinGroups {
+		inherit = append(inherit, [2]string{name, name})
+	}
+
+	fields := []forms.Field{
+		forms.NewTextField("description", forms.Trim),
+		forms.NewTextField("inherit", forms.Trim, forms.ChoicesPairs(inherit)),
+		forms.NewTextListField("permissions", forms.Trim),
+	}
+	if g == nil {
+		fields = append([]forms.Field{
+			forms.NewTextField("name", forms.Trim, forms.Required),
+		}, fields...)
+	}
+
+	f := &groupForm{
+		Form:  forms.Must(forms.WithTranslator(context.Background(), tr), fields...),
+		group: g,
+	}
+	if g != nil {
+		f.Get("description").Set(g.Description)
+		f.Get("inherit").Set(g.Inherit)
+		f.Get("permissions").Set([]string(g.Permissions))
+	}
+
+	return f
+}
+
+// Validate checks the group's permissions against all the
+// other custom groups.
+func (f *groupForm) Validate() {
+	if !f.IsValid() {
+		return
+	}
+
+	if err := users.ValidateGroup(f.values()); err != nil {
+		if errors.Is(err, acls.ErrInvalidGroupName) {
+			f.AddErrors("name", err)
+		} else {
+			f.AddErrors("permissions", err)
+		}
+	}
+}
+
+// PermissionsText returns the permission patterns, one per line.
+func (f *groupForm) PermissionsText() string {
+	return strings.Join(f.patterns(), "\n")
+}
+
+// patterns returns the permission patterns. A value can contain
+// several patterns, separated by spaces or new lines.
+func (f *groupForm) patterns() []string {
+	res := []string{}
+	if f.Get("permissions").IsNil() {
+		return res
+	}
+	for _, v := range f.Get("permissions").(forms.TypedField[[]string]).V() {
+		res = append(res, strings.Fields(v)...)
+	}
+	return res
+}
+
+// values returns the group with the form's values.
+func (f *groupForm) values() *users.Group {
+	g := &users.Group{}
+	if f.group != nil {
+		*g = *f.group
+	} else {
+		g.Name = f.Get("name").String()
+	}
+	g.Description = f.Get("description").String()
+	g.Inherit = f.Get("inherit").String()
+	g.Permissions = f.patterns()
+
+	return g
+}
+
+// save creates or updates the group, then applies
+// the new custom groups.
+func (f *groupForm) save() (*users.Group, error) {
+	g := f.values()
+
+	var err error
+	if g.ID == 0 {
+		err = users.Groups.Create(g)
+	} else {
+		err = g.Save()
+	}
+	if err == nil {
+		err = users.LoadGroups()
+	}
+	if err != nil {
+		f.AddErrors("", forms.ErrUnexpected)
+		return nil, err
+	}
+
+	return g, nil
+}
+
+type inviteForm struct {
+	*forms.Form
+}
+
+// newInviteForm returns a form to create an invitation. The
+// invitation is valid for a number of days.
+func newInviteForm(tr forms.Translator) *inviteForm {
+	groups := [][2]string{}
+	for _, g := range users.GroupChoices() {
+		if g[0] != "none" {
+			groups = append(groups, g)
+		}
+	}
+
+	return &inviteForm{forms.Must(
+		forms.WithTranslator(context.Background(), tr),
+		forms.NewTextField("email", forms.Trim, forms.IsEmail),
+		forms.NewTextField("group",
+			forms.Trim,
+			forms.Default(configs.Config.Auth.Registration.DefaultGroup),
+			forms.ChoicesPairs(groups),
+		),
+		forms.NewIntegerField("ttl",
+			forms.Default(configs.Config.Auth.Registration.InviteTTL),
+			forms.Gte(1), forms.Lte(365),
+		),
+	)}
+}
+
+// createInvite creates the invitation.
+func (f *inviteForm) createInvite() (*users.Invite, error) {
+	i := &users.Invite{
+		Email:   f.Get("email").String(),
+		Group:   f.Get("group").String(),
+		Expires: time.Now().AddDate(0, 0, f.Get("ttl").(forms.TypedField[int]).V()),
+	}
+	if i.Group == "" {
+		i.Group = configs.Config.Auth.Registration.DefaultGroup
+	}
+
+	if err := users.Invites.Create(i); err != nil {
+		f.AddErrors("", forms.ErrUnexpected)
+		return nil, err
+	}
+	return i, nil
+}
@@ -19,4 +19,7 @@ func SetupRoutes(s *server.Server) {
 
 	// Website views
 	s.AddRoute("/admin", newAdminViews(api))
+
+	// SCIM provisioning
+	s.AddRoute("/scim/v2", newSCIMAPI(api))
 }
@@ -0,0 +1,297 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"codeberg.org/readeck/readeck/internal/auth"
+	"codeberg.org/readeck/readeck/pkg/http/accept"
+)
+
+// SCIM 2.0 (RFC 7643 and RFC 7644) lets an identity provider create,
+// update, deactivate and delete users, and set their group.
+// A user has only one group, so adding a user to a group moves it
+// out of its previous group.
+
+const (
+	scimContentType = "application/scim+json"
+
+	// scimRole is the token role that gives access to the SCIM
+	// endpoints. Only a token with this role can use them.
+	scimRole = "scoped_scim"
+
+	// scimDefaultGroup is the group of the created users and of the
+	// users removed from their group.
+	scimDefaultGroup = "user"
+
+	// scimMaxResults is the maximum number of resources in a list.
+	scimMaxResults = 100
+
+	scimSchemaUser         = "urn:ietf:params:scim:schemas:core:2.0:User"
+	scimSchemaGroup        = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	scimSchemaListResponse = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	scimSchemaPatchOp      = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	scimSchemaError        = "urn:ietf:params:scim:api:messages:2.0:Error"
+	scimSchemaSPConfig     = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+	scimSchemaResourceType = "urn:ietf:params:scim:schemas:core:2.0:ResourceType"
+)
+
+// rxSCIMFilter matches the only filter form we support:
+// an attribute name, the "eq" operator and a quoted value.
+var rxSCIMFilter = regexp.MustCompile(`(?i)^\s*([a-z][a-z0-9.]*)\s+eq\s+"((?:[^"\\]|\\.)*)"\s*$`)
+
+// scimError is an error returned to the SCIM client, with its HTTP
+// status and an optional SCIM error type.
+type scimError struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	ScimType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail,omitempty"`
+}
+
+func newSCIMError(status int, scimType, detail string) *scimError {
+	return &scimError{
+		Schemas:  []string{scimSchemaError},
+		Status:   strconv.Itoa(status),
+		ScimType: scimType,
+		Detail:   detail,
+	}
+}
+
+func (e *scimError) Error() string {
+	return e.Detail
+}
+
+type scimMeta struct {
+	ResourceType string     `json:"resourceType"`
+	Created      *time.Time `json:"created,omitempty"`
+	LastModified *time.Time `json:"lastModified,omitempty"`
+	Location     string     `json:"location"`
+}
+
+type scimListResponse struct {
+	Schemas      []string `json:"schemas"`
+	TotalResults int      `json:"totalResults"`
+	StartIndex   int      `json:"startIndex"`
+	ItemsPerPage int      `json:"itemsPerPage"`
+	Resources    any      `json:"Resources"`
+}
+
+// scimPatchRequest is the body of a PATCH request.
+type scimPatchRequest struct {
+	Schemas    []string `json:"schemas"`
+	Operations []struct {
+		Op    string          `json:"op"`
+		Path  string          `json:"path"`
+		Value json.RawMessage `json:"value"`
+	} `json:"Operations"`
+}
+
+type scimAPI struct {
+	chi.Router
+	*adminAPI
+}
+
+func newSCIMAPI(api *adminAPI) *scimAPI {
+	r := api.srv.AuthenticatedRouter()
+	h := &scimAPI{r, api}
+
+	r.Use(withSCIMToken)
+
+	r.With(api.srv.WithPermission("api:scim", "read")).Group(func(r chi.Router) {
+		r.Get("/ServiceProviderConfig", h.serviceProviderConfig)
+		r.Get("/ResourceTypes", h.resourceTypes)
+		r.Get("/Users", h.userList)
+		r.With(h.withUser).Get("/Users/{uid:[a-zA-Z0-9]{18,22}}", h.userInfo)
+		r.Get("/Groups", h.groupList)
+		r.With(h.withGroup).Get("/Groups/{name}", h.groupInfo)
+	})
+
+	r.With(api.srv.WithPermission("api:scim", "write")).Group(func(r chi.Router) {
+		r.Post("/Users", h.userCreate)
+		r.With(h.withUser).Put("/Users/{uid:[a-zA-Z0-9]{18,22}}", h.userReplace)
+		r.With(h.withUser).Patch("/Users/{uid:[a-zA-Z0-9]{18,22}}", h.userPatch)
+		r.With(h.withUser).Delete("/Users/{uid:[a-zA-Z0-9]{18,22}}", h.userDelete)
+		r.With(h.withGroup).Patch("/Groups/{name}", h.groupPatch)
+	})
+
+	return h
+}
+
+// withSCIMToken only lets in the requests authenticated with a token
+// that has the SCIM role. The permission checks that the token's
+// owner is an administrator.
+func withSCIMToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		info := auth.GetRequestAuthInfo(r)
+		if info.Provider == nil || !slices.Contains(info.Provider.Roles, scimRole) {
+			w.Header().Set("Content-Type", scimContentType)
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(newSCIMError( //nolint:errcheck
+				http.StatusForbidden, "", "a token with the SCIM provisioning role is required",
+			))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// render sends a SCIM response. A client asking for
+// "application/json" receives it.
+func (h *scimAPI) render(w http.ResponseWriter, r *http.Request, status int, value any) {
+	w.Header().Set("Content-Type", accept.NegotiateContentType(
+		r.Header, []string{scimContentType, "application/json"}, scimContentType,
+	)+"; charset=utf-8")
+	h.srv.Render(w, r, status, value)
+}
+
+// error sends a SCIM error response. Any error that is not
+// a [scimError] is a server error.
+func (h *scimAPI) error(w http.ResponseWriter, r *http.Request, err error) {
+	var e *scimError
+	if !errors.As(err, &e) {
+		h.srv.Log(r).Error("server error", slog.Any("err", err))
+		e = newSCIMError(http.StatusInternalServerError, "", http.StatusText(http.StatusInternalServerError))
+	}
+
+	status, _ := strconv.Atoi(e.Status)
+	h.render(w, r, status, e)
+}
+
+// decode reads the JSON body of a request.
+func (h *scimAPI) decode(r *http.Request, v any) error {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		return newSCIMError(http.StatusBadRequest, "invalidSyntax", err.Error())
+	}
+	return nil
+}
+
+// decodePatch reads the body of a PATCH request.
+func (h *scimAPI) decodePatch(r *http.Request, req *scimPatchRequest) error {
+	if err := h.decode(r, req); err != nil {
+		return err
+	}
+	if !slices.Contains(req.Schemas, scimSchemaPatchOp) {
+		return newSCIMError(http.StatusBadRequest, "invalidSyntax", "not a PatchOp request")
+	}
+	return nil
+}
+
+// location returns the absolute URL of a resource.
+func (h *scimAPI) location(r *http.Request, parts ...string) string {
+	return h.srv.AbsoluteURL(r, append([]string{"/scim/v2"}, parts...)...).String()
+}
+
+// parseFilter returns the attribute and the value of a filter. Only
+// the "eq" operator is supported, which is what identity providers
+// use to find an existing resource.
+func parseFilter(filter string) (attr string, value string, err error) {
+	m := rxSCIMFilter.FindStringSubmatch(filter)
+	if m == nil {
+		return "", "", newSCIMError(http.StatusBadRequest, "invalidFilter",
+			fmt.Sprintf("unsupported filter: %s", filter),
+		)
+	}
+
+	if value, err = strconv.Unquote(`"` + m[2] + `"`); err != nil {
+		return "", "", newSCIMError(http.StatusBadRequest, "invalidFilter", err.Error())
+	}
+	return strings.ToLower(m[1]), value, nil
+}
+
+// paginate returns the 1-based start index and the number of items
+// of a list request.
+func paginate(r *http.Request) (start, count int) {
+	start, count = 1, scimMaxResults
+	if v, err := strconv.Atoi(r.URL.Query().Get("startIndex")); err == nil && v > 1 {
+		start = v
+	}
+	if v, err := strconv.Atoi(r.URL.Query().Get("count")); err == nil && v >= 0 && v < count {
+		count = v
+	}
+	return
+}
+
+// newListResponse returns a list response with a page of resources.
+func newListResponse[T any](page []T, total, start int) scimListResponse {
+	if page == nil {
+		page = []T{}
+	}
+	return scimListResponse{
+		Schemas:      []string{scimSchemaListResponse},
+		TotalResults: total,
+		StartIndex:   start,
+		ItemsPerPage: len(page),
+		Resources:    page,
+	}
+}
+
+// pageOf returns a page of items.
+func pageOf[T any](items []T, start, count int) []T {
+	if start > len(items) {
+		return nil
+	}
+	return items[start-1 : min(start-1+count, len(items))]
+}
+
+func (h *scimAPI) serviceProviderConfig(w http.ResponseWriter, r *http.Request) {
+	supported := func(v bool) map[string]bool {
+		return map[string]bool{"supported": v}
+	}
+
+	h.render(w, r, http.StatusOK, map[string]any{
+		"schemas":        []string{scimSchemaSPConfig},
+		"patch":          supported(true),
+		"bulk":           map[string]any{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":         map[string]any{"supported": true, "maxResults": scimMaxResults},
+		"changePassword": supported(true),
+		"sort":           supported(false),
+		"etag":           supported(false),
+		"authenticationSchemes": []map[string]any{{
+			"type":        "oauthbearertoken",
+			"name":        "OAuth Bearer Token",
+			"description": "Readeck API token with the SCIM provisioning role",
+			"primary":     true,
+		}},
+		"meta": scimMeta{
+			ResourceType: "ServiceProviderConfig",
+			Location:     h.location(r, "ServiceProviderConfig"),
+		},
+	})
+}
+
+func (h *scimAPI) resourceTypes(w http.ResponseWriter, r *http.Request) {
+	resourceType := func(name, endpoint, schema string) map[string]any {
+		return map[string]any{
+			"schemas":  []string{scimSchemaResourceType},
+			"id":       name,
+			"name":     name,
+			"endpoint": endpoint,
+			"schema":   schema,
+			"meta": scimMeta{
+				ResourceType: "ResourceType",
+				Location:     h.location(r, "ResourceTypes", name),
+			},
+		}
+	}
+
+	items := []map[string]any{
+		resourceType("User", "/Users", scimSchemaUser),
+		resourceType("Group", "/Groups", scimSchemaGroup),
+	}
+	h.render(w, r, http.StatusOK, newListResponse(items, len(items), 1))
+}
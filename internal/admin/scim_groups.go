@@ -0,0 +1,302 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/go-chi/chi/v5"
+
+	"codeberg.org/readeck/readeck/internal/acls"
+	"codeberg.org/readeck/readeck/internal/auth/users"
+)
+
+type ctxSCIMGroupKey struct{}
+
+type scimGroup struct {
+	Schemas     []string    `json:"schemas"`
+	ID          string      `json:"id"`
+	DisplayName string      `json:"displayName"`
+	Members     []scimValue `json:"members,omitempty"`
+	Meta        scimMeta    `json:"meta"`
+}
+
+// scimGroupNames returns the names of the built-in
+// and the custom groups.
+func scimGroupNames() []string {
+	res := slices.Clone(acls.BuiltinGroups)
+	for _, g := range acls.Groups() {
+		res = append(res, g.Name)
+	}
+	return res
+}
+
+func (h *scimAPI) withGroup(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := chi.URLParam(r, "name")
+		if !acls.IsGroup(name) {
+			h.error(w, r, newSCIMError(http.StatusNotFound, "", "group not found"))
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), ctxSCIMGroupKey{}, name)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// newSCIMGroup returns the SCIM resource of a group. The members are
+// left out when the client excludes them, since a group can have
+// many members.
+func (h *scimAPI) newSCIMGroup(r *http.Request, name string) (scimGroup, error) {
+	res := scimGroup{
+		Schemas:     []string{scimSchemaGroup},
+		ID:          name,
+		DisplayName: name,
+		Meta: scimMeta{
+			ResourceType: "Group",
+			Location:     h.location(r, "Groups", name),
+		},
+	}
+	if strings.Contains(strings.ToLower(r.URL.Query().Get("excludedAttributes")), "members") {
+		return res, nil
+	}
+
+	members := []*users.User{}
+	err := users.Users.Query().
+		Where(goqu.C("group").Eq(name)).
+		Order(goqu.C("id").Asc()).
+		ScanStructs(&members)
+	if err != nil {
+		return res, err
+	}
+
+	for _, u := range members {
+		res.Members = append(res.Members, scimValue{
+			Value:   u.UID,
+			Display: u.Username,
+			Ref:     h.location(r, "Users", u.UID),
+		})
+	}
+	return res, nil
+}
+
+// setMembers moves the users to a group. The deactivated users get
+// the group back when they're activated again.
+func (h *scimAPI) setMembers(r *http.Request, group string, members []scimValue) error {
+	for _, m := range members {
+		u, err := users.Users.GetOne(goqu.C("uid").Eq(m.Value))
+		if err != nil {
+			return newSCIMError(http.StatusBadRequest, "invalidValue", "unknown member: "+m.Value)
+		}
+		if u.Group == "none" {
+			if err = u.Update(goqu.Record{"previous_group": group}); err != nil {
+				return err
+			}
+			continue
+		}
+		if u.Group == group {
+			continue
+		}
+		if err = h.saveUser(r, u, userChanges{group: &group}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeMembers moves the members of a group to the default group.
+// The deactivated members lose the group they would get back.
+// When uids is nil, all the members are removed.
+func (h *scimAPI) removeMembers(r *http.Request, group string, uids []string) error {
+	if group == scimDefaultGroup {
+		return nil
+	}
+
+	ds := users.Users.Query().Where(goqu.Or(
+		goqu.C("group").Eq(group),
+		goqu.C("previous_group").Eq(group),
+	))
+	if uids != nil {
+		if len(uids) == 0 {
+			return nil
+		}
+		ds = ds.Where(goqu.C("uid").In(uids))
+	}
+
+	members := []*users.User{}
+	if err := ds.ScanStructs(&members); err != nil {
+		return err
+	}
+
+	fallback := scimDefaultGroup
+	for _, u := range members {
+		if u.Group == "none" {
+			if err := u.Update(goqu.Record{"previous_group": ""}); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := h.saveUser(r, u, userChanges{group: &fallback}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h *scimAPI) groupList(w http.ResponseWriter, r *http.Request) {
+	names := scimGroupNames()
+
+	if filter := r.URL.Query().Get("filter"); filter != "" {
+		attr, value, err := parseFilter(filter)
+		if err != nil {
+			h.error(w, r, err)
+			return
+		}
+		if attr != "id" && attr != "displayname" {
+			h.error(w, r, newSCIMError(http.StatusBadRequest, "invalidFilter", "unsupported filter attribute: "+attr))
+			return
+		}
+		names = slices.DeleteFunc(names, func(name string) bool {
+			return name != value
+		})
+	}
+
+	start, count := paginate(r)
+	page := pageOf(names, start, count)
+	items := make([]scimGroup, len(page))
+	for i, name := range page {
+		var err error
+		if items[i], err = h.newSCIMGroup(r, name); err != nil {
+			h.error(w, r, err)
+			return
+		}
+	}
+
+	h.render(w, r, http.StatusOK, newListResponse(items, len(names), start))
+}
+
+func (h *scimAPI) groupInfo(w http.ResponseWriter, r *http.Request) {
+	item, err := h.newSCIMGroup(r, r.Context().Value(ctxSCIMGroupKey{}).(string))
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+
+	h.render(w, r, http.StatusOK, item)
+}
+
+// groupPatch changes the members of a group. The groups can't be
+// renamed.
+func (h *scimAPI) groupPatch(w http.ResponseWriter, r *http.Request) {
+	group := r.Context().Value(ctxSCIMGroupKey{}).(string)
+
+	var req scimPatchRequest
+	if err := h.decodePatch(r, &req); err != nil {
+		h.error(w, r, err)
+		return
+	}
+
+	for _, op := range req.Operations {
+		path, value := op.Path, op.Value
+
+		// Without a path, the value contains the attributes
+		if path == "" {
+			var attrs struct {
+				DisplayName *string         `json:"displayName"`
+				Members     json.RawMessage `json:"members"`
+			}
+			if err := json.Unmarshal(op.Value, &attrs); err != nil {
+				h.error(w, r, newSCIMError(http.StatusBadRequest, "invalidValue", err.Error()))
+				return
+			}
+			if attrs.DisplayName != nil && *attrs.DisplayName != group {
+				h.error(w, r, newSCIMError(http.StatusBadRequest, "mutability", "a group can't be renamed"))
+				return
+			}
+			if attrs.Members == nil {
+				continue
+			}
+			path, value = "members", attrs.Members
+		}
+
+		if err := h.patchMembers(r, group, strings.ToLower(op.Op), path, value); err != nil {
+			h.error(w, r, err)
+			return
+		}
+	}
+
+	item, err := h.newSCIMGroup(r, group)
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	h.render(w, r, http.StatusOK, item)
+}
+
+// patchMembers applies a PATCH operation on the members of a group.
+func (h *scimAPI) patchMembers(r *http.Request, group, op, rawPath string, value json.RawMessage) error {
+	path := strings.ToLower(rawPath)
+
+	switch {
+	case path == "displayname":
+		var name string
+		if err := json.Unmarshal(value, &name); err != nil || name != group {
+			return newSCIMError(http.StatusBadRequest, "mutability", "a group can't be renamed")
+		}
+		return nil
+	case strings.HasPrefix(path, "members[") && op == "remove":
+		// members[value eq "<uid>"]
+		attr, uid, err := parseFilter(strings.TrimSuffix(rawPath[len("members["):], "]"))
+		if err != nil || attr != "value" {
+			return newSCIMError(http.StatusBadRequest, "invalidPath", "unsupported path: "+rawPath)
+		}
+		return h.removeMembers(r, group, []string{uid})
+	case path != "members":
+		return newSCIMError(http.StatusBadRequest, "invalidPath", "unsupported path: "+rawPath)
+	}
+
+	var members []scimValue
+	if len(value) > 0 {
+		if err := json.Unmarshal(value, &members); err != nil {
+			return newSCIMError(http.StatusBadRequest, "invalidValue", err.Error())
+		}
+	}
+
+	uids := make([]string, len(members))
+	for i, m := range members {
+		uids[i] = m.Value
+	}
+
+	switch op {
+	case "add":
+		return h.setMembers(r, group, members)
+	case "remove":
+		if members == nil {
+			return h.removeMembers(r, group, nil)
+		}
+		return h.removeMembers(r, group, uids)
+	case "replace":
+		if err := h.setMembers(r, group, members); err != nil {
+			return err
+		}
+		current := []string{}
+		err := users.Users.Query().Select("uid").
+			Where(goqu.C("group").Eq(group)).
+			ScanVals(&current)
+		if err != nil {
+			return err
+		}
+		return h.removeMembers(r, group, slices.DeleteFunc(current, func(uid string) bool {
+			return slices.Contains(uids, uid)
+		}))
+	}
+
+	return newSCIMError(http.StatusBadRequest, "invalidSyntax", "unsupported operation: "+op)
+}
@@ -0,0 +1,504 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package admin_test
+
+import (
+	"testing"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/stretchr/testify/require"
+
+	"codeberg.org/readeck/readeck/internal/auth/users"
+	"codeberg.org/readeck/readeck/internal/db/types"
+	. "codeberg.org/readeck/readeck/internal/testing" //revive:disable:dot-imports
+)
+
+func TestSCIM(t *testing.T) {
+	app := NewTestApp(t)
+	defer func() {
+		app.Close(t)
+	}()
+
+	client := NewClient(t, app)
+
+	setRoles := func(t *testing.T, user string, roles ...string) {
+		require.NoError(t, app.Users[user].Token.Update(goqu.Record{
+			"roles": types.Strings(roles),
+		}))
+	}
+
+	getUser := func(t *testing.T, username string) *users.User {
+		u, err := users.Users.GetOne(goqu.C("username").Eq(username))
+		require.NoError(t, err)
+		return u
+	}
+
+	patchOp := func(ops ...map[string]any) map[string]any {
+		return map[string]any{
+			"schemas":    []string{"urn:ietf:params:scim:api:messages:2.0:PatchOp"},
+			"Operations": ops,
+		}
+	}
+
+	t.Run("token", func(t *testing.T) {
+		// A token without the SCIM role can't use the endpoints
+		RunRequestSequence(t, client, "admin",
+			RequestTest{JSON: true, Target: "/scim/v2/Users", ExpectStatus: 403},
+		)
+
+		// A non admin user can't have a SCIM token
+		setRoles(t, "staff", "scoped_scim")
+		RunRequestSequence(t, client, "staff",
+			RequestTest{JSON: true, Target: "/scim/v2/Users", ExpectStatus: 403},
+		)
+
+		// No session either
+		RunRequestSequence(t, client, "",
+			RequestTest{JSON: true, Target: "/scim/v2/Users", ExpectStatus: 401},
+		)
+	})
+
+	setRoles(t, "admin", "scoped_scim")
+
+	t.Run("service provider", func(t *testing.T) {
+		RunRequestSequence(t, client, "admin",
+			RequestTest{
+				JSON:         true,
+				Target:       "/scim/v2/ServiceProviderConfig",
+				ExpectStatus: 200,
+				ExpectJQ: []any{
+					".patch.supported", true,
+					".bulk.supported", false,
+				},
+			},
+			RequestTest{
+				JSON:         true,
+				Target:       "/scim/v2/ResourceTypes",
+				ExpectStatus: 200,
+				ExpectJQ: []any{
+					".totalResults", 2.0,
+					"[.Resources[].endpoint]", []any{"/Users", "/Groups"},
+				},
+			},
+		)
+	})
+
+	t.Run("users", func(t *testing.T) {
+		RunRequestSequence(t, client, "admin",
+			RequestTest{
+				JSON:         true,
+				Target:       "/scim/v2/Users",
+				ExpectStatus: 200,
+				ExpectJQ: []any{
+					".totalResults", 4.0,
+					".startIndex", 1.0,
+				},
+			},
+			RequestTest{
+				JSON:         true,
+				Target:       "/scim/v2/Users?startIndex=2&count=1",
+				ExpectStatus: 200,
+				ExpectJQ: []any{
+					".totalResults", 4.0,
+					".itemsPerPage", 1.0,
+				},
+			},
+			RequestTest{
+				JSON:         true,
+				Target:       `/scim/v2/Users?filter=userName+eq+"user"`,
+				ExpectStatus: 200,
+				ExpectJQ: []any{
+					".totalResults", 1.0,
+					".Resources[0].userName", "user",
+					".Resources[0].active", true,
+					".Resources[0].groups[0].value", "user",
+				},
+			},
+			RequestTest{
+				JSON:         true,
+				Target:       `/scim/v2/Users?filter=userName+eq+"alice@example.org"`,
+				ExpectStatus: 200,
+				ExpectJQ: []any{
+					".totalResults", 0.0,
+				},
+			},
+			RequestTest{
+				JSON:         true,
+				Target:       `/scim/v2/Users?filter=userName+sw+"a"`,
+				ExpectStatus: 400,
+				ExpectJQ: []any{
+					".scimType", "invalidFilter",
+				},
+			},
+			RequestTest{
+				Method: "POST",
+				Target: "/scim/v2/Users",
+				JSON: map[string]any{
+					"schemas":  []string{"urn:ietf:params:scim:schemas:core:2.0:User"},
+					"userName": "alice@example.org",
+					"name":     map[string]any{"givenName": "Alice"},
+					"active":   true,
+				},
+				ExpectStatus: 201,
+				ExpectJSON: `{
+					"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"],
+					"id": "<<PRESENCE>>",
+					"userName": "alice",
+					"active": true,
+					"emails": [{"value": "alice@example.org", "type": "work", "primary": true}],
+					"groups": [{"value": "user", "display": "user", "$ref": "<<PRESENCE>>"}],
+					"meta": {
+						"resourceType": "User",
+						"created": "<<PRESENCE>>",
+						"lastModified": "<<PRESENCE>>",
+						"location": "<<PRESENCE>>"
+					}
+				}`,
+			},
+			RequestTest{
+				Method: "POST",
+				Target: "/scim/v2/Users",
+				JSON: map[string]any{
+					"userName": "alice",
+					"emails":   []map[string]any{{"value": "alice@example.net", "primary": true}},
+				},
+				ExpectStatus: 409,
+				ExpectJQ: []any{
+					".scimType", "uniqueness",
+					".status", "409",
+				},
+			},
+			RequestTest{
+				Method:       "POST",
+				Target:       "/scim/v2/Users",
+				JSON:         map[string]any{"userName": "bob"},
+				ExpectStatus: 400,
+				ExpectJQ: []any{
+					".scimType", "invalidValue",
+				},
+			},
+			RequestTest{
+				JSON:         true,
+				Target:       `/scim/v2/Users?filter=userName+eq+"alice@example.org"`,
+				ExpectStatus: 200,
+				ExpectJQ: []any{
+					".totalResults", 1.0,
+					".Resources[0].userName", "alice",
+				},
+			},
+			RequestTest{
+				JSON:         true,
+				Target:       `/scim/v2/Users?filter=emails.value+eq+"alice@example.org"`,
+				ExpectStatus: 200,
+				ExpectJQ: []any{
+					".totalResults", 1.0,
+				},
+			},
+		)
+
+		alice := getUser(t, "alice")
+		seed := alice.Seed
+
+		RunRequestSequence(t, client, "admin",
+			// Deactivation, the way some providers send it
+			RequestTest{
+				Method: "PATCH",
+				Target: "/scim/v2/Users/" + alice.UID,
+				JSON: patchOp(
+					map[string]any{"op": "Replace", "path": "active", "value": "False"},
+				),
+				ExpectStatus: 200,
+				ExpectJQ: []any{
+					".active", false,
+					".groups", []any{},
+				},
+				Assert: func(t *testing.T, _ *Response) {
+					u := getUser(t, "alice")
+					require.Equal(t, "none", u.Group)
+					require.NotEqual(t, seed, u.Seed)
+				},
+			},
+			RequestTest{
+				Method: "PATCH",
+				Target: "/scim/v2/Users/" + alice.UID,
+				JSON: patchOp(
+					map[string]any{"op": "replace", "value": map[string]any{"active": true}},
+					map[string]any{"op": "replace", "path": `emails[type eq "work"].value`, "value": "alice@example.net"},
+					map[string]any{"op": "add", "path": "name.familyName", "value": "Liddell"},
+				),
+				ExpectStatus: 200,
+				ExpectJQ: []any{
+					".active", true,
+					".emails[0].value", "alice@example.net",
+					".groups[0].value", "user",
+				},
+			},
+			RequestTest{
+				Method:       "PATCH",
+				Target:       "/scim/v2/Users/" + alice.UID,
+				JSON:         map[string]any{"Operations": []any{}},
+				ExpectStatus: 400,
+			},
+			RequestTest{
+				Method: "PUT",
+				Target: "/scim/v2/Users/" + alice.UID,
+				JSON: map[string]any{
+					"userName": "alice.liddell",
+					"emails":   []map[string]any{{"value": "alice@example.net"}},
+					"password": "wonderland",
+				},
+				ExpectStatus: 200,
+				ExpectJQ: []any{
+					".userName", "alice-liddell",
+					".active", true,
+				},
+				Assert: func(t *testing.T, _ *Response) {
+					require.True(t, getUser(t, "alice-liddell").CheckPassword("wonderland"))
+				},
+			},
+
+			// A reactivated user gets its group back
+			RequestTest{
+				Method: "PATCH",
+				Target: "/scim/v2/Groups/staff",
+				JSON: patchOp(
+					map[string]any{"op": "add", "path": "members", "value": []map[string]any{{"value": alice.UID}}},
+				),
+				ExpectStatus: 200,
+			},
+			RequestTest{
+				Method: "PATCH",
+				Target: "/scim/v2/Users/" + alice.UID,
+				JSON: patchOp(
+					map[string]any{"op": "replace", "path": "active", "value": false},
+				),
+				ExpectStatus: 200,
+				Assert: func(t *testing.T, _ *Response) {
+					u := getUser(t, "alice-liddell")
+					require.Equal(t, "none", u.Group)
+					require.Equal(t, "staff", u.PreviousGroup)
+				},
+			},
+			RequestTest{
+				Method: "PATCH",
+				Target: "/scim/v2/Groups/staff",
+				JSON: patchOp(
+					map[string]any{"op": "remove", "path": `members[value eq "` + alice.UID + `"]`},
+				),
+				ExpectStatus: 200,
+				Assert: func(t *testing.T, _ *Response) {
+					u := getUser(t, "alice-liddell")
+					require.Equal(t, "none", u.Group)
+					require.Equal(t, "", u.PreviousGroup)
+				},
+			},
+			RequestTest{
+				Method: "PATCH",
+				Target: "/scim/v2/Groups/staff",
+				JSON: patchOp(
+					map[string]any{"op": "add", "path": "members", "value": []map[string]any{{"value": alice.UID}}},
+				),
+				ExpectStatus: 200,
+				Assert: func(t *testing.T, _ *Response) {
+					u := getUser(t, "alice-liddell")
+					require.Equal(t, "none", u.Group)
+					require.Equal(t, "staff", u.PreviousGroup)
+				},
+			},
+			RequestTest{
+				Method: "PATCH",
+				Target: "/scim/v2/Users/" + alice.UID,
+				JSON: patchOp(
+					map[string]any{"op": "replace", "path": "active", "value": true},
+				),
+				ExpectStatus: 200,
+				ExpectJQ: []any{
+					".active", true,
+					".groups[0].value", "staff",
+				},
+			},
+
+			// The attributes we keep can't be removed
+			RequestTest{
+				Method: "PATCH",
+				Target: "/scim/v2/Users/" + alice.UID,
+				JSON: patchOp(
+					map[string]any{"op": "remove", "path": "emails"},
+				),
+				ExpectStatus: 400,
+				ExpectJQ: []any{
+					".scimType", "mutability",
+				},
+			},
+			RequestTest{
+				Method: "PATCH",
+				Target: "/scim/v2/Users/" + alice.UID,
+				JSON: patchOp(
+					map[string]any{"op": "remove", "path": "active"},
+				),
+				ExpectStatus: 400,
+				ExpectJQ: []any{
+					".scimType", "mutability",
+				},
+			},
+			RequestTest{
+				Method: "PATCH",
+				Target: "/scim/v2/Users/" + alice.UID,
+				JSON: patchOp(
+					map[string]any{"op": "remove"},
+				),
+				ExpectStatus: 400,
+				ExpectJQ: []any{
+					".scimType", "noTarget",
+				},
+			},
+			RequestTest{
+				Method: "PATCH",
+				Target: "/scim/v2/Users/" + alice.UID,
+				JSON: patchOp(
+					map[string]any{"op": "remove", "path": "name.familyName"},
+				),
+				ExpectStatus: 200,
+				ExpectJQ: []any{
+					".active", true,
+					".emails[0].value", "alice@example.net",
+				},
+			},
+
+			// The token's owner can't deactivate or delete itself
+			RequestTest{
+				Method: "PATCH",
+				Target: "/scim/v2/Users/" + app.Users["admin"].User.UID,
+				JSON: patchOp(
+					map[string]any{"op": "replace", "path": "active", "value": false},
+				),
+				ExpectStatus: 400,
+				ExpectJQ: []any{
+					".scimType", "mutability",
+				},
+			},
+			RequestTest{
+				Method:       "DELETE",
+				Target:       "/scim/v2/Users/" + app.Users["admin"].User.UID,
+				JSON:         true,
+				ExpectStatus: 409,
+			},
+
+			// Deprovisioning
+			RequestTest{
+				Method:       "DELETE",
+				Target:       "/scim/v2/Users/" + alice.UID,
+				JSON:         true,
+				ExpectStatus: 204,
+				Assert: func(t *testing.T, _ *Response) {
+					// The user is deactivated until the task removes it
+					u, err := users.Users.GetOne(goqu.C("uid").Eq(alice.UID))
+					if err == nil {
+						require.Equal(t, "none", u.Group)
+					} else {
+						require.ErrorIs(t, err, users.ErrNotFound)
+					}
+				},
+			},
+			RequestTest{
+				JSON:         true,
+				Target:       "/scim/v2/Users/" + alice.UID,
+				ExpectStatus: 404,
+			},
+		)
+	})
+
+	t.Run("groups", func(t *testing.T) {
+		u := app.Users["user"].User
+
+		RunRequestSequence(t, client, "admin",
+			RequestTest{
+				JSON:         true,
+				Target:       "/scim/v2/Groups?excludedAttributes=members",
+				ExpectStatus: 200,
+				ExpectJQ: []any{
+					".totalResults", 3.0,
+					"[.Resources[].id]", []any{"user", "staff", "admin"},
+					"[.Resources[].members]", []any{nil, nil, nil},
+				},
+			},
+			RequestTest{
+				JSON:         true,
+				Target:       `/scim/v2/Groups?filter=displayName+eq+"staff"`,
+				ExpectStatus: 200,
+				ExpectJQ: []any{
+					".totalResults", 1.0,
+					".Resources[0].members[0].display", "staff",
+				},
+			},
+			RequestTest{
+				JSON:         true,
+				Target:       "/scim/v2/Groups/nope",
+				ExpectStatus: 404,
+			},
+			RequestTest{
+				Method: "PATCH",
+				Target: "/scim/v2/Groups/staff",
+				JSON: patchOp(
+					map[string]any{"op": "add", "path": "members", "value": []map[string]any{{"value": u.UID}}},
+				),
+				ExpectStatus: 200,
+				ExpectJQ: []any{
+					"[.members[].display]", []any{"staff", "user"},
+				},
+				Assert: func(t *testing.T, _ *Response) {
+					require.Equal(t, "staff", getUser(t, "user").Group)
+				},
+			},
+			RequestTest{
+				Method: "PATCH",
+				Target: "/scim/v2/Groups/staff",
+				JSON: patchOp(
+					map[string]any{"op": "remove", "path": `members[value eq "` + u.UID + `"]`},
+				),
+				ExpectStatus: 200,
+				ExpectJQ: []any{
+					"[.members[].display]", []any{"staff"},
+				},
+				Assert: func(t *testing.T, _ *Response) {
+					require.Equal(t, "user", getUser(t, "user").Group)
+				},
+			},
+			RequestTest{
+				Method: "PATCH",
+				Target: "/scim/v2/Groups/staff",
+				JSON: patchOp(
+					map[string]any{"op": "replace", "path": "members", "value": []map[string]any{{"value": u.UID}}},
+				),
+				ExpectStatus: 200,
+				ExpectJQ: []any{
+					"[.members[].display]", []any{"user"},
+				},
+				Assert: func(t *testing.T, _ *Response) {
+					require.Equal(t, "user", getUser(t, "staff").Group)
+				},
+			},
+			RequestTest{
+				Method: "PATCH",
+				Target: "/scim/v2/Groups/staff",
+				JSON: patchOp(
+					map[string]any{"op": "replace", "path": "displayName", "value": "team"},
+				),
+				ExpectStatus: 400,
+				ExpectJQ: []any{
+					".scimType", "mutability",
+				},
+			},
+			RequestTest{
+				Method: "PATCH",
+				Target: "/scim/v2/Groups/staff",
+				JSON: patchOp(
+					map[string]any{"op": "add", "path": "members", "value": []map[string]any{{"value": "unknown"}}},
+				),
+				ExpectStatus: 400,
+			},
+		)
+	})
+}
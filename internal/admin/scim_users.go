@@ -0,0 +1,485 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/go-chi/chi/v5"
+
+	"codeberg.org/readeck/readeck/internal/acls"
+	"codeberg.org/readeck/readeck/internal/auth"
+	"codeberg.org/readeck/readeck/internal/auth/users"
+	"codeberg.org/readeck/readeck/pkg/base58"
+)
+
+// rxSCIMInvalidUsername matches the characters that can't be part
+// of a username.
+var rxSCIMInvalidUsername = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+type ctxSCIMUserKey struct{}
+
+// scimValue is a multi-valued attribute item, like an email address,
+// a group of a user or a member of a group.
+type scimValue struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+	Ref     string `json:"$ref,omitempty"`
+}
+
+type scimUser struct {
+	Schemas  []string    `json:"schemas"`
+	ID       string      `json:"id"`
+	UserName string      `json:"userName"`
+	Active   bool        `json:"active"`
+	Emails   []scimValue `json:"emails"`
+	Groups   []scimValue `json:"groups"`
+	Meta     scimMeta    `json:"meta"`
+}
+
+// scimUserInput is the body of a user creation or replacement, or
+// the value of a PATCH operation without a path. The other
+// attributes are ignored.
+type scimUserInput struct {
+	UserName *string         `json:"userName"`
+	Password *string         `json:"password"`
+	Active   json.RawMessage `json:"active"`
+	Emails   []scimValue     `json:"emails"`
+}
+
+// userChanges are the user attributes to update. A nil
+// attribute is left unchanged.
+type userChanges struct {
+	username *string
+	email    *string
+	password *string
+	active   *bool
+	group    *string
+}
+
+// merge adds the attributes of a user input to the changes.
+func (c *userChanges) merge(in scimUserInput) error {
+	if in.UserName != nil {
+		c.username = in.UserName
+	}
+	if in.Password != nil {
+		c.password = in.Password
+	}
+	if len(in.Active) > 0 {
+		active, err := scimBool(in.Active)
+		if err != nil {
+			return err
+		}
+		c.active = &active
+	}
+	if in.Emails != nil {
+		email := primaryEmail(in.Emails)
+		c.email = &email
+	}
+	return nil
+}
+
+// primaryEmail returns the primary email address of a list,
+// or the first one.
+func primaryEmail(emails []scimValue) string {
+	if len(emails) == 0 {
+		return ""
+	}
+	if i := slices.IndexFunc(emails, func(v scimValue) bool { return v.Primary }); i >= 0 {
+		return emails[i].Value
+	}
+	return emails[0].Value
+}
+
+// scimBool reads a boolean value. Some identity providers send
+// booleans as strings.
+func scimBool(data json.RawMessage) (bool, error) {
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return false, newSCIMError(http.StatusBadRequest, "invalidValue", err.Error())
+	}
+
+	switch v := v.(type) {
+	case bool:
+		return v, nil
+	case string:
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b, nil
+		}
+	}
+	return false, newSCIMError(http.StatusBadRequest, "invalidValue", "not a boolean value")
+}
+
+// scimUsername returns the username of a SCIM userName. When it's an
+// email address, the username is its local part. The invalid
+// characters are replaced with "-".
+func scimUsername(v string) string {
+	v, _, _ = strings.Cut(strings.TrimSpace(v), "@")
+	return strings.Trim(rxSCIMInvalidUsername.ReplaceAllString(v, "-"), "-")
+}
+
+func (h *scimAPI) withUser(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		u, err := users.Users.GetOne(goqu.C("uid").Eq(chi.URLParam(r, "uid")))
+		if err != nil || deleteUserTask.IsRunning(u.ID) {
+			h.error(w, r, newSCIMError(http.StatusNotFound, "", "user not found"))
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), ctxSCIMUserKey{}, u)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// newSCIMUser returns the SCIM resource of a user.
+func (h *scimAPI) newSCIMUser(r *http.Request, u *users.User) scimUser {
+	res := scimUser{
+		Schemas:  []string{scimSchemaUser},
+		ID:       u.UID,
+		UserName: u.Username,
+		Active:   u.Group != "none",
+		Emails:   []scimValue{{Value: u.Email, Type: "work", Primary: true}},
+		Groups:   []scimValue{},
+		Meta: scimMeta{
+			ResourceType: "User",
+			Created:      &u.Created,
+			LastModified: &u.Updated,
+			Location:     h.location(r, "Users", u.UID),
+		},
+	}
+	if res.Active {
+		res.Groups = append(res.Groups, scimValue{
+			Value:   u.Group,
+			Display: u.Group,
+			Ref:     h.location(r, "Groups", u.Group),
+		})
+	}
+	return res
+}
+
+// saveUser applies the changes to a user and saves it. A new user
+// is created with a random password, unless one is given.
+func (h *scimAPI) saveUser(r *http.Request, u *users.User, c userChanges) error {
+	group := u.Group
+
+	if c.username != nil {
+		u.Username = scimUsername(*c.username)
+		if strings.Contains(*c.username, "@") && c.email == nil && u.Email == "" {
+			c.email = c.username
+		}
+	}
+	if c.email != nil {
+		u.Email = strings.TrimSpace(*c.email)
+	}
+	if c.active != nil {
+		switch {
+		case !*c.active && u.Group != "none":
+			u.PreviousGroup = u.Group
+			u.Group = "none"
+		case *c.active && (u.Group == "none" || u.Group == ""):
+			// Give back the group the user had before its
+			// deactivation, when it still exists.
+			u.Group = scimDefaultGroup
+			if acls.IsGroup(u.PreviousGroup) {
+				u.Group = u.PreviousGroup
+			}
+			u.PreviousGroup = ""
+		}
+	}
+	if c.group != nil {
+		u.Group = *c.group
+	}
+	if u.Group == "" {
+		u.Group = scimDefaultGroup
+	}
+
+	switch {
+	case u.Username == "":
+		return newSCIMError(http.StatusBadRequest, "invalidValue", "userName is required")
+	case u.Email == "":
+		return newSCIMError(http.StatusBadRequest, "invalidValue", "an email address is required")
+	case strings.Count(u.Email, "@") != 1:
+		return newSCIMError(http.StatusBadRequest, "invalidValue", "not a valid email address")
+	case c.password != nil && len(*c.password) < 8:
+		return newSCIMError(http.StatusBadRequest, "invalidValue", "password must be at least 8 character long")
+	case u.ID != 0 && u.ID == auth.GetRequestUser(r).ID && u.Group != group:
+		return newSCIMError(http.StatusBadRequest, "mutability", "the token's owner can't change its own group")
+	}
+
+	count, err := users.Users.Query().Where(
+		goqu.C("id").Neq(u.ID),
+		goqu.Or(
+			goqu.C("username").Eq(u.Username),
+			goqu.C("email").Eq(u.Email),
+		),
+	).Count()
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return newSCIMError(http.StatusConflict, "uniqueness", "userName or email address is already in use")
+	}
+
+	if u.ID == 0 {
+		u.Password = base58.NewUUID() + base58.NewUUID()
+		if c.password != nil {
+			u.Password = *c.password
+		}
+		return users.Users.Create(u)
+	}
+
+	record := goqu.Record{
+		"username":       u.Username,
+		"email":          u.Email,
+		"group":          u.Group,
+		"updated":        time.Now(),
+		"previous_group": u.PreviousGroup,
+	}
+	if c.password != nil {
+		if u.Password, err = u.HashPassword(*c.password); err != nil {
+			return err
+		}
+		record["password"] = u.Password
+	}
+	if c.password != nil || u.Group != group {
+		// Sign out the user from everywhere
+		record["seed"] = u.SetSeed()
+	}
+	u.Updated = record["updated"].(time.Time)
+
+	return u.Update(record)
+}
+
+func (h *scimAPI) userList(w http.ResponseWriter, r *http.Request) {
+	ds := users.Users.Query().Order(goqu.C("id").Asc())
+
+	if filter := r.URL.Query().Get("filter"); filter != "" {
+		attr, value, err := parseFilter(filter)
+		if err != nil {
+			h.error(w, r, err)
+			return
+		}
+
+		switch attr {
+		case "id":
+			ds = ds.Where(goqu.C("uid").Eq(value))
+		case "username":
+			// An email address matches the username made
+			// of it or the email address itself.
+			var cond goqu.Expression = goqu.C("username").Eq(scimUsername(value))
+			if strings.Contains(value, "@") {
+				cond = goqu.Or(cond, goqu.C("email").Eq(value))
+			}
+			ds = ds.Where(cond)
+		case "email", "emails", "emails.value":
+			ds = ds.Where(goqu.C("email").Eq(value))
+		case "externalid":
+			// We don't keep the external ID
+			ds = ds.Where(goqu.L("1 = 0"))
+		default:
+			h.error(w, r, newSCIMError(http.StatusBadRequest, "invalidFilter", "unsupported filter attribute: "+attr))
+			return
+		}
+	}
+
+	total, err := ds.Count()
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+
+	start, count := paginate(r)
+	list := []*users.User{}
+	if err = ds.Offset(uint(start - 1)).Limit(uint(count)).ScanStructs(&list); err != nil {
+		h.error(w, r, err)
+		return
+	}
+
+	items := make([]scimUser, len(list))
+	for i, u := range list {
+		items[i] = h.newSCIMUser(r, u)
+	}
+
+	h.render(w, r, http.StatusOK, newListResponse(items, int(total), start))
+}
+
+func (h *scimAPI) userInfo(w http.ResponseWriter, r *http.Request) {
+	u := r.Context().Value(ctxSCIMUserKey{}).(*users.User)
+	h.render(w, r, http.StatusOK, h.newSCIMUser(r, u))
+}
+
+func (h *scimAPI) userCreate(w http.ResponseWriter, r *http.Request) {
+	var in scimUserInput
+	if err := h.decode(r, &in); err != nil {
+		h.error(w, r, err)
+		return
+	}
+
+	c := userChanges{}
+	if err := c.merge(in); err != nil {
+		h.error(w, r, err)
+		return
+	}
+
+	u := &users.User{}
+	if err := h.saveUser(r, u, c); err != nil {
+		h.error(w, r, err)
+		return
+	}
+
+	h.srv.Log(r).Info("user provisioned",
+		slog.String("user", u.Username),
+		slog.String("group", u.Group),
+	)
+
+	w.Header().Set("Location", h.location(r, "Users", u.UID))
+	h.render(w, r, http.StatusCreated, h.newSCIMUser(r, u))
+}
+
+// userReplace replaces the user attributes. A missing "active"
+// attribute means the user is active.
+func (h *scimAPI) userReplace(w http.ResponseWriter, r *http.Request) {
+	u := r.Context().Value(ctxSCIMUserKey{}).(*users.User)
+
+	var in scimUserInput
+	if err := h.decode(r, &in); err != nil {
+		h.error(w, r, err)
+		return
+	}
+
+	active := true
+	c := userChanges{active: &active}
+	if err := c.merge(in); err != nil {
+		h.error(w, r, err)
+		return
+	}
+
+	if err := h.saveUser(r, u, c); err != nil {
+		h.error(w, r, err)
+		return
+	}
+
+	h.render(w, r, http.StatusOK, h.newSCIMUser(r, u))
+}
+
+// userPatch applies the operations of a PATCH request on the user.
+// The attributes we don't keep, like the name or the external ID,
+// are ignored. The ones we keep are all required and can't be removed.
+func (h *scimAPI) userPatch(w http.ResponseWriter, r *http.Request) {
+	u := r.Context().Value(ctxSCIMUserKey{}).(*users.User)
+
+	var req scimPatchRequest
+	if err := h.decodePatch(r, &req); err != nil {
+		h.error(w, r, err)
+		return
+	}
+
+	c := userChanges{}
+	for _, op := range req.Operations {
+		path := strings.ToLower(op.Path)
+		var err error
+		switch {
+		case strings.EqualFold(op.Op, "remove"):
+			err = removeUserAttribute(op.Path)
+		case path == "":
+			var in scimUserInput
+			if err = json.Unmarshal(op.Value, &in); err != nil {
+				err = newSCIMError(http.StatusBadRequest, "invalidValue", err.Error())
+				break
+			}
+			err = c.merge(in)
+		case path == "active":
+			err = c.merge(scimUserInput{Active: op.Value})
+		case path == "username":
+			var v string
+			if err = json.Unmarshal(op.Value, &v); err == nil {
+				c.username = &v
+			}
+		case path == "password":
+			var v string
+			if err = json.Unmarshal(op.Value, &v); err == nil {
+				c.password = &v
+			}
+		case strings.HasPrefix(path, "emails"):
+			// The value is either an address or a list of addresses.
+			var v string
+			if json.Unmarshal(op.Value, &v) != nil {
+				var emails []scimValue
+				if err = json.Unmarshal(op.Value, &emails); err == nil {
+					v = primaryEmail(emails)
+				}
+			}
+			c.email = &v
+		}
+		if err != nil {
+			var e *scimError
+			if !errors.As(err, &e) {
+				err = newSCIMError(http.StatusBadRequest, "invalidValue", err.Error())
+			}
+			h.error(w, r, err)
+			return
+		}
+	}
+
+	if err := h.saveUser(r, u, c); err != nil {
+		h.error(w, r, err)
+		return
+	}
+
+	h.render(w, r, http.StatusOK, h.newSCIMUser(r, u))
+}
+
+// removeUserAttribute returns an error when a PATCH operation
+// removes an attribute we keep.
+func removeUserAttribute(rawPath string) error {
+	path := strings.ToLower(rawPath)
+	attr, _, _ := strings.Cut(path, "[")
+	attr, _, _ = strings.Cut(attr, ".")
+
+	switch attr {
+	case "":
+		return newSCIMError(http.StatusBadRequest, "noTarget", "a remove operation needs a path")
+	case "active", "username", "password", "emails":
+		return newSCIMError(http.StatusBadRequest, "mutability", rawPath+" can't be removed")
+	case "groups":
+		return newSCIMError(http.StatusBadRequest, "mutability", "groups is read-only")
+	}
+	return nil
+}
+
+// userDelete deactivates the user right away and launches
+// its deletion task.
+func (h *scimAPI) userDelete(w http.ResponseWriter, r *http.Request) {
+	u := r.Context().Value(ctxSCIMUserKey{}).(*users.User)
+	if u.ID == auth.GetRequestUser(r).ID {
+		h.error(w, r, newSCIMError(http.StatusConflict, "", errSameUser.Error()))
+		return
+	}
+
+	active := false
+	if err := h.saveUser(r, u, userChanges{active: &active}); err != nil {
+		h.error(w, r, err)
+		return
+	}
+	if err := deleteUserTask.Run(u.ID, u.ID); err != nil {
+		h.error(w, r, err)
+		return
+	}
+
+	h.srv.Log(r).Info("user deprovisioned", slog.String("user", u.Username))
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -7,6 +7,7 @@ package admin
 import (
 	"encoding/json"
 	"log/slog"
+	"time"
 
 	"github.com/doug-martin/goqu/v9"
 
@@ -15,12 +16,18 @@ import (
 	"codeberg.org/readeck/readeck/pkg/superbus"
 )
 
-var deleteUserTask superbus.Task
+var (
+	deleteUserTask superbus.Task
+
+	// purgeInvitesTask removes the expired invitations.
+	purgeInvitesTask superbus.Task
+)
 
 func init() {
 	bus.OnReady(func() {
 		deleteUserTask = bus.Tasks().NewTask(
 			"user.delete",
+			superbus.WithTaskQueue(bus.QueueMaintenance),
 			superbus.WithTaskDelay(20),
 			superbus.WithUnmarshall(func(data []byte) interface{} {
 				var res int
@@ -32,6 +39,13 @@ func init() {
 			}),
 			superbus.WithTaskHandler(deleteUserHandler),
 		)
+
+		purgeInvitesTask = bus.Tasks().NewTask(
+			"invite.purge",
+			superbus.WithTaskQueue(bus.QueueMaintenance),
+			superbus.WithTaskSchedule(superbus.Every(24*time.Hour)),
+			superbus.WithFallibleTaskHandler(purgeInvitesHandler),
+		)
 	})
 }
 
@@ -52,3 +66,12 @@ func deleteUserHandler(data interface{}) {
 
 	logger.Info("user removed")
 }
+
+func purgeInvitesHandler(_ interface{}) error {
+	n, err := users.Invites.Purge(time.Now())
+	if err != nil {
+		return err
+	}
+	slog.Debug("expired invitations purged", slog.Int64("count", n))
+	return nil
+}
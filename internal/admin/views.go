@@ -5,13 +5,18 @@
 package admin
 
 import (
+	"errors"
 	"log/slog"
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
 
+	"codeberg.org/readeck/readeck/configs"
+	"codeberg.org/readeck/readeck/internal/acls"
 	"codeberg.org/readeck/readeck/internal/auth"
+	"codeberg.org/readeck/readeck/internal/auth/signin"
 	"codeberg.org/readeck/readeck/internal/auth/users"
+	"codeberg.org/readeck/readeck/internal/bus"
 	"codeberg.org/readeck/readeck/internal/server"
 	"codeberg.org/readeck/readeck/pkg/forms"
 )
@@ -31,12 +36,27 @@ func newAdminViews(api *adminAPI) *adminViews {
 		r.With(api.withUserList).Get("/users", h.userList)
 		r.Get("/users/add", h.userCreate)
 		r.With(api.withUser).Get("/users/{uid:[a-zA-Z0-9]{18,22}}", h.userInfo)
+		r.With(api.withGroupList).Get("/groups", h.groupList)
+		r.Get("/groups/add", h.groupCreate)
+		r.With(api.withGroup).Get("/groups/{uid:[a-zA-Z0-9]{18,22}}", h.groupInfo)
+		r.With(api.withInviteList).Get("/invites", h.inviteList)
 	})
 
 	r.With(api.srv.WithPermission("admin:users", "write")).Group(func(r chi.Router) {
 		r.Post("/users/add", h.userCreate)
 		r.With(api.withUser).Post("/users/{uid:[a-zA-Z0-9]{18,22}}", h.userInfo)
 		r.With(api.withUser).Post("/users/{uid:[a-zA-Z0-9]{18,22}}/delete", h.userDelete)
+		r.With(api.withUser).Post("/users/{uid:[a-zA-Z0-9]{18,22}}/totp/reset", h.userTOTPReset)
+		r.With(api.withUser).Post("/users/{uid:[a-zA-Z0-9]{18,22}}/unlock", h.userUnlock)
+		r.Post("/groups/add", h.groupCreate)
+		r.With(api.withGroup).Post("/groups/{uid:[a-zA-Z0-9]{18,22}}", h.groupInfo)
+		r.With(api.withGroup).Post("/groups/{uid:[a-zA-Z0-9]{18,22}}/delete", h.groupDelete)
+		r.With(api.withInviteList).Post("/invites", h.inviteList)
+		r.With(api.withInvite).Post("/invites/{uid:[a-zA-Z0-9]{18,22}}/delete", h.inviteDelete)
+	})
+
+	r.With(api.srv.WithPermission("admin:tasks", "read")).Group(func(r chi.Router) {
+		r.Get("/tasks", h.taskList)
 	})
 
 	return h
@@ -99,6 +119,11 @@ func (h *adminViews) userInfo(w http.ResponseWriter, r *http.Request) {
 	tr := h.srv.Locale(r)
 	u := r.Context().Value(ctxUserKey{}).(*users.User)
 	item := newUserItem(h.srv, r, u, "./..")
+	if err := item.setTwoFactor(u); err != nil {
+		h.srv.Error(w, r, err)
+		return
+	}
+	item.setLockout(u)
 
 	f := users.NewUserForm(h.srv.Locale(r))
 	f.SetUser(u)
@@ -125,8 +150,9 @@ func (h *adminViews) userInfo(w http.ResponseWriter, r *http.Request) {
 	}
 
 	ctx := server.TC{
-		"User": item,
-		"Form": f,
+		"User":      item,
+		"Form":      f,
+		"TwoFactor": *item.TwoFactor,
 	}
 	ctx.SetBreadcrumbs([][2]string{
 		{tr.Gettext("Users"), h.srv.AbsoluteURL(r, "/admin/users").String()},
@@ -153,3 +179,189 @@ func (h *adminViews) userDelete(w http.ResponseWriter, r *http.Request) {
 	}
 	h.srv.Redirect(w, r, f.Get("_to").String())
 }
+
+func (h *adminViews) userTOTPReset(w http.ResponseWriter, r *http.Request) {
+	u := r.Context().Value(ctxUserKey{}).(*users.User)
+
+	if err := resetTOTP(u); err != nil {
+		h.srv.Error(w, r, err)
+		return
+	}
+	h.srv.AddFlash(w, r, "success", h.srv.Locale(r).Gettext("Two-factor authentication was reset."))
+	h.srv.Redirect(w, r, u.UID)
+}
+
+func (h *adminViews) userUnlock(w http.ResponseWriter, r *http.Request) {
+	u := r.Context().Value(ctxUserKey{}).(*users.User)
+
+	if err := signin.UserAttempts(u).Reset(); err != nil {
+		h.srv.Error(w, r, err)
+		return
+	}
+	h.srv.AddFlash(w, r, "success", h.srv.Locale(r).Gettext("The user was unlocked."))
+	h.srv.Redirect(w, r, u.UID)
+}
+
+func (h *adminViews) groupList(w http.ResponseWriter, r *http.Request) {
+	tr := h.srv.Locale(r)
+	items, err := newGroupItems(h.srv, r, r.Context().Value(ctxGroupListKey{}).([]*users.Group), ".")
+	if err != nil {
+		h.srv.Error(w, r, err)
+		return
+	}
+
+	ctx := server.TC{
+		"Groups": items,
+	}
+	ctx.SetBreadcrumbs([][2]string{
+		{tr.Gettext("Groups")},
+	})
+
+	h.srv.RenderTemplate(w, r, 200, "/admin/group_list", ctx)
+}
+
+func (h *adminViews) groupCreate(w http.ResponseWriter, r *http.Request) {
+	tr := h.srv.Locale(r)
+	f := newGroupForm(tr, nil)
+
+	if r.Method == http.MethodPost {
+		forms.Bind(f, r)
+		if f.IsValid() {
+			g, err := f.save()
+			if err != nil {
+				h.srv.Log(r).Error("", slog.Any("err", err))
+			} else {
+				h.srv.AddFlash(w, r, "success", tr.Gettext("Group created."))
+				h.srv.Redirect(w, r, "./..", g.UID)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	}
+
+	ctx := server.TC{
+		"Form":        f,
+		"Permissions": acls.Permissions(),
+	}
+	ctx.SetBreadcrumbs([][2]string{
+		{tr.Gettext("Groups"), h.srv.AbsoluteURL(r, "/admin/groups").String()},
+		{tr.Gettext("New Group")},
+	})
+	h.srv.RenderTemplate(w, r, 200, "/admin/group", ctx)
+}
+
+func (h *adminViews) groupInfo(w http.ResponseWriter, r *http.Request) {
+	tr := h.srv.Locale(r)
+	g := r.Context().Value(ctxGroupKey{}).(*users.Group)
+	f := newGroupForm(tr, g)
+
+	if r.Method == http.MethodPost {
+		forms.Bind(f, r)
+		if f.IsValid() {
+			if _, err := f.save(); err != nil {
+				h.srv.Log(r).Error("", slog.Any("err", err))
+			} else {
+				h.srv.AddFlash(w, r, "success", tr.Gettext("Group updated."))
+				h.srv.Redirect(w, r, g.UID)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	}
+
+	item, err := newGroupItem(h.srv, r, g, "./..")
+	if err != nil {
+		h.srv.Error(w, r, err)
+		return
+	}
+
+	ctx := server.TC{
+		"Group":       item,
+		"Form":        f,
+		"Permissions": acls.Permissions(),
+	}
+	ctx.SetBreadcrumbs([][2]string{
+		{tr.Gettext("Groups"), h.srv.AbsoluteURL(r, "/admin/groups").String()},
+		{g.Name},
+	})
+	h.srv.RenderTemplate(w, r, 200, "/admin/group", ctx)
+}
+
+func (h *adminViews) groupDelete(w http.ResponseWriter, r *http.Request) {
+	tr := h.srv.Locale(r)
+	g := r.Context().Value(ctxGroupKey{}).(*users.Group)
+
+	err := h.deleteGroup(g)
+	switch {
+	case errors.Is(err, errGroupInUse):
+		h.srv.AddFlash(w, r, "error", tr.Gettext("This group still has users."))
+		h.srv.Redirect(w, r, g.UID)
+		return
+	case err != nil:
+		h.srv.Error(w, r, err)
+		return
+	}
+
+	h.srv.AddFlash(w, r, "success", tr.Gettext("Group deleted."))
+	h.srv.Redirect(w, r, "/admin/groups")
+}
+
+func (h *adminViews) inviteList(w http.ResponseWriter, r *http.Request) {
+	tr := h.srv.Locale(r)
+	f := newInviteForm(tr)
+
+	if r.Method == http.MethodPost {
+		forms.Bind(f, r)
+		if f.IsValid() {
+			i, sent, err := h.createInvite(r, f)
+			if err != nil {
+				h.srv.Log(r).Error("", slog.Any("err", err))
+			} else {
+				if sent {
+					h.srv.AddFlash(w, r, "success", tr.Gettext("Invitation sent to %s.", i.Email))
+				} else {
+					h.srv.AddFlash(w, r, "success", tr.Gettext("Invitation created."))
+				}
+				h.srv.Redirect(w, r, "/admin/invites")
+				return
+			}
+		}
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	}
+
+	ctx := server.TC{
+		"Invites":      newInviteItems(h.srv, r, r.Context().Value(ctxInviteListKey{}).([]*users.Invite), "."),
+		"Form":         f,
+		"Registration": configs.Config.Auth.Registration,
+	}
+	ctx.SetBreadcrumbs([][2]string{
+		{tr.Gettext("Invitations")},
+	})
+
+	h.srv.RenderTemplate(w, r, 200, "/admin/invite_list", ctx)
+}
+
+func (h *adminViews) inviteDelete(w http.ResponseWriter, r *http.Request) {
+	i := r.Context().Value(ctxInviteKey{}).(*users.Invite)
+	if err := i.Delete(); err != nil {
+		h.srv.Error(w, r, err)
+		return
+	}
+
+	h.srv.AddFlash(w, r, "success", h.srv.Locale(r).Gettext("Invitation deleted."))
+	h.srv.Redirect(w, r, "/admin/invites")
+}
+
+func (h *adminViews) taskList(w http.ResponseWriter, r *http.Request) {
+	tr := h.srv.Locale(r)
+
+	ctx := server.TC{
+		"Queues":    bus.Tasks().Queues(),
+		"Scheduled": bus.Tasks().Schedules(),
+	}
+	ctx.SetBreadcrumbs([][2]string{
+		{tr.Gettext("Tasks")},
+	})
+
+	h.srv.RenderTemplate(w, r, 200, "/admin/task_list", ctx)
+}
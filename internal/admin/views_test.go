@@ -9,9 +9,14 @@ import (
 	"fmt"
 	"net/url"
 	"testing"
+	"time"
 
+	"github.com/doug-martin/goqu/v9"
 	"github.com/stretchr/testify/require"
 
+	"codeberg.org/readeck/readeck/internal/acls"
+	"codeberg.org/readeck/readeck/internal/auth/users"
+	"codeberg.org/readeck/readeck/internal/bus"
 	. "codeberg.org/readeck/readeck/internal/testing" //revive:disable:dot-imports
 )
 
@@ -185,4 +190,228 @@ func TestViews(t *testing.T) {
 			},
 		)
 	})
+
+	t.Run("totp reset", func(t *testing.T) {
+		a, err := users.Authenticators.Enroll(u1.User)
+		require.NoError(t, err)
+		_, err = a.Enable()
+		require.NoError(t, err)
+
+		RunRequestSequence(t, client, "admin",
+			RequestTest{
+				Target:         "/admin/users/" + u1.User.UID,
+				ExpectStatus:   200,
+				ExpectContains: "Reset two-factor authentication",
+			},
+			RequestTest{
+				Method:         "POST",
+				Target:         fmt.Sprintf("/admin/users/%s/totp/reset", u1.User.UID),
+				Form:           url.Values{},
+				ExpectStatus:   303,
+				ExpectRedirect: "/admin/users/" + u1.User.UID,
+				Assert: func(t *testing.T, _ *Response) {
+					_, err := users.Authenticators.ForUser(u1.User)
+					require.ErrorIs(t, err, users.ErrNotFound)
+				},
+			},
+			RequestTest{
+				Target:         "/admin/users/" + u1.User.UID,
+				ExpectStatus:   200,
+				ExpectContains: "Two-factor authentication is not enabled.",
+			},
+		)
+	})
+
+	t.Run("unlock", func(t *testing.T) {
+		key := fmt.Sprintf("login:user:%d", u1.User.ID)
+		require.NoError(t, bus.Store().Set(key, fmt.Sprintf(`{"c":10,"l":%q}`, time.Now().Format(time.RFC3339)), time.Hour))
+
+		RunRequestSequence(t, client, "admin",
+			RequestTest{
+				Target:         "/admin/users/" + u1.User.UID,
+				ExpectStatus:   200,
+				ExpectContains: "Sign in is locked after too many failed attempts",
+			},
+			RequestTest{
+				Method:         "POST",
+				Target:         fmt.Sprintf("/admin/users/%s/unlock", u1.User.UID),
+				Form:           url.Values{},
+				ExpectStatus:   303,
+				ExpectRedirect: "/admin/users/" + u1.User.UID,
+				Assert: func(t *testing.T, _ *Response) {
+					require.Empty(t, bus.Store().Get(key))
+				},
+			},
+		)
+	})
+
+	t.Run("groups", func(t *testing.T) {
+		RunRequestSequence(t, client, "admin",
+			RequestTest{
+				Target:         "/admin/groups",
+				ExpectStatus:   200,
+				ExpectContains: "Groups</h1>",
+			},
+			RequestTest{
+				Target:         "/admin/groups/add",
+				ExpectStatus:   200,
+				ExpectContains: "New Group</h1>",
+			},
+			RequestTest{
+				Method: "POST",
+				Target: "/admin/groups/add",
+				Form: url.Values{
+					"name":        {"Reader Only"},
+					"permissions": {"bookmarks:read"},
+				},
+				ExpectStatus:   422,
+				ExpectContains: "invalid group name",
+			},
+			RequestTest{Target: "/admin/groups/add"},
+			RequestTest{
+				Method: "POST",
+				Target: "/admin/groups/add",
+				Form: url.Values{
+					"name":        {"reader-only"},
+					"inherit":     {"user"},
+					"permissions": {"-*:import:*\r\n-email:send  -*:export"},
+				},
+				ExpectStatus:   303,
+				ExpectRedirect: `^/admin/groups/\w+$`,
+				Assert: func(t *testing.T, _ *Response) {
+					g, err := users.Groups.GetOne(goqu.C("name").Eq("reader-only"))
+					require.NoError(t, err)
+					require.Equal(t, []string{"-*:import:*", "-email:send", "-*:export"}, []string(g.Permissions))
+				},
+			},
+			RequestTest{
+				Target:         "{{ (index .History 0).Redirect }}",
+				ExpectStatus:   200,
+				ExpectContains: "reader-only</h1>",
+			},
+			RequestTest{
+				Method: "POST",
+				Target: "{{ (index .History 0).Path }}",
+				Form: url.Values{
+					"description": {"No import"},
+					"inherit":     {"user"},
+					"permissions": {"-*:import:*"},
+				},
+				ExpectStatus:   303,
+				ExpectRedirect: `^/admin/groups/\w+$`,
+			},
+			RequestTest{
+				Target:         "{{ (index .History 0).Redirect }}",
+				ExpectStatus:   200,
+				ExpectContains: "<strong>Group updated.</strong>",
+			},
+			RequestTest{
+				Target:         "/admin/users/" + u1.User.UID,
+				ExpectStatus:   200,
+				ExpectContains: `<option value="reader-only"`,
+			},
+			RequestTest{
+				Method:         "POST",
+				Target:         "{{ (index .History 1).Path }}/delete",
+				ExpectStatus:   303,
+				ExpectRedirect: "/admin/groups",
+				Assert: func(t *testing.T, _ *Response) {
+					require.False(t, acls.IsGroup("reader-only"))
+				},
+			},
+		)
+		RunRequestSequence(t, client, "staff",
+			RequestTest{
+				Target:       "/admin/groups",
+				ExpectStatus: 403,
+			},
+		)
+	})
+
+	t.Run("invites", func(t *testing.T) {
+		RunRequestSequence(t, client, "admin",
+			RequestTest{
+				Target:         "/admin/invites",
+				ExpectStatus:   200,
+				ExpectContains: "Invitations</h1>",
+			},
+			RequestTest{
+				Method: "POST",
+				Target: "/admin/invites",
+				Form: url.Values{
+					"email": {"nope"},
+					"group": {"user"},
+					"ttl":   {"7"},
+				},
+				ExpectStatus: 422,
+			},
+			RequestTest{Target: "/admin/invites"},
+			RequestTest{
+				Method: "POST",
+				Target: "/admin/invites",
+				Form: url.Values{
+					"email": {"guest@localhost"},
+					"group": {"staff"},
+					"ttl":   {"3"},
+				},
+				ExpectStatus:   303,
+				ExpectRedirect: "/admin/invites",
+				Assert: func(t *testing.T, _ *Response) {
+					i, err := users.Invites.GetOne(goqu.C("email").Eq("guest@localhost"))
+					require.NoError(t, err)
+					require.Equal(t, "staff", i.Group)
+					require.Contains(t, app.LastEmail, "/register/"+i.UID)
+				},
+			},
+			RequestTest{
+				Target:         "/admin/invites",
+				ExpectStatus:   200,
+				ExpectContains: "Invitation sent to guest@localhost.",
+			},
+		)
+
+		i, err := users.Invites.GetOne(goqu.C("email").Eq("guest@localhost"))
+		require.NoError(t, err)
+
+		RunRequestSequence(t, client, "admin",
+			RequestTest{Target: "/admin/invites"},
+			RequestTest{
+				Method:         "POST",
+				Target:         "/admin/invites/" + i.UID + "/delete",
+				ExpectStatus:   303,
+				ExpectRedirect: "/admin/invites",
+				Assert: func(t *testing.T, _ *Response) {
+					_, err := users.Invites.GetOne(goqu.C("uid").Eq(i.UID))
+					require.ErrorIs(t, err, users.ErrNotFound)
+				},
+			},
+		)
+		RunRequestSequence(t, client, "staff",
+			RequestTest{
+				Target:       "/admin/invites",
+				ExpectStatus: 403,
+			},
+		)
+	})
+
+	t.Run("tasks", func(t *testing.T) {
+		RunRequestSequence(t, client, "admin",
+			RequestTest{
+				Target:         "/admin/tasks",
+				ExpectStatus:   200,
+				ExpectContains: "Scheduled tasks</h2>",
+			},
+			RequestTest{
+				Target:         "/admin/tasks",
+				ExpectStatus:   200,
+				ExpectContains: "<td class=\"pr-4\">interactive</td>",
+			},
+		)
+		RunRequestSequence(t, client, "staff",
+			RequestTest{
+				Target:       "/admin/tasks",
+				ExpectStatus: 403,
+			},
+		)
+	})
 }
@@ -138,6 +138,11 @@ func InitApp() {
 		}
 	}
 
+	// Load the custom groups
+	if err := users.LoadGroups(); err != nil {
+		fatal("can't load custom groups", err)
+	}
+
 	// Set the commissioned flag
 	nbUser, err := users.Users.Count()
 	if err != nil {
@@ -12,11 +12,14 @@ import (
 	"log/slog"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/cristalhq/acmd"
 	"github.com/doug-martin/goqu/v9"
 
+	"codeberg.org/readeck/readeck/configs"
 	"codeberg.org/readeck/readeck/internal/bookmarks"
+	"codeberg.org/readeck/readeck/internal/sessions"
 )
 
 func init() {
@@ -48,7 +51,22 @@ func runCleanup(_ context.Context, args []string) error {
 	}
 
 	println("⚙️ removing orphan files")
-	return removeOrphanFiles()
+	if err := removeOrphanFiles(); err != nil {
+		return err
+	}
+
+	println("⚙️ emptying old items from the trash")
+	if err := removeOldTrash(); err != nil {
+		return err
+	}
+
+	println("⚙️ removing old deletion records")
+	if err := removeOldTombstones(); err != nil {
+		return err
+	}
+
+	println("⚙️ removing expired sessions")
+	return removeOldSessions()
 }
 
 func removeLoadingBookmarks() error {
@@ -118,3 +136,49 @@ func removeOrphanFiles() error {
 
 	return nil
 }
+
+func removeOldTombstones() error {
+	n, err := bookmarks.Tombstones.Purge(time.Now().Add(-bookmarks.TombstoneRetention))
+	if err != nil {
+		return err
+	}
+
+	if n > 0 {
+		fmt.Printf("  ❌ %d record(s) removed\n", n)
+	} else {
+		println("  ⭐ no old records")
+	}
+
+	return nil
+}
+
+func removeOldTrash() error {
+	n, err := bookmarks.Trash.Empty(nil, time.Now().Add(-bookmarks.TrashRetention()))
+	if err != nil {
+		return err
+	}
+
+	if n > 0 {
+		fmt.Printf("  ❌ %d item(s) removed\n", n)
+	} else {
+		println("  ⭐ no old items")
+	}
+
+	return nil
+}
+
+func removeOldSessions() error {
+	maxAge := time.Duration(configs.Config.Server.Session.MaxAge) * time.Second
+	n, err := sessions.Records.Purge(time.Now().Add(-maxAge))
+	if err != nil {
+		return err
+	}
+
+	if n > 0 {
+		fmt.Printf("  ❌ %d session(s) removed\n", n)
+	} else {
+		println("  ⭐ no expired sessions")
+	}
+
+	return nil
+}
@@ -25,17 +25,21 @@ import (
 	"codeberg.org/readeck/readeck/docs"
 	"codeberg.org/readeck/readeck/internal/admin"
 	"codeberg.org/readeck/readeck/internal/assets"
+	"codeberg.org/readeck/readeck/internal/auth/oauth"
 	"codeberg.org/readeck/readeck/internal/auth/onboarding"
+	"codeberg.org/readeck/readeck/internal/auth/registration"
 	"codeberg.org/readeck/readeck/internal/auth/signin"
 	bookmark_routes "codeberg.org/readeck/readeck/internal/bookmarks/routes"
 	"codeberg.org/readeck/readeck/internal/bus"
 	"codeberg.org/readeck/readeck/internal/cookbook"
 	"codeberg.org/readeck/readeck/internal/dashboard"
+	"codeberg.org/readeck/readeck/internal/kosync"
 	"codeberg.org/readeck/readeck/internal/metrics"
 	"codeberg.org/readeck/readeck/internal/opds"
 	"codeberg.org/readeck/readeck/internal/profile"
 	"codeberg.org/readeck/readeck/internal/server"
 	"codeberg.org/readeck/readeck/internal/videoplayer"
+	"codeberg.org/readeck/readeck/internal/wallabag"
 )
 
 type serveFlags struct {
@@ -197,9 +201,15 @@ func InitServer(s *server.Server) error {
 	// Auth routes
 	signin.SetupRoutes(s)
 
+	// OAuth routes
+	oauth.SetupRoutes(s)
+
 	// Onboarding routes
 	onboarding.SetupRoutes(s)
 
+	// Registration routes
+	registration.SetupRoutes(s)
+
 	// Dashboard routes
 	dashboard.SetupRoutes(s)
 
@@ -211,6 +221,12 @@ func InitServer(s *server.Server) error {
 	// OPDS routes
 	opds.SetupRoutes(s)
 
+	// Wallabag API routes
+	wallabag.SetupRoutes(s)
+
+	// KOReader synchronization routes
+	kosync.SetupRoutes(s)
+
 	// User routes
 	profile.SetupRoutes(s)
 
@@ -20,6 +20,7 @@ import (
 	"github.com/hlandau/passlib"
 	"golang.org/x/term"
 
+	"codeberg.org/readeck/readeck/internal/acls"
 	"codeberg.org/readeck/readeck/internal/auth/users"
 	"codeberg.org/readeck/readeck/internal/db"
 )
@@ -129,9 +130,12 @@ func (f *userFlags) setPassword(user *users.User) (err error) {
 	return nil
 }
 
-func (f *userFlags) setGroup(user *users.User) {
+func (f *userFlags) setGroup(user *users.User) error {
 	group := user.Group
 	if f.Group != "" {
+		if f.Group != "none" && !acls.IsGroup(f.Group) {
+			return fmt.Errorf("unknown group %q", f.Group)
+		}
 		user.Group = f.Group
 	}
 	if user.Group == "" {
@@ -141,6 +145,8 @@ func (f *userFlags) setGroup(user *users.User) {
 	if group != user.Group {
 		user.SetSeed()
 	}
+
+	return nil
 }
 
 func (f *userFlags) setEmail(user *users.User) {
@@ -230,7 +236,9 @@ func runUser(_ context.Context, args []string) (err error) {
 		return err
 	}
 
-	flags.setGroup(user)
+	if err = flags.setGroup(user); err != nil {
+		return err
+	}
 	flags.setEmail(user)
 
 	msg := "created"
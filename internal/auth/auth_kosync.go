@@ -0,0 +1,104 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package auth
+
+import (
+	"crypto/md5" //nolint:gosec
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+
+	"codeberg.org/readeck/readeck/internal/auth/tokens"
+	"codeberg.org/readeck/readeck/internal/auth/users"
+)
+
+// KosyncAuthProvider handles authentication for the KOReader progress
+// synchronization protocol. KOReader sends a username and the MD5 digest
+// of a password in the "x-auth-user" and "x-auth-key" headers.
+// The password must be one of the user's API tokens. Permissions are
+// the ones of the token, like with [TokenAuthProvider].
+type KosyncAuthProvider struct {
+	TokenAuthProvider
+}
+
+// IsActive returns true when the client submits KOReader
+// authentication headers.
+func (p *KosyncAuthProvider) IsActive(r *http.Request) bool {
+	return r.Header.Get("x-auth-user") != "" && r.Header.Get("x-auth-key") != ""
+}
+
+// Authenticate performs the authentication using the "x-auth-user" and
+// "x-auth-key" headers. The key must match the MD5 digest of one of the
+// user's enabled tokens. The token itself is accepted as well.
+func (p *KosyncAuthProvider) Authenticate(w http.ResponseWriter, r *http.Request) (*http.Request, error) {
+	username := strings.TrimSpace(r.Header.Get("x-auth-user"))
+	key := strings.ToLower(strings.TrimSpace(r.Header.Get("x-auth-key")))
+
+	u, err := users.Users.GetOne(goqu.C("username").Eq(username))
+	if err != nil {
+		p.denyAccess(w)
+		return r, err
+	}
+
+	tokenList := []*tokens.Token{}
+	if err = tokens.Tokens.Query().
+		Where(
+			goqu.C("user_id").Eq(u.ID),
+			goqu.C("is_enabled").Eq(true),
+		).
+		ScanStructs(&tokenList); err != nil {
+		return r, err
+	}
+
+	var token *tokens.Token
+	for _, t := range tokenList {
+		if t.IsExpired() {
+			continue
+		}
+		encoded, err := tokens.EncodeToken(t.UID)
+		if err != nil {
+			return r, err
+		}
+		digest := md5.Sum([]byte(encoded)) //nolint:gosec
+		if subtle.ConstantTimeCompare([]byte(hex.EncodeToString(digest[:])), []byte(key)) == 1 ||
+			subtle.ConstantTimeCompare([]byte(encoded), []byte(r.Header.Get("x-auth-key"))) == 1 {
+			token = t
+			break
+		}
+	}
+
+	if token == nil {
+		p.denyAccess(w)
+		return r, errors.New("invalid credentials")
+	}
+
+	if err := token.Update(goqu.Record{
+		"last_used": time.Now().UTC(),
+	}); err != nil {
+		return r, err
+	}
+
+	return SetRequestAuthInfo(r, &Info{
+		Provider: &ProviderInfo{
+			Name:        "kosync",
+			Application: token.Application,
+			Roles:       token.Roles,
+			ID:          token.UID,
+			Restriction: token.Restriction,
+		},
+		User: u,
+	}), nil
+}
+
+func (p *KosyncAuthProvider) denyAccess(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnauthorized)
+	w.Write([]byte(`{"message":"Unauthorized"}`))
+}
@@ -0,0 +1,122 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+
+	"codeberg.org/readeck/readeck/configs"
+	"codeberg.org/readeck/readeck/internal/auth/users"
+	"codeberg.org/readeck/readeck/pkg/base58"
+)
+
+// ProxyAuthProvider handles authentication by a reverse proxy, like
+// Authelia or oauth2-proxy, that sends the authenticated user in
+// request headers. The headers are only trusted when the request
+// comes from one of the trusted proxies.
+//
+// The requests keep a session, for flash messages and preferences,
+// but the user always comes from the headers.
+type ProxyAuthProvider struct {
+	// A function that returns true when the request
+	// comes from a trusted proxy.
+	IsTrusted func(*http.Request) bool
+}
+
+// IsActive returns true when proxy authentication is enabled and
+// a trusted proxy sends the user header.
+func (p *ProxyAuthProvider) IsActive(r *http.Request) bool {
+	cfg := configs.Config.Auth.Proxy
+	return cfg.Enabled && r.Header.Get(cfg.UserHeader) != "" && p.IsTrusted(r)
+}
+
+// Authenticate returns the user sent by the proxy. The user is created
+// when it doesn't exist and auto-provisioning is enabled. When a groups
+// mapping is configured, the user's group follows the groups header.
+func (p *ProxyAuthProvider) Authenticate(w http.ResponseWriter, r *http.Request) (*http.Request, error) {
+	cfg := configs.Config.Auth.Proxy
+	username := strings.TrimSpace(r.Header.Get(cfg.UserHeader))
+	email := strings.TrimSpace(r.Header.Get(cfg.EmailHeader))
+
+	groups := []string{}
+	for _, x := range strings.Split(r.Header.Get(cfg.GroupsHeader), ",") {
+		if x = strings.TrimSpace(x); x != "" {
+			groups = append(groups, x)
+		}
+	}
+
+	group := cfg.Groups.Map(groups)
+	if group == "" {
+		group = cfg.DefaultGroup
+	}
+	if group == "" || group == "none" {
+		p.denyAccess(w)
+		return r, errors.New("no group granted")
+	}
+
+	col := goqu.C("username")
+	if strings.Contains(username, "@") {
+		col = goqu.C("email")
+	}
+
+	u, err := users.Users.GetOne(col.Eq(username))
+	switch {
+	case errors.Is(err, users.ErrNotFound) && cfg.AutoCreate:
+		if u, err = p.createUser(username, email, group); err != nil {
+			p.denyAccess(w)
+			return r, err
+		}
+	case err != nil:
+		p.denyAccess(w)
+		return r, err
+	case cfg.Groups.IsSet() && u.Group != group:
+		u.Group = group
+		if err = u.Update(goqu.Record{"group": group, "updated": time.Now()}); err != nil {
+			return r, err
+		}
+	}
+
+	return SetRequestAuthInfo(r, &Info{
+		Provider: &ProviderInfo{
+			Name: "proxy",
+		},
+		User: u,
+	}), nil
+}
+
+// createUser creates a new user, with a random password.
+func (p *ProxyAuthProvider) createUser(username, email, group string) (*users.User, error) {
+	if strings.Contains(username, "@") {
+		email = username
+		username, _, _ = strings.Cut(username, "@")
+	}
+	if email == "" {
+		return nil, errors.New("no email address")
+	}
+
+	u := &users.User{
+		Username: username,
+		Email:    email,
+		Password: base58.NewUUID() + base58.NewUUID(),
+		Group:    group,
+	}
+	if err := users.Users.Create(u); err != nil {
+		return nil, err
+	}
+
+	// Load the user with its default settings
+	return users.Users.GetOne(goqu.C("id").Eq(u.ID))
+}
+
+func (p *ProxyAuthProvider) denyAccess(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusForbidden)
+	w.Write([]byte(http.StatusText(http.StatusForbidden)))
+}
@@ -0,0 +1,152 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package auth_test
+
+import (
+	"testing"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/stretchr/testify/require"
+
+	"codeberg.org/readeck/readeck/configs"
+	"codeberg.org/readeck/readeck/internal/auth/users"
+	. "codeberg.org/readeck/readeck/internal/testing" //revive:disable:dot-imports
+)
+
+func TestProxyAuth(t *testing.T) {
+	app := NewTestApp(t)
+	defer app.Close(t)
+
+	defaults := configs.Config.Auth
+	defer func() {
+		configs.Config.Auth = defaults
+	}()
+
+	reset := func() {
+		configs.Config.Auth = defaults
+		configs.Config.Auth.Proxy.Enabled = true
+	}
+
+	request := func(remoteAddr string, headers map[string]string) *Response {
+		client := NewClient(t, app)
+		req := client.NewJSONRequest("GET", "/api/profile", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("X-Forwarded-For", "203.0.113.1")
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		return client.Request(req)
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		configs.Config.Auth = defaults
+		rsp := request("127.0.0.1:1234", map[string]string{"Remote-User": "user"})
+		rsp.AssertStatus(t, 401)
+	})
+
+	t.Run("untrusted proxy", func(t *testing.T) {
+		reset()
+		rsp := request("203.0.113.2:1234", map[string]string{"Remote-User": "user"})
+		rsp.AssertStatus(t, 401)
+	})
+
+	t.Run("existing user", func(t *testing.T) {
+		reset()
+		rsp := request("127.0.0.1:1234", map[string]string{"Remote-User": "user"})
+		rsp.AssertStatus(t, 200)
+		rsp.AssertJQ(t, ".provider.name", "proxy")
+		rsp.AssertJQ(t, ".user.username", "user")
+
+		rsp = request("127.0.0.1:1234", map[string]string{"Remote-User": "staff@localhost"})
+		rsp.AssertStatus(t, 200)
+		rsp.AssertJQ(t, ".user.username", "staff")
+
+		rsp = request("127.0.0.1:1234", map[string]string{"Remote-User": "unknown"})
+		rsp.AssertStatus(t, 403)
+
+		rsp = request("127.0.0.1:1234", map[string]string{"Remote-User": "disabled"})
+		rsp.AssertStatus(t, 403)
+	})
+
+	t.Run("custom headers", func(t *testing.T) {
+		reset()
+		configs.Config.Auth.Proxy.UserHeader = "X-Forwarded-User"
+
+		rsp := request("127.0.0.1:1234", map[string]string{"Remote-User": "user"})
+		rsp.AssertStatus(t, 401)
+
+		rsp = request("127.0.0.1:1234", map[string]string{"X-Forwarded-User": "user"})
+		rsp.AssertStatus(t, 200)
+		rsp.AssertJQ(t, ".user.username", "user")
+	})
+
+	t.Run("provisioning", func(t *testing.T) {
+		reset()
+		configs.Config.Auth.Proxy.AutoCreate = true
+
+		rsp := request("127.0.0.1:1234", map[string]string{"Remote-User": "proxy-user"})
+		rsp.AssertStatus(t, 403)
+
+		rsp = request("127.0.0.1:1234", map[string]string{
+			"Remote-User":  "proxy-user",
+			"Remote-Email": "proxy-user@localhost",
+		})
+		rsp.AssertStatus(t, 200)
+		rsp.AssertJQ(t, ".user.username", "proxy-user")
+		rsp.AssertJQ(t, ".user.email", "proxy-user@localhost")
+
+		u, err := users.Users.GetOne(goqu.C("username").Eq("proxy-user"))
+		require.NoError(t, err)
+		require.Equal(t, "user", u.Group)
+	})
+
+	t.Run("groups", func(t *testing.T) {
+		reset()
+		configs.Config.Auth.Proxy.AutoCreate = true
+		configs.Config.Auth.Proxy.Groups.Admin = []string{"admins"}
+		configs.Config.Auth.Proxy.Groups.User = []string{"readers"}
+		configs.Config.Auth.Proxy.DefaultGroup = "none"
+
+		rsp := request("127.0.0.1:1234", map[string]string{
+			"Remote-User":   "proxy-admin",
+			"Remote-Email":  "proxy-admin@localhost",
+			"Remote-Groups": "dev",
+		})
+		rsp.AssertStatus(t, 403)
+
+		rsp = request("127.0.0.1:1234", map[string]string{
+			"Remote-User":   "proxy-admin",
+			"Remote-Email":  "proxy-admin@localhost",
+			"Remote-Groups": "dev, readers,admins",
+		})
+		rsp.AssertStatus(t, 200)
+
+		u, err := users.Users.GetOne(goqu.C("username").Eq("proxy-admin"))
+		require.NoError(t, err)
+		require.Equal(t, "admin", u.Group)
+
+		// The group follows the proxy's groups
+		rsp = request("127.0.0.1:1234", map[string]string{
+			"Remote-User":   "proxy-admin",
+			"Remote-Groups": "readers",
+		})
+		rsp.AssertStatus(t, 200)
+
+		u, err = users.Users.GetOne(goqu.C("username").Eq("proxy-admin"))
+		require.NoError(t, err)
+		require.Equal(t, "user", u.Group)
+	})
+
+	t.Run("web session", func(t *testing.T) {
+		reset()
+		client := NewClient(t, app)
+		req := client.NewRequest("GET", "/profile", nil)
+		req.RemoteAddr = "127.0.0.1:1234"
+		req.Header.Set("X-Forwarded-For", "203.0.113.1")
+		req.Header.Set("Remote-User", "user")
+		rsp := client.Request(req)
+		rsp.AssertStatus(t, 200)
+	})
+}
@@ -5,6 +5,7 @@
 package auth
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/doug-martin/goqu/v9"
@@ -34,7 +35,7 @@ func (p *SessionAuthProvider) IsActive(_ *http.Request) bool {
 // the user exists.
 func (p *SessionAuthProvider) Authenticate(w http.ResponseWriter, r *http.Request) (*http.Request, error) {
 	sess := p.GetSession(r)
-	u, err := p.checkSession(sess)
+	u, err := p.checkSession(sess, r)
 	if u == nil || err != nil {
 		p.clearSession(sess, w, r)
 		return r, err
@@ -51,7 +52,7 @@ func (p *SessionAuthProvider) Authenticate(w http.ResponseWriter, r *http.Reques
 	}), nil
 }
 
-func (p *SessionAuthProvider) checkSession(sess *sessions.Session) (u *users.User, err error) {
+func (p *SessionAuthProvider) checkSession(sess *sessions.Session, r *http.Request) (u *users.User, err error) {
 	if sess.IsNew {
 		return
 	}
@@ -68,6 +69,14 @@ func (p *SessionAuthProvider) checkSession(sess *sessions.Session) (u *users.Use
 		return nil, nil
 	}
 
+	// The session must have a record. Deleting it revokes
+	// the session.
+	if _, err = sess.Record(r); errors.Is(err, sessions.ErrNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
 	return
 }
 
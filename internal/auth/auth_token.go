@@ -66,6 +66,7 @@ func (p *TokenAuthProvider) Authenticate(w http.ResponseWriter, r *http.Request)
 			Application: res.Token.Application,
 			Roles:       res.Token.Roles,
 			ID:          res.Token.UID,
+			Restriction: res.Token.Restriction,
 		},
 		User: res.User,
 	}), nil
@@ -0,0 +1,168 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+// Package ldap checks users' passwords against an LDAP directory.
+// The user's entry is searched with a service account, then the
+// password is checked with a bind as this entry. Users are linked to,
+// or provisioned as, Readeck users.
+package ldap
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+
+	"codeberg.org/readeck/readeck/configs"
+)
+
+var (
+	// ErrInvalidCredentials is returned when the directory
+	// rejects the password.
+	ErrInvalidCredentials = errors.New("invalid credentials")
+
+	// ErrUserNotFound is returned when no entry matches the username.
+	ErrUserNotFound = errors.New("user not found")
+)
+
+// Entry contains the values of a user's directory entry.
+type Entry struct {
+	DN       string
+	Username string
+	Email    string
+	Groups   []string
+}
+
+// Enabled returns true when an LDAP directory is configured.
+func Enabled() bool {
+	return configs.Config.Auth.LDAP.IsEnabled()
+}
+
+// Authenticate returns the directory entry of the given username when
+// the password is valid.
+func Authenticate(username, password string) (*Entry, error) {
+	// An empty password would be an unauthenticated bind,
+	// that most servers accept.
+	if username == "" || password == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	conn, err := dial()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close() //nolint:errcheck
+
+	entry, err := findUser(conn, username)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = conn.Bind(entry.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+// dial opens a connection to the directory and binds
+// with the service account, when there's one.
+func dial() (*ldap.Conn, error) {
+	cfg := configs.Config.Auth.LDAP
+	timeout := time.Duration(cfg.Timeout) * time.Second
+	u, err := url.Parse(cfg.URL)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig := &tls.Config{
+		ServerName:         u.Hostname(),
+		InsecureSkipVerify: cfg.Insecure, //nolint:gosec
+	}
+
+	conn, err := ldap.DialURL(cfg.URL,
+		ldap.DialWithDialer(&net.Dialer{Timeout: timeout}),
+		ldap.DialWithTLSConfig(tlsConfig),
+	)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetTimeout(timeout)
+
+	if cfg.StartTLS {
+		if err = conn.StartTLS(tlsConfig); err != nil {
+			conn.Close() //nolint:errcheck
+			return nil, err
+		}
+	}
+
+	if cfg.BindDN != "" {
+		if err = conn.Bind(cfg.BindDN, cfg.BindPassword); err != nil {
+			conn.Close() //nolint:errcheck
+			return nil, fmt.Errorf("service account bind: %w", err)
+		}
+	}
+
+	return conn, nil
+}
+
+// findUser searches the directory entry of a username.
+func findUser(conn *ldap.Conn, username string) (*Entry, error) {
+	cfg := configs.Config.Auth.LDAP
+	filter := strings.ReplaceAll(cfg.UserFilter, "%s", ldap.EscapeFilter(username))
+
+	res, err := conn.Search(ldap.NewSearchRequest(
+		cfg.BaseDN,
+		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases,
+		2, cfg.Timeout, false,
+		filter,
+		[]string{cfg.UsernameAttr, cfg.EmailAttr, cfg.GroupAttr},
+		nil,
+	))
+	switch {
+	case ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded):
+		return nil, fmt.Errorf("more than one entry for %q", username)
+	case ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject):
+		return nil, ErrUserNotFound
+	case err != nil:
+		return nil, err
+	case len(res.Entries) == 0:
+		return nil, ErrUserNotFound
+	case len(res.Entries) > 1:
+		return nil, fmt.Errorf("more than one entry for %q", username)
+	}
+
+	e := res.Entries[0]
+	return &Entry{
+		DN:       e.DN,
+		Username: e.GetAttributeValue(cfg.UsernameAttr),
+		Email:    e.GetAttributeValue(cfg.EmailAttr),
+		Groups:   groupNames(e.GetAttributeValues(cfg.GroupAttr)),
+	}, nil
+}
+
+// groupNames returns the given groups, and the name of the groups
+// that are DNs, like "admins" for "cn=admins,ou=groups,dc=example,dc=net".
+// A mapping can then use any of them.
+func groupNames(values []string) []string {
+	res := []string{}
+	for _, v := range values {
+		res = append(res, v)
+		dn, err := ldap.ParseDN(v)
+		if err != nil || len(dn.RDNs) == 0 || len(dn.RDNs[0].Attributes) == 0 {
+			continue
+		}
+		if name := dn.RDNs[0].Attributes[0].Value; name != v {
+			res = append(res, name)
+		}
+	}
+	return res
+}
@@ -0,0 +1,201 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ldap_test
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/stretchr/testify/require"
+
+	"codeberg.org/readeck/readeck/configs"
+	"codeberg.org/readeck/readeck/internal/auth/ldap"
+	"codeberg.org/readeck/readeck/internal/auth/users"
+	. "codeberg.org/readeck/readeck/internal/testing" //revive:disable:dot-imports
+)
+
+func TestLDAP(t *testing.T) {
+	app := NewTestApp(t)
+	defer app.Close(t)
+
+	srv := newTestServer(t,
+		testEntry{
+			dn:       "cn=readeck,ou=services,dc=example,dc=org",
+			password: "service",
+		},
+		testEntry{
+			dn:       "uid=alice,ou=people,dc=example,dc=org",
+			password: "alice-secret",
+			attrs: map[string][]string{
+				"objectClass": {"person", "inetOrgPerson"},
+				"uid":         {"alice"},
+				"mail":        {"alice@example.org"},
+				"memberOf":    {"cn=admins,ou=groups,dc=example,dc=org"},
+			},
+		},
+		testEntry{
+			dn:       "uid=bob,ou=people,dc=example,dc=org",
+			password: "bob-secret",
+			attrs: map[string][]string{
+				"objectClass": {"person", "inetOrgPerson"},
+				"uid":         {"bob"},
+				"mail":        {"bob@example.org"},
+			},
+		},
+		testEntry{
+			dn:       "uid=user,ou=people,dc=example,dc=org",
+			password: "user-secret",
+			attrs: map[string][]string{
+				"objectClass": {"person"},
+				"uid":         {"user"},
+				"mail":        {"user@example.org"},
+			},
+		},
+	)
+	defer srv.Close()
+
+	defaults := configs.Config.Auth
+	defer func() {
+		configs.Config.Auth = defaults
+	}()
+
+	reset := func() {
+		configs.Config.Auth = defaults
+		configs.Config.Auth.LDAP.URL = srv.URL()
+		configs.Config.Auth.LDAP.BindDN = "cn=readeck,ou=services,dc=example,dc=org"
+		configs.Config.Auth.LDAP.BindPassword = "service"
+		configs.Config.Auth.LDAP.BaseDN = "dc=example,dc=org"
+	}
+
+	auth := func(username, password string) *Response {
+		client := NewClient(t, app)
+		return client.RequestJSON("POST", "/api/auth", map[string]string{
+			"application": "test",
+			"username":    username,
+			"password":    password,
+		})
+	}
+
+	t.Run("authenticate", func(t *testing.T) {
+		reset()
+		entry, err := ldap.Authenticate("alice", "alice-secret")
+		require.NoError(t, err)
+		require.Equal(t, &ldap.Entry{
+			DN:       "uid=alice,ou=people,dc=example,dc=org",
+			Username: "alice",
+			Email:    "alice@example.org",
+			Groups:   []string{"cn=admins,ou=groups,dc=example,dc=org", "admins"},
+		}, entry)
+
+		_, err = ldap.Authenticate("alice", "nope")
+		require.ErrorIs(t, err, ldap.ErrInvalidCredentials)
+
+		_, err = ldap.Authenticate("alice", "")
+		require.ErrorIs(t, err, ldap.ErrInvalidCredentials)
+
+		_, err = ldap.Authenticate("carol", "carol-secret")
+		require.ErrorIs(t, err, ldap.ErrUserNotFound)
+
+		// The username can't change the filter
+		_, err = ldap.Authenticate("*", "alice-secret")
+		require.ErrorIs(t, err, ldap.ErrUserNotFound)
+
+		configs.Config.Auth.LDAP.BindPassword = "nope"
+		_, err = ldap.Authenticate("alice", "alice-secret")
+		require.ErrorContains(t, err, "service account bind")
+	})
+
+	t.Run("new user", func(t *testing.T) {
+		reset()
+		configs.Config.Auth.LDAP.Groups.Admin = []string{"admins"}
+
+		auth("alice", "nope").AssertStatus(t, 403)
+		_, err := users.Users.GetOne(goqu.C("username").Eq("alice"))
+		require.ErrorIs(t, err, users.ErrNotFound)
+
+		auth("alice", "alice-secret").AssertStatus(t, 201)
+		u, err := users.Users.GetOne(goqu.C("username").Eq("alice"))
+		require.NoError(t, err)
+		require.Equal(t, "alice@example.org", u.Email)
+		require.Equal(t, "admin", u.Group)
+		require.False(t, u.CheckPassword("alice-secret"))
+
+		_, linked, err := users.Identities.GetUser("ldap", "alice")
+		require.NoError(t, err)
+		require.Equal(t, u.ID, linked.ID)
+
+		// The group follows the mapping
+		configs.Config.Auth.LDAP.Groups.Admin = []string{}
+		configs.Config.Auth.LDAP.Groups.Staff = []string{"staff"}
+		auth("alice", "alice-secret").AssertStatus(t, 201)
+		u, err = users.Users.GetOne(goqu.C("id").Eq(u.ID))
+		require.NoError(t, err)
+		require.Equal(t, "user", u.Group)
+	})
+
+	t.Run("no auto create", func(t *testing.T) {
+		reset()
+		configs.Config.Auth.LDAP.AutoCreate = false
+		auth("bob", "bob-secret").AssertStatus(t, 403)
+
+		count, err := users.Users.Query().Where(goqu.C("username").Eq("bob")).Count()
+		require.NoError(t, err)
+		require.Equal(t, int64(0), count)
+	})
+
+	t.Run("no group", func(t *testing.T) {
+		reset()
+		configs.Config.Auth.LDAP.DefaultGroup = "none"
+		auth("bob", "bob-secret").AssertStatus(t, 403)
+	})
+
+	t.Run("existing user", func(t *testing.T) {
+		reset()
+		user := app.Users["user"]
+
+		// The directory password replaces the local one.
+		auth("user", user.Password()).AssertStatus(t, 403)
+		auth("user", "user-secret").AssertStatus(t, 201)
+
+		u, err := users.Users.GetOne(goqu.C("id").Eq(user.User.ID))
+		require.NoError(t, err)
+		require.Equal(t, "user@example.org", u.Email)
+		require.Equal(t, "user", u.Group)
+	})
+
+	t.Run("local fallback", func(t *testing.T) {
+		reset()
+		staff := app.Users["staff"]
+		auth("staff", staff.Password()).AssertStatus(t, 403)
+
+		configs.Config.Auth.LDAP.LocalFallback = true
+		auth("staff", staff.Password()).AssertStatus(t, 201)
+		auth("staff@localhost", staff.Password()).AssertStatus(t, 201)
+
+		// A directory user can't use its local password.
+		auth("alice", "nope").AssertStatus(t, 403)
+
+		// All the users fall back when the directory is unavailable.
+		configs.Config.Auth.LDAP.URL = "ldap://127.0.0.1:1"
+		auth("staff", staff.Password()).AssertStatus(t, 201)
+	})
+
+	t.Run("login form", func(t *testing.T) {
+		reset()
+		client := NewClient(t, app)
+		client.Get("/login")
+		rsp := client.PostForm("/login", url.Values{
+			"username": {"bob"},
+			"password": {"bob-secret"},
+		})
+		rsp.AssertStatus(t, 303)
+		rsp.AssertRedirect(t, "^/$")
+
+		rsp = client.Get("/api/profile")
+		rsp.AssertStatus(t, 200)
+		rsp.AssertJQ(t, ".user.username", "bob")
+	})
+}
@@ -0,0 +1,233 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ldap_test
+
+import (
+	"io"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+)
+
+// LDAP operations and result codes used by the test server.
+const (
+	opBindRequest      = 0
+	opBindResponse     = 1
+	opUnbindRequest    = 2
+	opSearchRequest    = 3
+	opSearchResultItem = 4
+	opSearchResultDone = 5
+
+	resultSuccess            = 0
+	resultSizeLimitExceeded  = 4
+	resultInvalidCredentials = 49
+	resultInsufficientAccess = 50
+	resultUnwillingToPerform = 53
+)
+
+// testEntry is an entry of the test directory.
+type testEntry struct {
+	dn       string
+	password string
+	attrs    map[string][]string
+}
+
+// testServer is a minimal in-process LDAP server. It supports
+// simple binds and searches with "and", "or", "not", equality and
+// presence filters, on a flat list of entries. Searches are only
+// allowed after a successful bind.
+type testServer struct {
+	ln      net.Listener
+	wg      sync.WaitGroup
+	entries []testEntry
+}
+
+func newTestServer(t *testing.T, entries ...testEntry) *testServer {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &testServer{ln: ln, entries: entries}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			s.wg.Add(1)
+			go func() {
+				defer s.wg.Done()
+				s.serve(conn)
+			}()
+		}
+	}()
+
+	return s
+}
+
+// URL returns the server's URL.
+func (s *testServer) URL() string {
+	return "ldap://" + s.ln.Addr().String()
+}
+
+// Close stops the server.
+func (s *testServer) Close() {
+	s.ln.Close() //nolint:errcheck
+	s.wg.Wait()
+}
+
+func (s *testServer) serve(conn net.Conn) {
+	defer conn.Close() //nolint:errcheck
+
+	bound := false
+	for {
+		p, err := ber.ReadPacket(conn)
+		if err != nil {
+			return
+		}
+		if len(p.Children) < 2 {
+			return
+		}
+
+		id := p.Children[0].Value.(int64)
+		op := p.Children[1]
+
+		switch op.Tag {
+		case opBindRequest:
+			dn := op.Children[1].Value.(string)
+			password := op.Children[2].Data.String()
+			code := resultInvalidCredentials
+			if e := s.find(dn); e != nil && password != "" && e.password == password {
+				code = resultSuccess
+			}
+			bound = code == resultSuccess
+			s.write(conn, id, result(opBindResponse, code))
+		case opUnbindRequest:
+			return
+		case opSearchRequest:
+			if !bound {
+				s.write(conn, id, result(opSearchResultDone, resultInsufficientAccess))
+				continue
+			}
+			s.search(conn, id, op)
+		default:
+			s.write(conn, id, result(op.Tag+1, resultUnwillingToPerform))
+		}
+	}
+}
+
+func (s *testServer) search(w io.Writer, id int64, op *ber.Packet) {
+	base := strings.ToLower(op.Children[0].Value.(string))
+	sizeLimit := op.Children[3].Value.(int64)
+	filter := op.Children[6]
+	attrs := []string{}
+	for _, a := range op.Children[7].Children {
+		attrs = append(attrs, strings.ToLower(a.Value.(string)))
+	}
+
+	count := int64(0)
+	for _, e := range s.entries {
+		if !strings.HasSuffix(strings.ToLower(e.dn), base) || !match(e, filter) {
+			continue
+		}
+		if sizeLimit > 0 && count == sizeLimit {
+			s.write(w, id, result(opSearchResultDone, resultSizeLimitExceeded))
+			return
+		}
+		count++
+
+		item := ber.Encode(ber.ClassApplication, ber.TypeConstructed, opSearchResultItem, nil, "")
+		item.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, e.dn, ""))
+		list := ber.NewSequence("")
+		for name, values := range e.attrs {
+			if len(attrs) > 0 && !slices.Contains(attrs, strings.ToLower(name)) {
+				continue
+			}
+			attr := ber.NewSequence("")
+			attr.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, ""))
+			set := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "")
+			for _, v := range values {
+				set.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, v, ""))
+			}
+			attr.AppendChild(set)
+			list.AppendChild(attr)
+		}
+		item.AppendChild(list)
+		s.write(w, id, item)
+	}
+
+	s.write(w, id, result(opSearchResultDone, resultSuccess))
+}
+
+func (s *testServer) find(dn string) *testEntry {
+	for i, e := range s.entries {
+		if strings.EqualFold(e.dn, dn) {
+			return &s.entries[i]
+		}
+	}
+	return nil
+}
+
+func (s *testServer) write(w io.Writer, id int64, op *ber.Packet) {
+	p := ber.NewSequence("")
+	p.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, id, ""))
+	p.AppendChild(op)
+	w.Write(p.Bytes()) //nolint:errcheck
+}
+
+// result returns an LDAPResult operation.
+func result(tag ber.Tag, code int) *ber.Packet {
+	p := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "")
+	p.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, int64(code), ""))
+	p.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", ""))
+	p.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", ""))
+	return p
+}
+
+// match returns true when an entry matches a search filter.
+func match(e testEntry, f *ber.Packet) bool {
+	switch f.Tag {
+	case 0: // and
+		for _, c := range f.Children {
+			if !match(e, c) {
+				return false
+			}
+		}
+		return true
+	case 1: // or
+		for _, c := range f.Children {
+			if match(e, c) {
+				return true
+			}
+		}
+		return false
+	case 2: // not
+		return !match(e, f.Children[0])
+	case 3: // equality
+		values := attrValues(e, f.Children[0].Value.(string))
+		return slices.ContainsFunc(values, func(v string) bool {
+			return strings.EqualFold(v, f.Children[1].Value.(string))
+		})
+	case 7: // present
+		return len(attrValues(e, f.Data.String())) > 0
+	}
+	return false
+}
+
+func attrValues(e testEntry, name string) []string {
+	for k, v := range e.attrs {
+		if strings.EqualFold(k, name) {
+			return v
+		}
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ldap
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+
+	"codeberg.org/readeck/readeck/configs"
+	"codeberg.org/readeck/readeck/internal/auth/users"
+	"codeberg.org/readeck/readeck/pkg/base58"
+)
+
+// provider is the identity provider name of directory users.
+const provider = "ldap"
+
+var (
+	// ErrNoAccount is returned when no user matches the entry
+	// and users can't be created.
+	ErrNoAccount = errors.New("no matching account")
+
+	// ErrNoGroup is returned when the entry's groups don't
+	// grant any group.
+	ErrNoGroup = errors.New("no group granted")
+
+	rxInvalidUsername = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+)
+
+// SigninUser returns the Readeck user of a directory entry.
+//
+// A known entry returns its linked user. Otherwise, the entry is
+// linked to the user with the same username, or to a new user when
+// enabled. The user's email address follows the entry's and, when a
+// groups mapping is configured, so does its group.
+func SigninUser(entry *Entry) (*users.User, error) {
+	cfg := configs.Config.Auth.LDAP
+	if entry.Username == "" {
+		return nil, errors.New("no username attribute")
+	}
+
+	group, mapped := mapGroup(entry)
+	if group == "" {
+		return nil, ErrNoGroup
+	}
+
+	identity, user, err := users.Identities.GetUser(provider, entry.Username)
+	switch {
+	case errors.Is(err, users.ErrNotFound):
+		user, err = users.Users.GetOne(goqu.C("username").Eq(entry.Username))
+		if err != nil && !errors.Is(err, users.ErrNotFound) {
+			return nil, err
+		}
+	case err != nil:
+		return nil, err
+	}
+
+	if user == nil {
+		if !cfg.AutoCreate {
+			return nil, ErrNoAccount
+		}
+		if user, err = createUser(entry, group); err != nil {
+			return nil, err
+		}
+	}
+
+	if identity == nil {
+		if identity, err = users.Identities.Link(user, provider, entry.Username); err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	if err = identity.Update(goqu.Record{"last_used": now}); err != nil {
+		return nil, err
+	}
+
+	update := goqu.Record{}
+	if entry.Email != "" && entry.Email != user.Email {
+		user.Email = entry.Email
+		update["email"] = user.Email
+	}
+	if mapped && user.Group != group {
+		user.Group = group
+		update["group"] = user.Group
+	}
+	if len(update) > 0 {
+		update["updated"] = now
+		if err = user.Update(update); err != nil {
+			return nil, err
+		}
+	}
+
+	return user, nil
+}
+
+// mapGroup returns the group granted by the entry. It returns the
+// group with the most permissions listing one of the entry's groups
+// or the default group. mapped is true when a mapping is configured,
+// in which case the group must be applied to existing users.
+func mapGroup(entry *Entry) (group string, mapped bool) {
+	cfg := configs.Config.Auth.LDAP
+	if group = cfg.Groups.Map(entry.Groups); group != "" {
+		return group, true
+	}
+
+	group = cfg.DefaultGroup
+	if group == "none" {
+		group = ""
+	}
+	return group, cfg.Groups.IsSet()
+}
+
+// createUser creates a new user from a directory entry,
+// with a random password.
+func createUser(entry *Entry, group string) (*users.User, error) {
+	if entry.Email == "" {
+		return nil, errors.New("no email address")
+	}
+
+	// Find a free username
+	base := strings.Trim(rxInvalidUsername.ReplaceAllString(entry.Username, "-"), "-")
+	if base == "" {
+		base = "user"
+	}
+
+	username := base
+	for i := 2; ; i++ {
+		count, err := users.Users.Query().Where(goqu.C("username").Eq(username)).Count()
+		if err != nil {
+			return nil, err
+		}
+		if count == 0 {
+			break
+		}
+		username = base + "-" + strconv.Itoa(i)
+	}
+
+	u := &users.User{
+		Username: username,
+		Email:    entry.Email,
+		Password: base58.NewUUID() + base58.NewUUID(),
+		Group:    group,
+	}
+	if err := users.Users.Create(u); err != nil {
+		return nil, err
+	}
+
+	// Load the user with its default settings
+	return users.Users.GetOne(goqu.C("id").Eq(u.ID))
+}
@@ -0,0 +1,357 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package oauth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/go-chi/chi/v5"
+
+	"codeberg.org/readeck/readeck/internal/auth/users"
+	"codeberg.org/readeck/readeck/internal/db/types"
+	"codeberg.org/readeck/readeck/internal/server"
+	"codeberg.org/readeck/readeck/pkg/forms"
+)
+
+const (
+	grantAuthorizationCode = "authorization_code"
+	grantDeviceCode        = "urn:ietf:params:oauth:grant-type:device_code"
+	grantRefreshToken      = "refresh_token"
+)
+
+// oauthAPI serves the non authenticated OAuth endpoints, used by the
+// clients.
+type oauthAPI struct {
+	chi.Router
+	srv *server.Server
+}
+
+func newOAuthAPI(s *server.Server) *oauthAPI {
+	r := chi.NewRouter()
+	api := &oauthAPI{r, s}
+
+	r.Post("/client", api.clientCreate)
+	r.Post("/device", api.deviceAuthorize)
+	r.Post("/token", api.token)
+
+	return api
+}
+
+// sendError sends an OAuth error. An unknown client gets
+// a 401 status, as required by RFC 6749.
+func (api *oauthAPI) sendError(w http.ResponseWriter, r *http.Request, err error) {
+	var oErr *Error
+	if !errors.As(err, &oErr) {
+		api.srv.Error(w, r, err)
+		return
+	}
+
+	status := http.StatusBadRequest
+	if oErr.Code == "invalid_client" {
+		status = http.StatusUnauthorized
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	api.srv.Render(w, r, status, oErr)
+}
+
+// getClient returns the client with the given ID.
+func getClient(clientID string) (*Client, error) {
+	if clientID == "" {
+		return nil, newError("invalid_client", "no client_id")
+	}
+
+	c, err := Clients.GetOne(goqu.C("uid").Eq(clientID))
+	if errors.Is(err, ErrNotFound) {
+		return nil, newError("invalid_client", "unknown client")
+	}
+	return c, err
+}
+
+type clientForm struct {
+	*forms.Form
+}
+
+func newClientForm(tr forms.Translator) *clientForm {
+	return &clientForm{forms.Must(
+		forms.WithTranslator(context.Background(), tr),
+		forms.NewTextField("client_name", forms.Trim, forms.Required, maxLength(128)),
+		forms.NewTextField("client_uri", forms.Trim, forms.Skip, forms.IsURL("https", "http")),
+		forms.NewTextListField("redirect_uris", forms.Trim),
+		forms.NewTextField("software_id", forms.Trim, maxLength(128)),
+		forms.NewTextField("software_version", forms.Trim, maxLength(64)),
+		forms.NewTextField("token_endpoint_auth_method", forms.Trim),
+		forms.NewTextListField("grant_types", forms.Trim),
+		forms.NewTextListField("response_types", forms.Trim),
+	)}
+}
+
+func maxLength(n int) forms.ValueValidator[string] {
+	return forms.TypedValidator(func(v string) bool {
+		return len(v) <= n
+	}, errors.New("text is too long"))
+}
+
+// Validate checks the redirection URIs and the requested client
+// metadata that Readeck doesn't support.
+func (f *clientForm) Validate() {
+	for _, uri := range f.Get("redirect_uris").(forms.TypedField[[]string]).V() {
+		if err := checkRedirectURI(uri); err != nil {
+			f.Get("redirect_uris").AddErrors(err)
+		}
+	}
+
+	if v := f.Get("token_endpoint_auth_method").String(); v != "" && v != "none" {
+		f.Get("token_endpoint_auth_method").AddErrors(errors.New("only public clients are supported"))
+	}
+	for _, x := range f.Get("grant_types").(forms.TypedField[[]string]).V() {
+		if x != grantAuthorizationCode && x != grantDeviceCode && x != grantRefreshToken {
+			f.Get("grant_types").AddErrors(errors.New("unsupported grant type: " + x))
+		}
+	}
+	for _, x := range f.Get("response_types").(forms.TypedField[[]string]).V() {
+		if x != "code" {
+			f.Get("response_types").AddErrors(errors.New("unsupported response type: " + x))
+		}
+	}
+}
+
+type clientInfo struct {
+	ClientID                string   `json:"client_id"`
+	ClientIDIssuedAt        int64    `json:"client_id_issued_at"`
+	ClientName              string   `json:"client_name"`
+	ClientURI               string   `json:"client_uri,omitempty"`
+	RedirectURIs            []string `json:"redirect_uris"`
+	SoftwareID              string   `json:"software_id,omitempty"`
+	SoftwareVersion         string   `json:"software_version,omitempty"`
+	TokenEndpointAuthMethod string   `json:"token_endpoint_auth_method"`
+	GrantTypes              []string `json:"grant_types"`
+	ResponseTypes           []string `json:"response_types"`
+}
+
+// clientCreate registers a new client, as defined by RFC 7591.
+func (api *oauthAPI) clientCreate(w http.ResponseWriter, r *http.Request) {
+	f := newClientForm(api.srv.Locale(r))
+	forms.Bind(f, r)
+	if !f.IsValid() {
+		msg := []string{}
+		for name, field := range f.Fields() {
+			for _, err := range field.Errors() {
+				msg = append(msg, name+": "+err.Error())
+			}
+		}
+		for _, err := range f.Errors() {
+			msg = append(msg, err.Error())
+		}
+		api.sendError(w, r, newError("invalid_client_metadata", strings.Join(msg, ", ")))
+		return
+	}
+
+	c := &Client{
+		Name:            f.Get("client_name").String(),
+		Website:         f.Get("client_uri").String(),
+		RedirectURIs:    types.Strings(f.Get("redirect_uris").(forms.TypedField[[]string]).V()),
+		SoftwareID:      f.Get("software_id").String(),
+		SoftwareVersion: f.Get("software_version").String(),
+	}
+	if err := Clients.Create(c); err != nil {
+		api.srv.Error(w, r, err)
+		return
+	}
+
+	grants := []string{grantDeviceCode, grantRefreshToken}
+	responseTypes := []string{}
+	if len(c.RedirectURIs) > 0 {
+		grants = append([]string{grantAuthorizationCode}, grants...)
+		responseTypes = append(responseTypes, "code")
+	}
+
+	api.srv.Render(w, r, http.StatusCreated, clientInfo{
+		ClientID:                c.UID,
+		ClientIDIssuedAt:        c.Created.Unix(),
+		ClientName:              c.Name,
+		ClientURI:               c.Website,
+		RedirectURIs:            c.RedirectURIs,
+		SoftwareID:              c.SoftwareID,
+		SoftwareVersion:         c.SoftwareVersion,
+		TokenEndpointAuthMethod: "none",
+		GrantTypes:              grants,
+		ResponseTypes:           responseTypes,
+	})
+}
+
+type deviceForm struct {
+	*forms.Form
+}
+
+func newDeviceForm(tr forms.Translator) *deviceForm {
+	return &deviceForm{forms.Must(
+		forms.WithTranslator(context.Background(), tr),
+		forms.NewTextField("client_id", forms.Trim),
+		forms.NewTextField("scope", forms.Trim),
+	)}
+}
+
+type deviceResponse struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                int    `json:"interval"`
+}
+
+// deviceAuthorize starts a device authorization, as defined by RFC 8628.
+// The device shows the user code, that the user enters on the
+// verification page, while it polls the token endpoint.
+func (api *oauthAPI) deviceAuthorize(w http.ResponseWriter, r *http.Request) {
+	tr := api.srv.Locale(r)
+	f := newDeviceForm(tr)
+	forms.Bind(f, r)
+	if !f.IsValid() {
+		api.sendError(w, r, newError("invalid_request", ""))
+		return
+	}
+
+	client, err := getClient(f.Get("client_id").String())
+	if err != nil {
+		api.sendError(w, r, err)
+		return
+	}
+
+	scopes := parseScope(f.Get("scope").String())
+	if _, err = scopeChoices(tr, nil, scopes); err != nil {
+		api.sendError(w, r, err)
+		return
+	}
+
+	code, da, err := newDeviceAuthorization(client, scopes)
+	if err != nil {
+		api.srv.Error(w, r, err)
+		return
+	}
+
+	verification := api.srv.AbsoluteURL(r, "/oauth/device")
+	complete := *verification
+	complete.RawQuery = "user_code=" + da.UserCode
+
+	w.Header().Set("Cache-Control", "no-store")
+	api.srv.Render(w, r, http.StatusOK, deviceResponse{
+		DeviceCode:              code,
+		UserCode:                da.UserCode,
+		VerificationURI:         verification.String(),
+		VerificationURIComplete: complete.String(),
+		ExpiresIn:               int(deviceLifetime.Seconds()),
+		Interval:                int(devicePollInterval.Seconds()),
+	})
+}
+
+type tokenForm struct {
+	*forms.Form
+}
+
+func newTokenForm(tr forms.Translator) *tokenForm {
+	return &tokenForm{forms.Must(
+		forms.WithTranslator(context.Background(), tr),
+		forms.NewTextField("grant_type", forms.Trim, forms.Required),
+		forms.NewTextField("client_id", forms.Trim),
+		forms.NewTextField("code", forms.Trim),
+		forms.NewTextField("redirect_uri", forms.Trim),
+		forms.NewTextField("code_verifier", forms.Trim),
+		forms.NewTextField("device_code", forms.Trim),
+		forms.NewTextField("refresh_token", forms.Trim),
+	)}
+}
+
+// token is the token endpoint. It issues API tokens for authorization
+// codes and approved devices, and renews them with refresh tokens.
+func (api *oauthAPI) token(w http.ResponseWriter, r *http.Request) {
+	f := newTokenForm(api.srv.Locale(r))
+	forms.Bind(f, r)
+	if !f.IsValid() {
+		api.sendError(w, r, newError("invalid_request", "missing grant_type"))
+		return
+	}
+
+	client, err := getClient(f.Get("client_id").String())
+	if err != nil {
+		api.sendError(w, r, err)
+		return
+	}
+
+	var res *tokenResponse
+	switch f.Get("grant_type").String() {
+	case grantAuthorizationCode:
+		res, err = api.grantCode(client, f)
+	case grantDeviceCode:
+		res, err = api.grantDevice(client, f)
+	case grantRefreshToken:
+		res, err = refreshToken(client, f.Get("refresh_token").String())
+	default:
+		err = newError("unsupported_grant_type", "")
+	}
+
+	if err != nil {
+		api.sendError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	api.srv.Render(w, r, http.StatusOK, res)
+}
+
+// grantCode issues a token for an authorization code.
+func (api *oauthAPI) grantCode(client *Client, f *tokenForm) (*tokenResponse, error) {
+	ac, err := consumeAuthorizationCode(f.Get("code").String())
+	if err != nil {
+		return nil, err
+	}
+	if ac.ClientID != client.ID || ac.RedirectURI != f.Get("redirect_uri").String() {
+		return nil, errExpiredCode
+	}
+	if err = ac.checkVerifier(f.Get("code_verifier").String()); err != nil {
+		return nil, err
+	}
+
+	user, err := grantUser(ac.UserID, ac.Seed)
+	if err != nil {
+		return nil, err
+	}
+	return issueToken(client, user, ac.Scopes)
+}
+
+// grantDevice issues a token once the user approved the device.
+func (api *oauthAPI) grantDevice(client *Client, f *tokenForm) (*tokenResponse, error) {
+	da, err := getDeviceAuthorization(f.Get("device_code").String())
+	if err != nil {
+		return nil, err
+	}
+	if da.ClientID != client.ID {
+		return nil, newError("invalid_grant", "invalid device code")
+	}
+	if err = da.poll(); err != nil {
+		return nil, err
+	}
+
+	user, err := grantUser(da.UserID, da.Seed)
+	if err != nil {
+		return nil, err
+	}
+	return issueToken(client, user, da.Scopes)
+}
+
+// grantUser returns the user that gave an authorization. The grant
+// isn't valid anymore once the user changed its password.
+func grantUser(id, seed int) (*users.User, error) {
+	user, err := users.Users.GetOne(goqu.C("id").Eq(id))
+	if errors.Is(err, users.ErrNotFound) || err == nil && user.Seed != seed {
+		return nil, newError("invalid_grant", "invalid user")
+	}
+	return user, err
+}
@@ -0,0 +1,163 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package oauth
+
+import (
+	"errors"
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+
+	"codeberg.org/readeck/readeck/internal/db"
+	"codeberg.org/readeck/readeck/internal/db/types"
+	"codeberg.org/readeck/readeck/pkg/base58"
+)
+
+const (
+	// ClientTableName is the OAuth client table name in database.
+	ClientTableName = "oauth_client"
+)
+
+var (
+	// Clients is the OAuth client manager.
+	Clients = ClientManager{}
+
+	// ErrNotFound is returned when a client record was not found.
+	ErrNotFound = errors.New("not found")
+)
+
+// Client is an application registered to obtain API tokens on
+// behalf of users. Clients are public: they don't have a secret and
+// must use PKCE with the authorization code grant.
+type Client struct {
+	ID              int           `db:"id" goqu:"skipinsert,skipupdate"`
+	UID             string        `db:"uid"`
+	Created         time.Time     `db:"created" goqu:"skipupdate"`
+	Name            string        `db:"name"`
+	Website         string        `db:"website"`
+	RedirectURIs    types.Strings `db:"redirect_uris"`
+	SoftwareID      string        `db:"software_id"`
+	SoftwareVersion string        `db:"software_version"`
+}
+
+// ClientManager is a query helper for client entries.
+type ClientManager struct{}
+
+// Query returns a prepared goqu SelectDataset that can be extended later.
+func (m *ClientManager) Query() *goqu.SelectDataset {
+	return db.Q().From(goqu.T(ClientTableName).As("c")).Prepared(true)
+}
+
+// GetOne executes the a select query and returns the first result or an error
+// when there's no result.
+func (m *ClientManager) GetOne(expressions ...goqu.Expression) (*Client, error) {
+	var c Client
+	found, err := m.Query().Where(expressions...).ScanStruct(&c)
+
+	switch {
+	case err != nil:
+		return nil, err
+	case !found:
+		return nil, ErrNotFound
+	}
+
+	return &c, nil
+}
+
+// Create inserts a new client in the database.
+func (m *ClientManager) Create(c *Client) error {
+	if strings.TrimSpace(c.Name) == "" {
+		return errors.New("no client name")
+	}
+	if c.RedirectURIs == nil {
+		c.RedirectURIs = types.Strings{}
+	}
+
+	c.Created = time.Now()
+	c.UID = base58.NewUUID()
+
+	ds := db.Q().Insert(ClientTableName).
+		Rows(c).
+		Prepared(true)
+
+	id, err := db.InsertWithID(ds, "id")
+	if err != nil {
+		return err
+	}
+
+	c.ID = id
+	return nil
+}
+
+// Delete removes a client from the database, with all its tokens.
+func (c *Client) Delete() error {
+	_, err := db.Q().Delete(ClientTableName).Prepared(true).
+		Where(goqu.C("id").Eq(c.ID)).
+		Executor().Exec()
+
+	return err
+}
+
+// HasRedirectURI returns true when the given URI is one of the client's
+// redirection URIs. The port of a loopback redirection URI can change,
+// so native applications can listen on any port.
+func (c *Client) HasRedirectURI(uri string) bool {
+	if slices.Contains(c.RedirectURIs, uri) {
+		return true
+	}
+
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "http" || !isLoopback(u.Hostname()) {
+		return false
+	}
+
+	return slices.ContainsFunc(c.RedirectURIs, func(x string) bool {
+		r, err := url.Parse(x)
+		return err == nil &&
+			r.Scheme == u.Scheme &&
+			r.Hostname() == u.Hostname() &&
+			r.Path == u.Path &&
+			r.RawQuery == u.RawQuery
+	})
+}
+
+// checkRedirectURI checks a redirection URI given during a registration.
+// It must be an absolute URI without a fragment. Plain HTTP is only
+// allowed on a loopback interface, while native applications can use
+// their own scheme.
+func checkRedirectURI(uri string) error {
+	u, err := url.Parse(uri)
+	switch {
+	case err != nil:
+		return err
+	case !u.IsAbs():
+		return errors.New("redirect URI must be absolute")
+	case u.Fragment != "":
+		return errors.New("redirect URI can't have a fragment")
+	case u.Scheme == "https":
+		if u.Host == "" {
+			return errors.New("redirect URI has no host")
+		}
+	case u.Scheme == "http":
+		if !isLoopback(u.Hostname()) {
+			return errors.New("HTTP redirect URI must be on a loopback interface")
+		}
+	case u.Scheme == "javascript", u.Scheme == "data", u.Scheme == "file":
+		return errors.New("invalid redirect URI scheme")
+	}
+	return nil
+}
+
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
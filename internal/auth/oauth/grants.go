@@ -0,0 +1,428 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package oauth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+
+	"codeberg.org/readeck/readeck/internal/acls"
+	"codeberg.org/readeck/readeck/internal/auth/tokens"
+	"codeberg.org/readeck/readeck/internal/auth/users"
+	"codeberg.org/readeck/readeck/internal/bus"
+	"codeberg.org/readeck/readeck/pkg/base58"
+	"codeberg.org/readeck/readeck/pkg/forms"
+)
+
+const (
+	// codeLifetime is the lifetime of an authorization code.
+	codeLifetime = 5 * time.Minute
+
+	// deviceLifetime is the lifetime of a device authorization.
+	deviceLifetime = 10 * time.Minute
+
+	// devicePollInterval is the minimum interval between two
+	// token requests of a device.
+	devicePollInterval = 5 * time.Second
+
+	// accessTokenLifetime is the lifetime of an access token. It's
+	// then renewed with the refresh token.
+	accessTokenLifetime = time.Hour
+
+	// userCodeAlphabet contains the letters of a device's user code.
+	// There are no vowels so it can't make a word.
+	userCodeAlphabet = "BCDFGHJKLMNPQRSTVWXZ"
+
+	deviceStatusPending  = "pending"
+	deviceStatusApproved = "approved"
+	deviceStatusDenied   = "denied"
+)
+
+// Error is an OAuth error, as returned by the token endpoint or
+// in an authorization response.
+type Error struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+}
+
+func (e *Error) Error() string {
+	if e.Description == "" {
+		return e.Code
+	}
+	return e.Code + ": " + e.Description
+}
+
+func newError(code, description string) *Error {
+	return &Error{code, description}
+}
+
+var (
+	errExpiredCode     = newError("invalid_grant", "invalid or expired code")
+	errInvalidVerifier = newError("invalid_grant", "invalid code verifier")
+	errInvalidRefresh  = newError("invalid_grant", "invalid refresh token")
+	errPending         = newError("authorization_pending", "")
+	errSlowDown        = newError("slow_down", "")
+	errAccessDenied    = newError("access_denied", "")
+	errExpiredToken    = newError("expired_token", "")
+)
+
+// scopeChoices returns the roles matching the requested scopes, with
+// their names. All the scopes must be roles that the user, when given,
+// can access.
+func scopeChoices(tr forms.Translator, user *users.User, scope []string) ([]forms.ValueChoice[string], error) {
+	if len(scope) == 0 {
+		return nil, newError("invalid_scope", "no scope")
+	}
+
+	choices := users.RoleChoices(tr, user)
+	res := []forms.ValueChoice[string]{}
+	for _, x := range scope {
+		i := slices.IndexFunc(choices, func(c forms.ValueChoice[string]) bool {
+			return c.Value == x
+		})
+		if i < 0 {
+			return nil, newError("invalid_scope", "invalid scope: "+x)
+		}
+		res = append(res, choices[i])
+	}
+	return res, nil
+}
+
+// parseScope returns the scopes of a space separated list,
+// without duplicates.
+func parseScope(scope string) []string {
+	res := []string{}
+	for _, x := range strings.Fields(scope) {
+		if !slices.Contains(res, x) {
+			res = append(res, x)
+		}
+	}
+	return res
+}
+
+// authorizationCode is what an authorization code grants.
+type authorizationCode struct {
+	ClientID    int      `json:"c"`
+	UserID      int      `json:"u"`
+	Seed        int      `json:"s"`
+	RedirectURI string   `json:"r"`
+	Scopes      []string `json:"scopes"`
+	Challenge   string   `json:"challenge"`
+}
+
+// newAuthorizationCode saves an authorization and returns its code.
+func newAuthorizationCode(ac *authorizationCode) (string, error) {
+	data, err := json.Marshal(ac)
+	if err != nil {
+		return "", err
+	}
+
+	code := randomToken()
+	if err = bus.Store().Set("oauth:code:"+hashToken(code), string(data), codeLifetime); err != nil {
+		return "", err
+	}
+	return code, nil
+}
+
+// consumeAuthorizationCode returns the authorization of a code.
+// A code can only be used once.
+func consumeAuthorizationCode(code string) (*authorizationCode, error) {
+	// Two requests with the same code can't both take it.
+	data, err := bus.Store().Pop("oauth:code:" + hashToken(code))
+	if err != nil {
+		return nil, err
+	}
+	if data == "" {
+		return nil, errExpiredCode
+	}
+
+	ac := &authorizationCode{}
+	if err := json.Unmarshal([]byte(data), ac); err != nil {
+		return nil, err
+	}
+	return ac, nil
+}
+
+// checkVerifier checks a PKCE code verifier with the code's challenge.
+func (ac *authorizationCode) checkVerifier(verifier string) error {
+	sum := sha256.Sum256([]byte(verifier))
+	challenge := base64.RawURLEncoding.EncodeToString(sum[:])
+	if subtle.ConstantTimeCompare([]byte(challenge), []byte(ac.Challenge)) != 1 {
+		return errInvalidVerifier
+	}
+	return nil
+}
+
+// deviceAuthorization is a pending authorization of a device, that the
+// user approves on another device with its user code.
+type deviceAuthorization struct {
+	key      string
+	ClientID int       `json:"c"`
+	Scopes   []string  `json:"scopes"`
+	UserCode string    `json:"user_code"`
+	Status   string    `json:"status"`
+	UserID   int       `json:"u,omitempty"`
+	Seed     int       `json:"s,omitempty"`
+	Expires  time.Time `json:"expires"`
+	LastPoll time.Time `json:"last_poll"`
+}
+
+// newDeviceAuthorization saves a new device authorization and
+// returns its device code.
+func newDeviceAuthorization(client *Client, scopes []string) (string, *deviceAuthorization, error) {
+	code := randomToken()
+	da := &deviceAuthorization{
+		key:      "oauth:device:" + hashToken(code),
+		ClientID: client.ID,
+		Scopes:   scopes,
+		UserCode: newUserCode(),
+		Status:   deviceStatusPending,
+		Expires:  time.Now().Add(deviceLifetime),
+	}
+
+	if err := bus.Store().Set("oauth:user_code:"+da.UserCode, da.key, deviceLifetime); err != nil {
+		return "", nil, err
+	}
+	if err := da.save(); err != nil {
+		return "", nil, err
+	}
+	return code, da, nil
+}
+
+// loadDeviceAuthorization returns a device authorization saved with
+// the given key.
+func loadDeviceAuthorization(key string) (*deviceAuthorization, error) {
+	data := bus.Store().Get(key)
+	if data == "" {
+		return nil, errExpiredToken
+	}
+
+	da := &deviceAuthorization{key: key}
+	if err := json.Unmarshal([]byte(data), da); err != nil {
+		return nil, err
+	}
+	return da, nil
+}
+
+// getDeviceAuthorization returns the device authorization
+// of a device code.
+func getDeviceAuthorization(code string) (*deviceAuthorization, error) {
+	return loadDeviceAuthorization("oauth:device:" + hashToken(code))
+}
+
+// findDeviceAuthorization returns the device authorization
+// of a user code.
+func findDeviceAuthorization(userCode string) (*deviceAuthorization, error) {
+	key := bus.Store().Get("oauth:user_code:" + normalizeUserCode(userCode))
+	if key == "" {
+		return nil, errExpiredToken
+	}
+	return loadDeviceAuthorization(key)
+}
+
+func (da *deviceAuthorization) save() error {
+	data, err := json.Marshal(da)
+	if err != nil {
+		return err
+	}
+
+	ttl := time.Until(da.Expires)
+	if ttl <= 0 {
+		return errExpiredToken
+	}
+	return bus.Store().Set(da.key, string(data), ttl)
+}
+
+// delete removes the device authorization so it can't be used again.
+func (da *deviceAuthorization) delete() error {
+	if err := bus.Store().Del("oauth:user_code:" + da.UserCode); err != nil {
+		return err
+	}
+	return bus.Store().Del(da.key)
+}
+
+// approve grants the authorization to a user.
+func (da *deviceAuthorization) approve(user *users.User) error {
+	da.Status = deviceStatusApproved
+	da.UserID = user.ID
+	da.Seed = user.Seed
+	return da.save()
+}
+
+// deny refuses the authorization.
+func (da *deviceAuthorization) deny() error {
+	da.Status = deviceStatusDenied
+	return da.save()
+}
+
+// poll records a token request of the device and returns an error
+// until the user approved the authorization.
+func (da *deviceAuthorization) poll() error {
+	now := time.Now()
+	last := da.LastPoll
+	da.LastPoll = now
+
+	switch da.Status {
+	case deviceStatusApproved:
+		return da.delete()
+	case deviceStatusDenied:
+		if err := da.delete(); err != nil {
+			return err
+		}
+		return errAccessDenied
+	}
+
+	if err := da.save(); err != nil {
+		return err
+	}
+	if now.Sub(last) < devicePollInterval {
+		return errSlowDown
+	}
+	return errPending
+}
+
+// newUserCode returns a random user code, like "BCDF-GHJK".
+func newUserCode() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	for i := range b {
+		b[i] = userCodeAlphabet[int(b[i])%len(userCodeAlphabet)]
+	}
+	return string(b[:4]) + "-" + string(b[4:])
+}
+
+// normalizeUserCode returns a user code as typed by a user,
+// in its original form.
+func normalizeUserCode(code string) string {
+	code = strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(code))
+	if len(code) != 8 {
+		return code
+	}
+	return code[:4] + "-" + code[4:]
+}
+
+// tokenResponse is the token endpoint's response.
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+	Scope        string `json:"scope"`
+}
+
+// issueToken creates a new API token, with its refresh token.
+func issueToken(client *Client, user *users.User, scopes []string) (*tokenResponse, error) {
+	refresh := randomToken()
+	hash := hashToken(refresh)
+	expires := time.Now().Add(accessTokenLifetime).UTC()
+
+	t := &tokens.Token{
+		UserID:       &user.ID,
+		IsEnabled:    true,
+		Application:  client.Name,
+		Roles:        scopes,
+		Expires:      &expires,
+		ClientID:     &client.ID,
+		RefreshToken: &hash,
+		UserSeed:     &user.Seed,
+	}
+	if err := tokens.Tokens.Create(t); err != nil {
+		return nil, err
+	}
+
+	return newTokenResponse(t, refresh)
+}
+
+// refreshToken renews the token of a refresh token. The token gets a new
+// ID, so the previous access token can't be used anymore, and a new
+// refresh token.
+func refreshToken(client *Client, refresh string) (*tokenResponse, error) {
+	t, err := tokens.Tokens.GetOne(
+		goqu.C("refresh_token").Eq(hashToken(refresh)),
+		goqu.C("client_id").Eq(client.ID),
+		goqu.C("is_enabled").IsTrue(),
+	)
+	if errors.Is(err, tokens.ErrNotFound) {
+		return nil, errInvalidRefresh
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// The token is revoked when the user changed its password or
+	// was signed out everywhere.
+	user, err := users.Users.GetOne(goqu.C("id").Eq(*t.UserID))
+	if err != nil {
+		return nil, err
+	}
+	if t.UserSeed == nil || *t.UserSeed != user.Seed {
+		if err = t.Delete(); err != nil {
+			return nil, err
+		}
+		return nil, errInvalidRefresh
+	}
+
+	// The user must still have access to the token's roles
+	for _, r := range t.Roles {
+		if !acls.InGroup(r, user.Group) {
+			return nil, errInvalidRefresh
+		}
+	}
+
+	refresh = randomToken()
+	hash := hashToken(refresh)
+	expires := time.Now().Add(accessTokenLifetime).UTC()
+	t.UID = base58.NewUUID()
+	t.Expires = &expires
+	t.RefreshToken = &hash
+
+	if err = t.Update(goqu.Record{
+		"uid":           t.UID,
+		"expires":       expires,
+		"refresh_token": hash,
+	}); err != nil {
+		return nil, err
+	}
+
+	return newTokenResponse(t, refresh)
+}
+
+func newTokenResponse(t *tokens.Token, refresh string) (*tokenResponse, error) {
+	token, err := tokens.EncodeToken(t.UID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tokenResponse{
+		AccessToken:  token,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(accessTokenLifetime.Seconds()),
+		RefreshToken: refresh,
+		Scope:        strings.Join(t.Roles, " "),
+	}, nil
+}
+
+// randomToken returns a random 256-bit, base58 encoded, value.
+func randomToken() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return base58.EncodeToString(b)
+}
+
+// hashToken returns the hash of a token, as it's stored.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
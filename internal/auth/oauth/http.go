@@ -0,0 +1,70 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+// Package oauth implements an OAuth2 authorization server, so third-party
+// applications can obtain API tokens without asking for the user's password.
+// It supports the dynamic client registration (RFC 7591), the authorization
+// code grant with PKCE, the device authorization grant (RFC 8628) and
+// refresh tokens.
+package oauth
+
+import (
+	"net/http"
+	"strings"
+
+	"codeberg.org/readeck/readeck/internal/auth/users"
+	"codeberg.org/readeck/readeck/internal/server"
+)
+
+// SetupRoutes mounts the routes for the OAuth domain.
+func SetupRoutes(s *server.Server) {
+	// API routes
+	s.AddRoute("/api/oauth", newOAuthAPI(s))
+
+	// Website views
+	s.AddRoute("/oauth", newOAuthViews(s))
+
+	// Authorization server metadata (RFC 8414)
+	s.AddRoute("/.well-known/oauth-authorization-server", metadataHandler(s))
+}
+
+type serverMetadata struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	RegistrationEndpoint              string   `json:"registration_endpoint"`
+	DeviceAuthorizationEndpoint       string   `json:"device_authorization_endpoint"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+}
+
+func metadataHandler(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			s.Status(w, r, http.StatusMethodNotAllowed)
+			return
+		}
+
+		scopes := []string{}
+		for _, c := range users.RoleChoices(s.Locale(r), nil) {
+			scopes = append(scopes, c.Value)
+		}
+
+		s.Render(w, r, http.StatusOK, serverMetadata{
+			Issuer:                            strings.TrimSuffix(s.AbsoluteURL(r, "/").String(), "/"),
+			AuthorizationEndpoint:             s.AbsoluteURL(r, "/oauth/authorize").String(),
+			TokenEndpoint:                     s.AbsoluteURL(r, "/api/oauth/token").String(),
+			RegistrationEndpoint:              s.AbsoluteURL(r, "/api/oauth/client").String(),
+			DeviceAuthorizationEndpoint:       s.AbsoluteURL(r, "/api/oauth/device").String(),
+			ScopesSupported:                   scopes,
+			ResponseTypesSupported:            []string{"code"},
+			GrantTypesSupported:               []string{grantAuthorizationCode, grantDeviceCode, grantRefreshToken},
+			TokenEndpointAuthMethodsSupported: []string{"none"},
+			CodeChallengeMethodsSupported:     []string{"S256"},
+		})
+	}
+}
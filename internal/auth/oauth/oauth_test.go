@@ -0,0 +1,320 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package oauth_test
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"net/url"
+	"testing"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/stretchr/testify/require"
+
+	"codeberg.org/readeck/readeck/internal/auth/tokens"
+	. "codeberg.org/readeck/readeck/internal/testing" //revive:disable:dot-imports
+)
+
+func TestOAuth(t *testing.T) {
+	app := NewTestApp(t)
+	defer app.Close(t)
+
+	// api is a client without a session, like a third-party application.
+	api := NewClient(t, app)
+	api.Logout()
+
+	user := NewClient(t, app)
+	user.Login("user", app.Users["user"].Password())
+
+	var clientID string
+
+	bearer := func(token, target string) *Response {
+		req := api.NewRequest("GET", target, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		return api.Request(req)
+	}
+	jsonValue := func(rsp *Response, name string) string {
+		v, _ := rsp.JSON.(map[string]any)[name].(string)
+		return v
+	}
+
+	t.Run("metadata", func(t *testing.T) {
+		rsp := api.Get("/.well-known/oauth-authorization-server")
+		rsp.AssertStatus(t, 200)
+		rsp.AssertJQ(t, ".token_endpoint", "http://readeck.example.org/api/oauth/token")
+		rsp.AssertJQ(t, ".code_challenge_methods_supported", []any{"S256"})
+	})
+
+	t.Run("register", func(t *testing.T) {
+		rsp := api.RequestJSON("POST", "/api/oauth/client", map[string]any{
+			"client_name":   "Test App",
+			"client_uri":    "https://example.org/",
+			"redirect_uris": []string{"http://127.0.0.1/callback"},
+		})
+		rsp.AssertStatus(t, 201)
+		rsp.AssertJSON(t, `{
+			"client_id": "<<PRESENCE>>",
+			"client_id_issued_at": "<<PRESENCE>>",
+			"client_name": "Test App",
+			"client_uri": "https://example.org/",
+			"redirect_uris": ["http://127.0.0.1/callback"],
+			"token_endpoint_auth_method": "none",
+			"grant_types": [
+				"authorization_code",
+				"urn:ietf:params:oauth:grant-type:device_code",
+				"refresh_token"
+			],
+			"response_types": ["code"]
+		}`)
+		clientID = jsonValue(rsp, "client_id")
+
+		for _, data := range []map[string]any{
+			{"redirect_uris": []string{"https://example.org/"}},
+			{"client_name": "test", "redirect_uris": []string{"http://example.org/callback"}},
+			{"client_name": "test", "token_endpoint_auth_method": "client_secret_basic"},
+		} {
+			rsp = api.RequestJSON("POST", "/api/oauth/client", data)
+			rsp.AssertStatus(t, 400)
+			rsp.AssertJQ(t, ".error", "invalid_client_metadata")
+		}
+	})
+
+	t.Run("authorization code", func(t *testing.T) {
+		verifier := "a-random-verifier-that-is-long-enough-for-pkce"
+		sum := sha256.Sum256([]byte(verifier))
+		query := url.Values{
+			"response_type":         {"code"},
+			"client_id":             {clientID},
+			"redirect_uri":          {"http://127.0.0.1:8765/callback"},
+			"scope":                 {"scoped_bookmarks_r"},
+			"state":                 {"xyz"},
+			"code_challenge":        {base64.RawURLEncoding.EncodeToString(sum[:])},
+			"code_challenge_method": {"S256"},
+		}
+		target := "/oauth/authorize?" + query.Encode()
+
+		// Not signed in
+		rsp := api.Get(target)
+		rsp.AssertStatus(t, 303)
+		rsp.AssertRedirect(t, "^/login")
+
+		rsp = user.Get(target)
+		rsp.AssertStatus(t, 200)
+		require.Contains(t, string(rsp.Body), "Test App")
+		require.Contains(t, string(rsp.Body), "Bookmarks : Read Only")
+
+		rsp = user.PostForm(target, url.Values{"grant": {"0"}})
+		rsp.AssertStatus(t, 303)
+		rsp.AssertRedirect(t, `^http://127\.0\.0\.1:8765/callback\?error=access_denied&state=xyz$`)
+
+		user.Get(target)
+		rsp = user.PostForm(target, url.Values{"grant": {"1"}})
+		rsp.AssertStatus(t, 303)
+		rsp.AssertRedirect(t, `^http://127\.0\.0\.1:8765/callback\?code=.+&state=xyz$`)
+		u, err := url.Parse(rsp.Redirect)
+		require.NoError(t, err)
+		code := u.Query().Get("code")
+
+		exchange := url.Values{
+			"grant_type":    {"authorization_code"},
+			"client_id":     {clientID},
+			"code":          {code},
+			"redirect_uri":  {"http://127.0.0.1:8765/callback"},
+			"code_verifier": {verifier},
+		}
+		rsp = api.PostForm("/api/oauth/token", exchange)
+		rsp.AssertStatus(t, 200)
+		rsp.AssertJSON(t, `{
+			"access_token": "<<PRESENCE>>",
+			"token_type": "Bearer",
+			"expires_in": 3600,
+			"refresh_token": "<<PRESENCE>>",
+			"scope": "scoped_bookmarks_r"
+		}`)
+		access := jsonValue(rsp, "access_token")
+		refresh := jsonValue(rsp, "refresh_token")
+
+		// A code can be used only once
+		rsp = api.PostForm("/api/oauth/token", exchange)
+		rsp.AssertStatus(t, 400)
+		rsp.AssertJQ(t, ".error", "invalid_grant")
+
+		// The token is a regular API token
+		bearer(access, "/api/bookmarks").AssertStatus(t, 200)
+		bearer(access, "/api/admin/users").AssertStatus(t, 403)
+
+		count, err := tokens.Tokens.Query().Where(
+			goqu.C("user_id").Eq(app.Users["user"].User.ID),
+			goqu.C("client_id").IsNotNull(),
+		).Count()
+		require.NoError(t, err)
+		require.Equal(t, int64(1), count)
+
+		// Refresh rotates both tokens
+		rsp = api.PostForm("/api/oauth/token", url.Values{
+			"grant_type":    {"refresh_token"},
+			"client_id":     {clientID},
+			"refresh_token": {refresh},
+		})
+		rsp.AssertStatus(t, 200)
+		rsp0 := rsp
+		require.NotEqual(t, access, jsonValue(rsp, "access_token"))
+		require.NotEqual(t, refresh, jsonValue(rsp, "refresh_token"))
+		bearer(access, "/api/bookmarks").AssertStatus(t, 401)
+		bearer(jsonValue(rsp, "access_token"), "/api/bookmarks").AssertStatus(t, 200)
+
+		rsp = api.PostForm("/api/oauth/token", url.Values{
+			"grant_type":    {"refresh_token"},
+			"client_id":     {clientID},
+			"refresh_token": {refresh},
+		})
+		rsp.AssertStatus(t, 400)
+		rsp.AssertJQ(t, ".error", "invalid_grant")
+
+		// A new seed, after a password change, revokes the token.
+		access = jsonValue(rsp0, "access_token")
+		u0 := app.Users["user"].User
+		seed := u0.Seed
+		require.NoError(t, u0.Update(map[string]any{"seed": u0.SetSeed()}))
+		defer func() {
+			require.NoError(t, u0.Update(map[string]any{"seed": seed}))
+		}()
+		rsp = api.PostForm("/api/oauth/token", url.Values{
+			"grant_type":    {"refresh_token"},
+			"client_id":     {clientID},
+			"refresh_token": {jsonValue(rsp0, "refresh_token")},
+		})
+		rsp.AssertStatus(t, 400)
+		rsp.AssertJQ(t, ".error", "invalid_grant")
+		bearer(access, "/api/bookmarks").AssertStatus(t, 401)
+	})
+
+	t.Run("authorization errors", func(t *testing.T) {
+		base := url.Values{
+			"response_type":         {"code"},
+			"client_id":             {clientID},
+			"redirect_uri":          {"http://127.0.0.1/callback"},
+			"scope":                 {"scoped_bookmarks_r"},
+			"code_challenge":        {"challenge"},
+			"code_challenge_method": {"S256"},
+		}
+		with := func(name, value string) string {
+			q := url.Values{}
+			for k, v := range base {
+				q[k] = v
+			}
+			q.Set(name, value)
+			return "/oauth/authorize?" + q.Encode()
+		}
+
+		// No redirection to an unknown client or URI
+		user.Get(with("client_id", "unknown")).AssertStatus(t, 400)
+		user.Get(with("redirect_uri", "https://example.org/")).AssertStatus(t, 400)
+
+		rsp := user.Get(with("scope", "scoped_admin_r"))
+		rsp.AssertStatus(t, 303)
+		rsp.AssertRedirect(t, `error=invalid_scope`)
+
+		rsp = user.Get(with("code_challenge_method", "plain"))
+		rsp.AssertStatus(t, 303)
+		rsp.AssertRedirect(t, `error=invalid_request`)
+
+		rsp = user.Get(with("response_type", "token"))
+		rsp.AssertStatus(t, 303)
+		rsp.AssertRedirect(t, `error=unsupported_response_type`)
+
+		rsp = api.PostForm("/api/oauth/token", url.Values{
+			"grant_type": {"authorization_code"},
+			"client_id":  {"unknown"},
+		})
+		rsp.AssertStatus(t, 401)
+		rsp.AssertJQ(t, ".error", "invalid_client")
+
+		rsp = api.PostForm("/api/oauth/token", url.Values{
+			"grant_type": {"password"},
+			"client_id":  {clientID},
+		})
+		rsp.AssertStatus(t, 400)
+		rsp.AssertJQ(t, ".error", "unsupported_grant_type")
+	})
+
+	t.Run("device", func(t *testing.T) {
+		rsp := api.PostForm("/api/oauth/device", url.Values{
+			"client_id": {clientID},
+			"scope":     {"scoped_bookmarks_r scoped_bookmarks_w"},
+		})
+		rsp.AssertStatus(t, 200)
+		rsp.AssertJSON(t, `{
+			"device_code": "<<PRESENCE>>",
+			"user_code": "<<PRESENCE>>",
+			"verification_uri": "http://readeck.example.org/oauth/device",
+			"verification_uri_complete": "<<PRESENCE>>",
+			"expires_in": 600,
+			"interval": 5
+		}`)
+		deviceCode := jsonValue(rsp, "device_code")
+		userCode := jsonValue(rsp, "user_code")
+
+		poll := url.Values{
+			"grant_type":  {"urn:ietf:params:oauth:grant-type:device_code"},
+			"client_id":   {clientID},
+			"device_code": {deviceCode},
+		}
+		rsp = api.PostForm("/api/oauth/token", poll)
+		rsp.AssertStatus(t, 400)
+		rsp.AssertJQ(t, ".error", "authorization_pending")
+
+		rsp = api.PostForm("/api/oauth/token", poll)
+		rsp.AssertStatus(t, 400)
+		rsp.AssertJQ(t, ".error", "slow_down")
+
+		rsp = user.Get("/oauth/device?user_code=" + userCode)
+		rsp.AssertStatus(t, 200)
+		require.Contains(t, string(rsp.Body), userCode)
+
+		rsp = user.PostForm("/oauth/device", url.Values{"user_code": {"XXXX-XXXX"}})
+		rsp.AssertStatus(t, 422)
+
+		user.Get("/oauth/device")
+		rsp = user.PostForm("/oauth/device", url.Values{"user_code": {userCode}})
+		rsp.AssertStatus(t, 200)
+		require.Contains(t, string(rsp.Body), "Test App")
+
+		rsp = user.PostForm("/oauth/device", url.Values{"user_code": {userCode}, "grant": {"1"}})
+		rsp.AssertStatus(t, 200)
+		require.Contains(t, string(rsp.Body), "The device is now connected")
+
+		rsp = api.PostForm("/api/oauth/token", poll)
+		rsp.AssertStatus(t, 200)
+		rsp.AssertJQ(t, ".scope", "scoped_bookmarks_r scoped_bookmarks_w")
+		bearer(jsonValue(rsp, "access_token"), "/api/bookmarks").AssertStatus(t, 200)
+
+		rsp = api.PostForm("/api/oauth/token", poll)
+		rsp.AssertStatus(t, 400)
+		rsp.AssertJQ(t, ".error", "expired_token")
+	})
+
+	t.Run("device denied", func(t *testing.T) {
+		rsp := api.PostForm("/api/oauth/device", url.Values{
+			"client_id": {clientID},
+			"scope":     {"scoped_admin_r"},
+		})
+		rsp.AssertStatus(t, 200)
+		deviceCode := jsonValue(rsp, "device_code")
+
+		// The user can't give an admin permission.
+		user.Get("/oauth/device")
+		rsp = user.PostForm("/oauth/device", url.Values{"user_code": {jsonValue(rsp, "user_code")}})
+		rsp.AssertStatus(t, 400)
+
+		rsp = api.PostForm("/api/oauth/token", url.Values{
+			"grant_type":  {"urn:ietf:params:oauth:grant-type:device_code"},
+			"client_id":   {clientID},
+			"device_code": {deviceCode},
+		})
+		rsp.AssertStatus(t, 400)
+		rsp.AssertJQ(t, ".error", "access_denied")
+	})
+}
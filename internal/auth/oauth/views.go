@@ -0,0 +1,253 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package oauth
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/go-chi/chi/v5"
+
+	"codeberg.org/readeck/readeck/internal/auth"
+	"codeberg.org/readeck/readeck/internal/server"
+	"codeberg.org/readeck/readeck/pkg/forms"
+)
+
+var errInvalidUserCode = forms.Gettext("Invalid or expired code.")
+
+// oauthViews serves the pages where a user grants access
+// to a client.
+type oauthViews struct {
+	chi.Router
+	srv *server.Server
+}
+
+func newOAuthViews(s *server.Server) *oauthViews {
+	r := s.AuthenticatedRouter(s.WithRedirectLogin)
+	v := &oauthViews{r, s}
+
+	r.With(s.WithPermission("profile:tokens", "write")).Group(func(r chi.Router) {
+		r.Get("/authorize", v.authorize)
+		r.Post("/authorize", v.authorize)
+		r.Get("/device", v.device)
+		r.Post("/device", v.device)
+	})
+
+	return v
+}
+
+type authorizeForm struct {
+	*forms.Form
+}
+
+func newAuthorizeForm(tr forms.Translator) *authorizeForm {
+	return &authorizeForm{forms.Must(
+		forms.WithTranslator(context.Background(), tr),
+		forms.NewTextField("response_type", forms.Trim),
+		forms.NewTextField("client_id", forms.Trim),
+		forms.NewTextField("redirect_uri", forms.Trim),
+		forms.NewTextField("scope", forms.Trim),
+		forms.NewTextField("state"),
+		forms.NewTextField("code_challenge", forms.Trim),
+		forms.NewTextField("code_challenge_method", forms.Trim),
+	)}
+}
+
+// authorize is the authorization endpoint of the authorization code
+// grant. It shows the consent page and, once the user approved it,
+// sends an authorization code to the client's redirection URI.
+func (v *oauthViews) authorize(w http.ResponseWriter, r *http.Request) {
+	tr := v.srv.Locale(r)
+	user := auth.GetRequestUser(r)
+	f := newAuthorizeForm(tr)
+	forms.BindURL(f, r)
+
+	// An unknown client or redirection URI can't receive an error,
+	// the user gets an error page instead.
+	client, err := getClient(f.Get("client_id").String())
+	if err != nil {
+		v.renderError(w, r, err)
+		return
+	}
+	redirectURI := f.Get("redirect_uri").String()
+	if !client.HasRedirectURI(redirectURI) {
+		v.renderError(w, r, newError("invalid_request", "invalid redirect_uri"))
+		return
+	}
+
+	state := f.Get("state").String()
+	if f.Get("response_type").String() != "code" {
+		sendRedirect(w, r, redirectURI, state, newError("unsupported_response_type", ""))
+		return
+	}
+	if f.Get("code_challenge").String() == "" || f.Get("code_challenge_method").String() != "S256" {
+		sendRedirect(w, r, redirectURI, state, newError("invalid_request", "S256 code challenge required"))
+		return
+	}
+
+	scopes := parseScope(f.Get("scope").String())
+	choices, err := scopeChoices(tr, user, scopes)
+	if err != nil {
+		sendRedirect(w, r, redirectURI, state, err)
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		if r.PostFormValue("grant") != "1" {
+			sendRedirect(w, r, redirectURI, state, errAccessDenied)
+			return
+		}
+
+		code, err := newAuthorizationCode(&authorizationCode{
+			ClientID:    client.ID,
+			UserID:      user.ID,
+			Seed:        user.Seed,
+			RedirectURI: redirectURI,
+			Scopes:      scopes,
+			Challenge:   f.Get("code_challenge").String(),
+		})
+		if err != nil {
+			v.srv.Error(w, r, err)
+			return
+		}
+
+		v.srv.Log(r).Info("oauth authorization granted",
+			slog.String("client", client.UID),
+			slog.Int("user", user.ID),
+		)
+		sendRedirect(w, r, redirectURI, state, nil, "code", code)
+		return
+	}
+
+	v.srv.RenderTemplate(w, r, http.StatusOK, "/oauth/authorize", server.TC{
+		"Action": v.srv.CurrentPath(r),
+		"Client": client,
+		"Scopes": choices,
+	})
+}
+
+type deviceCodeForm struct {
+	*forms.Form
+}
+
+func newDeviceCodeForm(tr forms.Translator) *deviceCodeForm {
+	return &deviceCodeForm{forms.Must(
+		forms.WithTranslator(context.Background(), tr),
+		forms.NewTextField("user_code", forms.Trim, forms.Required),
+		forms.NewTextField("grant", forms.Trim),
+	)}
+}
+
+// device is the verification page of the device authorization grant.
+// The user enters the code shown by the device, then approves or
+// denies the authorization.
+func (v *oauthViews) device(w http.ResponseWriter, r *http.Request) {
+	tr := v.srv.Locale(r)
+	user := auth.GetRequestUser(r)
+	f := newDeviceCodeForm(tr)
+	tc := server.TC{"Form": f}
+
+	if r.Method == http.MethodGet {
+		f.Get("user_code").Set(r.URL.Query().Get("user_code"))
+		v.srv.RenderTemplate(w, r, http.StatusOK, "/oauth/device", tc)
+		return
+	}
+
+	forms.Bind(f, r)
+	var da *deviceAuthorization
+	var err error
+	if f.IsValid() {
+		da, err = findDeviceAuthorization(f.Get("user_code").String())
+		if err == nil && da.Status != deviceStatusPending {
+			err = errExpiredToken
+		}
+		if err != nil {
+			f.AddErrors("user_code", errInvalidUserCode)
+		}
+	}
+	if !f.IsValid() {
+		v.srv.RenderTemplate(w, r, http.StatusUnprocessableEntity, "/oauth/device", tc)
+		return
+	}
+
+	client, err := Clients.GetOne(goqu.C("id").Eq(da.ClientID))
+	if err != nil {
+		v.srv.Error(w, r, err)
+		return
+	}
+	choices, err := scopeChoices(tr, user, da.Scopes)
+	if err != nil {
+		// The user can't give the requested permissions.
+		if err = da.deny(); err != nil {
+			v.srv.Error(w, r, err)
+			return
+		}
+		v.renderError(w, r, errAccessDenied)
+		return
+	}
+
+	tc["Client"] = client
+	tc["Scopes"] = choices
+
+	switch f.Get("grant").String() {
+	case "1":
+		err = da.approve(user)
+		tc["Approved"] = true
+		v.srv.Log(r).Info("oauth device authorization granted",
+			slog.String("client", client.UID),
+			slog.Int("user", user.ID),
+		)
+	case "0":
+		err = da.deny()
+		tc["Denied"] = true
+	}
+	if err != nil {
+		v.srv.Error(w, r, err)
+		return
+	}
+
+	v.srv.RenderTemplate(w, r, http.StatusOK, "/oauth/device", tc)
+}
+
+// renderError shows an authorization error to the user.
+func (v *oauthViews) renderError(w http.ResponseWriter, r *http.Request, err error) {
+	var oErr *Error
+	if !errors.As(err, &oErr) {
+		v.srv.Error(w, r, err)
+		return
+	}
+
+	v.srv.RenderTemplate(w, r, http.StatusBadRequest, "/oauth/authorize", server.TC{
+		"Error": oErr,
+	})
+}
+
+// sendRedirect sends the user back to the client's redirection URI
+// with an error, or with the given parameters when there is no error.
+func sendRedirect(w http.ResponseWriter, r *http.Request, redirectURI, state string, err error, params ...string) {
+	u, _ := url.Parse(redirectURI)
+	q := u.Query()
+
+	var oErr *Error
+	if errors.As(err, &oErr) {
+		q.Set("error", oErr.Code)
+		if oErr.Description != "" {
+			q.Set("error_description", oErr.Description)
+		}
+	}
+	for i := 0; i+1 < len(params); i += 2 {
+		q.Set(params[i], params[i+1])
+	}
+	if state != "" {
+		q.Set("state", state)
+	}
+
+	u.RawQuery = q.Encode()
+	http.Redirect(w, r, u.String(), http.StatusSeeOther)
+}
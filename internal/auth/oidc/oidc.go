@@ -0,0 +1,311 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+// Package oidc implements an OpenID Connect relying party. Users sign in
+// with the authorization code flow and PKCE, and are then linked to, or
+// provisioned as, Readeck users.
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"codeberg.org/readeck/readeck/configs"
+)
+
+const (
+	// discoveryTTL is how long the provider metadata is cached.
+	discoveryTTL = time.Hour
+
+	// clockSkew is the tolerance on the ID token's time claims.
+	clockSkew = 2 * time.Minute
+)
+
+var (
+	httpClient = &http.Client{
+		Timeout: time.Second * 10,
+	}
+
+	// ErrInvalidToken is returned when the ID token is not valid.
+	ErrInvalidToken = errors.New("invalid ID token")
+
+	metadataCache struct {
+		sync.Mutex
+		issuer string
+		data   *providerMetadata
+		loaded time.Time
+	}
+)
+
+// providerMetadata contains the provider's discovery document values
+// we need.
+type providerMetadata struct {
+	Issuer                string   `json:"issuer"`
+	AuthorizationEndpoint string   `json:"authorization_endpoint"`
+	TokenEndpoint         string   `json:"token_endpoint"`
+	UserinfoEndpoint      string   `json:"userinfo_endpoint"`
+	TokenAuthMethods      []string `json:"token_endpoint_auth_methods_supported"`
+}
+
+// AuthRequest contains the values of an authorization request.
+// They must be kept by the client until the provider redirects to
+// the callback URL.
+type AuthRequest struct {
+	URL      string `json:"-"`
+	State    string `json:"s"`
+	Nonce    string `json:"n"`
+	Verifier string `json:"v"`
+}
+
+// Enabled returns true when an OpenID Connect provider is configured.
+func Enabled() bool {
+	return configs.Config.Auth.OIDC.IsEnabled()
+}
+
+// Name returns the provider's display name.
+func Name() string {
+	return configs.Config.Auth.OIDC.Name
+}
+
+// Issuer returns the provider's issuer identifier.
+func Issuer() string {
+	return strings.TrimSuffix(configs.Config.Auth.OIDC.Issuer, "/")
+}
+
+// getMetadata returns the provider metadata, from the cache
+// or the provider's discovery document.
+func getMetadata(ctx context.Context) (*providerMetadata, error) {
+	metadataCache.Lock()
+	defer metadataCache.Unlock()
+
+	issuer := Issuer()
+	if metadataCache.data != nil && metadataCache.issuer == issuer &&
+		time.Since(metadataCache.loaded) < discoveryTTL {
+		return metadataCache.data, nil
+	}
+
+	data := new(providerMetadata)
+	if err := getJSON(ctx, issuer+"/.well-known/openid-configuration", "", data); err != nil {
+		return nil, fmt.Errorf("discovery: %w", err)
+	}
+	if strings.TrimSuffix(data.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("discovery: issuer mismatch (%s)", data.Issuer)
+	}
+	if data.AuthorizationEndpoint == "" || data.TokenEndpoint == "" {
+		return nil, errors.New("discovery: missing endpoints")
+	}
+
+	metadataCache.issuer = issuer
+	metadataCache.data = data
+	metadataCache.loaded = time.Now()
+	return data, nil
+}
+
+// NewAuthRequest returns a new authorization request, with a random
+// state, nonce and PKCE code verifier. Its URL is where the user
+// must be sent to sign in.
+func NewAuthRequest(ctx context.Context, redirectURI string) (*AuthRequest, error) {
+	meta, err := getMetadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &AuthRequest{
+		State:    randomString(),
+		Nonce:    randomString(),
+		Verifier: randomString() + randomString(),
+	}
+
+	challenge := sha256.Sum256([]byte(req.Verifier))
+	values := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {configs.Config.Auth.OIDC.ClientID},
+		"redirect_uri":          {redirectURI},
+		"scope":                 {strings.Join(scopes(), " ")},
+		"state":                 {req.State},
+		"nonce":                 {req.Nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+
+	u, err := url.Parse(meta.AuthorizationEndpoint)
+	if err != nil {
+		return nil, err
+	}
+	q := u.Query()
+	for k, v := range values {
+		q[k] = v
+	}
+	u.RawQuery = q.Encode()
+	req.URL = u.String()
+
+	return req, nil
+}
+
+// Exchange exchanges an authorization code for tokens, validates
+// the ID token and returns its claims, completed by the userinfo
+// endpoint when available.
+//
+// The ID token comes directly from the token endpoint, over a TLS
+// connection, so its signature is not checked (OpenID Connect Core,
+// section 3.1.3.7). Its issuer, audience, expiration and nonce are.
+func Exchange(ctx context.Context, req *AuthRequest, code, redirectURI string) (Claims, error) {
+	meta, err := getMetadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := configs.Config.Auth.OIDC
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {redirectURI},
+		"code_verifier": {req.Verifier},
+		"client_id":     {cfg.ClientID},
+	}
+
+	useBasic := cfg.ClientSecret != "" && (len(meta.TokenAuthMethods) == 0 ||
+		slices.Contains(meta.TokenAuthMethods, "client_secret_basic"))
+	if cfg.ClientSecret != "" && !useBasic {
+		form.Set("client_secret", cfg.ClientSecret)
+	}
+
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, meta.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.Header.Set("Accept", "application/json")
+	if useBasic {
+		r.SetBasicAuth(url.QueryEscape(cfg.ClientID), url.QueryEscape(cfg.ClientSecret))
+	}
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+		IDToken     string `json:"id_token"`
+		Error       string `json:"error"`
+		Description string `json:"error_description"`
+	}
+	if err = doJSON(r, &token); err != nil && token.Error == "" {
+		return nil, fmt.Errorf("token: %w", err)
+	}
+	if token.Error != "" {
+		return nil, fmt.Errorf("token: %s (%s)", token.Error, token.Description)
+	}
+
+	claims, err := parseIDToken(token.IDToken, meta.Issuer, cfg.ClientID, req.Nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	// Complete the claims with the userinfo endpoint
+	if meta.UserinfoEndpoint != "" && token.AccessToken != "" {
+		info := Claims{}
+		if err = getJSON(ctx, meta.UserinfoEndpoint, token.AccessToken, &info); err != nil {
+			return nil, fmt.Errorf("userinfo: %w", err)
+		}
+		if info.String("sub") != claims.String("sub") {
+			return nil, errors.New("userinfo: subject mismatch")
+		}
+		for k, v := range info {
+			if _, ok := claims[k]; !ok {
+				claims[k] = v
+			}
+		}
+	}
+
+	return claims, nil
+}
+
+// parseIDToken decodes an ID token and checks its claims.
+func parseIDToken(raw, issuer, clientID, nonce string) (Claims, error) {
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidToken
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	claims := Claims{}
+	if err = json.Unmarshal(payload, &claims); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	now := time.Now()
+	switch {
+	case strings.TrimSuffix(claims.String("iss"), "/") != strings.TrimSuffix(issuer, "/"):
+		return nil, fmt.Errorf("%w: issuer mismatch", ErrInvalidToken)
+	case !slices.Contains(claims.Strings("aud"), clientID):
+		return nil, fmt.Errorf("%w: audience mismatch", ErrInvalidToken)
+	case len(claims.Strings("aud")) > 1 && claims.String("azp") != "" && claims.String("azp") != clientID:
+		return nil, fmt.Errorf("%w: authorized party mismatch", ErrInvalidToken)
+	case claims.Time("exp").IsZero() || now.After(claims.Time("exp").Add(clockSkew)):
+		return nil, fmt.Errorf("%w: expired", ErrInvalidToken)
+	case claims.String("nonce") != nonce:
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidToken)
+	case claims.String("sub") == "":
+		return nil, fmt.Errorf("%w: no subject", ErrInvalidToken)
+	}
+
+	return claims, nil
+}
+
+// scopes returns the requested scopes, always including "openid".
+func scopes() []string {
+	res := slices.Clone(configs.Config.Auth.OIDC.Scopes)
+	if !slices.Contains(res, "openid") {
+		res = append([]string{"openid"}, res...)
+	}
+	return res
+}
+
+// getJSON performs a GET request and decodes its JSON response.
+func getJSON(ctx context.Context, target, accessToken string, dst any) error {
+	r, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return err
+	}
+	r.Header.Set("Accept", "application/json")
+	if accessToken != "" {
+		r.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+	return doJSON(r, dst)
+}
+
+// doJSON performs a request and decodes its JSON response, even when
+// the response status is not successful.
+func doJSON(r *http.Request, dst any) error {
+	rsp, err := httpClient.Do(r)
+	if err != nil {
+		return err
+	}
+	defer rsp.Body.Close() //nolint:errcheck
+
+	decodeErr := json.NewDecoder(io.LimitReader(rsp.Body, 1<<20)).Decode(dst)
+	if rsp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d", rsp.StatusCode)
+	}
+	return decodeErr
+}
+
+// randomString returns a random, URL safe, 256-bit value.
+func randomString() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
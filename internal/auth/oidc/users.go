@@ -0,0 +1,229 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package oidc
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+
+	"codeberg.org/readeck/readeck/configs"
+	"codeberg.org/readeck/readeck/internal/auth/users"
+	"codeberg.org/readeck/readeck/pkg/base58"
+)
+
+var (
+	// ErrNoAccount is returned when no user matches the claims
+	// and users can't be created.
+	ErrNoAccount = errors.New("no matching account")
+
+	// ErrNoGroup is returned when the claims don't grant any group.
+	ErrNoGroup = errors.New("no group granted")
+
+	// ErrAlreadyLinked is returned when the provider's account
+	// is linked to another user.
+	ErrAlreadyLinked = errors.New("account linked to another user")
+
+	rxInvalidUsername = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+)
+
+// Claims contains the claims of an ID token, or from
+// the userinfo endpoint.
+type Claims map[string]any
+
+// Get returns a claim's value. A name with dots is a path
+// in nested objects, like "realm_access.roles".
+func (c Claims) Get(name string) any {
+	if v, ok := c[name]; ok {
+		return v
+	}
+
+	var v any = map[string]any(c)
+	for _, p := range strings.Split(name, ".") {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil
+		}
+		if v, ok = m[p]; !ok {
+			return nil
+		}
+	}
+	return v
+}
+
+// String returns a claim's value as a string.
+func (c Claims) String(name string) string {
+	if v, ok := c.Get(name).(string); ok {
+		return v
+	}
+	return ""
+}
+
+// Strings returns a claim's value as a list of strings. A single
+// string value becomes a list of one item.
+func (c Claims) Strings(name string) []string {
+	switch v := c.Get(name).(type) {
+	case string:
+		return []string{v}
+	case []any:
+		res := []string{}
+		for _, x := range v {
+			if s, ok := x.(string); ok {
+				res = append(res, s)
+			}
+		}
+		return res
+	}
+	return nil
+}
+
+// Bool returns a claim's value as a boolean. Some providers
+// send booleans as strings.
+func (c Claims) Bool(name string) bool {
+	switch v := c.Get(name).(type) {
+	case bool:
+		return v
+	case string:
+		b, _ := strconv.ParseBool(v)
+		return b
+	}
+	return false
+}
+
+// Time returns a claim's numeric date value.
+func (c Claims) Time(name string) time.Time {
+	if v, ok := c.Get(name).(float64); ok {
+		return time.Unix(int64(v), 0)
+	}
+	return time.Time{}
+}
+
+// SigninUser returns the Readeck user of the claims' account.
+//
+// A known account returns its linked user. Otherwise, the account is
+// linked to the current user when there's one, to the user with the
+// same verified email address when enabled, or to a new user when
+// enabled.
+//
+// When a groups mapping is configured, the user's group is updated
+// from the claims.
+func SigninUser(current *users.User, claims Claims) (*users.User, error) {
+	provider := Issuer()
+	subject := claims.String("sub")
+	cfg := configs.Config.Auth.OIDC
+
+	group, mapped := mapGroup(claims)
+	if group == "" {
+		return nil, ErrNoGroup
+	}
+
+	identity, user, err := users.Identities.GetUser(provider, subject)
+	switch {
+	case err == nil:
+		if current != nil && !current.IsAnonymous() && current.ID != user.ID {
+			return nil, ErrAlreadyLinked
+		}
+	case !errors.Is(err, users.ErrNotFound):
+		return nil, err
+	case current != nil && !current.IsAnonymous():
+		user = current
+	case cfg.LinkByEmail && claims.String("email") != "" && claims.Bool("email_verified"):
+		user, err = users.Users.GetOne(goqu.C("email").Eq(claims.String("email")))
+		if err != nil && !errors.Is(err, users.ErrNotFound) {
+			return nil, err
+		}
+	}
+
+	if user == nil {
+		if !cfg.AutoCreate {
+			return nil, ErrNoAccount
+		}
+		if user, err = createUser(claims, group); err != nil {
+			return nil, err
+		}
+	}
+
+	if identity == nil {
+		if identity, err = users.Identities.Link(user, provider, subject); err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	if err = identity.Update(goqu.Record{"last_used": now}); err != nil {
+		return nil, err
+	}
+
+	if mapped && user.Group != group {
+		user.Group = group
+		if err = user.Update(goqu.Record{"group": group, "updated": now}); err != nil {
+			return nil, err
+		}
+	}
+
+	return user, nil
+}
+
+// mapGroup returns the group granted by the claims. It returns the
+// group with the most permissions listing one of the claim's groups
+// or the default group. mapped is true when a mapping is configured,
+// in which case the group must be applied to existing users.
+func mapGroup(claims Claims) (group string, mapped bool) {
+	cfg := configs.Config.Auth.OIDC
+	if group = cfg.Groups.Map(claims.Strings(cfg.GroupsClaim)); group != "" {
+		return group, true
+	}
+
+	group = cfg.DefaultGroup
+	if group == "none" {
+		group = ""
+	}
+	return group, cfg.Groups.IsSet()
+}
+
+// createUser creates a new user from the claims, with a random password.
+func createUser(claims Claims, group string) (*users.User, error) {
+	email := claims.String("email")
+	if email == "" {
+		return nil, errors.New("no email address")
+	}
+
+	// Find a free username
+	base := claims.String("preferred_username")
+	if base == "" {
+		base, _, _ = strings.Cut(email, "@")
+	}
+	base = strings.Trim(rxInvalidUsername.ReplaceAllString(base, "-"), "-")
+	if base == "" {
+		base = "user"
+	}
+
+	username := base
+	for i := 2; ; i++ {
+		count, err := users.Users.Query().Where(goqu.C("username").Eq(username)).Count()
+		if err != nil {
+			return nil, err
+		}
+		if count == 0 {
+			break
+		}
+		username = base + "-" + strconv.Itoa(i)
+	}
+
+	u := &users.User{
+		Username: username,
+		Email:    email,
+		Password: base58.NewUUID() + base58.NewUUID(),
+		Group:    group,
+	}
+	if err := users.Users.Create(u); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
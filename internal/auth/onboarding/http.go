@@ -67,8 +67,10 @@ func (h *viewHandler) onboarding(w http.ResponseWriter, r *http.Request) {
 				configs.Config.Commissioned = true
 
 				sess := h.srv.GetSession(r)
-				sess.Payload.User = user.ID
-				sess.Payload.Seed = user.Seed
+				if err = sess.Start(r, user.ID, user.Seed); err != nil {
+					h.srv.Error(w, r, err)
+					return
+				}
 				sess.Save(w, r)
 
 				h.srv.Redirect(w, r, "/")
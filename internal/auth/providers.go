@@ -12,6 +12,7 @@ import (
 
 	"github.com/go-chi/chi/v5/middleware"
 
+	"codeberg.org/readeck/readeck/internal/auth/tokens"
 	"codeberg.org/readeck/readeck/internal/auth/users"
 )
 
@@ -33,6 +34,10 @@ type ProviderInfo struct {
 	Application string
 	Roles       []string
 	ID          string
+
+	// Restriction limits the bookmarks that can be accessed,
+	// when it's not nil.
+	Restriction *tokens.Restriction
 }
 
 // Provider is the interface that must implement any authentication
@@ -0,0 +1,95 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package registration
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/doug-martin/goqu/v9"
+
+	"codeberg.org/readeck/readeck/configs"
+	"codeberg.org/readeck/readeck/internal/auth/users"
+	"codeberg.org/readeck/readeck/pkg/forms"
+)
+
+type registerForm struct {
+	*forms.Form
+	invite *users.Invite
+}
+
+// newRegisterForm returns a registration form. With an invitation
+// tied to an email address, the email address must be this one.
+func newRegisterForm(tr forms.Translator, invite *users.Invite) *registerForm {
+	f := &registerForm{
+		Form: forms.Must(
+			forms.WithTranslator(context.Background(), tr),
+			forms.NewTextField("username", forms.Trim, forms.Required, users.IsValidUsername),
+			forms.NewTextField("email", forms.Trim, forms.Required, forms.IsEmail),
+			forms.NewTextField("password", forms.Required, users.IsValidPassword),
+		),
+		invite: invite,
+	}
+	if invite != nil && invite.Email != "" {
+		f.Get("email").Set(invite.Email)
+	}
+
+	return f
+}
+
+// Validate checks that the username and email address
+// are not already in use.
+func (f *registerForm) Validate() {
+	if f.invite != nil && f.invite.Email != "" &&
+		!strings.EqualFold(f.Get("email").String(), f.invite.Email) {
+		f.AddErrors("email", forms.Gettext("must be the address the invitation was sent to"))
+	}
+
+	for _, name := range []string{"username", "email"} {
+		c, err := users.Users.Query().
+			Where(goqu.C(name).Eq(f.Get(name).String())).
+			Count()
+		switch {
+		case err != nil:
+			f.AddErrors("", errors.New("validation process error"))
+		case c > 0 && name == "username":
+			f.AddErrors(name, forms.Gettext("username is already in use"))
+		case c > 0:
+			f.AddErrors(name, forms.Gettext("email address is already in use"))
+		}
+	}
+}
+
+// needsVerification returns true when the email address must be
+// verified before the account is created. An invitation tied to an
+// email address already proves it.
+func (f *registerForm) needsVerification() bool {
+	if f.invite != nil && f.invite.Email != "" {
+		return false
+	}
+	return configs.Config.Auth.Registration.VerifyEmail
+}
+
+// pendingUser returns the registration with a hashed password.
+func (f *registerForm) pendingUser(lang string) (*pendingUser, error) {
+	p := &pendingUser{
+		Username: f.Get("username").String(),
+		Email:    f.Get("email").String(),
+		Group:    configs.Config.Auth.Registration.DefaultGroup,
+		Lang:     lang,
+	}
+	if f.invite != nil {
+		p.Group = f.invite.Group
+		p.Invite = f.invite.UID
+	}
+
+	var err error
+	if p.Password, err = (&users.User{}).HashPassword(f.Get("password").String()); err != nil {
+		f.AddErrors("", forms.ErrUnexpected)
+		return nil, err
+	}
+	return p, nil
+}
@@ -0,0 +1,241 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package registration
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"codeberg.org/readeck/readeck/configs"
+	"codeberg.org/readeck/readeck/internal/auth/users"
+	"codeberg.org/readeck/readeck/internal/bus"
+	"codeberg.org/readeck/readeck/internal/email"
+	"codeberg.org/readeck/readeck/internal/server"
+	"codeberg.org/readeck/readeck/pkg/forms"
+)
+
+// SetupRoutes mounts the routes for the registration domain.
+func SetupRoutes(s *server.Server) {
+	r := chi.NewRouter()
+	r.Use(
+		s.WithSession(),
+		s.Csrf,
+		withPasswordLogin,
+	)
+
+	h := &viewHandler{r, s}
+	s.AddRoute("/register", r)
+
+	r.With(withOpenRegistration).Get("/", h.register)
+	r.With(withOpenRegistration).Post("/", h.register)
+	r.Get("/verify/{code}", h.verify)
+	r.Post("/verify/{code}", h.verify)
+	r.Get("/{code:[a-zA-Z0-9]{18,22}}", h.register)
+	r.Post("/{code:[a-zA-Z0-9]{18,22}}", h.register)
+}
+
+type viewHandler struct {
+	chi.Router
+	srv *server.Server
+}
+
+// withPasswordLogin is a middleware that disables the registration
+// when password login is disabled, since a new user couldn't sign in.
+func withPasswordLogin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !configs.Config.Auth.PasswordLogin {
+			http.NotFound(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// withOpenRegistration is a middleware that disables a route
+// when the registration is not open to anyone.
+func withOpenRegistration(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !IsOpen() {
+			http.NotFound(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (h *viewHandler) register(w http.ResponseWriter, r *http.Request) {
+	tr := h.srv.Locale(r)
+	tc := server.TC{}
+
+	var invite *users.Invite
+	if code := chi.URLParam(r, "code"); code != "" {
+		var err error
+		if invite, err = users.Invites.GetValid(code); err != nil {
+			if !errors.Is(err, users.ErrNotFound) {
+				h.srv.Log(r).Error("get invite", slog.Any("err", err))
+			}
+			tc["Error"] = tr.Gettext("This invitation is not valid or has expired.")
+			h.srv.RenderTemplate(w, r, http.StatusNotFound, "/auth/register", tc)
+			return
+		}
+		tc["Invite"] = invite
+	}
+
+	if err := CheckLimit(); err != nil {
+		if !errors.Is(err, ErrUserLimit) {
+			h.srv.Error(w, r, err)
+			return
+		}
+		tc["Error"] = tr.Gettext("This instance doesn't accept new users.")
+		h.srv.RenderTemplate(w, r, http.StatusForbidden, "/auth/register", tc)
+		return
+	}
+
+	f := newRegisterForm(tr, invite)
+	tc["Form"] = f
+
+	if r.Method == http.MethodPost {
+		forms.Bind(f, r)
+		if f.IsValid() {
+			p, err := f.pendingUser(tr.Tag.String())
+			if err != nil {
+				h.srv.Error(w, r, err)
+				return
+			}
+
+			if f.needsVerification() {
+				err = h.sendVerification(r, p)
+				if errors.Is(err, ErrTooManyEmails) {
+					w.Header().Set("Retry-After", strconv.Itoa(int(verifyEmailsTTL.Seconds())))
+					h.srv.RenderTemplate(w, r, http.StatusTooManyRequests, "/auth/register", server.TC{
+						"Error": tr.Gettext("Too many verification emails were sent from your address. Please try again later."),
+					})
+					return
+				}
+				if err != nil {
+					h.srv.Error(w, r, err)
+					return
+				}
+				tc["Sent"] = true
+				h.srv.RenderTemplate(w, r, http.StatusOK, "/auth/register", tc)
+				return
+			}
+
+			h.createUser(w, r, p)
+			return
+		}
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	}
+
+	h.srv.RenderTemplate(w, r, http.StatusOK, "/auth/register", tc)
+}
+
+// verify asks for a confirmation before creating the account of
+// a verification link. Link previews and email scanners only follow
+// the link, so they don't use the code.
+func (h *viewHandler) verify(w http.ResponseWriter, r *http.Request) {
+	code := chi.URLParam(r, "code")
+
+	p, ok := loadPendingUser(code)
+	if ok && r.Method == http.MethodPost {
+		p, ok = takePendingUser(code)
+	}
+	if !ok {
+		h.srv.RenderTemplate(w, r, http.StatusNotFound, "/auth/register", server.TC{
+			"Error": h.srv.Locale(r).Gettext("This verification link is not valid or has expired."),
+		})
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		h.createUser(w, r, p)
+		return
+	}
+
+	h.srv.RenderTemplate(w, r, http.StatusOK, "/auth/register", server.TC{
+		"Confirm": p,
+	})
+}
+
+// sendVerification saves a pending registration and sends the
+// verification link to its email address. It returns [ErrTooManyEmails]
+// when the client's address already sent too many emails.
+func (h *viewHandler) sendVerification(r *http.Request, p *pendingUser) error {
+	key := verifyPrefix + ":ip:" + r.RemoteAddr
+	sent, _ := strconv.Atoi(bus.Store().Get(key))
+	if sent >= maxVerifyEmails {
+		h.srv.Log(r).Warn("too many verification emails", slog.String("email", p.Email))
+		return ErrTooManyEmails
+	}
+	if err := bus.Store().Set(key, strconv.Itoa(sent+1), verifyEmailsTTL); err != nil {
+		return err
+	}
+
+	code, err := p.save()
+	if err != nil {
+		return err
+	}
+
+	msg, err := email.NewMsg(
+		configs.Config.Email.FromNoReply.String(),
+		p.Email,
+		"[Readeck] Confirm your email address",
+		email.WithMDTemplate(
+			"/emails/verify.jet.md",
+			h.srv.TemplateVars(r),
+			server.TC{
+				"SiteURL":    h.srv.AbsoluteURL(r, "/"),
+				"Username":   p.Username,
+				"VerifyLink": h.srv.AbsoluteURL(r, "/register/verify", code),
+			},
+		),
+	)
+	if err != nil {
+		return err
+	}
+
+	return email.Sender.SendEmail(msg)
+}
+
+// createUser creates the user of a registration and signs it in.
+func (h *viewHandler) createUser(w http.ResponseWriter, r *http.Request, p *pendingUser) {
+	tr := h.srv.Locale(r)
+
+	user, err := p.create()
+	switch {
+	case errors.Is(err, ErrUserLimit):
+		h.srv.RenderTemplate(w, r, http.StatusForbidden, "/auth/register", server.TC{
+			"Error": tr.Gettext("This instance doesn't accept new users."),
+		})
+		return
+	case errors.Is(err, ErrAlreadyExists), errors.Is(err, users.ErrNotFound):
+		h.srv.RenderTemplate(w, r, http.StatusConflict, "/auth/register", server.TC{
+			"Error": tr.Gettext("This account can't be created anymore. Please register again."),
+		})
+		return
+	case err != nil:
+		h.srv.Error(w, r, err)
+		return
+	}
+
+	h.srv.Log(r).Info("user registered",
+		slog.String("user", user.Username),
+		slog.String("group", user.Group),
+	)
+
+	sess := h.srv.GetSession(r)
+	if err = sess.Start(r, user.ID, user.Seed); err != nil {
+		h.srv.Error(w, r, err)
+		return
+	}
+	sess.AddFlash("success", tr.Gettext("Welcome to Readeck, %s!", user.Username))
+	sess.Save(w, r)
+
+	h.srv.Redirect(w, r, "/")
+}
@@ -0,0 +1,268 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package registration_test
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/stretchr/testify/require"
+
+	"codeberg.org/readeck/readeck/configs"
+	"codeberg.org/readeck/readeck/internal/auth/users"
+	"codeberg.org/readeck/readeck/internal/bus"
+	. "codeberg.org/readeck/readeck/internal/testing" //revive:disable:dot-imports
+)
+
+func TestRegistration(t *testing.T) {
+	app := NewTestApp(t)
+	defer app.Close(t)
+
+	client := NewClient(t, app)
+
+	defaults := configs.Config.Auth.Registration
+	reset := func() {
+		configs.Config.Auth.Registration = defaults
+	}
+
+	assertUser := func(username, group string) func(*testing.T, *Response) {
+		return func(t *testing.T, _ *Response) {
+			u, err := users.Users.GetOne(goqu.C("username").Eq(username))
+			require.NoError(t, err)
+			require.Equal(t, group, u.Group)
+		}
+	}
+
+	t.Run("closed", func(t *testing.T) {
+		defer reset()
+
+		RunRequestSequence(t, client, "",
+			RequestTest{Target: "/register", ExpectStatus: 404},
+			RequestTest{
+				Target:       "/login",
+				ExpectStatus: 200,
+				Assert: func(t *testing.T, r *Response) {
+					require.NotContains(t, string(r.Body), `href="/register"`)
+				},
+			},
+		)
+	})
+
+	t.Run("open with verification", func(t *testing.T) {
+		defer reset()
+		configs.Config.Auth.Registration.Open = true
+
+		code := ""
+		RunRequestSequence(t, client, "",
+			RequestTest{
+				Target:         "/login",
+				ExpectStatus:   200,
+				ExpectContains: `href="/register"`,
+			},
+			RequestTest{Target: "/register", ExpectStatus: 200},
+			RequestTest{
+				Method: "POST",
+				Target: "/register",
+				Form: url.Values{
+					"username": {"user"},
+					"email":    {"user@localhost"},
+					"password": {"1234"},
+				},
+				ExpectStatus:   422,
+				ExpectContains: "username is already in use",
+			},
+			RequestTest{Target: "/register", ExpectStatus: 200},
+			RequestTest{
+				Method: "POST",
+				Target: "/register",
+				Form: url.Values{
+					"username": {"alice"},
+					"email":    {"alice@localhost"},
+					"password": {"12345678"},
+				},
+				ExpectStatus: 200,
+				Assert: func(t *testing.T, _ *Response) {
+					rx := regexp.MustCompile(
+						regexp.QuoteMeta("http://"+client.URL.Host) + "/register/verify/(.+)\r\n",
+					)
+					m := rx.FindStringSubmatch(app.LastEmail)
+					if len(m) < 2 {
+						t.Fatal("could not find verification link in last email")
+					}
+					code = m[1]
+
+					_, err := users.Users.GetOne(goqu.C("username").Eq("alice"))
+					require.ErrorIs(t, err, users.ErrNotFound)
+				},
+			},
+		)
+
+		RunRequestSequence(t, client, "",
+			// Following the link only asks for a confirmation
+			RequestTest{
+				Target:         "/register/verify/" + code,
+				ExpectStatus:   200,
+				ExpectContains: "<strong>alice</strong>",
+				Assert: func(t *testing.T, _ *Response) {
+					_, err := users.Users.GetOne(goqu.C("username").Eq("alice"))
+					require.ErrorIs(t, err, users.ErrNotFound)
+				},
+			},
+			RequestTest{
+				Target:         "/register/verify/" + code,
+				ExpectStatus:   200,
+				ExpectContains: "<strong>alice</strong>",
+			},
+			RequestTest{
+				Method:         "POST",
+				Target:         "/register/verify/" + code,
+				ExpectStatus:   303,
+				ExpectRedirect: "/",
+				Assert:         assertUser("alice", "user"),
+			},
+			// The link can only be used once
+			RequestTest{
+				Target:       "/register/verify/" + code,
+				ExpectStatus: 404,
+			},
+		)
+	})
+
+	t.Run("verification emails limit", func(t *testing.T) {
+		defer reset()
+		configs.Config.Auth.Registration.Open = true
+		require.NoError(t, bus.Store().Del("registration:ip:192.0.2.1"))
+		defer bus.Store().Del("registration:ip:192.0.2.1") //nolint:errcheck
+
+		register := func(username string, status int) RequestTest {
+			return RequestTest{
+				Method: "POST",
+				Target: "/register",
+				Form: url.Values{
+					"username": {username},
+					"email":    {username + "@localhost"},
+					"password": {"12345678"},
+				},
+				ExpectStatus: status,
+			}
+		}
+
+		tests := []RequestTest{}
+		for i := range 5 {
+			tests = append(tests,
+				RequestTest{Target: "/register", ExpectStatus: 200},
+				register(fmt.Sprintf("carol%d", i), 200),
+			)
+		}
+		tests = append(tests,
+			RequestTest{Target: "/register", ExpectStatus: 200},
+			register("carol5", 429),
+		)
+		tests[len(tests)-1].ExpectContains = "Too many verification emails"
+		tests[len(tests)-1].Assert = func(t *testing.T, r *Response) {
+			require.Equal(t, "3600", r.Header.Get("Retry-After"))
+		}
+
+		RunRequestSequence(t, client, "", tests...)
+	})
+
+	t.Run("open without verification", func(t *testing.T) {
+		defer reset()
+		configs.Config.Auth.Registration.Open = true
+		configs.Config.Auth.Registration.VerifyEmail = false
+		configs.Config.Auth.Registration.DefaultGroup = "staff"
+
+		RunRequestSequence(t, client, "",
+			RequestTest{Target: "/register", ExpectStatus: 200},
+			RequestTest{
+				Method: "POST",
+				Target: "/register",
+				Form: url.Values{
+					"username": {"bob"},
+					"email":    {"bob@localhost"},
+					"password": {"12345678"},
+				},
+				ExpectStatus:   303,
+				ExpectRedirect: "/",
+				Assert:         assertUser("bob", "staff"),
+			},
+		)
+	})
+
+	t.Run("user limit", func(t *testing.T) {
+		defer reset()
+		count, err := users.Users.Count()
+		require.NoError(t, err)
+
+		configs.Config.Auth.Registration.Open = true
+		configs.Config.Auth.Registration.MaxUsers = int(count)
+
+		RunRequestSequence(t, client, "",
+			RequestTest{
+				Target:         "/register",
+				ExpectStatus:   403,
+				ExpectContains: "This instance doesn&#39;t accept new users.",
+			},
+		)
+	})
+
+	t.Run("invitation", func(t *testing.T) {
+		defer reset()
+
+		invite := &users.Invite{
+			Email:   "carol@localhost",
+			Group:   "staff",
+			Expires: time.Now().Add(time.Hour),
+		}
+		require.NoError(t, users.Invites.Create(invite))
+
+		expired := &users.Invite{
+			Group:   "user",
+			Expires: time.Now().Add(-time.Hour),
+		}
+		require.NoError(t, users.Invites.Create(expired))
+
+		RunRequestSequence(t, client, "",
+			RequestTest{Target: "/register/" + expired.UID, ExpectStatus: 404},
+			RequestTest{Target: "/register/" + invite.UID, ExpectStatus: 200},
+			RequestTest{
+				Method: "POST",
+				Target: "/register/" + invite.UID,
+				Form: url.Values{
+					"username": {"carol"},
+					"email":    {"someone@localhost"},
+					"password": {"12345678"},
+				},
+				ExpectStatus:   422,
+				ExpectContains: "must be the address the invitation was sent to",
+			},
+			RequestTest{Target: "/register/" + invite.UID, ExpectStatus: 200},
+			RequestTest{
+				Method: "POST",
+				Target: "/register/" + invite.UID,
+				Form: url.Values{
+					"username": {"carol"},
+					"email":    {"carol@localhost"},
+					"password": {"12345678"},
+				},
+				ExpectStatus:   303,
+				ExpectRedirect: "/",
+				Assert: func(t *testing.T, r *Response) {
+					assertUser("carol", "staff")(t, r)
+					_, err := users.Invites.GetOne(goqu.C("uid").Eq(invite.UID))
+					require.ErrorIs(t, err, users.ErrNotFound)
+				},
+			},
+		)
+
+		RunRequestSequence(t, client, "",
+			RequestTest{Target: "/register/" + invite.UID, ExpectStatus: 404},
+		)
+	})
+}
@@ -0,0 +1,176 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+// Package registration provides the self-service registration of
+// new users, either open to anyone or with an invitation, and the
+// verification of their email address.
+package registration
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+
+	"codeberg.org/readeck/readeck/configs"
+	"codeberg.org/readeck/readeck/internal/acls"
+	"codeberg.org/readeck/readeck/internal/auth/users"
+	"codeberg.org/readeck/readeck/internal/bus"
+	"codeberg.org/readeck/readeck/internal/email"
+	"codeberg.org/readeck/readeck/pkg/base58"
+)
+
+const (
+	// verifyPrefix is the key prefix of the pending
+	// registrations in the key/value store.
+	verifyPrefix = "registration"
+
+	// verifyTTL is how long a pending registration waits
+	// for its email address to be verified.
+	verifyTTL = 24 * time.Hour
+
+	// maxVerifyEmails is the number of verification emails a client
+	// address can send within verifyEmailsTTL of the last one.
+	maxVerifyEmails = 5
+	verifyEmailsTTL = time.Hour
+)
+
+var (
+	// ErrUserLimit is returned when the instance reached
+	// its maximum number of users.
+	ErrUserLimit = errors.New("this instance doesn't accept new users")
+
+	// ErrAlreadyExists is returned when the username or the
+	// email address of a registration is already in use.
+	ErrAlreadyExists = errors.New("user already exists")
+
+	// ErrTooManyEmails is returned when a client address sent
+	// too many verification emails.
+	ErrTooManyEmails = errors.New("too many verification emails")
+)
+
+// IsOpen returns true when anyone can register. The registration
+// stays closed when a required email verification can't be sent.
+func IsOpen() bool {
+	cfg := configs.Config.Auth.Registration
+	return cfg.Open && configs.Config.Auth.PasswordLogin &&
+		(!cfg.VerifyEmail || email.CanSendEmail())
+}
+
+// CheckLimit returns [ErrUserLimit] when the instance can't have
+// any new user.
+func CheckLimit() error {
+	limit := configs.Config.Auth.Registration.MaxUsers
+	if limit <= 0 {
+		return nil
+	}
+
+	count, err := users.Users.Count()
+	if err != nil {
+		return err
+	}
+	if count >= int64(limit) {
+		return ErrUserLimit
+	}
+	return nil
+}
+
+// pendingUser is a registration waiting for the verification
+// of its email address. The password is already hashed.
+type pendingUser struct {
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	Password string `json:"password"`
+	Group    string `json:"group"`
+	Lang     string `json:"lang"`
+	Invite   string `json:"invite,omitempty"`
+}
+
+// save stores the pending registration and returns its
+// verification code.
+func (p *pendingUser) save() (string, error) {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return "", err
+	}
+
+	code := base58.NewUUID()
+	return code, bus.Store().Set(pendingUserKey(code), string(data), verifyTTL)
+}
+
+// loadPendingUser returns the pending registration of a verification code.
+func loadPendingUser(code string) (*pendingUser, bool) {
+	v := bus.Store().Get(pendingUserKey(code))
+	if v == "" {
+		return nil, false
+	}
+
+	p := new(pendingUser)
+	if err := json.Unmarshal([]byte(v), p); err != nil {
+		return nil, false
+	}
+	return p, true
+}
+
+// takePendingUser returns the pending registration of a verification
+// code and removes it, so the code can only be used once.
+func takePendingUser(code string) (*pendingUser, bool) {
+	p, ok := loadPendingUser(code)
+	if ok {
+		bus.Store().Del(pendingUserKey(code)) //nolint:errcheck
+	}
+	return p, ok
+}
+
+func pendingUserKey(code string) string {
+	return fmt.Sprintf("%s_%s", verifyPrefix, code)
+}
+
+// create creates the user. The invitation, if any, is used
+// at this point and can't be used again.
+func (p *pendingUser) create() (*users.User, error) {
+	if err := CheckLimit(); err != nil {
+		return nil, err
+	}
+	if !acls.IsGroup(p.Group) {
+		return nil, fmt.Errorf("unknown group %q", p.Group)
+	}
+
+	count, err := users.Users.Query().Where(goqu.Or(
+		goqu.C("username").Eq(p.Username),
+		goqu.C("email").Eq(p.Email),
+	)).Count()
+	if err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, ErrAlreadyExists
+	}
+
+	if p.Invite != "" {
+		invite, err := users.Invites.GetValid(p.Invite)
+		if err != nil {
+			return nil, err
+		}
+		if err = invite.Delete(); err != nil {
+			return nil, err
+		}
+	}
+
+	u := &users.User{
+		Username: p.Username,
+		Email:    p.Email,
+		Password: p.Password,
+		Group:    p.Group,
+		Settings: &users.UserSettings{
+			Lang: p.Lang,
+		},
+	}
+	if err = users.Users.CreateHashed(u); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
@@ -5,6 +5,7 @@
 package signin
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
@@ -40,7 +41,16 @@ func (api *authAPI) auth(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user := checkUser(f)
+	user, err := CheckUser(r, f)
+	var throttle *ThrottleError
+	if errors.As(err, &throttle) {
+		throttle.SetHeader(w)
+		api.srv.Message(w, r, &server.Message{
+			Status:  http.StatusTooManyRequests,
+			Message: err.Error(),
+		})
+		return
+	}
 	if !f.IsValid() || user == nil {
 		api.srv.Message(w, r, &server.Message{
 			Status:  http.StatusForbidden,
@@ -49,6 +59,19 @@ func (api *authAPI) auth(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := CheckTOTP(r, user, f.Get("totp").String()); err != nil {
+		status := http.StatusForbidden
+		if errors.As(err, &throttle) {
+			throttle.SetHeader(w)
+			status = http.StatusTooManyRequests
+		}
+		api.srv.Message(w, r, &server.Message{
+			Status:  status,
+			Message: err.Error(),
+		})
+		return
+	}
+
 	t := &tokens.Token{
 		UserID:      &user.ID,
 		IsEnabled:   true,
@@ -6,15 +6,27 @@ package signin
 
 import (
 	"context"
+	"errors"
+	"log/slog"
+	"net/http"
 	"strings"
 
 	"github.com/doug-martin/goqu/v9"
 
+	"codeberg.org/readeck/readeck/configs"
+	"codeberg.org/readeck/readeck/internal/auth/ldap"
 	"codeberg.org/readeck/readeck/internal/auth/users"
 	"codeberg.org/readeck/readeck/pkg/forms"
 )
 
-var errInvalidLogin = forms.Gettext("Invalid user and/or password")
+var (
+	errInvalidLogin          = forms.Gettext("Invalid user and/or password")
+	errPasswordLoginDisabled = forms.Gettext("Sign in with a password is disabled")
+	errTOTPRequired          = forms.Gettext("A two-factor authentication code is required")
+	errTOTPSetupRequired     = forms.Gettext("Two-factor authentication must be set up first")
+	errInvalidTOTP           = forms.Gettext("Invalid two-factor authentication code")
+	errTooManyAttempts       = forms.Gettext("Too many failed attempts, please try again later")
+)
 
 type tokenLoginForm struct {
 	*forms.Form
@@ -26,6 +38,7 @@ func newTokenLoginForm(tr forms.Translator) *tokenLoginForm {
 		forms.NewTextField("username", forms.Trim, forms.Required),
 		forms.NewTextField("password", forms.Required),
 		forms.NewTextField("application", forms.Required),
+		forms.NewTextField("totp", forms.Trim),
 		users.NewRolesField(tr, nil),
 	)}
 }
@@ -43,23 +56,190 @@ func newLoginForm(tr forms.Translator) *loginForm {
 	)}
 }
 
-func checkUser(f forms.Binder) *users.User {
+type totpForm struct {
+	*forms.Form
+}
+
+func newTOTPForm(tr forms.Translator) *totpForm {
+	return &totpForm{forms.Must(
+		forms.WithTranslator(context.Background(), tr),
+		forms.NewTextField("code", forms.Trim, forms.Required),
+	)}
+}
+
+// CheckUser returns the user matching the form's "username" and "password"
+// fields. When there's no such user, when the password is wrong or when
+// password login is disabled, it adds an error to the form and returns it.
+//
+// Failed attempts are counted for the user and the client address. When
+// there are too many of them, the attempt is rejected with
+// a [*ThrottleError], without checking the password.
+func CheckUser(r *http.Request, f forms.Binder) (*users.User, error) {
+	if !configs.Config.Auth.PasswordLogin {
+		f.AddErrors("", errPasswordLoginDisabled)
+		return nil, errPasswordLoginDisabled
+	}
+
+	ip := ipAttempts(r)
+	if err := ip.check(); err != nil {
+		f.AddErrors("", errTooManyAttempts)
+		return nil, err
+	}
+
+	username := f.Get("username").String()
 	col := goqu.C("username")
-	if strings.Contains(f.Get("username").String(), "@") {
+	if strings.Contains(username, "@") {
 		// A username cannot contain a "@" so if we have one here,
 		// we can check on the email instead of the username.
 		col = goqu.C("email")
 	}
 
-	user, err := users.Users.GetOne(col.Eq(f.Get("username").String()))
-	if err != nil {
+	// A directory user might not exist yet.
+	user, err := users.Users.GetOne(col.Eq(username))
+	if err != nil && !errors.Is(err, users.ErrNotFound) {
 		f.AddErrors("", errInvalidLogin)
-		return nil
+		return nil, err
 	}
-	if !user.CheckPassword(f.Get("password").String()) {
+
+	var attempts *Attempts
+	if user != nil {
+		attempts = UserAttempts(user)
+		if err = attempts.check(); err != nil {
+			f.AddErrors("", errTooManyAttempts)
+			return nil, err
+		}
+	}
+
+	if user, err = checkPassword(user, username, f.Get("password").String()); err != nil {
+		ip.fail()
+		metricLoginFailures.WithLabelValues("invalid").Inc()
+		if attempts != nil {
+			attempts.fail()
+			if attempts.IsLocked() {
+				slog.Warn("sign in locked",
+					slog.String("username", username),
+					slog.String("ip", r.RemoteAddr),
+					slog.Time("until", attempts.LockedUntil()),
+				)
+			}
+		}
 		f.AddErrors("", errInvalidLogin)
+		return nil, errInvalidLogin
+	}
+
+	// With a second factor, the attempts are reset once it's checked.
+	if totp, _ := needsTOTP(user); !totp {
+		if err = UserAttempts(user).Reset(); err != nil {
+			slog.Error("login attempts", slog.Any("err", err))
+		}
+	}
+	return user, nil
+}
+
+// checkPassword returns the user matching a username and password.
+// When a directory is configured, the password is checked against it
+// and the directory entry gives the user. Otherwise, or when falling
+// back from the directory, the password is checked against the given
+// user's one, if any.
+func checkPassword(user *users.User, username, password string) (*users.User, error) {
+	if ldap.Enabled() {
+		entry, err := ldap.Authenticate(username, password)
+		switch {
+		case err == nil:
+			if user, err = ldap.SigninUser(entry); err != nil {
+				slog.Warn("ldap sign in",
+					slog.String("dn", entry.DN),
+					slog.Any("err", err),
+				)
+			}
+			return user, err
+		case errors.Is(err, ldap.ErrInvalidCredentials):
+			return nil, err
+		case !errors.Is(err, ldap.ErrUserNotFound):
+			slog.Error("ldap", slog.Any("err", err))
+		}
+
+		if !configs.Config.Auth.LDAP.LocalFallback {
+			return nil, err
+		}
+	}
+
+	if user == nil || !user.CheckPassword(password) {
+		return nil, errInvalidLogin
+	}
+	return user, nil
+}
+
+// CheckTOTP checks the second factor of a user, with a code from an
+// authenticator application or a recovery code. It returns nil when
+// the code is valid or when the user doesn't use a second factor.
+//
+// A wrong code counts as a failed attempt, like a wrong password.
+func CheckTOTP(r *http.Request, user *users.User, code string) error {
+	a, err := users.Authenticators.ForUser(user)
+	if errors.Is(err, users.ErrNotFound) || err == nil && !a.IsEnabled() {
+		if user.TOTPRequired() {
+			return errTOTPSetupRequired
+		}
 		return nil
 	}
+	if err != nil {
+		slog.Error("totp", slog.Any("err", err))
+		return errInvalidTOTP
+	}
+
+	if code == "" {
+		return errTOTPRequired
+	}
 
-	return user
+	ok, err := verifyTOTP(r, user, a, code)
+	var throttle *ThrottleError
+	switch {
+	case errors.As(err, &throttle):
+		return err
+	case err != nil:
+		slog.Error("totp", slog.Any("err", err))
+		return errInvalidTOTP
+	case !ok:
+		return errInvalidTOTP
+	}
+	return nil
+}
+
+// verifyTOTP checks a code with the user's authenticator. Failed
+// attempts are counted for the user and the client address. When
+// there are too many of them, the attempt is rejected with
+// a [*ThrottleError], without checking the code.
+func verifyTOTP(r *http.Request, user *users.User, a *users.Authenticator, code string) (bool, error) {
+	ip := ipAttempts(r)
+	if err := ip.check(); err != nil {
+		return false, err
+	}
+	attempts := UserAttempts(user)
+	if err := attempts.check(); err != nil {
+		return false, err
+	}
+
+	ok, err := a.Verify(code)
+	if err != nil {
+		return false, err
+	}
+	if !ok {
+		ip.fail()
+		attempts.fail()
+		metricLoginFailures.WithLabelValues("totp").Inc()
+		if attempts.IsLocked() {
+			slog.Warn("sign in locked",
+				slog.String("username", user.Username),
+				slog.String("ip", r.RemoteAddr),
+				slog.Time("until", attempts.LockedUntil()),
+			)
+		}
+		return false, nil
+	}
+
+	if err = attempts.Reset(); err != nil {
+		slog.Error("login attempts", slog.Any("err", err))
+	}
+	return true, nil
 }
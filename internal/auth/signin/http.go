@@ -6,14 +6,19 @@
 package signin
 
 import (
+	"errors"
 	"net/http"
-	"strings"
 
 	"github.com/go-chi/chi/v5"
 
+	"codeberg.org/readeck/readeck/configs"
 	"codeberg.org/readeck/readeck/internal/auth"
+	"codeberg.org/readeck/readeck/internal/auth/oidc"
+	"codeberg.org/readeck/readeck/internal/auth/registration"
 	"codeberg.org/readeck/readeck/internal/server"
+	"codeberg.org/readeck/readeck/pkg/base58"
 	"codeberg.org/readeck/readeck/pkg/forms"
+	"codeberg.org/readeck/readeck/pkg/http/securecookie"
 )
 
 // SetupRoutes mounts the routes for the auth domain.
@@ -26,7 +31,9 @@ func SetupRoutes(s *server.Server) {
 
 type authHandler struct {
 	chi.Router
-	srv *server.Server
+	srv       *server.Server
+	oidcState *securecookie.Handler
+	totpState *securecookie.Handler
 }
 
 func newAuthHandler(s *server.Server) *authHandler {
@@ -37,12 +44,21 @@ func newAuthHandler(s *server.Server) *authHandler {
 		s.Csrf,
 	)
 
-	h := &authHandler{r, s}
+	h := &authHandler{r, s, newOIDCStateHandler(s), newTOTPStateHandler(s)}
 	s.AddRoute("/login", r)
 	r.Get("/", h.login)
 	r.Post("/", h.login)
 
-	r.With(s.WithPermission("email", "send")).Route("/recover", func(r chi.Router) {
+	r.Get("/totp", h.totpLogin)
+	r.Post("/totp", h.totpLogin)
+
+	r.Get("/oidc", h.oidcLogin)
+	r.Get("/oidc/callback", h.oidcCallback)
+
+	r.With(
+		withPasswordLogin,
+		s.WithPermission("email", "send"),
+	).Route("/recover", func(r chi.Router) {
 		r.Get("/", h.recover)
 		r.Post("/", h.recover)
 		r.Get("/{code}", h.recover)
@@ -75,38 +91,74 @@ func (h *authHandler) login(w http.ResponseWriter, r *http.Request) {
 		forms.Bind(f, r)
 
 		if f.IsValid() {
-			user := checkUser(f)
+			user, err := CheckUser(r, f)
 			if user != nil {
-				// User is authenticated, let's carry on
-				sess := h.srv.GetSession(r)
-				sess.Payload.User = user.ID
-				sess.Payload.Seed = user.Seed
-				sess.Save(w, r)
-
-				// Renew CSRF token
-				h.srv.RenewCsrf(w, r)
+				// User is authenticated, let's carry on, unless
+				// a second factor is needed.
+				totp, err := needsTOTP(user)
+				if err != nil {
+					h.srv.Error(w, r, err)
+					return
+				}
+				if totp {
+					if err = h.totpState.Save(w, r, &totpState{
+						ID:       base58.NewUUID(),
+						User:     user.ID,
+						Seed:     user.Seed,
+						Redirect: f.Get("redirect").String(),
+					}); err != nil {
+						h.srv.Error(w, r, err)
+						return
+					}
+					h.srv.Redirect(w, r, "/login/totp")
+					return
+				}
 
 				// Get redirection from a form "redirect" parameter
-				// Since it goes to Redirect(), it will be sanitized there
-				// and can only stay within the app.
-				redir := f.Get("redirect").String()
-				if redir == "" || strings.HasPrefix(redir, "/login") {
-					redir = "/"
+				if err := h.setUser(w, r, user); err != nil {
+					h.srv.Error(w, r, err)
+					return
 				}
-
-				h.srv.Redirect(w, r, redir)
+				http.Redirect(w, r, h.loginRedirect(r, f.Get("redirect").String()), http.StatusSeeOther)
 				return
 			}
 			// we must set the content type to avoid the
 			// error middleware interception.
 			w.Header().Set("content-type", "text/html; charset=utf-8")
-			w.WriteHeader(http.StatusUnauthorized)
+			var throttle *ThrottleError
+			if errors.As(err, &throttle) {
+				throttle.SetHeader(w)
+				w.WriteHeader(http.StatusTooManyRequests)
+			} else {
+				w.WriteHeader(http.StatusUnauthorized)
+			}
 		}
 		w.WriteHeader(http.StatusUnprocessableEntity)
 	}
 
-	h.srv.RenderTemplate(w, r, http.StatusOK, "/auth/login", server.TC{
-		"Form": f,
+	h.srv.RenderTemplate(w, r, http.StatusOK, "/auth/login", h.loginContext(f))
+}
+
+// loginContext returns the login page's template context.
+func (h *authHandler) loginContext(f *loginForm) server.TC {
+	return server.TC{
+		"Form":          f,
+		"PasswordLogin": configs.Config.Auth.PasswordLogin,
+		"OIDC":          oidc.Enabled(),
+		"OIDCName":      oidc.Name(),
+		"Registration":  registration.IsOpen(),
+	}
+}
+
+// withPasswordLogin is a middleware that disables a route
+// when password login is disabled.
+func withPasswordLogin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !configs.Config.Auth.PasswordLogin {
+			http.NotFound(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
 	})
 }
 
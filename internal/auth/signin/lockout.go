@@ -0,0 +1,177 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package signin
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"codeberg.org/readeck/readeck/configs"
+	"codeberg.org/readeck/readeck/internal/auth/users"
+	"codeberg.org/readeck/readeck/internal/bus"
+)
+
+const (
+	// userFreeAttempts and ipFreeAttempts are the number of failed
+	// attempts before the next ones are delayed.
+	userFreeAttempts = 3
+	ipFreeAttempts   = 10
+
+	// maxDelay is the longest delay between two attempts, before
+	// the lockout.
+	maxDelay = 30 * time.Second
+)
+
+func init() {
+	prometheus.MustRegister(metricLoginFailures)
+	prometheus.MustRegister(metricLockouts)
+}
+
+var metricLoginFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name:      "login_failures_total",
+	Namespace: "readeck",
+	Help:      "Total of failed sign in attempts partitioned by reason",
+}, []string{"reason"})
+
+var metricLockouts = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name:      "login_lockouts_total",
+	Namespace: "readeck",
+	Help:      "Total of sign in lockouts partitioned by kind",
+}, []string{"kind"})
+
+// ThrottleError is returned when a sign in attempt is rejected
+// because of too many failed attempts.
+type ThrottleError struct {
+	RetryAfter time.Duration
+}
+
+func (e *ThrottleError) Error() string {
+	return errTooManyAttempts.Error()
+}
+
+// SetHeader sets the Retry-After header of a response.
+func (e *ThrottleError) SetHeader(w http.ResponseWriter) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(e.RetryAfter.Round(time.Second).Seconds())))
+}
+
+// Attempts is the failed sign in attempts of a user or a client address.
+// It's kept in the store until the lockout duration passed after the
+// last failure.
+type Attempts struct {
+	key   string
+	kind  string
+	free  int
+	limit int
+	Count int       `json:"c"`
+	Last  time.Time `json:"l"`
+}
+
+func loadAttempts(a *Attempts) *Attempts {
+	if data := bus.Store().Get(a.key); data != "" {
+		if err := json.Unmarshal([]byte(data), a); err != nil {
+			slog.Error("login attempts", slog.Any("err", err))
+		}
+	}
+	return a
+}
+
+// UserAttempts returns the failed sign in attempts of a user.
+func UserAttempts(u *users.User) *Attempts {
+	return loadAttempts(&Attempts{
+		key:   "login:user:" + strconv.Itoa(u.ID),
+		kind:  "user",
+		free:  userFreeAttempts,
+		limit: configs.Config.Auth.Lockout.MaxAttempts,
+	})
+}
+
+// ipAttempts returns the failed sign in attempts of the request's
+// client. The server sets the request's remote address to the
+// client's address when it comes through a trusted proxy.
+func ipAttempts(r *http.Request) *Attempts {
+	return loadAttempts(&Attempts{
+		key:   "login:ip:" + r.RemoteAddr,
+		kind:  "ip",
+		free:  ipFreeAttempts,
+		limit: configs.Config.Auth.Lockout.MaxIPAttempts,
+	})
+}
+
+func lockoutDuration() time.Duration {
+	return time.Duration(configs.Config.Auth.Lockout.Duration) * time.Minute
+}
+
+// IsLocked returns true when the limit of failed attempts was reached.
+func (a *Attempts) IsLocked() bool {
+	return a.limit > 0 && a.Count >= a.limit && time.Now().Before(a.LockedUntil())
+}
+
+// LockedUntil returns the end of the lockout.
+func (a *Attempts) LockedUntil() time.Time {
+	return a.Last.Add(lockoutDuration())
+}
+
+// retryAfter returns how long the client must wait before its next
+// attempt. The delay doubles with each failed attempt, until the
+// limit locks the sign in for the lockout duration.
+func (a *Attempts) retryAfter() time.Duration {
+	var d time.Duration
+	switch {
+	case !configs.Config.Auth.Lockout.Enabled:
+		return 0
+	case a.limit > 0 && a.Count >= a.limit:
+		d = lockoutDuration()
+	case a.Count >= a.free:
+		d = min(time.Second<<min(a.Count-a.free, 16), maxDelay)
+	default:
+		return 0
+	}
+	return max(time.Until(a.Last.Add(d)), 0)
+}
+
+// check returns a [*ThrottleError] when the client must wait
+// before a new attempt.
+func (a *Attempts) check() error {
+	if d := a.retryAfter(); d > 0 {
+		reason := "throttled"
+		if a.IsLocked() {
+			reason = "locked"
+		}
+		metricLoginFailures.WithLabelValues(reason).Inc()
+		return &ThrottleError{RetryAfter: d}
+	}
+	return nil
+}
+
+// fail records a failed attempt.
+func (a *Attempts) fail() {
+	a.Count++
+	a.Last = time.Now()
+	if a.limit > 0 && a.Count == a.limit {
+		metricLockouts.WithLabelValues(a.kind).Inc()
+	}
+
+	data, err := json.Marshal(a)
+	if err == nil {
+		err = bus.Store().Set(a.key, string(data), lockoutDuration()+maxDelay)
+	}
+	if err != nil {
+		slog.Error("login attempts", slog.Any("err", err))
+	}
+}
+
+// Reset removes the failed attempts, which unlocks the sign in.
+func (a *Attempts) Reset() error {
+	if a.Count == 0 {
+		return nil
+	}
+	a.Count = 0
+	return bus.Store().Del(a.key)
+}
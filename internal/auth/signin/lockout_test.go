@@ -0,0 +1,120 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package signin_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"codeberg.org/readeck/readeck/configs"
+	"codeberg.org/readeck/readeck/internal/bus"
+	. "codeberg.org/readeck/readeck/internal/testing" //revive:disable:dot-imports
+)
+
+func TestLockout(t *testing.T) {
+	app := NewTestApp(t)
+	defer app.Close(t)
+
+	defaults := configs.Config.Auth
+	defer func() {
+		configs.Config.Auth = defaults
+	}()
+	configs.Config.Auth.Lockout.MaxAttempts = 5
+
+	client := NewClient(t, app)
+	user := app.Users["user"]
+	userKey := fmt.Sprintf("login:user:%d", user.User.ID)
+
+	auth := func(password string) *Response {
+		return client.RequestJSON("POST", "/api/auth", map[string]string{
+			"application": "test",
+			"username":    "user",
+			"password":    password,
+		})
+	}
+
+	// setAttempts sets the failed attempts of a user, as if
+	// the last one happened at the given time.
+	setAttempts := func(t *testing.T, key string, count int, last time.Time) {
+		data, _ := json.Marshal(map[string]any{"c": count, "l": last})
+		require.NoError(t, bus.Store().Set(key, string(data), time.Hour))
+	}
+
+	t.Run("progressive delay", func(t *testing.T) {
+		for range 3 {
+			auth("nope").AssertStatus(t, 403)
+		}
+
+		// The next attempt must wait, even with the right password.
+		rsp := auth(user.Password())
+		rsp.AssertStatus(t, 429)
+		rsp.AssertJQ(t, ".message", "Too many failed attempts, please try again later")
+		require.Equal(t, "1", rsp.Header.Get("Retry-After"))
+
+		setAttempts(t, userKey, 3, time.Now().Add(-2*time.Second))
+		auth(user.Password()).AssertStatus(t, 201)
+
+		// A successful sign in resets the attempts.
+		require.Empty(t, bus.Store().Get(userKey))
+	})
+
+	t.Run("lockout", func(t *testing.T) {
+		setAttempts(t, userKey, 4, time.Now().Add(-time.Minute))
+		auth("nope").AssertStatus(t, 403)
+
+		rsp := auth(user.Password())
+		rsp.AssertStatus(t, 429)
+		require.Equal(t, "900", rsp.Header.Get("Retry-After"))
+
+		// The login form shows the error too.
+		client.Get("/login")
+		rsp = client.PostForm("/login", url.Values{
+			"username": {"user"},
+			"password": {user.Password()},
+		})
+		rsp.AssertStatus(t, 429)
+		require.Contains(t, string(rsp.Body), "Too many failed attempts")
+
+		// The lockout ends after its duration.
+		setAttempts(t, userKey, 5, time.Now().Add(-16*time.Minute))
+		auth(user.Password()).AssertStatus(t, 201)
+	})
+
+	t.Run("client address", func(t *testing.T) {
+		// The test client's address
+		require.NoError(t, bus.Store().Del("login:ip:192.0.2.1"))
+		for range 10 {
+			client.RequestJSON("POST", "/api/auth", map[string]string{
+				"application": "test",
+				"username":    "unknown",
+				"password":    "nope",
+			}).AssertStatus(t, 403)
+		}
+
+		// Other users can't sign in from the same address.
+		rsp := client.RequestJSON("POST", "/api/auth", map[string]string{
+			"application": "test",
+			"username":    "staff",
+			"password":    "staff",
+		})
+		rsp.AssertStatus(t, 429)
+		require.Empty(t, bus.Store().Get(fmt.Sprintf("login:user:%d", app.Users["staff"].User.ID)))
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		configs.Config.Auth.Lockout.Enabled = false
+		defer func() {
+			configs.Config.Auth.Lockout.Enabled = true
+		}()
+
+		setAttempts(t, userKey, 5, time.Now())
+		auth(user.Password()).AssertStatus(t, 201)
+	})
+}
@@ -0,0 +1,155 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package signin
+
+import (
+	"crypto/subtle"
+	"errors"
+	"log/slog"
+	"net/http"
+	"path"
+
+	"github.com/doug-martin/goqu/v9"
+
+	"codeberg.org/readeck/readeck/configs"
+	"codeberg.org/readeck/readeck/internal/auth/oidc"
+	"codeberg.org/readeck/readeck/internal/auth/users"
+	"codeberg.org/readeck/readeck/internal/server"
+	"codeberg.org/readeck/readeck/pkg/forms"
+	"codeberg.org/readeck/readeck/pkg/http/securecookie"
+)
+
+var (
+	errOIDCFailed   = forms.Gettext("Sign in with your account provider failed")
+	errOIDCNoAccess = forms.Gettext("Your account doesn't give access to Readeck")
+	errOIDCLinked   = forms.Gettext("This account is already linked to another user")
+)
+
+// oidcState is the content of the cookie holding the authorization
+// request during the sign in.
+type oidcState struct {
+	oidc.AuthRequest
+	Redirect string `json:"r"`
+}
+
+func newOIDCStateHandler(s *server.Server) *securecookie.Handler {
+	return securecookie.NewHandler(
+		securecookie.Key(configs.Keys.LoginKey()),
+		securecookie.WithName("oidc"),
+		securecookie.WithPath(path.Join(s.BasePath, "/login/oidc")),
+		securecookie.WithMaxAge(600),
+	)
+}
+
+// oidcRedirectURI returns the callback URL registered on the provider.
+func (h *authHandler) oidcRedirectURI(r *http.Request) string {
+	return h.srv.AbsoluteURL(r, "/login/oidc/callback").String()
+}
+
+// oidcLogin sends the user to the provider's authorization endpoint.
+func (h *authHandler) oidcLogin(w http.ResponseWriter, r *http.Request) {
+	if !oidc.Enabled() {
+		h.srv.Status(w, r, http.StatusNotFound)
+		return
+	}
+
+	req, err := oidc.NewAuthRequest(r.Context(), h.oidcRedirectURI(r))
+	if err != nil {
+		h.srv.Error(w, r, err)
+		return
+	}
+
+	state := oidcState{AuthRequest: *req, Redirect: r.URL.Query().Get("r")}
+	if err = h.oidcState.Save(w, r, &state); err != nil {
+		h.srv.Error(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, req.URL, http.StatusSeeOther)
+}
+
+// oidcCallback receives the authorization code from the provider, signs
+// the matching user in and redirects to the page that was initially
+// requested.
+func (h *authHandler) oidcCallback(w http.ResponseWriter, r *http.Request) {
+	if !oidc.Enabled() {
+		h.srv.Status(w, r, http.StatusNotFound)
+		return
+	}
+
+	state := oidcState{}
+	err := h.oidcState.Load(r, &state)
+	h.oidcState.Delete(w, r)
+	if err != nil {
+		h.srv.Log(r).Warn("oidc state", slog.Any("err", err))
+		h.oidcError(w, r, http.StatusBadRequest, errOIDCFailed)
+		return
+	}
+
+	q := r.URL.Query()
+	if subtle.ConstantTimeCompare([]byte(q.Get("state")), []byte(state.State)) != 1 {
+		h.srv.Log(r).Warn("oidc state mismatch")
+		h.oidcError(w, r, http.StatusBadRequest, errOIDCFailed)
+		return
+	}
+	if q.Get("error") != "" {
+		h.srv.Log(r).Warn("oidc provider error",
+			slog.String("error", q.Get("error")),
+			slog.String("description", q.Get("error_description")),
+		)
+		h.oidcError(w, r, http.StatusUnauthorized, errOIDCFailed)
+		return
+	}
+
+	claims, err := oidc.Exchange(r.Context(), &state.AuthRequest, q.Get("code"), h.oidcRedirectURI(r))
+	if err != nil {
+		h.srv.Log(r).Error("oidc code exchange", slog.Any("err", err))
+		h.oidcError(w, r, http.StatusUnauthorized, errOIDCFailed)
+		return
+	}
+
+	sess := h.srv.GetSession(r)
+
+	// A signed in user links the account to its own.
+	var current *users.User
+	if !sess.IsNew && sess.Payload.User != 0 {
+		if u, err := users.Users.GetOne(goqu.C("id").Eq(sess.Payload.User)); err == nil && u.Seed == sess.Payload.Seed {
+			if _, err = sess.Record(r); err == nil {
+				current = u
+			}
+		}
+	}
+
+	user, err := oidc.SigninUser(current, claims)
+	switch {
+	case errors.Is(err, oidc.ErrNoAccount), errors.Is(err, oidc.ErrNoGroup):
+		h.srv.Log(r).Warn("oidc access denied",
+			slog.String("sub", claims.String("sub")),
+			slog.Any("err", err),
+		)
+		h.oidcError(w, r, http.StatusForbidden, errOIDCNoAccess)
+		return
+	case errors.Is(err, oidc.ErrAlreadyLinked):
+		h.oidcError(w, r, http.StatusConflict, errOIDCLinked)
+		return
+	case err != nil:
+		h.srv.Error(w, r, err)
+		return
+	}
+
+	if err = h.setUser(w, r, user); err != nil {
+		h.srv.Error(w, r, err)
+		return
+	}
+	http.Redirect(w, r, h.loginRedirect(r, state.Redirect), http.StatusSeeOther)
+}
+
+// oidcError renders the login page with an error.
+func (h *authHandler) oidcError(w http.ResponseWriter, r *http.Request, status int, err error) {
+	f := newLoginForm(h.srv.Locale(r))
+	f.Bind()
+	f.AddErrors("", err)
+	h.srv.RenderTemplate(w, r, status, "/auth/login", h.loginContext(f))
+}
@@ -0,0 +1,324 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package signin_test
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/stretchr/testify/require"
+
+	"codeberg.org/readeck/readeck/configs"
+	"codeberg.org/readeck/readeck/internal/auth/users"
+	. "codeberg.org/readeck/readeck/internal/testing" //revive:disable:dot-imports
+)
+
+// testProvider is a minimal OpenID Connect provider. It issues
+// an ID token with its claims for any authorization code.
+type testProvider struct {
+	*httptest.Server
+	claims    map[string]any
+	nonce     string
+	challenge string
+}
+
+func newTestProvider(t *testing.T) *testProvider {
+	p := &testProvider{}
+	mux := http.NewServeMux()
+	p.Server = httptest.NewServer(mux)
+
+	writeJSON := func(w http.ResponseWriter, data any) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(data) //nolint:errcheck
+	}
+
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, map[string]any{
+			"issuer":                 p.URL,
+			"authorization_endpoint": p.URL + "/authorize",
+			"token_endpoint":         p.URL + "/token",
+			"userinfo_endpoint":      p.URL + "/userinfo",
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		clientID, secret, _ := r.BasicAuth()
+		require.Equal(t, "readeck", clientID)
+		require.Equal(t, "secret", secret)
+		require.Equal(t, "authorization_code", r.Form.Get("grant_type"))
+
+		verifier := sha256.Sum256([]byte(r.Form.Get("code_verifier")))
+		if base64.RawURLEncoding.EncodeToString(verifier[:]) != p.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			writeJSON(w, map[string]string{"error": "invalid_grant"})
+			return
+		}
+
+		claims := map[string]any{
+			"iss":   p.URL,
+			"aud":   "readeck",
+			"exp":   time.Now().Add(time.Minute).Unix(),
+			"nonce": p.nonce,
+			"sub":   p.claims["sub"],
+		}
+		payload, _ := json.Marshal(claims)
+		writeJSON(w, map[string]any{
+			"access_token": "access",
+			"token_type":   "Bearer",
+			"id_token": "eyJhbGciOiJub25lIn0." +
+				base64.RawURLEncoding.EncodeToString(payload) + ".",
+		})
+	})
+	mux.HandleFunc("/userinfo", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "Bearer access", r.Header.Get("Authorization"))
+		writeJSON(w, p.claims)
+	})
+
+	return p
+}
+
+// signin performs the authorization code flow with the given claims.
+func (p *testProvider) signin(t *testing.T, client *Client, claims map[string]any) *Response {
+	rsp := client.Get("/login/oidc?r=/bookmarks")
+	rsp.AssertStatus(t, 303)
+
+	u, err := url.Parse(rsp.Redirect)
+	require.NoError(t, err)
+	require.Equal(t, p.URL+"/authorize", u.Scheme+"://"+u.Host+u.Path)
+
+	q := u.Query()
+	require.Equal(t, "code", q.Get("response_type"))
+	require.Equal(t, "readeck", q.Get("client_id"))
+	require.Equal(t, "http://readeck.example.org/login/oidc/callback", q.Get("redirect_uri"))
+	require.Equal(t, "S256", q.Get("code_challenge_method"))
+	require.Equal(t, "openid profile email", q.Get("scope"))
+
+	p.claims = claims
+	p.nonce = q.Get("nonce")
+	p.challenge = q.Get("code_challenge")
+
+	return client.Get("/login/oidc/callback?" + url.Values{
+		"code":  {"code"},
+		"state": {q.Get("state")},
+	}.Encode())
+}
+
+func TestOIDC(t *testing.T) {
+	app := NewTestApp(t)
+	defer app.Close(t)
+
+	p := newTestProvider(t)
+	defer p.Close()
+
+	defaults := configs.Config.Auth
+	defer func() {
+		configs.Config.Auth = defaults
+	}()
+
+	reset := func() {
+		configs.Config.Auth = defaults
+		configs.Config.Auth.OIDC.Issuer = p.URL
+		configs.Config.Auth.OIDC.ClientID = "readeck"
+		configs.Config.Auth.OIDC.ClientSecret = "secret"
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		client := NewClient(t, app)
+		rsp := client.Get("/login")
+		rsp.AssertStatus(t, 200)
+		require.NotContains(t, string(rsp.Body), "/login/oidc")
+
+		client.Get("/login/oidc").AssertStatus(t, 404)
+		client.Get("/login/oidc/callback").AssertStatus(t, 404)
+	})
+
+	t.Run("provisioning", func(t *testing.T) {
+		reset()
+		client := NewClient(t, app)
+
+		rsp := client.Get("/login")
+		rsp.AssertStatus(t, 200)
+		require.Contains(t, string(rsp.Body), "Sign in with OpenID Connect")
+
+		rsp = p.signin(t, client, map[string]any{
+			"sub":                "new-user",
+			"email":              "oidc@localhost",
+			"email_verified":     true,
+			"preferred_username": "oidc user",
+		})
+		rsp.AssertStatus(t, 303)
+		rsp.AssertRedirect(t, "^/bookmarks$")
+		client.Get("/profile").AssertStatus(t, 200)
+
+		u, err := users.Users.GetOne(goqu.C("email").Eq("oidc@localhost"))
+		require.NoError(t, err)
+		require.Equal(t, "oidc-user", u.Username)
+		require.Equal(t, "user", u.Group)
+
+		// Next sign in uses the same user
+		client = NewClient(t, app)
+		rsp = p.signin(t, client, map[string]any{
+			"sub":   "new-user",
+			"email": "changed@localhost",
+		})
+		rsp.AssertStatus(t, 303)
+		count, err := users.Users.Query().Where(goqu.C("username").Like("oidc-user%")).Count()
+		require.NoError(t, err)
+		require.Equal(t, int64(1), count)
+	})
+
+	t.Run("link by email", func(t *testing.T) {
+		reset()
+		client := NewClient(t, app)
+
+		// Unverified emails are never linked
+		configs.Config.Auth.OIDC.AutoCreate = false
+		rsp := p.signin(t, client, map[string]any{
+			"sub":   "user",
+			"email": "user@localhost",
+		})
+		rsp.AssertStatus(t, 403)
+		require.Contains(t, string(rsp.Body), "Your account doesn&#39;t give access to Readeck")
+
+		rsp = p.signin(t, client, map[string]any{
+			"sub":            "user",
+			"email":          "user@localhost",
+			"email_verified": true,
+		})
+		rsp.AssertStatus(t, 303)
+
+		_, u, err := users.Identities.GetUser(p.URL, "user")
+		require.NoError(t, err)
+		require.Equal(t, app.Users["user"].User.ID, u.ID)
+	})
+
+	t.Run("link current user", func(t *testing.T) {
+		reset()
+		configs.Config.Auth.OIDC.LinkByEmail = false
+		configs.Config.Auth.OIDC.AutoCreate = false
+
+		client := NewClient(t, app)
+		client.Login("staff", app.Users["staff"].Password())
+		rsp := p.signin(t, client, map[string]any{
+			"sub":   "staff",
+			"email": "someone@example.org",
+		})
+		rsp.AssertStatus(t, 303)
+
+		_, u, err := users.Identities.GetUser(p.URL, "staff")
+		require.NoError(t, err)
+		require.Equal(t, app.Users["staff"].User.ID, u.ID)
+
+		// The account can't be linked to another user
+		client = NewClient(t, app)
+		client.Login("admin", app.Users["admin"].Password())
+		rsp = p.signin(t, client, map[string]any{"sub": "staff"})
+		rsp.AssertStatus(t, 409)
+	})
+
+	t.Run("groups", func(t *testing.T) {
+		reset()
+		configs.Config.Auth.OIDC.Groups.Staff = []string{"editors"}
+		configs.Config.Auth.OIDC.DefaultGroup = "none"
+
+		client := NewClient(t, app)
+		rsp := p.signin(t, client, map[string]any{
+			"sub":            "groups",
+			"email":          "groups@localhost",
+			"email_verified": true,
+			"groups":         []string{"readers"},
+		})
+		rsp.AssertStatus(t, 403)
+
+		rsp = p.signin(t, client, map[string]any{
+			"sub":            "groups",
+			"email":          "groups@localhost",
+			"email_verified": true,
+			"groups":         []string{"readers", "editors"},
+		})
+		rsp.AssertStatus(t, 303)
+
+		_, u, err := users.Identities.GetUser(p.URL, "groups")
+		require.NoError(t, err)
+		require.Equal(t, "staff", u.Group)
+
+		// The group follows the provider's groups
+		configs.Config.Auth.OIDC.DefaultGroup = "user"
+		client = NewClient(t, app)
+		rsp = p.signin(t, client, map[string]any{
+			"sub":    "groups",
+			"groups": []string{"readers"},
+		})
+		rsp.AssertStatus(t, 303)
+
+		u, err = users.Users.GetOne(goqu.C("id").Eq(u.ID))
+		require.NoError(t, err)
+		require.Equal(t, "user", u.Group)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		reset()
+		client := NewClient(t, app)
+
+		rsp := client.Get("/login/oidc/callback?code=code&state=nope")
+		rsp.AssertStatus(t, 400)
+
+		rsp = client.Get("/login/oidc")
+		rsp.AssertStatus(t, 303)
+		rsp = client.Get("/login/oidc/callback?code=code&state=nope")
+		rsp.AssertStatus(t, 400)
+
+		rsp = client.Get("/login/oidc")
+		u, _ := url.Parse(rsp.Redirect)
+		rsp = client.Get("/login/oidc/callback?" + url.Values{
+			"error": {"access_denied"},
+			"state": {u.Query().Get("state")},
+		}.Encode())
+		rsp.AssertStatus(t, 401)
+
+		// Wrong PKCE verifier
+		rsp = client.Get("/login/oidc")
+		u, _ = url.Parse(rsp.Redirect)
+		p.challenge = "nope"
+		rsp = client.Get("/login/oidc/callback?" + url.Values{
+			"code":  {"code"},
+			"state": {u.Query().Get("state")},
+		}.Encode())
+		rsp.AssertStatus(t, 401)
+	})
+
+	t.Run("password login disabled", func(t *testing.T) {
+		reset()
+		configs.Config.Auth.PasswordLogin = false
+		client := NewClient(t, app)
+
+		rsp := client.Get("/login")
+		rsp.AssertStatus(t, 200)
+		require.NotContains(t, string(rsp.Body), `name="password"`)
+		require.Contains(t, string(rsp.Body), "/login/oidc")
+
+		rsp = client.PostForm("/login", url.Values{
+			"username": {"user"},
+			"password": {app.Users["user"].Password()},
+		})
+		rsp.AssertStatus(t, 401)
+
+		client.Get("/login/recover").AssertStatus(t, 404)
+
+		rsp = client.RequestJSON("POST", "/api/auth", map[string]string{
+			"username":    "user",
+			"password":    app.Users["user"].Password(),
+			"application": "test",
+		})
+		rsp.AssertStatus(t, 403)
+	})
+}
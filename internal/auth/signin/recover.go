@@ -21,6 +21,7 @@ import (
 	"codeberg.org/readeck/readeck/internal/bus"
 	"codeberg.org/readeck/readeck/internal/email"
 	"codeberg.org/readeck/readeck/internal/server"
+	"codeberg.org/readeck/readeck/internal/sessions"
 	"codeberg.org/readeck/readeck/pkg/base58"
 	"codeberg.org/readeck/readeck/pkg/forms"
 )
@@ -190,6 +191,9 @@ func (h *authHandler) recover(w http.ResponseWriter, r *http.Request) {
 		if err = user.Save(); err != nil {
 			return
 		}
+		if err = sessions.Records.DeleteUser(user.ID); err != nil {
+			return
+		}
 
 		if err = f.delCode(recoverCode); err != nil {
 			return
@@ -0,0 +1,203 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package signin
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+
+	"codeberg.org/readeck/readeck/configs"
+	"codeberg.org/readeck/readeck/internal/auth/users"
+	"codeberg.org/readeck/readeck/internal/bus"
+	"codeberg.org/readeck/readeck/internal/server"
+	"codeberg.org/readeck/readeck/pkg/forms"
+	"codeberg.org/readeck/readeck/pkg/http/securecookie"
+)
+
+// totpStateMaxAge is the lifetime of the second step, in seconds.
+const totpStateMaxAge = 300
+
+// maxTOTPAttempts is the number of wrong codes after which the
+// user must sign in again with a password.
+const maxTOTPAttempts = 5
+
+// totpState is the content of the cookie holding a user that gave
+// a valid password and must now give a second factor.
+type totpState struct {
+	ID       string `json:"i"`
+	User     int    `json:"u"`
+	Seed     int    `json:"s"`
+	Redirect string `json:"r"`
+}
+
+// failures returns the number of wrong codes given with the state.
+// It's kept in the store, so a copy of the cookie can't reset it.
+func (s totpState) failures() int {
+	n, _ := strconv.Atoi(bus.Store().Get("login:totp:" + s.ID))
+	return n
+}
+
+// fail records a wrong code and returns the number of wrong codes
+// given with the state.
+func (s totpState) fail() int {
+	n := s.failures() + 1
+	if err := bus.Store().Set("login:totp:"+s.ID, strconv.Itoa(n), totpStateMaxAge*time.Second); err != nil {
+		slog.Error("login attempts", slog.Any("err", err))
+	}
+	return n
+}
+
+func newTOTPStateHandler(s *server.Server) *securecookie.Handler {
+	return securecookie.NewHandler(
+		securecookie.Key(configs.Keys.LoginKey()),
+		securecookie.WithName("totp"),
+		securecookie.WithPath(path.Join(s.BasePath, "/login/totp")),
+		securecookie.WithMaxAge(totpStateMaxAge),
+	)
+}
+
+// needsTOTP returns true when a user must give a second factor
+// after its password.
+func needsTOTP(user *users.User) (bool, error) {
+	if user.TOTPRequired() {
+		return true, nil
+	}
+	return users.Authenticators.IsEnabled(user)
+}
+
+// setUser starts the user's session and renews the CSRF token.
+func (h *authHandler) setUser(w http.ResponseWriter, r *http.Request, user *users.User) error {
+	sess := h.srv.GetSession(r)
+	if err := sess.Start(r, user.ID, user.Seed); err != nil {
+		return err
+	}
+	sess.Save(w, r)
+
+	// Renew CSRF token
+	h.srv.RenewCsrf(w, r)
+	return nil
+}
+
+// loginRedirect returns the URL of the page that was initially
+// requested. Since it goes through AbsoluteURL(), it can only stay
+// within the app.
+func (h *authHandler) loginRedirect(r *http.Request, redir string) string {
+	if redir == "" || strings.HasPrefix(redir, "/login") {
+		redir = "/"
+	}
+	return h.srv.AbsoluteURL(r, redir).String()
+}
+
+// totpLogin is the second step of the sign in, for users with
+// two-factor authentication. A user whose group requires it, and
+// that didn't set it up yet, enrolls an authenticator there.
+func (h *authHandler) totpLogin(w http.ResponseWriter, r *http.Request) {
+	state := totpState{}
+	if err := h.totpState.Load(r, &state); err != nil || state.ID == "" || state.failures() >= maxTOTPAttempts {
+		h.totpState.Delete(w, r)
+		h.srv.Redirect(w, r, "/login")
+		return
+	}
+
+	user, err := users.Users.GetOne(goqu.C("id").Eq(state.User))
+	if err != nil || user.Seed != state.Seed {
+		h.totpState.Delete(w, r)
+		h.srv.Redirect(w, r, "/login")
+		return
+	}
+
+	// A pending authenticator gets a new secret each time the page
+	// is displayed.
+	a, err := users.Authenticators.ForUser(user)
+	if errors.Is(err, users.ErrNotFound) || (err == nil && !a.IsEnabled() && r.Method == http.MethodGet) {
+		a, err = users.Authenticators.Enroll(user)
+	}
+	if err != nil {
+		h.srv.Error(w, r, err)
+		return
+	}
+
+	f := newTOTPForm(h.srv.Locale(r))
+	tc := server.TC{"Form": f}
+	if !a.IsEnabled() {
+		if tc["URL"], err = a.URL(user); err != nil {
+			h.srv.Error(w, r, err)
+			return
+		}
+		if tc["Secret"], err = a.EncodedSecret(); err != nil {
+			h.srv.Error(w, r, err)
+			return
+		}
+	}
+
+	if r.Method == http.MethodPost {
+		forms.Bind(f, r)
+		status := http.StatusUnprocessableEntity
+		if f.IsValid() {
+			ok, err := verifyTOTP(r, user, a, f.Get("code").String())
+			var throttle *ThrottleError
+			switch {
+			case errors.As(err, &throttle):
+				throttle.SetHeader(w)
+				status = http.StatusTooManyRequests
+				f.AddErrors("", errTooManyAttempts)
+			case err != nil:
+				h.srv.Error(w, r, err)
+				return
+			case !ok:
+				h.srv.Log(r).Warn("invalid totp code", slog.Int("user", user.ID))
+				// Too many wrong codes, the user must sign in again.
+				if state.fail() >= maxTOTPAttempts {
+					h.totpState.Delete(w, r)
+					h.srv.Redirect(w, r, "/login")
+					return
+				}
+				f.AddErrors("code", errInvalidTOTP)
+			}
+		}
+
+		if !f.IsValid() {
+			h.srv.RenderTemplate(w, r, status, "/auth/totp", tc)
+			return
+		}
+
+		h.totpState.Delete(w, r)
+
+		if a.IsEnabled() {
+			if err = h.setUser(w, r, user); err != nil {
+				h.srv.Error(w, r, err)
+				return
+			}
+			http.Redirect(w, r, h.loginRedirect(r, state.Redirect), http.StatusSeeOther)
+			return
+		}
+
+		// A new authenticator shows its recovery codes
+		// before going to the requested page.
+		codes, err := a.Enable()
+		if err != nil {
+			h.srv.Error(w, r, err)
+			return
+		}
+		if err = h.setUser(w, r, user); err != nil {
+			h.srv.Error(w, r, err)
+			return
+		}
+		h.srv.RenderTemplate(w, r, http.StatusOK, "/auth/totp", server.TC{
+			"RecoveryCodes": codes,
+			"Redirect":      h.loginRedirect(r, state.Redirect),
+		})
+		return
+	}
+
+	h.srv.RenderTemplate(w, r, http.StatusOK, "/auth/totp", tc)
+}
@@ -0,0 +1,245 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package signin_test
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"codeberg.org/readeck/readeck/configs"
+	"codeberg.org/readeck/readeck/internal/auth/signin"
+	"codeberg.org/readeck/readeck/internal/auth/users"
+	"codeberg.org/readeck/readeck/internal/bus"
+	. "codeberg.org/readeck/readeck/internal/testing" //revive:disable:dot-imports
+	"codeberg.org/readeck/readeck/pkg/totp"
+)
+
+func TestTOTP(t *testing.T) {
+	app := NewTestApp(t)
+	defer app.Close(t)
+
+	defaults := configs.Config.Auth
+	defer func() {
+		configs.Config.Auth = defaults
+	}()
+
+	user := app.Users["user"]
+
+	// resetAttempts removes the failed attempts of the user and
+	// the test client's address.
+	resetAttempts := func(t *testing.T) {
+		require.NoError(t, signin.UserAttempts(user.User).Reset())
+		require.NoError(t, bus.Store().Del("login:ip:192.0.2.1"))
+	}
+
+	// Each code is accepted only once, so the tests use
+	// the steps around the current one in order.
+	step := totp.Step(time.Now()) - 1
+	nextCode := func(t *testing.T, a *users.Authenticator) string {
+		key, err := a.Key()
+		require.NoError(t, err)
+		code := totp.Code(key, step)
+		step++
+		return code
+	}
+
+	a, err := users.Authenticators.Enroll(user.User)
+	require.NoError(t, err)
+	recovery, err := a.Enable()
+	require.NoError(t, err)
+	require.Len(t, recovery, 10)
+
+	// login signs in with a password and loads the second step's
+	// page, for its CSRF token.
+	login := func(client *Client) *Response {
+		client.Get("/login")
+		rsp := client.PostForm("/login", url.Values{
+			"username": {"user"},
+			"password": {user.Password()},
+			"redirect": {"/bookmarks"},
+		})
+		client.Get("/login/totp")
+		return rsp
+	}
+
+	t.Run("login", func(t *testing.T) {
+		client := NewClient(t, app)
+		rsp := login(client)
+		rsp.AssertStatus(t, 303)
+		rsp.AssertRedirect(t, "^/login/totp$")
+
+		rsp = client.Get("/login/totp")
+		rsp.AssertStatus(t, 200)
+		require.Contains(t, string(rsp.Body), `name="code"`)
+
+		// Not signed in yet
+		client.Get("/profile").AssertStatus(t, 303)
+		client.Get("/login/totp")
+
+		rsp = client.PostForm("/login/totp", url.Values{"code": {"000000"}})
+		rsp.AssertStatus(t, 422)
+
+		code := nextCode(t, a)
+		rsp = client.PostForm("/login/totp", url.Values{"code": {code}})
+		rsp.AssertStatus(t, 303)
+		rsp.AssertRedirect(t, "^/bookmarks$")
+		client.Get("/profile").AssertStatus(t, 200)
+
+		// A code can't be used twice
+		client = NewClient(t, app)
+		login(client).AssertStatus(t, 303)
+		rsp = client.PostForm("/login/totp", url.Values{"code": {code}})
+		rsp.AssertStatus(t, 422)
+	})
+
+	t.Run("recovery code", func(t *testing.T) {
+		client := NewClient(t, app)
+		login(client).AssertStatus(t, 303)
+		rsp := client.PostForm("/login/totp", url.Values{"code": {recovery[0]}})
+		rsp.AssertStatus(t, 303)
+		client.Get("/profile").AssertStatus(t, 200)
+
+		client = NewClient(t, app)
+		login(client).AssertStatus(t, 303)
+		rsp = client.PostForm("/login/totp", url.Values{"code": {recovery[0]}})
+		rsp.AssertStatus(t, 422)
+	})
+
+	t.Run("wrong codes", func(t *testing.T) {
+		resetAttempts(t)
+		defer resetAttempts(t)
+		configs.Config.Auth.Lockout.Enabled = false
+
+		client := NewClient(t, app)
+		login(client).AssertStatus(t, 303)
+
+		// Each wrong code is a failed attempt for the user
+		for i := range 4 {
+			rsp := client.PostForm("/login/totp", url.Values{"code": {"000000"}})
+			rsp.AssertStatus(t, 422)
+			require.Equal(t, i+1, signin.UserAttempts(user.User).Count)
+		}
+
+		// The last one ends the second step
+		rsp := client.PostForm("/login/totp", url.Values{"code": {"000000"}})
+		rsp.AssertStatus(t, 303)
+		rsp.AssertRedirect(t, "^/login$")
+		rsp = client.Get("/login/totp")
+		rsp.AssertRedirect(t, "^/login$")
+		require.Equal(t, 5, signin.UserAttempts(user.User).Count)
+	})
+
+	t.Run("throttled codes", func(t *testing.T) {
+		resetAttempts(t)
+		defer resetAttempts(t)
+		configs.Config.Auth.Lockout.Enabled = true
+
+		client := NewClient(t, app)
+		login(client).AssertStatus(t, 303)
+		for range 3 {
+			client.PostForm("/login/totp", url.Values{"code": {"000000"}}).AssertStatus(t, 422)
+		}
+
+		// The next code must wait, even when it's valid. It's not
+		// checked, so it can be used later.
+		key, err := a.Key()
+		require.NoError(t, err)
+		code := totp.Code(key, step)
+		rsp := client.PostForm("/login/totp", url.Values{"code": {code}})
+		rsp.AssertStatus(t, 429)
+		require.NotEmpty(t, rsp.Header.Get("Retry-After"))
+
+		// The API counts the wrong codes too
+		rsp = client.RequestJSON("POST", "/api/auth", map[string]string{
+			"username":    "user",
+			"password":    user.Password(),
+			"application": "test",
+			"totp":        code,
+		})
+		rsp.AssertStatus(t, 429)
+	})
+
+	t.Run("no state", func(t *testing.T) {
+		client := NewClient(t, app)
+		rsp := client.Get("/login/totp")
+		rsp.AssertStatus(t, 303)
+		rsp.AssertRedirect(t, "^/login$")
+	})
+
+	t.Run("api", func(t *testing.T) {
+		client := NewClient(t, app)
+		rsp := client.RequestJSON("POST", "/api/auth", map[string]string{
+			"username":    "user",
+			"password":    user.Password(),
+			"application": "test",
+		})
+		rsp.AssertStatus(t, 403)
+		rsp.AssertJQ(t, ".message", "A two-factor authentication code is required")
+
+		rsp = client.RequestJSON("POST", "/api/auth", map[string]string{
+			"username":    "user",
+			"password":    user.Password(),
+			"application": "test",
+			"totp":        "000000",
+		})
+		rsp.AssertStatus(t, 403)
+
+		rsp = client.RequestJSON("POST", "/api/auth", map[string]string{
+			"username":    "user",
+			"password":    user.Password(),
+			"application": "test",
+			"totp":        nextCode(t, a),
+		})
+		rsp.AssertStatus(t, 201)
+	})
+
+	t.Run("required", func(t *testing.T) {
+		configs.Config.Auth = defaults
+		configs.Config.Auth.TOTPRequiredGroups = []string{"staff"}
+
+		staff := app.Users["staff"]
+		client := NewClient(t, app)
+		client.Get("/login")
+		rsp := client.PostForm("/login", url.Values{
+			"username": {"staff"},
+			"password": {staff.Password()},
+		})
+		rsp.AssertStatus(t, 303)
+		rsp.AssertRedirect(t, "^/login/totp$")
+
+		// The API refuses a user that must set up an authenticator
+		rsp = client.RequestJSON("POST", "/api/auth", map[string]string{
+			"username":    "staff",
+			"password":    staff.Password(),
+			"application": "test",
+		})
+		rsp.AssertStatus(t, 403)
+
+		// Enrollment
+		rsp = client.Get("/login/totp")
+		rsp.AssertStatus(t, 200)
+		require.Contains(t, string(rsp.Body), "data:image/png;base64,")
+
+		sa, err := users.Authenticators.ForUser(staff.User)
+		require.NoError(t, err)
+		require.False(t, sa.IsEnabled())
+
+		key, err := sa.Key()
+		require.NoError(t, err)
+		rsp = client.PostForm("/login/totp", url.Values{
+			"code": {totp.Code(key, totp.Step(time.Now()))},
+		})
+		rsp.AssertStatus(t, 200)
+		require.Contains(t, string(rsp.Body), "recovery codes")
+		client.Get("/profile").AssertStatus(t, 200)
+
+		enabled, err := users.Authenticators.IsEnabled(staff.User)
+		require.NoError(t, err)
+		require.True(t, enabled)
+	})
+}
@@ -7,6 +7,8 @@
 package tokens
 
 import (
+	"database/sql/driver"
+	"encoding/json"
 	"errors"
 	"strings"
 	"time"
@@ -43,6 +45,56 @@ type Token struct {
 	IsEnabled   bool          `db:"is_enabled"`
 	Application string        `db:"application"`
 	Roles       types.Strings `db:"roles"`
+
+	// OAuth client that obtained the token, and the hash of
+	// the refresh token it can use to renew the token. The refresh
+	// token only works while the user's seed doesn't change.
+	ClientID     *int    `db:"client_id"`
+	RefreshToken *string `db:"refresh_token"`
+	UserSeed     *int    `db:"user_seed"`
+
+	// Restriction limits the bookmarks the token can access.
+	Restriction *Restriction `db:"restriction"`
+}
+
+// Restriction limits a token to a subset of the user's bookmarks:
+// the ones in a collection, with one of the labels and matching a
+// search query. Every condition that is set must match.
+type Restriction struct {
+	Collection string   `json:"collection,omitempty"`
+	Labels     []string `json:"labels,omitempty"`
+	Search     string   `json:"search,omitempty"`
+}
+
+// Scan loads a [Restriction] from a column.
+func (r *Restriction) Scan(value any) error {
+	if value == nil {
+		return nil
+	}
+
+	v, err := types.JSONBytes(value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(v, r)
+}
+
+// Value encodes a [Restriction] for storage.
+func (r *Restriction) Value() (driver.Value, error) {
+	if r.IsEmpty() {
+		return nil, nil
+	}
+
+	v, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+	return string(v), nil
+}
+
+// IsEmpty returns true when the restriction doesn't limit anything.
+func (r *Restriction) IsEmpty() bool {
+	return r == nil || (r.Collection == "" && len(r.Labels) == 0 && r.Search == "")
 }
 
 // Manager is a query helper for token entries.
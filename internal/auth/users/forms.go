@@ -80,7 +80,7 @@ func NewUserForm(tr forms.Translator) *UserForm {
 		forms.NewTextField("group",
 			forms.Trim,
 			forms.Default("user"),
-			forms.ChoicesPairs(availableGroups),
+			forms.ChoicesPairs(GroupChoices()),
 			hasUser().False(forms.Required),
 		),
 	)}
@@ -199,13 +199,27 @@ func (f *UserForm) UpdateUser(u *User) (res map[string]interface{}, err error) {
 	return
 }
 
-// NewRolesField returns a forms.Field with user's role choices.
-func NewRolesField(tr forms.Translator, user *User) forms.Field {
+// RoleChoices returns the roles that can restrict an API token's
+// access. When a user is given, only the roles it can access are
+// returned.
+func RoleChoices(tr forms.Translator, user *User) []forms.ValueChoice[string] {
 	availableScopes := []forms.ValueChoice[string]{
 		forms.Choice(tr.Gettext("Bookmarks : Read Only"), "scoped_bookmarks_r"),
 		forms.Choice(tr.Gettext("Bookmarks : Write Only"), "scoped_bookmarks_w"),
 		forms.Choice(tr.Gettext("Admin : Read Only"), "scoped_admin_r"),
 		forms.Choice(tr.Gettext("Admin : Write Only"), "scoped_admin_w"),
+		forms.Choice(tr.Gettext("Admin : SCIM Provisioning"), "scoped_scim"),
+	}
+
+	// Custom groups can restrict a token too
+	for _, g := range acls.Groups() {
+		name := g.Name
+		if g.Description != "" {
+			name += " : " + g.Description
+		}
+		availableScopes = append(availableScopes,
+			forms.Choice(tr.Gettext("Group : %s", name), g.Name),
+		)
 	}
 
 	// Only present policies that the current user can access
@@ -216,5 +230,10 @@ func NewRolesField(tr forms.Translator, user *User) forms.Field {
 		}
 	}
 
-	return forms.NewTextListField("roles", forms.Choices(choices...))
+	return choices
+}
+
+// NewRolesField returns a forms.Field with user's role choices.
+func NewRolesField(tr forms.Translator, user *User) forms.Field {
+	return forms.NewTextListField("roles", forms.Choices(RoleChoices(tr, user)...))
 }
@@ -0,0 +1,220 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package users
+
+import (
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+
+	"codeberg.org/readeck/readeck/configs"
+	"codeberg.org/readeck/readeck/internal/acls"
+	"codeberg.org/readeck/readeck/internal/db"
+	"codeberg.org/readeck/readeck/internal/db/types"
+	"codeberg.org/readeck/readeck/pkg/base58"
+)
+
+const (
+	// GroupTableName is the custom group table name in database.
+	GroupTableName = "user_group"
+)
+
+// Groups is the custom group manager.
+var Groups = GroupManager{}
+
+// Group is a custom group created from the admin area. The groups
+// defined in the configuration file aren't stored.
+type Group struct {
+	ID          int           `db:"id" goqu:"skipinsert,skipupdate"`
+	UID         string        `db:"uid"`
+	Created     time.Time     `db:"created" goqu:"skipupdate"`
+	Updated     time.Time     `db:"updated"`
+	Name        string        `db:"name"`
+	Description string        `db:"description"`
+	Inherit     string        `db:"inherit"`
+	Permissions types.Strings `db:"permissions"`
+}
+
+// GroupManager is a query helper for custom groups.
+type GroupManager struct{}
+
+// Query returns a prepared goqu SelectDataset that can be extended later.
+func (m *GroupManager) Query() *goqu.SelectDataset {
+	return db.Q().From(goqu.T(GroupTableName).As("g")).Prepared(true)
+}
+
+// GetOne executes the a select query and returns the first result or an error
+// when there's no result.
+func (m *GroupManager) GetOne(expressions ...goqu.Expression) (*Group, error) {
+	var g Group
+	found, err := m.Query().Where(expressions...).ScanStruct(&g)
+
+	switch {
+	case err != nil:
+		return nil, err
+	case !found:
+		return nil, ErrNotFound
+	}
+
+	return &g, nil
+}
+
+// All returns all the groups stored in database, by name.
+func (m *GroupManager) All() ([]*Group, error) {
+	res := []*Group{}
+	err := m.Query().Order(goqu.C("name").Asc()).ScanStructs(&res)
+	return res, err
+}
+
+// Create inserts a new group in the database.
+func (m *GroupManager) Create(g *Group) error {
+	if g.Name == "" {
+		return errors.New("no group name")
+	}
+
+	g.Created = time.Now().UTC()
+	g.Updated = g.Created
+	g.UID = base58.NewUUID()
+	if g.Permissions == nil {
+		g.Permissions = types.Strings{}
+	}
+
+	ds := db.Q().Insert(GroupTableName).
+		Rows(g).
+		Prepared(true)
+
+	id, err := db.InsertWithID(ds, "id")
+	if err != nil {
+		return err
+	}
+
+	g.ID = id
+	return nil
+}
+
+// Update updates some group values.
+func (g *Group) Update(v interface{}) error {
+	if g.ID == 0 {
+		return errors.New("no ID")
+	}
+
+	_, err := db.Q().Update(GroupTableName).Prepared(true).
+		Set(v).
+		Where(goqu.C("id").Eq(g.ID)).
+		Executor().Exec()
+
+	return err
+}
+
+// Save updates all the group values.
+func (g *Group) Save() error {
+	g.Updated = time.Now().UTC()
+	return g.Update(g)
+}
+
+// Delete removes a group from the database.
+func (g *Group) Delete() error {
+	_, err := db.Q().Delete(GroupTableName).Prepared(true).
+		Where(goqu.C("id").Eq(g.ID)).
+		Executor().Exec()
+
+	return err
+}
+
+// CountUsers returns the number of users in the group.
+func (g *Group) CountUsers() (int64, error) {
+	return Users.Query().Where(goqu.C("group").Eq(g.Name)).Count()
+}
+
+// ACLGroup returns the group's permission definition.
+func (g *Group) ACLGroup() acls.Group {
+	return acls.Group{
+		Name:        g.Name,
+		Description: g.Description,
+		Inherit:     g.Inherit,
+		Permissions: slices.Clone(g.Permissions),
+	}
+}
+
+// ConfigGroups returns the groups defined in the configuration file.
+func ConfigGroups() []acls.Group {
+	res := make([]acls.Group, len(configs.Config.Groups))
+	for i, g := range configs.Config.Groups {
+		res[i] = acls.Group{
+			Name:        g.Name,
+			Description: g.Description,
+			Inherit:     g.Inherit,
+			Permissions: slices.Clone(g.Permissions),
+		}
+	}
+	return res
+}
+
+// IsConfigGroup returns true when a group is defined in the
+// configuration file.
+func IsConfigGroup(name string) bool {
+	return slices.ContainsFunc(ConfigGroups(), func(g acls.Group) bool {
+		return g.Name == name
+	})
+}
+
+// ValidateGroup checks that a new or updated group works
+// with all the other custom groups.
+func ValidateGroup(g *Group) error {
+	list, err := allGroups()
+	if err != nil {
+		return err
+	}
+
+	if g.ID > 0 && !IsConfigGroup(g.Name) {
+		list = slices.DeleteFunc(list, func(x acls.Group) bool {
+			return x.Name == g.Name
+		})
+	}
+	return acls.ValidateGroups(append(list, g.ACLGroup())...)
+}
+
+// LoadGroups applies the custom groups of the configuration
+// file and of the database.
+func LoadGroups() error {
+	list, err := allGroups()
+	if err != nil {
+		return err
+	}
+	return acls.SetGroups(list...)
+}
+
+// GroupChoices returns the groups a user can belong to.
+func GroupChoices() [][2]string {
+	res := [][2]string{{"none", "no group"}}
+	for _, name := range acls.BuiltinGroups {
+		res = append(res, [2]string{name, name})
+	}
+	for _, g := range acls.Groups() {
+		res = append(res, [2]string{g.Name, g.Name})
+	}
+	return res
+}
+
+// allGroups returns the groups of the configuration file, then the
+// ones stored in database. A configuration group replaces a stored
+// group with the same name.
+func allGroups() ([]acls.Group, error) {
+	stored, err := Groups.All()
+	if err != nil {
+		return nil, err
+	}
+
+	res := ConfigGroups()
+	for _, g := range stored {
+		if IsConfigGroup(g.Name) {
+			continue
+		}
+		res = append(res, g.ACLGroup())
+	}
+	return res, nil
+}
@@ -0,0 +1,133 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package users
+
+import (
+	"errors"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+
+	"codeberg.org/readeck/readeck/internal/db"
+)
+
+// IdentityTableName is the user identity table name in database.
+const IdentityTableName = "user_identity"
+
+// Identities is the user identity manager.
+var Identities = IdentityManager{}
+
+// Identity links a user to an account of an external
+// authentication provider. The provider is usually an issuer URL
+// and the subject the account's identifier on this provider.
+type Identity struct {
+	ID       int        `db:"id" goqu:"skipinsert,skipupdate"`
+	UserID   int        `db:"user_id"`
+	Created  time.Time  `db:"created" goqu:"skipupdate"`
+	LastUsed *time.Time `db:"last_used"`
+	Provider string     `db:"provider"`
+	Subject  string     `db:"subject"`
+}
+
+// IdentityManager is a query helper for user identity entries.
+type IdentityManager struct{}
+
+// Query returns a prepared goqu SelectDataset that can be extended later.
+func (m *IdentityManager) Query() *goqu.SelectDataset {
+	return db.Q().From(goqu.T(IdentityTableName).As("i")).Prepared(true)
+}
+
+// GetOne executes the a select query and returns the first result or an error
+// when there's no result.
+func (m *IdentityManager) GetOne(expressions ...goqu.Expression) (*Identity, error) {
+	var i Identity
+	found, err := m.Query().Where(expressions...).ScanStruct(&i)
+
+	switch {
+	case err != nil:
+		return nil, err
+	case !found:
+		return nil, ErrNotFound
+	}
+
+	return &i, nil
+}
+
+// GetUser returns the user linked to a provider's subject.
+func (m *IdentityManager) GetUser(provider, subject string) (*Identity, *User, error) {
+	i, err := m.GetOne(
+		goqu.C("provider").Eq(provider),
+		goqu.C("subject").Eq(subject),
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	u, err := Users.GetOne(goqu.C("id").Eq(i.UserID))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return i, u, nil
+}
+
+// ForUser returns all the identities of a user.
+func (m *IdentityManager) ForUser(u *User) ([]*Identity, error) {
+	res := []*Identity{}
+	err := m.Query().
+		Where(goqu.C("user_id").Eq(u.ID)).
+		Order(goqu.C("created").Asc()).
+		ScanStructs(&res)
+	return res, err
+}
+
+// Link creates a new identity for a user.
+func (m *IdentityManager) Link(u *User, provider, subject string) (*Identity, error) {
+	if u.ID == 0 {
+		return nil, errors.New("no user ID")
+	}
+
+	i := &Identity{
+		UserID:   u.ID,
+		Created:  time.Now(),
+		Provider: provider,
+		Subject:  subject,
+	}
+
+	ds := db.Q().Insert(IdentityTableName).
+		Rows(i).
+		Prepared(true)
+
+	id, err := db.InsertWithID(ds, "id")
+	if err != nil {
+		return nil, err
+	}
+
+	i.ID = id
+	return i, nil
+}
+
+// Update updates some identity values.
+func (i *Identity) Update(v interface{}) error {
+	if i.ID == 0 {
+		return errors.New("no ID")
+	}
+
+	_, err := db.Q().Update(IdentityTableName).Prepared(true).
+		Set(v).
+		Where(goqu.C("id").Eq(i.ID)).
+		Executor().Exec()
+
+	return err
+}
+
+// Delete removes the identity from the database.
+func (i *Identity) Delete() error {
+	_, err := db.Q().Delete(IdentityTableName).Prepared(true).
+		Where(goqu.C("id").Eq(i.ID)).
+		Executor().Exec()
+
+	return err
+}
@@ -0,0 +1,111 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package users
+
+import (
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+
+	"codeberg.org/readeck/readeck/internal/db"
+	"codeberg.org/readeck/readeck/pkg/base58"
+)
+
+const (
+	// InviteTableName is the invitation table name in database.
+	InviteTableName = "user_invite"
+)
+
+// Invites is the invitation manager.
+var Invites = InviteManager{}
+
+// Invite is an invitation to register on the instance. Its UID is
+// the secret part of the invitation link. An invitation can only be
+// used once, before it expires.
+type Invite struct {
+	ID      int       `db:"id" goqu:"skipinsert,skipupdate"`
+	UID     string    `db:"uid"`
+	Created time.Time `db:"created" goqu:"skipupdate"`
+	Expires time.Time `db:"expires"`
+	Email   string    `db:"email"`
+	Group   string    `db:"group"`
+}
+
+// InviteManager is a query helper for invitations.
+type InviteManager struct{}
+
+// Query returns a prepared goqu SelectDataset that can be extended later.
+func (m *InviteManager) Query() *goqu.SelectDataset {
+	return db.Q().From(goqu.T(InviteTableName).As("i")).Prepared(true)
+}
+
+// GetOne executes the a select query and returns the first result or an error
+// when there's no result.
+func (m *InviteManager) GetOne(expressions ...goqu.Expression) (*Invite, error) {
+	var i Invite
+	found, err := m.Query().Where(expressions...).ScanStruct(&i)
+
+	switch {
+	case err != nil:
+		return nil, err
+	case !found:
+		return nil, ErrNotFound
+	}
+
+	return &i, nil
+}
+
+// GetValid returns an invitation that didn't expire.
+func (m *InviteManager) GetValid(uid string) (*Invite, error) {
+	return m.GetOne(
+		goqu.C("uid").Eq(uid),
+		goqu.C("expires").Gt(time.Now().UTC()),
+	)
+}
+
+// Create inserts a new invitation in the database.
+func (m *InviteManager) Create(i *Invite) error {
+	i.Created = time.Now().UTC()
+	i.Expires = i.Expires.UTC()
+	i.UID = base58.NewUUID()
+
+	ds := db.Q().Insert(InviteTableName).
+		Rows(i).
+		Prepared(true)
+
+	id, err := db.InsertWithID(ds, "id")
+	if err != nil {
+		return err
+	}
+
+	i.ID = id
+	return nil
+}
+
+// Purge removes the invitations that expired before the given time.
+func (m *InviteManager) Purge(before time.Time) (int64, error) {
+	res, err := db.Q().Delete(InviteTableName).Prepared(true).
+		Where(goqu.C("expires").Lt(before.UTC())).
+		Executor().Exec()
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+// Delete removes an invitation from the database.
+func (i *Invite) Delete() error {
+	_, err := db.Q().Delete(InviteTableName).Prepared(true).
+		Where(goqu.C("id").Eq(i.ID)).
+		Executor().Exec()
+
+	return err
+}
+
+// IsExpired returns true when the invitation can't be used anymore.
+func (i *Invite) IsExpired() bool {
+	return !i.Expires.After(time.Now())
+}
@@ -41,13 +41,6 @@ var (
 
 	// ErrNotFound is returned when a user record was not found.
 	ErrNotFound = errors.New("not found")
-
-	availableGroups = [][2]string{
-		{"none", "no group"},
-		{"user", "user"},
-		{"staff", "staff"},
-		{"admin", "admin"},
-	}
 )
 
 // User is a user record in database.
@@ -62,6 +55,10 @@ type User struct {
 	Group    string        `db:"group"`
 	Settings *UserSettings `db:"settings"`
 	Seed     int           `db:"seed"`
+
+	// PreviousGroup is the group of a deactivated user,
+	// given back when the user is activated again.
+	PreviousGroup string `db:"previous_group"`
 }
 
 // Manager is a query helper for user entries.
@@ -105,6 +102,16 @@ func (m *Manager) Create(user *User) error {
 	}
 	user.Password = hash
 
+	return m.CreateHashed(user)
+}
+
+// CreateHashed inserts a new user in the database, with
+// a password that is already hashed.
+func (m *Manager) CreateHashed(user *User) error {
+	if strings.TrimSpace(user.Password) == "" {
+		return errors.New("password is empty")
+	}
+
 	user.Created = time.Now()
 	user.Updated = user.Created
 	user.UID = base58.NewUUID()
@@ -0,0 +1,296 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package users
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"golang.org/x/crypto/chacha20poly1305"
+
+	"codeberg.org/readeck/readeck/configs"
+	"codeberg.org/readeck/readeck/internal/db"
+	"codeberg.org/readeck/readeck/internal/db/types"
+	"codeberg.org/readeck/readeck/pkg/totp"
+)
+
+const (
+	// AuthenticatorTableName is the user TOTP authenticator table name in database.
+	AuthenticatorTableName = "user_totp"
+
+	// totpIssuer is the issuer shown in authenticator applications.
+	totpIssuer = "Readeck"
+
+	// recoveryCodeCount is the number of recovery codes of an authenticator.
+	recoveryCodeCount = 10
+)
+
+var (
+	// Authenticators is the user TOTP authenticator manager.
+	Authenticators = AuthenticatorManager{}
+
+	// ErrAuthenticatorEnabled is returned when enrolling a user that
+	// already has an enabled authenticator.
+	ErrAuthenticatorEnabled = errors.New("authenticator already enabled")
+
+	recoveryEncoding = base32.NewEncoding("abcdefghijkmnpqrstuvwxyz23456789").WithPadding(base32.NoPadding)
+)
+
+// Authenticator is a user's TOTP second factor. It's enabled once the
+// user confirms a first code. Its secret is encrypted and only the
+// hashes of its one-time recovery codes are stored.
+type Authenticator struct {
+	ID            int           `db:"id" goqu:"skipinsert,skipupdate"`
+	UserID        int           `db:"user_id"`
+	Created       time.Time     `db:"created" goqu:"skipupdate"`
+	Enabled       *time.Time    `db:"enabled"`
+	Secret        string        `db:"secret"`
+	LastStep      int64         `db:"last_step"`
+	RecoveryCodes types.Strings `db:"recovery_codes"`
+}
+
+// AuthenticatorManager is a query helper for authenticator entries.
+type AuthenticatorManager struct{}
+
+// Query returns a prepared goqu SelectDataset that can be extended later.
+func (m *AuthenticatorManager) Query() *goqu.SelectDataset {
+	return db.Q().From(goqu.T(AuthenticatorTableName).As("a")).Prepared(true)
+}
+
+// GetOne executes the a select query and returns the first result or an error
+// when there's no result.
+func (m *AuthenticatorManager) GetOne(expressions ...goqu.Expression) (*Authenticator, error) {
+	var a Authenticator
+	found, err := m.Query().Where(expressions...).ScanStruct(&a)
+
+	switch {
+	case err != nil:
+		return nil, err
+	case !found:
+		return nil, ErrNotFound
+	}
+
+	return &a, nil
+}
+
+// ForUser returns the user's authenticator.
+func (m *AuthenticatorManager) ForUser(u *User) (*Authenticator, error) {
+	return m.GetOne(goqu.C("user_id").Eq(u.ID))
+}
+
+// IsEnabled returns true when the user has an enabled authenticator.
+func (m *AuthenticatorManager) IsEnabled(u *User) (bool, error) {
+	count, err := m.Query().Where(
+		goqu.C("user_id").Eq(u.ID),
+		goqu.C("enabled").IsNotNull(),
+	).Count()
+	return count > 0, err
+}
+
+// Enroll creates a new, not yet enabled, authenticator for a user.
+// It replaces a previous one that wasn't enabled.
+func (m *AuthenticatorManager) Enroll(u *User) (*Authenticator, error) {
+	if u.ID == 0 {
+		return nil, errors.New("no user ID")
+	}
+
+	if a, err := m.ForUser(u); err == nil {
+		if a.IsEnabled() {
+			return nil, ErrAuthenticatorEnabled
+		}
+		if err = a.Delete(); err != nil {
+			return nil, err
+		}
+	} else if !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+
+	a := &Authenticator{
+		UserID:        u.ID,
+		Created:       time.Now(),
+		RecoveryCodes: types.Strings{},
+	}
+	if err := a.setSecret(totp.NewSecret()); err != nil {
+		return nil, err
+	}
+
+	ds := db.Q().Insert(AuthenticatorTableName).
+		Rows(a).
+		Prepared(true)
+
+	id, err := db.InsertWithID(ds, "id")
+	if err != nil {
+		return nil, err
+	}
+
+	a.ID = id
+	return a, nil
+}
+
+// IsEnabled returns true when the authenticator was confirmed.
+func (a *Authenticator) IsEnabled() bool {
+	return a.Enabled != nil
+}
+
+// Key returns the authenticator's decrypted secret.
+func (a *Authenticator) Key() ([]byte, error) {
+	data, err := base64.RawURLEncoding.DecodeString(a.Secret)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < chacha20poly1305.NonceSizeX {
+		return nil, errors.New("invalid secret")
+	}
+
+	aead, err := chacha20poly1305.NewX(configs.Keys.TOTPKey())
+	if err != nil {
+		return nil, err
+	}
+	nonce, ciphertext := data[:chacha20poly1305.NonceSizeX], data[chacha20poly1305.NonceSizeX:]
+	return aead.Open(nil, nonce, ciphertext, []byte(strconv.Itoa(a.UserID)))
+}
+
+// setSecret encrypts and sets the authenticator's secret. The user
+// ID is the additional data so a secret can't move to another user.
+func (a *Authenticator) setSecret(secret []byte) error {
+	aead, err := chacha20poly1305.NewX(configs.Keys.TOTPKey())
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, chacha20poly1305.NonceSizeX, chacha20poly1305.NonceSizeX+len(secret)+aead.Overhead())
+	rand.Read(nonce)
+
+	a.Secret = base64.RawURLEncoding.EncodeToString(
+		aead.Seal(nonce, nonce, secret, []byte(strconv.Itoa(a.UserID))),
+	)
+	return nil
+}
+
+// EncodedSecret returns the secret as users type it in
+// authenticator applications.
+func (a *Authenticator) EncodedSecret() (string, error) {
+	key, err := a.Key()
+	if err != nil {
+		return "", err
+	}
+	return totp.EncodeSecret(key), nil
+}
+
+// URL returns the "otpauth" URL for the user's authenticator application.
+func (a *Authenticator) URL(u *User) (string, error) {
+	key, err := a.Key()
+	if err != nil {
+		return "", err
+	}
+	return totp.URL(totpIssuer, u.Username, key), nil
+}
+
+// Verify checks a code from the authenticator application or, once
+// enabled, one of the recovery codes, that can't be used again.
+// A code can't be used twice either.
+func (a *Authenticator) Verify(code string) (bool, error) {
+	key, err := a.Key()
+	if err != nil {
+		return false, err
+	}
+
+	if step, ok := totp.Validate(key, code, time.Now()); ok {
+		if step <= a.LastStep {
+			return false, nil
+		}
+		a.LastStep = step
+		return true, a.Update(goqu.Record{"last_step": step})
+	}
+
+	if !a.IsEnabled() {
+		return false, nil
+	}
+
+	hash := hashRecoveryCode(code)
+	i := slices.Index(a.RecoveryCodes, hash)
+	if i < 0 {
+		return false, nil
+	}
+	a.RecoveryCodes = slices.Delete(a.RecoveryCodes, i, i+1)
+	return true, a.Update(goqu.Record{"recovery_codes": a.RecoveryCodes})
+}
+
+// Enable enables the authenticator and returns its new recovery codes.
+func (a *Authenticator) Enable() ([]string, error) {
+	now := time.Now()
+	a.Enabled = &now
+	if err := a.Update(goqu.Record{"enabled": now}); err != nil {
+		return nil, err
+	}
+
+	return a.NewRecoveryCodes()
+}
+
+// NewRecoveryCodes replaces the recovery codes and returns them.
+// They can't be retrieved later.
+func (a *Authenticator) NewRecoveryCodes() ([]string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make(types.Strings, recoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, 7)
+		rand.Read(b)
+		code := recoveryEncoding.EncodeToString(b)[:10]
+		codes[i] = code[:5] + "-" + code[5:]
+		hashes[i] = hashRecoveryCode(code)
+	}
+
+	a.RecoveryCodes = hashes
+	if err := a.Update(goqu.Record{"recovery_codes": hashes}); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Update updates some authenticator values.
+func (a *Authenticator) Update(v interface{}) error {
+	if a.ID == 0 {
+		return errors.New("no ID")
+	}
+
+	_, err := db.Q().Update(AuthenticatorTableName).Prepared(true).
+		Set(v).
+		Where(goqu.C("id").Eq(a.ID)).
+		Executor().Exec()
+
+	return err
+}
+
+// Delete removes the authenticator from the database.
+func (a *Authenticator) Delete() error {
+	_, err := db.Q().Delete(AuthenticatorTableName).Prepared(true).
+		Where(goqu.C("id").Eq(a.ID)).
+		Executor().Exec()
+
+	return err
+}
+
+// TOTPRequired returns true when the user's group must use
+// a TOTP second factor.
+func (u *User) TOTPRequired() bool {
+	return slices.Contains(configs.Config.Auth.TOTPRequiredGroups, u.Group)
+}
+
+// hashRecoveryCode returns the hash of a recovery code, ignoring
+// its case, spaces and dashes.
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
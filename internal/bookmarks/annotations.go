@@ -20,6 +20,7 @@ import (
 type BookmarkAnnotations []*BookmarkAnnotation
 
 // BookmarkAnnotation is an annotation that can be serialized in a database JSON column.
+// Text is the annotated text and Note an optional comment.
 type BookmarkAnnotation struct {
 	ID            string    `json:"id"`
 	StartSelector string    `json:"start_selector"`
@@ -29,6 +30,7 @@ type BookmarkAnnotation struct {
 	Color         string    `json:"color"`
 	Created       time.Time `json:"created"`
 	Text          string    `json:"text"`
+	Note          string    `json:"note,omitempty"`
 }
 
 // Scan loads a BookmarkAnnotations instance from a column.
@@ -134,3 +136,25 @@ func (a *BookmarkAnnotations) Delete(id string) {
 	}
 	*a = set
 }
+
+// Scan loads a BookmarkAnnotation instance from a column.
+func (a *BookmarkAnnotation) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+
+	v, err := types.JSONBytes(value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(v, a)
+}
+
+// Value encodes a BookmarkAnnotation instance for storage.
+func (a *BookmarkAnnotation) Value() (driver.Value, error) {
+	v, err := json.Marshal(a)
+	if err != nil {
+		return "", err
+	}
+	return string(v), nil
+}
@@ -102,6 +102,7 @@ type Bookmark struct {
 	IsMarked      bool                `db:"is_marked"`
 	Annotations   BookmarkAnnotations `db:"annotations"`
 	Links         BookmarkLinks       `db:"links"`
+	Deleted       *time.Time          `db:"deleted"`
 }
 
 // BookmarkManager is a query helper for bookmark entries.
@@ -131,19 +132,33 @@ func (m *BookmarkManager) Create(bookmark *Bookmark) error {
 	}
 
 	bookmark.ID = id
+	bookmark.Notify(EventBookmarkCreated)
 	return nil
 }
 
 // Query returns a prepared goqu SelectDataset that can be extended later.
+// It doesn't include the bookmarks in the trash.
 func (m *BookmarkManager) Query() *goqu.SelectDataset {
+	return m.QueryAll().Where(goqu.C("deleted").Table("b").IsNull())
+}
+
+// QueryAll returns a prepared goqu SelectDataset on all the bookmarks,
+// including the ones in the trash.
+func (m *BookmarkManager) QueryAll() *goqu.SelectDataset {
 	return db.Q().From(goqu.T(TableName).As("b")).Prepared(true)
 }
 
+// TrashQuery returns a prepared goqu SelectDataset on the bookmarks
+// in the trash.
+func (m *BookmarkManager) TrashQuery() *goqu.SelectDataset {
+	return m.QueryAll().Where(goqu.C("deleted").Table("b").IsNotNull())
+}
+
 // GetOne executes the a select query and returns the first result or an error
-// when there's no result.
+// when there's no result. It also finds the bookmarks in the trash.
 func (m *BookmarkManager) GetOne(expressions ...goqu.Expression) (*Bookmark, error) {
 	var b Bookmark
-	found, err := m.Query().Where(expressions...).ScanStruct(&b)
+	found, err := m.QueryAll().Where(expressions...).ScanStruct(&b)
 
 	switch {
 	case err != nil:
@@ -160,7 +175,7 @@ func (m *BookmarkManager) GetOne(expressions ...goqu.Expression) (*Bookmark, err
 // holds a file, we can't only rely on the foreign key cascade
 // deletion. Hence this.
 func (m *BookmarkManager) DeleteUserBookmakrs(u *users.User) error {
-	ds := Bookmarks.Query().
+	ds := Bookmarks.QueryAll().
 		Where(goqu.C("user_id").Eq(u.ID))
 
 	items := []*Bookmark{}
@@ -197,7 +212,7 @@ func (m *BookmarkManager) GetLastUpdate(expressions ...goqu.Expression) (time.Ti
 }
 
 // GetLabels returns a dataset that returns all the tags
-// defined in the bookmark table.
+// defined in the bookmark table. It ignores the bookmarks in the trash.
 func (m *BookmarkManager) GetLabels() *goqu.SelectDataset {
 	switch db.Driver().Dialect() {
 	case "postgres":
@@ -213,6 +228,7 @@ func (m *BookmarkManager) GetLabels() *goqu.SelectDataset {
 					else '[]' end
 					)`).As("name"),
 			).
+			Where(goqu.C("deleted").Table("b").IsNull()).
 			GroupBy(goqu.C("name")).
 			Order(goqu.C("name").Asc()).
 			Prepared(true)
@@ -226,7 +242,10 @@ func (m *BookmarkManager) GetLabels() *goqu.SelectDataset {
 				goqu.T(TableName).As("b"),
 				goqu.Func("json_each", goqu.C("labels").Table("b")).As("l"),
 			).
-			Where(goqu.C("value").Table("l").Neq(nil)).
+			Where(
+				goqu.C("value").Table("l").Neq(nil),
+				goqu.C("deleted").Table("b").IsNull(),
+			).
 			GroupBy(goqu.C("name")).
 			Order(goqu.L("`name` COLLATE UNICODE").Asc()).
 			Prepared(true)
@@ -385,6 +404,11 @@ func (m *BookmarkManager) RenameLabel(u *users.User, oldLabel, newLabel string)
 		return nil, err
 	}
 
+	// The old label doesn't exist anymore, whether it was renamed or deleted.
+	if err = Tombstones.Add(u.ID, TombstoneLabel, oldLabel, ""); err != nil {
+		return nil, err
+	}
+
 	return
 }
 
@@ -416,33 +440,140 @@ func (m *BookmarkManager) DiskUsage() (uint64, error) {
 	return totalSize, nil
 }
 
+// BookmarkUpdate contains the values to update on a bookmark.
+type BookmarkUpdate struct {
+	Bookmark *Bookmark
+	Values   map[string]interface{}
+}
+
+// UpdateAll updates several bookmarks in a single transaction.
+// The notifications are sent once every bookmark is saved.
+func (m *BookmarkManager) UpdateAll(updates []BookmarkUpdate) error {
+	tx, err := db.Q().Begin()
+	if err != nil {
+		return err
+	}
+
+	events := make([][]string, len(updates))
+	err = tx.Wrap(func() error {
+		for i, u := range updates {
+			if u.Bookmark.ID == 0 {
+				return errors.New("No ID")
+			}
+			events[i] = prepareUpdate(u.Values)
+			if err := u.Bookmark.update(tx, u.Values); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for i, u := range updates {
+		u.Bookmark.notifyUpdate(events[i])
+	}
+	return nil
+}
+
 // Update updates some bookmark values.
 func (b *Bookmark) Update(v interface{}) error {
 	if b.ID == 0 {
 		return errors.New("No ID")
 	}
 
+	events := prepareUpdate(v)
+	if err := b.update(db.Q(), v); err != nil {
+		return err
+	}
+
+	b.notifyUpdate(events)
+	return nil
+}
+
+// prepareUpdate sets the update date on a map of values and
+// returns the events, on top of "updated", that the change implies.
+func prepareUpdate(v interface{}) (events []string) {
 	switch v := v.(type) {
 	case map[string]interface{}:
 		v["updated"] = time.Now()
+		if v["is_archived"] == true {
+			events = append(events, EventBookmarkArchived)
+		}
+		if v["is_marked"] == true {
+			events = append(events, EventBookmarkMarked)
+		}
+		// Moving a bookmark to the trash removes it from the user's
+		// lists, restoring it adds it back.
+		if d, ok := v["deleted"]; ok {
+			if d == nil {
+				events = append(events, EventBookmarkCreated)
+			} else {
+				events = append(events, EventBookmarkDeleted)
+			}
+		}
 	default:
 		//
 	}
+	return
+}
 
-	_, err := db.Q().Update(TableName).Prepared(true).
+func (b *Bookmark) update(q interface {
+	Update(table interface{}) *goqu.UpdateDataset
+}, v interface{},
+) error {
+	_, err := q.Update(TableName).Prepared(true).
 		Set(v).
 		Where(goqu.C("id").Eq(b.ID)).
 		Executor().Exec()
-
 	return err
 }
 
+func (b *Bookmark) notifyUpdate(events []string) {
+	b.Notify(EventBookmarkUpdated)
+	for _, name := range events {
+		b.Notify(name)
+	}
+}
+
 // Save updates all the bookmark values.
 func (b *Bookmark) Save() error {
 	b.Updated = time.Now()
 	return b.Update(b)
 }
 
+// Trash moves the bookmark to the trash. It stays there until the trash
+// is emptied or its retention period is over. Its owner is notified
+// that the bookmark was deleted.
+func (b *Bookmark) Trash() error {
+	now := time.Now()
+	if err := b.Update(map[string]interface{}{"deleted": now}); err != nil {
+		return err
+	}
+	b.Deleted = &now
+	return nil
+}
+
+// Restore takes the bookmark out of the trash. Its owner is notified
+// as if the bookmark was created again.
+func (b *Bookmark) Restore() error {
+	if err := b.Update(map[string]interface{}{"deleted": nil}); err != nil {
+		return err
+	}
+	b.Deleted = nil
+
+	if b.UserID != nil {
+		return Tombstones.Remove(*b.UserID, TombstoneBookmark, b.UID)
+	}
+	return nil
+}
+
+// IsDeleted returns true when the bookmark is in the trash.
+func (b *Bookmark) IsDeleted() bool {
+	return b.Deleted != nil
+}
+
 // Delete removes a bookmark from the database.
 func (b *Bookmark) Delete() error {
 	_, err := db.Q().Delete(TableName).Prepared(true).
@@ -452,6 +583,16 @@ func (b *Bookmark) Delete() error {
 		return err
 	}
 
+	if b.UserID != nil {
+		if err = Tombstones.Add(*b.UserID, TombstoneBookmark, b.UID, ""); err != nil {
+			return err
+		}
+	}
+
+	// A bookmark in the trash was already announced as deleted.
+	if !b.IsDeleted() {
+		b.Notify(EventBookmarkDeleted)
+	}
 	b.RemoveFiles()
 	return nil
 }
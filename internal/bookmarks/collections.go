@@ -29,29 +29,43 @@ var (
 
 // Collection is a collection record in the database.
 type Collection struct {
-	ID       int       `db:"id" goqu:"skipinsert,skipupdate"`
-	UID      string    `db:"uid"`
-	UserID   *int      `db:"user_id"`
-	Created  time.Time `db:"created" goqu:"skipupdate"`
-	Updated  time.Time `db:"updated"`
-	Name     string    `db:"name"`
-	IsPinned bool      `db:"is_pinned"`
-	Filters  Filters   `db:"filters"`
+	ID       int        `db:"id" goqu:"skipinsert,skipupdate"`
+	UID      string     `db:"uid"`
+	UserID   *int       `db:"user_id"`
+	Created  time.Time  `db:"created" goqu:"skipupdate"`
+	Updated  time.Time  `db:"updated"`
+	Name     string     `db:"name"`
+	IsPinned bool       `db:"is_pinned"`
+	Filters  Filters    `db:"filters"`
+	Deleted  *time.Time `db:"deleted"`
 }
 
 // CollectionManager is a query helper for bookmark entries.
 type CollectionManager struct{}
 
 // Query returns a prepared goqu SelectDataset that can be extended later.
+// It doesn't include the collections in the trash.
 func (m *CollectionManager) Query() *goqu.SelectDataset {
+	return m.QueryAll().Where(goqu.C("deleted").Table("c").IsNull())
+}
+
+// QueryAll returns a prepared goqu SelectDataset on all the collections,
+// including the ones in the trash.
+func (m *CollectionManager) QueryAll() *goqu.SelectDataset {
 	return db.Q().From(goqu.T(CollectionTable).As("c")).Prepared(true)
 }
 
+// TrashQuery returns a prepared goqu SelectDataset on the collections
+// in the trash.
+func (m *CollectionManager) TrashQuery() *goqu.SelectDataset {
+	return m.QueryAll().Where(goqu.C("deleted").Table("c").IsNotNull())
+}
+
 // GetOne executes the a select query and returns the first result or an error
-// when there's no result.
+// when there's no result. It also finds the collections in the trash.
 func (m *CollectionManager) GetOne(expressions ...goqu.Expression) (*Collection, error) {
 	var c Collection
-	found, err := m.Query().Where(expressions...).ScanStruct(&c)
+	found, err := m.QueryAll().Where(expressions...).ScanStruct(&c)
 
 	switch {
 	case err != nil:
@@ -114,13 +128,49 @@ func (c *Collection) Save() error {
 	return c.Update(c)
 }
 
+// Trash moves the collection to the trash. Synchronization clients
+// see it as removed.
+func (c *Collection) Trash() error {
+	now := time.Now()
+	if err := c.Update(map[string]interface{}{"deleted": now}); err != nil {
+		return err
+	}
+	c.Deleted = &now
+
+	if c.UserID != nil {
+		return Tombstones.Add(*c.UserID, TombstoneCollection, c.UID, "")
+	}
+	return nil
+}
+
+// Restore takes the collection out of the trash.
+func (c *Collection) Restore() error {
+	if err := c.Update(map[string]interface{}{"deleted": nil}); err != nil {
+		return err
+	}
+	c.Deleted = nil
+	return nil
+}
+
+// IsDeleted returns true when the collection is in the trash.
+func (c *Collection) IsDeleted() bool {
+	return c.Deleted != nil
+}
+
 // Delete removes a collection from the database.
 func (c *Collection) Delete() error {
 	_, err := db.Q().Delete(CollectionTable).Prepared(true).
 		Where(goqu.C("id").Eq(c.ID)).
 		Executor().Exec()
+	if err != nil {
+		return err
+	}
 
-	return err
+	// A collection in the trash already has its tombstone.
+	if c.UserID != nil && c.Deleted == nil {
+		return Tombstones.Add(*c.UserID, TombstoneCollection, c.UID, "")
+	}
+	return nil
 }
 
 // GetSumStrings returns the string used to generate the etag
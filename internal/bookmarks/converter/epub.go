@@ -7,8 +7,10 @@ package converter
 import (
 	"bytes"
 	"context"
+	"crypto/md5" //nolint:gosec
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"path"
@@ -22,6 +24,7 @@ import (
 	"codeberg.org/readeck/readeck/internal/bookmarks"
 	"codeberg.org/readeck/readeck/internal/server"
 	"codeberg.org/readeck/readeck/pkg/epub"
+	"codeberg.org/readeck/readeck/pkg/partialmd5"
 	"codeberg.org/readeck/readeck/pkg/utils"
 )
 
@@ -65,13 +68,22 @@ func (e EPUBExporter) Export(ctx context.Context, w io.Writer, _ *http.Request,
 		id += x.UID
 	}
 
+	filename := fmt.Sprintf("%s-%s.epub",
+		date.Format(time.DateOnly),
+		utils.Slug(strings.TrimSuffix(utils.ShortText(title, 40), "...")),
+	)
+
 	if w, ok := w.(http.ResponseWriter); ok {
 		w.Header().Set("Content-Type", "application/epub+zip")
-		w.Header().Set("Content-Disposition", fmt.Sprintf(
-			`attachment; filename="%s-%s.epub"`,
-			date.Format(time.DateOnly),
-			utils.Slug(strings.TrimSuffix(utils.ShortText(title, 40), "...")),
-		))
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	}
+
+	// When exporting a single bookmark, the file's digests are recorded
+	// so e-readers can synchronize their reading progress.
+	var digest *partialmd5.Writer
+	if e.Collection == nil && len(bookmarkList) == 1 {
+		digest = partialmd5.New()
+		w = io.MultiWriter(w, digest)
 	}
 
 	m, err := newEpubMaker(w, uuid.NewSHA1(uuidURL, []byte(id)))
@@ -86,6 +98,16 @@ func (e EPUBExporter) Export(ctx context.Context, w io.Writer, _ *http.Request,
 			err = m.WritePackage()
 		}
 		m.Close() //nolint:errcheck
+
+		if err == nil && digest != nil {
+			if err := bookmarks.Documents.Record(
+				bookmarkList[0],
+				digest.Sum(),
+				fmt.Sprintf("%x", md5.Sum([]byte(filename))), //nolint:gosec
+			); err != nil {
+				slog.Error("can't record document", slog.Any("err", err))
+			}
+		}
 	}()
 
 	ctx = WithURLReplacer(ctx, func(_ *bookmarks.Bookmark) func(name string) string {
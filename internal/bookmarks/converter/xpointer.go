@@ -0,0 +1,281 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package converter
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+
+	"codeberg.org/readeck/readeck/internal/bookmarks"
+)
+
+var (
+	rxXPointerPrefix   = regexp.MustCompile(`^/body/DocFragment(?:\[\d+\])?/body/main(?:\[(\d+)\])?`)
+	rxXPointerSegment  = regexp.MustCompile(`^([a-zA-Z][a-zA-Z0-9_-]*)(?:\[(\d+)\])?(?:\.\d+)?$`)
+	rxSelectorSegment  = regexp.MustCompile(`^([a-zA-Z][a-zA-Z0-9_-]*):nth-child\((\d+)\)$`)
+	blockPositionAtoms = map[atom.Atom]bool{
+		atom.P: true, atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true,
+		atom.H5: true, atom.H6: true, atom.Li: true, atom.Blockquote: true,
+		atom.Pre: true, atom.Figure: true, atom.Table: true, atom.Dd: true, atom.Dt: true,
+	}
+)
+
+// EPUBPosition translates reading positions between the web reader and
+// an EPUB file produced by [EPUBExporter] for a single bookmark.
+//
+// The web reader stores a CSS selector (with nth-child() segments)
+// relative to the article's root. E-readers like KOReader use an
+// XPointer relative to the EPUB document body, in which the article
+// is the content of a "main" element.
+type EPUBPosition struct {
+	root   *html.Node
+	prefix string
+	main   int
+}
+
+// NewEPUBPosition returns a new [EPUBPosition] for a bookmark.
+func NewEPUBPosition(ctx context.Context, b *bookmarks.Bookmark) (*EPUBPosition, error) {
+	r, err := HTMLConverter{}.GetArticle(ctx, b)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := html.ParseWithOptions(r)
+	if err != nil {
+		return nil, err
+	}
+	p := &EPUBPosition{
+		root:   findElement(body, atom.Body),
+		prefix: "/body/DocFragment[1]/body/main",
+		main:   1,
+	}
+	if p.root == nil {
+		p.root = &html.Node{Type: html.ElementNode, DataAtom: atom.Body, Data: "body"}
+	}
+
+	// See the epub/bookmark template: the photo or video comes first,
+	// in its own "main" element.
+	if b.DocumentType == "photo" || b.DocumentType == "video" {
+		p.prefix = "/body/DocFragment[1]/body/main[2]"
+		p.main = 2
+	}
+
+	return p, nil
+}
+
+// Selector returns the CSS selector of an XPointer's element. It returns
+// an empty string when the XPointer is outside of the article.
+func (p *EPUBPosition) Selector(xpointer string) string {
+	m := rxXPointerPrefix.FindStringSubmatch(xpointer)
+	if m == nil {
+		return ""
+	}
+	if idx, _ := strconv.Atoi(m[1]); max(idx, 1) != p.main {
+		return ""
+	}
+
+	names := []string{}
+	node := p.root
+	for _, s := range strings.Split(strings.TrimPrefix(xpointer, m[0]), "/") {
+		if s == "" {
+			continue
+		}
+		sm := rxXPointerSegment.FindStringSubmatch(s)
+		if sm == nil {
+			// text() or anything else stops here
+			break
+		}
+		idx := 1
+		if sm[2] != "" {
+			idx, _ = strconv.Atoi(sm[2])
+		}
+
+		child, nth := nthNamedChild(node, strings.ToLower(sm[1]), idx)
+		if child == nil {
+			break
+		}
+		names = append(names, fmt.Sprintf("%s:nth-child(%d)", child.Data, nth))
+		node = child
+	}
+
+	return strings.Join(names, ">")
+}
+
+// XPointer returns the XPointer of a CSS selector's element. When the
+// selector is empty or doesn't match, it uses the block element found
+// at the given progress (between 0 and 100) instead.
+func (p *EPUBPosition) XPointer(selector string, progress int) string {
+	node := p.root
+	res := p.prefix
+
+	if selector == "" || p.selectorNode(selector) == nil {
+		if progress <= 0 {
+			return res
+		}
+		selector = p.progressSelector(progress)
+	}
+
+	for _, s := range strings.Split(selector, ">") {
+		sm := rxSelectorSegment.FindStringSubmatch(strings.TrimSpace(s))
+		if sm == nil {
+			break
+		}
+		nth, _ := strconv.Atoi(sm[2])
+		child := nthChild(node, nth)
+		if child == nil || child.Data != strings.ToLower(sm[1]) {
+			break
+		}
+
+		idx, count := 0, 0
+		for c := node.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.ElementNode && c.Data == child.Data {
+				count++
+				if c == child {
+					idx = count
+				}
+			}
+		}
+
+		if count > 1 {
+			res += fmt.Sprintf("/%s[%d]", child.Data, idx)
+		} else {
+			res += "/" + child.Data
+		}
+		node = child
+	}
+
+	return res
+}
+
+// selectorNode returns the node matching a selector, or nil.
+func (p *EPUBPosition) selectorNode(selector string) *html.Node {
+	node := p.root
+	for _, s := range strings.Split(selector, ">") {
+		sm := rxSelectorSegment.FindStringSubmatch(strings.TrimSpace(s))
+		if sm == nil {
+			return nil
+		}
+		nth, _ := strconv.Atoi(sm[2])
+		node = nthChild(node, nth)
+		if node == nil || node.Data != strings.ToLower(sm[1]) {
+			return nil
+		}
+	}
+	return node
+}
+
+// progressSelector returns the selector of the first block element
+// found after the given text progress.
+func (p *EPUBPosition) progressSelector(progress int) string {
+	total := textLength(p.root)
+	target := total * progress / 100
+	pos := 0
+
+	var found *html.Node
+	var walk func(n *html.Node) bool
+	walk = func(n *html.Node) bool {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			switch c.Type {
+			case html.TextNode:
+				pos += len(c.Data)
+			case html.ElementNode:
+				if blockPositionAtoms[c.DataAtom] {
+					found = c
+					if pos >= target {
+						return true
+					}
+					pos += textLength(c)
+					continue
+				}
+				if walk(c) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	walk(p.root)
+
+	if found == nil {
+		return ""
+	}
+
+	names := []string{}
+	for n := found; n != nil && n != p.root; n = n.Parent {
+		i := 1
+		for s := n.PrevSibling; s != nil; s = s.PrevSibling {
+			if s.Type == html.ElementNode {
+				i++
+			}
+		}
+		names = append([]string{fmt.Sprintf("%s:nth-child(%d)", n.Data, i)}, names...)
+	}
+	return strings.Join(names, ">")
+}
+
+// nthChild returns the nth (starting at 1) element child of a node.
+func nthChild(n *html.Node, nth int) *html.Node {
+	i := 0
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.ElementNode {
+			continue
+		}
+		i++
+		if i == nth {
+			return c
+		}
+	}
+	return nil
+}
+
+// nthNamedChild returns the idx-th (starting at 1) element child with
+// the given name, and its position among all the element children.
+func nthNamedChild(n *html.Node, name string, idx int) (*html.Node, int) {
+	nth, count := 0, 0
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.ElementNode {
+			continue
+		}
+		nth++
+		if c.Data == name {
+			count++
+			if count == idx {
+				return c, nth
+			}
+		}
+	}
+	return nil, 0
+}
+
+// findElement returns the first element with the given atom.
+func findElement(n *html.Node, a atom.Atom) *html.Node {
+	if n.Type == html.ElementNode && n.DataAtom == a {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if res := findElement(c, a); res != nil {
+			return res
+		}
+	}
+	return nil
+}
+
+// textLength returns the length of all the text in a node.
+func textLength(n *html.Node) int {
+	if n.Type == html.TextNode {
+		return len(n.Data)
+	}
+	res := 0
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		res += textLength(c)
+	}
+	return res
+}
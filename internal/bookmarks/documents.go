@@ -0,0 +1,118 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package bookmarks
+
+import (
+	"errors"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+
+	"codeberg.org/readeck/readeck/internal/db"
+)
+
+// DocumentTable is the bookmark document table name in database.
+const DocumentTable = "bookmark_document"
+
+// Documents is the bookmark document query manager.
+var Documents = DocumentManager{}
+
+// ErrDocumentNotFound is returned when a document record was not found.
+var ErrDocumentNotFound = errors.New("not found")
+
+// Document is a file generated from a bookmark, identified by a hash.
+// E-readers use this hash to synchronize their reading progress.
+type Document struct {
+	ID         int        `db:"id" goqu:"skipinsert,skipupdate"`
+	BookmarkID int        `db:"bookmark_id" goqu:"skipupdate"`
+	Created    time.Time  `db:"created" goqu:"skipupdate"`
+	Hash       string     `db:"hash" goqu:"skipupdate"`
+	Device     string     `db:"device"`
+	DeviceID   string     `db:"device_id"`
+	Synced     *time.Time `db:"synced"`
+}
+
+// DocumentManager is a query helper for bookmark document entries.
+type DocumentManager struct{}
+
+// Query returns a prepared goqu SelectDataset that can be extended later.
+func (m *DocumentManager) Query() *goqu.SelectDataset {
+	return db.Q().From(goqu.T(DocumentTable).As("d")).Prepared(true)
+}
+
+// Record saves the given hashes for a bookmark. Hashes already
+// known for this bookmark are ignored.
+func (m *DocumentManager) Record(b *Bookmark, hashes ...string) error {
+	if b.ID == 0 {
+		return errors.New("no bookmark ID")
+	}
+
+	now := time.Now()
+	rows := []*Document{}
+	for _, h := range hashes {
+		rows = append(rows, &Document{
+			BookmarkID: b.ID,
+			Created:    now,
+			Hash:       h,
+		})
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+
+	_, err := db.Q().Insert(DocumentTable).
+		Rows(rows).
+		OnConflict(goqu.DoNothing()).
+		Prepared(true).
+		Executor().Exec()
+	return err
+}
+
+// GetBookmark returns the most recent document with the given hash,
+// and its bookmark, for a user. Bookmarks in the trash are ignored.
+// The expressions, on the "b" bookmark table, can limit the bookmarks
+// further.
+func (m *DocumentManager) GetBookmark(userID int, hash string, expressions ...goqu.Expression) (*Document, *Bookmark, error) {
+	var d Document
+	found, err := m.Query().
+		Select(goqu.T("d").All()).
+		Join(goqu.T(TableName).As("b"), goqu.On(goqu.Ex{"b.id": goqu.I("d.bookmark_id")})).
+		Where(
+			goqu.C("hash").Table("d").Eq(hash),
+			goqu.C("user_id").Table("b").Eq(userID),
+			goqu.C("deleted").Table("b").IsNull(),
+		).
+		Where(expressions...).
+		Order(goqu.C("created").Table("d").Desc()).
+		ScanStruct(&d)
+
+	switch {
+	case err != nil:
+		return nil, nil, err
+	case !found:
+		return nil, nil, ErrDocumentNotFound
+	}
+
+	b, err := Bookmarks.GetOne(goqu.C("id").Eq(d.BookmarkID))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &d, b, nil
+}
+
+// Update updates some document values.
+func (d *Document) Update(v interface{}) error {
+	if d.ID == 0 {
+		return errors.New("no ID")
+	}
+
+	_, err := db.Q().Update(DocumentTable).Prepared(true).
+		Set(v).
+		Where(goqu.C("id").Eq(d.ID)).
+		Executor().Exec()
+
+	return err
+}
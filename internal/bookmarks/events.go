@@ -0,0 +1,85 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package bookmarks
+
+import (
+	"codeberg.org/readeck/readeck/internal/bus"
+)
+
+// Notifications sent to a user's connected clients.
+const (
+	EventBookmarkCreated   = "bookmark.created"
+	EventBookmarkUpdated   = "bookmark.updated"
+	EventBookmarkLoaded    = "bookmark.loaded"
+	EventBookmarkError     = "bookmark.error"
+	EventBookmarkArchived  = "bookmark.archived"
+	EventBookmarkMarked    = "bookmark.marked"
+	EventBookmarkDeleted   = "bookmark.deleted"
+	EventAnnotationCreated = "annotation.created"
+	EventAnnotationUpdated = "annotation.updated"
+	EventAnnotationDeleted = "annotation.deleted"
+)
+
+// Event is a change on a bookmark or one of its annotations.
+type Event struct {
+	Name         string
+	Bookmark     *Bookmark
+	AnnotationID string
+}
+
+var eventHandlers []func(Event)
+
+// OnEvent registers a function that is called on every bookmark
+// or annotation event, on top of the user notification.
+func OnEvent(f func(Event)) {
+	eventHandlers = append(eventHandlers, f)
+}
+
+func dispatchEvent(e Event) {
+	for _, f := range eventHandlers {
+		f(e)
+	}
+}
+
+// BookmarkEvent is the content of a bookmark notification.
+type BookmarkEvent struct {
+	ID string `json:"id"`
+}
+
+// AnnotationEvent is the content of an annotation notification.
+type AnnotationEvent struct {
+	ID         string `json:"id"`
+	BookmarkID string `json:"bookmark_id"`
+}
+
+// Notify sends a bookmark notification to its owner.
+func (b *Bookmark) Notify(name string) {
+	if b.UserID == nil {
+		return
+	}
+	bus.Notify(*b.UserID, name, BookmarkEvent{ID: b.UID})
+	dispatchEvent(Event{Name: name, Bookmark: b})
+}
+
+// NotifyState sends a notification when the bookmark is
+// loaded or in error.
+func (b *Bookmark) NotifyState() {
+	switch b.State {
+	case StateLoaded:
+		b.Notify(EventBookmarkLoaded)
+	case StateError:
+		b.Notify(EventBookmarkError)
+	}
+}
+
+// NotifyAnnotation sends an annotation notification to
+// the bookmark's owner.
+func (b *Bookmark) NotifyAnnotation(name string, id string) {
+	if b.UserID == nil {
+		return
+	}
+	bus.Notify(*b.UserID, name, AnnotationEvent{ID: id, BookmarkID: b.UID})
+	dispatchEvent(Event{Name: name, Bookmark: b, AnnotationID: id})
+}
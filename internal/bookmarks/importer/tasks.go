@@ -21,6 +21,9 @@ import (
 	"codeberg.org/readeck/readeck/pkg/superbus"
 )
 
+// EventImportProgress is the notification sent when an import progresses.
+const EventImportProgress = "import.progress"
+
 var (
 	// ImportBookmarksTask is the bookmark import task.
 	ImportBookmarksTask superbus.Task
@@ -44,6 +47,7 @@ func init() {
 	bus.OnReady(func() {
 		ImportBookmarksTask = bus.Tasks().NewTask(
 			"bookmarks.import",
+			superbus.WithTaskQueue(bus.QueueImport),
 			superbus.WithUnmarshall(func(data []byte) interface{} {
 				var res ImportParams
 				err := json.Unmarshal(data, &res)
@@ -56,6 +60,7 @@ func init() {
 		)
 		ImportExtractTask = bus.Tasks().NewTask(
 			"bookmarks.import_extract",
+			superbus.WithTaskQueue(bus.QueueImport),
 			superbus.WithUnmarshall(func(data []byte) interface{} {
 				var res tasks.ExtractParams
 				err := json.Unmarshal(data, &res)
@@ -109,6 +114,7 @@ func importBookmarksHandler(data interface{}) {
 	imp.Import(func(ids []int) {
 		_ = setStoreProgressList(trackID, ids)
 	})
+	notifyProgress(imp.user.ID, trackID)
 }
 
 func importExtractHandler(data interface{}) {
@@ -121,13 +127,18 @@ func importExtractHandler(data interface{}) {
 	)
 
 	defer func() {
-		p, err := NewImportProgress(trackID)
-		if err != nil {
-			logger.Error("fetching progress", slog.Any("err", err))
+		var userID int
+		if _, err := db.Q().From(bookmarks.TableName).Prepared(true).
+			Select("user_id").
+			Where(goqu.C("id").Eq(params.BookmarkID)).
+			ScanVal(&userID); err != nil {
+			logger.Error("fetching bookmark", slog.Any("err", err))
 		}
 
+		p := notifyProgress(userID, trackID)
+
 		if p.Status == 1 {
-			if err = clearStoreProgressList(trackID); err != nil {
+			if err := clearStoreProgressList(trackID); err != nil {
 				logger.Error("clearing progress", slog.Any("err", err))
 			}
 			logger.Info("import finished")
@@ -137,6 +148,21 @@ func importExtractHandler(data interface{}) {
 	tasks.ExtractPage(params)
 }
 
+// notifyProgress sends the import progress to the user's connected
+// clients and returns it.
+func notifyProgress(userID int, trackID string) ImportProgress {
+	p, err := NewImportProgress(trackID)
+	if err != nil {
+		slog.Error("fetching progress", slog.String("track_id", trackID), slog.Any("err", err))
+		return p
+	}
+
+	if userID > 0 {
+		bus.Notify(userID, EventImportProgress, ImportProgressEvent{ID: trackID, ImportProgress: p})
+	}
+	return p
+}
+
 func getStoreProgressList(trackID string) (ids []int) {
 	ids = []int{}
 	data := bus.Store().Get("bookmark_import_" + trackID)
@@ -174,6 +200,12 @@ type ImportProgress struct {
 	Status int   `json:"status"`
 }
 
+// ImportProgressEvent is the content of an import progress notification.
+type ImportProgressEvent struct {
+	ID string `json:"id"`
+	ImportProgress
+}
+
 // NewImportProgress returns an ImportProgress instance based on a
 // trackID. It counts bookmarks with a state not StateLoading.
 func NewImportProgress(trackID string) (p ImportProgress, err error) {
@@ -26,7 +26,6 @@ import (
 	"codeberg.org/readeck/readeck/internal/auth"
 	"codeberg.org/readeck/readeck/internal/bookmarks"
 	"codeberg.org/readeck/readeck/internal/bookmarks/converter"
-	"codeberg.org/readeck/readeck/internal/bookmarks/tasks"
 	"codeberg.org/readeck/readeck/internal/server"
 	"codeberg.org/readeck/readeck/pkg/annotate"
 	"codeberg.org/readeck/readeck/pkg/forms"
@@ -67,7 +66,9 @@ func (api *apiRouter) bookmarkSyncList(w http.ResponseWriter, r *http.Request) {
 
 	urlPrefix := api.srv.AbsoluteURL(r, "./..").String()
 	for _, item := range bl {
-		item.Href = urlPrefix + item.ID
+		if item.Type == bookmarks.TombstoneBookmark && !item.IsDeleted {
+			item.Href = urlPrefix + item.ID
+		}
 	}
 	api.srv.Render(w, r, http.StatusOK, bl)
 }
@@ -285,10 +286,16 @@ func (api *apiRouter) bookmarkUpdate(w http.ResponseWriter, r *http.Request) {
 	api.srv.Render(w, r, http.StatusOK, updated)
 }
 
-// bookmarkDelete deletes a bookmark.
+// bookmarkDelete moves a bookmark to the trash. When the bookmark
+// is already in the trash, it's removed for good.
 func (api *apiRouter) bookmarkDelete(w http.ResponseWriter, r *http.Request) {
 	b := r.Context().Value(ctxBookmarkKey{}).(*bookmarks.Bookmark)
-	if err := b.Delete(); err != nil {
+
+	del := b.Trash
+	if b.IsDeleted() {
+		del = b.Delete
+	}
+	if err := del(); err != nil {
 		api.srv.Error(w, r, err)
 		return
 	}
@@ -296,6 +303,112 @@ func (api *apiRouter) bookmarkDelete(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// bookmarkBatchUpdate applies the same changes to several bookmarks.
+// The bookmarks are selected by their IDs, by the list filters given in
+// the query string, or both. All the changes are saved in one transaction.
+func (api *apiRouter) bookmarkBatchUpdate(w http.ResponseWriter, r *http.Request) {
+	tr := api.srv.Locale(r)
+	f := newBatchUpdateForm(tr)
+	forms.Bind(f, r)
+
+	filterForm := newFilterForm(tr)
+	forms.BindURL(filterForm, r)
+
+	// Without any ID or filter, the changes would apply to every bookmark.
+	ids, _ := f.Get("ids").Value().([]string)
+	if len(ids) == 0 && !filterForm.hasFilters() {
+		f.AddErrors("", forms.Gettext("no bookmark selected"))
+		api.srv.Render(w, r, http.StatusUnprocessableEntity, f)
+		return
+	}
+
+	if !f.IsValid() {
+		api.srv.Render(w, r, http.StatusBadRequest, f)
+		return
+	}
+	if !filterForm.IsValid() {
+		api.srv.Render(w, r, http.StatusBadRequest, filterForm)
+		return
+	}
+
+	// Restoring bookmarks only selects the ones in the trash.
+	ds := bookmarks.Bookmarks.Query()
+	if v, ok := f.Get("is_deleted").Value().(bool); ok && !v {
+		ds = bookmarks.Bookmarks.TrashQuery()
+	}
+
+	ds = ds.
+		Select(
+			"b.id", "b.uid", "b.user_id", "b.is_marked", "b.is_archived",
+			"b.read_progress", "b.read_anchor", "b.labels", "b.deleted").
+		Where(
+			goqu.C("user_id").Table("b").Eq(auth.GetRequestUser(r).ID),
+			bookmarkRestriction(r),
+		).
+		Order(goqu.I("created").Desc())
+
+	ds = bookmarks.NewFiltersFromForm(filterForm).ToSelectDataSet(ds)
+	if !filterForm.Get("id").IsNil() {
+		ds = ds.Where(goqu.C("uid").Table("b").In(filterForm.Get("id").Value().([]string)))
+	}
+	if len(ids) > 0 {
+		ds = ds.Where(goqu.C("uid").Table("b").In(ids))
+	}
+
+	items := []*bookmarks.Bookmark{}
+	if err := ds.ScanStructs(&items); err != nil {
+		api.srv.Error(w, r, err)
+		return
+	}
+
+	res := make([]batchResult, len(items))
+	updates := []bookmarks.BookmarkUpdate{}
+	deleted := make([]*bool, len(items))
+	found := map[string]bool{}
+
+	for i, b := range items {
+		found[b.UID] = true
+		updated, del := f.changes(b)
+		if len(updated) > 0 {
+			updates = append(updates, bookmarks.BookmarkUpdate{Bookmark: b, Values: updated})
+		}
+		deleted[i] = del
+
+		res[i] = batchResult{
+			ID:      b.UID,
+			Href:    api.srv.AbsoluteURL(r, ".", b.UID).String(),
+			Status:  http.StatusOK,
+			Updated: updated,
+		}
+	}
+
+	if err := bookmarks.Bookmarks.UpdateAll(updates); err != nil {
+		api.srv.Error(w, r, err)
+		return
+	}
+
+	for i := range items {
+		if deleted[i] != nil {
+			delete(res[i].Updated, "deleted")
+			res[i].Updated["is_deleted"] = *deleted[i]
+		}
+	}
+
+	// Requested IDs that don't match any bookmark.
+	for _, id := range ids {
+		if !found[id] {
+			found[id] = true
+			res = append(res, batchResult{
+				ID:     id,
+				Status: http.StatusNotFound,
+				Error:  "not found",
+			})
+		}
+	}
+
+	api.srv.Render(w, r, http.StatusOK, res)
+}
+
 // bookmarkShareLink returns a publicly shared bookmark link.
 func (api *apiRouter) bookmarkShareLink(w http.ResponseWriter, r *http.Request) {
 	info := r.Context().Value(ctxSharedInfoKey{}).(linkShareInfo)
@@ -349,6 +462,7 @@ func (api *apiRouter) labelInfo(w http.ResponseWriter, r *http.Request) {
 		Where(
 			goqu.C("user_id").Table("b").Eq(auth.GetRequestUser(r).ID),
 			goqu.I("name").Eq(label),
+			bookmarkRestriction(r),
 		)
 
 	var res labelItem
@@ -435,6 +549,7 @@ func (api *apiRouter) annotationCreate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	b.NotifyAnnotation(bookmarks.EventAnnotationCreated, annotation.ID)
 	w.Header().Add("Location", api.srv.AbsoluteURL(r, ".", annotation.ID).String())
 	api.srv.Render(w, r, http.StatusCreated, annotation)
 }
@@ -470,6 +585,7 @@ func (api *apiRouter) annotationUpdate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	b.NotifyAnnotation(bookmarks.EventAnnotationUpdated, id)
 	api.srv.Render(w, r, http.StatusOK, update)
 }
 
@@ -485,11 +601,7 @@ func (api *apiRouter) annotationDelete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	b.Annotations.Delete(id)
-	err := b.Update(map[string]interface{}{
-		"annotations": b.Annotations,
-	})
-	if err != nil {
+	if err := bookmarks.Trash.TrashAnnotation(b, id); err != nil {
 		api.srv.Error(w, r, err)
 		return
 	}
@@ -513,6 +625,7 @@ func (api *apiRouter) withBookmark(next http.Handler) http.Handler {
 		b, err := bookmarks.Bookmarks.GetOne(
 			goqu.C("uid").Eq(uid),
 			goqu.C("user_id").Eq(auth.GetRequestUser(r).ID),
+			bookmarkRestriction(r),
 		)
 		if err != nil {
 			api.srv.Status(w, r, http.StatusNotFound)
@@ -622,6 +735,7 @@ func (api *apiRouter) withCollectionFilters(next http.Handler) http.Handler {
 			c, err = bookmarks.Collections.GetOne(
 				goqu.C("uid").Eq(uid),
 				goqu.C("user_id").Eq(auth.GetRequestUser(r).ID),
+				collectionRestriction(r),
 			)
 			if err != nil {
 				api.srv.Status(w, r, http.StatusNotFound)
@@ -689,6 +803,7 @@ func (api *apiRouter) withBookmarkList(next http.Handler) http.Handler {
 				"b.labels", "b.description", "b.word_count", "b.duration", "b.file_path", "b.files").
 			Where(
 				goqu.C("user_id").Table("b").Eq(auth.GetRequestUser(r).ID),
+				bookmarkRestriction(r),
 			)
 
 		ds = ds.Order(goqu.I("created").Desc())
@@ -773,20 +888,65 @@ func (api *apiRouter) withBookmarkList(next http.Handler) http.Handler {
 
 func (api *apiRouter) withBookmarkSyncList(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		ds := bookmarks.Bookmarks.Query().
-			Select("b.uid", "b.created", "b.updated").
-			Where(goqu.C("user_id").Table("b").Eq(auth.GetRequestUser(r).ID)).
+		user := auth.GetRequestUser(r)
+		ds := bookmarks.Bookmarks.QueryAll().
+			Select("b.uid", "b.created", "b.updated", "b.deleted").
+			Where(
+				goqu.C("user_id").Table("b").Eq(user.ID),
+				bookmarkRestriction(r),
+			).
 			Order(
 				goqu.I("updated").Desc(),
 				goqu.I("created").Desc(),
 			)
 
+		// With a "since" parameter, we only return the bookmarks updated
+		// after the given date, and every item deleted in the meantime.
+		var since time.Time
+		if v := r.URL.Query().Get("since"); v != "" {
+			var err error
+			if since, err = time.Parse(time.RFC3339, v); err != nil {
+				api.srv.TextMessage(w, r, http.StatusBadRequest, "invalid since parameter")
+				return
+			}
+			// Dates are stored in the server's timezone and SQLite compares them as strings.
+			since = since.Local()
+			ds = ds.Where(goqu.C("updated").Table("b").Gte(since))
+		} else {
+			ds = ds.Where(goqu.C("deleted").Table("b").IsNull())
+		}
+
 		res := bookmarkSyncList{}
 
 		if err := ds.ScanStructs(&res); err != nil {
 			api.srv.Error(w, r, err)
 			return
 		}
+		// Bookmarks in the trash are deleted for the clients.
+		for _, item := range res {
+			item.Type = bookmarks.TombstoneBookmark
+			item.IsDeleted = item.Deleted != nil
+		}
+
+		if !since.IsZero() {
+			tombstones, err := bookmarks.Tombstones.Since(user.ID, since)
+			if err == nil {
+				tombstones, err = restrictTombstones(r, tombstones)
+			}
+			if err != nil {
+				api.srv.Error(w, r, err)
+				return
+			}
+			for _, t := range tombstones {
+				res = append(res, &bookmarkSyncItem{
+					ID:         t.UID,
+					Type:       t.Kind,
+					Updated:    t.Deleted,
+					IsDeleted:  true,
+					BookmarkID: t.ParentUID,
+				})
+			}
+		}
 
 		ctx := context.WithValue(r.Context(), ctxBookmarkSyncListKey{}, res)
 		tagers := []server.Etager{res}
@@ -814,6 +974,7 @@ func (api *apiRouter) withAnnotationList(next http.Handler) http.Handler {
 		ds := bookmarks.Bookmarks.GetAnnotations().
 			Where(
 				goqu.C("user_id").Table("b").Eq(auth.GetRequestUser(r).ID),
+				bookmarkRestriction(r),
 			)
 
 		ds = ds.
@@ -852,6 +1013,7 @@ func (api *apiRouter) withLabelList(next http.Handler) http.Handler {
 		ds := bookmarks.Bookmarks.GetLabels().
 			Where(
 				goqu.C("user_id").Table("b").Eq(auth.GetRequestUser(r).ID),
+				bookmarkRestriction(r),
 			)
 
 		f := newLabelSearchForm(api.srv.Locale(r))
@@ -943,6 +1105,15 @@ func (api *apiRouter) withShareEmail(next http.Handler) http.Handler {
 	})
 }
 
+// batchResult is the result of a batch update on one bookmark.
+type batchResult struct {
+	ID      string                 `json:"id"`
+	Href    string                 `json:"href,omitempty"`
+	Status  int                    `json:"status"`
+	Error   string                 `json:"error,omitempty"`
+	Updated map[string]interface{} `json:"updated,omitempty"`
+}
+
 // bookmarkList is a paginated list of BookmarkItem instances.
 type bookmarkList struct {
 	items      []*bookmarks.Bookmark
@@ -1032,7 +1203,7 @@ func newBookmarkItem(s *server.Server, r *http.Request, b *bookmarks.Bookmark, b
 		TextDirection: b.TextDirection,
 		DocumentType:  b.DocumentType,
 		Description:   b.Description,
-		IsDeleted:     tasks.DeleteBookmarkTask.IsRunning(b.ID),
+		IsDeleted:     b.IsDeleted(),
 		IsMarked:      b.IsMarked,
 		IsArchived:    b.IsArchived,
 		ReadProgress:  b.ReadProgress,
@@ -1225,17 +1396,21 @@ type bookmarkSyncList []*bookmarkSyncItem
 func (bl bookmarkSyncList) GetSumStrings() []string {
 	r := []string{}
 	for i := range bl {
-		r = append(r, bl[i].Updated.String(), bl[i].ID)
+		r = append(r, bl[i].Type, bl[i].Updated.String(), bl[i].ID)
 	}
 
 	return r
 }
 
 type bookmarkSyncItem struct {
-	ID      string    `json:"id" db:"uid"`
-	Href    string    `json:"href" db:"-"`
-	Created time.Time `json:"created" db:"created"`
-	Updated time.Time `json:"updated" db:"updated"`
+	ID         string     `json:"id" db:"uid"`
+	Type       string     `json:"type" db:"-"`
+	Href       string     `json:"href,omitempty" db:"-"`
+	Created    time.Time  `json:"created,omitzero" db:"created"`
+	Updated    time.Time  `json:"updated" db:"updated"`
+	IsDeleted  bool       `json:"is_deleted,omitempty" db:"-"`
+	BookmarkID string     `json:"bookmark_id,omitempty" db:"-"`
+	Deleted    *time.Time `json:"-" db:"deleted"`
 }
 
 type labelItem struct {
@@ -5,10 +5,15 @@
 package routes_test
 
 import (
+	"strings"
 	"testing"
 
+	"github.com/doug-martin/goqu/v9"
 	"github.com/stretchr/testify/require"
 
+	"codeberg.org/readeck/readeck/internal/auth/tokens"
+	"codeberg.org/readeck/readeck/internal/bookmarks"
+	"codeberg.org/readeck/readeck/internal/db/types"
 	. "codeberg.org/readeck/readeck/internal/testing" //revive:disable:dot-imports
 )
 
@@ -44,3 +49,451 @@ func TestBookmarkAPIShare(t *testing.T) {
 		},
 	)
 }
+
+func TestBookmarkAPISync(t *testing.T) {
+	app := NewTestApp(t)
+	defer func() {
+		app.Close(t)
+	}()
+
+	client := NewClient(t, app)
+	u := app.Users["user"]
+	deleted := u.Bookmarks[0].UID
+	require.NoError(t, u.Bookmarks[0].Delete())
+
+	RunRequestSequence(t, client, "user",
+		RequestTest{
+			Target:       "/api/bookmarks/sync?since=invalid",
+			ExpectStatus: 400,
+		},
+		RequestTest{
+			Target:       "/api/bookmarks/sync",
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				items := r.JSON.([]any)
+				require.Len(t, items, len(u.Bookmarks)-1)
+				for _, x := range items {
+					require.Equal(t, "bookmark", x.(map[string]any)["type"])
+					require.NotContains(t, x, "is_deleted")
+				}
+			},
+		},
+		RequestTest{
+			Target:       "/api/bookmarks/sync?since=2000-01-01T00:00:00Z",
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				items := r.JSON.([]any)
+				require.Len(t, items, len(u.Bookmarks))
+
+				tombstone := items[len(items)-1].(map[string]any)
+				require.Equal(t, deleted, tombstone["id"])
+				require.Equal(t, "bookmark", tombstone["type"])
+				require.Equal(t, true, tombstone["is_deleted"])
+				require.NotContains(t, tombstone, "href")
+			},
+		},
+		RequestTest{
+			Target:       "/api/bookmarks/sync?since=2100-01-01T00:00:00Z",
+			ExpectStatus: 200,
+			ExpectJSON:   `[]`,
+		},
+	)
+}
+
+func TestBookmarkAPIBatch(t *testing.T) {
+	app := NewTestApp(t)
+	defer func() {
+		app.Close(t)
+	}()
+
+	client := NewClient(t, app)
+	u := app.Users["user"]
+
+	items := []*bookmarks.Bookmark{u.Bookmarks[0]}
+	for _, url := range []string{"https://example.net/a", "https://example.net/b"} {
+		b := &bookmarks.Bookmark{
+			UserID: &u.User.ID,
+			URL:    url,
+			State:  bookmarks.StateLoaded,
+			Labels: types.Strings{"batch"},
+		}
+		require.NoError(t, bookmarks.Bookmarks.Create(b))
+		items = append(items, b)
+	}
+
+	getBookmark := func(uid string) *bookmarks.Bookmark {
+		b, err := bookmarks.Bookmarks.GetOne(goqu.C("uid").Eq(uid))
+		require.NoError(t, err)
+		return b
+	}
+
+	RunRequestSequence(t, client, "user",
+		RequestTest{
+			Method:       "PATCH",
+			Target:       "/api/bookmarks",
+			JSON:         map[string]any{"is_marked": true},
+			ExpectStatus: 422,
+			ExpectJSON: `{
+				"is_valid": false,
+				"errors": ["no bookmark selected"],
+				"fields": "<<PRESENCE>>"
+			}`,
+		},
+		RequestTest{
+			Method:       "PATCH",
+			Target:       "/api/bookmarks?foo=1",
+			JSON:         map[string]any{"is_marked": true},
+			ExpectStatus: 422,
+		},
+		RequestTest{
+			Method:       "PATCH",
+			Target:       "/api/bookmarks?search=&labels=",
+			JSON:         map[string]any{"is_marked": true},
+			ExpectStatus: 422,
+			Assert: func(t *testing.T, _ *Response) {
+				for _, b := range items {
+					require.False(t, getBookmark(b.UID).IsMarked)
+				}
+			},
+		},
+		RequestTest{
+			Method:       "PATCH",
+			Target:       "/api/bookmarks",
+			JSON:         map[string]any{"ids": []string{items[0].UID}, "read_progress": 120},
+			ExpectStatus: 400,
+		},
+		RequestTest{
+			Method: "PATCH",
+			Target: "/api/bookmarks",
+			JSON: map[string]any{
+				"ids":           []string{items[1].UID, items[0].UID, "abcdefghijklmnopqrstuv"},
+				"is_archived":   true,
+				"add_labels":    []string{"new"},
+				"remove_labels": []string{"batch", "test label"},
+			},
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				r.AssertJQ(t, "length", 3)
+				r.AssertJQ(t, "[.[].status]", []any{200.0, 200.0, 404.0})
+				r.AssertJQ(t, ".[2].id", "abcdefghijklmnopqrstuv")
+				r.AssertJQ(t, ".[0].updated.labels", []any{"new"})
+				r.AssertJQ(t, ".[0].updated.is_archived", true)
+
+				for _, b := range items[0:2] {
+					b = getBookmark(b.UID)
+					require.True(t, b.IsArchived)
+					require.Equal(t, types.Strings{"new"}, b.Labels)
+				}
+				require.False(t, getBookmark(items[2].UID).IsArchived)
+			},
+		},
+		RequestTest{
+			Method: "PATCH",
+			Target: "/api/bookmarks?labels=batch",
+			JSON: map[string]any{
+				"is_marked":     true,
+				"read_progress": 100,
+			},
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				r.AssertJQ(t, "length", 1)
+				r.AssertJQ(t, ".[0].id", items[2].UID)
+				r.AssertJQ(t, ".[0].updated.is_marked", true)
+
+				b := getBookmark(items[2].UID)
+				require.True(t, b.IsMarked)
+				require.Equal(t, 100, b.ReadProgress)
+				require.False(t, getBookmark(items[0].UID).IsMarked)
+			},
+		},
+		RequestTest{
+			Method:       "PATCH",
+			Target:       "/api/bookmarks?is_archived=1",
+			JSON:         map[string]any{"is_deleted": true},
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				r.AssertJQ(t, "length", 2)
+				r.AssertJQ(t, "[.[].updated.is_deleted]", []any{true, true})
+
+				for _, b := range items[0:2] {
+					require.True(t, getBookmark(b.UID).IsDeleted())
+				}
+				require.False(t, getBookmark(items[2].UID).IsDeleted())
+			},
+		},
+	)
+
+	// Another user's bookmarks are never selected
+	RunRequestSequence(t, client, "staff",
+		RequestTest{
+			Method:       "PATCH",
+			Target:       "/api/bookmarks",
+			JSON:         map[string]any{"ids": []string{items[2].UID}, "is_marked": false},
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				r.AssertJQ(t, "[.[].status]", []any{404.0})
+				require.True(t, getBookmark(items[2].UID).IsMarked)
+			},
+		},
+	)
+}
+
+func TestBookmarkAPIRestriction(t *testing.T) {
+	app := NewTestApp(t)
+	defer func() {
+		app.Close(t)
+	}()
+
+	client := NewClient(t, app)
+	u := app.Users["user"]
+
+	newBookmark := func(title string, labels ...string) *bookmarks.Bookmark {
+		b := &bookmarks.Bookmark{
+			UserID: &u.User.ID,
+			URL:    "https://example.org/" + title,
+			Title:  title,
+			State:  bookmarks.StateLoaded,
+			Labels: labels,
+		}
+		require.NoError(t, bookmarks.Bookmarks.Create(b))
+		return b
+	}
+	alpha := newBookmark("alpha", "widget")
+	beta := newBookmark("beta", "widget", "family")
+	gamma := newBookmark("gamma")
+
+	collection := &bookmarks.Collection{
+		UserID:  &u.User.ID,
+		Name:    "Reading list",
+		Filters: bookmarks.Filters{Labels: `"family"`},
+	}
+	require.NoError(t, bookmarks.Collections.Create(collection))
+
+	restrict := func(t *testing.T, r *tokens.Restriction) {
+		u.Token.Restriction = r
+		require.NoError(t, u.Token.Save())
+	}
+	defer restrict(t, nil)
+
+	listIDs := func(t *testing.T, r *Response) []string {
+		ids := []string{}
+		for _, x := range r.JSON.([]any) {
+			ids = append(ids, x.(map[string]any)["id"].(string))
+		}
+		return ids
+	}
+
+	t.Run("labels", func(t *testing.T) {
+		restrict(t, &tokens.Restriction{Labels: []string{"widget", "test label"}})
+
+		RunRequestSequence(t, client, "user",
+			RequestTest{
+				Target:       "/api/bookmarks",
+				JSON:         true,
+				ExpectStatus: 200,
+				Assert: func(t *testing.T, r *Response) {
+					require.ElementsMatch(t,
+						[]string{u.Bookmarks[0].UID, alpha.UID, beta.UID},
+						listIDs(t, r),
+					)
+				},
+			},
+			RequestTest{
+				Target:       "/api/bookmarks/" + alpha.UID,
+				JSON:         true,
+				ExpectStatus: 200,
+			},
+			RequestTest{
+				Target:       "/api/bookmarks/" + gamma.UID,
+				JSON:         true,
+				ExpectStatus: 404,
+			},
+			RequestTest{
+				Method:       "PATCH",
+				Target:       "/api/bookmarks/" + gamma.UID,
+				JSON:         map[string]any{"is_marked": true},
+				ExpectStatus: 404,
+			},
+			RequestTest{
+				Target:       "/api/bookmarks/" + gamma.UID + "/article.md",
+				JSON:         true,
+				ExpectStatus: 404,
+			},
+			RequestTest{
+				Target:       "/api/bookmarks/labels",
+				JSON:         true,
+				ExpectStatus: 200,
+				Assert: func(t *testing.T, r *Response) {
+					r.AssertJQ(t, "[.[].name]", []any{"family", "test label", "widget"})
+				},
+			},
+			RequestTest{
+				Method:       "PATCH",
+				Target:       "/api/bookmarks/labels/widget",
+				JSON:         map[string]any{"name": "gadget"},
+				ExpectStatus: 403,
+			},
+			RequestTest{
+				Method:       "DELETE",
+				Target:       "/api/bookmarks/trash",
+				JSON:         true,
+				ExpectStatus: 403,
+			},
+		)
+	})
+
+	t.Run("collection", func(t *testing.T) {
+		restrict(t, &tokens.Restriction{Collection: collection.UID})
+
+		// Removed items of the user
+		for _, x := range [][3]string{
+			{bookmarks.TombstoneBookmark, "deleted-bookmark", ""},
+			{bookmarks.TombstoneLabel, "old label", ""},
+			{bookmarks.TombstoneCollection, "deleted-collection", ""},
+			{bookmarks.TombstoneAnnotation, "beta-annotation", beta.UID},
+			{bookmarks.TombstoneAnnotation, "gamma-annotation", gamma.UID},
+		} {
+			require.NoError(t, bookmarks.Tombstones.Add(u.User.ID, x[0], x[1], x[2]))
+		}
+
+		RunRequestSequence(t, client, "user",
+			RequestTest{
+				// Only the removed items of the bookmarks the token
+				// can access are listed.
+				Target:       "/api/bookmarks/sync?since=2000-01-01T00:00:00Z",
+				JSON:         true,
+				ExpectStatus: 200,
+				Assert: func(t *testing.T, r *Response) {
+					require.Equal(t, []string{beta.UID, "beta-annotation"}, listIDs(t, r))
+				},
+			},
+			RequestTest{
+				Target:       "/api/bookmarks",
+				JSON:         true,
+				ExpectStatus: 200,
+				Assert: func(t *testing.T, r *Response) {
+					require.Equal(t, []string{beta.UID}, listIDs(t, r))
+				},
+			},
+			RequestTest{
+				Target:       "/api/bookmarks/collections",
+				JSON:         true,
+				ExpectStatus: 200,
+				Assert: func(t *testing.T, r *Response) {
+					r.AssertJQ(t, "[.[].id]", []any{collection.UID})
+				},
+			},
+			RequestTest{
+				Target:       "/api/bookmarks/export.md",
+				JSON:         true,
+				ExpectStatus: 200,
+				Assert: func(t *testing.T, r *Response) {
+					require.Contains(t, string(r.Body), beta.URL)
+					require.NotContains(t, string(r.Body), alpha.URL)
+				},
+			},
+			RequestTest{
+				Target:       "/opds/bookmarks/all",
+				JSON:         true,
+				ExpectStatus: 200,
+				Assert: func(t *testing.T, r *Response) {
+					require.Contains(t, string(r.Body), beta.UID)
+					require.NotContains(t, string(r.Body), alpha.UID)
+				},
+			},
+		)
+
+		// A deleted collection gives access to nothing.
+		require.NoError(t, collection.Delete())
+		RunRequestSequence(t, client, "user",
+			RequestTest{
+				Target:       "/api/bookmarks",
+				JSON:         true,
+				ExpectStatus: 200,
+				ExpectJSON:   `[]`,
+			},
+		)
+	})
+
+	t.Run("search", func(t *testing.T) {
+		restrict(t, &tokens.Restriction{Search: "gamma"})
+
+		RunRequestSequence(t, client, "user",
+			RequestTest{
+				Target:       "/api/bookmarks",
+				JSON:         true,
+				ExpectStatus: 200,
+				Assert: func(t *testing.T, r *Response) {
+					require.Equal(t, []string{gamma.UID}, listIDs(t, r))
+				},
+			},
+			RequestTest{
+				Target:       "/api/bookmarks/" + beta.UID,
+				JSON:         true,
+				ExpectStatus: 404,
+			},
+		)
+	})
+}
+
+func TestBookmarkAPISyncCollection(t *testing.T) {
+	app := NewTestApp(t)
+	defer func() {
+		app.Close(t)
+	}()
+
+	client := NewClient(t, app)
+
+	collectionTombstones := func(r *Response) []any {
+		res := []any{}
+		for _, x := range r.JSON.([]any) {
+			if x.(map[string]any)["type"] == "collection" {
+				res = append(res, x.(map[string]any)["id"])
+			}
+		}
+		return res
+	}
+
+	var location string
+
+	RunRequestSequence(t, client, "user",
+		RequestTest{
+			Method:       "POST",
+			Target:       "/api/bookmarks/collections",
+			JSON:         map[string]any{"name": "test"},
+			ExpectStatus: 201,
+			Assert: func(_ *testing.T, r *Response) {
+				location = r.Redirect
+			},
+		},
+		RequestTest{
+			Method:       "PATCH",
+			Target:       "{{ (index .History 0).Redirect }}",
+			JSON:         map[string]any{"name": "renamed", "is_marked": true},
+			ExpectStatus: 200,
+		},
+		RequestTest{
+			// An updated collection is not removed
+			Target:       "/api/bookmarks/sync?since=2000-01-01T00:00:00Z",
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				require.Empty(t, collectionTombstones(r))
+			},
+		},
+		RequestTest{
+			Method:       "DELETE",
+			Target:       "{{ (index .History 2).Redirect }}",
+			JSON:         true,
+			ExpectStatus: 204,
+		},
+		RequestTest{
+			Target:       "/api/bookmarks/sync?since=2000-01-01T00:00:00Z",
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				tombstones := collectionTombstones(r)
+				require.Len(t, tombstones, 1)
+				require.True(t, strings.HasSuffix(location, "/"+tombstones[0].(string)))
+			},
+		},
+	)
+}
@@ -15,7 +15,6 @@ import (
 
 	"codeberg.org/readeck/readeck/internal/auth"
 	"codeberg.org/readeck/readeck/internal/bookmarks"
-	"codeberg.org/readeck/readeck/internal/bookmarks/tasks"
 	"codeberg.org/readeck/readeck/internal/db/types"
 	"codeberg.org/readeck/readeck/internal/server"
 	"codeberg.org/readeck/readeck/pkg/forms"
@@ -85,9 +84,16 @@ func (api *apiRouter) collectionUpdate(w http.ResponseWriter, r *http.Request) {
 	api.srv.Render(w, r, http.StatusOK, updated)
 }
 
+// collectionDelete moves a collection to the trash. When the collection
+// is already in the trash, it's removed for good.
 func (api *apiRouter) collectionDelete(w http.ResponseWriter, r *http.Request) {
 	c := r.Context().Value(ctxCollectionKey{}).(*bookmarks.Collection)
-	if err := c.Delete(); err != nil {
+
+	del := c.Trash
+	if c.IsDeleted() {
+		del = c.Delete
+	}
+	if err := del(); err != nil {
 		api.srv.Error(w, r, err)
 		return
 	}
@@ -112,6 +118,7 @@ func (api *apiRouter) withColletionList(next http.Handler) http.Handler {
 			).
 			Where(
 				goqu.C("user_id").Table("c").Eq(auth.GetRequestUser(r).ID),
+				collectionRestriction(r),
 			)
 
 		ds = ds.Order(goqu.I("name").Asc()).
@@ -150,6 +157,7 @@ func (api *apiRouter) withCollection(next http.Handler) http.Handler {
 		c, err := bookmarks.Collections.GetOne(
 			goqu.C("uid").Eq(uid),
 			goqu.C("user_id").Eq(auth.GetRequestUser(r).ID),
+			collectionRestriction(r),
 		)
 		if err != nil {
 			api.srv.Status(w, r, http.StatusNotFound)
@@ -210,7 +218,7 @@ func newCollectionItem(s *server.Server, r *http.Request, c *bookmarks.Collectio
 		Updated:    c.Updated,
 		Name:       c.Name,
 		IsPinned:   c.IsPinned,
-		IsDeleted:  tasks.DeleteCollectionTask.IsRunning(c.ID),
+		IsDeleted:  c.IsDeleted(),
 
 		// Filters
 		Search:     c.Filters.Search,
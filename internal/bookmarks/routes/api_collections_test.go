@@ -379,6 +379,20 @@ func TestCollectionAPI(t *testing.T) {
 			Target:       "{{ (index .History 0).Path }}",
 			ExpectStatus: 204,
 		},
+		RequestTest{
+			JSON:         true,
+			Target:       "{{ (index .History 0).Path }}",
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				r.AssertJQ(t, ".is_deleted", true)
+			},
+		},
+		RequestTest{
+			JSON:         true,
+			Method:       "DELETE",
+			Target:       "{{ (index .History 0).Path }}",
+			ExpectStatus: 204,
+		},
 		RequestTest{
 			JSON:         true,
 			Target:       "{{ (index .History 0).Path }}",
@@ -0,0 +1,116 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package routes
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+
+	"codeberg.org/readeck/readeck/internal/auth"
+	"codeberg.org/readeck/readeck/internal/bookmarks"
+	"codeberg.org/readeck/readeck/internal/bus"
+)
+
+// eventKeepAlive is the interval between two comments sent to
+// keep the connection open when there's no event.
+const eventKeepAlive = 30 * time.Second
+
+// eventStream sends the current user's notifications as server-sent events.
+// With a restricted token, only the events of the bookmarks it can access
+// are sent.
+func (api *apiRouter) eventStream(w http.ResponseWriter, r *http.Request) {
+	sub := bus.Subscribe(auth.GetRequestUser(r).ID)
+	defer sub.Close()
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	send := func(format string, args ...any) bool {
+		if _, err := fmt.Fprintf(w, format, args...); err != nil {
+			return false
+		}
+		return rc.Flush() == nil
+	}
+
+	if !send(": connected\n\n") {
+		return
+	}
+
+	ticker := time.NewTicker(eventKeepAlive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+			if !send(": ping\n\n") {
+				return
+			}
+		case msg, ok := <-sub.C:
+			if !ok {
+				return
+			}
+
+			var n bus.Notification
+			if err := json.Unmarshal(msg, &n); err != nil {
+				api.srv.Log(r).Error("decoding notification", slog.Any("err", err))
+				continue
+			}
+			if !api.eventAllowed(r, n) {
+				continue
+			}
+			if !send("event: %s\ndata: %s\n\n", n.Name, n.Data) {
+				return
+			}
+		}
+	}
+}
+
+// eventAllowed returns true when a notification can be sent with the
+// request's token. A token restricted to some bookmarks only receives
+// the events of these bookmarks, including the ones in the trash.
+func (api *apiRouter) eventAllowed(r *http.Request, n bus.Notification) bool {
+	info := auth.GetRequestAuthInfo(r)
+	if info.Provider == nil || info.Provider.Restriction.IsEmpty() {
+		return true
+	}
+
+	var data struct {
+		ID         string `json:"id"`
+		BookmarkID string `json:"bookmark_id"`
+	}
+	if err := json.Unmarshal(n.Data, &data); err != nil {
+		return false
+	}
+
+	uid := data.BookmarkID
+	if strings.HasPrefix(n.Name, "bookmark.") {
+		uid = data.ID
+	}
+	if uid == "" {
+		return false
+	}
+
+	count, err := bookmarks.Bookmarks.QueryAll().Where(
+		goqu.C("uid").Table("b").Eq(uid),
+		goqu.C("user_id").Table("b").Eq(info.User.ID),
+		bookmarkRestriction(r),
+	).Count()
+	if err != nil {
+		api.srv.Log(r).Error("checking notification", slog.Any("err", err))
+		return false
+	}
+	return count > 0
+}
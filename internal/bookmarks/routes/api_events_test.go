@@ -0,0 +1,158 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package routes_test
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"codeberg.org/readeck/readeck/internal/auth/tokens"
+	"codeberg.org/readeck/readeck/internal/bookmarks"
+	"codeberg.org/readeck/readeck/internal/bus"
+	"codeberg.org/readeck/readeck/internal/db/types"
+	. "codeberg.org/readeck/readeck/internal/testing" //revive:disable:dot-imports
+)
+
+// openEventStream connects to the event stream with a user's token and
+// returns a function reading the next event. The subscription is ready
+// once the first comment is received.
+func openEventStream(t *testing.T, app *TestApp, u *TestUser) func() []string {
+	srv := httptest.NewServer(app.Srv.Router)
+	t.Cleanup(srv.Close)
+
+	// The stream never ends, the deadline stops a test waiting
+	// for an event that doesn't come.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	t.Cleanup(cancel)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/api/bookmarks/events", nil)
+	require.NoError(t, err)
+	req.Host = "readeck.example.org"
+	req.Header.Set("Authorization", "Bearer "+u.APIToken())
+
+	rsp, err := srv.Client().Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		rsp.Body.Close() //nolint:errcheck,gosec
+	})
+
+	require.Equal(t, http.StatusOK, rsp.StatusCode)
+	require.Equal(t, "text/event-stream", rsp.Header.Get("Content-Type"))
+
+	lines := bufio.NewScanner(rsp.Body)
+	readEvent := func() []string {
+		res := []string{}
+		for lines.Scan() {
+			if lines.Text() == "" {
+				return res
+			}
+			res = append(res, lines.Text())
+		}
+		return res
+	}
+
+	require.Equal(t, []string{": connected"}, readEvent())
+	return readEvent
+}
+
+func TestBookmarkAPIEvents(t *testing.T) {
+	app := NewTestApp(t)
+	defer func() {
+		app.Close(t)
+	}()
+
+	u := app.Users["user"]
+	readEvent := openEventStream(t, app, u)
+
+	// Another user's notifications are not sent
+	bus.Notify(app.Users["admin"].User.ID, bookmarks.EventBookmarkCreated, bookmarks.BookmarkEvent{ID: "abc"})
+
+	b := u.Bookmarks[0]
+	require.NoError(t, b.Update(map[string]any{"is_marked": true}))
+	require.Equal(t, []string{
+		"event: bookmark.updated",
+		`data: {"id":"` + b.UID + `"}`,
+	}, readEvent())
+	require.Equal(t, []string{
+		"event: bookmark.marked",
+		`data: {"id":"` + b.UID + `"}`,
+	}, readEvent())
+
+	b.NotifyAnnotation(bookmarks.EventAnnotationDeleted, "xyz")
+	require.Equal(t, []string{
+		"event: annotation.deleted",
+		`data: {"id":"xyz","bookmark_id":"` + b.UID + `"}`,
+	}, readEvent())
+
+	// Trashed and restored bookmarks
+	require.NoError(t, b.Trash())
+	require.Equal(t, "event: bookmark.updated", readEvent()[0])
+	require.Equal(t, []string{
+		"event: bookmark.deleted",
+		`data: {"id":"` + b.UID + `"}`,
+	}, readEvent())
+
+	require.NoError(t, b.Restore())
+	require.Equal(t, "event: bookmark.updated", readEvent()[0])
+	require.Equal(t, []string{
+		"event: bookmark.created",
+		`data: {"id":"` + b.UID + `"}`,
+	}, readEvent())
+
+	require.NoError(t, b.Delete())
+	event := readEvent()
+	require.Equal(t, "event: bookmark.deleted", event[0])
+	require.True(t, strings.Contains(event[1], b.UID))
+}
+
+func TestBookmarkAPIEventsRestriction(t *testing.T) {
+	app := NewTestApp(t)
+	defer func() {
+		app.Close(t)
+	}()
+
+	u := app.Users["user"]
+	alpha := &bookmarks.Bookmark{
+		UserID: &u.User.ID,
+		URL:    "https://example.org/alpha",
+		State:  bookmarks.StateLoaded,
+		Labels: types.Strings{"widget"},
+	}
+	require.NoError(t, bookmarks.Bookmarks.Create(alpha))
+
+	u.Token.Restriction = &tokens.Restriction{Labels: []string{"widget"}}
+	require.NoError(t, u.Token.Save())
+
+	readEvent := openEventStream(t, app, u)
+
+	// The events of other bookmarks are not sent
+	b := u.Bookmarks[0]
+	require.NoError(t, b.Update(map[string]any{"is_marked": true}))
+	b.NotifyAnnotation(bookmarks.EventAnnotationDeleted, "xyz")
+	bus.Notify(u.User.ID, "import.progress", map[string]any{"id": "abc"})
+
+	require.NoError(t, alpha.Trash())
+	require.Equal(t, []string{
+		"event: bookmark.updated",
+		`data: {"id":"` + alpha.UID + `"}`,
+	}, readEvent())
+	require.Equal(t, []string{
+		"event: bookmark.deleted",
+		`data: {"id":"` + alpha.UID + `"}`,
+	}, readEvent())
+
+	alpha.NotifyAnnotation(bookmarks.EventAnnotationCreated, "xyz")
+	require.Equal(t, []string{
+		"event: annotation.created",
+		`data: {"id":"xyz","bookmark_id":"` + alpha.UID + `"}`,
+	}, readEvent())
+}
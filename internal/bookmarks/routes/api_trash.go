@@ -0,0 +1,180 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package routes
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+
+	"codeberg.org/readeck/readeck/internal/auth"
+	"codeberg.org/readeck/readeck/internal/auth/users"
+	"codeberg.org/readeck/readeck/internal/bookmarks"
+	"codeberg.org/readeck/readeck/pkg/forms"
+)
+
+type (
+	ctxTrashListKey struct{}
+)
+
+// trashList returns the items in the trash.
+func (api *apiRouter) trashList(w http.ResponseWriter, r *http.Request) {
+	items := r.Context().Value(ctxTrashListKey{}).(trashList)
+	api.srv.Render(w, r, http.StatusOK, items)
+}
+
+// trashRestore restores some or all the items in the trash.
+func (api *apiRouter) trashRestore(w http.ResponseWriter, r *http.Request) {
+	f := newTrashForm(api.srv.Locale(r))
+	forms.Bind(f, r)
+
+	if !f.IsValid() {
+		api.srv.Render(w, r, http.StatusUnprocessableEntity, f)
+		return
+	}
+
+	items := r.Context().Value(ctxTrashListKey{}).(trashList)
+	restored, err := f.restore(items)
+	if err != nil {
+		api.srv.Error(w, r, err)
+		return
+	}
+
+	api.srv.Render(w, r, http.StatusOK, restored)
+}
+
+// trashEmpty removes all the items in the trash.
+func (api *apiRouter) trashEmpty(w http.ResponseWriter, r *http.Request) {
+	if _, err := bookmarks.Trash.Empty(&auth.GetRequestUser(r).ID, time.Now()); err != nil {
+		api.srv.Error(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// withTrashList loads all the user's items in the trash, most recently
+// deleted first.
+func (api *apiRouter) withTrashList(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		res, err := newTrashList(api, r, auth.GetRequestUser(r))
+		if err != nil {
+			api.srv.Error(w, r, err)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), ctxTrashListKey{}, res)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// trashItem is an item in the trash. It can be a bookmark,
+// a collection or an annotation.
+type trashItem struct {
+	ID         string    `json:"id"`
+	Type       string    `json:"type"`
+	Href       string    `json:"href,omitempty"`
+	Title      string    `json:"title"`
+	Deleted    time.Time `json:"deleted"`
+	Expires    time.Time `json:"expires"`
+	BookmarkID string    `json:"bookmark_id,omitempty"`
+
+	restore func() error
+}
+
+type trashList []*trashItem
+
+func newTrashList(api *apiRouter, r *http.Request, u *users.User) (trashList, error) {
+	res := trashList{}
+	retention := bookmarks.TrashRetention()
+
+	bookmarkList := []*bookmarks.Bookmark{}
+	err := bookmarks.Bookmarks.TrashQuery().
+		Select("b.id", "b.uid", "b.user_id", "b.title", "b.deleted").
+		Where(goqu.C("user_id").Table("b").Eq(u.ID)).
+		ScanStructs(&bookmarkList)
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range bookmarkList {
+		res = append(res, &trashItem{
+			ID:      b.UID,
+			Type:    bookmarks.TombstoneBookmark,
+			Href:    api.srv.AbsoluteURL(r, "/api/bookmarks", b.UID).String(),
+			Title:   b.Title,
+			Deleted: *b.Deleted,
+			Expires: b.Deleted.Add(retention),
+			restore: b.Restore,
+		})
+	}
+
+	collectionList := []*bookmarks.Collection{}
+	err = bookmarks.Collections.TrashQuery().
+		Where(goqu.C("user_id").Table("c").Eq(u.ID)).
+		ScanStructs(&collectionList)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range collectionList {
+		res = append(res, &trashItem{
+			ID:      c.UID,
+			Type:    bookmarks.TombstoneCollection,
+			Href:    api.srv.AbsoluteURL(r, "/api/bookmarks/collections", c.UID).String(),
+			Title:   c.Name,
+			Deleted: *c.Deleted,
+			Expires: c.Deleted.Add(retention),
+			restore: c.Restore,
+		})
+	}
+
+	annotationList := []*bookmarks.TrashedAnnotation{}
+	err = bookmarks.Trash.Annotations().
+		Where(goqu.C("user_id").Table("a").Eq(u.ID)).
+		ScanStructs(&annotationList)
+	if err != nil {
+		return nil, err
+	}
+
+	// The annotation's bookmark can be in the trash too.
+	bookmarkIDs := map[int]string{}
+	if len(annotationList) > 0 {
+		ids := []int{}
+		for _, a := range annotationList {
+			ids = append(ids, a.BookmarkID)
+		}
+		items := []*bookmarks.Bookmark{}
+		err = bookmarks.Bookmarks.QueryAll().
+			Select("b.id", "b.uid").
+			Where(goqu.C("id").Table("b").In(ids)).
+			ScanStructs(&items)
+		if err != nil {
+			return nil, err
+		}
+		for _, b := range items {
+			bookmarkIDs[b.ID] = b.UID
+		}
+	}
+
+	for _, a := range annotationList {
+		res = append(res, &trashItem{
+			ID:         a.Annotation.ID,
+			Type:       bookmarks.TombstoneAnnotation,
+			Title:      a.Annotation.Text,
+			Deleted:    a.Deleted,
+			Expires:    a.Deleted.Add(retention),
+			BookmarkID: bookmarkIDs[a.BookmarkID],
+			restore:    a.Restore,
+		})
+	}
+
+	slices.SortStableFunc(res, func(a, b *trashItem) int {
+		return b.Deleted.Compare(a.Deleted)
+	})
+
+	return res, nil
+}
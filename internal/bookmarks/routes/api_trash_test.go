@@ -0,0 +1,170 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package routes_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/stretchr/testify/require"
+
+	"codeberg.org/readeck/readeck/internal/bookmarks"
+	. "codeberg.org/readeck/readeck/internal/testing" //revive:disable:dot-imports
+)
+
+func TestTrashAPI(t *testing.T) {
+	app := NewTestApp(t)
+	defer func() {
+		app.Close(t)
+	}()
+
+	client := NewClient(t, app)
+	u := app.Users["user"]
+	b := u.Bookmarks[0]
+
+	require.NoError(t, b.Update(map[string]interface{}{
+		"annotations": bookmarks.BookmarkAnnotations{
+			{ID: "Tnm6NJxYvghNoaPZ4sAszJ", Text: "some text", Created: time.Now()},
+		},
+	}))
+
+	c := &bookmarks.Collection{UserID: &u.User.ID, Name: "my collection"}
+	require.NoError(t, bookmarks.Collections.Create(c))
+
+	getBookmark := func() *bookmarks.Bookmark {
+		b, err := bookmarks.Bookmarks.GetOne(goqu.C("uid").Eq(b.UID))
+		require.NoError(t, err)
+		return b
+	}
+
+	RunRequestSequence(t, client, "user",
+		RequestTest{
+			Target:       "/api/bookmarks/trash",
+			JSON:         true,
+			ExpectStatus: 200,
+			ExpectJSON:   `[]`,
+		},
+		RequestTest{
+			Method:       "DELETE",
+			JSON:         true,
+			Target:       "/api/bookmarks/" + b.UID + "/annotations/Tnm6NJxYvghNoaPZ4sAszJ",
+			ExpectStatus: 204,
+			Assert: func(t *testing.T, _ *Response) {
+				require.Empty(t, getBookmark().Annotations)
+			},
+		},
+		RequestTest{
+			Method:       "DELETE",
+			JSON:         true,
+			Target:       "/api/bookmarks/" + b.UID,
+			ExpectStatus: 204,
+			Assert: func(t *testing.T, _ *Response) {
+				require.True(t, getBookmark().IsDeleted())
+			},
+		},
+		RequestTest{
+			Method:       "DELETE",
+			JSON:         true,
+			Target:       "/api/bookmarks/collections/" + c.UID,
+			ExpectStatus: 204,
+		},
+		RequestTest{
+			Target:       "/api/bookmarks",
+			ExpectStatus: 200,
+			ExpectJSON:   `[]`,
+		},
+		RequestTest{
+			Target:       "/api/bookmarks/trash",
+			JSON:         true,
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				r.AssertJQ(t, "length", 3)
+				r.AssertJQ(t, "[.[].type]", []any{"collection", "bookmark", "annotation"})
+				r.AssertJQ(t, "[.[].id]", []any{c.UID, b.UID, "Tnm6NJxYvghNoaPZ4sAszJ"})
+				r.AssertJQ(t, ".[2].bookmark_id", b.UID)
+				r.AssertJQ(t, ".[2].title", "some text")
+			},
+		},
+		RequestTest{
+			Target:       "/api/bookmarks/sync?since=2000-01-01T00:00:00Z",
+			JSON:         true,
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				r.AssertJQ(t, `[.[] | select(.type == "annotation") | .id]`, []any{"Tnm6NJxYvghNoaPZ4sAszJ"})
+			},
+		},
+		RequestTest{
+			Method:       "POST",
+			Target:       "/api/bookmarks/trash/restore",
+			JSON:         map[string]any{},
+			ExpectStatus: 422,
+			ExpectJSON: `{
+				"is_valid": false,
+				"errors": ["no item selected"],
+				"fields": "<<PRESENCE>>"
+			}`,
+		},
+		RequestTest{
+			Method:       "POST",
+			Target:       "/api/bookmarks/trash/restore",
+			JSON:         map[string]any{"ids": []string{b.UID, "Tnm6NJxYvghNoaPZ4sAszJ"}},
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				r.AssertJQ(t, "length", 2)
+				b := getBookmark()
+				require.False(t, b.IsDeleted())
+				require.Len(t, b.Annotations, 1)
+				require.Equal(t, "some text", b.Annotations[0].Text)
+			},
+		},
+		RequestTest{
+			// A restored annotation is not removed anymore
+			Target:       "/api/bookmarks/sync?since=2000-01-01T00:00:00Z",
+			JSON:         true,
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				r.AssertJQ(t, `[.[] | select(.type == "annotation") | .id]`, []any{})
+			},
+		},
+		RequestTest{
+			Target:       "/api/bookmarks/trash",
+			JSON:         true,
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				r.AssertJQ(t, "[.[].id]", []any{c.UID})
+			},
+		},
+		RequestTest{
+			Method:       "DELETE",
+			JSON:         true,
+			Target:       "/api/bookmarks/trash",
+			ExpectStatus: 204,
+		},
+		RequestTest{
+			Target:       "/api/bookmarks/trash",
+			JSON:         true,
+			ExpectStatus: 200,
+			ExpectJSON:   `[]`,
+			Assert: func(t *testing.T, _ *Response) {
+				_, err := bookmarks.Collections.GetOne(goqu.C("uid").Eq(c.UID))
+				require.Error(t, err)
+				require.False(t, getBookmark().IsDeleted())
+			},
+		},
+	)
+
+	// Items that stayed in the trash long enough are purged.
+	require.NoError(t, b.Trash())
+	n, err := bookmarks.Trash.Empty(nil, time.Now().Add(-bookmarks.TrashRetention()))
+	require.NoError(t, err)
+	require.Equal(t, int64(0), n)
+
+	n, err = bookmarks.Trash.Empty(nil, time.Now().Add(time.Second))
+	require.NoError(t, err)
+	require.Equal(t, int64(1), n)
+	_, err = bookmarks.Bookmarks.GetOne(goqu.C("uid").Eq(b.UID))
+	require.ErrorIs(t, err, bookmarks.ErrBookmarkNotFound)
+}
@@ -208,8 +208,36 @@ func newUpdateForm(tr forms.Translator) *updateForm {
 }
 
 func (f *updateForm) update(b *bookmarks.Bookmark) (updated map[string]interface{}, err error) {
+	updated, deleted := f.changes(b)
+
+	defer func() {
+		updated["id"] = b.UID
+		if err != nil {
+			f.AddErrors("", forms.ErrUnexpected)
+		}
+	}()
+
+	if len(updated) > 0 {
+		updated["updated"] = time.Now()
+		if err = b.Update(updated); err != nil {
+			return
+		}
+
+	}
+
+	if deleted != nil {
+		delete(updated, "deleted")
+		updated["is_deleted"] = *deleted
+	}
+
+	return
+}
+
+// changes applies the form's values to a bookmark and returns the
+// values to save. deleted is not nil when the form sets is_deleted,
+// in which case the bookmark moves to or out of the trash.
+func (f *updateForm) changes(b *bookmarks.Bookmark) (updated map[string]interface{}, deleted *bool) {
 	updated = map[string]interface{}{}
-	var deleted *bool
 	labelsChanged := false
 
 	for _, field := range f.Fields() {
@@ -266,31 +294,45 @@ func (f *updateForm) update(b *bookmarks.Bookmark) (updated map[string]interface
 		}
 	}
 
-	defer func() {
-		updated["id"] = b.UID
-		if err != nil {
-			f.AddErrors("", forms.ErrUnexpected)
-		}
-	}()
-
-	if len(updated) > 0 || deleted != nil {
-		updated["updated"] = time.Now()
-		if err = b.Update(updated); err != nil {
-			return
-		}
-
-	}
-
-	if deleted != nil {
-		updated["is_deleted"] = *deleted
-		df := newDeleteForm(nil)
-		df.Get("cancel").Set(!*deleted)
-		err = df.trigger(b)
+	switch {
+	case deleted == nil:
+	case *deleted && !b.IsDeleted():
+		now := time.Now()
+		b.Deleted = &now
+		updated["deleted"] = now
+	case !*deleted && b.IsDeleted():
+		b.Deleted = nil
+		updated["deleted"] = nil
 	}
 
 	return
 }
 
+// batchMaxIDs is the maximum number of bookmark IDs a batch update accepts.
+const batchMaxIDs = 5000
+
+// newBatchUpdateForm returns an update form for several bookmarks at once.
+// It only accepts the values that make sense on a set of bookmarks.
+func newBatchUpdateForm(tr forms.Translator) *updateForm {
+	return &updateForm{forms.Must(
+		forms.WithTranslator(context.Background(), tr),
+		forms.NewTextListField("ids", forms.Trim, forms.DiscardEmpty, forms.ValueValidatorFunc[[]string](
+			func(_ forms.Field, v []string) error {
+				if len(v) > batchMaxIDs {
+					return forms.Gettext("too many bookmarks (max %d)", batchMaxIDs)
+				}
+				return nil
+			},
+		)),
+		forms.NewBooleanField("is_marked"),
+		forms.NewBooleanField("is_archived"),
+		forms.NewBooleanField("is_deleted"),
+		forms.NewIntegerField("read_progress", forms.Gte(0), forms.Lte(100)),
+		forms.NewTextListField("add_labels", forms.Trim, forms.DiscardEmpty),
+		forms.NewTextListField("remove_labels", forms.Trim, forms.DiscardEmpty),
+	)}
+}
+
 type deleteForm struct {
 	*forms.Form
 }
@@ -303,13 +345,13 @@ func newDeleteForm(tr forms.Translator) *deleteForm {
 	)}
 }
 
-// trigger launch the user deletion or cancel task.
+// trigger moves the bookmark to the trash or, on cancel, restores it.
 func (f *deleteForm) trigger(b *bookmarks.Bookmark) error {
 	if !f.Get("cancel").IsNil() && f.Get("cancel").Value().(bool) {
-		return tasks.DeleteBookmarkTask.Cancel(b.ID)
+		return b.Restore()
 	}
 
-	return tasks.DeleteBookmarkTask.Run(b.ID, b.ID)
+	return b.Trash()
 }
 
 type labelForm struct {
@@ -413,6 +455,21 @@ func newContextFilterForm(c context.Context, tr forms.Translator) *filterForm {
 	return ff
 }
 
+// hasFilters returns true when at least one filter has a value.
+// Unknown parameters and empty values don't count.
+func (f *filterForm) hasFilters() bool {
+	for _, field := range f.Fields() {
+		if field.Name() == "bf" || !field.IsBound() || field.IsNil() || field.IsEmpty() {
+			continue
+		}
+		if v, ok := field.Value().([]string); ok && !slices.ContainsFunc(v, func(s string) bool { return s != "" }) {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
 func (f *filterForm) Validate() {
 	// First, we must build a search string based on
 	// the provided free form search and
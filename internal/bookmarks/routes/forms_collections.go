@@ -11,7 +11,6 @@ import (
 	"time"
 
 	"codeberg.org/readeck/readeck/internal/bookmarks"
-	"codeberg.org/readeck/readeck/internal/bookmarks/tasks"
 	"codeberg.org/readeck/readeck/pkg/forms"
 )
 
@@ -27,12 +26,13 @@ func newCollectionDeleteForm(tr forms.Translator) *collectionDeleteForm {
 	)}
 }
 
+// trigger moves the collection to the trash or, on cancel, restores it.
 func (f *collectionDeleteForm) trigger(c *bookmarks.Collection) error {
 	if !f.Get("cancel").IsNil() && f.Get("cancel").Value().(bool) {
-		return tasks.DeleteCollectionTask.Cancel(c.ID)
+		return c.Restore()
 	}
 
-	return tasks.DeleteCollectionTask.Run(c.ID, c.ID)
+	return c.Trash()
 }
 
 type collectionForm struct {
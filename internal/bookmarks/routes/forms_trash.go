@@ -0,0 +1,52 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package routes
+
+import (
+	"context"
+	"slices"
+
+	"codeberg.org/readeck/readeck/pkg/forms"
+)
+
+type trashForm struct {
+	*forms.Form
+}
+
+func newTrashForm(tr forms.Translator) *trashForm {
+	return &trashForm{forms.Must(
+		forms.WithTranslator(context.Background(), tr),
+		forms.NewTextListField("ids", forms.Trim, forms.DiscardEmpty),
+		forms.NewBooleanField("all"),
+		forms.NewTextField("_to", forms.Trim),
+	)}
+}
+
+func (f *trashForm) Validate() {
+	all, _ := f.Get("all").Value().(bool)
+	ids, _ := f.Get("ids").Value().([]string)
+	if !all && len(ids) == 0 {
+		f.AddErrors("", forms.Gettext("no item selected"))
+	}
+}
+
+// restore restores the items selected by the form and returns them.
+func (f *trashForm) restore(items trashList) (trashList, error) {
+	all, _ := f.Get("all").Value().(bool)
+	ids, _ := f.Get("ids").Value().([]string)
+
+	res := trashList{}
+	for _, item := range items {
+		if !all && !slices.Contains(ids, item.ID) {
+			continue
+		}
+		if err := item.restore(); err != nil {
+			return res, err
+		}
+		res = append(res, item)
+	}
+
+	return res, nil
+}
@@ -70,6 +70,7 @@ func newAPIRouter(s *server.Server) *apiRouter {
 		r.With(
 			api.withBookmarkSyncList,
 		).Get("/sync", api.bookmarkSyncList)
+		r.Get("/events", api.eventStream)
 		r.With(api.withBookmark).Route("/{uid:[a-zA-Z0-9]{18,22}}", func(r chi.Router) {
 			r.Get("/", api.bookmarkInfo)
 			r.Get("/article", api.bookmarkArticle)
@@ -108,6 +109,8 @@ func newAPIRouter(s *server.Server) *apiRouter {
 			).Get("/{uid:[a-zA-Z0-9]{18,22}}/article.{format}", api.bookmarkExport)
 		})
 
+		r.With(api.withoutRestriction, api.withTrashList).Get("/trash", api.trashList)
+
 		r.Route("/labels", func(r chi.Router) {
 			r.With(api.withLabelList).Get("/", api.labelList)
 			r.With(api.withLabel).Get("/{label}", api.labelInfo)
@@ -116,6 +119,7 @@ func newAPIRouter(s *server.Server) *apiRouter {
 
 	r.With(api.srv.WithPermission("api:bookmarks", "write")).Group(func(r chi.Router) {
 		r.Post("/", api.bookmarkCreate)
+		r.Patch("/", api.bookmarkBatchUpdate)
 		r.With(api.withBookmark).Group(func(r chi.Router) {
 			r.Patch("/{uid:[a-zA-Z0-9]{18,22}}", api.bookmarkUpdate)
 			r.Delete("/{uid:[a-zA-Z0-9]{18,22}}", api.bookmarkDelete)
@@ -123,8 +127,13 @@ func newAPIRouter(s *server.Server) *apiRouter {
 			r.Patch("/{uid:[a-zA-Z0-9]{18,22}}/annotations/{id:[a-zA-Z0-9]{18,22}}", api.annotationUpdate)
 			r.Delete("/{uid:[a-zA-Z0-9]{18,22}}/annotations/{id:[a-zA-Z0-9]{18,22}}", api.annotationDelete)
 		})
-		r.With(api.withLabel).Patch("/labels/{label}", api.labelUpdate)
-		r.With(api.withLabel).Delete("/labels/{label}", api.labelDelete)
+		r.With(api.withoutRestriction).Group(func(r chi.Router) {
+			r.With(api.withLabel).Patch("/labels/{label}", api.labelUpdate)
+			r.With(api.withLabel).Delete("/labels/{label}", api.labelDelete)
+
+			r.With(api.withTrashList).Post("/trash/restore", api.trashRestore)
+			r.Delete("/trash", api.trashEmpty)
+		})
 	})
 
 	// Collection API
@@ -134,7 +143,10 @@ func newAPIRouter(s *server.Server) *apiRouter {
 			r.With(api.withCollection).Get("/{uid:[a-zA-Z0-9]{18,22}}", api.collectionInfo)
 		})
 
-		r.With(api.srv.WithPermission("api:bookmarks:collections", "write")).Group(func(r chi.Router) {
+		r.With(
+			api.srv.WithPermission("api:bookmarks:collections", "write"),
+			api.withoutRestriction,
+		).Group(func(r chi.Router) {
 			r.Post("/", api.collectionCreate)
 			r.With(api.withCollection).Patch("/{uid:[a-zA-Z0-9]{18,22}}", api.collectionUpdate)
 			r.With(api.withCollection).Delete("/{uid:[a-zA-Z0-9]{18,22}}", api.collectionDelete)
@@ -197,6 +209,7 @@ func newViewsRouter(api *apiRouter) *viewsRouter {
 			r.With(api.withAnnotationList).Route("/highlights", func(r chi.Router) {
 				r.Get("/", h.annotationList)
 			})
+			r.With(api.withTrashList).Get("/trash", h.trashList)
 		})
 	})
 
@@ -211,6 +224,8 @@ func newViewsRouter(api *apiRouter) *viewsRouter {
 				r.Post("/labels/{label}", h.labelInfo)
 				r.Post("/labels/{label}/delete", h.labelDelete)
 			})
+			r.With(api.withTrashList).Post("/trash", h.trashList)
+			r.Post("/trash/empty", h.trashEmpty)
 		})
 	})
 
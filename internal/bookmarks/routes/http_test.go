@@ -78,6 +78,21 @@ func TestPermissions(t *testing.T) {
 					}
 				},
 			},
+			RequestTest{
+				Method: "PATCH",
+				Target: "/api/bookmarks",
+				JSON:   map[string]any{},
+				Assert: func(t *testing.T, r *Response) {
+					switch user {
+					case "admin", "staff", "user":
+						r.AssertStatus(t, 422)
+					case "disabled":
+						r.AssertStatus(t, 403)
+					case "":
+						r.AssertStatus(t, 401)
+					}
+				},
+			},
 			RequestTest{
 				Target: "/api/bookmarks/{{(index .User.Bookmarks 0).UID}}",
 				Assert: func(t *testing.T, r *Response) {
@@ -171,6 +186,34 @@ func TestPermissions(t *testing.T) {
 					}
 				},
 			},
+			RequestTest{
+				Target: "/api/bookmarks/trash",
+				Assert: func(t *testing.T, r *Response) {
+					switch user {
+					case "admin", "staff", "user":
+						r.AssertStatus(t, 200)
+					case "disabled":
+						r.AssertStatus(t, 403)
+					case "":
+						r.AssertStatus(t, 401)
+					}
+				},
+			},
+			RequestTest{
+				Method: "POST",
+				Target: "/api/bookmarks/trash/restore",
+				JSON:   map[string]any{},
+				Assert: func(t *testing.T, r *Response) {
+					switch user {
+					case "admin", "staff", "user":
+						r.AssertStatus(t, 422)
+					case "disabled":
+						r.AssertStatus(t, 403)
+					case "":
+						r.AssertStatus(t, 401)
+					}
+				},
+			},
 			RequestTest{
 				Target: "/api/bookmarks/collections",
 				Assert: func(t *testing.T, r *Response) {
@@ -377,6 +420,20 @@ func TestPermissions(t *testing.T) {
 					}
 				},
 			},
+			RequestTest{
+				Target: "/bookmarks/trash",
+				Assert: func(t *testing.T, r *Response) {
+					switch user {
+					case "admin", "staff", "user":
+						r.AssertStatus(t, 200)
+					case "disabled":
+						r.AssertStatus(t, 403)
+					default:
+						r.AssertStatus(t, 303)
+						r.AssertRedirect(t, "/login")
+					}
+				},
+			},
 			RequestTest{
 				Target: "/bookmarks/collections",
 				Assert: func(t *testing.T, r *Response) {
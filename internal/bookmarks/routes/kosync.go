@@ -0,0 +1,178 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package routes
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/go-chi/chi/v5"
+
+	"codeberg.org/readeck/readeck/internal/auth"
+	"codeberg.org/readeck/readeck/internal/bookmarks"
+	"codeberg.org/readeck/readeck/internal/bookmarks/converter"
+	"codeberg.org/readeck/readeck/internal/server"
+	"codeberg.org/readeck/readeck/pkg/forms"
+)
+
+// kosyncDevice and kosyncDeviceID identify Readeck as a device,
+// when the last reading progress doesn't come from an e-reader.
+const (
+	kosyncDevice   = "Readeck"
+	kosyncDeviceID = "readeck"
+)
+
+type kosyncRouter struct {
+	chi.Router
+	*apiRouter
+}
+
+// NewKosyncRouteHandler returns a chi Router handler with the
+// KOReader progress synchronization routes for the bookmark domain.
+// A document is an EPUB file exported from a bookmark, identified by
+// one of the hashes recorded during the export.
+func NewKosyncRouteHandler(s *server.Server) func(r chi.Router) {
+	return func(r chi.Router) {
+		h := &kosyncRouter{r, newAPIRouter(s)}
+
+		r.With(h.srv.WithPermission("api:bookmarks", "read")).
+			Get("/syncs/progress/{document}", h.progressInfo)
+		r.With(h.srv.WithPermission("api:bookmarks", "write")).
+			Put("/syncs/progress", h.progressUpdate)
+	}
+}
+
+type kosyncProgressForm struct {
+	*forms.Form
+}
+
+func newKosyncProgressForm(tr forms.Translator) *kosyncProgressForm {
+	return &kosyncProgressForm{forms.Must(
+		forms.WithTranslator(context.Background(), tr),
+		forms.NewTextField("document", forms.Trim, forms.Required),
+		forms.NewTextField("progress", forms.Trim),
+		forms.NewFloatField("percentage", forms.Required),
+		forms.NewTextField("device", forms.Trim),
+		forms.NewTextField("device_id", forms.Trim),
+	)}
+}
+
+type kosyncProgress struct {
+	Document   string  `json:"document"`
+	Progress   string  `json:"progress"`
+	Percentage float64 `json:"percentage"`
+	Device     string  `json:"device"`
+	DeviceID   string  `json:"device_id"`
+	Timestamp  int64   `json:"timestamp"`
+}
+
+// progressInfo returns the reading progress of a document. The
+// bookmark's read anchor is converted to an XPointer in the document.
+// Unknown documents have no progress.
+func (h *kosyncRouter) progressInfo(w http.ResponseWriter, r *http.Request) {
+	hash := chi.URLParam(r, "document")
+	d, b, err := bookmarks.Documents.GetBookmark(auth.GetRequestUser(r).ID, hash, bookmarkRestriction(r))
+	if errors.Is(err, bookmarks.ErrDocumentNotFound) {
+		h.srv.Render(w, r, http.StatusOK, map[string]any{})
+		return
+	}
+	if err != nil {
+		h.srv.Error(w, r, err)
+		return
+	}
+
+	pos, err := converter.NewEPUBPosition(r.Context(), b)
+	if err != nil {
+		h.srv.Error(w, r, err)
+		return
+	}
+
+	res := kosyncProgress{
+		Document:   hash,
+		Progress:   pos.XPointer(b.ReadAnchor, b.ReadProgress),
+		Percentage: float64(b.ReadProgress) / 100,
+		Device:     kosyncDevice,
+		DeviceID:   kosyncDeviceID,
+		Timestamp:  b.Updated.Unix(),
+	}
+
+	// The device only reports the last progress when it was
+	// the last one to change the bookmark.
+	if d.Synced != nil && !d.Synced.Before(b.Updated) {
+		res.Device = d.Device
+		res.DeviceID = d.DeviceID
+	}
+
+	h.srv.Render(w, r, http.StatusOK, res)
+}
+
+// progressUpdate saves the reading progress of a document on its
+// bookmark. The XPointer sent by the device is converted to a read anchor.
+func (h *kosyncRouter) progressUpdate(w http.ResponseWriter, r *http.Request) {
+	f := newKosyncProgressForm(h.srv.Locale(r))
+	forms.Bind(f, r)
+	if !f.IsValid() {
+		h.srv.Render(w, r, http.StatusUnprocessableEntity, f)
+		return
+	}
+
+	hash := f.Get("document").String()
+	d, b, err := bookmarks.Documents.GetBookmark(auth.GetRequestUser(r).ID, hash, bookmarkRestriction(r))
+	if errors.Is(err, bookmarks.ErrDocumentNotFound) {
+		h.srv.Message(w, r, &server.Message{
+			Status:  http.StatusNotFound,
+			Message: "Document not found",
+		})
+		return
+	}
+	if err != nil {
+		h.srv.Error(w, r, err)
+		return
+	}
+
+	pos, err := converter.NewEPUBPosition(r.Context(), b)
+	if err != nil {
+		h.srv.Error(w, r, err)
+		return
+	}
+
+	percentage := f.Get("percentage").(forms.TypedField[float64]).V()
+	progress := min(max(int(math.Round(percentage*100)), 0), 100)
+
+	uf := newUpdateForm(h.srv.Locale(r))
+	forms.BindValues(uf, url.Values{
+		"read_progress": {strconv.Itoa(progress)},
+		"read_anchor":   {pos.Selector(f.Get("progress").String())},
+	})
+	if !uf.IsValid() {
+		h.srv.Render(w, r, http.StatusUnprocessableEntity, uf)
+		return
+	}
+	if _, err = uf.update(b); err != nil {
+		h.srv.Error(w, r, err)
+		return
+	}
+
+	now := time.Now()
+	if err = d.Update(goqu.Record{
+		"device":    f.Get("device").String(),
+		"device_id": f.Get("device_id").String(),
+		"synced":    now,
+	}); err != nil {
+		h.srv.Error(w, r, err)
+		return
+	}
+
+	h.srv.Render(w, r, http.StatusOK, map[string]any{
+		"document":  hash,
+		"timestamp": now.Unix(),
+	})
+}
@@ -0,0 +1,134 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package routes
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/doug-martin/goqu/v9"
+	goquexp "github.com/doug-martin/goqu/v9/exp"
+
+	"codeberg.org/readeck/readeck/internal/auth"
+	"codeberg.org/readeck/readeck/internal/bookmarks"
+	"codeberg.org/readeck/readeck/internal/db/exp"
+)
+
+// bookmarkRestriction returns an expression that limits a bookmark
+// query (with the "b" alias) to the bookmarks the request's token
+// can access. Without a token restriction, the expression is empty.
+//
+// A token restricted to a collection that doesn't exist anymore, or
+// that is in the trash, can't access any bookmark.
+func bookmarkRestriction(r *http.Request) goquexp.Expression {
+	info := auth.GetRequestAuthInfo(r)
+	if info.Provider == nil || info.Provider.Restriction.IsEmpty() {
+		return goqu.And()
+	}
+	res := info.Provider.Restriction
+
+	ds := bookmarks.Bookmarks.QueryAll().
+		Select(goqu.C("id").Table("b")).
+		Where(goqu.C("user_id").Table("b").Eq(info.User.ID))
+
+	if res.Collection != "" {
+		c, err := bookmarks.Collections.GetOne(
+			goqu.C("uid").Eq(res.Collection),
+			goqu.C("user_id").Eq(info.User.ID),
+		)
+		if err != nil || c.IsDeleted() {
+			return goqu.L("FALSE")
+		}
+		ds = c.Filters.ToSelectDataSet(ds)
+	}
+
+	// The bookmarks must have at least one of the labels.
+	if len(res.Labels) > 0 {
+		or := goqu.Or()
+		for _, label := range res.Labels {
+			or = or.Append(goqu.C("id").Table("b").In(
+				exp.JSONListFilter(
+					bookmarks.Bookmarks.QueryAll().Select(goqu.C("id").Table("b")),
+					goqu.I("b.labels").Eq(label),
+				),
+			))
+		}
+		ds = ds.Where(or)
+	}
+
+	if res.Search != "" {
+		ds = bookmarks.Filters{Search: res.Search}.ToSelectDataSet(ds)
+	}
+
+	return goqu.C("id").Table("b").In(ds)
+}
+
+// collectionRestriction returns an expression that limits a collection
+// query to the collection of the request's token restriction. Without
+// such a restriction, the expression is empty.
+func collectionRestriction(r *http.Request) goquexp.Expression {
+	info := auth.GetRequestAuthInfo(r)
+	if info.Provider == nil || info.Provider.Restriction.IsEmpty() ||
+		info.Provider.Restriction.Collection == "" {
+		return goqu.And()
+	}
+	return goqu.C("uid").Eq(info.Provider.Restriction.Collection)
+}
+
+// restrictTombstones returns the tombstones a restricted token can see.
+// A removed bookmark, label or collection can't be checked against
+// the restriction anymore, so only the removed annotations of the
+// bookmarks the token can access are kept.
+func restrictTombstones(r *http.Request, tombstones []*bookmarks.Tombstone) ([]*bookmarks.Tombstone, error) {
+	info := auth.GetRequestAuthInfo(r)
+	if info.Provider == nil || info.Provider.Restriction.IsEmpty() {
+		return tombstones, nil
+	}
+
+	parents := []string{}
+	for _, t := range tombstones {
+		if t.Kind == bookmarks.TombstoneAnnotation {
+			parents = append(parents, t.ParentUID)
+		}
+	}
+	if len(parents) == 0 {
+		return []*bookmarks.Tombstone{}, nil
+	}
+
+	var uids []string
+	err := bookmarks.Bookmarks.QueryAll().
+		Select(goqu.C("uid").Table("b")).
+		Where(
+			goqu.C("uid").Table("b").In(parents),
+			goqu.C("user_id").Table("b").Eq(info.User.ID),
+			bookmarkRestriction(r),
+		).
+		ScanVals(&uids)
+	if err != nil {
+		return nil, err
+	}
+
+	res := []*bookmarks.Tombstone{}
+	for _, t := range tombstones {
+		if t.Kind == bookmarks.TombstoneAnnotation && slices.Contains(uids, t.ParentUID) {
+			res = append(res, t)
+		}
+	}
+	return res, nil
+}
+
+// withoutRestriction is a middleware that denies the routes acting on
+// all the user's bookmarks, like renaming a label or emptying the trash,
+// to tokens restricted to some of them.
+func (api *apiRouter) withoutRestriction(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		info := auth.GetRequestAuthInfo(r)
+		if info.Provider != nil && !info.Provider.Restriction.IsEmpty() {
+			api.srv.Status(w, r, http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -206,11 +206,6 @@ func (h *viewsRouter) bookmarkDelete(w http.ResponseWriter, r *http.Request) {
 	f := newDeleteForm(h.srv.Locale(r))
 	forms.Bind(f, r)
 
-	if err := b.Update(map[string]interface{}{}); err != nil {
-		h.srv.Error(w, r, err)
-		return
-	}
-
 	if err := f.trigger(b); err != nil {
 		h.srv.Error(w, r, err)
 		return
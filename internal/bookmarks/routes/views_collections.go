@@ -107,11 +107,6 @@ func (h *viewsRouter) collectionDelete(w http.ResponseWriter, r *http.Request) {
 
 	c := r.Context().Value(ctxCollectionKey{}).(*bookmarks.Collection)
 
-	// This update forces cache invalidation
-	if err := c.Update(map[string]interface{}{}); err != nil {
-		h.srv.Error(w, r, err)
-		return
-	}
 	if err := f.trigger(c); err != nil {
 		h.srv.Error(w, r, err)
 		return
@@ -0,0 +1,50 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package routes
+
+import (
+	"net/http"
+	"time"
+
+	"codeberg.org/readeck/readeck/internal/auth"
+	"codeberg.org/readeck/readeck/internal/bookmarks"
+	"codeberg.org/readeck/readeck/internal/server"
+	"codeberg.org/readeck/readeck/pkg/forms"
+)
+
+func (h *viewsRouter) trashList(w http.ResponseWriter, r *http.Request) {
+	f := newTrashForm(h.srv.Locale(r))
+	items := r.Context().Value(ctxTrashListKey{}).(trashList)
+
+	if r.Method == http.MethodPost {
+		forms.Bind(f, r)
+		if f.IsValid() {
+			if _, err := f.restore(items); err != nil {
+				h.srv.Error(w, r, err)
+				return
+			}
+			h.srv.AddFlash(w, r, "success", h.srv.Locale(r).Gettext("Items restored."))
+			h.srv.Redirect(w, r, "/bookmarks/trash")
+			return
+		}
+	}
+
+	ctx := r.Context().Value(ctxBaseContextKey{}).(server.TC)
+	ctx["Form"] = f
+	ctx["Items"] = items
+	ctx["Retention"] = int(bookmarks.TrashRetention().Hours() / 24)
+
+	h.srv.RenderTemplate(w, r, http.StatusOK, "/bookmarks/trash", ctx)
+}
+
+func (h *viewsRouter) trashEmpty(w http.ResponseWriter, r *http.Request) {
+	if _, err := bookmarks.Trash.Empty(&auth.GetRequestUser(r).ID, time.Now()); err != nil {
+		h.srv.Error(w, r, err)
+		return
+	}
+
+	h.srv.AddFlash(w, r, "success", h.srv.Locale(r).Gettext("The trash is empty."))
+	h.srv.Redirect(w, r, "/bookmarks/trash")
+}
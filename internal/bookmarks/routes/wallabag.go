@@ -0,0 +1,944 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package routes
+
+import (
+	"context"
+	"crypto/sha1" //nolint:gosec
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"hash/crc32"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/go-chi/chi/v5"
+
+	"codeberg.org/readeck/readeck/internal/auth"
+	"codeberg.org/readeck/readeck/internal/bookmarks"
+	"codeberg.org/readeck/readeck/internal/bookmarks/converter"
+	"codeberg.org/readeck/readeck/internal/bookmarks/tasks"
+	"codeberg.org/readeck/readeck/internal/db"
+	"codeberg.org/readeck/readeck/internal/db/exp"
+	"codeberg.org/readeck/readeck/internal/server"
+	"codeberg.org/readeck/readeck/pkg/annotate"
+	"codeberg.org/readeck/readeck/pkg/forms"
+	"codeberg.org/readeck/readeck/pkg/utils"
+)
+
+type ctxWallabagAnnotationKey struct{}
+
+// wallabagTimeFormat is the date format used by the Wallabag API.
+const wallabagTimeFormat = "2006-01-02T15:04:05-0700"
+
+type wallabagRouter struct {
+	chi.Router
+	*apiRouter
+}
+
+// NewWallabagRouteHandler returns a chi Router handler with the
+// Wallabag API routes for the bookmark domain.
+// Wallabag entries are bookmarks (identified by their numeric ID),
+// tags are labels and annotations are bookmark annotations.
+// The handlers stay in this package so they reuse the bookmark API's
+// forms, update and export code instead of exporting them.
+func NewWallabagRouteHandler(s *server.Server) func(r chi.Router) {
+	return func(r chi.Router) {
+		h := &wallabagRouter{r, newAPIRouter(s)}
+
+		r.With(h.srv.WithPermission("api:bookmarks", "read")).Group(func(r chi.Router) {
+			r.Get("/entries", h.entryList)
+			r.Get("/entries/exists", h.entryExists)
+			r.With(h.withEntry).Get("/entries/{entry:[0-9]+}", h.entryInfo)
+			r.With(h.withEntry).Get("/entries/{entry:[0-9]+}/tags", h.entryTags)
+			r.With(h.srv.WithPermission("api:bookmarks", "export"), h.withEntry).
+				Get("/entries/{entry:[0-9]+}/export.{format}", h.bookmarkExport)
+			r.Get("/tags", h.tagList)
+			r.With(h.withEntry).Get("/annotations/{entry:[0-9]+}", h.annotationList)
+		})
+
+		r.With(h.srv.WithPermission("api:bookmarks", "write")).Group(func(r chi.Router) {
+			r.Post("/entries", h.entryCreate)
+			r.With(h.withEntry).Patch("/entries/{entry:[0-9]+}", h.entryUpdate)
+			r.With(h.withEntry).Delete("/entries/{entry:[0-9]+}", h.entryDelete)
+			r.With(h.withEntry).Post("/entries/{entry:[0-9]+}/tags", h.entryAddTags)
+			r.With(h.withEntry).Delete("/entries/{entry:[0-9]+}/tags/{tag:[0-9]+}", h.entryRemoveTag)
+			r.With(h.withoutRestriction).Group(func(r chi.Router) {
+				r.Delete("/tags/{tag:[0-9]+}", h.tagDelete)
+				r.Delete("/tag/label", h.tagDeleteByLabel)
+				r.Delete("/tags/label", h.tagDeleteByLabel)
+			})
+			r.With(h.withEntry).Post("/annotations/{entry:[0-9]+}", h.annotationCreate)
+			r.With(h.withAnnotation).Put("/annotations/{annotation:[a-zA-Z0-9]{18,22}}", h.annotationUpdate)
+			r.With(h.withAnnotation).Delete("/annotations/{annotation:[a-zA-Z0-9]{18,22}}", h.annotationDelete)
+		})
+	}
+}
+
+// withEntry fetches the bookmark with the numeric ID of the "entry"
+// URL parameter and adds it into the request's context.
+func (h *wallabagRouter) withEntry(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, _ := strconv.Atoi(chi.URLParam(r, "entry"))
+		b, err := findEntry(r, goqu.C("id").Table("b").Eq(id))
+		if err != nil {
+			h.srv.Status(w, r, http.StatusNotFound)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), ctxBookmarkKey{}, b)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// withAnnotation finds the bookmark holding the annotation of the
+// "annotation" URL parameter and adds both into the request's context.
+func (h *wallabagRouter) withAnnotation(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := auth.GetRequestUser(r)
+		id := chi.URLParam(r, "annotation")
+
+		var bookmarkID int
+		exists, err := db.Q().
+			From(bookmarks.Bookmarks.GetAnnotations().
+				Where(goqu.C("user_id").Table("b").Eq(user.ID)).
+				As("a"),
+			).
+			Select(goqu.C("b.id")).
+			Where(goqu.C("annotation_id").Eq(id)).
+			ScanVal(&bookmarkID)
+		if err != nil {
+			h.srv.Error(w, r, err)
+			return
+		}
+		if !exists {
+			h.srv.Status(w, r, http.StatusNotFound)
+			return
+		}
+
+		b, err := findEntry(r, goqu.C("id").Table("b").Eq(bookmarkID))
+		if err != nil {
+			h.srv.Status(w, r, http.StatusNotFound)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), ctxBookmarkKey{}, b)
+		ctx = context.WithValue(ctx, ctxWallabagAnnotationKey{}, id)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// findEntry returns the first bookmark of the current user, within the
+// token's restriction, matching the given expressions.
+// Unlike [bookmarks.BookmarkManager.GetOne], it ignores the bookmarks
+// in the trash, since Wallabag deletes entries right away.
+func findEntry(r *http.Request, expressions ...goqu.Expression) (*bookmarks.Bookmark, error) {
+	var b bookmarks.Bookmark
+	found, err := bookmarks.Bookmarks.Query().
+		Where(
+			goqu.C("user_id").Table("b").Eq(auth.GetRequestUser(r).ID),
+			bookmarkRestriction(r),
+		).
+		Where(expressions...).
+		ScanStruct(&b)
+	switch {
+	case err != nil:
+		return nil, err
+	case !found:
+		return nil, bookmarks.ErrBookmarkNotFound
+	}
+
+	return &b, nil
+}
+
+func (h *wallabagRouter) entryList(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	ds := bookmarks.Bookmarks.Query().
+		Where(
+			goqu.C("user_id").Table("b").Eq(auth.GetRequestUser(r).ID),
+			bookmarkRestriction(r),
+		)
+
+	switch q.Get("archive") {
+	case "0", "1":
+		ds = ds.Where(goqu.C("is_archived").Table("b").Eq(q.Get("archive") == "1"))
+	}
+	switch q.Get("starred") {
+	case "0", "1":
+		ds = ds.Where(goqu.C("is_marked").Table("b").Eq(q.Get("starred") == "1"))
+	}
+	if tags := wallabagTagList(q.Get("tags")); len(tags) > 0 {
+		for _, tag := range tags {
+			ds = exp.JSONListFilter(ds, goqu.C("labels").Table("b").Eq(tag))
+		}
+	}
+	if v, err := strconv.ParseInt(q.Get("since"), 10, 64); err == nil && v > 0 {
+		ds = ds.Where(goqu.C("updated").Table("b").Gte(time.Unix(v, 0)))
+	}
+	if v := q.Get("domain_name"); v != "" {
+		ds = ds.Where(goqu.C("site").Table("b").Eq(v))
+	}
+
+	count, err := ds.Count()
+	if err != nil {
+		h.srv.Error(w, r, err)
+		return
+	}
+
+	page, _ := strconv.Atoi(q.Get("page"))
+	page = max(page, 1)
+	limit, _ := strconv.Atoi(q.Get("perPage"))
+	if limit <= 0 {
+		limit = 30
+	}
+	limit = min(limit, 500)
+	pages := max(int(math.Ceil(float64(count)/float64(limit))), 1)
+
+	col := "created"
+	if q.Get("sort") == "updated" || q.Get("sort") == "archived" {
+		col = "updated"
+	}
+	order := goqu.C(col).Table("b").Desc()
+	if q.Get("order") == "asc" {
+		order = goqu.C(col).Table("b").Asc()
+	}
+
+	items := []*bookmarks.Bookmark{}
+	err = ds.
+		Order(order, goqu.C("id").Table("b").Desc()).
+		Limit(uint(limit)).
+		Offset(uint((page - 1) * limit)).
+		ScanStructs(&items)
+	if err != nil {
+		h.srv.Error(w, r, err)
+		return
+	}
+
+	res := wallabagEntryList{
+		Page:  page,
+		Limit: limit,
+		Pages: pages,
+		Total: int(count),
+		Links: wallabagLinks{},
+	}
+	res.Embedded.Items = make([]wallabagEntry, len(items))
+	withContent := q.Get("detail") != "metadata"
+	for i, b := range items {
+		res.Embedded.Items[i] = newWallabagEntry(h.srv, r, b, withContent)
+	}
+
+	pageURL := func(n int) wallabagLink {
+		u := h.srv.AbsoluteURL(r)
+		v := u.Query()
+		v.Set("page", strconv.Itoa(n))
+		v.Set("perPage", strconv.Itoa(limit))
+		u.RawQuery = v.Encode()
+		return wallabagLink{u.String()}
+	}
+	res.Links["self"] = pageURL(page)
+	res.Links["first"] = pageURL(1)
+	res.Links["last"] = pageURL(pages)
+	if page < pages {
+		res.Links["next"] = pageURL(page + 1)
+	}
+	if page > 1 {
+		res.Links["previous"] = pageURL(page - 1)
+	}
+
+	h.srv.Render(w, r, http.StatusOK, res)
+}
+
+// entryExists checks whether one or several URLs were already saved.
+// URLs are given as is or by their SHA1 hash.
+func (h *wallabagRouter) entryExists(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	returnID := q.Get("return_id") == "1"
+
+	items := []*bookmarks.Bookmark{}
+	err := bookmarks.Bookmarks.Query().
+		Select("b.id", "b.url", "b.initial_url").
+		Where(
+			goqu.C("user_id").Table("b").Eq(auth.GetRequestUser(r).ID),
+			bookmarkRestriction(r),
+		).
+		ScanStructs(&items)
+	if err != nil {
+		h.srv.Error(w, r, err)
+		return
+	}
+
+	byURL := map[string]int{}
+	byHash := map[string]int{}
+	for _, b := range items {
+		for _, u := range []string{b.InitialURL, b.URL} {
+			if u != "" {
+				byURL[u] = b.ID
+				byHash[wallabagHash(u)] = b.ID
+			}
+		}
+	}
+
+	result := func(id int, ok bool) any {
+		if returnID {
+			if ok {
+				return id
+			}
+			return nil
+		}
+		return ok
+	}
+
+	if v := q.Get("url"); v != "" {
+		id, ok := byURL[v]
+		h.srv.Render(w, r, http.StatusOK, map[string]any{"exists": result(id, ok)})
+		return
+	}
+	if v := q.Get("hashed_url"); v != "" {
+		id, ok := byHash[strings.ToLower(v)]
+		h.srv.Render(w, r, http.StatusOK, map[string]any{"exists": result(id, ok)})
+		return
+	}
+
+	res := map[string]any{}
+	for _, v := range q["urls[]"] {
+		id, ok := byURL[v]
+		res[v] = result(id, ok)
+	}
+	for _, v := range q["hashed_urls[]"] {
+		id, ok := byHash[strings.ToLower(v)]
+		res[v] = result(id, ok)
+	}
+	h.srv.Render(w, r, http.StatusOK, res)
+}
+
+func (h *wallabagRouter) entryInfo(w http.ResponseWriter, r *http.Request) {
+	b := r.Context().Value(ctxBookmarkKey{}).(*bookmarks.Bookmark)
+	h.srv.Render(w, r, http.StatusOK, newWallabagEntry(h.srv, r, b, true))
+}
+
+// entryCreate saves a new entry. Like Wallabag does, an entry that
+// already exists for the URL is updated instead.
+func (h *wallabagRouter) entryCreate(w http.ResponseWriter, r *http.Request) {
+	f := newWallabagEntryForm(h.srv.Locale(r))
+	forms.Bind(f, r)
+	if f.Get("url").String() == "" {
+		f.AddErrors("url", forms.ErrRequired)
+	}
+	if !f.IsValid() {
+		h.srv.Render(w, r, http.StatusBadRequest, f)
+		return
+	}
+
+	user := auth.GetRequestUser(r)
+	b, err := findEntry(r, goqu.C("url").Table("b").Eq(f.Get("url").String()))
+	switch {
+	case errors.Is(err, bookmarks.ErrBookmarkNotFound):
+		cf := newCreateForm(h.srv.Locale(r), user.ID, h.srv.GetReqID(r))
+		forms.BindValues(cf, url.Values{
+			"url":    {f.Get("url").String()},
+			"title":  {f.Get("title").String()},
+			"labels": wallabagTagList(f.Get("tags").String()),
+		})
+		if !cf.IsValid() {
+			h.srv.Render(w, r, http.StatusBadRequest, cf)
+			return
+		}
+
+		// Provided content is used instead of fetching the page.
+		if content := f.Get("content").String(); content != "" {
+			cf.resources = append(cf.resources, tasks.MultipartResource{
+				URL:     cf.Get("url").String(),
+				Headers: map[string]string{"content-type": "text/html; charset=utf-8"},
+				Data:    []byte(content),
+			})
+		}
+
+		if b, err = cf.createBookmark(); err != nil {
+			h.srv.Error(w, r, err)
+			return
+		}
+	case err != nil:
+		h.srv.Error(w, r, err)
+		return
+	}
+
+	if err = h.updateEntry(r, b, f); err != nil {
+		h.srv.Error(w, r, err)
+		return
+	}
+
+	h.srv.Render(w, r, http.StatusOK, newWallabagEntry(h.srv, r, b, true))
+}
+
+func (h *wallabagRouter) entryUpdate(w http.ResponseWriter, r *http.Request) {
+	b := r.Context().Value(ctxBookmarkKey{}).(*bookmarks.Bookmark)
+	f := newWallabagEntryForm(h.srv.Locale(r))
+	forms.Bind(f, r)
+	if !f.IsValid() {
+		h.srv.Render(w, r, http.StatusBadRequest, f)
+		return
+	}
+
+	if err := h.updateEntry(r, b, f); err != nil {
+		h.srv.Error(w, r, err)
+		return
+	}
+
+	h.srv.Render(w, r, http.StatusOK, newWallabagEntry(h.srv, r, b, true))
+}
+
+// updateEntry applies the title, tags, archive and starred values of
+// the form to a bookmark.
+func (h *wallabagRouter) updateEntry(r *http.Request, b *bookmarks.Bookmark, f *wallabagEntryForm) error {
+	values := url.Values{}
+	if v := f.Get("title").String(); v != "" {
+		values.Set("title", v)
+	}
+	if v, ok := f.Get("archive").Value().(int); ok {
+		values.Set("is_archived", strconv.FormatBool(v == 1))
+	}
+	if v, ok := f.Get("starred").Value().(int); ok {
+		values.Set("is_marked", strconv.FormatBool(v == 1))
+	}
+	if tags := wallabagTagList(f.Get("tags").String()); len(tags) > 0 {
+		values["add_labels"] = tags
+	}
+
+	return h.updateBookmark(r, b, values)
+}
+
+// updateBookmark runs an update form with the given values on a bookmark.
+func (h *wallabagRouter) updateBookmark(r *http.Request, b *bookmarks.Bookmark, values url.Values) error {
+	if len(values) == 0 {
+		return nil
+	}
+
+	uf := newUpdateForm(h.srv.Locale(r))
+	forms.BindValues(uf, values)
+	if !uf.IsValid() {
+		return errors.New("invalid entry values")
+	}
+	_, err := uf.update(b)
+	return err
+}
+
+// entryDelete moves the bookmark to the trash and returns the entry
+// as it was, or only its ID with "expect=id".
+func (h *wallabagRouter) entryDelete(w http.ResponseWriter, r *http.Request) {
+	b := r.Context().Value(ctxBookmarkKey{}).(*bookmarks.Bookmark)
+	e := newWallabagEntry(h.srv, r, b, false)
+
+	if err := b.Trash(); err != nil {
+		h.srv.Error(w, r, err)
+		return
+	}
+
+	if r.URL.Query().Get("expect") == "id" {
+		h.srv.Render(w, r, http.StatusOK, map[string]int{"id": b.ID})
+		return
+	}
+	h.srv.Render(w, r, http.StatusOK, e)
+}
+
+func (h *wallabagRouter) entryTags(w http.ResponseWriter, r *http.Request) {
+	b := r.Context().Value(ctxBookmarkKey{}).(*bookmarks.Bookmark)
+	h.srv.Render(w, r, http.StatusOK, newWallabagTags(b.Labels))
+}
+
+func (h *wallabagRouter) entryAddTags(w http.ResponseWriter, r *http.Request) {
+	b := r.Context().Value(ctxBookmarkKey{}).(*bookmarks.Bookmark)
+	f := newWallabagEntryForm(h.srv.Locale(r))
+	forms.Bind(f, r)
+	if !f.IsValid() {
+		h.srv.Render(w, r, http.StatusBadRequest, f)
+		return
+	}
+
+	err := h.updateBookmark(r, b, url.Values{"add_labels": wallabagTagList(f.Get("tags").String())})
+	if err != nil {
+		h.srv.Error(w, r, err)
+		return
+	}
+
+	h.srv.Render(w, r, http.StatusOK, newWallabagEntry(h.srv, r, b, true))
+}
+
+func (h *wallabagRouter) entryRemoveTag(w http.ResponseWriter, r *http.Request) {
+	b := r.Context().Value(ctxBookmarkKey{}).(*bookmarks.Bookmark)
+	id, _ := strconv.Atoi(chi.URLParam(r, "tag"))
+
+	i := slices.IndexFunc(b.Labels, func(label string) bool {
+		return wallabagTagID(label) == id
+	})
+	if i < 0 {
+		h.srv.Status(w, r, http.StatusNotFound)
+		return
+	}
+
+	if err := h.updateBookmark(r, b, url.Values{"remove_labels": {b.Labels[i]}}); err != nil {
+		h.srv.Error(w, r, err)
+		return
+	}
+
+	h.srv.Render(w, r, http.StatusOK, newWallabagEntry(h.srv, r, b, true))
+}
+
+// userLabels returns all the labels of the current user, within
+// the token's restriction.
+func (h *wallabagRouter) userLabels(r *http.Request) ([]*labelItem, error) {
+	res := []*labelItem{}
+	err := bookmarks.Bookmarks.GetLabels().
+		Where(
+			goqu.C("user_id").Table("b").Eq(auth.GetRequestUser(r).ID),
+			bookmarkRestriction(r),
+		).
+		ScanStructs(&res)
+	return res, err
+}
+
+func (h *wallabagRouter) tagList(w http.ResponseWriter, r *http.Request) {
+	labels, err := h.userLabels(r)
+	if err != nil {
+		h.srv.Error(w, r, err)
+		return
+	}
+
+	res := make([]wallabagTag, len(labels))
+	for i, l := range labels {
+		res[i] = newWallabagTag(string(l.Name))
+		res[i].NbEntries = l.Count
+	}
+	h.srv.Render(w, r, http.StatusOK, res)
+}
+
+// tagDelete removes a label, found by its tag ID, from all the bookmarks.
+func (h *wallabagRouter) tagDelete(w http.ResponseWriter, r *http.Request) {
+	labels, err := h.userLabels(r)
+	if err != nil {
+		h.srv.Error(w, r, err)
+		return
+	}
+
+	id, _ := strconv.Atoi(chi.URLParam(r, "tag"))
+	i := slices.IndexFunc(labels, func(l *labelItem) bool {
+		return wallabagTagID(string(l.Name)) == id
+	})
+	if i < 0 {
+		h.srv.Status(w, r, http.StatusNotFound)
+		return
+	}
+
+	if _, err = bookmarks.Bookmarks.RenameLabel(auth.GetRequestUser(r), string(labels[i].Name), ""); err != nil {
+		h.srv.Error(w, r, err)
+		return
+	}
+
+	h.srv.Render(w, r, http.StatusOK, newWallabagTag(string(labels[i].Name)))
+}
+
+// tagDeleteByLabel removes one label ("tag" parameter) or several
+// labels ("tags" parameter, comma separated) from all the bookmarks.
+func (h *wallabagRouter) tagDeleteByLabel(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	names := wallabagTagList(q.Get("tags"))
+	if v := strings.TrimSpace(q.Get("tag")); v != "" {
+		names = []string{v}
+	}
+
+	res := []wallabagTag{}
+	for _, name := range names {
+		ids, err := bookmarks.Bookmarks.RenameLabel(auth.GetRequestUser(r), name, "")
+		if err != nil {
+			h.srv.Error(w, r, err)
+			return
+		}
+		if len(ids) > 0 {
+			res = append(res, newWallabagTag(name))
+		}
+	}
+
+	if len(res) == 0 {
+		h.srv.Status(w, r, http.StatusNotFound)
+		return
+	}
+	if q.Has("tag") {
+		h.srv.Render(w, r, http.StatusOK, res[0])
+		return
+	}
+	h.srv.Render(w, r, http.StatusOK, res)
+}
+
+func (h *wallabagRouter) annotationList(w http.ResponseWriter, r *http.Request) {
+	b := r.Context().Value(ctxBookmarkKey{}).(*bookmarks.Bookmark)
+	user := auth.GetRequestUser(r)
+
+	res := struct {
+		Total int                  `json:"total"`
+		Rows  []wallabagAnnotation `json:"rows"`
+	}{Rows: []wallabagAnnotation{}}
+	for _, a := range b.Annotations {
+		res.Rows = append(res.Rows, newWallabagAnnotation(a, user.Username))
+	}
+	res.Total = len(res.Rows)
+
+	h.srv.Render(w, r, http.StatusOK, res)
+}
+
+// annotationCreate adds an annotation to a bookmark. Wallabag only
+// sends one range per annotation, with absolute selectors that are
+// relative to the article's body in Readeck.
+func (h *wallabagRouter) annotationCreate(w http.ResponseWriter, r *http.Request) {
+	b := r.Context().Value(ctxBookmarkKey{}).(*bookmarks.Bookmark)
+
+	var payload wallabagAnnotationPayload
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&payload); err != nil || len(payload.Ranges) == 0 {
+		h.srv.Message(w, r, &server.Message{
+			Status:  http.StatusBadRequest,
+			Message: "Invalid annotation",
+		})
+		return
+	}
+
+	rg := payload.Ranges[0]
+	f := newAnnotationForm(h.srv.Locale(r))
+	forms.BindValues(f, url.Values{
+		"start_selector": {strings.TrimPrefix(rg.Start, "/")},
+		"start_offset":   {strconv.Itoa(int(rg.StartOffset))},
+		"end_selector":   {strings.TrimPrefix(rg.End, "/")},
+		"end_offset":     {strconv.Itoa(int(rg.EndOffset))},
+		"color":          {"yellow"},
+	})
+	if !f.IsValid() {
+		h.srv.Render(w, r, http.StatusBadRequest, f)
+		return
+	}
+
+	bi := newBookmarkItem(h.srv, r, b, "")
+	annotation, err := f.addToBookmark(&bi)
+	if err != nil {
+		if errors.As(err, &annotate.ErrAnotate) {
+			h.srv.Message(w, r, &server.Message{
+				Status:  http.StatusBadRequest,
+				Message: err.Error(),
+			})
+		} else {
+			h.srv.Error(w, r, err)
+		}
+		return
+	}
+
+	if payload.Text != "" {
+		annotation.Note = payload.Text
+		if err = b.Update(map[string]interface{}{"annotations": b.Annotations}); err != nil {
+			h.srv.Error(w, r, err)
+			return
+		}
+	}
+
+	b.NotifyAnnotation(bookmarks.EventAnnotationCreated, annotation.ID)
+	h.srv.Render(w, r, http.StatusOK, newWallabagAnnotation(annotation, auth.GetRequestUser(r).Username))
+}
+
+// annotationUpdate changes an annotation's note.
+func (h *wallabagRouter) annotationUpdate(w http.ResponseWriter, r *http.Request) {
+	b := r.Context().Value(ctxBookmarkKey{}).(*bookmarks.Bookmark)
+	id := r.Context().Value(ctxWallabagAnnotationKey{}).(string)
+
+	var payload wallabagAnnotationPayload
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&payload); err != nil {
+		h.srv.Message(w, r, &server.Message{
+			Status:  http.StatusBadRequest,
+			Message: "Invalid annotation",
+		})
+		return
+	}
+
+	annotation := b.Annotations.Get(id)
+	annotation.Note = payload.Text
+	if err := b.Update(map[string]interface{}{"annotations": b.Annotations}); err != nil {
+		h.srv.Error(w, r, err)
+		return
+	}
+
+	b.NotifyAnnotation(bookmarks.EventAnnotationUpdated, id)
+	h.srv.Render(w, r, http.StatusOK, newWallabagAnnotation(annotation, auth.GetRequestUser(r).Username))
+}
+
+func (h *wallabagRouter) annotationDelete(w http.ResponseWriter, r *http.Request) {
+	b := r.Context().Value(ctxBookmarkKey{}).(*bookmarks.Bookmark)
+	id := r.Context().Value(ctxWallabagAnnotationKey{}).(string)
+	res := newWallabagAnnotation(b.Annotations.Get(id), auth.GetRequestUser(r).Username)
+
+	if err := bookmarks.Trash.TrashAnnotation(b, id); err != nil {
+		h.srv.Error(w, r, err)
+		return
+	}
+
+	h.srv.Render(w, r, http.StatusOK, res)
+}
+
+// wallabagEntryForm receives the entry values sent by Wallabag clients.
+// "archive" and "starred" are 0 or 1, "tags" is a comma separated list.
+type wallabagEntryForm struct {
+	*forms.Form
+}
+
+func newWallabagEntryForm(tr forms.Translator) *wallabagEntryForm {
+	return &wallabagEntryForm{forms.Must(
+		forms.WithTranslator(context.Background(), tr),
+		forms.NewTextField("url", forms.Trim),
+		forms.NewTextField("title", forms.Trim),
+		forms.NewTextField("tags", forms.Trim),
+		forms.NewIntegerField("archive", forms.Gte(0), forms.Lte(1)),
+		forms.NewIntegerField("starred", forms.Gte(0), forms.Lte(1)),
+		forms.NewTextField("content"),
+	)}
+}
+
+// wallabagTime is a date in the Wallabag API format.
+type wallabagTime time.Time
+
+func (t wallabagTime) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Time(t).Format(wallabagTimeFormat))
+}
+
+func newWallabagTime(t *time.Time) *wallabagTime {
+	if t == nil || t.IsZero() {
+		return nil
+	}
+	res := wallabagTime(*t)
+	return &res
+}
+
+type wallabagLink struct {
+	Href string `json:"href"`
+}
+
+type wallabagLinks map[string]wallabagLink
+
+type wallabagEntryList struct {
+	Page     int           `json:"page"`
+	Limit    int           `json:"limit"`
+	Pages    int           `json:"pages"`
+	Total    int           `json:"total"`
+	Links    wallabagLinks `json:"_links"`
+	Embedded struct {
+		Items []wallabagEntry `json:"items"`
+	} `json:"_embedded"`
+}
+
+type wallabagEntry struct {
+	ID             int                  `json:"id"`
+	URL            string               `json:"url"`
+	HashedURL      string               `json:"hashed_url"`
+	GivenURL       string               `json:"given_url"`
+	HashedGivenURL string               `json:"hashed_given_url"`
+	OriginURL      *string              `json:"origin_url"`
+	Title          string               `json:"title"`
+	Content        string               `json:"content"`
+	IsArchived     int                  `json:"is_archived"`
+	ArchivedAt     *wallabagTime        `json:"archived_at"`
+	IsStarred      int                  `json:"is_starred"`
+	StarredAt      *wallabagTime        `json:"starred_at"`
+	IsPublic       bool                 `json:"is_public"`
+	Tags           []wallabagTag        `json:"tags"`
+	Annotations    []wallabagAnnotation `json:"annotations"`
+	MimeType       string               `json:"mimetype"`
+	Language       string               `json:"language"`
+	ReadingTime    int                  `json:"reading_time"`
+	DomainName     string               `json:"domain_name"`
+	PreviewPicture *string              `json:"preview_picture"`
+	PublishedAt    *wallabagTime        `json:"published_at"`
+	PublishedBy    []string             `json:"published_by"`
+	CreatedAt      wallabagTime         `json:"created_at"`
+	UpdatedAt      wallabagTime         `json:"updated_at"`
+	UserName       string               `json:"user_name"`
+	UserEmail      string               `json:"user_email"`
+	UserID         int                  `json:"user_id"`
+	Links          wallabagLinks        `json:"_links"`
+}
+
+// newWallabagEntry builds a Wallabag entry from a bookmark. When
+// withContent is true, the entry contains the article's HTML content
+// with absolute media URLs.
+func newWallabagEntry(s *server.Server, r *http.Request, b *bookmarks.Bookmark, withContent bool) wallabagEntry {
+	user := auth.GetRequestUser(r)
+	mediaURL := s.AbsoluteURL(r, "/bm", b.FilePath)
+
+	givenURL := b.InitialURL
+	if givenURL == "" {
+		givenURL = b.URL
+	}
+
+	res := wallabagEntry{
+		ID:             b.ID,
+		URL:            b.URL,
+		HashedURL:      wallabagHash(b.URL),
+		GivenURL:       givenURL,
+		HashedGivenURL: wallabagHash(givenURL),
+		Title:          b.Title,
+		IsPublic:       false,
+		Tags:           newWallabagTags(b.Labels),
+		Annotations:    []wallabagAnnotation{},
+		MimeType:       "text/html",
+		Language:       b.Lang,
+		ReadingTime:    b.ReadingTime(),
+		DomainName:     b.Site,
+		PublishedAt:    newWallabagTime(b.Published),
+		PublishedBy:    b.Authors,
+		CreatedAt:      wallabagTime(b.Created),
+		UpdatedAt:      wallabagTime(b.Updated),
+		UserName:       user.Username,
+		UserEmail:      user.Email,
+		UserID:         user.ID,
+		Links: wallabagLinks{
+			"self": {s.AbsoluteURL(r, "/wallabag/api/entries", strconv.Itoa(b.ID)).String()},
+		},
+	}
+
+	if res.PublishedBy == nil {
+		res.PublishedBy = []string{}
+	}
+	if b.IsArchived {
+		res.IsArchived = 1
+		res.ArchivedAt = newWallabagTime(&b.Updated)
+	}
+	if b.IsMarked {
+		res.IsStarred = 1
+		res.StarredAt = newWallabagTime(&b.Updated)
+	}
+	if v, ok := b.Files["image"]; ok {
+		src := mediaURL.JoinPath(v.Name).String()
+		res.PreviewPicture = &src
+	}
+	for _, a := range b.Annotations {
+		res.Annotations = append(res.Annotations, newWallabagAnnotation(a, user.Username))
+	}
+
+	if withContent && b.State == bookmarks.StateLoaded {
+		ctx := converter.WithURLReplacer(context.Background(), func(_ *bookmarks.Bookmark) func(name string) string {
+			return func(name string) string {
+				return mediaURL.JoinPath(name).String()
+			}
+		})
+		if reader, err := (converter.HTMLConverter{}).GetArticle(ctx, b); err == nil {
+			buf := new(strings.Builder)
+			if _, err = io.Copy(buf, reader); err == nil {
+				res.Content = buf.String()
+			}
+		}
+	}
+
+	return res
+}
+
+type wallabagTag struct {
+	ID        int    `json:"id"`
+	Label     string `json:"label"`
+	Slug      string `json:"slug"`
+	NbEntries int    `json:"nb_entries,omitempty"`
+}
+
+func newWallabagTag(label string) wallabagTag {
+	return wallabagTag{
+		ID:    wallabagTagID(label),
+		Label: label,
+		Slug:  utils.Slug(label),
+	}
+}
+
+func newWallabagTags(labels []string) []wallabagTag {
+	res := make([]wallabagTag, len(labels))
+	for i, label := range labels {
+		res[i] = newWallabagTag(label)
+	}
+	return res
+}
+
+// wallabagTagID returns a stable numeric ID for a label,
+// since Wallabag clients identify tags by an integer.
+func wallabagTagID(label string) int {
+	return int(crc32.ChecksumIEEE([]byte(label)) & math.MaxInt32)
+}
+
+// wallabagTagList splits a comma separated list of tags.
+func wallabagTagList(s string) []string {
+	res := []string{}
+	for _, tag := range strings.Split(s, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			res = append(res, tag)
+		}
+	}
+	return res
+}
+
+// wallabagHash returns the URL hash used by Wallabag to look up entries.
+func wallabagHash(s string) string {
+	sum := sha1.Sum([]byte(s)) //nolint:gosec
+	return hex.EncodeToString(sum[:])
+}
+
+type wallabagRange struct {
+	Start       string         `json:"start"`
+	StartOffset wallabagOffset `json:"startOffset"`
+	End         string         `json:"end"`
+	EndOffset   wallabagOffset `json:"endOffset"`
+}
+
+// wallabagOffset is a range offset that clients send
+// either as a number or as a string.
+type wallabagOffset int
+
+func (o *wallabagOffset) UnmarshalJSON(data []byte) error {
+	v, err := strconv.Atoi(strings.Trim(string(data), `"`))
+	if err != nil {
+		return err
+	}
+	*o = wallabagOffset(v)
+	return nil
+}
+
+type wallabagAnnotationPayload struct {
+	Text   string          `json:"text"`
+	Quote  string          `json:"quote"`
+	Ranges []wallabagRange `json:"ranges"`
+}
+
+type wallabagAnnotation struct {
+	ID                     string          `json:"id"`
+	AnnotatorSchemaVersion string          `json:"annotator_schema_version"`
+	Text                   string          `json:"text"`
+	Quote                  string          `json:"quote"`
+	Ranges                 []wallabagRange `json:"ranges"`
+	CreatedAt              wallabagTime    `json:"created_at"`
+	UpdatedAt              wallabagTime    `json:"updated_at"`
+	User                   string          `json:"user"`
+}
+
+func newWallabagAnnotation(a *bookmarks.BookmarkAnnotation, username string) wallabagAnnotation {
+	return wallabagAnnotation{
+		ID:                     a.ID,
+		AnnotatorSchemaVersion: "v1.0",
+		Text:                   a.Note,
+		Quote:                  a.Text,
+		Ranges: []wallabagRange{{
+			Start:       "/" + a.StartSelector,
+			StartOffset: wallabagOffset(a.StartOffset),
+			End:         "/" + a.EndSelector,
+			EndOffset:   wallabagOffset(a.EndOffset),
+		}},
+		CreatedAt: wallabagTime(a.Created),
+		UpdatedAt: wallabagTime(a.Created),
+		User:      username,
+	}
+}
@@ -0,0 +1,35 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package routes
+
+import (
+	"net/http"
+	"net/url"
+
+	"codeberg.org/readeck/readeck/internal/bookmarks"
+	"codeberg.org/readeck/readeck/internal/server"
+	"codeberg.org/readeck/readeck/internal/webhooks"
+)
+
+func init() {
+	// The encoder is set on init so a worker process, that doesn't
+	// mount any route, sends the same payloads as the server.
+	webhooks.SetBookmarkEncoder(encodeWebhookBookmark)
+}
+
+// encodeWebhookBookmark returns the bookmark's representation in a webhook
+// payload. It's the same as the bookmark information API route, with
+// the URLs built from the webhook's base URL.
+func encodeWebhookBookmark(base *url.URL, b *bookmarks.Bookmark) any {
+	s := &server.Server{BasePath: base.Path}
+	r, err := http.NewRequest(http.MethodGet, base.JoinPath("api/bookmarks/").String(), nil)
+	if err != nil {
+		return map[string]string{"id": b.UID}
+	}
+
+	item := newBookmarkItem(s, r, b, ".")
+	item.Errors = b.Errors
+	return item
+}
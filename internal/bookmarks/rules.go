@@ -0,0 +1,332 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package bookmarks
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+
+	"codeberg.org/readeck/readeck/internal/db"
+	"codeberg.org/readeck/readeck/internal/db/exp"
+	"codeberg.org/readeck/readeck/internal/db/types"
+	"codeberg.org/readeck/readeck/internal/searchstring"
+	"codeberg.org/readeck/readeck/pkg/base58"
+)
+
+const (
+	// RuleTable is the rule table name in database.
+	RuleTable = "bookmark_rule"
+)
+
+var (
+	// Rules is the rule query manager.
+	Rules = RuleManager{}
+
+	// ErrRuleNotFound is returned when a rule record was not found.
+	ErrRuleNotFound = errors.New("not found")
+)
+
+// Rule is a user's rule, applied to the bookmarks once they're saved.
+// Its query uses the search string syntax, with the "domain" and "type"
+// fields on top of the bookmark list search fields.
+type Rule struct {
+	ID        int         `db:"id" goqu:"skipinsert,skipupdate"`
+	UID       string      `db:"uid"`
+	UserID    *int        `db:"user_id"`
+	Created   time.Time   `db:"created" goqu:"skipupdate"`
+	Updated   time.Time   `db:"updated"`
+	IsEnabled bool        `db:"is_enabled"`
+	Name      string      `db:"name"`
+	Query     string      `db:"query"`
+	Actions   RuleActions `db:"actions"`
+}
+
+// RuleActions contains the changes a rule applies to the matching bookmarks.
+type RuleActions struct {
+	AddLabels    types.Strings `json:"add_labels"`
+	IsMarked     *bool         `json:"is_marked"`
+	IsArchived   *bool         `json:"is_archived"`
+	ReadProgress *int          `json:"read_progress"`
+}
+
+// Scan loads a [RuleActions] instance from a column.
+func (a *RuleActions) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+
+	v, err := types.JSONBytes(value)
+	if err != nil {
+		return err
+	}
+	json.Unmarshal(v, a) //nolint:errcheck
+	return nil
+}
+
+// Value encodes a [RuleActions] value for storage.
+func (a RuleActions) Value() (driver.Value, error) {
+	if a.AddLabels == nil {
+		a.AddLabels = types.Strings{}
+	}
+	v, err := json.Marshal(a)
+	if err != nil {
+		return "", err
+	}
+	return string(v), nil
+}
+
+// IsEmpty returns true when there's no action.
+func (a RuleActions) IsEmpty() bool {
+	return len(a.AddLabels) == 0 && a.IsMarked == nil && a.IsArchived == nil && a.ReadProgress == nil
+}
+
+// Changes applies the actions to the given bookmark and returns
+// the values that changed. The result is empty when the bookmark already
+// has everything the actions would set.
+func (a RuleActions) Changes(b *Bookmark) map[string]interface{} {
+	res := map[string]interface{}{}
+
+	if len(a.AddLabels) > 0 {
+		labels := append(slices.Clone(b.Labels), a.AddLabels...)
+		slices.SortFunc(labels, exp.UnaccentCompare)
+		labels = slices.Compact(labels)
+		if !slices.Equal(labels, b.Labels) {
+			b.Labels = labels
+			res["labels"] = b.Labels
+		}
+	}
+	if a.IsMarked != nil && *a.IsMarked != b.IsMarked {
+		b.IsMarked = *a.IsMarked
+		res["is_marked"] = b.IsMarked
+	}
+	if a.IsArchived != nil && *a.IsArchived != b.IsArchived {
+		b.IsArchived = *a.IsArchived
+		res["is_archived"] = b.IsArchived
+	}
+	if a.ReadProgress != nil && *a.ReadProgress != b.ReadProgress {
+		b.ReadProgress = *a.ReadProgress
+		res["read_progress"] = b.ReadProgress
+		if b.ReadProgress == 0 || b.ReadProgress == 100 {
+			b.ReadAnchor = ""
+			res["read_anchor"] = ""
+		}
+	}
+
+	return res
+}
+
+// RuleManager is a query helper for rule entries.
+type RuleManager struct{}
+
+// Query returns a prepared goqu SelectDataset that can be extended later.
+func (m *RuleManager) Query() *goqu.SelectDataset {
+	return db.Q().From(goqu.T(RuleTable).As("r")).Prepared(true)
+}
+
+// GetOne executes the a select query and returns the first result or an error
+// when there's no result.
+func (m *RuleManager) GetOne(expressions ...goqu.Expression) (*Rule, error) {
+	var r Rule
+	found, err := m.Query().Where(expressions...).ScanStruct(&r)
+
+	switch {
+	case err != nil:
+		return nil, err
+	case !found:
+		return nil, ErrRuleNotFound
+	}
+
+	return &r, nil
+}
+
+// Create inserts a new rule in the database.
+func (m *RuleManager) Create(r *Rule) error {
+	if r.UserID == nil {
+		return errors.New("no rule user")
+	}
+	if strings.TrimSpace(r.Query) == "" {
+		return errors.New("no query")
+	}
+
+	r.Created = time.Now()
+	r.Updated = r.Created
+	r.UID = base58.NewUUID()
+
+	ds := db.Q().Insert(RuleTable).
+		Rows(r).
+		Prepared(true)
+
+	id, err := db.InsertWithID(ds, "id")
+	if err != nil {
+		return err
+	}
+
+	r.ID = id
+	return nil
+}
+
+// ApplyAll runs the user's enabled rules on a bookmark, in their creation
+// order. It returns the rules that changed the bookmark.
+func (m *RuleManager) ApplyAll(b *Bookmark) ([]*Rule, error) {
+	if b.UserID == nil {
+		return nil, nil
+	}
+
+	rules := []*Rule{}
+	err := m.Query().
+		Where(
+			goqu.C("user_id").Table("r").Eq(*b.UserID),
+			goqu.C("is_enabled").Table("r").IsTrue(),
+		).
+		Order(goqu.C("created").Table("r").Asc(), goqu.C("id").Table("r").Asc()).
+		ScanStructs(&rules)
+	if err != nil {
+		return nil, err
+	}
+
+	res := []*Rule{}
+	for _, r := range rules {
+		ok, err := r.Matches(b)
+		if err != nil {
+			return res, err
+		}
+		if !ok {
+			continue
+		}
+
+		values := r.Actions.Changes(b)
+		if len(values) == 0 {
+			continue
+		}
+		if err = b.Update(values); err != nil {
+			return res, err
+		}
+		res = append(res, r)
+	}
+
+	return res, nil
+}
+
+// Update updates some rule values.
+func (r *Rule) Update(v interface{}) error {
+	if r.ID == 0 {
+		return errors.New("no ID")
+	}
+
+	switch v := v.(type) {
+	case map[string]interface{}:
+		v["updated"] = time.Now()
+	case *Rule:
+		v.Updated = time.Now()
+	}
+
+	_, err := db.Q().Update(RuleTable).Prepared(true).
+		Set(v).
+		Where(goqu.C("id").Eq(r.ID)).
+		Executor().Exec()
+
+	return err
+}
+
+// Save updates all the rule values.
+func (r *Rule) Save() error {
+	return r.Update(r)
+}
+
+// Delete removes a rule from the database.
+func (r *Rule) Delete() error {
+	_, err := db.Q().Delete(RuleTable).Prepared(true).
+		Where(goqu.C("id").Eq(r.ID)).
+		Executor().Exec()
+
+	return err
+}
+
+// ToSelectDataSet adds the rule's query to the given [*goqu.SelectDataset]
+// on the bookmarks and returns it.
+func (r *Rule) ToSelectDataSet(ds *goqu.SelectDataset) *goqu.SelectDataset {
+	domains, sq := searchstring.ParseQuery(r.Query).PopField("domain")
+	kinds, sq := sq.PopField("type")
+
+	// Domains match their subdomains too.
+	col := goqu.C("domain").Table("b")
+	for _, x := range domains.Terms {
+		v := strings.ToLower(x.Value)
+		switch {
+		case x.Wildcard && x.Exclude:
+			ds = ds.Where(col.NotLike(v + "%"))
+		case x.Wildcard:
+			ds = ds.Where(col.Like(v + "%"))
+		case x.Exclude:
+			ds = ds.Where(col.Neq(v), col.NotLike("%."+v))
+		default:
+			ds = ds.Where(goqu.Or(col.Eq(v), col.Like("%."+v)))
+		}
+	}
+
+	col = goqu.C("type").Table("b")
+	for _, x := range kinds.Terms {
+		v := x.Value
+		if v == "picture" {
+			v = "photo"
+		}
+		if x.Exclude {
+			ds = ds.Where(col.Neq(v))
+		} else {
+			ds = ds.Where(col.Eq(v))
+		}
+	}
+
+	return Filters{Search: sq.String()}.ToSelectDataSet(ds)
+}
+
+// Select returns a dataset with all the rule owner's bookmarks that match
+// the rule's query.
+func (r *Rule) Select() *goqu.SelectDataset {
+	ds := Bookmarks.Query()
+	if r.UserID != nil {
+		ds = ds.Where(goqu.C("user_id").Table("b").Eq(*r.UserID))
+	}
+	return r.ToSelectDataSet(ds)
+}
+
+// Matches returns true when the bookmark matches the rule's query.
+func (r *Rule) Matches(b *Bookmark) (bool, error) {
+	count, err := r.Select().
+		Where(goqu.C("id").Table("b").Eq(b.ID)).
+		Count()
+	return count > 0, err
+}
+
+// Apply runs the rule's actions on every bookmark it matches.
+// It returns the bookmarks that changed.
+func (r *Rule) Apply() ([]*Bookmark, error) {
+	items := []*Bookmark{}
+	if err := r.Select().ScanStructs(&items); err != nil {
+		return nil, err
+	}
+
+	res := []*Bookmark{}
+	updates := []BookmarkUpdate{}
+	for _, b := range items {
+		values := r.Actions.Changes(b)
+		if len(values) == 0 {
+			continue
+		}
+		updates = append(updates, BookmarkUpdate{Bookmark: b, Values: values})
+		res = append(res, b)
+	}
+
+	if len(updates) == 0 {
+		return res, nil
+	}
+	return res, Bookmarks.UpdateAll(updates)
+}
@@ -0,0 +1,172 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package bookmarks_test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/stretchr/testify/require"
+
+	"codeberg.org/readeck/readeck/internal/bookmarks"
+	"codeberg.org/readeck/readeck/internal/db/types"
+	. "codeberg.org/readeck/readeck/internal/testing" //revive:disable:dot-imports
+)
+
+func TestRuleActions(t *testing.T) {
+	b := &bookmarks.Bookmark{
+		Labels:       types.Strings{"b", "c"},
+		IsMarked:     true,
+		ReadProgress: 40,
+		ReadAnchor:   "p:nth-child(2)",
+	}
+
+	a := bookmarks.RuleActions{
+		AddLabels:    types.Strings{"a", "c"},
+		IsMarked:     ptrTo(true),
+		IsArchived:   ptrTo(true),
+		ReadProgress: ptrTo(100),
+	}
+	require.Equal(t, map[string]interface{}{
+		"labels":        types.Strings{"a", "b", "c"},
+		"is_archived":   true,
+		"read_progress": 100,
+		"read_anchor":   "",
+	}, a.Changes(b))
+	require.Equal(t, types.Strings{"a", "b", "c"}, b.Labels)
+	require.True(t, b.IsArchived)
+
+	// Nothing left to change
+	require.Empty(t, a.Changes(b))
+	require.True(t, bookmarks.RuleActions{AddLabels: types.Strings{}}.IsEmpty())
+}
+
+func TestRules(t *testing.T) {
+	app := NewTestApp(t)
+	defer func() {
+		app.Close(t)
+	}()
+
+	u := app.Users["user"]
+	items := []*bookmarks.Bookmark{}
+	for i, x := range []struct {
+		domain string
+		kind   string
+		title  string
+	}{
+		{"example.net", "article", "Some news"},
+		{"blog.example.net", "article", "Some blog post"},
+		{"example.org", "video", "Some video"},
+	} {
+		b := &bookmarks.Bookmark{
+			UserID:       &u.User.ID,
+			URL:          "https://" + x.domain + "/" + strconv.Itoa(i),
+			Domain:       x.domain,
+			Site:         x.domain,
+			DocumentType: x.kind,
+			Title:        x.title,
+			State:        bookmarks.StateLoaded,
+		}
+		require.NoError(t, bookmarks.Bookmarks.Create(b))
+		items = append(items, b)
+	}
+
+	uids := func(list []*bookmarks.Bookmark) []string {
+		res := []string{}
+		for _, b := range list {
+			res = append(res, b.UID)
+		}
+		return res
+	}
+
+	t.Run("select", func(t *testing.T) {
+		tests := []struct {
+			query    string
+			expected []string
+		}{
+			{"domain:example.net", uids(items[0:2])},
+			{"-domain:example.net", []string{u.Bookmarks[0].UID, items[2].UID}},
+			{"domain:blog.*", uids(items[1:2])},
+			{"type:video", uids(items[2:])},
+			{"-type:video domain:example.net", uids(items[0:2])},
+			{"title:blog", uids(items[1:2])},
+			{"domain:example.org title:blog", []string{}},
+		}
+
+		for i, test := range tests {
+			t.Run(strconv.Itoa(i+1), func(t *testing.T) {
+				r := &bookmarks.Rule{UserID: &u.User.ID, Query: test.query}
+				res := []string{}
+				err := r.Select().Select(goqu.C("uid").Table("b")).
+					Order(goqu.C("id").Table("b").Asc()).
+					ScanVals(&res)
+				require.NoError(t, err)
+				require.Equal(t, test.expected, res)
+			})
+		}
+	})
+
+	t.Run("apply", func(t *testing.T) {
+		r := &bookmarks.Rule{
+			UserID:    &u.User.ID,
+			IsEnabled: true,
+			Query:     "domain:example.net",
+			Actions:   bookmarks.RuleActions{AddLabels: types.Strings{"news"}},
+		}
+		require.NoError(t, bookmarks.Rules.Create(r))
+
+		changed, err := r.Apply()
+		require.NoError(t, err)
+		require.Equal(t, uids(items[0:2]), uids(changed))
+
+		// A second run doesn't change anything
+		changed, err = r.Apply()
+		require.NoError(t, err)
+		require.Empty(t, changed)
+
+		require.NoError(t, r.Delete())
+	})
+
+	t.Run("apply all", func(t *testing.T) {
+		rules := []*bookmarks.Rule{
+			{
+				UserID:    &u.User.ID,
+				IsEnabled: true,
+				Query:     "type:video",
+				Actions:   bookmarks.RuleActions{AddLabels: types.Strings{"watch"}},
+			},
+			{
+				UserID:    &u.User.ID,
+				IsEnabled: true,
+				Query:     "label:watch",
+				Actions:   bookmarks.RuleActions{IsMarked: ptrTo(true)},
+			},
+			{
+				UserID:    &u.User.ID,
+				IsEnabled: false,
+				Query:     "type:video",
+				Actions:   bookmarks.RuleActions{IsArchived: ptrTo(true)},
+			},
+		}
+		for _, r := range rules {
+			require.NoError(t, bookmarks.Rules.Create(r))
+		}
+
+		applied, err := bookmarks.Rules.ApplyAll(items[2])
+		require.NoError(t, err)
+		require.Len(t, applied, 2)
+
+		b, err := bookmarks.Bookmarks.GetOne(goqu.C("id").Eq(items[2].ID))
+		require.NoError(t, err)
+		require.Equal(t, types.Strings{"watch"}, b.Labels)
+		require.True(t, b.IsMarked)
+		require.False(t, b.IsArchived)
+
+		applied, err = bookmarks.Rules.ApplyAll(items[0])
+		require.NoError(t, err)
+		require.Empty(t, applied)
+	})
+}
@@ -45,12 +45,25 @@ import (
 var (
 	// ExtractPageTask is the bookmark creation task.
 	ExtractPageTask superbus.Task
-	// DeleteBookmarkTask is the bookmark deletion task.
-	DeleteBookmarkTask superbus.Task
-	// DeleteCollectionTask is the collection deletion task.
-	DeleteCollectionTask superbus.Task
+	// BackgroundExtractTask is the bookmark creation task for the
+	// bookmarks nobody is waiting for, like feed entries.
+	BackgroundExtractTask superbus.Task
 	// DeleteLabelTask is the label deletion task.
 	DeleteLabelTask superbus.Task
+	// PurgeTombstonesTask is the daily removal of old deletion records.
+	PurgeTombstonesTask superbus.Task
+	// PurgeTrashTask is the daily removal of the items that stayed
+	// in the trash longer than the retention period.
+	PurgeTrashTask superbus.Task
+
+	// extractRetryPolicy lets a bookmark extraction run again when
+	// the page couldn't be loaded because of a transient error.
+	extractRetryPolicy = superbus.RetryPolicy{
+		MaxAttempts: 4,
+		Backoff:     time.Second * 30,
+		MaxBackoff:  time.Minute * 10,
+		Retryable:   extract.IsRetryable,
+	}
 )
 
 type (
@@ -80,6 +93,7 @@ func init() {
 	bus.OnReady(func() {
 		ExtractPageTask = bus.Tasks().NewTask(
 			"bookmark.create",
+			superbus.WithTaskQueue(bus.QueueInteractive),
 			superbus.WithUnmarshall(func(data []byte) interface{} {
 				var res ExtractParams
 				err := json.Unmarshal(data, &res)
@@ -88,39 +102,30 @@ func init() {
 				}
 				return res
 			}),
-			superbus.WithTaskHandler(extractPageHandler),
+			superbus.WithFallibleTaskHandler(extractPageHandler),
+			superbus.WithTaskRetry(extractRetryPolicy),
+			superbus.WithTaskFailure(extractPageFailure),
 		)
 
-		DeleteBookmarkTask = bus.Tasks().NewTask(
-			"bookmark.delete",
-			superbus.WithTaskDelay(20),
+		BackgroundExtractTask = bus.Tasks().NewTask(
+			"bookmark.create_background",
+			superbus.WithTaskQueue(bus.QueueImport),
 			superbus.WithUnmarshall(func(data []byte) interface{} {
-				var res int
-				err := json.Unmarshal(data, &res)
-				if err != nil {
-					panic(err)
-				}
-				return res
-			}),
-			superbus.WithTaskHandler(deleteBookmarkHandler),
-		)
-
-		DeleteCollectionTask = bus.Tasks().NewTask(
-			"collection.delete",
-			superbus.WithTaskDelay(20),
-			superbus.WithUnmarshall(func(data []byte) interface{} {
-				var res int
+				var res ExtractParams
 				err := json.Unmarshal(data, &res)
 				if err != nil {
 					panic(err)
 				}
 				return res
 			}),
-			superbus.WithTaskHandler(deleteCollectionHandler),
+			superbus.WithFallibleTaskHandler(extractPageHandler),
+			superbus.WithTaskRetry(extractRetryPolicy),
+			superbus.WithTaskFailure(extractPageFailure),
 		)
 
 		DeleteLabelTask = bus.Tasks().NewTask(
 			"label.delete",
+			superbus.WithTaskQueue(bus.QueueMaintenance),
 			superbus.WithTaskDelay(20),
 			superbus.WithUnmarshall(func(data []byte) interface{} {
 				var res LabelDeleteParams
@@ -132,52 +137,50 @@ func init() {
 			}),
 			superbus.WithTaskHandler(deleteLabelHandler),
 		)
+
+		PurgeTombstonesTask = bus.Tasks().NewTask(
+			"tombstone.purge",
+			superbus.WithTaskQueue(bus.QueueMaintenance),
+			superbus.WithTaskSchedule(superbus.Every(24*time.Hour)),
+			superbus.WithFallibleTaskHandler(purgeTombstonesHandler),
+		)
+
+		PurgeTrashTask = bus.Tasks().NewTask(
+			"trash.purge",
+			superbus.WithTaskQueue(bus.QueueMaintenance),
+			superbus.WithTaskSchedule(superbus.Every(24*time.Hour)),
+			superbus.WithFallibleTaskHandler(purgeTrashHandler),
+		)
 	})
 }
 
 // ExtractPage is the public function that run an extraction synchronously.
+// It doesn't retry on a transient error.
 // Caution: it will panic and should only be run insisde another task.
 func ExtractPage(params ExtractParams) {
-	extractPageHandler(params)
+	if err := extractPageHandler(params); err != nil {
+		extractPageFailure(params, err)
+	}
 }
 
-func deleteBookmarkHandler(data interface{}) {
-	id := data.(int)
-	logger := slog.With(slog.Int("id", id))
-
-	logger.Debug("deleting bookmark")
-	b, err := bookmarks.Bookmarks.GetOne(goqu.C("id").Eq(id))
+func purgeTombstonesHandler(_ interface{}) error {
+	n, err := bookmarks.Tombstones.Purge(time.Now().Add(-bookmarks.TombstoneRetention))
 	if err != nil {
-		logger.Error("bookmark retrieve", slog.Any("err", err))
-		return
-	}
-
-	if err := b.Delete(); err != nil {
-		logger.Error("bookmark removal", slog.Any("err", err))
-		return
+		return err
 	}
 
-	logger.Info("bookmark removed")
+	slog.Debug("tombstones purged", slog.Int64("count", n))
+	return nil
 }
 
-func deleteCollectionHandler(data interface{}) {
-	id := data.(int)
-	logger := slog.With(slog.Int("id", id))
-
-	logger.Debug("deleting collection")
-
-	c, err := bookmarks.Collections.GetOne(goqu.C("id").Eq(id))
+func purgeTrashHandler(_ interface{}) error {
+	n, err := bookmarks.Trash.Empty(nil, time.Now().Add(-bookmarks.TrashRetention()))
 	if err != nil {
-		logger.Error("collection retrieve", slog.Any("err", err))
-		return
-	}
-
-	if err := c.Delete(); err != nil {
-		logger.Error("collection removal", slog.Any("err", err))
-		return
+		return err
 	}
 
-	logger.Info("collection removed")
+	slog.Debug("trash purged", slog.Int64("count", n))
+	return nil
 }
 
 func deleteLabelHandler(data interface{}) {
@@ -202,7 +205,7 @@ func deleteLabelHandler(data interface{}) {
 	logger.Info("label removed")
 }
 
-func extractPageHandler(data interface{}) {
+func extractPageHandler(data interface{}) (retryErr error) {
 	var b *bookmarks.Bookmark
 	var err error
 
@@ -223,6 +226,12 @@ func extractPageHandler(data interface{}) {
 			return
 		}
 
+		// The page will be loaded again, the bookmark stays as is.
+		if retryErr != nil {
+			logger.Warn("extraction failed", slog.Any("err", retryErr))
+			return
+		}
+
 		// Recover from any error that could have arose
 		if r := recover(); r != nil {
 			logger.Error("error during extraction", slog.Any("recover", r))
@@ -245,6 +254,14 @@ func extractPageHandler(data interface{}) {
 			}
 		}
 
+		// Apply the user's rules on the saved bookmark
+		if applied, err := bookmarks.Rules.ApplyAll(b); err != nil {
+			logger.Error("applying rules", slog.Any("err", err))
+		} else if len(applied) > 0 {
+			logger.Debug("rules applied", slog.Int("count", len(applied)))
+		}
+		b.NotifyState()
+
 		metricCreation.WithLabelValues(b.StateName()).Inc()
 		metricTiming.WithLabelValues(b.StateName()).Observe(time.Since(start).Seconds())
 		metricResources.Observe(float64(resourceCount))
@@ -254,7 +271,7 @@ func extractPageHandler(data interface{}) {
 	b, err = bookmarks.Bookmarks.GetOne(goqu.C("id").Eq(params.BookmarkID))
 	if err != nil {
 		logger.Error("", slog.Any("err", err))
-		return
+		return nil
 	}
 
 	proxyList := make([]extract.ProxyMatcher, len(configs.Config.Extractor.ProxyMatch))
@@ -273,7 +290,7 @@ func extractPageHandler(data interface{}) {
 	)
 	if err != nil {
 		logger.Error("", slog.Any("err", err))
-		return
+		return nil
 	}
 
 	for _, x := range params.Resources {
@@ -309,6 +326,7 @@ func extractPageHandler(data interface{}) {
 		CleanDomProcessor,
 		extractLinksProcessor,
 		contents.Text,
+		checkRetryableError(&retryErr),
 		saveBookmark(b, &saved, &resourceCount),
 		fetchLinksProcessor(b),
 	)
@@ -319,6 +337,53 @@ func extractPageHandler(data interface{}) {
 	}
 
 	ex.Run()
+	return retryErr
+}
+
+// extractPageFailure is called when an extraction failed for good.
+// The bookmark is then marked as in error.
+func extractPageFailure(data interface{}, err error) {
+	params := data.(ExtractParams)
+	logger := slog.With(
+		slog.String("@id", params.RequestID),
+		slog.Int("bookmark_id", params.BookmarkID),
+	)
+
+	b, gerr := bookmarks.Bookmarks.GetOne(goqu.C("id").Eq(params.BookmarkID))
+	if gerr != nil {
+		logger.Error("", slog.Any("err", gerr))
+		return
+	}
+
+	b.State = bookmarks.StateError
+	b.Errors = append(b.Errors, err.Error())
+	if err := b.Save(); err != nil {
+		logger.Error("saving bookmark", slog.Any("err", err))
+	}
+	b.NotifyState()
+}
+
+// checkRetryableError stops the process when the page could not be
+// loaded because of a transient error, so the task can run again later.
+func checkRetryableError(err *error) extract.Processor {
+	return func(m *extract.ProcessMessage, next extract.Processor) extract.Processor {
+		if m.Step() != extract.StepDone {
+			return next
+		}
+
+		drop := m.Extractor.Drop()
+		if drop != nil && len(drop.Body) > 0 {
+			return next
+		}
+
+		// Only the main document's failure decides; a failed image
+		// or a secondary page must not delay the bookmark.
+		if lerr := m.Extractor.LoadError(); extract.IsRetryable(lerr) {
+			*err = lerr
+			return nil
+		}
+		return next
+	}
 }
 
 func conditionnalProcessor(test bool, p extract.Processor) extract.Processor {
@@ -0,0 +1,110 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package bookmarks
+
+import (
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+
+	"codeberg.org/readeck/readeck/internal/db"
+)
+
+const (
+	// TombstoneTable is the tombstone table name in database.
+	TombstoneTable = "bookmark_tombstone"
+
+	// TombstoneRetention is how long deletion records are kept
+	// for the synchronization API.
+	TombstoneRetention = 180 * 24 * time.Hour
+)
+
+// Tombstone kinds.
+const (
+	TombstoneBookmark   = "bookmark"
+	TombstoneAnnotation = "annotation"
+	TombstoneLabel      = "label"
+	TombstoneCollection = "collection"
+)
+
+// Tombstones is the tombstone query manager.
+var Tombstones = TombstoneManager{}
+
+// Tombstone is a record of a removed item. It lets offline clients
+// know what was deleted since their last synchronization.
+type Tombstone struct {
+	ID        int       `db:"id" goqu:"skipinsert,skipupdate"`
+	UserID    *int      `db:"user_id"`
+	Deleted   time.Time `db:"deleted"`
+	Kind      string    `db:"kind"`
+	UID       string    `db:"uid"`
+	ParentUID string    `db:"parent_uid"`
+}
+
+// TombstoneManager is a query helper for tombstone entries.
+type TombstoneManager struct{}
+
+// Query returns a prepared goqu SelectDataset that can be extended later.
+func (m *TombstoneManager) Query() *goqu.SelectDataset {
+	return db.Q().From(goqu.T(TombstoneTable).As("t")).Prepared(true)
+}
+
+// Add records the removal of an item. The parent is the UID of the
+// item's container (the bookmark of an annotation) and can be empty.
+func (m *TombstoneManager) Add(userID int, kind, uid, parent string) error {
+	t := &Tombstone{
+		UserID:    &userID,
+		Deleted:   time.Now(),
+		Kind:      kind,
+		UID:       uid,
+		ParentUID: parent,
+	}
+
+	_, err := db.Q().Insert(TombstoneTable).
+		Rows(t).
+		Prepared(true).
+		Executor().Exec()
+	return err
+}
+
+// Remove forgets the removal of an item that was restored, so
+// clients don't receive both the item and its removal.
+func (m *TombstoneManager) Remove(userID int, kind, uid string) error {
+	_, err := db.Q().Delete(TombstoneTable).Prepared(true).
+		Where(
+			goqu.C("user_id").Eq(userID),
+			goqu.C("kind").Eq(kind),
+			goqu.C("uid").Eq(uid),
+		).
+		Executor().Exec()
+	return err
+}
+
+// Since returns all the tombstones of a user that were
+// recorded on or after the given time.
+func (m *TombstoneManager) Since(userID int, since time.Time) ([]*Tombstone, error) {
+	res := []*Tombstone{}
+	err := m.Query().
+		Where(
+			goqu.C("user_id").Eq(userID),
+			goqu.C("deleted").Gte(since),
+		).
+		Order(goqu.C("deleted").Desc()).
+		ScanStructs(&res)
+
+	return res, err
+}
+
+// Purge removes all the tombstones recorded before the given time.
+func (m *TombstoneManager) Purge(before time.Time) (int64, error) {
+	res, err := db.Q().Delete(TombstoneTable).Prepared(true).
+		Where(goqu.C("deleted").Lt(before)).
+		Executor().Exec()
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
@@ -0,0 +1,198 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package bookmarks
+
+import (
+	"errors"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+
+	"codeberg.org/readeck/readeck/configs"
+	"codeberg.org/readeck/readeck/internal/db"
+)
+
+const (
+	// AnnotationTrashTable is the table of the annotations in the trash.
+	AnnotationTrashTable = "bookmark_annotation_trash"
+)
+
+var (
+	// Trash is the trash query manager.
+	Trash = TrashManager{}
+
+	// ErrTrashNotFound is returned when an item is not in the trash.
+	ErrTrashNotFound = errors.New("not found")
+)
+
+// TrashRetention returns how long deleted items stay in the trash
+// before they're removed for good.
+func TrashRetention() time.Duration {
+	return time.Duration(configs.Config.Bookmarks.TrashRetention) * 24 * time.Hour
+}
+
+// TrashedAnnotation is an annotation removed from its bookmark and
+// kept in the trash.
+type TrashedAnnotation struct {
+	ID         int                 `db:"id" goqu:"skipinsert,skipupdate"`
+	UserID     *int                `db:"user_id"`
+	BookmarkID int                 `db:"bookmark_id"`
+	Deleted    time.Time           `db:"deleted"`
+	Annotation *BookmarkAnnotation `db:"annotation"`
+}
+
+// TrashManager is a query helper for the items in the trash.
+type TrashManager struct{}
+
+// Annotations returns a prepared goqu SelectDataset on the annotations
+// in the trash.
+func (m *TrashManager) Annotations() *goqu.SelectDataset {
+	return db.Q().From(goqu.T(AnnotationTrashTable).As("a")).Prepared(true)
+}
+
+// GetAnnotation returns an annotation in the trash.
+func (m *TrashManager) GetAnnotation(expressions ...goqu.Expression) (*TrashedAnnotation, error) {
+	var a TrashedAnnotation
+	found, err := m.Annotations().Where(expressions...).ScanStruct(&a)
+
+	switch {
+	case err != nil:
+		return nil, err
+	case !found:
+		return nil, ErrTrashNotFound
+	}
+
+	return &a, nil
+}
+
+// TrashAnnotation removes an annotation from a bookmark and moves it to the trash.
+func (m *TrashManager) TrashAnnotation(b *Bookmark, id string) error {
+	a := b.Annotations.Get(id)
+	if a == nil {
+		return ErrTrashNotFound
+	}
+
+	_, err := db.Q().Insert(AnnotationTrashTable).
+		Rows(&TrashedAnnotation{
+			UserID:     b.UserID,
+			BookmarkID: b.ID,
+			Deleted:    time.Now(),
+			Annotation: a,
+		}).
+		Prepared(true).
+		Executor().Exec()
+	if err != nil {
+		return err
+	}
+
+	b.Annotations.Delete(id)
+	if err = b.Update(map[string]interface{}{
+		"annotations": b.Annotations,
+	}); err != nil {
+		return err
+	}
+
+	if b.UserID != nil {
+		if err = Tombstones.Add(*b.UserID, TombstoneAnnotation, id, b.UID); err != nil {
+			return err
+		}
+	}
+
+	b.NotifyAnnotation(EventAnnotationDeleted, id)
+	return nil
+}
+
+// Bookmark returns the bookmark of an annotation in the trash.
+func (a *TrashedAnnotation) Bookmark() (*Bookmark, error) {
+	return Bookmarks.GetOne(goqu.C("id").Eq(a.BookmarkID))
+}
+
+// Restore puts the annotation back on its bookmark.
+func (a *TrashedAnnotation) Restore() error {
+	b, err := a.Bookmark()
+	if err != nil {
+		return err
+	}
+
+	if b.Annotations == nil {
+		b.Annotations = BookmarkAnnotations{}
+	}
+	if b.Annotations.Get(a.Annotation.ID) == nil {
+		b.Annotations.Add(a.Annotation)
+		if err = b.Update(map[string]interface{}{
+			"annotations": b.Annotations,
+		}); err != nil {
+			return err
+		}
+	}
+
+	if err = a.Delete(); err != nil {
+		return err
+	}
+
+	if b.UserID != nil {
+		if err = Tombstones.Remove(*b.UserID, TombstoneAnnotation, a.Annotation.ID); err != nil {
+			return err
+		}
+	}
+
+	b.NotifyAnnotation(EventAnnotationCreated, a.Annotation.ID)
+	return nil
+}
+
+// Delete removes the annotation from the trash.
+func (a *TrashedAnnotation) Delete() error {
+	_, err := db.Q().Delete(AnnotationTrashTable).Prepared(true).
+		Where(goqu.C("id").Eq(a.ID)).
+		Executor().Exec()
+	return err
+}
+
+// Empty removes for good the items put in the trash before the given time.
+// When userID is not nil, only the user's items are removed.
+// It returns the number of removed items.
+func (m *TrashManager) Empty(userID *int, before time.Time) (int64, error) {
+	var count int64
+
+	where := func(table string) []goqu.Expression {
+		res := []goqu.Expression{goqu.C("deleted").Table(table).Lt(before)}
+		if userID != nil {
+			res = append(res, goqu.C("user_id").Table(table).Eq(*userID))
+		}
+		return res
+	}
+
+	// Bookmarks are deleted one by one, to remove their files.
+	bookmarkList := []*Bookmark{}
+	if err := Bookmarks.TrashQuery().Where(where("b")...).ScanStructs(&bookmarkList); err != nil {
+		return count, err
+	}
+	for _, b := range bookmarkList {
+		if err := b.Delete(); err != nil {
+			return count, err
+		}
+		count++
+	}
+
+	collectionList := []*Collection{}
+	if err := Collections.TrashQuery().Where(where("c")...).ScanStructs(&collectionList); err != nil {
+		return count, err
+	}
+	for _, c := range collectionList {
+		if err := c.Delete(); err != nil {
+			return count, err
+		}
+		count++
+	}
+
+	res, err := db.Q().Delete(AnnotationTrashTable).Prepared(true).
+		Where(where(AnnotationTrashTable)...).
+		Executor().Exec()
+	if err != nil {
+		return count, err
+	}
+	n, err := res.RowsAffected()
+	return count + n, err
+}
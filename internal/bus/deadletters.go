@@ -0,0 +1,135 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package bus
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+
+	"codeberg.org/readeck/readeck/internal/db"
+	"codeberg.org/readeck/readeck/pkg/base58"
+	"codeberg.org/readeck/readeck/pkg/superbus"
+)
+
+const (
+	// DeadLetterTable is the dead letter table name in database.
+	DeadLetterTable = "task_dead_letter"
+)
+
+var (
+	// DeadLetters is the dead letter query manager.
+	DeadLetters = DeadLetterManager{}
+
+	// ErrDeadLetterNotFound is returned when a dead letter record was not found.
+	ErrDeadLetterNotFound = errors.New("not found")
+)
+
+// DeadLetter is a task operation that failed and won't be retried.
+// It keeps everything needed to launch the task again.
+type DeadLetter struct {
+	ID       int       `db:"id" goqu:"skipinsert,skipupdate"`
+	UID      string    `db:"uid"`
+	Created  time.Time `db:"created"`
+	Name     string    `db:"name"`
+	OpID     string    `db:"op_id"`
+	Attempts int       `db:"attempts"`
+	Data     string    `db:"data"`
+	Error    string    `db:"error"`
+}
+
+// DeadLetterManager is a query helper for dead letter entries.
+type DeadLetterManager struct{}
+
+// Query returns a prepared goqu SelectDataset that can be extended later.
+func (m *DeadLetterManager) Query() *goqu.SelectDataset {
+	return db.Q().From(goqu.T(DeadLetterTable).As("d")).Prepared(true)
+}
+
+// GetOne executes the a select query and returns the first result or an error
+// when there's no result.
+func (m *DeadLetterManager) GetOne(expressions ...goqu.Expression) (*DeadLetter, error) {
+	var d DeadLetter
+	found, err := m.Query().Where(expressions...).ScanStruct(&d)
+
+	switch {
+	case err != nil:
+		return nil, err
+	case !found:
+		return nil, ErrDeadLetterNotFound
+	}
+
+	return &d, nil
+}
+
+// Create inserts a new dead letter in the database.
+func (m *DeadLetterManager) Create(d *DeadLetter) error {
+	d.Created = time.Now()
+	d.UID = base58.NewUUID()
+
+	ds := db.Q().Insert(DeadLetterTable).
+		Rows(d).
+		Prepared(true)
+
+	id, err := db.InsertWithID(ds, "id")
+	if err != nil {
+		return err
+	}
+
+	d.ID = id
+	return nil
+}
+
+// Purge removes all the dead letters.
+func (m *DeadLetterManager) Purge() (int64, error) {
+	res, err := db.Q().Delete(DeadLetterTable).Prepared(true).
+		Executor().Exec()
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+// Delete removes a dead letter from the database.
+func (d *DeadLetter) Delete() error {
+	_, err := db.Q().Delete(DeadLetterTable).Prepared(true).
+		Where(goqu.C("id").Eq(d.ID)).
+		Executor().Exec()
+
+	return err
+}
+
+// Requeue launches the task again with its original data and
+// removes the dead letter.
+func (d *DeadLetter) Requeue() error {
+	if err := Tasks().Launch(d.Name, d.OpID, 0, json.RawMessage(d.Data)); err != nil {
+		return err
+	}
+
+	return d.Delete()
+}
+
+// saveDeadLetter is the task manager's dead letter handler.
+func saveDeadLetter(op *superbus.Operation, p *superbus.Payload, err error) {
+	d := &DeadLetter{
+		Name:     op.Name,
+		OpID:     fmt.Sprintf("%v", op.ID),
+		Attempts: p.Attempt,
+		Data:     string(p.Data),
+		Error:    err.Error(),
+	}
+
+	if err := DeadLetters.Create(d); err != nil {
+		slog.Error("saving dead letter",
+			slog.Any("operation", *op),
+			slog.Any("err", err),
+		)
+	}
+}
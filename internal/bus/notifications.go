@@ -0,0 +1,50 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package bus
+
+import (
+	"encoding/json"
+	"log/slog"
+	"strconv"
+
+	"codeberg.org/readeck/readeck/pkg/superbus"
+)
+
+// Notification is a message sent to every connected client of a user.
+type Notification struct {
+	Name string          `json:"name"`
+	Data json.RawMessage `json:"data"`
+}
+
+func userChannel(userID int) string {
+	return "user:" + strconv.Itoa(userID)
+}
+
+// Notify sends a notification to a user's connected clients.
+// A notification is informative only and an error is just logged.
+// It does nothing when the bus is not loaded.
+func Notify(userID int, name string, data any) {
+	if pubsub == nil {
+		return
+	}
+
+	l := slog.With(slog.Int("user", userID), slog.String("name", name))
+
+	d, err := json.Marshal(data)
+	if err != nil {
+		l.Error("encoding notification", slog.Any("err", err))
+		return
+	}
+	msg, _ := json.Marshal(Notification{Name: name, Data: d})
+
+	if err = pubsub.Publish(userChannel(userID), msg); err != nil {
+		l.Error("sending notification", slog.Any("err", err))
+	}
+}
+
+// Subscribe returns a subscription to a user's notifications.
+func Subscribe(userID int) *superbus.Subscription {
+	return pubsub.Subscribe(userChannel(userID))
+}
@@ -6,23 +6,39 @@
 package bus
 
 import (
+	"cmp"
 	"fmt"
 	"net/url"
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/go-redis/redis/v8"
 
 	"codeberg.org/readeck/readeck/configs"
+	"codeberg.org/readeck/readeck/internal/db"
 	"codeberg.org/readeck/readeck/pkg/superbus"
 )
 
+// Task queues. Each one has its own workers, so a long import can't
+// delay a bookmark saved by a user. Idle workers run the operations
+// of a queue with a higher priority first.
+const (
+	// QueueInteractive is for the tasks a user is waiting for.
+	QueueInteractive = "interactive"
+	// QueueImport is for bulk imports.
+	QueueImport = "import"
+	// QueueMaintenance is for deletions and periodic cleanups.
+	QueueMaintenance = "maintenance"
+)
+
 var (
 	rdc          *redis.Client
 	protocol     string
 	eventManager superbus.EventManager
 	store        superbus.Store
+	pubsub       superbus.PubSub
 	taskManager  *superbus.TaskManager
 	readyFuncs   []func()
 )
@@ -43,20 +59,31 @@ func Load() error {
 		return err
 	}
 
+	options := []superbus.TaskManagerOption{}
+
 	switch dsn.Scheme {
 	case "memory":
 		eventManager = superbus.NewEagerEventManager()
 		store = superbus.NewMemStore()
+		pubsub = superbus.NewMemPubSub()
 	case "redis":
 		startRedis(dsn)
 		eventManager = superbus.NewRedisEventManager(rdc)
 		store = superbus.NewRedisStore(rdc, "readeck")
+		pubsub = superbus.NewRedisPubSub(rdc, "readeck")
+	case "db":
+		// Tasks are stored in the main database, we keep their
+		// payload long enough to resume them after a restart.
+		eventManager = superbus.NewDBEventManager(db.Q(), "bus_event")
+		store = superbus.NewDBStore(db.Q(), "bus_store")
+		pubsub = superbus.NewDBPubSub(db.Q(), "bus_message")
+		options = append(options, superbus.WithPayloadTTL(time.Hour*24))
 	default:
 		return fmt.Errorf("cannot load worker protocol %s", dsn.Scheme)
 	}
 	protocol = dsn.Scheme
 
-	initTaskManager()
+	initTaskManager(options...)
 	return nil
 }
 
@@ -65,14 +92,23 @@ func LoadCustom(p string, em superbus.EventManager, s superbus.Store) {
 	protocol = p
 	eventManager = em
 	store = s
+	pubsub = superbus.NewMemPubSub()
 	initTaskManager()
 }
 
-func initTaskManager() {
+func initTaskManager(options ...superbus.TaskManagerOption) {
 	taskManager = superbus.NewTaskManager(
 		eventManager, store,
-		superbus.WithOperationPrefix("tasks"),
-		superbus.WithNumWorkers(configs.Config.Extractor.NumWorkers),
+		append([]superbus.TaskManagerOption{
+			superbus.WithOperationPrefix("tasks"),
+			superbus.WithQueue(QueueInteractive, cmp.Or(
+				configs.Config.Worker.Queues.Interactive,
+				configs.Config.Extractor.NumWorkers,
+			), 20),
+			superbus.WithQueue(QueueMaintenance, configs.Config.Worker.Queues.Maintenance, 10),
+			superbus.WithQueue(QueueImport, configs.Config.Worker.Queues.Import, 0),
+			superbus.WithDeadLetter(saveDeadLetter),
+		}, options...)...,
 	)
 
 	for _, f := range readyFuncs {
@@ -100,6 +136,11 @@ func Store() superbus.Store {
 	return store
 }
 
+// PubSub returns the default message broadcaster.
+func PubSub() superbus.PubSub {
+	return pubsub
+}
+
 func startRedis(dsn *url.URL) {
 	if rdc != nil {
 		return
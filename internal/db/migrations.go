@@ -99,4 +99,23 @@ var migrationList = []migrationEntry{
 	newMigrationEntry(17, "user_uid", migrations.M17useruid),
 	newMigrationEntry(18, "auth_last_used", applyMigrationFile("18_auth_last_used.sql")),
 	newMigrationEntry(19, "bookmark_text_normalization", migrations.M19bookmarkTextNormalization),
+	newMigrationEntry(20, "bookmark_tombstone", applyMigrationFile("20_bookmark_tombstone.sql")),
+	newMigrationEntry(21, "bus", applyMigrationFile("21_bus.sql")),
+	newMigrationEntry(22, "task_dead_letter", applyMigrationFile("22_task_dead_letter.sql")),
+	newMigrationEntry(23, "bus_message", applyMigrationFile("23_bus_message.sql")),
+	newMigrationEntry(24, "webhook", applyMigrationFile("24_webhook.sql")),
+	newMigrationEntry(25, "trash", applyMigrationFile("25_trash.sql")),
+	newMigrationEntry(26, "bookmark_rule", applyMigrationFile("26_bookmark_rule.sql")),
+	newMigrationEntry(27, "feed", applyMigrationFile("27_feed.sql")),
+	newMigrationEntry(28, "inbound_address", applyMigrationFile("28_inbound_address.sql")),
+	newMigrationEntry(29, "bookmark_document", applyMigrationFile("29_bookmark_document.sql")),
+	newMigrationEntry(30, "user_identity", applyMigrationFile("30_user_identity.sql")),
+	newMigrationEntry(31, "user_totp", applyMigrationFile("31_user_totp.sql")),
+	newMigrationEntry(32, "oauth", applyMigrationFile("32_oauth.sql")),
+	newMigrationEntry(33, "user_session", applyMigrationFile("33_user_session.sql")),
+	newMigrationEntry(34, "token_restriction", applyMigrationFile("34_token_restriction.sql")),
+	newMigrationEntry(35, "user_group", applyMigrationFile("35_user_group.sql")),
+	newMigrationEntry(36, "user_invite", applyMigrationFile("36_user_invite.sql")),
+	newMigrationEntry(37, "token_user_seed", applyMigrationFile("37_token_user_seed.sql")),
+	newMigrationEntry(38, "user_previous_group", applyMigrationFile("38_user_previous_group.sql")),
 }
@@ -0,0 +1,16 @@
+-- SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+--
+-- SPDX-License-Identifier: AGPL-3.0-only
+
+CREATE TABLE IF NOT EXISTS bus_event (
+    id          SERIAL       PRIMARY KEY,
+    created     timestamptz  NOT NULL,
+    name        varchar(128) NOT NULL,
+    value       bytea        NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS bus_store (
+    key         varchar(256) PRIMARY KEY,
+    value       text         NOT NULL,
+    expires     bigint       NOT NULL DEFAULT 0
+);
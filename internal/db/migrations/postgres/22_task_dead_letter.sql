@@ -0,0 +1,14 @@
+-- SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+--
+-- SPDX-License-Identifier: AGPL-3.0-only
+
+CREATE TABLE IF NOT EXISTS task_dead_letter (
+    id          SERIAL       PRIMARY KEY,
+    uid         varchar(32)  UNIQUE NOT NULL,
+    created     timestamptz  NOT NULL,
+    name        varchar(128) NOT NULL,
+    op_id       text         NOT NULL,
+    attempts    integer      NOT NULL DEFAULT 0,
+    data        text         NOT NULL DEFAULT '',
+    error       text         NOT NULL DEFAULT ''
+);
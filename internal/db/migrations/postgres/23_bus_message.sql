@@ -0,0 +1,10 @@
+-- SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+--
+-- SPDX-License-Identifier: AGPL-3.0-only
+
+CREATE TABLE IF NOT EXISTS bus_message (
+    id          SERIAL       PRIMARY KEY,
+    created     timestamptz  NOT NULL,
+    channel     varchar(128) NOT NULL,
+    value       bytea        NOT NULL
+);
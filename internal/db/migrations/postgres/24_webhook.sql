@@ -0,0 +1,36 @@
+-- SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+--
+-- SPDX-License-Identifier: AGPL-3.0-only
+
+CREATE TABLE IF NOT EXISTS webhook (
+    id          SERIAL        PRIMARY KEY,
+    uid         varchar(32)   UNIQUE NOT NULL,
+    user_id     integer       NOT NULL,
+    created     timestamptz   NOT NULL,
+    updated     timestamptz   NOT NULL,
+    is_enabled  boolean       NOT NULL DEFAULT true,
+    name        varchar(128)  NOT NULL DEFAULT '',
+    url         text          NOT NULL,
+    secret      varchar(128)  NOT NULL,
+    events      jsonb         NOT NULL DEFAULT '[]',
+    base_url    text          NOT NULL DEFAULT '',
+
+    CONSTRAINT fk_webhook_user FOREIGN KEY (user_id) REFERENCES "user"(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS webhook_delivery (
+    id          SERIAL        PRIMARY KEY,
+    uid         varchar(32)   UNIQUE NOT NULL,
+    webhook_id  integer       NOT NULL,
+    created     timestamptz   NOT NULL,
+    updated     timestamptz   NOT NULL,
+    event       varchar(64)   NOT NULL,
+    payload     text          NOT NULL,
+    attempts    integer       NOT NULL DEFAULT 0,
+    status_code integer       NOT NULL DEFAULT 0,
+    error       text          NOT NULL DEFAULT '',
+
+    CONSTRAINT fk_webhook_delivery_webhook FOREIGN KEY (webhook_id) REFERENCES webhook(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS webhook_delivery_created_idx ON webhook_delivery(webhook_id, created);
@@ -0,0 +1,19 @@
+-- SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+--
+-- SPDX-License-Identifier: AGPL-3.0-only
+
+CREATE TABLE IF NOT EXISTS bookmark_rule (
+    id          SERIAL        PRIMARY KEY,
+    uid         varchar(32)   UNIQUE NOT NULL,
+    user_id     integer       NOT NULL,
+    created     timestamptz   NOT NULL,
+    updated     timestamptz   NOT NULL,
+    is_enabled  boolean       NOT NULL DEFAULT true,
+    name        varchar(128)  NOT NULL DEFAULT '',
+    query       text          NOT NULL,
+    actions     jsonb         NOT NULL DEFAULT '{}',
+
+    CONSTRAINT fk_bookmark_rule_user FOREIGN KEY (user_id) REFERENCES "user"(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS bookmark_rule_user_idx ON bookmark_rule(user_id, created);
@@ -0,0 +1,17 @@
+-- SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+--
+-- SPDX-License-Identifier: AGPL-3.0-only
+
+CREATE TABLE IF NOT EXISTS inbound_address (
+    id            SERIAL        PRIMARY KEY,
+    uid           varchar(32)   UNIQUE NOT NULL,
+    user_id       integer       NOT NULL,
+    created       timestamptz   NOT NULL,
+    updated       timestamptz   NOT NULL,
+    name          text          NOT NULL DEFAULT '',
+    token         varchar(64)   UNIQUE NOT NULL,
+    labels        jsonb         NOT NULL DEFAULT '[]',
+    last_received timestamptz   NULL,
+
+    CONSTRAINT fk_inbound_address_user FOREIGN KEY (user_id) REFERENCES "user"(id) ON DELETE CASCADE
+);
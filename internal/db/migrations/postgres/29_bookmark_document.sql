@@ -0,0 +1,18 @@
+-- SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+--
+-- SPDX-License-Identifier: AGPL-3.0-only
+
+CREATE TABLE IF NOT EXISTS bookmark_document (
+    id          SERIAL        PRIMARY KEY,
+    bookmark_id integer       NOT NULL,
+    created     timestamptz   NOT NULL,
+    hash        varchar(32)   NOT NULL,
+    device      text          NOT NULL DEFAULT '',
+    device_id   text          NOT NULL DEFAULT '',
+    synced      timestamptz   NULL,
+
+    CONSTRAINT fk_bookmark_document_bookmark FOREIGN KEY (bookmark_id) REFERENCES bookmark(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS bookmark_document_hash_idx ON bookmark_document(bookmark_id, hash);
+CREATE INDEX IF NOT EXISTS bookmark_document_lookup_idx ON bookmark_document(hash);
@@ -0,0 +1,20 @@
+-- SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+--
+-- SPDX-License-Identifier: AGPL-3.0-only
+
+CREATE TABLE IF NOT EXISTS oauth_client (
+    id               SERIAL        PRIMARY KEY,
+    uid              varchar(32)   UNIQUE NOT NULL,
+    created          timestamptz   NOT NULL,
+    name             varchar(128)  NOT NULL,
+    website          text          NOT NULL DEFAULT '',
+    redirect_uris    jsonb         NOT NULL DEFAULT '[]',
+    software_id      text          NOT NULL DEFAULT '',
+    software_version text          NOT NULL DEFAULT ''
+);
+
+ALTER TABLE token ADD COLUMN client_id integer NULL;
+ALTER TABLE token ADD COLUMN refresh_token text NULL;
+ALTER TABLE token ADD CONSTRAINT fk_token_client FOREIGN KEY (client_id) REFERENCES oauth_client(id) ON DELETE CASCADE;
+
+CREATE UNIQUE INDEX IF NOT EXISTS token_refresh_token_idx ON token(refresh_token);
@@ -0,0 +1,14 @@
+-- SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+--
+-- SPDX-License-Identifier: AGPL-3.0-only
+
+CREATE TABLE IF NOT EXISTS user_group (
+    id          SERIAL       PRIMARY KEY,
+    uid         varchar(32)  UNIQUE NOT NULL,
+    created     timestamptz  NOT NULL,
+    updated     timestamptz  NOT NULL,
+    name        varchar(64)  UNIQUE NOT NULL,
+    description text         NOT NULL DEFAULT '',
+    inherit     varchar(64)  NOT NULL DEFAULT '',
+    permissions jsonb        NOT NULL DEFAULT '[]'
+);
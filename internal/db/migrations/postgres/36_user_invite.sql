@@ -0,0 +1,14 @@
+-- SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+--
+-- SPDX-License-Identifier: AGPL-3.0-only
+
+CREATE TABLE IF NOT EXISTS user_invite (
+    id      SERIAL       PRIMARY KEY,
+    uid     varchar(32)  UNIQUE NOT NULL,
+    created timestamptz  NOT NULL,
+    expires timestamptz  NOT NULL,
+    email   varchar(128) NOT NULL DEFAULT '',
+    "group" varchar(64)  NOT NULL DEFAULT 'user'
+);
+
+CREATE INDEX IF NOT EXISTS user_invite_expires_idx ON user_invite(expires);
@@ -18,7 +18,19 @@ CREATE TABLE IF NOT EXISTS "user" (
     password varchar(256) NOT NULL,
     "group"  varchar(64)  NOT NULL DEFAULT 'user',
     settings jsonb        NOT NULL DEFAULT '{}',
-    seed     integer      NOT NULL DEFAULT 0
+    seed     integer      NOT NULL DEFAULT 0,
+    previous_group varchar(64) NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS oauth_client (
+    id               SERIAL        PRIMARY KEY,
+    uid              varchar(32)   UNIQUE NOT NULL,
+    created          timestamptz   NOT NULL,
+    name             varchar(128)  NOT NULL,
+    website          text          NOT NULL DEFAULT '',
+    redirect_uris    jsonb         NOT NULL DEFAULT '[]',
+    software_id      text          NOT NULL DEFAULT '',
+    software_version text          NOT NULL DEFAULT ''
 );
 
 CREATE TABLE IF NOT EXISTS token (
@@ -31,10 +43,17 @@ CREATE TABLE IF NOT EXISTS token (
     is_enabled  boolean       NOT NULL DEFAULT true,
     application varchar(128)  NOT NULL,
     roles       jsonb         NOT NULL DEFAULT '[]',
+    client_id   integer       NULL,
+    refresh_token text        NULL,
+    user_seed   integer       NULL,
+    restriction jsonb         NULL,
 
-    CONSTRAINT fk_token_user FOREIGN KEY (user_id) REFERENCES "user"(id) ON DELETE CASCADE
+    CONSTRAINT fk_token_user FOREIGN KEY (user_id) REFERENCES "user"(id) ON DELETE CASCADE,
+    CONSTRAINT fk_token_client FOREIGN KEY (client_id) REFERENCES oauth_client(id) ON DELETE CASCADE
 );
 
+CREATE UNIQUE INDEX IF NOT EXISTS token_refresh_token_idx ON token(refresh_token);
+
 CREATE TABLE IF NOT EXISTS "credential" (
     id         SERIAL       PRIMARY KEY,
     uid        varchar(32)  UNIQUE NOT NULL,
@@ -82,6 +101,7 @@ CREATE TABLE IF NOT EXISTS bookmark (
     read_anchor   text        NOT NULL DEFAULT '',
     annotations   jsonb       NOT NULL DEFAULT '[]',
     links         jsonb       NOT NULL DEFAULT '[]',
+    deleted       timestamptz,
 
     CONSTRAINT fk_bookmark_user FOREIGN KEY (user_id) REFERENCES "user"(id) ON DELETE CASCADE
   );
@@ -90,6 +110,7 @@ CREATE INDEX bookmark_created_idx ON "bookmark" USING btree (created DESC);
 CREATE INDEX bookmark_updated_idx ON "bookmark" USING btree (updated DESC);
 CREATE INDEX bookmark_url_idx ON "bookmark" (url);
 CREATE INDEX bookmark_initial_url_idx ON "bookmark" (initial_url);
+CREATE INDEX bookmark_deleted_idx ON "bookmark" (deleted);
 
 --
 -- Search configuration
@@ -161,6 +182,240 @@ CREATE TABLE IF NOT EXISTS bookmark_collection (
     name        text        NOT NULL,
     is_pinned   boolean     NOT NULL DEFAULT false,
     filters     json        NOT NULL DEFAULT '{}',
+    deleted     timestamptz,
 
     CONSTRAINT fk_bookmark_collection_user FOREIGN KEY (user_id) REFERENCES "user"(id) ON DELETE CASCADE
 );
+
+CREATE TABLE IF NOT EXISTS bookmark_tombstone (
+    id          SERIAL      PRIMARY KEY,
+    user_id     integer     NOT NULL,
+    deleted     timestamptz NOT NULL,
+    kind        varchar(32) NOT NULL,
+    uid         text        NOT NULL,
+    parent_uid  text        NOT NULL DEFAULT '',
+
+    CONSTRAINT fk_bookmark_tombstone_user FOREIGN KEY (user_id) REFERENCES "user"(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS bookmark_tombstone_deleted_idx ON bookmark_tombstone(user_id, deleted);
+
+CREATE TABLE IF NOT EXISTS bus_event (
+    id          SERIAL       PRIMARY KEY,
+    created     timestamptz  NOT NULL,
+    name        varchar(128) NOT NULL,
+    value       bytea        NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS bus_store (
+    key         varchar(256) PRIMARY KEY,
+    value       text         NOT NULL,
+    expires     bigint       NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS task_dead_letter (
+    id          SERIAL       PRIMARY KEY,
+    uid         varchar(32)  UNIQUE NOT NULL,
+    created     timestamptz  NOT NULL,
+    name        varchar(128) NOT NULL,
+    op_id       text         NOT NULL,
+    attempts    integer      NOT NULL DEFAULT 0,
+    data        text         NOT NULL DEFAULT '',
+    error       text         NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS bus_message (
+    id          SERIAL       PRIMARY KEY,
+    created     timestamptz  NOT NULL,
+    channel     varchar(128) NOT NULL,
+    value       bytea        NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS webhook (
+    id          SERIAL        PRIMARY KEY,
+    uid         varchar(32)   UNIQUE NOT NULL,
+    user_id     integer       NOT NULL,
+    created     timestamptz   NOT NULL,
+    updated     timestamptz   NOT NULL,
+    is_enabled  boolean       NOT NULL DEFAULT true,
+    name        varchar(128)  NOT NULL DEFAULT '',
+    url         text          NOT NULL,
+    secret      varchar(128)  NOT NULL,
+    events      jsonb         NOT NULL DEFAULT '[]',
+    base_url    text          NOT NULL DEFAULT '',
+
+    CONSTRAINT fk_webhook_user FOREIGN KEY (user_id) REFERENCES "user"(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS webhook_delivery (
+    id          SERIAL        PRIMARY KEY,
+    uid         varchar(32)   UNIQUE NOT NULL,
+    webhook_id  integer       NOT NULL,
+    created     timestamptz   NOT NULL,
+    updated     timestamptz   NOT NULL,
+    event       varchar(64)   NOT NULL,
+    payload     text          NOT NULL,
+    attempts    integer       NOT NULL DEFAULT 0,
+    status_code integer       NOT NULL DEFAULT 0,
+    error       text          NOT NULL DEFAULT '',
+
+    CONSTRAINT fk_webhook_delivery_webhook FOREIGN KEY (webhook_id) REFERENCES webhook(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS webhook_delivery_created_idx ON webhook_delivery(webhook_id, created);
+
+CREATE TABLE IF NOT EXISTS bookmark_annotation_trash (
+    id          SERIAL      PRIMARY KEY,
+    user_id     integer     NOT NULL,
+    bookmark_id integer     NOT NULL,
+    deleted     timestamptz NOT NULL,
+    annotation  jsonb       NOT NULL,
+
+    CONSTRAINT fk_bookmark_annotation_trash_user FOREIGN KEY (user_id) REFERENCES "user"(id) ON DELETE CASCADE,
+    CONSTRAINT fk_bookmark_annotation_trash_bookmark FOREIGN KEY (bookmark_id) REFERENCES bookmark(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS bookmark_annotation_trash_deleted_idx ON bookmark_annotation_trash(user_id, deleted);
+
+CREATE TABLE IF NOT EXISTS bookmark_rule (
+    id          SERIAL        PRIMARY KEY,
+    uid         varchar(32)   UNIQUE NOT NULL,
+    user_id     integer       NOT NULL,
+    created     timestamptz   NOT NULL,
+    updated     timestamptz   NOT NULL,
+    is_enabled  boolean       NOT NULL DEFAULT true,
+    name        varchar(128)  NOT NULL DEFAULT '',
+    query       text          NOT NULL,
+    actions     jsonb         NOT NULL DEFAULT '{}',
+
+    CONSTRAINT fk_bookmark_rule_user FOREIGN KEY (user_id) REFERENCES "user"(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS bookmark_rule_user_idx ON bookmark_rule(user_id, created);
+
+CREATE TABLE IF NOT EXISTS feed (
+    id            SERIAL        PRIMARY KEY,
+    uid           varchar(32)   UNIQUE NOT NULL,
+    user_id       integer       NOT NULL,
+    created       timestamptz   NOT NULL,
+    updated       timestamptz   NOT NULL,
+    is_enabled    boolean       NOT NULL DEFAULT true,
+    url           text          NOT NULL,
+    title         text          NOT NULL DEFAULT '',
+    site_url      text          NOT NULL DEFAULT '',
+    labels        jsonb         NOT NULL DEFAULT '[]',
+    include_terms jsonb         NOT NULL DEFAULT '[]',
+    exclude_terms jsonb         NOT NULL DEFAULT '[]',
+    max_entries   integer       NOT NULL DEFAULT 10,
+    last_fetched  timestamptz   NULL,
+    last_error    text          NOT NULL DEFAULT '',
+    etag          text          NOT NULL DEFAULT '',
+    last_modified text          NOT NULL DEFAULT '',
+
+    CONSTRAINT fk_feed_user FOREIGN KEY (user_id) REFERENCES "user"(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS feed_user_url_idx ON feed(user_id, url);
+
+CREATE TABLE IF NOT EXISTS feed_entry (
+    id          SERIAL        PRIMARY KEY,
+    feed_id     integer       NOT NULL,
+    created     timestamptz   NOT NULL,
+    guid        text          NOT NULL,
+
+    CONSTRAINT fk_feed_entry_feed FOREIGN KEY (feed_id) REFERENCES feed(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS feed_entry_guid_idx ON feed_entry(feed_id, guid);
+
+CREATE TABLE IF NOT EXISTS inbound_address (
+    id            SERIAL        PRIMARY KEY,
+    uid           varchar(32)   UNIQUE NOT NULL,
+    user_id       integer       NOT NULL,
+    created       timestamptz   NOT NULL,
+    updated       timestamptz   NOT NULL,
+    name          text          NOT NULL DEFAULT '',
+    token         varchar(64)   UNIQUE NOT NULL,
+    labels        jsonb         NOT NULL DEFAULT '[]',
+    last_received timestamptz   NULL,
+
+    CONSTRAINT fk_inbound_address_user FOREIGN KEY (user_id) REFERENCES "user"(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS bookmark_document (
+    id          SERIAL        PRIMARY KEY,
+    bookmark_id integer       NOT NULL,
+    created     timestamptz   NOT NULL,
+    hash        varchar(32)   NOT NULL,
+    device      text          NOT NULL DEFAULT '',
+    device_id   text          NOT NULL DEFAULT '',
+    synced      timestamptz   NULL,
+
+    CONSTRAINT fk_bookmark_document_bookmark FOREIGN KEY (bookmark_id) REFERENCES bookmark(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS bookmark_document_hash_idx ON bookmark_document(bookmark_id, hash);
+CREATE INDEX IF NOT EXISTS bookmark_document_lookup_idx ON bookmark_document(hash);
+
+CREATE TABLE IF NOT EXISTS user_identity (
+    id        SERIAL        PRIMARY KEY,
+    user_id   integer       NOT NULL,
+    created   timestamptz   NOT NULL,
+    last_used timestamptz   NULL,
+    provider  text          NOT NULL,
+    subject   text          NOT NULL,
+
+    CONSTRAINT fk_user_identity_user FOREIGN KEY (user_id) REFERENCES "user"(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS user_identity_subject_idx ON user_identity(provider, subject);
+
+CREATE TABLE IF NOT EXISTS user_totp (
+    id             SERIAL        PRIMARY KEY,
+    user_id        integer       NOT NULL,
+    created        timestamptz   NOT NULL,
+    enabled        timestamptz   NULL,
+    secret         text          NOT NULL,
+    last_step      bigint        NOT NULL DEFAULT 0,
+    recovery_codes jsonb         NOT NULL DEFAULT '[]',
+
+    CONSTRAINT fk_user_totp_user FOREIGN KEY (user_id) REFERENCES "user"(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS user_totp_user_idx ON user_totp(user_id);
+
+CREATE TABLE IF NOT EXISTS user_session (
+    id         SERIAL       PRIMARY KEY,
+    uid        varchar(32)  UNIQUE NOT NULL,
+    user_id    integer      NOT NULL,
+    created    timestamptz  NOT NULL,
+    last_seen  timestamptz  NOT NULL,
+    user_agent text         NOT NULL DEFAULT '',
+    ip_address text         NOT NULL DEFAULT '',
+
+    CONSTRAINT fk_user_session_user FOREIGN KEY (user_id) REFERENCES "user"(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS user_session_user_idx ON user_session(user_id);
+
+CREATE TABLE IF NOT EXISTS user_group (
+    id          SERIAL       PRIMARY KEY,
+    uid         varchar(32)  UNIQUE NOT NULL,
+    created     timestamptz  NOT NULL,
+    updated     timestamptz  NOT NULL,
+    name        varchar(64)  UNIQUE NOT NULL,
+    description text         NOT NULL DEFAULT '',
+    inherit     varchar(64)  NOT NULL DEFAULT '',
+    permissions jsonb        NOT NULL DEFAULT '[]'
+);
+
+CREATE TABLE IF NOT EXISTS user_invite (
+    id      SERIAL       PRIMARY KEY,
+    uid     varchar(32)  UNIQUE NOT NULL,
+    created timestamptz  NOT NULL,
+    expires timestamptz  NOT NULL,
+    email   varchar(128) NOT NULL DEFAULT '',
+    "group" varchar(64)  NOT NULL DEFAULT 'user'
+);
+
+CREATE INDEX IF NOT EXISTS user_invite_expires_idx ON user_invite(expires);
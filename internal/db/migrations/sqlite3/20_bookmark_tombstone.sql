@@ -0,0 +1,16 @@
+-- SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+--
+-- SPDX-License-Identifier: AGPL-3.0-only
+
+CREATE TABLE IF NOT EXISTS bookmark_tombstone (
+    id          integer  PRIMARY KEY AUTOINCREMENT,
+    user_id     integer  NOT NULL,
+    deleted     datetime NOT NULL,
+    kind        text     NOT NULL,
+    uid         text     NOT NULL,
+    parent_uid  text     NOT NULL DEFAULT "",
+
+    CONSTRAINT fk_bookmark_tombstone_user FOREIGN KEY (user_id) REFERENCES user(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS bookmark_tombstone_deleted_idx ON bookmark_tombstone(user_id, deleted);
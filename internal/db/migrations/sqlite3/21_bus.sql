@@ -0,0 +1,16 @@
+-- SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+--
+-- SPDX-License-Identifier: AGPL-3.0-only
+
+CREATE TABLE IF NOT EXISTS bus_event (
+    id          integer  PRIMARY KEY AUTOINCREMENT,
+    created     datetime NOT NULL,
+    name        text     NOT NULL,
+    value       blob     NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS bus_store (
+    key         text     PRIMARY KEY,
+    value       text     NOT NULL,
+    expires     integer  NOT NULL DEFAULT 0
+);
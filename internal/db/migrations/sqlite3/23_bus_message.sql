@@ -0,0 +1,10 @@
+-- SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+--
+-- SPDX-License-Identifier: AGPL-3.0-only
+
+CREATE TABLE IF NOT EXISTS bus_message (
+    id          integer  PRIMARY KEY AUTOINCREMENT,
+    created     datetime NOT NULL,
+    channel     text     NOT NULL,
+    value       blob     NOT NULL
+);
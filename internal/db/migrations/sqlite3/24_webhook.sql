@@ -0,0 +1,36 @@
+-- SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+--
+-- SPDX-License-Identifier: AGPL-3.0-only
+
+CREATE TABLE IF NOT EXISTS webhook (
+    id          integer  PRIMARY KEY AUTOINCREMENT,
+    uid         text     UNIQUE NOT NULL,
+    user_id     integer  NOT NULL,
+    created     datetime NOT NULL,
+    updated     datetime NOT NULL,
+    is_enabled  integer  NOT NULL DEFAULT 1,
+    name        text     NOT NULL DEFAULT "",
+    url         text     NOT NULL,
+    secret      text     NOT NULL,
+    events      json     NOT NULL DEFAULT "[]",
+    base_url    text     NOT NULL DEFAULT "",
+
+    CONSTRAINT fk_webhook_user FOREIGN KEY (user_id) REFERENCES user(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS webhook_delivery (
+    id          integer  PRIMARY KEY AUTOINCREMENT,
+    uid         text     UNIQUE NOT NULL,
+    webhook_id  integer  NOT NULL,
+    created     datetime NOT NULL,
+    updated     datetime NOT NULL,
+    event       text     NOT NULL,
+    payload     text     NOT NULL,
+    attempts    integer  NOT NULL DEFAULT 0,
+    status_code integer  NOT NULL DEFAULT 0,
+    error       text     NOT NULL DEFAULT "",
+
+    CONSTRAINT fk_webhook_delivery_webhook FOREIGN KEY (webhook_id) REFERENCES webhook(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS webhook_delivery_created_idx ON webhook_delivery(webhook_id, created);
@@ -0,0 +1,21 @@
+-- SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+--
+-- SPDX-License-Identifier: AGPL-3.0-only
+
+ALTER TABLE "bookmark" ADD COLUMN deleted datetime;
+ALTER TABLE "bookmark_collection" ADD COLUMN deleted datetime;
+
+CREATE INDEX IF NOT EXISTS bookmark_deleted_idx ON "bookmark" (deleted);
+
+CREATE TABLE IF NOT EXISTS bookmark_annotation_trash (
+    id          integer  PRIMARY KEY AUTOINCREMENT,
+    user_id     integer  NOT NULL,
+    bookmark_id integer  NOT NULL,
+    deleted     datetime NOT NULL,
+    annotation  json     NOT NULL,
+
+    CONSTRAINT fk_bookmark_annotation_trash_user FOREIGN KEY (user_id) REFERENCES user(id) ON DELETE CASCADE,
+    CONSTRAINT fk_bookmark_annotation_trash_bookmark FOREIGN KEY (bookmark_id) REFERENCES bookmark(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS bookmark_annotation_trash_deleted_idx ON bookmark_annotation_trash(user_id, deleted);
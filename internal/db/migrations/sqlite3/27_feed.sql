@@ -0,0 +1,38 @@
+-- SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+--
+-- SPDX-License-Identifier: AGPL-3.0-only
+
+CREATE TABLE IF NOT EXISTS feed (
+    id            integer  PRIMARY KEY AUTOINCREMENT,
+    uid           text     UNIQUE NOT NULL,
+    user_id       integer  NOT NULL,
+    created       datetime NOT NULL,
+    updated       datetime NOT NULL,
+    is_enabled    integer  NOT NULL DEFAULT 1,
+    url           text     NOT NULL,
+    title         text     NOT NULL DEFAULT "",
+    site_url      text     NOT NULL DEFAULT "",
+    labels        json     NOT NULL DEFAULT "[]",
+    include_terms json     NOT NULL DEFAULT "[]",
+    exclude_terms json     NOT NULL DEFAULT "[]",
+    max_entries   integer  NOT NULL DEFAULT 10,
+    last_fetched  datetime NULL,
+    last_error    text     NOT NULL DEFAULT "",
+    etag          text     NOT NULL DEFAULT "",
+    last_modified text     NOT NULL DEFAULT "",
+
+    CONSTRAINT fk_feed_user FOREIGN KEY (user_id) REFERENCES user(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS feed_user_url_idx ON feed(user_id, url);
+
+CREATE TABLE IF NOT EXISTS feed_entry (
+    id          integer  PRIMARY KEY AUTOINCREMENT,
+    feed_id     integer  NOT NULL,
+    created     datetime NOT NULL,
+    guid        text     NOT NULL,
+
+    CONSTRAINT fk_feed_entry_feed FOREIGN KEY (feed_id) REFERENCES feed(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS feed_entry_guid_idx ON feed_entry(feed_id, guid);
@@ -0,0 +1,17 @@
+-- SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+--
+-- SPDX-License-Identifier: AGPL-3.0-only
+
+CREATE TABLE IF NOT EXISTS inbound_address (
+    id            integer  PRIMARY KEY AUTOINCREMENT,
+    uid           text     UNIQUE NOT NULL,
+    user_id       integer  NOT NULL,
+    created       datetime NOT NULL,
+    updated       datetime NOT NULL,
+    name          text     NOT NULL DEFAULT "",
+    token         text     UNIQUE NOT NULL,
+    labels        json     NOT NULL DEFAULT "[]",
+    last_received datetime NULL,
+
+    CONSTRAINT fk_inbound_address_user FOREIGN KEY (user_id) REFERENCES user(id) ON DELETE CASCADE
+);
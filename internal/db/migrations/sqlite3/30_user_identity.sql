@@ -0,0 +1,16 @@
+-- SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+--
+-- SPDX-License-Identifier: AGPL-3.0-only
+
+CREATE TABLE IF NOT EXISTS user_identity (
+    id        integer  PRIMARY KEY AUTOINCREMENT,
+    user_id   integer  NOT NULL,
+    created   datetime NOT NULL,
+    last_used datetime NULL,
+    provider  text     NOT NULL,
+    subject   text     NOT NULL,
+
+    CONSTRAINT fk_user_identity_user FOREIGN KEY (user_id) REFERENCES user(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS user_identity_subject_idx ON user_identity(provider, subject);
@@ -0,0 +1,17 @@
+-- SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+--
+-- SPDX-License-Identifier: AGPL-3.0-only
+
+CREATE TABLE IF NOT EXISTS user_totp (
+    id             integer  PRIMARY KEY AUTOINCREMENT,
+    user_id        integer  NOT NULL,
+    created        datetime NOT NULL,
+    enabled        datetime NULL,
+    secret         text     NOT NULL,
+    last_step      integer  NOT NULL DEFAULT 0,
+    recovery_codes json     NOT NULL DEFAULT "[]",
+
+    CONSTRAINT fk_user_totp_user FOREIGN KEY (user_id) REFERENCES user(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS user_totp_user_idx ON user_totp(user_id);
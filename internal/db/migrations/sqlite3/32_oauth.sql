@@ -0,0 +1,19 @@
+-- SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+--
+-- SPDX-License-Identifier: AGPL-3.0-only
+
+CREATE TABLE IF NOT EXISTS oauth_client (
+    id               integer  PRIMARY KEY AUTOINCREMENT,
+    uid              text     UNIQUE NOT NULL,
+    created          datetime NOT NULL,
+    name             text     NOT NULL,
+    website          text     NOT NULL DEFAULT "",
+    redirect_uris    json     NOT NULL DEFAULT "[]",
+    software_id      text     NOT NULL DEFAULT "",
+    software_version text     NOT NULL DEFAULT ""
+);
+
+ALTER TABLE token ADD COLUMN client_id integer NULL REFERENCES oauth_client(id) ON DELETE CASCADE;
+ALTER TABLE token ADD COLUMN refresh_token text NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS token_refresh_token_idx ON token(refresh_token);
@@ -0,0 +1,17 @@
+-- SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+--
+-- SPDX-License-Identifier: AGPL-3.0-only
+
+CREATE TABLE IF NOT EXISTS user_session (
+    id         integer  PRIMARY KEY AUTOINCREMENT,
+    uid        text     UNIQUE NOT NULL,
+    user_id    integer  NOT NULL,
+    created    datetime NOT NULL,
+    last_seen  datetime NOT NULL,
+    user_agent text     NOT NULL DEFAULT "",
+    ip_address text     NOT NULL DEFAULT "",
+
+    CONSTRAINT fk_user_session_user FOREIGN KEY (user_id) REFERENCES user(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS user_session_user_idx ON user_session(user_id);
@@ -0,0 +1,14 @@
+-- SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+--
+-- SPDX-License-Identifier: AGPL-3.0-only
+
+CREATE TABLE IF NOT EXISTS user_group (
+    id          integer  PRIMARY KEY AUTOINCREMENT,
+    uid         text     UNIQUE NOT NULL,
+    created     datetime NOT NULL,
+    updated     datetime NOT NULL,
+    name        text     UNIQUE NOT NULL,
+    description text     NOT NULL DEFAULT "",
+    inherit     text     NOT NULL DEFAULT "",
+    permissions json     NOT NULL DEFAULT "[]"
+);
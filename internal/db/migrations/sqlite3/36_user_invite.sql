@@ -0,0 +1,14 @@
+-- SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+--
+-- SPDX-License-Identifier: AGPL-3.0-only
+
+CREATE TABLE IF NOT EXISTS user_invite (
+    id      integer  PRIMARY KEY AUTOINCREMENT,
+    uid     text     UNIQUE NOT NULL,
+    created datetime NOT NULL,
+    expires datetime NOT NULL,
+    email   text     NOT NULL DEFAULT "",
+    `group` text     NOT NULL DEFAULT "user"
+);
+
+CREATE INDEX IF NOT EXISTS user_invite_expires_idx ON user_invite(expires);
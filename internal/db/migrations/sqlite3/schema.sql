@@ -18,7 +18,19 @@ CREATE TABLE IF NOT EXISTS user (
     password text     NOT NULL,
     `group`  text     NOT NULL DEFAULT "user",
     settings json     NOT NULL DEFAULT "{}",
-    seed     integer  NOT NULL DEFAULT 0
+    seed     integer  NOT NULL DEFAULT 0,
+    previous_group text NOT NULL DEFAULT ""
+);
+
+CREATE TABLE IF NOT EXISTS oauth_client (
+    id               integer  PRIMARY KEY AUTOINCREMENT,
+    uid              text     UNIQUE NOT NULL,
+    created          datetime NOT NULL,
+    name             text     NOT NULL,
+    website          text     NOT NULL DEFAULT "",
+    redirect_uris    json     NOT NULL DEFAULT "[]",
+    software_id      text     NOT NULL DEFAULT "",
+    software_version text     NOT NULL DEFAULT ""
 );
 
 CREATE TABLE IF NOT EXISTS token (
@@ -31,10 +43,17 @@ CREATE TABLE IF NOT EXISTS token (
     is_enabled  integer  NOT NULL DEFAULT 1,
     application text     NOT NULL,
     roles       json     NOT NULL DEFAULT "",
+    client_id   integer  NULL,
+    refresh_token text   NULL,
+    user_seed   integer  NULL,
+    restriction json     NULL,
 
-    CONSTRAINT fk_token_user FOREIGN KEY (user_id) REFERENCES user(id) ON DELETE CASCADE
+    CONSTRAINT fk_token_user FOREIGN KEY (user_id) REFERENCES user(id) ON DELETE CASCADE,
+    CONSTRAINT fk_token_client FOREIGN KEY (client_id) REFERENCES oauth_client(id) ON DELETE CASCADE
 );
 
+CREATE UNIQUE INDEX IF NOT EXISTS token_refresh_token_idx ON token(refresh_token);
+
 CREATE TABLE IF NOT EXISTS credential (
     id          integer  PRIMARY KEY AUTOINCREMENT,
     uid         text     UNIQUE NOT NULL,
@@ -82,6 +101,7 @@ CREATE TABLE IF NOT EXISTS bookmark (
     read_anchor   text     NOT NULL DEFAULT "",
     annotations   json     NOT NULL DEFAULT "",
     links         json     NOT NULL DEFAULT "",
+    deleted       datetime,
 
     CONSTRAINT fk_bookmark_user FOREIGN KEY (user_id) REFERENCES user(id) ON DELETE CASCADE
 );
@@ -90,6 +110,7 @@ CREATE INDEX bookmark_created_idx ON "bookmark" (created DESC);
 CREATE INDEX bookmark_updated_idx ON "bookmark" (updated DESC);
 CREATE INDEX bookmark_url_idx ON "bookmark" (url);
 CREATE INDEX bookmark_initial_url_idx ON "bookmark" (initial_url);
+CREATE INDEX bookmark_deleted_idx ON "bookmark" (deleted);
 
 CREATE VIRTUAL TABLE IF NOT EXISTS bookmark_idx USING fts5(
     tokenize='unicode61 remove_diacritics 2',
@@ -147,6 +168,240 @@ CREATE TABLE IF NOT EXISTS bookmark_collection (
     name        text     NOT NULL,
     is_pinned   integer  NOT NULL DEFAULT 0,
     filters     json     NOT NULL DEFAULT "{}",
+    deleted     datetime,
 
     CONSTRAINT fk_bookmark_collection_user FOREIGN KEY (user_id) REFERENCES user(id) ON DELETE CASCADE
 );
+
+CREATE TABLE IF NOT EXISTS bookmark_tombstone (
+    id          integer  PRIMARY KEY AUTOINCREMENT,
+    user_id     integer  NOT NULL,
+    deleted     datetime NOT NULL,
+    kind        text     NOT NULL,
+    uid         text     NOT NULL,
+    parent_uid  text     NOT NULL DEFAULT "",
+
+    CONSTRAINT fk_bookmark_tombstone_user FOREIGN KEY (user_id) REFERENCES user(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS bookmark_tombstone_deleted_idx ON bookmark_tombstone(user_id, deleted);
+
+CREATE TABLE IF NOT EXISTS bus_event (
+    id          integer  PRIMARY KEY AUTOINCREMENT,
+    created     datetime NOT NULL,
+    name        text     NOT NULL,
+    value       blob     NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS bus_store (
+    key         text     PRIMARY KEY,
+    value       text     NOT NULL,
+    expires     integer  NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS task_dead_letter (
+    id          integer  PRIMARY KEY AUTOINCREMENT,
+    uid         text     UNIQUE NOT NULL,
+    created     datetime NOT NULL,
+    name        text     NOT NULL,
+    op_id       text     NOT NULL,
+    attempts    integer  NOT NULL DEFAULT 0,
+    data        text     NOT NULL DEFAULT "",
+    error       text     NOT NULL DEFAULT ""
+);
+
+CREATE TABLE IF NOT EXISTS bus_message (
+    id          integer  PRIMARY KEY AUTOINCREMENT,
+    created     datetime NOT NULL,
+    channel     text     NOT NULL,
+    value       blob     NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS webhook (
+    id          integer  PRIMARY KEY AUTOINCREMENT,
+    uid         text     UNIQUE NOT NULL,
+    user_id     integer  NOT NULL,
+    created     datetime NOT NULL,
+    updated     datetime NOT NULL,
+    is_enabled  integer  NOT NULL DEFAULT 1,
+    name        text     NOT NULL DEFAULT "",
+    url         text     NOT NULL,
+    secret      text     NOT NULL,
+    events      json     NOT NULL DEFAULT "[]",
+    base_url    text     NOT NULL DEFAULT "",
+
+    CONSTRAINT fk_webhook_user FOREIGN KEY (user_id) REFERENCES user(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS webhook_delivery (
+    id          integer  PRIMARY KEY AUTOINCREMENT,
+    uid         text     UNIQUE NOT NULL,
+    webhook_id  integer  NOT NULL,
+    created     datetime NOT NULL,
+    updated     datetime NOT NULL,
+    event       text     NOT NULL,
+    payload     text     NOT NULL,
+    attempts    integer  NOT NULL DEFAULT 0,
+    status_code integer  NOT NULL DEFAULT 0,
+    error       text     NOT NULL DEFAULT "",
+
+    CONSTRAINT fk_webhook_delivery_webhook FOREIGN KEY (webhook_id) REFERENCES webhook(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS webhook_delivery_created_idx ON webhook_delivery(webhook_id, created);
+
+CREATE TABLE IF NOT EXISTS bookmark_annotation_trash (
+    id          integer  PRIMARY KEY AUTOINCREMENT,
+    user_id     integer  NOT NULL,
+    bookmark_id integer  NOT NULL,
+    deleted     datetime NOT NULL,
+    annotation  json     NOT NULL,
+
+    CONSTRAINT fk_bookmark_annotation_trash_user FOREIGN KEY (user_id) REFERENCES user(id) ON DELETE CASCADE,
+    CONSTRAINT fk_bookmark_annotation_trash_bookmark FOREIGN KEY (bookmark_id) REFERENCES bookmark(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS bookmark_annotation_trash_deleted_idx ON bookmark_annotation_trash(user_id, deleted);
+
+CREATE TABLE IF NOT EXISTS bookmark_rule (
+    id          integer  PRIMARY KEY AUTOINCREMENT,
+    uid         text     UNIQUE NOT NULL,
+    user_id     integer  NOT NULL,
+    created     datetime NOT NULL,
+    updated     datetime NOT NULL,
+    is_enabled  integer  NOT NULL DEFAULT 1,
+    name        text     NOT NULL DEFAULT "",
+    query       text     NOT NULL,
+    actions     json     NOT NULL DEFAULT "{}",
+
+    CONSTRAINT fk_bookmark_rule_user FOREIGN KEY (user_id) REFERENCES user(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS bookmark_rule_user_idx ON bookmark_rule(user_id, created);
+
+CREATE TABLE IF NOT EXISTS feed (
+    id            integer  PRIMARY KEY AUTOINCREMENT,
+    uid           text     UNIQUE NOT NULL,
+    user_id       integer  NOT NULL,
+    created       datetime NOT NULL,
+    updated       datetime NOT NULL,
+    is_enabled    integer  NOT NULL DEFAULT 1,
+    url           text     NOT NULL,
+    title         text     NOT NULL DEFAULT "",
+    site_url      text     NOT NULL DEFAULT "",
+    labels        json     NOT NULL DEFAULT "[]",
+    include_terms json     NOT NULL DEFAULT "[]",
+    exclude_terms json     NOT NULL DEFAULT "[]",
+    max_entries   integer  NOT NULL DEFAULT 10,
+    last_fetched  datetime NULL,
+    last_error    text     NOT NULL DEFAULT "",
+    etag          text     NOT NULL DEFAULT "",
+    last_modified text     NOT NULL DEFAULT "",
+
+    CONSTRAINT fk_feed_user FOREIGN KEY (user_id) REFERENCES user(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS feed_user_url_idx ON feed(user_id, url);
+
+CREATE TABLE IF NOT EXISTS feed_entry (
+    id          integer  PRIMARY KEY AUTOINCREMENT,
+    feed_id     integer  NOT NULL,
+    created     datetime NOT NULL,
+    guid        text     NOT NULL,
+
+    CONSTRAINT fk_feed_entry_feed FOREIGN KEY (feed_id) REFERENCES feed(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS feed_entry_guid_idx ON feed_entry(feed_id, guid);
+
+CREATE TABLE IF NOT EXISTS inbound_address (
+    id            integer  PRIMARY KEY AUTOINCREMENT,
+    uid           text     UNIQUE NOT NULL,
+    user_id       integer  NOT NULL,
+    created       datetime NOT NULL,
+    updated       datetime NOT NULL,
+    name          text     NOT NULL DEFAULT "",
+    token         text     UNIQUE NOT NULL,
+    labels        json     NOT NULL DEFAULT "[]",
+    last_received datetime NULL,
+
+    CONSTRAINT fk_inbound_address_user FOREIGN KEY (user_id) REFERENCES user(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS bookmark_document (
+    id          integer  PRIMARY KEY AUTOINCREMENT,
+    bookmark_id integer  NOT NULL,
+    created     datetime NOT NULL,
+    hash        text     NOT NULL,
+    device      text     NOT NULL DEFAULT "",
+    device_id   text     NOT NULL DEFAULT "",
+    synced      datetime NULL,
+
+    CONSTRAINT fk_bookmark_document_bookmark FOREIGN KEY (bookmark_id) REFERENCES bookmark(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS bookmark_document_hash_idx ON bookmark_document(bookmark_id, hash);
+CREATE INDEX IF NOT EXISTS bookmark_document_lookup_idx ON bookmark_document(hash);
+
+CREATE TABLE IF NOT EXISTS user_identity (
+    id        integer  PRIMARY KEY AUTOINCREMENT,
+    user_id   integer  NOT NULL,
+    created   datetime NOT NULL,
+    last_used datetime NULL,
+    provider  text     NOT NULL,
+    subject   text     NOT NULL,
+
+    CONSTRAINT fk_user_identity_user FOREIGN KEY (user_id) REFERENCES user(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS user_identity_subject_idx ON user_identity(provider, subject);
+
+CREATE TABLE IF NOT EXISTS user_totp (
+    id             integer  PRIMARY KEY AUTOINCREMENT,
+    user_id        integer  NOT NULL,
+    created        datetime NOT NULL,
+    enabled        datetime NULL,
+    secret         text     NOT NULL,
+    last_step      integer  NOT NULL DEFAULT 0,
+    recovery_codes json     NOT NULL DEFAULT "[]",
+
+    CONSTRAINT fk_user_totp_user FOREIGN KEY (user_id) REFERENCES user(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS user_totp_user_idx ON user_totp(user_id);
+
+CREATE TABLE IF NOT EXISTS user_session (
+    id         integer  PRIMARY KEY AUTOINCREMENT,
+    uid        text     UNIQUE NOT NULL,
+    user_id    integer  NOT NULL,
+    created    datetime NOT NULL,
+    last_seen  datetime NOT NULL,
+    user_agent text     NOT NULL DEFAULT "",
+    ip_address text     NOT NULL DEFAULT "",
+
+    CONSTRAINT fk_user_session_user FOREIGN KEY (user_id) REFERENCES user(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS user_session_user_idx ON user_session(user_id);
+
+CREATE TABLE IF NOT EXISTS user_group (
+    id          integer  PRIMARY KEY AUTOINCREMENT,
+    uid         text     UNIQUE NOT NULL,
+    created     datetime NOT NULL,
+    updated     datetime NOT NULL,
+    name        text     UNIQUE NOT NULL,
+    description text     NOT NULL DEFAULT "",
+    inherit     text     NOT NULL DEFAULT "",
+    permissions json     NOT NULL DEFAULT "[]"
+);
+
+CREATE TABLE IF NOT EXISTS user_invite (
+    id      integer  PRIMARY KEY AUTOINCREMENT,
+    uid     text     UNIQUE NOT NULL,
+    created datetime NOT NULL,
+    expires datetime NOT NULL,
+    email   text     NOT NULL DEFAULT "",
+    `group` text     NOT NULL DEFAULT "user"
+);
+
+CREATE INDEX IF NOT EXISTS user_invite_expires_idx ON user_invite(expires);
@@ -0,0 +1,255 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+// Package feeds contains the models and functions to manage the user's
+// feed subscriptions and save their new entries as bookmarks.
+package feeds
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+
+	"codeberg.org/readeck/readeck/configs"
+	"codeberg.org/readeck/readeck/internal/db"
+	"codeberg.org/readeck/readeck/internal/db/types"
+	"codeberg.org/readeck/readeck/pkg/base58"
+	"codeberg.org/readeck/readeck/pkg/feed"
+)
+
+const (
+	// TableName is the feed table name in database.
+	TableName = "feed"
+	// EntryTable is the table of the feed entries already seen.
+	EntryTable = "feed_entry"
+
+	// DefaultMaxEntries is the default number of bookmarks
+	// created on every fetch.
+	DefaultMaxEntries = 10
+
+	// entryRetention is the time an entry that left the feed
+	// stays in the seen list.
+	entryRetention = time.Hour * 24 * 30
+)
+
+var (
+	// Feeds is the feed manager.
+	Feeds = Manager{}
+
+	// ErrNotFound is returned when a feed record was not found.
+	ErrNotFound = errors.New("not found")
+)
+
+// PollInterval returns the time between two fetches of every feed.
+func PollInterval() time.Duration {
+	return time.Duration(max(configs.Config.Bookmarks.FeedPollInterval, 5)) * time.Minute
+}
+
+// Feed is a feed subscription record in database.
+type Feed struct {
+	ID           int           `db:"id" goqu:"skipinsert,skipupdate"`
+	UID          string        `db:"uid"`
+	UserID       *int          `db:"user_id"`
+	Created      time.Time     `db:"created" goqu:"skipupdate"`
+	Updated      time.Time     `db:"updated"`
+	IsEnabled    bool          `db:"is_enabled"`
+	URL          string        `db:"url"`
+	Title        string        `db:"title"`
+	SiteURL      string        `db:"site_url"`
+	Labels       types.Strings `db:"labels"`
+	Include      types.Strings `db:"include_terms"`
+	Exclude      types.Strings `db:"exclude_terms"`
+	MaxEntries   int           `db:"max_entries"`
+	LastFetched  *time.Time    `db:"last_fetched"`
+	LastError    string        `db:"last_error"`
+	ETag         string        `db:"etag"`
+	LastModified string        `db:"last_modified"`
+}
+
+// Manager is a query helper for feed entries.
+type Manager struct{}
+
+// Query returns a prepared goqu SelectDataset that can be extended later.
+func (m *Manager) Query() *goqu.SelectDataset {
+	return db.Q().From(goqu.T(TableName).As("f")).Prepared(true)
+}
+
+// GetOne executes the a select query and returns the first result or an error
+// when there's no result.
+func (m *Manager) GetOne(expressions ...goqu.Expression) (*Feed, error) {
+	var f Feed
+	found, err := m.Query().Where(expressions...).ScanStruct(&f)
+
+	switch {
+	case err != nil:
+		return nil, err
+	case !found:
+		return nil, ErrNotFound
+	}
+
+	return &f, nil
+}
+
+// Exists returns true when the user already follows the given URL.
+func (m *Manager) Exists(userID int, url string) (bool, error) {
+	count, err := m.Query().
+		Where(
+			goqu.C("user_id").Eq(userID),
+			goqu.C("url").Eq(url),
+		).
+		Count()
+	return count > 0, err
+}
+
+// Create inserts a new feed in the database.
+func (m *Manager) Create(f *Feed) error {
+	if f.UserID == nil {
+		return errors.New("no feed user")
+	}
+	if strings.TrimSpace(f.URL) == "" {
+		return errors.New("no URL")
+	}
+
+	f.Created = time.Now()
+	f.Updated = f.Created
+	f.UID = base58.NewUUID()
+	if f.MaxEntries <= 0 {
+		f.MaxEntries = DefaultMaxEntries
+	}
+	for _, x := range []*types.Strings{&f.Labels, &f.Include, &f.Exclude} {
+		if *x == nil {
+			*x = types.Strings{}
+		}
+	}
+
+	ds := db.Q().Insert(TableName).
+		Rows(f).
+		Prepared(true)
+
+	id, err := db.InsertWithID(ds, "id")
+	if err != nil {
+		return err
+	}
+
+	f.ID = id
+	return nil
+}
+
+// Update updates some feed values.
+func (f *Feed) Update(v interface{}) error {
+	if f.ID == 0 {
+		return errors.New("no ID")
+	}
+
+	switch v := v.(type) {
+	case map[string]interface{}:
+		v["updated"] = time.Now()
+	case *Feed:
+		v.Updated = time.Now()
+	}
+
+	_, err := db.Q().Update(TableName).Prepared(true).
+		Set(v).
+		Where(goqu.C("id").Eq(f.ID)).
+		Executor().Exec()
+
+	return err
+}
+
+// Save updates all the feed values.
+func (f *Feed) Save() error {
+	return f.Update(f)
+}
+
+// Delete removes a feed from the database. The bookmarks
+// it created are kept.
+func (f *Feed) Delete() error {
+	_, err := db.Q().Delete(TableName).Prepared(true).
+		Where(goqu.C("id").Eq(f.ID)).
+		Executor().Exec()
+
+	return err
+}
+
+// Matches returns true when an entry passes the feed's filters.
+// The terms are matched, regardless of case, on the entry's
+// title and summary.
+func (f *Feed) Matches(item feed.Item) bool {
+	text := strings.ToLower(item.Title + "\n" + item.Summary)
+	contains := func(terms []string) bool {
+		for _, x := range terms {
+			if x = strings.ToLower(strings.TrimSpace(x)); x != "" && strings.Contains(text, x) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if contains(f.Exclude) {
+		return false
+	}
+	return len(f.Include) == 0 || contains(f.Include)
+}
+
+// seenEntries returns the IDs, among the given ones, of the entries
+// that were already seen.
+func (f *Feed) seenEntries(ids []string) (map[string]bool, error) {
+	res := map[string]bool{}
+	if len(ids) == 0 {
+		return res, nil
+	}
+
+	seen := []string{}
+	err := db.Q().From(EntryTable).Prepared(true).
+		Select("guid").
+		Where(
+			goqu.C("feed_id").Eq(f.ID),
+			goqu.C("guid").In(ids),
+		).
+		ScanVals(&seen)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, x := range seen {
+		res[x] = true
+	}
+	return res, nil
+}
+
+// addEntries marks entries as seen.
+func (f *Feed) addEntries(ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	rows := make([]goqu.Record, len(ids))
+	for i, x := range ids {
+		rows[i] = goqu.Record{"feed_id": f.ID, "created": now, "guid": x}
+	}
+
+	_, err := db.Q().Insert(EntryTable).Prepared(true).
+		Rows(rows).
+		Executor().Exec()
+	return err
+}
+
+// pruneEntries removes the old entries that aren't
+// in the feed anymore.
+func (f *Feed) pruneEntries(current []string) error {
+	ds := db.Q().Delete(EntryTable).Prepared(true).
+		Where(
+			goqu.C("feed_id").Eq(f.ID),
+			goqu.C("created").Lt(time.Now().Add(-entryRetention)),
+		)
+	if len(current) > 0 {
+		ds = ds.Where(goqu.C("guid").NotIn(current))
+	}
+
+	_, err := ds.Executor().Exec()
+	return err
+}
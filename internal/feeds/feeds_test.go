@@ -0,0 +1,238 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package feeds_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/require"
+
+	"codeberg.org/readeck/readeck/configs"
+	"codeberg.org/readeck/readeck/internal/bookmarks"
+	"codeberg.org/readeck/readeck/internal/db/types"
+	"codeberg.org/readeck/readeck/internal/feeds"
+	. "codeberg.org/readeck/readeck/internal/testing" //revive:disable:dot-imports
+	"codeberg.org/readeck/readeck/pkg/feed"
+	"codeberg.org/readeck/readeck/pkg/opml"
+)
+
+const rssItem = `<item>
+	<title>%s</title>
+	<link>%s</link>
+	<guid>%s</guid>
+	<pubDate>%s</pubDate>
+</item>`
+
+func rssDocument(items ...string) string {
+	return `<?xml version="1.0" encoding="UTF-8"?>
+	<rss version="2.0"><channel>
+		<title>Example feed</title>
+		<link>https://example.net/</link>
+		` + strings.Join(items, "\n") + `
+	</channel></rss>`
+}
+
+func newTestApp(t *testing.T) *TestApp {
+	// The mocked hosts can't be resolved.
+	configs.Config.Extractor.DeniedIPs = nil
+	return NewTestApp(t)
+}
+
+func TestMatches(t *testing.T) {
+	tests := []struct {
+		include []string
+		exclude []string
+		item    feed.Item
+		match   bool
+	}{
+		{nil, nil, feed.Item{Title: "abc"}, true},
+		{[]string{"Go"}, nil, feed.Item{Title: "Learning go"}, true},
+		{[]string{"go"}, nil, feed.Item{Title: "Rust", Summary: "and GO"}, true},
+		{[]string{"go"}, nil, feed.Item{Title: "Rust"}, false},
+		{nil, []string{"sponsored"}, feed.Item{Title: "Sponsored post"}, false},
+		{[]string{"go"}, []string{"ad"}, feed.Item{Title: "go", Summary: "an ad"}, false},
+		{[]string{" "}, nil, feed.Item{Title: "abc"}, false},
+	}
+
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i+1), func(t *testing.T) {
+			f := &feeds.Feed{Include: test.include, Exclude: test.exclude}
+			require.Equal(t, test.match, f.Matches(test.item))
+		})
+	}
+}
+
+func TestFetch(t *testing.T) {
+	app := newTestApp(t)
+	defer func() {
+		app.Close(t)
+	}()
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	u := app.Users["user"]
+	items := []string{
+		fmt.Sprintf(rssItem, "First", "https://example.net/1", "1", "Sat, 01 Mar 2025 10:00:00 GMT"),
+		fmt.Sprintf(rssItem, "Sponsored", "https://example.net/2", "2", "Sun, 02 Mar 2025 10:00:00 GMT"),
+		fmt.Sprintf(rssItem, "Third", "/3", "3", "Mon, 03 Mar 2025 10:00:00 GMT"),
+		fmt.Sprintf(rssItem, "Fourth", "https://example.net/4", "4", ""),
+	}
+	status := http.StatusOK
+	httpmock.RegisterResponder("GET", "https://example.net/feed.xml",
+		func(r *http.Request) (*http.Response, error) {
+			if status != http.StatusOK {
+				return httpmock.NewStringResponse(status, ""), nil
+			}
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				return httpmock.NewStringResponse(http.StatusNotModified, ""), nil
+			}
+			rsp := httpmock.NewStringResponse(http.StatusOK, rssDocument(items...))
+			rsp.Header.Set("Content-Type", "application/rss+xml")
+			rsp.Header.Set("ETag", `"v1"`)
+			return rsp, nil
+		},
+	)
+
+	f := &feeds.Feed{
+		UserID:     &u.User.ID,
+		IsEnabled:  true,
+		URL:        "https://example.net/feed.xml",
+		Labels:     types.Strings{"feed"},
+		Exclude:    types.Strings{"sponsored"},
+		MaxEntries: 2,
+	}
+	require.NoError(t, feeds.Feeds.Create(f))
+
+	t.Run("first fetch", func(t *testing.T) {
+		defer Events().Clear()
+
+		res, err := f.Fetch()
+		require.NoError(t, err)
+		require.Len(t, res, 2)
+		require.Equal(t, "https://example.net/3", res[0].URL)
+		require.Equal(t, "https://example.net/1", res[1].URL)
+		require.Equal(t, types.Strings{"feed"}, res[0].Labels)
+		require.Equal(t, bookmarks.StateLoading, res[0].State)
+		require.NotNil(t, res[0].Published)
+
+		require.Len(t, Events().Records("task"), 2)
+		evt := map[string]any{}
+		require.NoError(t, json.Unmarshal(Events().Records("task")[0], &evt))
+		// Feed entries are extracted on the import queue
+		require.Equal(t, "bookmark.create_background", evt["name"])
+
+		saved, err := feeds.Feeds.GetOne(goqu.C("id").Eq(f.ID))
+		require.NoError(t, err)
+		require.Equal(t, "Example feed", saved.Title)
+		require.Equal(t, "https://example.net/", saved.SiteURL)
+		require.Equal(t, `"v1"`, saved.ETag)
+		require.NotNil(t, saved.LastFetched)
+		require.Empty(t, saved.LastError)
+	})
+
+	t.Run("not modified", func(t *testing.T) {
+		res, err := f.Fetch()
+		require.NoError(t, err)
+		require.Empty(t, res)
+		require.Empty(t, f.LastError)
+	})
+
+	t.Run("seen entries", func(t *testing.T) {
+		f.ETag = ""
+		res, err := f.Fetch()
+		require.NoError(t, err)
+		require.Empty(t, res)
+	})
+
+	t.Run("new entry", func(t *testing.T) {
+		defer Events().Clear()
+
+		items = append(items,
+			fmt.Sprintf(rssItem, "Fifth", "https://example.net/5", "5", "Tue, 04 Mar 2025 10:00:00 GMT"),
+			fmt.Sprintf(rssItem, "Sixth", "https://example.net/1#again", "6", "Wed, 05 Mar 2025 10:00:00 GMT"),
+		)
+		f.ETag = ""
+		res, err := f.Fetch()
+		require.NoError(t, err)
+		require.Len(t, res, 1)
+		require.Equal(t, "https://example.net/5", res[0].URL)
+		require.Len(t, Events().Records("task"), 1)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		status = http.StatusBadGateway
+		f.ETag = ""
+		res, err := f.Fetch()
+		require.NoError(t, err)
+		require.Empty(t, res)
+
+		saved, err := feeds.Feeds.GetOne(goqu.C("id").Eq(f.ID))
+		require.NoError(t, err)
+		require.Equal(t, "unexpected status 502", saved.LastError)
+	})
+}
+
+func TestOPML(t *testing.T) {
+	app := newTestApp(t)
+	defer func() {
+		app.Close(t)
+	}()
+
+	u := app.Users["user"]
+	require.NoError(t, feeds.Feeds.Create(&feeds.Feed{
+		UserID:  &u.User.ID,
+		URL:     "https://example.net/feed.xml",
+		Title:   "Example",
+		SiteURL: "https://example.net/",
+		Labels:  types.Strings{"news"},
+	}))
+
+	doc, err := opml.Parse(strings.NewReader(`<?xml version="1.0" encoding="UTF-8"?>
+	<opml version="2.0">
+		<body>
+			<outline text="Example" xmlUrl="https://example.net/feed.xml" />
+			<outline text="Tech">
+				<outline text="Blog" xmlUrl="https://example.org/atom.xml" category="tech,/go" />
+				<outline text="Local" xmlUrl="file:///etc/passwd" />
+			</outline>
+		</body>
+	</opml>`))
+	require.NoError(t, err)
+
+	created, err := feeds.ImportOPML(doc, u.User.ID)
+	require.NoError(t, err)
+	require.Len(t, created, 1)
+	require.Equal(t, "https://example.org/atom.xml", created[0].URL)
+	require.Equal(t, "Blog", created[0].Title)
+	require.Equal(t, types.Strings{"Tech", "go", "tech"}, created[0].Labels)
+	require.True(t, created[0].IsEnabled)
+
+	buf := new(bytes.Buffer)
+	require.NoError(t, feeds.ExportOPML(buf, u.User.ID))
+
+	doc, err = opml.Parse(buf)
+	require.NoError(t, err)
+	require.Equal(t, []opml.Subscription{
+		{
+			Title:      "Example",
+			XMLURL:     "https://example.net/feed.xml",
+			HTMLURL:    "https://example.net/",
+			Categories: []string{"news"},
+		},
+		{
+			Title:      "Blog",
+			XMLURL:     "https://example.org/atom.xml",
+			Categories: []string{"Tech", "go", "tech"},
+		},
+	}, doc.Subscriptions())
+}
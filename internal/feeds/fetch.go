@@ -0,0 +1,259 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package feeds
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+
+	"codeberg.org/readeck/readeck/configs"
+	"codeberg.org/readeck/readeck/internal/bookmarks"
+	"codeberg.org/readeck/readeck/internal/bookmarks/tasks"
+	"codeberg.org/readeck/readeck/pkg/extract"
+	"codeberg.org/readeck/readeck/pkg/feed"
+)
+
+// maxFeedSize is the maximum size of a feed document.
+const maxFeedSize = 10 << 20
+
+// statusError is returned when the feed's server responds
+// with an unsuccessful status.
+type statusError int
+
+func (e statusError) Error() string {
+	return fmt.Sprintf("unexpected status %d", int(e))
+}
+
+// newClient returns the HTTP client used to load the feeds. It can't
+// reach the addresses the extractor can't reach either.
+func newClient() *http.Client {
+	client := extract.NewClient()
+	extract.SetClientDeniedIPs(client, configs.ExtractorDeniedIPs())
+	return client
+}
+
+// Fetch loads the feed and saves its new entries as bookmarks, then launches
+// their extraction. It returns the created bookmarks.
+//
+// When the feed can't be loaded or read, the error is recorded in
+// the feed's LastError and Fetch doesn't return it.
+func (f *Feed) Fetch() ([]*bookmarks.Bookmark, error) {
+	doc, err := f.load()
+
+	now := time.Now()
+	f.LastFetched = &now
+	f.LastError = ""
+	if err != nil {
+		f.LastError = err.Error()
+	}
+
+	values := map[string]interface{}{
+		"last_fetched":  f.LastFetched,
+		"last_error":    f.LastError,
+		"etag":          f.ETag,
+		"last_modified": f.LastModified,
+	}
+	if doc != nil {
+		f.SiteURL = doc.SiteURL
+		values["site_url"] = f.SiteURL
+		if f.Title == "" {
+			f.Title = doc.Title
+			values["title"] = f.Title
+		}
+	}
+	if err := f.Update(values); err != nil {
+		return nil, err
+	}
+
+	if doc == nil {
+		return nil, nil
+	}
+	return f.saveEntries(doc.Items)
+}
+
+// load retrieves and parses the feed document. It returns nil, without
+// an error, when the feed didn't change since the last fetch.
+func (f *Feed) load() (*feed.Feed, error) {
+	req, err := http.NewRequest(http.MethodGet, f.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/rss+xml, application/atom+xml, application/feed+json, application/xml;q=0.9, */*;q=0.8")
+	if f.ETag != "" {
+		req.Header.Set("If-None-Match", f.ETag)
+	}
+	if f.LastModified != "" {
+		req.Header.Set("If-Modified-Since", f.LastModified)
+	}
+
+	rsp, err := newClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer rsp.Body.Close() //nolint:errcheck
+
+	if rsp.StatusCode == http.StatusNotModified {
+		return nil, nil
+	}
+	if rsp.StatusCode < 200 || rsp.StatusCode >= 300 {
+		return nil, statusError(rsp.StatusCode)
+	}
+
+	doc, err := feed.Parse(io.LimitReader(rsp.Body, maxFeedSize))
+	if err != nil {
+		return nil, err
+	}
+
+	f.ETag = rsp.Header.Get("ETag")
+	f.LastModified = rsp.Header.Get("Last-Modified")
+
+	// Entry URLs can be relative to the feed.
+	base := req.URL
+	if rsp.Request != nil {
+		// After a redirection
+		base = rsp.Request.URL
+	}
+	for i, x := range doc.Items {
+		doc.Items[i].URL = resolveURL(base, x.URL)
+	}
+	doc.SiteURL = resolveURL(base, doc.SiteURL)
+
+	return doc, nil
+}
+
+// saveEntries creates a bookmark for the most recent new entries
+// that pass the filters, up to the feed's maximum number of entries.
+// All the new entries are marked as seen.
+func (f *Feed) saveEntries(items []feed.Item) ([]*bookmarks.Bookmark, error) {
+	items = slices.DeleteFunc(slices.Clone(items), func(x feed.Item) bool {
+		return x.URL == "" || x.ID == ""
+	})
+
+	ids := []string{}
+	for _, x := range items {
+		if !slices.Contains(ids, x.ID) {
+			ids = append(ids, x.ID)
+		}
+	}
+
+	seen, err := f.seenEntries(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	newIDs := []string{}
+	candidates := []feed.Item{}
+	for _, x := range items {
+		if seen[x.ID] || slices.Contains(newIDs, x.ID) {
+			continue
+		}
+		newIDs = append(newIDs, x.ID)
+		if f.Matches(x) {
+			candidates = append(candidates, x)
+		}
+	}
+
+	// Most recent first, the entries without a date come last.
+	slices.SortStableFunc(candidates, func(a, b feed.Item) int {
+		switch {
+		case a.Published.IsZero() && b.Published.IsZero():
+			return 0
+		case a.Published.IsZero():
+			return 1
+		case b.Published.IsZero():
+			return -1
+		}
+		return b.Published.Compare(a.Published)
+	})
+
+	res := []*bookmarks.Bookmark{}
+	for _, x := range candidates {
+		if len(res) >= cmp.Or(f.MaxEntries, DefaultMaxEntries) {
+			break
+		}
+
+		b, err := f.createBookmark(x)
+		if err != nil {
+			return res, err
+		}
+		if b != nil {
+			res = append(res, b)
+		}
+	}
+
+	if err := f.addEntries(newIDs); err != nil {
+		return res, err
+	}
+	return res, f.pruneEntries(ids)
+}
+
+// createBookmark saves a feed entry as a new bookmark and launches its
+// extraction. It returns nil when the user already has a bookmark
+// with the same URL.
+func (f *Feed) createBookmark(item feed.Item) (*bookmarks.Bookmark, error) {
+	uri, err := url.Parse(item.URL)
+	if err != nil || (uri.Scheme != "http" && uri.Scheme != "https") {
+		return nil, nil
+	}
+	uri.Fragment = ""
+
+	count, err := bookmarks.Bookmarks.Query().Where(
+		goqu.C("user_id").Eq(*f.UserID),
+		goqu.Or(
+			goqu.C("url").Eq(uri.String()),
+			goqu.C("initial_url").Eq(uri.String()),
+		),
+	).Count()
+	if err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, nil
+	}
+
+	b := &bookmarks.Bookmark{
+		UserID:   f.UserID,
+		State:    bookmarks.StateLoading,
+		URL:      uri.String(),
+		Title:    item.Title,
+		Site:     uri.Hostname(),
+		SiteName: uri.Hostname(),
+		Labels:   slices.Clone(f.Labels),
+	}
+	if !item.Published.IsZero() {
+		b.Published = &item.Published
+	}
+
+	if err := bookmarks.Bookmarks.Create(b); err != nil {
+		return nil, err
+	}
+
+	// The extraction shares the import queue, so a large feed doesn't
+	// delay the bookmarks a user is waiting for.
+	err = tasks.BackgroundExtractTask.Run(b.ID, tasks.ExtractParams{
+		BookmarkID: b.ID,
+		RequestID:  f.UID,
+		FindMain:   true,
+	})
+	return b, err
+}
+
+func resolveURL(base *url.URL, value string) string {
+	if value == "" {
+		return ""
+	}
+	u, err := base.Parse(value)
+	if err != nil {
+		return ""
+	}
+	return u.String()
+}
@@ -0,0 +1,80 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package feeds
+
+import (
+	"io"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+
+	"codeberg.org/readeck/readeck/internal/db/types"
+	"codeberg.org/readeck/readeck/pkg/opml"
+)
+
+// ExportOPML writes all the user's feeds as an OPML document.
+// The feed labels are the outline categories.
+func ExportOPML(w io.Writer, userID int) error {
+	items := []*Feed{}
+	err := Feeds.Query().
+		Where(goqu.C("user_id").Eq(userID)).
+		Order(goqu.C("created").Asc(), goqu.C("id").Asc()).
+		ScanStructs(&items)
+	if err != nil {
+		return err
+	}
+
+	doc := opml.New("Readeck feeds", time.Now())
+	for _, f := range items {
+		doc.Add(opml.Subscription{
+			Title:      f.Title,
+			XMLURL:     f.URL,
+			HTMLURL:    f.SiteURL,
+			Categories: f.Labels,
+		})
+	}
+
+	return doc.Write(w)
+}
+
+// ImportOPML creates a feed for every subscription of an OPML document
+// that the user doesn't follow yet. The subscription's categories and
+// folders become the feed's labels. It returns the created feeds.
+func ImportOPML(doc *opml.Document, userID int) ([]*Feed, error) {
+	res := []*Feed{}
+	for _, s := range doc.Subscriptions() {
+		u, err := url.Parse(s.XMLURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			continue
+		}
+
+		exists, err := Feeds.Exists(userID, u.String())
+		if err != nil {
+			return res, err
+		}
+		if exists {
+			continue
+		}
+
+		labels := slices.Clone(s.Categories)
+		slices.Sort(labels)
+		f := &Feed{
+			UserID:    &userID,
+			IsEnabled: true,
+			URL:       u.String(),
+			Title:     s.Title,
+			SiteURL:   s.HTMLURL,
+			Labels:    types.Strings(slices.Compact(labels)),
+		}
+		if err := Feeds.Create(f); err != nil {
+			return res, err
+		}
+		res = append(res, f)
+	}
+
+	return res, nil
+}
@@ -0,0 +1,95 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package feeds
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+
+	"github.com/doug-martin/goqu/v9"
+
+	"codeberg.org/readeck/readeck/internal/bus"
+	"codeberg.org/readeck/readeck/pkg/superbus"
+)
+
+var (
+	// FetchTask loads a feed and saves its new entries.
+	FetchTask superbus.Task
+
+	// PollTask launches the fetch of every enabled feed.
+	PollTask superbus.Task
+)
+
+func init() {
+	bus.OnReady(func() {
+		FetchTask = bus.Tasks().NewTask(
+			"feed.fetch",
+			superbus.WithTaskQueue(bus.QueueImport),
+			superbus.WithUnmarshall(func(data []byte) interface{} {
+				var res int
+				err := json.Unmarshal(data, &res)
+				if err != nil {
+					panic(err)
+				}
+				return res
+			}),
+			superbus.WithFallibleTaskHandler(fetchHandler),
+		)
+
+		PollTask = bus.Tasks().NewTask(
+			"feed.poll",
+			superbus.WithTaskQueue(bus.QueueMaintenance),
+			superbus.WithTaskSchedule(superbus.Every(PollInterval())),
+			superbus.WithFallibleTaskHandler(pollHandler),
+		)
+	})
+}
+
+func fetchHandler(data interface{}) error {
+	id := data.(int)
+
+	f, err := Feeds.GetOne(goqu.C("id").Eq(id))
+	if errors.Is(err, ErrNotFound) {
+		// The feed was removed in the meantime.
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	logger := slog.With(slog.String("feed", f.UID))
+	created, err := f.Fetch()
+	if err != nil {
+		return err
+	}
+	if f.LastError != "" {
+		logger.Warn("feed fetch failed", slog.String("err", f.LastError))
+		return nil
+	}
+
+	logger.Debug("feed fetched", slog.Int("count", len(created)))
+	return nil
+}
+
+func pollHandler(_ interface{}) error {
+	ids := []int{}
+	err := Feeds.Query().
+		Select(goqu.C("id").Table("f")).
+		Where(goqu.C("is_enabled").Table("f").IsTrue()).
+		ScanVals(&ids)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		if err := FetchTask.Run(id, id); err != nil {
+			return err
+		}
+	}
+
+	slog.Debug("feeds poll launched", slog.Int("count", len(ids)))
+	return nil
+}
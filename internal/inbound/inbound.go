@@ -0,0 +1,193 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+// Package inbound contains the models and functions to receive email
+// messages, like newsletters, and save them as bookmarks.
+package inbound
+
+import (
+	"crypto/rand"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+
+	"codeberg.org/readeck/readeck/configs"
+	"codeberg.org/readeck/readeck/internal/db"
+	"codeberg.org/readeck/readeck/internal/db/types"
+	"codeberg.org/readeck/readeck/pkg/base58"
+)
+
+const (
+	// TableName is the inbound address table name in database.
+	TableName = "inbound_address"
+)
+
+var (
+	// Addresses is the inbound address manager.
+	Addresses = Manager{}
+
+	// ErrNotFound is returned when an inbound address record was not found.
+	ErrNotFound = errors.New("not found")
+)
+
+// Address is an inbound address record in database.
+// A message sent to an address is saved as a bookmark
+// of the address' user.
+type Address struct {
+	ID           int           `db:"id" goqu:"skipinsert,skipupdate"`
+	UID          string        `db:"uid"`
+	UserID       *int          `db:"user_id"`
+	Created      time.Time     `db:"created" goqu:"skipupdate"`
+	Updated      time.Time     `db:"updated"`
+	Name         string        `db:"name"`
+	Token        string        `db:"token"`
+	Labels       types.Strings `db:"labels"`
+	LastReceived *time.Time    `db:"last_received"`
+}
+
+// Manager is a query helper for inbound address entries.
+type Manager struct{}
+
+// Query returns a prepared goqu SelectDataset that can be extended later.
+func (m *Manager) Query() *goqu.SelectDataset {
+	return db.Q().From(goqu.T(TableName).As("a")).Prepared(true)
+}
+
+// GetOne executes the a select query and returns the first result or an error
+// when there's no result.
+func (m *Manager) GetOne(expressions ...goqu.Expression) (*Address, error) {
+	var a Address
+	found, err := m.Query().Where(expressions...).ScanStruct(&a)
+
+	switch {
+	case err != nil:
+		return nil, err
+	case !found:
+		return nil, ErrNotFound
+	}
+
+	return &a, nil
+}
+
+// Create inserts a new inbound address in the database,
+// with a new token.
+func (m *Manager) Create(a *Address) error {
+	if a.UserID == nil {
+		return errors.New("no address user")
+	}
+
+	a.Created = time.Now()
+	a.Updated = a.Created
+	a.UID = base58.NewUUID()
+	a.Token = NewToken()
+	if a.Labels == nil {
+		a.Labels = types.Strings{}
+	}
+
+	ds := db.Q().Insert(TableName).
+		Rows(a).
+		Prepared(true)
+
+	id, err := db.InsertWithID(ds, "id")
+	if err != nil {
+		return err
+	}
+
+	a.ID = id
+	return nil
+}
+
+// FindRecipient returns the address matching one of the given
+// email addresses.
+func (m *Manager) FindRecipient(recipients []string) (*Address, error) {
+	tokens := []string{}
+	for _, x := range recipients {
+		if t := tokenFromEmail(x); t != "" {
+			tokens = append(tokens, t)
+		}
+	}
+	if len(tokens) == 0 {
+		return nil, ErrNotFound
+	}
+
+	return m.GetOne(goqu.C("token").In(tokens))
+}
+
+// Update updates some address values.
+func (a *Address) Update(v interface{}) error {
+	if a.ID == 0 {
+		return errors.New("no ID")
+	}
+
+	switch v := v.(type) {
+	case map[string]interface{}:
+		v["updated"] = time.Now()
+	case *Address:
+		v.Updated = time.Now()
+	}
+
+	_, err := db.Q().Update(TableName).Prepared(true).
+		Set(v).
+		Where(goqu.C("id").Eq(a.ID)).
+		Executor().Exec()
+
+	return err
+}
+
+// Save updates all the address values.
+func (a *Address) Save() error {
+	return a.Update(a)
+}
+
+// Delete removes an address from the database. The bookmarks
+// it created are kept.
+func (a *Address) Delete() error {
+	_, err := db.Q().Delete(TableName).Prepared(true).
+		Where(goqu.C("id").Eq(a.ID)).
+		Executor().Exec()
+
+	return err
+}
+
+// ResetToken gives a new token to the address. The messages sent
+// to the previous email address are then rejected.
+func (a *Address) ResetToken() error {
+	a.Token = NewToken()
+	return a.Update(map[string]interface{}{"token": a.Token})
+}
+
+// Email returns the email address of an inbound address. It's made
+// of the configured address, with the token after a "+" sign in the
+// local part. It returns an empty string when there's no
+// configured address.
+func (a *Address) Email() string {
+	local, domain, ok := strings.Cut(configs.Config.Inbound.Address, "@")
+	if !ok || local == "" || domain == "" {
+		return ""
+	}
+	return local + "+" + a.Token + "@" + domain
+}
+
+// NewToken returns a new random address token. It only contains
+// lowercase letters and digits so it survives the case changes
+// of mail servers.
+func NewToken() string {
+	return strings.ToLower(rand.Text())
+}
+
+// tokenFromEmail returns the token part of an email address. It's
+// the part after the "+" sign of the local part or, when there's none,
+// the whole local part.
+func tokenFromEmail(email string) string {
+	local, _, ok := strings.Cut(strings.ToLower(strings.TrimSpace(email)), "@")
+	if !ok {
+		return ""
+	}
+	if _, t, ok := strings.Cut(local, "+"); ok {
+		return t
+	}
+	return local
+}
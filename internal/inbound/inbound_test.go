@@ -0,0 +1,187 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package inbound_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/stretchr/testify/require"
+
+	"codeberg.org/readeck/readeck/configs"
+	"codeberg.org/readeck/readeck/internal/bookmarks"
+	"codeberg.org/readeck/readeck/internal/db/types"
+	"codeberg.org/readeck/readeck/internal/inbound"
+	. "codeberg.org/readeck/readeck/internal/testing" //revive:disable:dot-imports
+)
+
+const testMessage = `Message-ID: <%s@example.net>
+Date: Tue, 04 Mar 2025 10:30:00 +0100
+From: The Weekly <news@example.net>
+To: %s
+Subject: This week's news
+MIME-Version: 1.0
+Content-Type: text/html; charset=utf-8
+
+<html><body><h1>This week</h1><p>Some news.</p></body></html>
+`
+
+func writeMessage(t *testing.T, dir, name, id, to string) {
+	t.Helper()
+	for _, x := range []string{"new", "cur", "tmp"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, x), 0o750))
+	}
+	require.NoError(t, os.WriteFile(
+		filepath.Join(dir, "new", name),
+		[]byte(fmt.Sprintf(testMessage, id, to)),
+		0o640,
+	))
+}
+
+func TestAddress(t *testing.T) {
+	app := NewTestApp(t)
+	defer func() {
+		app.Close(t)
+	}()
+
+	u := app.Users["user"]
+	a := &inbound.Address{
+		UserID: &u.User.ID,
+		Name:   "newsletters",
+	}
+	require.NoError(t, inbound.Addresses.Create(a))
+	require.Regexp(t, `^[a-z0-9]{26}$`, a.Token)
+	require.Equal(t, types.Strings{}, a.Labels)
+
+	t.Run("email", func(t *testing.T) {
+		require.Equal(t, "", a.Email())
+
+		configs.Config.Inbound.Address = "readeck@example.org"
+		defer func() {
+			configs.Config.Inbound.Address = ""
+		}()
+		require.Equal(t, "readeck+"+a.Token+"@example.org", a.Email())
+	})
+
+	t.Run("find recipient", func(t *testing.T) {
+		for _, recipients := range [][]string{
+			{"readeck+" + a.Token + "@example.org"},
+			{"someone@example.net", "Readeck+" + a.Token + "@Example.org"},
+			{a.Token + "@inbound.example.org"},
+		} {
+			x, err := inbound.Addresses.FindRecipient(recipients)
+			require.NoError(t, err)
+			require.Equal(t, a.ID, x.ID)
+		}
+
+		for _, recipients := range [][]string{
+			nil,
+			{"readeck@example.org"},
+			{"readeck+abc@example.org", "invalid"},
+		} {
+			_, err := inbound.Addresses.FindRecipient(recipients)
+			require.ErrorIs(t, err, inbound.ErrNotFound)
+		}
+	})
+
+	t.Run("reset token", func(t *testing.T) {
+		token := a.Token
+		require.NoError(t, a.ResetToken())
+		require.NotEqual(t, token, a.Token)
+
+		_, err := inbound.Addresses.FindRecipient([]string{"readeck+" + token + "@example.org"})
+		require.ErrorIs(t, err, inbound.ErrNotFound)
+
+		x, err := inbound.Addresses.GetOne(goqu.C("id").Eq(a.ID))
+		require.NoError(t, err)
+		require.Equal(t, a.Token, x.Token)
+	})
+}
+
+func TestReadMaildir(t *testing.T) {
+	app := NewTestApp(t)
+	defer func() {
+		app.Close(t)
+	}()
+
+	u := app.Users["user"]
+	a := &inbound.Address{
+		UserID: &u.User.ID,
+		Labels: types.Strings{"newsletter"},
+	}
+	require.NoError(t, inbound.Addresses.Create(a))
+	to := "Readeck <readeck+" + a.Token + "@example.org>"
+
+	t.Run("no folder", func(t *testing.T) {
+		_, err := inbound.ReadMaildir(filepath.Join(t.TempDir(), "none"))
+		require.Error(t, err)
+	})
+
+	t.Run("deliver", func(t *testing.T) {
+		defer Events().Clear()
+		dir := t.TempDir()
+		writeMessage(t, dir, "1.msg", "abc", to)
+		writeMessage(t, dir, "2.msg", "def", "someone@example.org")
+
+		count, err := inbound.ReadMaildir(dir)
+		require.NoError(t, err)
+		require.Equal(t, 1, count)
+
+		require.FileExists(t, filepath.Join(dir, "cur", "1.msg:2,S"))
+		require.FileExists(t, filepath.Join(dir, "cur", "2.msg:2,T"))
+		entries, _ := os.ReadDir(filepath.Join(dir, "new"))
+		require.Empty(t, entries)
+
+		b, err := bookmarks.Bookmarks.GetOne(
+			goqu.C("user_id").Eq(u.User.ID),
+			goqu.C("site").Eq(inbound.MessageHost),
+		)
+		require.NoError(t, err)
+		require.Equal(t, "This week's news", b.Title)
+		require.Equal(t, "The Weekly", b.SiteName)
+		require.Equal(t, types.Strings{"newsletter"}, b.Labels)
+		require.Equal(t, bookmarks.StateLoading, b.State)
+		require.NotNil(t, b.Published)
+
+		require.Len(t, Events().Records("task"), 1)
+		evt := map[string]any{}
+		require.NoError(t, json.Unmarshal(Events().Records("task")[0], &evt))
+		require.Equal(t, "bookmark.create", evt["name"])
+
+		x, err := inbound.Addresses.GetOne(goqu.C("id").Eq(a.ID))
+		require.NoError(t, err)
+		require.NotNil(t, x.LastReceived)
+
+		// The same message is only saved once.
+		writeMessage(t, dir, "3.msg", "abc", to)
+		count, err = inbound.ReadMaildir(dir)
+		require.NoError(t, err)
+		require.Equal(t, 1, count)
+		require.FileExists(t, filepath.Join(dir, "cur", "3.msg:2,S"))
+		require.Len(t, Events().Records("task"), 1)
+
+		n, err := bookmarks.Bookmarks.Query().Where(
+			goqu.C("user_id").Eq(u.User.ID),
+			goqu.C("site").Eq(inbound.MessageHost),
+		).Count()
+		require.NoError(t, err)
+		require.Equal(t, int64(1), n)
+	})
+
+	t.Run("invalid message", func(t *testing.T) {
+		dir := t.TempDir()
+		writeMessage(t, dir, "1.msg", "ghi", to)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "new", "1.msg"), []byte("invalid"), 0o640))
+
+		count, err := inbound.ReadMaildir(dir)
+		require.NoError(t, err)
+		require.Equal(t, 0, count)
+		require.FileExists(t, filepath.Join(dir, "cur", "1.msg:2,T"))
+	})
+}
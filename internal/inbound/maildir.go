@@ -0,0 +1,116 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package inbound
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"codeberg.org/readeck/readeck/pkg/base58"
+	"codeberg.org/readeck/readeck/pkg/mailmsg"
+)
+
+// maxMessageSize is the maximum size of a message.
+const maxMessageSize = 25 << 20
+
+// rejectError is returned when a message can't be saved,
+// and never will.
+type rejectError struct {
+	err error
+}
+
+func (e rejectError) Error() string {
+	return e.err.Error()
+}
+
+func (e rejectError) Unwrap() error {
+	return e.err
+}
+
+// ReadMaildir saves the new messages of a Maildir folder as bookmarks
+// and returns the number of saved messages.
+//
+// A saved message moves to the "cur" folder with the "S" (seen) flag.
+// A message that can't be read or that isn't sent to a known address
+// moves to the "cur" folder with the "T" (trashed) flag.
+func ReadMaildir(dir string) (int, error) {
+	entries, err := os.ReadDir(filepath.Join(dir, "new"))
+	if err != nil {
+		return 0, err
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "cur"), 0o750); err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, x := range entries {
+		if !x.Type().IsRegular() || strings.HasPrefix(x.Name(), ".") {
+			continue
+		}
+
+		name := filepath.Join(dir, "new", x.Name())
+		logger := slog.With(slog.String("message", x.Name()))
+
+		flag := "S"
+		err := deliverFile(name)
+		switch {
+		case errors.As(err, &rejectError{}):
+			logger.Warn("message rejected", slog.Any("err", err))
+			flag = "T"
+		case err != nil:
+			// The message stays in the "new" folder
+			// and the next read tries again.
+			return count, err
+		default:
+			count++
+		}
+
+		dest := filepath.Join(dir, "cur", strings.SplitN(x.Name(), ":", 2)[0]+":2,"+flag)
+		if err := os.Rename(name, dest); err != nil {
+			return count, err
+		}
+	}
+
+	return count, nil
+}
+
+// deliverFile saves a message file as a bookmark. It returns
+// a [rejectError] when the message can't be saved.
+func deliverFile(name string) error {
+	fp, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer fp.Close() //nolint:errcheck
+
+	msg, err := mailmsg.Parse(io.LimitReader(fp, maxMessageSize))
+	if err != nil {
+		return rejectError{err}
+	}
+
+	a, err := Addresses.FindRecipient(msg.Recipients)
+	if errors.Is(err, ErrNotFound) {
+		return rejectError{errors.New("unknown recipient")}
+	}
+	if err != nil {
+		return err
+	}
+
+	b, err := a.Receive(msg, base58.NewUUID())
+	if err != nil {
+		return err
+	}
+	if b != nil {
+		slog.Debug("message saved",
+			slog.String("address", a.UID),
+			slog.Int("bookmark_id", b.ID),
+		)
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package inbound
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/go-shiori/dom"
+	"golang.org/x/net/html"
+
+	"codeberg.org/readeck/readeck/internal/bookmarks"
+	"codeberg.org/readeck/readeck/internal/bookmarks/tasks"
+	"codeberg.org/readeck/readeck/pkg/base58"
+	"codeberg.org/readeck/readeck/pkg/mailmsg"
+)
+
+// MessageHost is the host of the bookmarks created from a message.
+// Their URL isn't reachable, the ".invalid" domain can't exist, and
+// their content always comes from the message.
+const MessageHost = "email.readeck.invalid"
+
+// Receive saves a message as a new bookmark and launches its extraction.
+// The message content and its inline parts are passed to the extraction
+// as resources. It returns nil when the message was already saved.
+func (a *Address) Receive(msg *mailmsg.Message, requestID string) (*bookmarks.Bookmark, error) {
+	key := msg.MessageID
+	if key == "" {
+		key = base58.NewUUID()
+	}
+	sum := sha256.Sum256([]byte(key))
+	base := &url.URL{Scheme: "https", Host: MessageHost, Path: "/" + hex.EncodeToString(sum[:16]) + "/"}
+
+	count, err := bookmarks.Bookmarks.Query().Where(
+		goqu.C("user_id").Eq(*a.UserID),
+		goqu.C("url").Eq(base.String()),
+	).Count()
+	if err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, nil
+	}
+
+	resources, err := messageResources(msg, base)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &bookmarks.Bookmark{
+		UserID:   a.UserID,
+		State:    bookmarks.StateLoading,
+		URL:      base.String(),
+		Title:    msg.Subject,
+		Site:     MessageHost,
+		SiteName: msg.SenderName(),
+		Labels:   slices.Clone(a.Labels),
+	}
+	if !msg.Date.IsZero() {
+		b.Published = &msg.Date
+	}
+
+	if err = bookmarks.Bookmarks.Create(b); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	a.LastReceived = &now
+	if err = a.Update(map[string]interface{}{"last_received": a.LastReceived}); err != nil {
+		return b, err
+	}
+
+	err = tasks.ExtractPageTask.Run(b.ID, tasks.ExtractParams{
+		BookmarkID: b.ID,
+		RequestID:  requestID,
+		Resources:  resources,
+		FindMain:   true,
+	})
+	return b, err
+}
+
+// messageResources returns the resources of a message's bookmark. The first
+// one is an HTML document with the message content and its metadata. The
+// inline parts it references come next.
+func messageResources(msg *mailmsg.Message, base *url.URL) ([]tasks.MultipartResource, error) {
+	doc, err := html.Parse(strings.NewReader(msg.HTML))
+	if err != nil {
+		return nil, err
+	}
+
+	// The content is always in UTF-8 now.
+	for _, node := range dom.QuerySelectorAll(doc, "meta[charset],meta[http-equiv],base,title") {
+		node.Parent.RemoveChild(node)
+	}
+
+	head := dom.QuerySelector(doc, "head")
+	title := dom.CreateElement("title")
+	dom.SetTextContent(title, msg.Subject)
+	head.AppendChild(title)
+
+	for _, x := range [][3]string{
+		{"property", "og:title", msg.Subject},
+		{"property", "og:type", "article"},
+		{"property", "og:site_name", msg.SenderName()},
+		{"name", "author", msg.SenderName()},
+	} {
+		if x[2] == "" {
+			continue
+		}
+		node := dom.CreateElement("meta")
+		dom.SetAttribute(node, x[0], x[1])
+		dom.SetAttribute(node, "content", x[2])
+		head.AppendChild(node)
+	}
+	if !msg.Date.IsZero() {
+		node := dom.CreateElement("meta")
+		dom.SetAttribute(node, "name", "date")
+		dom.SetAttribute(node, "content", msg.Date.Format(time.RFC3339))
+		head.AppendChild(node)
+	}
+
+	// Inline parts are referenced with a "cid:" URL, they get
+	// a URL under the message's URL.
+	parts := map[string]string{}
+	res := []tasks.MultipartResource{{URL: base.String()}}
+	for _, p := range msg.Inline {
+		if _, ok := parts[p.ContentID]; ok {
+			continue
+		}
+		u := base.JoinPath(url.PathEscape(p.ContentID)).String()
+		parts[p.ContentID] = u
+		res = append(res, tasks.MultipartResource{
+			URL:     u,
+			Headers: map[string]string{"Content-Type": p.ContentType},
+			Data:    p.Data,
+		})
+	}
+
+	for _, node := range dom.QuerySelectorAll(doc, "[src],[background]") {
+		for _, name := range []string{"src", "background"} {
+			value := dom.GetAttribute(node, name)
+			if !strings.HasPrefix(strings.ToLower(value), "cid:") {
+				continue
+			}
+			cid, _ := url.PathUnescape(value[4:])
+			if u, ok := parts[strings.Trim(cid, "<>")]; ok {
+				dom.SetAttribute(node, name, u)
+			}
+		}
+	}
+
+	buf := new(bytes.Buffer)
+	if err := html.Render(buf, doc); err != nil {
+		return nil, err
+	}
+
+	res[0].Headers = map[string]string{"Content-Type": "text/html; charset=utf-8"}
+	res[0].Data = buf.Bytes()
+	return res, nil
+}
@@ -0,0 +1,44 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package inbound
+
+import (
+	"log/slog"
+	"time"
+
+	"codeberg.org/readeck/readeck/configs"
+	"codeberg.org/readeck/readeck/internal/bus"
+	"codeberg.org/readeck/readeck/pkg/superbus"
+)
+
+// PollTask saves the new messages of the inbound Maildir folder.
+var PollTask superbus.Task
+
+func init() {
+	bus.OnReady(func() {
+		options := []superbus.TaskOption{
+			superbus.WithTaskQueue(bus.QueueImport),
+			superbus.WithFallibleTaskHandler(pollHandler),
+		}
+		if configs.Config.Inbound.Maildir != "" {
+			options = append(options, superbus.WithTaskSchedule(superbus.Every(time.Minute)))
+		}
+
+		PollTask = bus.Tasks().NewTask("inbound.poll", options...)
+	})
+}
+
+func pollHandler(_ interface{}) error {
+	dir := configs.Config.Inbound.Maildir
+	if dir == "" {
+		return nil
+	}
+
+	count, err := ReadMaildir(dir)
+	if count > 0 {
+		slog.Info("inbound messages saved", slog.Int("count", count))
+	}
+	return err
+}
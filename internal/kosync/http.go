@@ -0,0 +1,60 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+// Package kosync provides a KOReader progress synchronization server,
+// so the reading progress of exported e-books follows between an
+// e-reader and Readeck.
+package kosync
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	bookmark_routes "codeberg.org/readeck/readeck/internal/bookmarks/routes"
+	"codeberg.org/readeck/readeck/internal/server"
+)
+
+type kosyncRouter struct {
+	chi.Router
+	srv *server.Server
+}
+
+// SetupRoutes adds the KOReader synchronization HTTP routes.
+func SetupRoutes(s *server.Server) {
+	r := chi.NewRouter()
+	h := &kosyncRouter{r, s}
+
+	// Non authenticated routes
+	r.Post("/users/create", h.userCreate)
+	r.Get("/healthcheck", h.healthcheck)
+
+	// Authenticated routes
+	r.Group(func(r chi.Router) {
+		r.Use(s.AuthenticatedRouter().Middlewares()...)
+		r.Use(s.WithPermission("api:kosync", "read"))
+
+		r.Get("/users/auth", h.userAuth)
+		r.Group(bookmark_routes.NewKosyncRouteHandler(s))
+	})
+
+	s.AddRoute("/kosync", r)
+}
+
+// userCreate always fails. Users must exist in Readeck and
+// use one of their API tokens as a password.
+func (h *kosyncRouter) userCreate(w http.ResponseWriter, r *http.Request) {
+	h.srv.Render(w, r, http.StatusPaymentRequired, map[string]any{
+		"code":    2005,
+		"message": "User registration is disabled.",
+	})
+}
+
+func (h *kosyncRouter) healthcheck(w http.ResponseWriter, r *http.Request) {
+	h.srv.Render(w, r, http.StatusOK, map[string]string{"state": "OK"})
+}
+
+func (h *kosyncRouter) userAuth(w http.ResponseWriter, r *http.Request) {
+	h.srv.Render(w, r, http.StatusOK, map[string]string{"authorized": "OK"})
+}
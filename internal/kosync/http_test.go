@@ -0,0 +1,183 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package kosync_test
+
+import (
+	"crypto/md5" //nolint:gosec
+	"encoding/hex"
+	"testing"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/stretchr/testify/require"
+
+	"codeberg.org/readeck/readeck/internal/auth/tokens"
+	"codeberg.org/readeck/readeck/internal/bookmarks"
+	. "codeberg.org/readeck/readeck/internal/testing" //revive:disable:dot-imports
+)
+
+func TestKosync(t *testing.T) {
+	app := NewTestApp(t)
+	defer app.Close(t)
+
+	client := NewClient(t, app)
+	client.Logout()
+
+	// Give the fixture bookmark an article and a known document.
+	b := app.Users["user"].Bookmarks[0]
+	require.NoError(t, b.Update(map[string]any{"files": bookmarks.BookmarkFiles{
+		"article": {Name: "index.html", Type: "text/html"},
+	}}))
+	const document = "0123456789abcdef0123456789abcdef"
+	require.NoError(t, bookmarks.Documents.Record(b, document))
+
+	digest := md5.Sum([]byte(app.Users["user"].APIToken())) //nolint:gosec
+	key := hex.EncodeToString(digest[:])
+
+	request := func(method, target string, data any, username, key string) *Response {
+		req := client.NewJSONRequest(method, target, data)
+		req.Header.Set("Accept", "application/vnd.koreader.v1+json")
+		if username != "" {
+			req.Header.Set("x-auth-user", username)
+			req.Header.Set("x-auth-key", key)
+		}
+		return client.Request(req)
+	}
+
+	t.Run("users", func(t *testing.T) {
+		rsp := request("POST", "/kosync/users/create", map[string]string{"username": "test", "password": "test"}, "", "")
+		rsp.AssertStatus(t, 402)
+
+		rsp = request("GET", "/kosync/healthcheck", nil, "", "")
+		rsp.AssertStatus(t, 200)
+		rsp.AssertJSON(t, `{"state": "OK"}`)
+
+		rsp = request("GET", "/kosync/users/auth", nil, "user", key)
+		rsp.AssertStatus(t, 200)
+		rsp.AssertJSON(t, `{"authorized": "OK"}`)
+
+		rsp = request("GET", "/kosync/users/auth", nil, "user", app.Users["user"].APIToken())
+		rsp.AssertStatus(t, 200)
+
+		rsp = request("GET", "/kosync/users/auth", nil, "user", "nope")
+		rsp.AssertStatus(t, 401)
+
+		rsp = request("GET", "/kosync/users/auth", nil, "staff", key)
+		rsp.AssertStatus(t, 401)
+
+		rsp = request("GET", "/kosync/users/auth", nil, "", "")
+		rsp.AssertStatus(t, 401)
+	})
+
+	t.Run("progress", func(t *testing.T) {
+		rsp := request("GET", "/kosync/syncs/progress/"+document, nil, "user", key)
+		rsp.AssertStatus(t, 200)
+		rsp.AssertJSON(t, `{
+			"document": "`+document+`",
+			"progress": "/body/DocFragment[1]/body/main",
+			"percentage": 0,
+			"device": "Readeck",
+			"device_id": "readeck",
+			"timestamp": "<<PRESENCE>>"
+		}`)
+
+		rsp = request("GET", "/kosync/syncs/progress/unknown", nil, "user", key)
+		rsp.AssertStatus(t, 200)
+		rsp.AssertJSON(t, `{}`)
+
+		rsp = request("PUT", "/kosync/syncs/progress", map[string]any{
+			"document":   document,
+			"progress":   "/body/DocFragment[1]/body/main/section/p[3]/text().10",
+			"percentage": 0.1234,
+			"device":     "Kobo",
+			"device_id":  "ABCDEF",
+		}, "user", key)
+		rsp.AssertStatus(t, 200)
+		rsp.AssertJSON(t, `{"document": "`+document+`", "timestamp": "<<PRESENCE>>"}`)
+
+		b, err := bookmarks.Bookmarks.GetOne(goqu.C("id").Eq(b.ID))
+		require.NoError(t, err)
+		require.Equal(t, 12, b.ReadProgress)
+		require.Equal(t, "section:nth-child(1)>p:nth-child(7)", b.ReadAnchor)
+
+		rsp = request("GET", "/kosync/syncs/progress/"+document, nil, "user", key)
+		rsp.AssertStatus(t, 200)
+		rsp.AssertJSON(t, `{
+			"document": "`+document+`",
+			"progress": "/body/DocFragment[1]/body/main/section/p[3]",
+			"percentage": 0.12,
+			"device": "Kobo",
+			"device_id": "ABCDEF",
+			"timestamp": "<<PRESENCE>>"
+		}`)
+
+		// Progress from the web reader
+		require.NoError(t, b.Update(map[string]any{
+			"read_progress": 40,
+			"read_anchor":   "section:nth-child(1)>h2:nth-child(8)",
+		}))
+		rsp = request("GET", "/kosync/syncs/progress/"+document, nil, "user", key)
+		rsp.AssertStatus(t, 200)
+		rsp.AssertJQ(t, ".progress", "/body/DocFragment[1]/body/main/section/h2[1]")
+		rsp.AssertJQ(t, ".percentage", 0.4)
+		rsp.AssertJQ(t, ".device", "Readeck")
+
+		// Progress without anchor
+		require.NoError(t, b.Update(map[string]any{"read_anchor": ""}))
+		rsp = request("GET", "/kosync/syncs/progress/"+document, nil, "user", key)
+		rsp.AssertStatus(t, 200)
+		require.Regexp(t, `^/body/DocFragment\[1\]/body/main/section/`, rsp.JSON.(map[string]any)["progress"])
+
+		rsp = request("PUT", "/kosync/syncs/progress", map[string]any{
+			"document":   "unknown",
+			"progress":   "/body/DocFragment[1]/body/main",
+			"percentage": 0.5,
+		}, "user", key)
+		rsp.AssertStatus(t, 404)
+
+		rsp = request("PUT", "/kosync/syncs/progress", map[string]any{
+			"document": document,
+		}, "user", key)
+		rsp.AssertStatus(t, 422)
+
+		// Documents belong to a user
+		rsp = request("GET", "/kosync/syncs/progress/"+document, nil, "staff", app.Users["staff"].APIToken())
+		rsp.AssertStatus(t, 200)
+		rsp.AssertJSON(t, `{}`)
+	})
+
+	t.Run("restriction", func(t *testing.T) {
+		u := app.Users["user"]
+		restrict := func(r *tokens.Restriction) {
+			u.Token.Restriction = r
+			require.NoError(t, u.Token.Save())
+		}
+		defer restrict(nil)
+
+		// The token can't access the document's bookmark
+		restrict(&tokens.Restriction{Labels: []string{"nope"}})
+
+		rsp := request("GET", "/kosync/syncs/progress/"+document, nil, "user", key)
+		rsp.AssertStatus(t, 200)
+		rsp.AssertJSON(t, `{}`)
+
+		rsp = request("PUT", "/kosync/syncs/progress", map[string]any{
+			"document":   document,
+			"progress":   "/body/DocFragment[1]/body/main",
+			"percentage": 0.9,
+		}, "user", key)
+		rsp.AssertStatus(t, 404)
+
+		b, err := bookmarks.Bookmarks.GetOne(goqu.C("id").Eq(b.ID))
+		require.NoError(t, err)
+		require.Equal(t, 40, b.ReadProgress)
+
+		// It can when the bookmark matches
+		restrict(&tokens.Restriction{Search: b.Title})
+
+		rsp = request("GET", "/kosync/syncs/progress/"+document, nil, "user", key)
+		rsp.AssertStatus(t, 200)
+		rsp.AssertJQ(t, ".document", document)
+	})
+}
@@ -7,6 +7,10 @@ package opds_test
 import (
 	"testing"
 
+	"github.com/stretchr/testify/require"
+
+	"codeberg.org/readeck/readeck/internal/auth/tokens"
+	"codeberg.org/readeck/readeck/internal/bookmarks"
 	. "codeberg.org/readeck/readeck/internal/testing" //revive:disable:dot-imports
 )
 
@@ -61,3 +65,94 @@ func TestPermissions(t *testing.T) {
 		)
 	}
 }
+
+func TestRestriction(t *testing.T) {
+	app := NewTestApp(t)
+	defer app.Close(t)
+
+	client := NewClient(t, app)
+	u := app.Users["user"]
+
+	newBookmark := func(title string, labels ...string) *bookmarks.Bookmark {
+		b := &bookmarks.Bookmark{
+			UserID: &u.User.ID,
+			URL:    "https://example.org/" + title,
+			Title:  title,
+			State:  bookmarks.StateLoaded,
+			Labels: labels,
+		}
+		require.NoError(t, bookmarks.Bookmarks.Create(b))
+		return b
+	}
+	alpha := newBookmark("alpha", "family")
+	gamma := newBookmark("gamma")
+
+	newCollection := func(name string, labels string) *bookmarks.Collection {
+		c := &bookmarks.Collection{
+			UserID:  &u.User.ID,
+			Name:    name,
+			Filters: bookmarks.Filters{Labels: labels},
+		}
+		require.NoError(t, bookmarks.Collections.Create(c))
+		return c
+	}
+	family := newCollection("Family", `"family"`)
+	other := newCollection("Other", `"other"`)
+
+	u.Token.Restriction = &tokens.Restriction{Collection: family.UID}
+	require.NoError(t, u.Token.Save())
+	defer func() {
+		u.Token.Restriction = nil
+		require.NoError(t, u.Token.Save())
+	}()
+
+	RunRequestSequence(t, client, "user",
+		RequestTest{
+			JSON:         true,
+			Target:       "/opds/bookmarks/all",
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				require.Contains(t, string(r.Body), "/api/bookmarks/"+alpha.UID+"/article.epub")
+				require.NotContains(t, string(r.Body), gamma.UID)
+				require.NotContains(t, string(r.Body), u.Bookmarks[0].UID)
+			},
+		},
+		RequestTest{
+			JSON:         true,
+			Target:       "/opds/bookmarks/unread",
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				require.Contains(t, string(r.Body), alpha.UID)
+				require.NotContains(t, string(r.Body), gamma.UID)
+			},
+		},
+		RequestTest{
+			JSON:         true,
+			Target:       "/opds/bookmarks/collections",
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				require.Contains(t, string(r.Body), family.UID)
+				require.NotContains(t, string(r.Body), other.UID)
+			},
+		},
+		RequestTest{
+			JSON:         true,
+			Target:       "/opds/bookmarks/collections/" + other.UID,
+			ExpectStatus: 404,
+		},
+		RequestTest{
+			JSON:         true,
+			Target:       "/api/bookmarks/" + gamma.UID + "/article.epub",
+			ExpectStatus: 404,
+		},
+		RequestTest{
+			JSON:         true,
+			Target:       "/api/bookmarks/export.md",
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				require.Contains(t, string(r.Body), alpha.URL)
+				require.NotContains(t, string(r.Body), gamma.URL)
+			},
+		},
+	)
+}
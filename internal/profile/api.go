@@ -5,9 +5,13 @@
 package profile
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
+	"log/slog"
 	"net/http"
+	"slices"
 	"time"
 
 	"github.com/doug-martin/goqu/v9"
@@ -16,13 +20,26 @@ import (
 	"codeberg.org/readeck/readeck/internal/auth"
 	"codeberg.org/readeck/readeck/internal/auth/tokens"
 	"codeberg.org/readeck/readeck/internal/auth/users"
+	"codeberg.org/readeck/readeck/internal/bookmarks"
+	"codeberg.org/readeck/readeck/internal/feeds"
+	"codeberg.org/readeck/readeck/internal/inbound"
 	"codeberg.org/readeck/readeck/internal/server"
+	"codeberg.org/readeck/readeck/internal/webhooks"
 	"codeberg.org/readeck/readeck/pkg/forms"
+	"codeberg.org/readeck/readeck/pkg/opml"
 )
 
 type (
-	ctxTokenListKey struct{}
-	ctxtTokenKey    struct{}
+	ctxTokenListKey   struct{}
+	ctxtTokenKey      struct{}
+	ctxWebhookListKey struct{}
+	ctxWebhookKey     struct{}
+	ctxRuleListKey    struct{}
+	ctxRuleKey        struct{}
+	ctxFeedListKey    struct{}
+	ctxFeedKey        struct{}
+	ctxInboundListKey struct{}
+	ctxInboundKey     struct{}
 )
 
 // profileAPI is the base settings API router.
@@ -50,9 +67,73 @@ func newProfileAPI(s *server.Server) *profileAPI {
 		r.With(api.withToken).Delete("/tokens/{uid}", api.tokenDelete)
 	})
 
+	r.With(api.srv.WithPermission("api:profile:webhooks", "read"), api.withoutRestriction).Group(func(r chi.Router) {
+		r.With(api.withWebhookList).Get("/webhooks", api.webhookList)
+		r.With(api.withWebhook).Get("/webhooks/{uid}", api.webhookInfo)
+		r.With(api.withWebhook).Get("/webhooks/{uid}/deliveries", api.webhookDeliveries)
+	})
+
+	r.With(api.srv.WithPermission("api:profile:webhooks", "write"), api.withoutRestriction).Group(func(r chi.Router) {
+		r.Post("/webhooks", api.webhookCreate)
+		r.With(api.withWebhook).Patch("/webhooks/{uid}", api.webhookUpdate)
+		r.With(api.withWebhook).Delete("/webhooks/{uid}", api.webhookDelete)
+	})
+
+	r.With(api.srv.WithPermission("api:profile:rules", "read"), api.withoutRestriction).Group(func(r chi.Router) {
+		r.With(api.withRuleList).Get("/rules", api.ruleList)
+		r.With(api.withRule).Get("/rules/{uid}", api.ruleInfo)
+	})
+
+	r.With(api.srv.WithPermission("api:profile:rules", "write"), api.withoutRestriction).Group(func(r chi.Router) {
+		r.Post("/rules", api.ruleCreate)
+		r.Post("/rules/test", api.ruleTest)
+		r.With(api.withRule).Patch("/rules/{uid}", api.ruleUpdate)
+		r.With(api.withRule).Delete("/rules/{uid}", api.ruleDelete)
+	})
+
+	r.With(api.srv.WithPermission("api:profile:feeds", "read"), api.withoutRestriction).Group(func(r chi.Router) {
+		r.With(api.withFeedList).Get("/feeds", api.feedList)
+		r.Get("/feeds/opml", api.feedExport)
+		r.With(api.withFeed).Get("/feeds/{uid}", api.feedInfo)
+	})
+
+	r.With(api.srv.WithPermission("api:profile:feeds", "write"), api.withoutRestriction).Group(func(r chi.Router) {
+		r.Post("/feeds", api.feedCreate)
+		r.Post("/feeds/opml", api.feedImport)
+		r.With(api.withFeed).Patch("/feeds/{uid}", api.feedUpdate)
+		r.With(api.withFeed).Delete("/feeds/{uid}", api.feedDelete)
+		r.With(api.withFeed).Post("/feeds/{uid}/fetch", api.feedFetch)
+	})
+
+	r.With(api.srv.WithPermission("api:profile:inbound", "read"), api.withoutRestriction).Group(func(r chi.Router) {
+		r.With(api.withInboundList).Get("/inbound", api.inboundList)
+		r.With(api.withInbound).Get("/inbound/{uid}", api.inboundInfo)
+	})
+
+	r.With(api.srv.WithPermission("api:profile:inbound", "write"), api.withoutRestriction).Group(func(r chi.Router) {
+		r.Post("/inbound", api.inboundCreate)
+		r.With(api.withInbound).Patch("/inbound/{uid}", api.inboundUpdate)
+		r.With(api.withInbound).Delete("/inbound/{uid}", api.inboundDelete)
+		r.With(api.withInbound).Post("/inbound/{uid}/reset", api.inboundReset)
+	})
+
 	return api
 }
 
+// withoutRestriction is a middleware that denies the routes acting on
+// all the user's bookmarks, like webhooks, rules and feeds, to tokens
+// restricted to some of them.
+func (api *profileAPI) withoutRestriction(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		info := auth.GetRequestAuthInfo(r)
+		if info.Provider != nil && !info.Provider.Restriction.IsEmpty() {
+			api.srv.Status(w, r, http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
 // userProfile is the mapping returned by the profileInfo route.
 type profileInfoProvider struct {
 	Name        string   `json:"name"`
@@ -60,6 +141,8 @@ type profileInfoProvider struct {
 	Application string   `json:"application"`
 	Roles       []string `json:"roles"`
 	Permissions []string `json:"permissions"`
+
+	Restriction *tokens.Restriction `json:"restriction,omitempty"`
 }
 type profileInfoUser struct {
 	Username string              `json:"username"`
@@ -84,6 +167,7 @@ func (api *profileAPI) profileInfo(w http.ResponseWriter, r *http.Request) {
 			ID:          info.Provider.ID,
 			Roles:       info.Provider.Roles,
 			Permissions: auth.GetPermissions(r),
+			Restriction: info.Provider.Restriction,
 		},
 		User: profileInfoUser{
 			Username: info.User.Username,
@@ -238,6 +322,8 @@ type tokenItem struct {
 	IsEnabled bool       `json:"is_enabled"`
 	IsDeleted bool       `json:"is_deleted"`
 	Roles     []string   `json:"roles"`
+
+	Restriction *tokens.Restriction `json:"restriction,omitempty"`
 }
 
 func newTokenItem(s *server.Server, r *http.Request, t *tokens.Token, base string) tokenItem {
@@ -251,5 +337,876 @@ func newTokenItem(s *server.Server, r *http.Request, t *tokens.Token, base strin
 		IsEnabled: t.IsEnabled,
 		IsDeleted: deleteTokenTask.IsRunning(t.ID),
 		Roles:     t.Roles,
+
+		Restriction: t.Restriction,
+	}
+}
+
+func (api *profileAPI) withWebhookList(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		res := webhookList{}
+
+		pf := api.srv.GetPageParams(r, 30)
+		if pf == nil {
+			api.srv.Status(w, r, http.StatusNotFound)
+			return
+		}
+
+		ds := webhooks.Webhooks.Query().
+			Where(
+				goqu.C("user_id").Eq(auth.GetRequestUser(r).ID),
+			).
+			Order(goqu.C("created").Desc()).
+			Limit(uint(pf.Limit())).
+			Offset(uint(pf.Offset()))
+
+		count, err := ds.ClearOrder().ClearLimit().ClearOffset().Count()
+		if err != nil {
+			api.srv.Error(w, r, err)
+			return
+		}
+
+		items := []*webhooks.Webhook{}
+		if err := ds.ScanStructs(&items); err != nil {
+			api.srv.Error(w, r, err)
+			return
+		}
+
+		res.Pagination = api.srv.NewPagination(r, int(count), pf.Limit(), pf.Offset())
+
+		res.Items = make([]webhookItem, len(items))
+		for i, item := range items {
+			res.Items[i] = newWebhookItem(api.srv, r, item, ".")
+		}
+
+		ctx := context.WithValue(r.Context(), ctxWebhookListKey{}, res)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (api *profileAPI) withWebhook(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		uid := chi.URLParam(r, "uid")
+		wh, err := webhooks.Webhooks.GetOne(
+			goqu.C("uid").Eq(uid),
+			goqu.C("user_id").Eq(auth.GetRequestUser(r).ID),
+		)
+		if err != nil {
+			api.srv.Status(w, r, http.StatusNotFound)
+			return
+		}
+
+		item := newWebhookItem(api.srv, r, wh, "./..")
+		ctx := context.WithValue(r.Context(), ctxWebhookKey{}, item)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (api *profileAPI) webhookList(w http.ResponseWriter, r *http.Request) {
+	wl := r.Context().Value(ctxWebhookListKey{}).(webhookList)
+
+	api.srv.SendPaginationHeaders(w, r, wl.Pagination)
+	api.srv.Render(w, r, http.StatusOK, wl.Items)
+}
+
+func (api *profileAPI) webhookInfo(w http.ResponseWriter, r *http.Request) {
+	api.srv.Render(w, r, http.StatusOK, r.Context().Value(ctxWebhookKey{}).(webhookItem))
+}
+
+func (api *profileAPI) webhookCreate(w http.ResponseWriter, r *http.Request) {
+	f := newWebhookForm(api.srv.Locale(r), true)
+	forms.Bind(f, r)
+
+	if !f.IsValid() {
+		api.srv.Render(w, r, http.StatusUnprocessableEntity, f)
+		return
+	}
+
+	wh, err := f.createWebhook(auth.GetRequestUser(r).ID, api.srv.AbsoluteURL(r, "/").String())
+	if err != nil {
+		api.srv.Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Location", api.srv.AbsoluteURL(r, ".", wh.UID).String())
+	api.srv.Render(w, r, http.StatusCreated, newWebhookItem(api.srv, r, wh, "."))
+}
+
+func (api *profileAPI) webhookUpdate(w http.ResponseWriter, r *http.Request) {
+	wi := r.Context().Value(ctxWebhookKey{}).(webhookItem)
+	f := newWebhookForm(api.srv.Locale(r), false)
+	forms.Bind(f, r)
+
+	if !f.IsValid() {
+		api.srv.Render(w, r, http.StatusUnprocessableEntity, f)
+		return
+	}
+
+	if err := f.updateWebhook(wi.Webhook, api.srv.AbsoluteURL(r, "/").String()); err != nil {
+		api.srv.Error(w, r, err)
+		return
+	}
+
+	api.srv.Render(w, r, http.StatusOK, newWebhookItem(api.srv, r, wi.Webhook, "./.."))
+}
+
+func (api *profileAPI) webhookDelete(w http.ResponseWriter, r *http.Request) {
+	wi := r.Context().Value(ctxWebhookKey{}).(webhookItem)
+	if err := wi.Delete(); err != nil {
+		api.srv.Error(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (api *profileAPI) webhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	wi := r.Context().Value(ctxWebhookKey{}).(webhookItem)
+
+	pf := api.srv.GetPageParams(r, 50)
+	if pf == nil {
+		api.srv.Status(w, r, http.StatusNotFound)
+		return
+	}
+
+	items, pagination, err := api.getDeliveries(r, wi.Webhook, pf)
+	if err != nil {
+		api.srv.Error(w, r, err)
+		return
+	}
+
+	api.srv.SendPaginationHeaders(w, r, pagination)
+	api.srv.Render(w, r, http.StatusOK, items)
+}
+
+// getDeliveries returns a page of a webhook's deliveries, most recent first.
+func (api *profileAPI) getDeliveries(r *http.Request, wh *webhooks.Webhook, pf *server.PaginationForm) ([]deliveryItem, server.Pagination, error) {
+	ds := webhooks.Deliveries.Query().
+		Where(goqu.C("webhook_id").Eq(wh.ID)).
+		Order(goqu.C("created").Desc(), goqu.C("id").Desc()).
+		Limit(uint(pf.Limit())).
+		Offset(uint(pf.Offset()))
+
+	count, err := ds.ClearOrder().ClearLimit().ClearOffset().Count()
+	if err != nil {
+		return nil, server.Pagination{}, err
+	}
+
+	items := []*webhooks.Delivery{}
+	if err := ds.ScanStructs(&items); err != nil {
+		return nil, server.Pagination{}, err
+	}
+
+	res := make([]deliveryItem, len(items))
+	for i, item := range items {
+		res[i] = newDeliveryItem(item)
+	}
+
+	return res, api.srv.NewPagination(r, int(count), pf.Limit(), pf.Offset()), nil
+}
+
+type webhookList struct {
+	Pagination server.Pagination
+	Items      []webhookItem
+}
+
+type webhookItem struct {
+	*webhooks.Webhook `json:"-"`
+
+	ID        string    `json:"id"`
+	Href      string    `json:"href"`
+	Created   time.Time `json:"created"`
+	Updated   time.Time `json:"updated"`
+	Name      string    `json:"name"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"secret"`
+	IsEnabled bool      `json:"is_enabled"`
+	Events    []string  `json:"events"`
+}
+
+func newWebhookItem(s *server.Server, r *http.Request, wh *webhooks.Webhook, base string) webhookItem {
+	return webhookItem{
+		Webhook:   wh,
+		ID:        wh.UID,
+		Href:      s.AbsoluteURL(r, base, wh.UID).String(),
+		Created:   wh.Created,
+		Updated:   wh.Updated,
+		Name:      wh.Name,
+		URL:       wh.URL,
+		Secret:    wh.Secret,
+		IsEnabled: wh.IsEnabled,
+		Events:    wh.Events,
+	}
+}
+
+type deliveryItem struct {
+	*webhooks.Delivery `json:"-"`
+
+	ID         string          `json:"id"`
+	Created    time.Time       `json:"created"`
+	Updated    time.Time       `json:"updated"`
+	Event      string          `json:"event"`
+	Payload    json.RawMessage `json:"payload"`
+	Attempts   int             `json:"attempts"`
+	StatusCode int             `json:"status_code"`
+	Error      string          `json:"error"`
+}
+
+func newDeliveryItem(d *webhooks.Delivery) deliveryItem {
+	return deliveryItem{
+		Delivery:   d,
+		ID:         d.UID,
+		Created:    d.Created,
+		Updated:    d.Updated,
+		Event:      d.Event,
+		Payload:    json.RawMessage(d.Payload),
+		Attempts:   d.Attempts,
+		StatusCode: d.StatusCode,
+		Error:      d.Error,
+	}
+}
+
+func (api *profileAPI) withRuleList(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		res := ruleList{}
+
+		pf := api.srv.GetPageParams(r, 30)
+		if pf == nil {
+			api.srv.Status(w, r, http.StatusNotFound)
+			return
+		}
+
+		ds := bookmarks.Rules.Query().
+			Where(
+				goqu.C("user_id").Eq(auth.GetRequestUser(r).ID),
+			).
+			Order(goqu.C("created").Asc(), goqu.C("id").Asc()).
+			Limit(uint(pf.Limit())).
+			Offset(uint(pf.Offset()))
+
+		count, err := ds.ClearOrder().ClearLimit().ClearOffset().Count()
+		if err != nil {
+			api.srv.Error(w, r, err)
+			return
+		}
+
+		items := []*bookmarks.Rule{}
+		if err := ds.ScanStructs(&items); err != nil {
+			api.srv.Error(w, r, err)
+			return
+		}
+
+		res.Pagination = api.srv.NewPagination(r, int(count), pf.Limit(), pf.Offset())
+
+		res.Items = make([]ruleItem, len(items))
+		for i, item := range items {
+			res.Items[i] = newRuleItem(api.srv, r, item, ".")
+		}
+
+		ctx := context.WithValue(r.Context(), ctxRuleListKey{}, res)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (api *profileAPI) withRule(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		uid := chi.URLParam(r, "uid")
+		rule, err := bookmarks.Rules.GetOne(
+			goqu.C("uid").Eq(uid),
+			goqu.C("user_id").Eq(auth.GetRequestUser(r).ID),
+		)
+		if err != nil {
+			api.srv.Status(w, r, http.StatusNotFound)
+			return
+		}
+
+		item := newRuleItem(api.srv, r, rule, "./..")
+		ctx := context.WithValue(r.Context(), ctxRuleKey{}, item)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (api *profileAPI) ruleList(w http.ResponseWriter, r *http.Request) {
+	rl := r.Context().Value(ctxRuleListKey{}).(ruleList)
+
+	api.srv.SendPaginationHeaders(w, r, rl.Pagination)
+	api.srv.Render(w, r, http.StatusOK, rl.Items)
+}
+
+func (api *profileAPI) ruleInfo(w http.ResponseWriter, r *http.Request) {
+	api.srv.Render(w, r, http.StatusOK, r.Context().Value(ctxRuleKey{}).(ruleItem))
+}
+
+func (api *profileAPI) ruleCreate(w http.ResponseWriter, r *http.Request) {
+	f := newRuleForm(api.srv.Locale(r), nil)
+	forms.Bind(f, r)
+
+	if !f.IsValid() {
+		api.srv.Render(w, r, http.StatusUnprocessableEntity, f)
+		return
+	}
+
+	rule, err := f.createRule(auth.GetRequestUser(r).ID)
+	if err != nil {
+		api.srv.Error(w, r, err)
+		return
+	}
+
+	item := newRuleItem(api.srv, r, rule, ".")
+	if item.Applied, err = f.applyRule(rule); err != nil {
+		api.srv.Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Location", item.Href)
+	api.srv.Render(w, r, http.StatusCreated, item)
+}
+
+func (api *profileAPI) ruleUpdate(w http.ResponseWriter, r *http.Request) {
+	ri := r.Context().Value(ctxRuleKey{}).(ruleItem)
+	f := newRuleForm(api.srv.Locale(r), ri.Rule)
+	forms.Bind(f, r)
+
+	if !f.IsValid() {
+		api.srv.Render(w, r, http.StatusUnprocessableEntity, f)
+		return
+	}
+
+	if err := f.updateRule(ri.Rule); err != nil {
+		api.srv.Error(w, r, err)
+		return
+	}
+
+	item := newRuleItem(api.srv, r, ri.Rule, "./..")
+	var err error
+	if item.Applied, err = f.applyRule(ri.Rule); err != nil {
+		api.srv.Error(w, r, err)
+		return
+	}
+
+	api.srv.Render(w, r, http.StatusOK, item)
+}
+
+func (api *profileAPI) ruleDelete(w http.ResponseWriter, r *http.Request) {
+	ri := r.Context().Value(ctxRuleKey{}).(ruleItem)
+	if err := ri.Delete(); err != nil {
+		api.srv.Error(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ruleTest runs a rule, without saving it, against the existing bookmarks
+// and returns the ones it would change.
+func (api *profileAPI) ruleTest(w http.ResponseWriter, r *http.Request) {
+	f := newRuleForm(api.srv.Locale(r), nil)
+	forms.Bind(f, r)
+
+	if !f.IsValid() {
+		api.srv.Render(w, r, http.StatusUnprocessableEntity, f)
+		return
+	}
+
+	rule := &bookmarks.Rule{UserID: &auth.GetRequestUser(r).ID}
+	f.bindRule(rule)
+
+	res, err := api.getRuleTest(r, rule)
+	if err != nil {
+		api.srv.Error(w, r, err)
+		return
+	}
+
+	api.srv.Render(w, r, http.StatusOK, res)
+}
+
+// ruleTestLimit is the maximum number of bookmarks in a rule test result.
+const ruleTestLimit = 50
+
+// getRuleTest returns the bookmarks a rule matches and what it would
+// change on them, most recent first.
+func (api *profileAPI) getRuleTest(r *http.Request, rule *bookmarks.Rule) (ruleTestResult, error) {
+	res := ruleTestResult{Bookmarks: []ruleTestItem{}}
+
+	ds := rule.Select()
+	count, err := ds.Count()
+	if err != nil {
+		return res, err
+	}
+	res.Count = int(count)
+
+	items := []*bookmarks.Bookmark{}
+	err = ds.Order(goqu.C("created").Table("b").Desc()).
+		Limit(ruleTestLimit).
+		ScanStructs(&items)
+	if err != nil {
+		return res, err
+	}
+
+	for _, b := range items {
+		res.Bookmarks = append(res.Bookmarks, ruleTestItem{
+			ID:      b.UID,
+			Href:    api.srv.AbsoluteURL(r, "/api/bookmarks", b.UID).String(),
+			Title:   b.Title,
+			URL:     b.URL,
+			Changes: rule.Actions.Changes(b),
+		})
+	}
+
+	return res, nil
+}
+
+type ruleList struct {
+	Pagination server.Pagination
+	Items      []ruleItem
+}
+
+type ruleItem struct {
+	*bookmarks.Rule `json:"-"`
+
+	ID        string                `json:"id"`
+	Href      string                `json:"href"`
+	Created   time.Time             `json:"created"`
+	Updated   time.Time             `json:"updated"`
+	Name      string                `json:"name"`
+	Query     string                `json:"query"`
+	IsEnabled bool                  `json:"is_enabled"`
+	Actions   bookmarks.RuleActions `json:"actions"`
+	Applied   *int                  `json:"applied,omitempty"`
+}
+
+func newRuleItem(s *server.Server, r *http.Request, rule *bookmarks.Rule, base string) ruleItem {
+	res := ruleItem{
+		Rule:      rule,
+		ID:        rule.UID,
+		Href:      s.AbsoluteURL(r, base, rule.UID).String(),
+		Created:   rule.Created,
+		Updated:   rule.Updated,
+		Name:      rule.Name,
+		Query:     rule.Query,
+		IsEnabled: rule.IsEnabled,
+		Actions:   rule.Actions,
+	}
+	if res.Actions.AddLabels == nil {
+		res.Actions.AddLabels = []string{}
+	}
+	return res
+}
+
+type ruleTestResult struct {
+	Count     int            `json:"count"`
+	Bookmarks []ruleTestItem `json:"bookmarks"`
+}
+
+type ruleTestItem struct {
+	ID      string                 `json:"id"`
+	Href    string                 `json:"href"`
+	Title   string                 `json:"title"`
+	URL     string                 `json:"url"`
+	Changes map[string]interface{} `json:"changes"`
+}
+
+// ChangedFields returns the sorted names of the values a rule would change.
+func (i ruleTestItem) ChangedFields() []string {
+	res := make([]string, 0, len(i.Changes))
+	for k := range i.Changes {
+		if k != "read_anchor" {
+			res = append(res, k)
+		}
+	}
+	slices.Sort(res)
+	return res
+}
+
+func (api *profileAPI) withFeedList(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		res := feedList{}
+
+		pf := api.srv.GetPageParams(r, 30)
+		if pf == nil {
+			api.srv.Status(w, r, http.StatusNotFound)
+			return
+		}
+
+		ds := feeds.Feeds.Query().
+			Where(
+				goqu.C("user_id").Eq(auth.GetRequestUser(r).ID),
+			).
+			Order(goqu.C("created").Desc(), goqu.C("id").Desc()).
+			Limit(uint(pf.Limit())).
+			Offset(uint(pf.Offset()))
+
+		count, err := ds.ClearOrder().ClearLimit().ClearOffset().Count()
+		if err != nil {
+			api.srv.Error(w, r, err)
+			return
+		}
+
+		items := []*feeds.Feed{}
+		if err := ds.ScanStructs(&items); err != nil {
+			api.srv.Error(w, r, err)
+			return
+		}
+
+		res.Pagination = api.srv.NewPagination(r, int(count), pf.Limit(), pf.Offset())
+
+		res.Items = make([]feedItem, len(items))
+		for i, item := range items {
+			res.Items[i] = newFeedItem(api.srv, r, item, ".")
+		}
+
+		ctx := context.WithValue(r.Context(), ctxFeedListKey{}, res)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (api *profileAPI) withFeed(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		uid := chi.URLParam(r, "uid")
+		f, err := feeds.Feeds.GetOne(
+			goqu.C("uid").Eq(uid),
+			goqu.C("user_id").Eq(auth.GetRequestUser(r).ID),
+		)
+		if err != nil {
+			api.srv.Status(w, r, http.StatusNotFound)
+			return
+		}
+
+		item := newFeedItem(api.srv, r, f, "./..")
+		ctx := context.WithValue(r.Context(), ctxFeedKey{}, item)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (api *profileAPI) feedList(w http.ResponseWriter, r *http.Request) {
+	fl := r.Context().Value(ctxFeedListKey{}).(feedList)
+
+	api.srv.SendPaginationHeaders(w, r, fl.Pagination)
+	api.srv.Render(w, r, http.StatusOK, fl.Items)
+}
+
+func (api *profileAPI) feedInfo(w http.ResponseWriter, r *http.Request) {
+	api.srv.Render(w, r, http.StatusOK, r.Context().Value(ctxFeedKey{}).(feedItem))
+}
+
+func (api *profileAPI) feedCreate(w http.ResponseWriter, r *http.Request) {
+	f := newFeedForm(api.srv.Locale(r), auth.GetRequestUser(r).ID, nil)
+	forms.Bind(f, r)
+
+	if !f.IsValid() {
+		api.srv.Render(w, r, http.StatusUnprocessableEntity, f)
+		return
+	}
+
+	x, err := f.createFeed()
+	if err != nil {
+		api.srv.Error(w, r, err)
+		return
+	}
+	api.launchFeedFetch(r, x)
+
+	w.Header().Set("Location", api.srv.AbsoluteURL(r, ".", x.UID).String())
+	api.srv.Render(w, r, http.StatusCreated, newFeedItem(api.srv, r, x, "."))
+}
+
+func (api *profileAPI) feedUpdate(w http.ResponseWriter, r *http.Request) {
+	fi := r.Context().Value(ctxFeedKey{}).(feedItem)
+	f := newFeedForm(api.srv.Locale(r), auth.GetRequestUser(r).ID, fi.Feed)
+	forms.Bind(f, r)
+
+	if !f.IsValid() {
+		api.srv.Render(w, r, http.StatusUnprocessableEntity, f)
+		return
+	}
+
+	if err := f.updateFeed(fi.Feed); err != nil {
+		api.srv.Error(w, r, err)
+		return
+	}
+
+	api.srv.Render(w, r, http.StatusOK, newFeedItem(api.srv, r, fi.Feed, "./.."))
+}
+
+func (api *profileAPI) feedDelete(w http.ResponseWriter, r *http.Request) {
+	fi := r.Context().Value(ctxFeedKey{}).(feedItem)
+	if err := fi.Delete(); err != nil {
+		api.srv.Error(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// feedFetch launches the fetch of a feed without waiting
+// for the next poll.
+func (api *profileAPI) feedFetch(w http.ResponseWriter, r *http.Request) {
+	fi := r.Context().Value(ctxFeedKey{}).(feedItem)
+	if err := feeds.FetchTask.Run(fi.Feed.ID, fi.Feed.ID); err != nil {
+		api.srv.Error(w, r, err)
+		return
+	}
+
+	api.srv.Render(w, r, http.StatusAccepted, fi)
+}
+
+// feedExport returns all the user's feeds as an OPML document.
+func (api *profileAPI) feedExport(w http.ResponseWriter, r *http.Request) {
+	buf := new(bytes.Buffer)
+	if err := feeds.ExportOPML(buf, auth.GetRequestUser(r).ID); err != nil {
+		api.srv.Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", opml.MimeType+"; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="readeck-feeds.opml"`)
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes()) //nolint:errcheck
+}
+
+// feedImport creates the feeds of an OPML document and returns them.
+func (api *profileAPI) feedImport(w http.ResponseWriter, r *http.Request) {
+	f := newFeedImportForm(api.srv.Locale(r), auth.GetRequestUser(r).ID)
+	forms.Bind(f, r)
+
+	if !f.IsValid() {
+		api.srv.Render(w, r, http.StatusUnprocessableEntity, f)
+		return
+	}
+
+	items, err := f.importFeeds()
+	if err != nil {
+		api.srv.Error(w, r, err)
+		return
+	}
+	if !f.IsValid() {
+		api.srv.Render(w, r, http.StatusUnprocessableEntity, f)
+		return
+	}
+
+	res := make([]feedItem, len(items))
+	for i, x := range items {
+		api.launchFeedFetch(r, x)
+		res[i] = newFeedItem(api.srv, r, x, "./..")
+	}
+	api.srv.Render(w, r, http.StatusOK, res)
+}
+
+// launchFeedFetch launches the first fetch of a new feed.
+func (api *profileAPI) launchFeedFetch(r *http.Request, f *feeds.Feed) {
+	if err := feeds.FetchTask.Run(f.ID, f.ID); err != nil {
+		api.srv.Log(r).Error("launching feed fetch", slog.Any("err", err))
+	}
+}
+
+type feedList struct {
+	Pagination server.Pagination
+	Items      []feedItem
+}
+
+type feedItem struct {
+	*feeds.Feed `json:"-"`
+
+	ID          string     `json:"id"`
+	Href        string     `json:"href"`
+	Created     time.Time  `json:"created"`
+	Updated     time.Time  `json:"updated"`
+	URL         string     `json:"url"`
+	Title       string     `json:"title"`
+	SiteURL     string     `json:"site_url"`
+	IsEnabled   bool       `json:"is_enabled"`
+	Labels      []string   `json:"labels"`
+	Include     []string   `json:"include"`
+	Exclude     []string   `json:"exclude"`
+	MaxEntries  int        `json:"max_entries"`
+	LastFetched *time.Time `json:"last_fetched"`
+	LastError   string     `json:"last_error"`
+}
+
+func newFeedItem(s *server.Server, r *http.Request, f *feeds.Feed, base string) feedItem {
+	res := feedItem{
+		Feed:        f,
+		ID:          f.UID,
+		Href:        s.AbsoluteURL(r, base, f.UID).String(),
+		Created:     f.Created,
+		Updated:     f.Updated,
+		URL:         f.URL,
+		Title:       f.Title,
+		SiteURL:     f.SiteURL,
+		IsEnabled:   f.IsEnabled,
+		Labels:      []string{},
+		Include:     []string{},
+		Exclude:     []string{},
+		MaxEntries:  f.MaxEntries,
+		LastFetched: f.LastFetched,
+		LastError:   f.LastError,
+	}
+	res.Labels = append(res.Labels, f.Labels...)
+	res.Include = append(res.Include, f.Include...)
+	res.Exclude = append(res.Exclude, f.Exclude...)
+	return res
+}
+
+func (api *profileAPI) withInboundList(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		res := inboundList{}
+
+		pf := api.srv.GetPageParams(r, 30)
+		if pf == nil {
+			api.srv.Status(w, r, http.StatusNotFound)
+			return
+		}
+
+		ds := inbound.Addresses.Query().
+			Where(
+				goqu.C("user_id").Eq(auth.GetRequestUser(r).ID),
+			).
+			Order(goqu.C("created").Desc(), goqu.C("id").Desc()).
+			Limit(uint(pf.Limit())).
+			Offset(uint(pf.Offset()))
+
+		count, err := ds.ClearOrder().ClearLimit().ClearOffset().Count()
+		if err != nil {
+			api.srv.Error(w, r, err)
+			return
+		}
+
+		items := []*inbound.Address{}
+		if err := ds.ScanStructs(&items); err != nil {
+			api.srv.Error(w, r, err)
+			return
+		}
+
+		res.Pagination = api.srv.NewPagination(r, int(count), pf.Limit(), pf.Offset())
+
+		res.Items = make([]inboundItem, len(items))
+		for i, item := range items {
+			res.Items[i] = newInboundItem(api.srv, r, item, ".")
+		}
+
+		ctx := context.WithValue(r.Context(), ctxInboundListKey{}, res)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (api *profileAPI) withInbound(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		uid := chi.URLParam(r, "uid")
+		a, err := inbound.Addresses.GetOne(
+			goqu.C("uid").Eq(uid),
+			goqu.C("user_id").Eq(auth.GetRequestUser(r).ID),
+		)
+		if err != nil {
+			api.srv.Status(w, r, http.StatusNotFound)
+			return
+		}
+
+		item := newInboundItem(api.srv, r, a, "./..")
+		ctx := context.WithValue(r.Context(), ctxInboundKey{}, item)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (api *profileAPI) inboundList(w http.ResponseWriter, r *http.Request) {
+	il := r.Context().Value(ctxInboundListKey{}).(inboundList)
+
+	api.srv.SendPaginationHeaders(w, r, il.Pagination)
+	api.srv.Render(w, r, http.StatusOK, il.Items)
+}
+
+func (api *profileAPI) inboundInfo(w http.ResponseWriter, r *http.Request) {
+	api.srv.Render(w, r, http.StatusOK, r.Context().Value(ctxInboundKey{}).(inboundItem))
+}
+
+func (api *profileAPI) inboundCreate(w http.ResponseWriter, r *http.Request) {
+	f := newInboundForm(api.srv.Locale(r), auth.GetRequestUser(r).ID)
+	forms.Bind(f, r)
+
+	if !f.IsValid() {
+		api.srv.Render(w, r, http.StatusUnprocessableEntity, f)
+		return
+	}
+
+	a, err := f.createAddress()
+	if err != nil {
+		api.srv.Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Location", api.srv.AbsoluteURL(r, ".", a.UID).String())
+	api.srv.Render(w, r, http.StatusCreated, newInboundItem(api.srv, r, a, "."))
+}
+
+func (api *profileAPI) inboundUpdate(w http.ResponseWriter, r *http.Request) {
+	ii := r.Context().Value(ctxInboundKey{}).(inboundItem)
+	f := newInboundForm(api.srv.Locale(r), auth.GetRequestUser(r).ID)
+	forms.Bind(f, r)
+
+	if !f.IsValid() {
+		api.srv.Render(w, r, http.StatusUnprocessableEntity, f)
+		return
+	}
+
+	if err := f.updateAddress(ii.Address); err != nil {
+		api.srv.Error(w, r, err)
+		return
+	}
+
+	api.srv.Render(w, r, http.StatusOK, newInboundItem(api.srv, r, ii.Address, "./.."))
+}
+
+func (api *profileAPI) inboundDelete(w http.ResponseWriter, r *http.Request) {
+	ii := r.Context().Value(ctxInboundKey{}).(inboundItem)
+	if err := ii.Delete(); err != nil {
+		api.srv.Error(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// inboundReset gives a new email address to an inbound address.
+func (api *profileAPI) inboundReset(w http.ResponseWriter, r *http.Request) {
+	ii := r.Context().Value(ctxInboundKey{}).(inboundItem)
+	if err := ii.ResetToken(); err != nil {
+		api.srv.Error(w, r, err)
+		return
+	}
+
+	api.srv.Render(w, r, http.StatusOK, newInboundItem(api.srv, r, ii.Address, "./../.."))
+}
+
+type inboundList struct {
+	Pagination server.Pagination
+	Items      []inboundItem
+}
+
+type inboundItem struct {
+	*inbound.Address `json:"-"`
+
+	ID           string     `json:"id"`
+	Href         string     `json:"href"`
+	Created      time.Time  `json:"created"`
+	Updated      time.Time  `json:"updated"`
+	Name         string     `json:"name"`
+	Token        string     `json:"token"`
+	Email        string     `json:"email"`
+	Labels       []string   `json:"labels"`
+	LastReceived *time.Time `json:"last_received"`
+}
+
+func newInboundItem(s *server.Server, r *http.Request, a *inbound.Address, base string) inboundItem {
+	res := inboundItem{
+		Address:      a,
+		ID:           a.UID,
+		Href:         s.AbsoluteURL(r, base, a.UID).String(),
+		Created:      a.Created,
+		Updated:      a.Updated,
+		Name:         a.Name,
+		Token:        a.Token,
+		Email:        a.Email(),
+		Labels:       []string{},
+		LastReceived: a.LastReceived,
 	}
+	res.Labels = append(res.Labels, a.Labels...)
+	return res
 }
@@ -5,8 +5,15 @@
 package profile_test
 
 import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
 	"testing"
 
+	"github.com/stretchr/testify/require"
+
+	"codeberg.org/readeck/readeck/configs"
+	"codeberg.org/readeck/readeck/internal/auth/tokens"
 	. "codeberg.org/readeck/readeck/internal/testing" //revive:disable:dot-imports
 )
 
@@ -224,3 +231,558 @@ func TestAPIDeleteToken(t *testing.T) {
 		},
 	)
 }
+
+func TestAPIRestriction(t *testing.T) {
+	app := NewTestApp(t)
+	defer func() {
+		app.Close(t)
+	}()
+
+	client := NewClient(t, app)
+
+	// A token restricted to some bookmarks can't use the settings
+	// acting on all of them.
+	u := app.Users["user"]
+	u.Token.Restriction = &tokens.Restriction{Labels: []string{"widget"}}
+	require.NoError(t, u.Token.Save())
+
+	tests := []RequestTest{
+		{Target: "/api/profile", JSON: true, ExpectStatus: 200},
+	}
+	for _, name := range []string{"webhooks", "rules", "feeds", "inbound"} {
+		tests = append(tests,
+			RequestTest{
+				Target:       "/api/profile/" + name,
+				JSON:         true,
+				ExpectStatus: 403,
+			},
+			RequestTest{
+				Method:       "POST",
+				Target:       "/api/profile/" + name,
+				JSON:         map[string]any{"name": "test", "url": "https://example.net/hook"},
+				ExpectStatus: 403,
+			},
+		)
+	}
+	tests = append(tests, RequestTest{
+		Method:       "POST",
+		Target:       "/api/profile/rules/test",
+		JSON:         map[string]any{},
+		ExpectStatus: 403,
+	})
+
+	RunRequestSequence(t, client, "user", tests...)
+}
+
+func TestAPIWebhooks(t *testing.T) {
+	app := NewTestApp(t)
+	defer func() {
+		app.Close(t)
+	}()
+
+	client := NewClient(t, app)
+
+	RunRequestSequence(t, client, "user",
+		RequestTest{
+			JSON:         true,
+			Target:       "/api/profile/webhooks",
+			ExpectStatus: 200,
+			ExpectJSON:   `[]`,
+		},
+		RequestTest{
+			Method:       "POST",
+			Target:       "/api/profile/webhooks",
+			JSON:         map[string]interface{}{"url": "ftp://example.net/"},
+			ExpectStatus: 422,
+		},
+		RequestTest{
+			Method: "POST",
+			Target: "/api/profile/webhooks",
+			JSON: map[string]interface{}{
+				"name":   "test",
+				"url":    "https://example.net/hook",
+				"events": []string{"bookmark.marked"},
+			},
+			ExpectStatus:   201,
+			ExpectRedirect: "/api/profile/webhooks/.+",
+			ExpectJSON: `{
+				"id": "<<PRESENCE>>",
+				"href": "<<PRESENCE>>",
+				"created": "<<PRESENCE>>",
+				"updated": "<<PRESENCE>>",
+				"name": "test",
+				"url": "https://example.net/hook",
+				"secret": "<<PRESENCE>>",
+				"is_enabled": true,
+				"events": ["bookmark.marked"]
+			}`,
+		},
+		RequestTest{
+			JSON:         true,
+			Target:       "{{ (index .History 0).Redirect }}",
+			ExpectStatus: 200,
+			ExpectJSON: `{
+				"id": "<<PRESENCE>>",
+				"href": "<<PRESENCE>>",
+				"created": "<<PRESENCE>>",
+				"updated": "<<PRESENCE>>",
+				"name": "test",
+				"url": "https://example.net/hook",
+				"secret": "<<PRESENCE>>",
+				"is_enabled": true,
+				"events": ["bookmark.marked"]
+			}`,
+		},
+		RequestTest{
+			Method:       "PATCH",
+			Target:       "{{ (index .History 0).Path }}",
+			JSON:         map[string]interface{}{"events": []string{"bookmark.unknown"}},
+			ExpectStatus: 422,
+		},
+		RequestTest{
+			Method: "PATCH",
+			Target: "{{ (index .History 1).Path }}",
+			JSON: map[string]interface{}{
+				"events": []string{"bookmark.marked", "bookmark.archived"},
+			},
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				r.AssertJQ(t, ".url", "https://example.net/hook")
+				r.AssertJQ(t, ".events", []any{"bookmark.marked", "bookmark.archived"})
+			},
+		},
+		RequestTest{
+			JSON:         true,
+			Target:       "{{ (index .History 0).Path }}/deliveries",
+			ExpectStatus: 200,
+			ExpectJSON:   `[]`,
+		},
+
+		// Marking a bookmark creates a delivery
+		RequestTest{
+			Method:       "PATCH",
+			Target:       "/api/bookmarks/" + app.Users["user"].Bookmarks[0].UID,
+			JSON:         map[string]interface{}{"is_marked": true},
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, _ *Response) {
+				names := []string{}
+				for _, x := range Events().Records("task") {
+					evt := map[string]interface{}{}
+					require.NoError(t, json.Unmarshal(x, &evt))
+					names = append(names, evt["name"].(string))
+				}
+				require.Equal(t, []string{"webhook.deliver"}, names)
+			},
+		},
+		RequestTest{
+			JSON:         true,
+			Target:       "{{ (index .History 2).Path }}/deliveries",
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				uid := app.Users["user"].Bookmarks[0].UID
+				r.AssertJQ(t, "length", 1)
+				r.AssertJQ(t, ".[0].event", "bookmark.marked")
+				r.AssertJQ(t, ".[0].attempts", 0.0)
+				r.AssertJQ(t, ".[0].payload.event", "bookmark.marked")
+				r.AssertJQ(t, ".[0].payload.bookmark.id", uid)
+				r.AssertJQ(t, ".[0].payload.bookmark.href", "http://"+r.URL.Host+"/api/bookmarks/"+uid)
+				r.AssertJQ(t, ".[0].payload.bookmark.is_marked", true)
+			},
+		},
+	)
+}
+
+func TestAPIRules(t *testing.T) {
+	app := NewTestApp(t)
+	defer func() {
+		app.Close(t)
+	}()
+
+	client := NewClient(t, app)
+	uid := app.Users["user"].Bookmarks[0].UID
+
+	RunRequestSequence(t, client, "user",
+		RequestTest{
+			JSON:         true,
+			Target:       "/api/profile/rules",
+			ExpectStatus: 200,
+			ExpectJSON:   `[]`,
+		},
+		RequestTest{
+			Method:       "POST",
+			Target:       "/api/profile/rules",
+			JSON:         map[string]interface{}{"query": "label:test"},
+			ExpectStatus: 422,
+			ExpectJSON: `{
+				"is_valid": false,
+				"errors": ["a rule needs at least one action"],
+				"fields": "<<PRESENCE>>"
+			}`,
+		},
+		RequestTest{
+			Method: "POST",
+			Target: "/api/profile/rules/test",
+			JSON: map[string]interface{}{
+				"query":     `label:"test label"`,
+				"is_marked": true,
+			},
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				r.AssertJQ(t, ".count", 1.0)
+				r.AssertJQ(t, ".bookmarks[0].id", uid)
+				r.AssertJQ(t, ".bookmarks[0].changes", map[string]any{"is_marked": true})
+			},
+		},
+		RequestTest{
+			Method: "POST",
+			Target: "/api/profile/rules",
+			JSON: map[string]interface{}{
+				"name":       "test",
+				"query":      `label:"test label"`,
+				"add_labels": []string{"rule"},
+				"apply":      true,
+			},
+			ExpectStatus:   201,
+			ExpectRedirect: "/api/profile/rules/.+",
+			ExpectJSON: `{
+				"id": "<<PRESENCE>>",
+				"href": "<<PRESENCE>>",
+				"created": "<<PRESENCE>>",
+				"updated": "<<PRESENCE>>",
+				"name": "test",
+				"query": "label:\"test label\"",
+				"is_enabled": true,
+				"actions": {
+					"add_labels": ["rule"],
+					"is_marked": null,
+					"is_archived": null,
+					"read_progress": null
+				},
+				"applied": 1
+			}`,
+		},
+		RequestTest{
+			JSON:         true,
+			Target:       "/api/bookmarks/" + uid,
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				r.AssertJQ(t, ".labels", []any{"rule", "test label"})
+			},
+		},
+		RequestTest{
+			JSON:         true,
+			Target:       "{{ (index .History 1).Redirect }}",
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				r.AssertJQ(t, ".name", "test")
+				r.AssertJQ(t, ".applied", nil)
+			},
+		},
+		RequestTest{
+			Method:       "PATCH",
+			Target:       "{{ (index .History 0).Path }}",
+			JSON:         map[string]interface{}{"read_progress": 120},
+			ExpectStatus: 422,
+		},
+		RequestTest{
+			Method: "PATCH",
+			Target: "{{ (index .History 1).Path }}",
+			JSON: map[string]interface{}{
+				"is_enabled":  false,
+				"is_archived": true,
+			},
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				r.AssertJQ(t, ".is_enabled", false)
+				r.AssertJQ(t, ".actions.add_labels", []any{"rule"})
+				r.AssertJQ(t, ".actions.is_archived", true)
+			},
+		},
+		RequestTest{
+			JSON:         true,
+			Target:       "/api/profile/rules",
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				r.AssertJQ(t, "length", 1)
+			},
+		},
+		RequestTest{
+			Method:       "DELETE",
+			JSON:         true,
+			Target:       "{{ (index .History 1).Path }}",
+			ExpectStatus: 204,
+		},
+		RequestTest{
+			JSON:         true,
+			Target:       "{{ (index .History 0).Path }}",
+			ExpectStatus: 404,
+		},
+	)
+}
+
+func TestAPIFeeds(t *testing.T) {
+	app := NewTestApp(t)
+	defer func() {
+		app.Close(t)
+	}()
+
+	client := NewClient(t, app)
+
+	RunRequestSequence(t, client, "user",
+		RequestTest{
+			JSON:         true,
+			Target:       "/api/profile/feeds",
+			ExpectStatus: 200,
+			ExpectJSON:   `[]`,
+		},
+		RequestTest{
+			Method:       "POST",
+			Target:       "/api/profile/feeds",
+			JSON:         map[string]interface{}{"url": "ftp://example.net/feed"},
+			ExpectStatus: 422,
+			Assert: func(t *testing.T, r *Response) {
+				r.AssertJQ(t, ".fields.url.errors | length", 1)
+			},
+		},
+		RequestTest{
+			Method: "POST",
+			Target: "/api/profile/feeds",
+			JSON: map[string]interface{}{
+				"url":     "https://example.net/feed.xml",
+				"labels":  []string{"news"},
+				"exclude": []string{"sponsored"},
+			},
+			ExpectStatus:   201,
+			ExpectRedirect: "/api/profile/feeds/.+",
+			ExpectJSON: `{
+				"id": "<<PRESENCE>>",
+				"href": "<<PRESENCE>>",
+				"created": "<<PRESENCE>>",
+				"updated": "<<PRESENCE>>",
+				"url": "https://example.net/feed.xml",
+				"title": "",
+				"site_url": "",
+				"is_enabled": true,
+				"labels": ["news"],
+				"include": [],
+				"exclude": ["sponsored"],
+				"max_entries": 10,
+				"last_fetched": null,
+				"last_error": ""
+			}`,
+			Assert: func(t *testing.T, _ *Response) {
+				require.Len(t, Events().Records("task"), 1)
+				evt := map[string]any{}
+				require.NoError(t, json.Unmarshal(Events().Records("task")[0], &evt))
+				require.Equal(t, "feed.fetch", evt["name"])
+			},
+		},
+		RequestTest{
+			Method:       "POST",
+			Target:       "/api/profile/feeds",
+			JSON:         map[string]interface{}{"url": "https://example.net/feed.xml"},
+			ExpectStatus: 422,
+			Assert: func(t *testing.T, r *Response) {
+				r.AssertJQ(t, ".fields.url.errors", []any{"You already follow this feed"})
+			},
+		},
+		RequestTest{
+			JSON:         true,
+			Target:       "{{ (index .History 1).Redirect }}",
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				r.AssertJQ(t, ".url", "https://example.net/feed.xml")
+			},
+		},
+		RequestTest{
+			Method:       "PATCH",
+			Target:       "{{ (index .History 0).Path }}",
+			JSON:         map[string]interface{}{"max_entries": 0},
+			ExpectStatus: 422,
+		},
+		RequestTest{
+			Method: "PATCH",
+			Target: "{{ (index .History 1).Path }}",
+			JSON: map[string]interface{}{
+				"title":       "Example",
+				"is_enabled":  false,
+				"max_entries": 5,
+			},
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				r.AssertJQ(t, ".title", "Example")
+				r.AssertJQ(t, ".is_enabled", false)
+				r.AssertJQ(t, ".max_entries", 5.0)
+				r.AssertJQ(t, ".labels", []any{"news"})
+			},
+		},
+		RequestTest{
+			Method:       "POST",
+			JSON:         true,
+			Target:       "{{ (index .History 0).Path }}/fetch",
+			ExpectStatus: 202,
+		},
+		RequestTest{
+			Target:       "/api/profile/feeds/opml",
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				require.Contains(t, r.Header.Get("Content-Type"), "text/x-opml")
+				require.Contains(t, string(r.Body),
+					`<outline text="Example" title="Example" type="rss" xmlUrl="https://example.net/feed.xml" category="news"></outline>`,
+				)
+			},
+		},
+	)
+
+	t.Run("opml import", func(t *testing.T) {
+		app.Users["user"].Login(client)
+		defer client.Logout()
+		defer Events().Clear()
+		csrfToken := client.Get("/profile/feeds").CsrfToken
+
+		body := new(bytes.Buffer)
+		writer := multipart.NewWriter(body)
+		part, _ := writer.CreateFormFile("data", "feeds.opml")
+		_, _ = part.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?>
+		<opml version="2.0"><body>
+			<outline text="Example" xmlUrl="https://example.net/feed.xml" />
+			<outline text="Blog" xmlUrl="https://example.org/atom.xml" category="tech" />
+		</body></opml>`))
+		writer.Close() //nolint:errcheck
+
+		req := client.NewRequest("POST", "/api/profile/feeds/opml", body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		req.Header.Set("Accept", "application/json")
+		req.Header.Set("X-CSRF-Token", csrfToken)
+		rsp := client.Request(req)
+		require.Equal(t, 200, rsp.StatusCode)
+		rsp.AssertJQ(t, "length", 1)
+		rsp.AssertJQ(t, ".[0].url", "https://example.org/atom.xml")
+		rsp.AssertJQ(t, ".[0].labels", []any{"tech"})
+		require.Len(t, Events().Records("task"), 1)
+
+		req = client.NewRequest("POST", "/api/profile/feeds/opml", nil)
+		req.Header.Set("Accept", "application/json")
+		req.Header.Set("X-CSRF-Token", csrfToken)
+		rsp = client.Request(req)
+		require.Equal(t, 422, rsp.StatusCode)
+	})
+
+	RunRequestSequence(t, client, "user",
+		RequestTest{
+			JSON:         true,
+			Target:       "/api/profile/feeds",
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				r.AssertJQ(t, "length", 2)
+			},
+		},
+		RequestTest{
+			Method:       "DELETE",
+			JSON:         true,
+			Target:       `{{ (index .History 0).Path }}/{{ index (index (index .History 0).JSON 0) "id" }}`,
+			ExpectStatus: 204,
+		},
+		RequestTest{
+			JSON:         true,
+			Target:       "{{ (index .History 0).Path }}",
+			ExpectStatus: 404,
+		},
+	)
+}
+
+func TestAPIInbound(t *testing.T) {
+	app := NewTestApp(t)
+	defer func() {
+		app.Close(t)
+	}()
+
+	client := NewClient(t, app)
+	configs.Config.Inbound.Address = "readeck@example.org"
+	defer func() {
+		configs.Config.Inbound.Address = ""
+	}()
+
+	var token string
+	RunRequestSequence(t, client, "user",
+		RequestTest{
+			JSON:         true,
+			Target:       "/api/profile/inbound",
+			ExpectStatus: 200,
+			ExpectJSON:   `[]`,
+		},
+		RequestTest{
+			Method: "POST",
+			Target: "/api/profile/inbound",
+			JSON: map[string]interface{}{
+				"name":   "Newsletters",
+				"labels": []string{"news", "", "news"},
+			},
+			ExpectStatus:   201,
+			ExpectRedirect: "/api/profile/inbound/.+",
+			ExpectJSON: `{
+				"id": "<<PRESENCE>>",
+				"href": "<<PRESENCE>>",
+				"created": "<<PRESENCE>>",
+				"updated": "<<PRESENCE>>",
+				"name": "Newsletters",
+				"token": "<<PRESENCE>>",
+				"email": "<<PRESENCE>>",
+				"labels": ["news"],
+				"last_received": null
+			}`,
+			Assert: func(t *testing.T, r *Response) {
+				token = r.JSON.(map[string]any)["token"].(string)
+				require.Regexp(t, `^[a-z0-9]+$`, token)
+				r.AssertJQ(t, ".email", "readeck+"+token+"@example.org")
+			},
+		},
+		RequestTest{
+			JSON:         true,
+			Target:       "{{ (index .History 0).Redirect }}",
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				r.AssertJQ(t, ".name", "Newsletters")
+			},
+		},
+		RequestTest{
+			Method:       "PATCH",
+			Target:       "{{ (index .History 0).Path }}",
+			JSON:         map[string]interface{}{"labels": []string{}},
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				r.AssertJQ(t, ".name", "Newsletters")
+				r.AssertJQ(t, ".labels", []any{})
+			},
+		},
+		RequestTest{
+			Method:       "POST",
+			JSON:         true,
+			Target:       "{{ (index .History 0).Path }}/reset",
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				r.AssertJQ(t, ".token != \""+token+"\"", true)
+				r.AssertJQ(t, ".email | endswith(\"@example.org\")", true)
+			},
+		},
+		RequestTest{
+			JSON:         true,
+			Target:       "/api/profile/inbound",
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				r.AssertJQ(t, "length", 1)
+			},
+		},
+		RequestTest{
+			Method:       "DELETE",
+			JSON:         true,
+			Target:       `{{ (index .History 0).Path }}/{{ index (index (index .History 0).JSON 0) "id" }}`,
+			ExpectStatus: 204,
+		},
+		RequestTest{
+			JSON:         true,
+			Target:       "{{ (index .History 0).Path }}",
+			ExpectStatus: 404,
+		},
+	)
+}
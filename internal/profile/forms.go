@@ -7,6 +7,8 @@ package profile
 import (
 	"context"
 	"errors"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
@@ -14,9 +16,16 @@ import (
 
 	"codeberg.org/readeck/readeck/internal/auth/tokens"
 	"codeberg.org/readeck/readeck/internal/auth/users"
+	"codeberg.org/readeck/readeck/internal/bookmarks"
+	"codeberg.org/readeck/readeck/internal/db/types"
+	"codeberg.org/readeck/readeck/internal/feeds"
+	"codeberg.org/readeck/readeck/internal/inbound"
+	"codeberg.org/readeck/readeck/internal/searchstring"
 	"codeberg.org/readeck/readeck/internal/sessions"
+	"codeberg.org/readeck/readeck/internal/webhooks"
 	"codeberg.org/readeck/readeck/locales"
 	"codeberg.org/readeck/readeck/pkg/forms"
+	"codeberg.org/readeck/readeck/pkg/opml"
 )
 
 type (
@@ -31,6 +40,8 @@ type profileForm struct {
 var (
 	errInvalidUserOrEmail = forms.Gettext("invalid username and/or email")
 	errInvalidPassword    = forms.Gettext("invalid password")
+	errInvalidTOTP        = forms.Gettext("invalid code")
+	errTOTPRequired       = forms.Gettext("two-factor authentication is required for your account")
 )
 
 // newProfileForm returns a ProfileForm instance.
@@ -227,7 +238,8 @@ func (f *passwordForm) setUser(u *users.User) {
 }
 
 // updatePassword performs the user's password update.
-func (f *passwordForm) updatePassword(u *users.User) (err error) {
+// It signs out all the user's sessions, except the given ones.
+func (f *passwordForm) updatePassword(u *users.User, keep ...string) (err error) {
 	defer func() {
 		if err != nil {
 			f.AddErrors("", forms.ErrUnexpected)
@@ -237,7 +249,10 @@ func (f *passwordForm) updatePassword(u *users.User) (err error) {
 	if err = u.SetPassword(f.Get("password").String()); err != nil {
 		return
 	}
-	err = u.Update(map[string]interface{}{"seed": u.SetSeed()})
+	if err = u.Update(map[string]interface{}{"seed": u.SetSeed()}); err != nil {
+		return
+	}
+	err = sessions.Records.DeleteUser(u.ID, keep...)
 	return
 }
 
@@ -316,13 +331,31 @@ type tokenForm struct {
 }
 
 // tokenForm returns a tokenForm instance.
+// The restriction fields limit the bookmarks the token can access
+// to one of the user's collections, some labels or a search query.
 func newTokenForm(tr forms.Translator, user *users.User) *tokenForm {
+	collections := [][2]string{{"", tr.Gettext("All bookmarks")}}
+	var items []*bookmarks.Collection
+	err := bookmarks.Collections.Query().
+		Select("c.uid", "c.name").
+		Where(goqu.C("user_id").Table("c").Eq(user.ID)).
+		Order(goqu.I("name").Asc()).
+		ScanStructs(&items)
+	if err == nil {
+		for _, c := range items {
+			collections = append(collections, [2]string{c.UID, c.Name})
+		}
+	}
+
 	return &tokenForm{forms.Must(
 		forms.WithTranslator(context.Background(), tr),
 		forms.NewTextField("application", forms.Trim, forms.Required),
 		forms.NewBooleanField("is_enabled", forms.RequiredOrNil),
 		forms.NewDatetimeField("expires"),
 		users.NewRolesField(tr, user),
+		forms.NewTextField("restrict_collection", forms.Trim, forms.ChoicesPairs(collections)),
+		forms.NewTextField("restrict_labels", forms.Trim),
+		forms.NewTextField("restrict_search", forms.Trim),
 	)}
 }
 
@@ -335,10 +368,25 @@ func (f *tokenForm) setToken(t *tokens.Token) {
 	roles := make([]string, len(t.Roles))
 	copy(roles, t.Roles)
 	f.Get("roles").Set(roles)
+
+	if t.Restriction != nil {
+		labels := make([]string, len(t.Restriction.Labels))
+		for i, l := range t.Restriction.Labels {
+			labels[i] = strconv.Quote(l)
+		}
+		f.Get("restrict_collection").Set(t.Restriction.Collection)
+		f.Get("restrict_labels").Set(strings.Join(labels, " "))
+		f.Get("restrict_search").Set(t.Restriction.Search)
+	}
 }
 
 // updateToken performs the token update.
 func (f *tokenForm) updateToken(t *tokens.Token) error {
+	restriction := &tokens.Restriction{}
+	if t.Restriction != nil {
+		*restriction = *t.Restriction
+	}
+
 	for _, field := range f.Fields() {
 		if !field.IsBound() {
 			continue
@@ -361,12 +409,539 @@ func (f *tokenForm) updateToken(t *tokens.Token) error {
 			} else {
 				t.Roles = nil
 			}
+		case "restrict_collection":
+			restriction.Collection = field.String()
+		case "restrict_labels":
+			restriction.Labels = nil
+			for _, term := range searchstring.ParseQuery(field.String()).Terms {
+				restriction.Labels = append(restriction.Labels, term.Value)
+			}
+		case "restrict_search":
+			restriction.Search = field.String()
 		}
 	}
 
+	t.Restriction = nil
+	if !restriction.IsEmpty() {
+		t.Restriction = restriction
+	}
+
 	if err := t.Save(); err != nil {
 		f.AddErrors("", forms.ErrUnexpected)
 		return err
 	}
 	return nil
 }
+
+// webhookForm is the form used to create or update a webhook.
+type webhookForm struct {
+	*forms.Form
+}
+
+// newWebhookForm returns a webhookForm instance. The URL is
+// only required when creating a new webhook.
+func newWebhookForm(tr forms.Translator, create bool) *webhookForm {
+	urlRequired := forms.RequiredOrNil
+	if create {
+		urlRequired = forms.Required
+	}
+
+	return &webhookForm{forms.Must(
+		forms.WithTranslator(context.Background(), tr),
+		forms.NewTextField("name", forms.Trim),
+		forms.NewTextField("url", forms.Trim, urlRequired, forms.IsURL("http", "https")),
+		forms.NewTextListField("events", forms.Choices(
+			forms.Choice(tr.Gettext("Bookmark created"), bookmarks.EventBookmarkCreated),
+			forms.Choice(tr.Gettext("Bookmark loaded"), bookmarks.EventBookmarkLoaded),
+			forms.Choice(tr.Gettext("Bookmark failed to load"), bookmarks.EventBookmarkError),
+			forms.Choice(tr.Gettext("Bookmark archived"), bookmarks.EventBookmarkArchived),
+			forms.Choice(tr.Gettext("Bookmark marked as favorite"), bookmarks.EventBookmarkMarked),
+			forms.Choice(tr.Gettext("Bookmark deleted"), bookmarks.EventBookmarkDeleted),
+			forms.Choice(tr.Gettext("Highlight created"), bookmarks.EventAnnotationCreated),
+			forms.Choice(tr.Gettext("Highlight deleted"), bookmarks.EventAnnotationDeleted),
+		)),
+		forms.NewBooleanField("is_enabled", forms.RequiredOrNil),
+	)}
+}
+
+// setWebhook sets the form's values from an existing webhook.
+func (f *webhookForm) setWebhook(w *webhooks.Webhook) {
+	f.Get("name").Set(w.Name)
+	f.Get("url").Set(w.URL)
+	f.Get("is_enabled").Set(w.IsEnabled)
+
+	events := make([]string, len(w.Events))
+	copy(events, w.Events)
+	f.Get("events").Set(events)
+}
+
+// createWebhook creates a new webhook for the given user.
+// baseURL is the instance's root URL used in the payloads.
+// Without any event in the form, the webhook receives all of them.
+func (f *webhookForm) createWebhook(userID int, baseURL string) (*webhooks.Webhook, error) {
+	w := &webhooks.Webhook{
+		UserID:    &userID,
+		IsEnabled: true,
+		Events:    slices.Clone(webhooks.Events),
+	}
+	f.bindWebhook(w, baseURL)
+
+	if err := webhooks.Webhooks.Create(w); err != nil {
+		f.AddErrors("", forms.ErrUnexpected)
+		return nil, err
+	}
+	return w, nil
+}
+
+// updateWebhook performs the webhook update.
+func (f *webhookForm) updateWebhook(w *webhooks.Webhook, baseURL string) error {
+	f.bindWebhook(w, baseURL)
+
+	if err := w.Save(); err != nil {
+		f.AddErrors("", forms.ErrUnexpected)
+		return err
+	}
+	return nil
+}
+
+func (f *webhookForm) bindWebhook(w *webhooks.Webhook, baseURL string) {
+	w.BaseURL = baseURL
+
+	for _, field := range f.Fields() {
+		if !field.IsBound() {
+			continue
+		}
+		switch field.Name() {
+		case "name":
+			w.Name = field.String()
+		case "url":
+			if !field.IsNil() {
+				w.URL = field.String()
+			}
+		case "is_enabled":
+			if !field.IsNil() {
+				w.IsEnabled = field.(forms.TypedField[bool]).V()
+			}
+		case "events":
+			if field.Value() != nil {
+				w.Events = field.(forms.TypedField[[]string]).V()
+			} else {
+				w.Events = types.Strings{}
+			}
+		}
+	}
+}
+
+// ruleForm is the form used to create or update a rule.
+type ruleForm struct {
+	*forms.Form
+	rule *bookmarks.Rule
+}
+
+// newRuleForm returns a ruleForm instance. The rule is nil when
+// the form creates a new one.
+func newRuleForm(tr forms.Translator, r *bookmarks.Rule) *ruleForm {
+	queryRequired := forms.Required
+	if r != nil {
+		queryRequired = forms.RequiredOrNil
+	}
+
+	return &ruleForm{
+		Form: forms.Must(
+			forms.WithTranslator(context.Background(), tr),
+			forms.NewTextField("name", forms.Trim),
+			forms.NewTextField("query", forms.Trim, queryRequired),
+			forms.NewBooleanField("is_enabled", forms.RequiredOrNil),
+			forms.NewTextListField("add_labels", forms.Trim, forms.DiscardEmpty),
+			forms.NewBooleanField("is_marked"),
+			forms.NewBooleanField("is_archived"),
+			forms.NewIntegerField("read_progress", forms.Gte(0), forms.Lte(100)),
+			forms.NewBooleanField("apply"),
+		),
+		rule: r,
+	}
+}
+
+// Validate checks that the rule ends up with at least one action.
+func (f *ruleForm) Validate() {
+	base := bookmarks.RuleActions{}
+	if f.rule != nil {
+		base = f.rule.Actions
+	}
+	if f.actions(base).IsEmpty() {
+		f.AddErrors("", forms.Gettext("a rule needs at least one action"))
+	}
+}
+
+// setRule sets the form's values from an existing rule.
+func (f *ruleForm) setRule(r *bookmarks.Rule) {
+	f.Get("name").Set(r.Name)
+	f.Get("query").Set(r.Query)
+	f.Get("is_enabled").Set(r.IsEnabled)
+	f.Get("add_labels").Set(slices.Clone([]string(r.Actions.AddLabels)))
+	if r.Actions.IsMarked != nil {
+		f.Get("is_marked").Set(*r.Actions.IsMarked)
+	}
+	if r.Actions.IsArchived != nil {
+		f.Get("is_archived").Set(*r.Actions.IsArchived)
+	}
+	if r.Actions.ReadProgress != nil {
+		f.Get("read_progress").Set(*r.Actions.ReadProgress)
+	}
+}
+
+// actions returns the given actions with the form's bound values.
+func (f *ruleForm) actions(a bookmarks.RuleActions) bookmarks.RuleActions {
+	for _, field := range f.Fields() {
+		if !field.IsBound() {
+			continue
+		}
+		switch field.Name() {
+		case "add_labels":
+			a.AddLabels = types.Strings{}
+			if !field.IsNil() {
+				a.AddLabels = field.(forms.TypedField[[]string]).V()
+			}
+		case "is_marked":
+			a.IsMarked = nil
+			if !field.IsNil() {
+				a.IsMarked = new(bool)
+				*a.IsMarked = field.(forms.TypedField[bool]).V()
+			}
+		case "is_archived":
+			a.IsArchived = nil
+			if !field.IsNil() {
+				a.IsArchived = new(bool)
+				*a.IsArchived = field.(forms.TypedField[bool]).V()
+			}
+		case "read_progress":
+			a.ReadProgress = nil
+			if !field.IsNil() {
+				a.ReadProgress = new(int)
+				*a.ReadProgress = field.(forms.TypedField[int]).V()
+			}
+		}
+	}
+	return a
+}
+
+// bindRule sets the rule's values from the form.
+func (f *ruleForm) bindRule(r *bookmarks.Rule) {
+	for _, field := range f.Fields() {
+		if !field.IsBound() || field.IsNil() {
+			continue
+		}
+		switch field.Name() {
+		case "name":
+			r.Name = field.String()
+		case "query":
+			r.Query = field.String()
+		case "is_enabled":
+			r.IsEnabled = field.(forms.TypedField[bool]).V()
+		}
+	}
+	r.Actions = f.actions(r.Actions)
+}
+
+// createRule creates a new rule for the given user.
+func (f *ruleForm) createRule(userID int) (*bookmarks.Rule, error) {
+	r := &bookmarks.Rule{
+		UserID:    &userID,
+		IsEnabled: true,
+	}
+	f.bindRule(r)
+
+	if err := bookmarks.Rules.Create(r); err != nil {
+		f.AddErrors("", forms.ErrUnexpected)
+		return nil, err
+	}
+	return r, nil
+}
+
+// updateRule performs the rule update.
+func (f *ruleForm) updateRule(r *bookmarks.Rule) error {
+	f.bindRule(r)
+
+	if err := r.Save(); err != nil {
+		f.AddErrors("", forms.ErrUnexpected)
+		return err
+	}
+	return nil
+}
+
+// applyRule runs the rule on the existing bookmarks when the form
+// asks for it. It returns the number of changed bookmarks, or nil
+// when the rule was not applied.
+func (f *ruleForm) applyRule(r *bookmarks.Rule) (*int, error) {
+	if !f.Get("apply").(forms.TypedField[bool]).V() {
+		return nil, nil
+	}
+
+	items, err := r.Apply()
+	if err != nil {
+		f.AddErrors("", forms.ErrUnexpected)
+		return nil, err
+	}
+	n := len(items)
+	return &n, nil
+}
+
+// feedForm is the form used to create or update a feed.
+type feedForm struct {
+	*forms.Form
+	userID int
+	feed   *feeds.Feed
+}
+
+// newFeedForm returns a feedForm instance. The URL is only
+// required when creating a new feed.
+func newFeedForm(tr forms.Translator, userID int, f *feeds.Feed) *feedForm {
+	urlRequired := forms.Required
+	if f != nil {
+		urlRequired = forms.RequiredOrNil
+	}
+
+	return &feedForm{
+		Form: forms.Must(
+			forms.WithTranslator(context.Background(), tr),
+			forms.NewTextField("url", forms.Trim, urlRequired, forms.IsURL("http", "https")),
+			forms.NewTextField("title", forms.Trim),
+			forms.NewBooleanField("is_enabled", forms.RequiredOrNil),
+			forms.NewTextListField("labels", forms.Trim, forms.DiscardEmpty),
+			forms.NewTextListField("include", forms.Trim, forms.DiscardEmpty),
+			forms.NewTextListField("exclude", forms.Trim, forms.DiscardEmpty),
+			forms.NewIntegerField("max_entries", forms.Gte(1), forms.Lte(100)),
+		),
+		userID: userID,
+		feed:   f,
+	}
+}
+
+// Validate checks that the user doesn't already follow the feed's URL.
+func (f *feedForm) Validate() {
+	field := f.Get("url")
+	if !field.IsValid() || field.IsNil() || (f.feed != nil && f.feed.URL == field.String()) {
+		return
+	}
+
+	exists, err := feeds.Feeds.Exists(f.userID, field.String())
+	if err != nil {
+		f.AddErrors("", forms.ErrUnexpected)
+		return
+	}
+	if exists {
+		f.AddErrors("url", forms.Gettext("You already follow this feed"))
+	}
+}
+
+// setFeed sets the form's values from an existing feed.
+func (f *feedForm) setFeed(x *feeds.Feed) {
+	f.Get("url").Set(x.URL)
+	f.Get("title").Set(x.Title)
+	f.Get("is_enabled").Set(x.IsEnabled)
+	f.Get("labels").Set(slices.Clone([]string(x.Labels)))
+	f.Get("include").Set(slices.Clone([]string(x.Include)))
+	f.Get("exclude").Set(slices.Clone([]string(x.Exclude)))
+	f.Get("max_entries").Set(x.MaxEntries)
+}
+
+// bindFeed sets the feed's values from the form.
+func (f *feedForm) bindFeed(x *feeds.Feed) {
+	for _, field := range f.Fields() {
+		if !field.IsBound() {
+			continue
+		}
+		switch field.Name() {
+		case "url":
+			if !field.IsNil() && field.String() != x.URL {
+				x.URL = field.String()
+				x.ETag = ""
+				x.LastModified = ""
+			}
+		case "title":
+			x.Title = field.String()
+		case "is_enabled":
+			if !field.IsNil() {
+				x.IsEnabled = field.(forms.TypedField[bool]).V()
+			}
+		case "labels", "include", "exclude":
+			v := types.Strings{}
+			if !field.IsNil() {
+				v = append(v, field.(forms.TypedField[[]string]).V()...)
+			}
+			switch field.Name() {
+			case "labels":
+				slices.Sort(v)
+				x.Labels = slices.Compact(v)
+			case "include":
+				x.Include = v
+			case "exclude":
+				x.Exclude = v
+			}
+		case "max_entries":
+			if !field.IsNil() {
+				x.MaxEntries = field.(forms.TypedField[int]).V()
+			}
+		}
+	}
+}
+
+// createFeed creates a new feed for the form's user.
+func (f *feedForm) createFeed() (*feeds.Feed, error) {
+	x := &feeds.Feed{
+		UserID:    &f.userID,
+		IsEnabled: true,
+	}
+	f.bindFeed(x)
+
+	if err := feeds.Feeds.Create(x); err != nil {
+		f.AddErrors("", forms.ErrUnexpected)
+		return nil, err
+	}
+	return x, nil
+}
+
+// updateFeed performs the feed update.
+func (f *feedForm) updateFeed(x *feeds.Feed) error {
+	f.bindFeed(x)
+
+	if err := x.Save(); err != nil {
+		f.AddErrors("", forms.ErrUnexpected)
+		return err
+	}
+	return nil
+}
+
+// feedImportForm is the form used to import an OPML file.
+type feedImportForm struct {
+	*forms.Form
+	userID int
+}
+
+func newFeedImportForm(tr forms.Translator, userID int) *feedImportForm {
+	return &feedImportForm{
+		Form: forms.Must(
+			forms.WithTranslator(context.Background(), tr),
+			forms.NewFileField("data", forms.Required),
+		),
+		userID: userID,
+	}
+}
+
+// importFeeds creates the feeds found in the OPML file.
+func (f *feedImportForm) importFeeds() ([]*feeds.Feed, error) {
+	reader, err := f.Get("data").(*forms.FileField).V().Open()
+	if err != nil {
+		f.AddErrors("", forms.ErrUnexpected)
+		return nil, err
+	}
+	defer reader.Close() //nolint:errcheck
+
+	doc, err := opml.Parse(reader)
+	if err != nil {
+		f.AddErrors("data", forms.Gettext("Unable to read OPML content"), err)
+		return nil, nil
+	}
+
+	res, err := feeds.ImportOPML(doc, f.userID)
+	if err != nil {
+		f.AddErrors("", forms.ErrUnexpected)
+	}
+	return res, err
+}
+
+// inboundForm is the form used to create or update an inbound address.
+type inboundForm struct {
+	*forms.Form
+	userID int
+}
+
+// newInboundForm returns an inboundForm instance.
+func newInboundForm(tr forms.Translator, userID int) *inboundForm {
+	return &inboundForm{
+		Form: forms.Must(
+			forms.WithTranslator(context.Background(), tr),
+			forms.NewTextField("name", forms.Trim),
+			forms.NewTextListField("labels", forms.Trim, forms.DiscardEmpty),
+		),
+		userID: userID,
+	}
+}
+
+// setAddress sets the form's values from an existing address.
+func (f *inboundForm) setAddress(a *inbound.Address) {
+	f.Get("name").Set(a.Name)
+	f.Get("labels").Set(slices.Clone([]string(a.Labels)))
+}
+
+// bindAddress sets the address' values from the form.
+func (f *inboundForm) bindAddress(a *inbound.Address) {
+	for _, field := range f.Fields() {
+		if !field.IsBound() {
+			continue
+		}
+		switch field.Name() {
+		case "name":
+			a.Name = field.String()
+		case "labels":
+			v := types.Strings{}
+			if !field.IsNil() {
+				v = append(v, field.(forms.TypedField[[]string]).V()...)
+			}
+			slices.Sort(v)
+			a.Labels = slices.Compact(v)
+		}
+	}
+}
+
+// createAddress creates a new inbound address for the form's user.
+func (f *inboundForm) createAddress() (*inbound.Address, error) {
+	a := &inbound.Address{UserID: &f.userID}
+	f.bindAddress(a)
+
+	if err := inbound.Addresses.Create(a); err != nil {
+		f.AddErrors("", forms.ErrUnexpected)
+		return nil, err
+	}
+	return a, nil
+}
+
+// updateAddress performs the address update.
+func (f *inboundForm) updateAddress(a *inbound.Address) error {
+	f.bindAddress(a)
+
+	if err := a.Save(); err != nil {
+		f.AddErrors("", forms.ErrUnexpected)
+		return err
+	}
+	return nil
+}
+
+// totpForm confirms a two-factor authentication change with a code
+// from the authenticator application or a recovery code.
+type totpForm struct {
+	*forms.Form
+}
+
+func newTOTPForm(tr forms.Translator) *totpForm {
+	return &totpForm{forms.Must(
+		forms.WithTranslator(context.Background(), tr),
+		forms.NewTextField("code", forms.Trim, forms.Required),
+	)}
+}
+
+// verify checks the code with the user's authenticator.
+func (f *totpForm) verify(a *users.Authenticator) error {
+	if !f.IsValid() {
+		return nil
+	}
+
+	ok, err := a.Verify(f.Get("code").String())
+	if err != nil {
+		return err
+	}
+	if !ok {
+		f.AddErrors("code", errInvalidTOTP)
+	}
+	return nil
+}
@@ -21,6 +21,7 @@ func init() {
 	bus.OnReady(func() {
 		deleteTokenTask = bus.Tasks().NewTask(
 			"token.delete",
+			superbus.WithTaskQueue(bus.QueueMaintenance),
 			superbus.WithTaskDelay(20),
 			superbus.WithUnmarshall(func(data []byte) interface{} {
 				var res int
@@ -5,15 +5,22 @@
 package profile
 
 import (
+	"cmp"
+	"errors"
 	"log/slog"
 	"net/http"
+	"time"
 
+	"github.com/doug-martin/goqu/v9"
 	"github.com/go-chi/chi/v5"
 
 	"codeberg.org/readeck/readeck/configs"
 	"codeberg.org/readeck/readeck/internal/auth"
 	"codeberg.org/readeck/readeck/internal/auth/tokens"
+	"codeberg.org/readeck/readeck/internal/auth/users"
+	"codeberg.org/readeck/readeck/internal/feeds"
 	"codeberg.org/readeck/readeck/internal/server"
+	"codeberg.org/readeck/readeck/internal/sessions"
 	"codeberg.org/readeck/readeck/pkg/forms"
 )
 
@@ -31,12 +38,19 @@ func newProfileViews(api *profileAPI) *profileViews {
 	r.With(api.srv.WithPermission("profile", "read")).Group(func(r chi.Router) {
 		r.Get("/", v.userProfile)
 		r.Get("/password", v.userPassword)
+		r.Get("/totp", v.userTOTP)
+		r.Get("/sessions", v.sessionList)
 	})
 
 	r.With(api.srv.WithPermission("profile", "write")).Group(func(r chi.Router) {
 		r.Post("/", v.userProfile)
 		r.Post("/password", v.userPassword)
 		r.Post("/session", v.userSession)
+		r.Post("/totp", v.userTOTP)
+		r.Post("/totp/recovery", v.userTOTPRecovery)
+		r.Post("/totp/delete", v.userTOTPDelete)
+		r.Post("/sessions/delete", v.sessionDeleteAll)
+		r.Post("/sessions/{uid}/delete", v.sessionDelete)
 	})
 
 	r.With(api.srv.WithPermission("profile:tokens", "read")).Group(func(r chi.Router) {
@@ -50,6 +64,53 @@ func newProfileViews(api *profileAPI) *profileViews {
 		r.With(api.withToken).Post("/tokens/{uid}/delete", v.tokenDelete)
 	})
 
+	r.With(api.srv.WithPermission("profile:webhooks", "read")).Group(func(r chi.Router) {
+		r.With(api.withWebhookList).Get("/webhooks", v.webhookList)
+		r.With(api.withWebhook).Get("/webhooks/{uid}", v.webhookInfo)
+	})
+
+	r.With(api.srv.WithPermission("profile:webhooks", "write")).Group(func(r chi.Router) {
+		r.With(api.withWebhookList).Post("/webhooks", v.webhookList)
+		r.With(api.withWebhook).Post("/webhooks/{uid}", v.webhookInfo)
+		r.With(api.withWebhook).Post("/webhooks/{uid}/delete", v.webhookDelete)
+	})
+
+	r.With(api.srv.WithPermission("profile:rules", "read")).Group(func(r chi.Router) {
+		r.With(api.withRuleList).Get("/rules", v.ruleList)
+		r.With(api.withRule).Get("/rules/{uid}", v.ruleInfo)
+	})
+
+	r.With(api.srv.WithPermission("profile:rules", "write")).Group(func(r chi.Router) {
+		r.With(api.withRuleList).Post("/rules", v.ruleList)
+		r.With(api.withRule).Post("/rules/{uid}", v.ruleInfo)
+		r.With(api.withRule).Post("/rules/{uid}/delete", v.ruleDelete)
+	})
+
+	r.With(api.srv.WithPermission("profile:feeds", "read")).Group(func(r chi.Router) {
+		r.With(api.withFeedList).Get("/feeds", v.feedList)
+		r.With(api.withFeed).Get("/feeds/{uid}", v.feedInfo)
+	})
+
+	r.With(api.srv.WithPermission("profile:feeds", "write")).Group(func(r chi.Router) {
+		r.With(api.withFeedList).Post("/feeds", v.feedList)
+		r.With(api.withFeedList).Post("/feeds/import", v.feedImport)
+		r.With(api.withFeed).Post("/feeds/{uid}", v.feedInfo)
+		r.With(api.withFeed).Post("/feeds/{uid}/fetch", v.feedFetch)
+		r.With(api.withFeed).Post("/feeds/{uid}/delete", v.feedDelete)
+	})
+
+	r.With(api.srv.WithPermission("profile:inbound", "read")).Group(func(r chi.Router) {
+		r.With(api.withInboundList).Get("/inbound", v.inboundList)
+		r.With(api.withInbound).Get("/inbound/{uid}", v.inboundInfo)
+	})
+
+	r.With(api.srv.WithPermission("profile:inbound", "write")).Group(func(r chi.Router) {
+		r.With(api.withInboundList).Post("/inbound", v.inboundList)
+		r.With(api.withInbound).Post("/inbound/{uid}", v.inboundInfo)
+		r.With(api.withInbound).Post("/inbound/{uid}/reset", v.inboundReset)
+		r.With(api.withInbound).Post("/inbound/{uid}/delete", v.inboundDelete)
+	})
+
 	return v
 }
 
@@ -99,12 +160,12 @@ func (v *profileViews) userPassword(w http.ResponseWriter, r *http.Request) {
 		f.setUser(user)
 		forms.Bind(f, r)
 		if f.IsValid() {
-			if err := f.updatePassword(user); err != nil {
+			sess := v.srv.GetSession(r)
+			if err := f.updatePassword(user, sess.Payload.ID); err != nil {
 				v.srv.Log(r).Error("", slog.Any("err", err))
 			} else {
 				// Set the new seed in the session.
 				// We needn't save the session since AddFlash does it already.
-				sess := v.srv.GetSession(r)
 				sess.Payload.Seed = user.Seed
 				v.srv.AddFlash(w, r, "success", tr.Gettext("Your password was changed."))
 				v.srv.Redirect(w, r, "password")
@@ -124,12 +185,146 @@ func (v *profileViews) userPassword(w http.ResponseWriter, r *http.Request) {
 	v.srv.RenderTemplate(w, r, 200, "profile/password", ctx)
 }
 
+// userTOTP handles GET and POST requests on /profile/totp. A user
+// without two-factor authentication gets a new authenticator that's
+// enabled once a first code is confirmed.
+func (v *profileViews) userTOTP(w http.ResponseWriter, r *http.Request) {
+	tr := v.srv.Locale(r)
+	user := auth.GetRequestUser(r)
+	f := newTOTPForm(tr)
+
+	// A pending authenticator gets a new secret each time the page
+	// is displayed.
+	a, err := users.Authenticators.ForUser(user)
+	if errors.Is(err, users.ErrNotFound) || (err == nil && !a.IsEnabled() && r.Method == http.MethodGet) {
+		a, err = users.Authenticators.Enroll(user)
+	}
+	if err != nil {
+		v.srv.Error(w, r, err)
+		return
+	}
+
+	status := http.StatusOK
+	if r.Method == http.MethodPost && !a.IsEnabled() {
+		forms.Bind(f, r)
+		if err = f.verify(a); err != nil {
+			v.srv.Error(w, r, err)
+			return
+		}
+		if f.IsValid() {
+			codes, err := a.Enable()
+			if err != nil {
+				v.srv.Error(w, r, err)
+				return
+			}
+			v.renderTOTP(w, r, http.StatusOK, server.TC{"RecoveryCodes": codes})
+			return
+		}
+		status = http.StatusUnprocessableEntity
+	}
+
+	ctx := server.TC{
+		"Form":          f,
+		"Authenticator": a,
+		"Required":      user.TOTPRequired(),
+	}
+	if !a.IsEnabled() {
+		if ctx["URL"], err = a.URL(user); err != nil {
+			v.srv.Error(w, r, err)
+			return
+		}
+		if ctx["Secret"], err = a.EncodedSecret(); err != nil {
+			v.srv.Error(w, r, err)
+			return
+		}
+	}
+	v.renderTOTP(w, r, status, ctx)
+}
+
+// userTOTPRecovery replaces the user's recovery codes.
+func (v *profileViews) userTOTPRecovery(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetRequestUser(r)
+	a, err := users.Authenticators.ForUser(user)
+	if err != nil || !a.IsEnabled() {
+		v.srv.Redirect(w, r, "/profile/totp")
+		return
+	}
+
+	f := newTOTPForm(v.srv.Locale(r))
+	forms.Bind(f, r)
+	if err = f.verify(a); err != nil {
+		v.srv.Error(w, r, err)
+		return
+	}
+	if !f.IsValid() {
+		v.renderTOTP(w, r, http.StatusUnprocessableEntity, server.TC{
+			"Form":          f,
+			"Authenticator": a,
+			"Required":      user.TOTPRequired(),
+		})
+		return
+	}
+
+	codes, err := a.NewRecoveryCodes()
+	if err != nil {
+		v.srv.Error(w, r, err)
+		return
+	}
+	v.renderTOTP(w, r, http.StatusOK, server.TC{"RecoveryCodes": codes})
+}
+
+// userTOTPDelete disables two-factor authentication, unless the
+// user's group requires it.
+func (v *profileViews) userTOTPDelete(w http.ResponseWriter, r *http.Request) {
+	tr := v.srv.Locale(r)
+	user := auth.GetRequestUser(r)
+	a, err := users.Authenticators.ForUser(user)
+	if err != nil || !a.IsEnabled() {
+		v.srv.Redirect(w, r, "/profile/totp")
+		return
+	}
+
+	f := newTOTPForm(tr)
+	forms.Bind(f, r)
+	if user.TOTPRequired() {
+		f.AddErrors("", errTOTPRequired)
+	}
+	if err = f.verify(a); err != nil {
+		v.srv.Error(w, r, err)
+		return
+	}
+	if !f.IsValid() {
+		v.renderTOTP(w, r, http.StatusUnprocessableEntity, server.TC{
+			"Form":          f,
+			"Authenticator": a,
+			"Required":      user.TOTPRequired(),
+		})
+		return
+	}
+
+	if err = a.Delete(); err != nil {
+		v.srv.Error(w, r, err)
+		return
+	}
+	v.srv.AddFlash(w, r, "success", tr.Gettext("Two-factor authentication is disabled."))
+	v.srv.Redirect(w, r, "/profile/totp")
+}
+
+func (v *profileViews) renderTOTP(w http.ResponseWriter, r *http.Request, status int, ctx server.TC) {
+	tr := v.srv.Locale(r)
+	ctx.SetBreadcrumbs([][2]string{
+		{tr.Gettext("Profile"), v.srv.AbsoluteURL(r, "/profile").String()},
+		{tr.Gettext("Two-factor authentication")},
+	})
+	v.srv.RenderTemplate(w, r, status, "profile/totp", ctx)
+}
+
 // userSession handles changes of user session preferences.
-// This returns an API response but since it only works with a SessionAuthProvider
+// This returns an API response but since it only works with a session
 // it makes more sense to have it in the views.
 func (v *profileViews) userSession(w http.ResponseWriter, r *http.Request) {
-	p, ok := auth.GetRequestProvider(r).(*auth.SessionAuthProvider)
-	if !ok {
+	sess := v.srv.GetSession(r)
+	if sess == nil {
 		v.srv.TextMessage(w, r, http.StatusBadRequest, "invalid authentication provider")
 		return
 	}
@@ -142,7 +337,6 @@ func (v *profileViews) userSession(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	sess := p.GetSession(r)
 	updated, err := f.updateSession(sess.Payload)
 	if err != nil {
 		v.srv.Error(w, r, err)
@@ -153,6 +347,93 @@ func (v *profileViews) userSession(w http.ResponseWriter, r *http.Request) {
 	v.srv.Render(w, r, http.StatusOK, updated)
 }
 
+// sessionList shows the user's active sessions.
+func (v *profileViews) sessionList(w http.ResponseWriter, r *http.Request) {
+	tr := v.srv.Locale(r)
+	user := auth.GetRequestUser(r)
+	sess := v.srv.GetSession(r)
+	if sess == nil {
+		v.srv.Status(w, r, http.StatusNotFound)
+		return
+	}
+
+	maxAge := time.Duration(configs.Config.Server.Session.MaxAge) * time.Second
+	items, err := sessions.Records.ForUser(user.ID, time.Now().Add(-maxAge))
+	if err != nil {
+		v.srv.Error(w, r, err)
+		return
+	}
+
+	ctx := server.TC{
+		"Sessions": items,
+		"Current":  sess.Payload.ID,
+	}
+	ctx.SetBreadcrumbs([][2]string{
+		{tr.Gettext("Profile"), v.srv.AbsoluteURL(r, "/profile").String()},
+		{tr.Gettext("Sessions")},
+	})
+
+	v.srv.RenderTemplate(w, r, 200, "profile/session_list", ctx)
+}
+
+// sessionDelete signs out one of the user's sessions.
+func (v *profileViews) sessionDelete(w http.ResponseWriter, r *http.Request) {
+	tr := v.srv.Locale(r)
+	user := auth.GetRequestUser(r)
+	sess := v.srv.GetSession(r)
+	if sess == nil {
+		v.srv.Status(w, r, http.StatusNotFound)
+		return
+	}
+
+	rec, err := sessions.Records.GetOne(
+		goqu.C("uid").Eq(chi.URLParam(r, "uid")),
+		goqu.C("user_id").Eq(user.ID),
+	)
+	if errors.Is(err, sessions.ErrNotFound) {
+		v.srv.Status(w, r, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		v.srv.Error(w, r, err)
+		return
+	}
+
+	if rec.UID == sess.Payload.ID {
+		sess.Clear(w, r)
+		v.srv.RenewCsrf(w, r)
+		v.srv.Redirect(w, r, "/login")
+		return
+	}
+
+	if err = rec.Delete(); err != nil {
+		v.srv.Error(w, r, err)
+		return
+	}
+	v.srv.AddFlash(w, r, "success", tr.Gettext("The device was signed out."))
+	v.srv.Redirect(w, r, "/profile/sessions")
+}
+
+// sessionDeleteAll signs out all the user's sessions,
+// including the current one.
+func (v *profileViews) sessionDeleteAll(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetRequestUser(r)
+	sess := v.srv.GetSession(r)
+	if sess == nil {
+		v.srv.Status(w, r, http.StatusNotFound)
+		return
+	}
+
+	if err := sessions.Records.DeleteUser(user.ID); err != nil {
+		v.srv.Error(w, r, err)
+		return
+	}
+
+	sess.Clear(w, r)
+	v.srv.RenewCsrf(w, r)
+	v.srv.Redirect(w, r, "/login")
+}
+
 func (v *profileViews) tokenList(w http.ResponseWriter, r *http.Request) {
 	tl := r.Context().Value(ctxTokenListKey{}).(tokenList)
 	tr := v.srv.Locale(r)
@@ -243,3 +524,431 @@ func (v *profileViews) tokenDelete(w http.ResponseWriter, r *http.Request) {
 	}
 	v.srv.Redirect(w, r, f.Get("_to").String())
 }
+
+func (v *profileViews) webhookList(w http.ResponseWriter, r *http.Request) {
+	wl := r.Context().Value(ctxWebhookListKey{}).(webhookList)
+	tr := v.srv.Locale(r)
+	f := newWebhookForm(tr, true)
+
+	if r.Method == http.MethodPost {
+		forms.Bind(f, r)
+		if f.IsValid() {
+			wh, err := f.createWebhook(auth.GetRequestUser(r).ID, v.srv.AbsoluteURL(r, "/").String())
+			if err != nil {
+				v.srv.Log(r).Error("", slog.Any("err", err))
+			} else {
+				v.srv.AddFlash(w, r, "success", tr.Gettext("New webhook created."))
+				v.srv.Redirect(w, r, ".", wh.UID)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	}
+
+	ctx := server.TC{
+		"Pagination": wl.Pagination,
+		"Webhooks":   wl.Items,
+		"Form":       f,
+	}
+	ctx.SetBreadcrumbs([][2]string{
+		{tr.Gettext("Profile"), v.srv.AbsoluteURL(r, "/profile").String()},
+		{tr.Gettext("Webhooks")},
+	})
+
+	v.srv.RenderTemplate(w, r, 200, "profile/webhook_list", ctx)
+}
+
+func (v *profileViews) webhookInfo(w http.ResponseWriter, r *http.Request) {
+	tr := v.srv.Locale(r)
+	wi := r.Context().Value(ctxWebhookKey{}).(webhookItem)
+	f := newWebhookForm(tr, false)
+
+	if r.Method == http.MethodGet {
+		f.setWebhook(wi.Webhook)
+	}
+
+	if r.Method == http.MethodPost {
+		forms.Bind(f, r)
+		if f.IsValid() {
+			if err := f.updateWebhook(wi.Webhook, v.srv.AbsoluteURL(r, "/").String()); err != nil {
+				v.srv.Log(r).Error("", slog.Any("err", err))
+			} else {
+				v.srv.AddFlash(w, r, "success", tr.Gettext("Webhook was updated."))
+				v.srv.Redirect(w, r, wi.UID)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	}
+
+	pf := v.srv.GetPageParams(r, 20)
+	if pf == nil {
+		v.srv.Status(w, r, http.StatusNotFound)
+		return
+	}
+	deliveries, pagination, err := v.getDeliveries(r, wi.Webhook, pf)
+	if err != nil {
+		v.srv.Error(w, r, err)
+		return
+	}
+
+	ctx := server.TC{
+		"Webhook":    wi,
+		"Form":       f,
+		"Deliveries": deliveries,
+		"Pagination": pagination,
+	}
+	ctx.SetBreadcrumbs([][2]string{
+		{tr.Gettext("Profile"), v.srv.AbsoluteURL(r, "/profile").String()},
+		{tr.Gettext("Webhooks"), v.srv.AbsoluteURL(r, "/profile/webhooks").String()},
+		{cmp.Or(wi.Name, wi.UID)},
+	})
+
+	v.srv.RenderTemplate(w, r, 200, "profile/webhook", ctx)
+}
+
+func (v *profileViews) webhookDelete(w http.ResponseWriter, r *http.Request) {
+	tr := v.srv.Locale(r)
+	wi := r.Context().Value(ctxWebhookKey{}).(webhookItem)
+
+	if err := wi.Delete(); err != nil {
+		v.srv.Error(w, r, err)
+		return
+	}
+
+	v.srv.AddFlash(w, r, "success", tr.Gettext("Webhook was removed."))
+	v.srv.Redirect(w, r, "/profile/webhooks")
+}
+
+func (v *profileViews) ruleList(w http.ResponseWriter, r *http.Request) {
+	rl := r.Context().Value(ctxRuleListKey{}).(ruleList)
+	tr := v.srv.Locale(r)
+	f := newRuleForm(tr, nil)
+
+	if r.Method == http.MethodPost {
+		forms.Bind(f, r)
+		if f.IsValid() {
+			rule, err := f.createRule(auth.GetRequestUser(r).ID)
+			if err == nil {
+				var applied *int
+				if applied, err = f.applyRule(rule); err == nil {
+					v.srv.AddFlash(w, r, "success", tr.Gettext("New rule created."))
+					v.addAppliedFlash(w, r, applied)
+					v.srv.Redirect(w, r, ".", rule.UID)
+					return
+				}
+			}
+			v.srv.Log(r).Error("", slog.Any("err", err))
+		}
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	}
+
+	ctx := server.TC{
+		"Pagination": rl.Pagination,
+		"Rules":      rl.Items,
+		"Form":       f,
+	}
+	ctx.SetBreadcrumbs([][2]string{
+		{tr.Gettext("Profile"), v.srv.AbsoluteURL(r, "/profile").String()},
+		{tr.Gettext("Rules")},
+	})
+
+	v.srv.RenderTemplate(w, r, 200, "profile/rule_list", ctx)
+}
+
+func (v *profileViews) ruleInfo(w http.ResponseWriter, r *http.Request) {
+	tr := v.srv.Locale(r)
+	ri := r.Context().Value(ctxRuleKey{}).(ruleItem)
+	f := newRuleForm(tr, ri.Rule)
+
+	if r.Method == http.MethodGet {
+		f.setRule(ri.Rule)
+	}
+
+	if r.Method == http.MethodPost {
+		forms.Bind(f, r)
+		if f.IsValid() {
+			err := f.updateRule(ri.Rule)
+			if err == nil {
+				var applied *int
+				if applied, err = f.applyRule(ri.Rule); err == nil {
+					v.srv.AddFlash(w, r, "success", tr.Gettext("Rule was updated."))
+					v.addAppliedFlash(w, r, applied)
+					v.srv.Redirect(w, r, ri.UID)
+					return
+				}
+			}
+			v.srv.Log(r).Error("", slog.Any("err", err))
+		}
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	}
+
+	// Dry run on the existing bookmarks
+	test, err := v.getRuleTest(r, ri.Rule)
+	if err != nil {
+		v.srv.Error(w, r, err)
+		return
+	}
+
+	ctx := server.TC{
+		"Rule": ri,
+		"Form": f,
+		"Test": test,
+	}
+	ctx.SetBreadcrumbs([][2]string{
+		{tr.Gettext("Profile"), v.srv.AbsoluteURL(r, "/profile").String()},
+		{tr.Gettext("Rules"), v.srv.AbsoluteURL(r, "/profile/rules").String()},
+		{cmp.Or(ri.Name, ri.UID)},
+	})
+
+	v.srv.RenderTemplate(w, r, 200, "profile/rule", ctx)
+}
+
+func (v *profileViews) ruleDelete(w http.ResponseWriter, r *http.Request) {
+	tr := v.srv.Locale(r)
+	ri := r.Context().Value(ctxRuleKey{}).(ruleItem)
+
+	if err := ri.Delete(); err != nil {
+		v.srv.Error(w, r, err)
+		return
+	}
+
+	v.srv.AddFlash(w, r, "success", tr.Gettext("Rule was removed."))
+	v.srv.Redirect(w, r, "/profile/rules")
+}
+
+// addAppliedFlash adds a message with the number of bookmarks
+// a rule changed, when it was applied.
+func (v *profileViews) addAppliedFlash(w http.ResponseWriter, r *http.Request, applied *int) {
+	if applied == nil {
+		return
+	}
+	v.srv.AddFlash(w, r, "info", v.srv.Locale(r).Ngettext(
+		"%d bookmark was updated.", "%d bookmarks were updated.", *applied, *applied,
+	))
+}
+
+func (v *profileViews) feedList(w http.ResponseWriter, r *http.Request) {
+	tr := v.srv.Locale(r)
+	f := newFeedForm(tr, auth.GetRequestUser(r).ID, nil)
+
+	if r.Method == http.MethodPost {
+		forms.Bind(f, r)
+		if f.IsValid() {
+			x, err := f.createFeed()
+			if err != nil {
+				v.srv.Log(r).Error("", slog.Any("err", err))
+			} else {
+				v.launchFeedFetch(r, x)
+				v.srv.AddFlash(w, r, "success", tr.Gettext("New feed created."))
+				v.srv.Redirect(w, r, ".", x.UID)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	}
+
+	v.renderFeedList(w, r, f, newFeedImportForm(tr, auth.GetRequestUser(r).ID))
+}
+
+func (v *profileViews) feedImport(w http.ResponseWriter, r *http.Request) {
+	tr := v.srv.Locale(r)
+	f := newFeedImportForm(tr, auth.GetRequestUser(r).ID)
+
+	forms.Bind(f, r)
+	if f.IsValid() {
+		items, err := f.importFeeds()
+		if err != nil {
+			v.srv.Log(r).Error("", slog.Any("err", err))
+		}
+		if f.IsValid() {
+			for _, x := range items {
+				v.launchFeedFetch(r, x)
+			}
+			v.srv.AddFlash(w, r, "success", tr.Ngettext(
+				"%d feed was imported.", "%d feeds were imported.", len(items), len(items),
+			))
+			v.srv.Redirect(w, r, "/profile/feeds")
+			return
+		}
+	}
+	w.WriteHeader(http.StatusUnprocessableEntity)
+
+	v.renderFeedList(w, r, newFeedForm(tr, auth.GetRequestUser(r).ID, nil), f)
+}
+
+func (v *profileViews) renderFeedList(w http.ResponseWriter, r *http.Request, f *feedForm, importForm *feedImportForm) {
+	tr := v.srv.Locale(r)
+	fl := r.Context().Value(ctxFeedListKey{}).(feedList)
+
+	ctx := server.TC{
+		"Pagination": fl.Pagination,
+		"Feeds":      fl.Items,
+		"Form":       f,
+		"ImportForm": importForm,
+	}
+	ctx.SetBreadcrumbs([][2]string{
+		{tr.Gettext("Profile"), v.srv.AbsoluteURL(r, "/profile").String()},
+		{tr.Gettext("Feeds")},
+	})
+
+	v.srv.RenderTemplate(w, r, 200, "profile/feed_list", ctx)
+}
+
+func (v *profileViews) feedInfo(w http.ResponseWriter, r *http.Request) {
+	tr := v.srv.Locale(r)
+	fi := r.Context().Value(ctxFeedKey{}).(feedItem)
+	f := newFeedForm(tr, auth.GetRequestUser(r).ID, fi.Feed)
+
+	if r.Method == http.MethodGet {
+		f.setFeed(fi.Feed)
+	}
+
+	if r.Method == http.MethodPost {
+		forms.Bind(f, r)
+		if f.IsValid() {
+			if err := f.updateFeed(fi.Feed); err != nil {
+				v.srv.Log(r).Error("", slog.Any("err", err))
+			} else {
+				v.srv.AddFlash(w, r, "success", tr.Gettext("Feed was updated."))
+				v.srv.Redirect(w, r, fi.UID)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	}
+
+	ctx := server.TC{
+		"Feed": fi,
+		"Form": f,
+	}
+	ctx.SetBreadcrumbs([][2]string{
+		{tr.Gettext("Profile"), v.srv.AbsoluteURL(r, "/profile").String()},
+		{tr.Gettext("Feeds"), v.srv.AbsoluteURL(r, "/profile/feeds").String()},
+		{cmp.Or(fi.Title, fi.URL)},
+	})
+
+	v.srv.RenderTemplate(w, r, 200, "profile/feed", ctx)
+}
+
+func (v *profileViews) feedFetch(w http.ResponseWriter, r *http.Request) {
+	tr := v.srv.Locale(r)
+	fi := r.Context().Value(ctxFeedKey{}).(feedItem)
+
+	if err := feeds.FetchTask.Run(fi.Feed.ID, fi.Feed.ID); err != nil {
+		v.srv.Error(w, r, err)
+		return
+	}
+
+	v.srv.AddFlash(w, r, "info", tr.Gettext("The feed will be refreshed in a moment."))
+	v.srv.Redirect(w, r, "/profile/feeds", fi.UID)
+}
+
+func (v *profileViews) feedDelete(w http.ResponseWriter, r *http.Request) {
+	tr := v.srv.Locale(r)
+	fi := r.Context().Value(ctxFeedKey{}).(feedItem)
+
+	if err := fi.Delete(); err != nil {
+		v.srv.Error(w, r, err)
+		return
+	}
+
+	v.srv.AddFlash(w, r, "success", tr.Gettext("Feed was removed."))
+	v.srv.Redirect(w, r, "/profile/feeds")
+}
+
+func (v *profileViews) inboundList(w http.ResponseWriter, r *http.Request) {
+	tr := v.srv.Locale(r)
+	il := r.Context().Value(ctxInboundListKey{}).(inboundList)
+	f := newInboundForm(tr, auth.GetRequestUser(r).ID)
+
+	if r.Method == http.MethodPost {
+		forms.Bind(f, r)
+		if f.IsValid() {
+			a, err := f.createAddress()
+			if err != nil {
+				v.srv.Log(r).Error("", slog.Any("err", err))
+			} else {
+				v.srv.AddFlash(w, r, "success", tr.Gettext("New address created."))
+				v.srv.Redirect(w, r, ".", a.UID)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	}
+
+	ctx := server.TC{
+		"Pagination": il.Pagination,
+		"Addresses":  il.Items,
+		"Form":       f,
+		"Enabled":    configs.Config.Inbound.Maildir != "",
+	}
+	ctx.SetBreadcrumbs([][2]string{
+		{tr.Gettext("Profile"), v.srv.AbsoluteURL(r, "/profile").String()},
+		{tr.Gettext("Email addresses")},
+	})
+
+	v.srv.RenderTemplate(w, r, 200, "profile/inbound_list", ctx)
+}
+
+func (v *profileViews) inboundInfo(w http.ResponseWriter, r *http.Request) {
+	tr := v.srv.Locale(r)
+	ii := r.Context().Value(ctxInboundKey{}).(inboundItem)
+	f := newInboundForm(tr, auth.GetRequestUser(r).ID)
+
+	if r.Method == http.MethodGet {
+		f.setAddress(ii.Address)
+	}
+
+	if r.Method == http.MethodPost {
+		forms.Bind(f, r)
+		if f.IsValid() {
+			if err := f.updateAddress(ii.Address); err != nil {
+				v.srv.Log(r).Error("", slog.Any("err", err))
+			} else {
+				v.srv.AddFlash(w, r, "success", tr.Gettext("Address was updated."))
+				v.srv.Redirect(w, r, ii.UID)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	}
+
+	ctx := server.TC{
+		"Address": ii,
+		"Form":    f,
+	}
+	ctx.SetBreadcrumbs([][2]string{
+		{tr.Gettext("Profile"), v.srv.AbsoluteURL(r, "/profile").String()},
+		{tr.Gettext("Email addresses"), v.srv.AbsoluteURL(r, "/profile/inbound").String()},
+		{cmp.Or(ii.Name, ii.Email, ii.Token)},
+	})
+
+	v.srv.RenderTemplate(w, r, 200, "profile/inbound", ctx)
+}
+
+func (v *profileViews) inboundReset(w http.ResponseWriter, r *http.Request) {
+	tr := v.srv.Locale(r)
+	ii := r.Context().Value(ctxInboundKey{}).(inboundItem)
+
+	if err := ii.ResetToken(); err != nil {
+		v.srv.Error(w, r, err)
+		return
+	}
+
+	v.srv.AddFlash(w, r, "success", tr.Gettext("The address was changed."))
+	v.srv.Redirect(w, r, "/profile/inbound", ii.UID)
+}
+
+func (v *profileViews) inboundDelete(w http.ResponseWriter, r *http.Request) {
+	tr := v.srv.Locale(r)
+	ii := r.Context().Value(ctxInboundKey{}).(inboundItem)
+
+	if err := ii.Delete(); err != nil {
+		v.srv.Error(w, r, err)
+		return
+	}
+
+	v.srv.AddFlash(w, r, "success", tr.Gettext("Address was removed."))
+	v.srv.Redirect(w, r, "/profile/inbound")
+}
@@ -10,12 +10,22 @@ import (
 	"net/url"
 	"path"
 	"testing"
+	"time"
 
 	"github.com/doug-martin/goqu/v9"
 	"github.com/stretchr/testify/require"
 
+	"codeberg.org/readeck/readeck/configs"
 	"codeberg.org/readeck/readeck/internal/auth/tokens"
+	"codeberg.org/readeck/readeck/internal/auth/users"
+	"codeberg.org/readeck/readeck/internal/bookmarks"
+	"codeberg.org/readeck/readeck/internal/db/types"
+	"codeberg.org/readeck/readeck/internal/feeds"
+	"codeberg.org/readeck/readeck/internal/inbound"
+	"codeberg.org/readeck/readeck/internal/sessions"
 	. "codeberg.org/readeck/readeck/internal/testing" //revive:disable:dot-imports
+	"codeberg.org/readeck/readeck/internal/webhooks"
+	"codeberg.org/readeck/readeck/pkg/totp"
 )
 
 func TestViews(t *testing.T) {
@@ -102,6 +112,10 @@ func TestViews(t *testing.T) {
 			}
 		}()
 
+		other := NewClient(t, app)
+		app.Users["user"].Login(other)
+		other.Get("/profile").AssertStatus(t, 200)
+
 		RunRequestSequence(t, client, "user",
 			RequestTest{Target: "/profile/password", ExpectStatus: 200},
 			RequestTest{
@@ -117,6 +131,107 @@ func TestViews(t *testing.T) {
 			// The session has been updated, we can still use the website
 			RequestTest{Target: "/profile", ExpectStatus: 200},
 		)
+
+		// The other sessions are signed out
+		other.Get("/profile").AssertStatus(t, 303)
+		records, err := sessions.Records.ForUser(app.Users["user"].User.ID, time.Time{})
+		require.NoError(t, err)
+		require.Len(t, records, 1)
+	})
+
+	t.Run("sessions", func(t *testing.T) {
+		uid := app.Users["user"].User.ID
+		require.NoError(t, sessions.Records.DeleteUser(uid))
+
+		other := NewClient(t, app)
+		app.Users["user"].Login(other)
+		records, err := sessions.Records.ForUser(uid, time.Time{})
+		require.NoError(t, err)
+		require.Len(t, records, 1)
+		otherID := records[0].UID
+		require.NoError(t, records[0].Update(goqu.Record{"user_agent": "Other Browser"}))
+
+		app.Users["user"].Login(client)
+		defer client.Logout()
+
+		rsp := client.Get("/profile/sessions")
+		rsp.AssertStatus(t, 200)
+		require.Contains(t, string(rsp.Body), "Other Browser")
+		require.Contains(t, string(rsp.Body), "This device")
+
+		rsp = client.PostForm("/profile/sessions/unknown/delete", url.Values{})
+		rsp.AssertStatus(t, 404)
+
+		client.Get("/profile/sessions")
+		rsp = client.PostForm("/profile/sessions/"+otherID+"/delete", url.Values{})
+		rsp.AssertStatus(t, 303)
+		rsp.AssertRedirect(t, "^/profile/sessions$")
+		other.Get("/profile").AssertStatus(t, 303)
+		client.Get("/profile").AssertStatus(t, 200)
+
+		// Sign out everywhere
+		app.Users["user"].Login(other)
+		client.Get("/profile/sessions")
+		rsp = client.PostForm("/profile/sessions/delete", url.Values{})
+		rsp.AssertStatus(t, 303)
+		rsp.AssertRedirect(t, "^/login$")
+		client.Get("/profile").AssertStatus(t, 303)
+		other.Get("/profile").AssertStatus(t, 303)
+
+		records, err = sessions.Records.ForUser(uid, time.Time{})
+		require.NoError(t, err)
+		require.Empty(t, records)
+	})
+
+	t.Run("totp", func(t *testing.T) {
+		app.Users["user"].Login(client)
+		defer client.Logout()
+
+		rsp := client.Get("/profile/totp")
+		rsp.AssertStatus(t, 200)
+		require.Contains(t, string(rsp.Body), "data:image/png;base64,")
+
+		a, err := users.Authenticators.ForUser(app.Users["user"].User)
+		require.NoError(t, err)
+		require.False(t, a.IsEnabled())
+		key, err := a.Key()
+		require.NoError(t, err)
+		step := totp.Step(time.Now()) - 1
+
+		rsp = client.PostForm("/profile/totp", url.Values{"code": {"000000"}})
+		rsp.AssertStatus(t, 422)
+
+		rsp = client.PostForm("/profile/totp", url.Values{"code": {totp.Code(key, step)}})
+		rsp.AssertStatus(t, 200)
+		require.Contains(t, string(rsp.Body), "recovery codes")
+
+		enabled, err := users.Authenticators.IsEnabled(app.Users["user"].User)
+		require.NoError(t, err)
+		require.True(t, enabled)
+
+		// New recovery codes
+		client.Get("/profile/totp")
+		rsp = client.PostForm("/profile/totp/recovery", url.Values{"code": {"000000"}})
+		rsp.AssertStatus(t, 422)
+		rsp = client.PostForm("/profile/totp/recovery", url.Values{"code": {totp.Code(key, step+1)}})
+		rsp.AssertStatus(t, 200)
+		require.Contains(t, string(rsp.Body), "recovery codes")
+
+		// Can't disable when it's required
+		defaults := configs.Config.Auth
+		configs.Config.Auth.TOTPRequiredGroups = []string{"user"}
+		client.Get("/profile/totp")
+		rsp = client.PostForm("/profile/totp/delete", url.Values{"code": {totp.Code(key, step+2)}})
+		configs.Config.Auth = defaults
+		rsp.AssertStatus(t, 422)
+
+		client.Get("/profile/totp")
+		rsp = client.PostForm("/profile/totp/delete", url.Values{"code": {totp.Code(key, step+2)}})
+		rsp.AssertStatus(t, 303)
+		rsp.AssertRedirect(t, "^/profile/totp$")
+
+		_, err = users.Authenticators.ForUser(app.Users["user"].User)
+		require.ErrorIs(t, err, users.ErrNotFound)
 	})
 
 	t.Run("tokens", func(t *testing.T) {
@@ -208,4 +323,341 @@ func TestViews(t *testing.T) {
 			},
 		)
 	})
+
+	t.Run("token restriction", func(t *testing.T) {
+		u := app.Users["staff"]
+		c := &bookmarks.Collection{UserID: &u.User.ID, Name: "Reading list"}
+		require.NoError(t, bookmarks.Collections.Create(c))
+
+		RunRequestSequence(t, client, "staff",
+			RequestTest{
+				Target:         "/profile/tokens/" + u.Token.UID,
+				ExpectStatus:   200,
+				ExpectContains: "Reading list",
+			},
+			RequestTest{
+				Method: "POST",
+				Target: "/profile/tokens/" + u.Token.UID,
+				Form: url.Values{
+					"application":         {"widget"},
+					"restrict_collection": {"unknown"},
+				},
+				ExpectStatus: 422,
+			},
+			RequestTest{Target: "/profile/tokens/" + u.Token.UID},
+			RequestTest{
+				Method: "POST",
+				Target: "/profile/tokens/" + u.Token.UID,
+				Form: url.Values{
+					"application":         {"widget"},
+					"restrict_collection": {c.UID},
+					"restrict_labels":     {`news "to read"`},
+					"restrict_search":     {"golang"},
+				},
+				ExpectStatus: 303,
+				Assert: func(t *testing.T, _ *Response) {
+					token, err := tokens.Tokens.GetOne(goqu.C("uid").Eq(u.Token.UID))
+					require.NoError(t, err)
+					require.Equal(t, &tokens.Restriction{
+						Collection: c.UID,
+						Labels:     []string{"news", "to read"},
+						Search:     "golang",
+					}, token.Restriction)
+				},
+			},
+			RequestTest{
+				Target:         "/profile/tokens/" + u.Token.UID,
+				ExpectStatus:   200,
+				ExpectContains: `value="&#34;news&#34; &#34;to read&#34;"`,
+			},
+			RequestTest{
+				Method: "POST",
+				Target: "/profile/tokens/" + u.Token.UID,
+				Form: url.Values{
+					"application":         {"widget"},
+					"restrict_collection": {""},
+					"restrict_labels":     {""},
+					"restrict_search":     {""},
+				},
+				ExpectStatus: 303,
+				Assert: func(t *testing.T, _ *Response) {
+					token, err := tokens.Tokens.GetOne(goqu.C("uid").Eq(u.Token.UID))
+					require.NoError(t, err)
+					require.Nil(t, token.Restriction)
+				},
+			},
+		)
+	})
+
+	t.Run("webhooks", func(t *testing.T) {
+		RunRequestSequence(t, client, "staff",
+			RequestTest{Target: "/profile/webhooks", ExpectStatus: 200},
+			RequestTest{
+				Method:       "POST",
+				Target:       "/profile/webhooks",
+				Form:         url.Values{"url": {"not an url"}},
+				ExpectStatus: 422,
+			},
+			RequestTest{Target: "/profile/webhooks", ExpectStatus: 200},
+			RequestTest{
+				Method: "POST",
+				Target: "/profile/webhooks",
+				Form: url.Values{
+					"name":   {"test hook"},
+					"url":    {"https://example.net/hook"},
+					"events": {"bookmark.created", "bookmark.deleted"},
+				},
+				ExpectStatus:   303,
+				ExpectRedirect: "/profile/webhooks/.+",
+			},
+			RequestTest{
+				Target:         "{{ (index .History 0).Redirect }}",
+				ExpectStatus:   200,
+				ExpectContains: "New webhook created",
+			},
+			RequestTest{
+				Method: "POST",
+				Target: "{{ (index .History 0).Path }}",
+				Form: url.Values{
+					"name":       {"test hook"},
+					"url":        {"https://example.net/hook2"},
+					"is_enabled": {"f"},
+					"events":     {"\uff00"},
+				},
+				ExpectStatus:   303,
+				ExpectRedirect: "/profile/webhooks/.+",
+				Assert: func(t *testing.T, r *Response) {
+					_, uid := path.Split(r.URL.Path)
+					w, err := webhooks.Webhooks.GetOne(goqu.C("uid").Eq(uid))
+					require.NoError(t, err)
+					require.Equal(t, "https://example.net/hook2", w.URL)
+					require.False(t, w.IsEnabled)
+					require.Empty(t, w.Events)
+				},
+			},
+			RequestTest{Target: "/profile/webhooks", ExpectStatus: 200, ExpectContains: "test hook"},
+
+			// Delete webhook
+			RequestTest{
+				Method:         "POST",
+				Target:         "{{ (index .History 1).Path }}/delete",
+				ExpectStatus:   303,
+				ExpectRedirect: "/profile/webhooks",
+			},
+			RequestTest{
+				Target:       "{{ (index .History 2).Path }}",
+				ExpectStatus: 404,
+			},
+		)
+	})
+
+	t.Run("rules", func(t *testing.T) {
+		RunRequestSequence(t, client, "staff",
+			RequestTest{Target: "/profile/rules", ExpectStatus: 200},
+			RequestTest{
+				Method:       "POST",
+				Target:       "/profile/rules",
+				Form:         url.Values{"query": {"type:video"}},
+				ExpectStatus: 422,
+			},
+			RequestTest{Target: "/profile/rules", ExpectStatus: 200},
+			RequestTest{
+				Method: "POST",
+				Target: "/profile/rules",
+				Form: url.Values{
+					"name":       {"test rule"},
+					"query":      {"type:video"},
+					"add_labels": {"watch", ""},
+				},
+				ExpectStatus:   303,
+				ExpectRedirect: "/profile/rules/.+",
+			},
+			RequestTest{
+				Target:         "{{ (index .History 0).Redirect }}",
+				ExpectStatus:   200,
+				ExpectContains: "New rule created",
+			},
+			RequestTest{
+				Method: "POST",
+				Target: "{{ (index .History 0).Path }}",
+				Form: url.Values{
+					"name":       {"test rule"},
+					"query":      {"type:photo"},
+					"is_enabled": {"f"},
+					"add_labels": {"watch"},
+					"is_marked":  {"t"},
+				},
+				ExpectStatus:   303,
+				ExpectRedirect: "/profile/rules/.+",
+				Assert: func(t *testing.T, r *Response) {
+					_, uid := path.Split(r.URL.Path)
+					rule, err := bookmarks.Rules.GetOne(goqu.C("uid").Eq(uid))
+					require.NoError(t, err)
+					require.Equal(t, "type:photo", rule.Query)
+					require.False(t, rule.IsEnabled)
+					require.NotNil(t, rule.Actions.IsMarked)
+					require.True(t, *rule.Actions.IsMarked)
+				},
+			},
+			RequestTest{Target: "/profile/rules", ExpectStatus: 200, ExpectContains: "test rule"},
+
+			// Delete rule
+			RequestTest{
+				Method:         "POST",
+				Target:         "{{ (index .History 1).Path }}/delete",
+				ExpectStatus:   303,
+				ExpectRedirect: "/profile/rules",
+			},
+			RequestTest{
+				Target:       "{{ (index .History 2).Path }}",
+				ExpectStatus: 404,
+			},
+		)
+	})
+
+	t.Run("feeds", func(t *testing.T) {
+		RunRequestSequence(t, client, "staff",
+			RequestTest{Target: "/profile/feeds", ExpectStatus: 200},
+			RequestTest{
+				Method:       "POST",
+				Target:       "/profile/feeds",
+				Form:         url.Values{"url": {"not a url"}},
+				ExpectStatus: 422,
+			},
+			RequestTest{Target: "/profile/feeds", ExpectStatus: 200},
+			RequestTest{
+				Method: "POST",
+				Target: "/profile/feeds",
+				Form: url.Values{
+					"url":         {"https://example.net/feed.xml"},
+					"title":       {"test feed"},
+					"labels":      {"news", ""},
+					"max_entries": {"5"},
+				},
+				ExpectStatus:   303,
+				ExpectRedirect: "/profile/feeds/.+",
+			},
+			RequestTest{
+				Target:         "{{ (index .History 0).Redirect }}",
+				ExpectStatus:   200,
+				ExpectContains: "New feed created",
+			},
+			RequestTest{
+				Method: "POST",
+				Target: "{{ (index .History 0).Path }}",
+				Form: url.Values{
+					"url":         {"https://example.net/feed.xml"},
+					"title":       {"test feed"},
+					"is_enabled":  {"f"},
+					"labels":      {""},
+					"exclude":     {"sponsored"},
+					"max_entries": {"5"},
+				},
+				ExpectStatus:   303,
+				ExpectRedirect: "/profile/feeds/.+",
+				Assert: func(t *testing.T, r *Response) {
+					_, uid := path.Split(r.URL.Path)
+					f, err := feeds.Feeds.GetOne(goqu.C("uid").Eq(uid))
+					require.NoError(t, err)
+					require.False(t, f.IsEnabled)
+					require.Equal(t, types.Strings{"sponsored"}, f.Exclude)
+					require.Equal(t, types.Strings{}, f.Labels)
+					require.Equal(t, 5, f.MaxEntries)
+				},
+			},
+			RequestTest{Target: "{{ (index .History 0).Redirect }}", ExpectStatus: 200},
+			RequestTest{
+				Method:         "POST",
+				Target:         "{{ (index .History 0).Path }}/fetch",
+				ExpectStatus:   303,
+				ExpectRedirect: "/profile/feeds/[^/]+$",
+				Assert: func(t *testing.T, _ *Response) {
+					require.Len(t, Events().Records("task"), 2)
+				},
+			},
+			RequestTest{Target: "/profile/feeds", ExpectStatus: 200, ExpectContains: "test feed"},
+
+			// Delete feed
+			RequestTest{
+				Method:         "POST",
+				Target:         "{{ (index .History 2).Path }}/delete",
+				ExpectStatus:   303,
+				ExpectRedirect: "/profile/feeds",
+			},
+			RequestTest{
+				Target:       "{{ (index .History 3).Path }}",
+				ExpectStatus: 404,
+			},
+		)
+	})
+
+	t.Run("inbound", func(t *testing.T) {
+		RunRequestSequence(t, client, "staff",
+			RequestTest{
+				Target:         "/profile/inbound",
+				ExpectStatus:   200,
+				ExpectContains: "Email reception is not configured",
+			},
+			RequestTest{
+				Method: "POST",
+				Target: "/profile/inbound",
+				Form: url.Values{
+					"name":   {"newsletters"},
+					"labels": {"news", ""},
+				},
+				ExpectStatus:   303,
+				ExpectRedirect: "/profile/inbound/.+",
+			},
+			RequestTest{
+				Target:         "{{ (index .History 0).Redirect }}",
+				ExpectStatus:   200,
+				ExpectContains: "New address created",
+			},
+			RequestTest{
+				Method: "POST",
+				Target: "{{ (index .History 0).Path }}",
+				Form: url.Values{
+					"name":   {"test address"},
+					"labels": {""},
+				},
+				ExpectStatus:   303,
+				ExpectRedirect: "/profile/inbound/.+",
+				Assert: func(t *testing.T, r *Response) {
+					_, uid := path.Split(r.URL.Path)
+					a, err := inbound.Addresses.GetOne(goqu.C("uid").Eq(uid))
+					require.NoError(t, err)
+					require.Equal(t, "test address", a.Name)
+					require.Equal(t, types.Strings{}, a.Labels)
+				},
+			},
+			RequestTest{Target: "{{ (index .History 0).Redirect }}", ExpectStatus: 200},
+			RequestTest{
+				Method:         "POST",
+				Target:         "{{ (index .History 0).Path }}/reset",
+				ExpectStatus:   303,
+				ExpectRedirect: "/profile/inbound/[^/]+$",
+			},
+			RequestTest{
+				Target:         "{{ (index .History 0).Redirect }}",
+				ExpectStatus:   200,
+				ExpectContains: "The address was changed",
+				Assert: func(t *testing.T, r *Response) {
+					require.Contains(t, string(r.Body), "Delete address")
+				},
+			},
+			RequestTest{Target: "/profile/inbound", ExpectStatus: 200, ExpectContains: "test address"},
+
+			// Delete address
+			RequestTest{
+				Method:         "POST",
+				Target:         "{{ (index .History 1).Path }}/delete",
+				ExpectStatus:   303,
+				ExpectRedirect: "/profile/inbound",
+			},
+			RequestTest{
+				Target:       "{{ (index .History 2).Path }}",
+				ExpectStatus: 404,
+			},
+		)
+	})
 }
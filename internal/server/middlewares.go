@@ -221,6 +221,12 @@ func (w *responseWriterInterceptor) WriteHeader(statusCode int) {
 	}
 }
 
+// Unwrap returns the original response writer, so an http.ResponseController
+// can flush a streamed response.
+func (w *responseWriterInterceptor) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
 // Write overrides the wrapped Write method to discard all contents and
 // send its own response when it needs to.
 func (w *responseWriterInterceptor) Write(c []byte) (int, error) {
@@ -58,6 +58,12 @@ func New(basePath string) *Server {
 		s.CannonicalPaths,
 		auth.Init(
 			&auth.TokenAuthProvider{},
+			&auth.KosyncAuthProvider{},
+			&auth.ProxyAuthProvider{
+				IsTrusted: func(r *http.Request) bool {
+					return GetRemoteInfo(r).IsTrusted
+				},
+			},
 			&auth.SessionAuthProvider{
 				GetSession:          s.GetSession,
 				UnauthorizedHandler: s.unauthorizedHandler,
@@ -43,7 +43,9 @@ func (s *Server) WithSession() func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			// Ignore non session requests
-			if _, ok := auth.GetRequestProvider(r).(*auth.SessionAuthProvider); !ok {
+			switch auth.GetRequestProvider(r).(type) {
+			case *auth.SessionAuthProvider, *auth.ProxyAuthProvider:
+			default:
 				next.ServeHTTP(w, r)
 				return
 			}
@@ -0,0 +1,174 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package sessions
+
+import (
+	"errors"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+
+	"codeberg.org/readeck/readeck/internal/db"
+	"codeberg.org/readeck/readeck/pkg/base58"
+)
+
+const (
+	// TableName is the session table name in database.
+	TableName = "user_session"
+
+	// touchInterval is the minimum delay between two updates
+	// of a record's last seen time.
+	touchInterval = time.Minute
+)
+
+var (
+	// Records is the session record manager.
+	Records = RecordManager{}
+
+	// ErrNotFound is returned when a session record was not found.
+	ErrNotFound = errors.New("not found")
+)
+
+// Record is the server side counterpart of a session cookie.
+// A session cookie is only valid while its record exists, so
+// deleting a record signs out the device that holds the cookie.
+type Record struct {
+	ID        int       `db:"id" goqu:"skipinsert,skipupdate"`
+	UID       string    `db:"uid"`
+	UserID    int       `db:"user_id"`
+	Created   time.Time `db:"created" goqu:"skipupdate"`
+	LastSeen  time.Time `db:"last_seen"`
+	UserAgent string    `db:"user_agent"`
+	IPAddress string    `db:"ip_address"`
+}
+
+// RecordManager is a query helper for session records.
+type RecordManager struct{}
+
+// Query returns a prepared goqu SelectDataset that can be extended later.
+func (m *RecordManager) Query() *goqu.SelectDataset {
+	return db.Q().From(goqu.T(TableName).As("s")).Prepared(true)
+}
+
+// GetOne executes the a select query and returns the first result or an error
+// when there's no result.
+func (m *RecordManager) GetOne(expressions ...goqu.Expression) (*Record, error) {
+	var s Record
+	found, err := m.Query().Where(expressions...).ScanStruct(&s)
+
+	switch {
+	case err != nil:
+		return nil, err
+	case !found:
+		return nil, ErrNotFound
+	}
+
+	return &s, nil
+}
+
+// Create inserts a new session record in the database.
+func (m *RecordManager) Create(s *Record) error {
+	if s.UserID == 0 {
+		return errors.New("no session user")
+	}
+
+	s.Created = time.Now().UTC()
+	s.LastSeen = s.Created
+	s.UID = base58.NewUUID()
+
+	ds := db.Q().Insert(TableName).
+		Rows(s).
+		Prepared(true)
+
+	id, err := db.InsertWithID(ds, "id")
+	if err != nil {
+		return err
+	}
+
+	s.ID = id
+	return nil
+}
+
+// ForUser returns the sessions of a user that were seen after
+// the given time, the most recent first.
+func (m *RecordManager) ForUser(userID int, since time.Time) ([]*Record, error) {
+	res := []*Record{}
+	err := m.Query().
+		Where(
+			goqu.C("user_id").Eq(userID),
+			goqu.C("last_seen").Gt(since.UTC()),
+		).
+		Order(goqu.C("last_seen").Desc()).
+		ScanStructs(&res)
+
+	return res, err
+}
+
+// DeleteUser removes all the sessions of a user, except the
+// ones with the given UIDs.
+func (m *RecordManager) DeleteUser(userID int, except ...string) error {
+	ds := db.Q().Delete(TableName).Prepared(true).
+		Where(goqu.C("user_id").Eq(userID))
+	if len(except) > 0 {
+		ds = ds.Where(goqu.C("uid").NotIn(except))
+	}
+
+	_, err := ds.Executor().Exec()
+	return err
+}
+
+// Purge removes the sessions that weren't seen since the given time.
+func (m *RecordManager) Purge(before time.Time) (int64, error) {
+	res, err := db.Q().Delete(TableName).Prepared(true).
+		Where(goqu.C("last_seen").Lt(before.UTC())).
+		Executor().Exec()
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+// Update updates some session record values.
+func (s *Record) Update(v interface{}) error {
+	if s.ID == 0 {
+		return errors.New("no ID")
+	}
+
+	_, err := db.Q().Update(TableName).Prepared(true).
+		Set(v).
+		Where(goqu.C("id").Eq(s.ID)).
+		Executor().Exec()
+
+	return err
+}
+
+// Delete removes a session record from the database.
+func (s *Record) Delete() error {
+	_, err := db.Q().Delete(TableName).Prepared(true).
+		Where(goqu.C("id").Eq(s.ID)).
+		Executor().Exec()
+
+	return err
+}
+
+// Touch records a new request of the session. To spare some writes,
+// the record is only updated once in a while or when the client
+// changed.
+func (s *Record) Touch(userAgent, ip string) error {
+	now := time.Now().UTC()
+	if now.Sub(s.LastSeen) < touchInterval && userAgent == s.UserAgent && ip == s.IPAddress {
+		return nil
+	}
+
+	s.LastSeen = now
+	s.UserAgent = userAgent
+	s.IPAddress = ip
+	return s.Update(goqu.Record{
+		"last_seen":  s.LastSeen,
+		"user_agent": s.UserAgent,
+		"ip_address": s.IPAddress,
+	})
+}
@@ -5,18 +5,25 @@
 // Package sessions provides a cookie based session manager.
 // It's heavily based on gorilla session but with a structured session
 // payload that can be serialized to json.
+// An authenticated session also has a record in database, so it can
+// be listed and revoked.
 package sessions
 
 import (
+	"log/slog"
 	"net/http"
 	"strconv"
 	"time"
 
+	"github.com/doug-martin/goqu/v9"
+
+	"codeberg.org/readeck/readeck/internal/db"
 	"codeberg.org/readeck/readeck/pkg/http/securecookie"
 )
 
 // Payload contains session values.
 type Payload struct {
+	ID          string         `json:"id,omitempty"`
 	Seed        int            `json:"s"`
 	User        int            `json:"u"`
 	LastUpdate  time.Time      `json:"lu"`
@@ -69,11 +76,76 @@ func (s *Session) Save(w http.ResponseWriter, r *http.Request) error {
 	return s.handler.Save(w, r, s.Payload)
 }
 
-// Clear deletes the session.
+// Start binds the session to a user, with a new session record.
+// The session must be saved afterward.
+func (s *Session) Start(r *http.Request, user, seed int) error {
+	if err := s.deleteRecord(); err != nil {
+		return err
+	}
+
+	rec := &Record{
+		UserID:    user,
+		UserAgent: userAgent(r),
+		IPAddress: r.RemoteAddr,
+	}
+	if err := Records.Create(rec); err != nil {
+		return err
+	}
+
+	s.Payload.ID = rec.UID
+	s.Payload.User = user
+	s.Payload.Seed = seed
+	return nil
+}
+
+// Record returns the session record of an authenticated session
+// and updates its last seen time.
+func (s *Session) Record(r *http.Request) (*Record, error) {
+	if s.Payload.ID == "" {
+		return nil, ErrNotFound
+	}
+
+	rec, err := Records.GetOne(
+		goqu.C("uid").Eq(s.Payload.ID),
+		goqu.C("user_id").Eq(s.Payload.User),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return rec, rec.Touch(userAgent(r), r.RemoteAddr)
+}
+
+// Clear deletes the session and its record.
 func (s *Session) Clear(w http.ResponseWriter, r *http.Request) {
+	if err := s.deleteRecord(); err != nil {
+		slog.Error("session record", slog.Any("err", err))
+	}
 	s.handler.Delete(w, r)
 }
 
+func (s *Session) deleteRecord() error {
+	if s.Payload.ID == "" {
+		return nil
+	}
+
+	_, err := db.Q().Delete(TableName).Prepared(true).
+		Where(goqu.C("uid").Eq(s.Payload.ID)).
+		Executor().Exec()
+	s.Payload.ID = ""
+	return err
+}
+
+// userAgent returns the request's user agent, truncated to
+// a reasonable size.
+func userAgent(r *http.Request) string {
+	ua := r.UserAgent()
+	if len(ua) > 512 {
+		ua = ua[:512]
+	}
+	return ua
+}
+
 // AddFlash add a new flash message to the session.
 func (s *Session) AddFlash(typ, msg string) {
 	s.Payload.Flashes = append(s.Payload.Flashes, FlashMessage{typ, msg})
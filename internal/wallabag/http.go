@@ -0,0 +1,251 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+// Package wallabag provides a Wallabag compatible API, so Wallabag
+// applications and scripts can work with Readeck.
+//
+// This package holds the authentication and server information routes.
+// Like the OPDS catalog, the entry, tag and annotation routes are provided
+// by the bookmarks routes package, since they share its forms, its
+// update logic and its export.
+package wallabag
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/go-chi/chi/v5"
+
+	"codeberg.org/readeck/readeck/internal/auth"
+	"codeberg.org/readeck/readeck/internal/auth/signin"
+	"codeberg.org/readeck/readeck/internal/auth/tokens"
+	"codeberg.org/readeck/readeck/internal/auth/users"
+	bookmark_routes "codeberg.org/readeck/readeck/internal/bookmarks/routes"
+	"codeberg.org/readeck/readeck/internal/server"
+	"codeberg.org/readeck/readeck/pkg/forms"
+)
+
+// Version is the Wallabag version reported to clients.
+// Some of them need a 2.x version to work.
+const Version = "2.6.9"
+
+// tokenLifetime is the announced lifetime of an access token.
+// Tokens don't expire but clients need a value.
+const tokenLifetime = 365 * 24 * time.Hour
+
+type wallabagRouter struct {
+	chi.Router
+	srv *server.Server
+}
+
+// SetupRoutes adds the Wallabag API HTTP routes.
+func SetupRoutes(s *server.Server) {
+	r := chi.NewRouter()
+	r.Use(stripJSONSuffix)
+	h := &wallabagRouter{r, s}
+
+	// Non authenticated routes
+	r.Post("/oauth/v2/token", h.oauthToken)
+	r.Get("/api/version", h.version)
+	r.Get("/api/info", h.info)
+
+	// Authenticated routes
+	ar := s.AuthenticatedRouter()
+	ar.With(s.WithPermission("api:wallabag", "read")).Group(func(r chi.Router) {
+		r.Get("/user", h.userInfo)
+		r.Group(bookmark_routes.NewWallabagRouteHandler(s))
+	})
+	r.Mount("/api", ar)
+
+	s.AddRoute("/wallabag", r)
+}
+
+// stripJSONSuffix removes the ".json" suffix Wallabag clients
+// can add to every API path.
+func stripJSONSuffix(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rctx := chi.RouteContext(r.Context())
+		if rctx != nil {
+			p := rctx.RoutePath
+			if p == "" {
+				p = r.URL.Path
+			}
+			rctx.RoutePath = strings.TrimSuffix(p, ".json")
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+type tokenForm struct {
+	*forms.Form
+}
+
+func newTokenForm(tr forms.Translator) *tokenForm {
+	return &tokenForm{forms.Must(
+		forms.WithTranslator(context.Background(), tr),
+		forms.NewTextField("grant_type", forms.Trim, forms.Required),
+		forms.NewTextField("client_id", forms.Trim),
+		forms.NewTextField("client_secret"),
+		forms.NewTextField("username", forms.Trim),
+		forms.NewTextField("password"),
+		forms.NewTextField("refresh_token", forms.Trim),
+	)}
+}
+
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	ExpiresIn    int    `json:"expires_in"`
+	TokenType    string `json:"token_type"`
+	Scope        string `json:"scope"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+type tokenError struct {
+	Error       string `json:"error"`
+	Description string `json:"error_description"`
+}
+
+// oauthToken implements the OAuth2 token endpoint used by Wallabag clients.
+// The "password" grant returns the API token of the user's client. The
+// "refresh_token" grant returns the same token, as long as it's valid.
+// Client ID and secret are not checked; the client ID is only recorded
+// in the token's application name.
+func (h *wallabagRouter) oauthToken(w http.ResponseWriter, r *http.Request) {
+	f := newTokenForm(h.srv.Locale(r))
+	forms.Bind(f, r)
+	if !f.IsValid() {
+		h.srv.Render(w, r, http.StatusBadRequest, tokenError{"invalid_request", "missing parameters"})
+		return
+	}
+
+	var token string
+	switch f.Get("grant_type").String() {
+	case "password":
+		user, err := signin.CheckUser(r, f)
+		var throttle *signin.ThrottleError
+		if errors.As(err, &throttle) {
+			throttle.SetHeader(w)
+			h.srv.Render(w, r, http.StatusTooManyRequests, tokenError{"invalid_grant", err.Error()})
+			return
+		}
+		if user == nil {
+			h.srv.Render(w, r, http.StatusBadRequest, tokenError{"invalid_grant", "Invalid username and password combination"})
+			return
+		}
+		// Wallabag clients can't send a second factor
+		if err := signin.CheckTOTP(r, user, ""); err != nil {
+			h.srv.Render(w, r, http.StatusBadRequest, tokenError{"invalid_grant", "Two-factor authentication is enabled"})
+			return
+		}
+
+		application := "Wallabag"
+		if v := f.Get("client_id").String(); v != "" {
+			application += " - " + v
+		}
+		t, err := clientToken(user, application)
+		if err != nil {
+			h.srv.Error(w, r, err)
+			return
+		}
+
+		if token, err = tokens.EncodeToken(t.UID); err != nil {
+			h.srv.Error(w, r, err)
+			return
+		}
+	case "refresh_token":
+		token = f.Get("refresh_token").String()
+		uid, err := tokens.DecodeToken(token)
+		if err != nil {
+			h.srv.Render(w, r, http.StatusBadRequest, tokenError{"invalid_grant", "Invalid refresh token"})
+			return
+		}
+		res, err := tokens.Tokens.GetUser(uid)
+		if err != nil || !res.Token.IsEnabled || res.Token.IsExpired() {
+			h.srv.Render(w, r, http.StatusBadRequest, tokenError{"invalid_grant", "Invalid refresh token"})
+			return
+		}
+	default:
+		h.srv.Render(w, r, http.StatusBadRequest, tokenError{"unsupported_grant_type", "Invalid grant_type parameter or parameter missing"})
+		return
+	}
+
+	h.srv.Render(w, r, http.StatusOK, tokenResponse{
+		AccessToken:  token,
+		ExpiresIn:    int(tokenLifetime.Seconds()),
+		TokenType:    "bearer",
+		Scope:        "read write",
+		RefreshToken: token,
+	})
+}
+
+// clientToken returns the API token of a Wallabag client. A client signing
+// in again gets its token back, as long as it's enabled and not expired.
+// Otherwise, a new token replaces the previous one.
+func clientToken(user *users.User, application string) (*tokens.Token, error) {
+	t, err := tokens.Tokens.GetOne(
+		goqu.C("user_id").Eq(user.ID),
+		goqu.C("application").Eq(application),
+	)
+	switch {
+	case err == nil && t.IsEnabled && !t.IsExpired():
+		return t, nil
+	case err == nil:
+		if err = t.Delete(); err != nil {
+			return nil, err
+		}
+	case !errors.Is(err, tokens.ErrNotFound):
+		return nil, err
+	}
+
+	t = &tokens.Token{
+		UserID:      &user.ID,
+		IsEnabled:   true,
+		Application: application,
+		Roles:       []string{"scoped_bookmarks_r", "scoped_bookmarks_w"},
+	}
+	if err = tokens.Tokens.Create(t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (h *wallabagRouter) version(w http.ResponseWriter, r *http.Request) {
+	h.srv.Render(w, r, http.StatusOK, Version)
+}
+
+func (h *wallabagRouter) info(w http.ResponseWriter, r *http.Request) {
+	h.srv.Render(w, r, http.StatusOK, map[string]any{
+		"appname":              "wallabag",
+		"version":              Version,
+		"allowed_registration": false,
+	})
+}
+
+type userInfo struct {
+	ID        int    `json:"id"`
+	Username  string `json:"username"`
+	Email     string `json:"email"`
+	Name      string `json:"name"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+func newUserInfo(u *users.User) userInfo {
+	return userInfo{
+		ID:        u.ID,
+		Username:  u.Username,
+		Email:     u.Email,
+		Name:      u.Username,
+		CreatedAt: u.Created.Format("2006-01-02T15:04:05-0700"),
+		UpdatedAt: u.Updated.Format("2006-01-02T15:04:05-0700"),
+	}
+}
+
+func (h *wallabagRouter) userInfo(w http.ResponseWriter, r *http.Request) {
+	h.srv.Render(w, r, http.StatusOK, newUserInfo(auth.GetRequestUser(r)))
+}
@@ -0,0 +1,445 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package wallabag_test
+
+import (
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/stretchr/testify/require"
+
+	"codeberg.org/readeck/readeck/internal/auth/tokens"
+	"codeberg.org/readeck/readeck/internal/bookmarks"
+	. "codeberg.org/readeck/readeck/internal/testing" //revive:disable:dot-imports
+)
+
+func TestOAuth(t *testing.T) {
+	app := NewTestApp(t)
+	defer app.Close(t)
+
+	client := NewClient(t, app)
+	client.Logout()
+
+	var token string
+
+	t.Run("password grant", func(t *testing.T) {
+		rsp := client.PostForm("/wallabag/oauth/v2/token", url.Values{
+			"grant_type":    {"password"},
+			"client_id":     {"koreader"},
+			"client_secret": {"secret"},
+			"username":      {"user"},
+			"password":      {app.Users["user"].Password()},
+		})
+		rsp.AssertStatus(t, 200)
+		rsp.AssertJSON(t, `{
+			"access_token": "<<PRESENCE>>",
+			"expires_in": 31536000,
+			"token_type": "bearer",
+			"scope": "read write",
+			"refresh_token": "<<PRESENCE>>"
+		}`)
+		token = rsp.JSON.(map[string]any)["access_token"].(string)
+	})
+
+	t.Run("password grant again", func(t *testing.T) {
+		signin := func(clientID string) string {
+			rsp := client.PostForm("/wallabag/oauth/v2/token", url.Values{
+				"grant_type": {"password"},
+				"client_id":  {clientID},
+				"username":   {"user"},
+				"password":   {app.Users["user"].Password()},
+			})
+			rsp.AssertStatus(t, 200)
+			return rsp.JSON.(map[string]any)["access_token"].(string)
+		}
+		countTokens := func() int64 {
+			count, err := tokens.Tokens.Query().Where(
+				goqu.C("user_id").Eq(app.Users["user"].User.ID),
+				goqu.C("application").Like("Wallabag%"),
+			).Count()
+			require.NoError(t, err)
+			return count
+		}
+
+		// The same client gets the same token
+		require.Equal(t, token, signin("koreader"))
+		require.Equal(t, int64(1), countTokens())
+
+		// Another client gets its own token
+		other := signin("android")
+		require.NotEqual(t, token, other)
+		require.Equal(t, int64(2), countTokens())
+
+		// A disabled token is replaced
+		uid, err := tokens.DecodeToken(other)
+		require.NoError(t, err)
+		tk, err := tokens.Tokens.GetOne(goqu.C("uid").Eq(uid))
+		require.NoError(t, err)
+		require.NoError(t, tk.Update(map[string]any{"is_enabled": false}))
+
+		require.NotEqual(t, other, signin("android"))
+		require.Equal(t, int64(2), countTokens())
+	})
+
+	t.Run("refresh grant", func(t *testing.T) {
+		rsp := client.PostForm("/wallabag/oauth/v2/token", url.Values{
+			"grant_type":    {"refresh_token"},
+			"refresh_token": {token},
+		})
+		rsp.AssertStatus(t, 200)
+		require.Equal(t, token, rsp.JSON.(map[string]any)["access_token"])
+	})
+
+	t.Run("invalid grants", func(t *testing.T) {
+		for _, values := range []url.Values{
+			{"grant_type": {"password"}, "username": {"user"}, "password": {"nope"}},
+			{"grant_type": {"refresh_token"}, "refresh_token": {"nope"}},
+		} {
+			rsp := client.PostForm("/wallabag/oauth/v2/token", values)
+			rsp.AssertStatus(t, 400)
+			rsp.AssertJSON(t, `{"error": "invalid_grant", "error_description": "<<PRESENCE>>"}`)
+		}
+
+		rsp := client.PostForm("/wallabag/oauth/v2/token", url.Values{"grant_type": {"client_credentials"}})
+		rsp.AssertStatus(t, 400)
+		rsp.AssertJSON(t, `{"error": "unsupported_grant_type", "error_description": "<<PRESENCE>>"}`)
+	})
+
+	t.Run("user", func(t *testing.T) {
+		req := client.NewRequest("GET", "/wallabag/api/user.json", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rsp := client.Request(req)
+		rsp.AssertStatus(t, 200)
+		rsp.AssertJSON(t, `{
+			"id": `+strconv.Itoa(app.Users["user"].User.ID)+`,
+			"username": "user",
+			"email": "user@localhost",
+			"name": "user",
+			"created_at": "<<PRESENCE>>",
+			"updated_at": "<<PRESENCE>>"
+		}`)
+	})
+}
+
+func TestAPI(t *testing.T) {
+	app := NewTestApp(t)
+	defer app.Close(t)
+
+	client := NewClient(t, app)
+
+	// Give the fixture bookmark an article and a picture.
+	b := app.Users["user"].Bookmarks[0]
+	require.NoError(t, b.Update(map[string]any{"files": bookmarks.BookmarkFiles{
+		"article": {Name: "index.html", Type: "text/html"},
+		"image":   {Name: "img/image.png", Type: "image/png"},
+	}}))
+
+	// ID of the entry created during the sequence
+	var entryID any
+
+	RunRequestSequence(t, client, "",
+		RequestTest{
+			Target:       "/wallabag/api/version.json",
+			ExpectStatus: 200,
+			ExpectJSON:   `"2.6.9"`,
+		},
+		RequestTest{
+			Target:       "/wallabag/api/info",
+			ExpectStatus: 200,
+			ExpectJSON:   `{"appname": "wallabag", "version": "2.6.9", "allowed_registration": false}`,
+		},
+		RequestTest{
+			JSON:         true,
+			Target:       "/wallabag/api/entries.json",
+			ExpectStatus: 401,
+		},
+	)
+
+	RunRequestSequence(t, client, "user",
+		RequestTest{
+			JSON:         true,
+			Target:       "/wallabag/api/entries.json?perPage=10",
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				r.AssertJQ(t, ".page", 1.0)
+				r.AssertJQ(t, ".limit", 10.0)
+				r.AssertJQ(t, ".pages", 1.0)
+				r.AssertJQ(t, ".total", 1.0)
+				r.AssertJQ(t, "._embedded.items[0].id", float64(app.Users["user"].Bookmarks[0].ID))
+				r.AssertJQ(t, "._embedded.items[0].tags[0].label", "test label")
+				r.AssertJQ(t, "._embedded.items[0].is_archived", 0.0)
+			},
+		},
+		RequestTest{
+			JSON:         true,
+			Target:       "/wallabag/api/entries.json?archive=1",
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				r.AssertJQ(t, ".total", 0.0)
+			},
+		},
+		RequestTest{
+			JSON:         true,
+			Target:       "/wallabag/api/entries.json?tags=test%20label",
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				r.AssertJQ(t, ".total", 1.0)
+			},
+		},
+		RequestTest{
+			JSON:         true,
+			Target:       "/wallabag/api/entries/exists.json?return_id=1&url=https://en.wikipedia.org/wiki/Go_(programming_language)",
+			ExpectStatus: 200,
+			ExpectJSON:   `{"exists": {{ (index .User.Bookmarks 0).ID }}}`,
+		},
+		RequestTest{
+			JSON:         true,
+			Target:       "/wallabag/api/entries/exists.json?hashed_urls[]=7b6e8a8f5ffa1fd3b7d6b6e8d6a4e4f2c5b1a9f0",
+			ExpectStatus: 200,
+			ExpectJSON:   `{"7b6e8a8f5ffa1fd3b7d6b6e8d6a4e4f2c5b1a9f0": false}`,
+		},
+		RequestTest{
+			JSON:         true,
+			Target:       "/wallabag/api/entries/{{ (index .User.Bookmarks 0).ID }}.json",
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				r.AssertJQ(t, ".url", "https://en.wikipedia.org/wiki/Go_(programming_language)")
+				r.AssertJQ(t, ".mimetype", "text/html")
+				r.AssertJQ(t, ".user_name", "user")
+				r.AssertJQ(t, ".content | contains(\"/bm/us/us6NJxYvghNoaPZ4sAszJW/_resources/KUhyzHK6GqcKLf4e4557qP.png\")", true)
+				r.AssertJQ(t, ".preview_picture", "http://readeck.example.org/bm/us/us6NJxYvghNoaPZ4sAszJW/img/image.png")
+			},
+		},
+		RequestTest{
+			Method:       "PATCH",
+			JSON:         map[string]any{"archive": 1, "starred": 1, "tags": "wallabag, tests"},
+			Target:       "/wallabag/api/entries/{{ (index .User.Bookmarks 0).ID }}.json",
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				r.AssertJQ(t, ".is_archived", 1.0)
+				r.AssertJQ(t, ".is_starred", 1.0)
+				r.AssertJQ(t, "[.tags[].label]", []any{"test label", "tests", "wallabag"})
+			},
+		},
+		RequestTest{
+			JSON:         true,
+			Target:       "/wallabag/api/tags.json",
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				r.AssertJQ(t, "[.[].label]", []any{"test label", "tests", "wallabag"})
+				r.AssertJQ(t, ".[0].nb_entries", 1.0)
+			},
+		},
+		RequestTest{
+			Method:       "DELETE",
+			JSON:         true,
+			Target:       "/wallabag/api/tag/label.json?tag=tests",
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				r.AssertJQ(t, ".label", "tests")
+			},
+		},
+		RequestTest{
+			JSON:         true,
+			Target:       "/wallabag/api/entries/{{ (index .User.Bookmarks 0).ID }}/tags.json",
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				r.AssertJQ(t, "[.[].label]", []any{"test label", "wallabag"})
+			},
+		},
+		RequestTest{
+			Method:       "POST",
+			JSON:         map[string]any{"tags": "other"},
+			Target:       "/wallabag/api/entries/{{ (index .User.Bookmarks 0).ID }}/tags.json",
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				r.AssertJQ(t, "[.tags[].label]", []any{"other", "test label", "wallabag"})
+			},
+		},
+		RequestTest{
+			Method:       "DELETE",
+			JSON:         true,
+			Target:       `/wallabag/api/entries/{{ (index .User.Bookmarks 0).ID }}/tags/{{ printf "%.0f" (index (index .History 0).JSON.tags 0).id }}.json`,
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				r.AssertJQ(t, "[.tags[].label]", []any{"test label", "wallabag"})
+			},
+		},
+		RequestTest{
+			Method: "POST",
+			JSON: map[string]any{
+				"text":  "a note",
+				"quote": "For the 2003",
+				"ranges": []map[string]any{{
+					"start":       "/section/div[1]",
+					"startOffset": "0",
+					"end":         "/section/div[1]",
+					"endOffset":   12,
+				}},
+			},
+			Target:       "/wallabag/api/annotations/{{ (index .User.Bookmarks 0).ID }}.json",
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				r.AssertJQ(t, ".text", "a note")
+				r.AssertJQ(t, ".quote", "For the 2003")
+				r.AssertJQ(t, ".ranges[0].start", "/section/div[1]")
+				r.AssertJQ(t, ".ranges[0].endOffset", 12.0)
+			},
+		},
+		RequestTest{
+			Method:       "PUT",
+			JSON:         map[string]any{"text": "another note"},
+			Target:       "/wallabag/api/annotations/{{ (index .History 0).JSON.id }}.json",
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				r.AssertJQ(t, ".text", "another note")
+			},
+		},
+		RequestTest{
+			JSON:         true,
+			Target:       "/wallabag/api/annotations/{{ (index .User.Bookmarks 0).ID }}.json",
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				r.AssertJQ(t, ".total", 1.0)
+				r.AssertJQ(t, ".rows[0].text", "another note")
+			},
+		},
+		RequestTest{
+			Method:       "DELETE",
+			JSON:         true,
+			Target:       "/wallabag/api/annotations/{{ (index .History 1).JSON.id }}.json",
+			ExpectStatus: 200,
+		},
+		RequestTest{
+			JSON:         true,
+			Target:       "/wallabag/api/annotations/{{ (index .User.Bookmarks 0).ID }}.json",
+			ExpectStatus: 200,
+			ExpectJSON:   `{"total": 0, "rows": []}`,
+		},
+		RequestTest{
+			JSON:         true,
+			Target:       "/wallabag/api/entries/{{ (index .User.Bookmarks 0).ID }}/export.epub",
+			ExpectStatus: 200,
+		},
+		RequestTest{
+			Method:       "POST",
+			JSON:         map[string]any{"url": "https://example.org/", "title": "Example", "tags": "a,b", "archive": 1},
+			Target:       "/wallabag/api/entries.json",
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				r.AssertJQ(t, ".url", "https://example.org/")
+				r.AssertJQ(t, ".title", "Example")
+				r.AssertJQ(t, ".is_archived", 1.0)
+				r.AssertJQ(t, "[.tags[].label]", []any{"a", "b"})
+				entryID = r.JSON.(map[string]any)["id"]
+			},
+		},
+		RequestTest{
+			Method:       "POST",
+			JSON:         map[string]any{"url": "https://example.org/", "starred": 1},
+			Target:       "/wallabag/api/entries.json",
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				r.AssertJQ(t, ".id", entryID)
+				r.AssertJQ(t, ".is_starred", 1.0)
+			},
+		},
+		RequestTest{
+			Method:       "DELETE",
+			JSON:         true,
+			Target:       "/wallabag/api/entries/{{ (index .History 0).JSON.id }}.json?expect=id",
+			ExpectStatus: 200,
+			ExpectJSON:   `{"id": {{ (index .History 1).JSON.id }}}`,
+		},
+		RequestTest{
+			JSON:         true,
+			Target:       "/wallabag/api/entries/{{ (index .History 0).JSON.id }}.json",
+			ExpectStatus: 404,
+		},
+	)
+}
+
+func TestAPIRestriction(t *testing.T) {
+	app := NewTestApp(t)
+	defer app.Close(t)
+
+	client := NewClient(t, app)
+	u := app.Users["user"]
+
+	newBookmark := func(title string, labels ...string) *bookmarks.Bookmark {
+		b := &bookmarks.Bookmark{
+			UserID: &u.User.ID,
+			URL:    "https://example.org/" + title,
+			Title:  title,
+			State:  bookmarks.StateLoaded,
+			Labels: labels,
+		}
+		require.NoError(t, bookmarks.Bookmarks.Create(b))
+		return b
+	}
+	alpha := newBookmark("alpha", "widget")
+	gamma := newBookmark("gamma")
+
+	u.Token.Restriction = &tokens.Restriction{Labels: []string{"widget"}}
+	require.NoError(t, u.Token.Save())
+	defer func() {
+		u.Token.Restriction = nil
+		require.NoError(t, u.Token.Save())
+	}()
+
+	RunRequestSequence(t, client, "user",
+		RequestTest{
+			JSON:         true,
+			Target:       "/wallabag/api/entries.json",
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				r.AssertJQ(t, ".total", 1.0)
+				r.AssertJQ(t, "[._embedded.items[].id]", []any{float64(alpha.ID)})
+			},
+		},
+		RequestTest{
+			JSON:         true,
+			Target:       "/wallabag/api/entries/" + strconv.Itoa(alpha.ID) + ".json",
+			ExpectStatus: 200,
+		},
+		RequestTest{
+			JSON:         true,
+			Target:       "/wallabag/api/entries/" + strconv.Itoa(gamma.ID) + ".json",
+			ExpectStatus: 404,
+		},
+		RequestTest{
+			Method:       "PATCH",
+			JSON:         map[string]any{"starred": 1},
+			Target:       "/wallabag/api/entries/" + strconv.Itoa(gamma.ID) + ".json",
+			ExpectStatus: 404,
+		},
+		RequestTest{
+			JSON:         true,
+			Target:       "/wallabag/api/entries/exists.json?url=" + url.QueryEscape(gamma.URL),
+			ExpectStatus: 200,
+			ExpectJSON:   `{"exists": false}`,
+		},
+		RequestTest{
+			JSON:         true,
+			Target:       "/wallabag/api/tags.json",
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				r.AssertJQ(t, "[.[].label]", []any{"widget"})
+			},
+		},
+		RequestTest{
+			Method:       "DELETE",
+			JSON:         true,
+			Target:       "/wallabag/api/tag/label.json?tag=widget",
+			ExpectStatus: 403,
+		},
+	)
+
+	b, err := bookmarks.Bookmarks.GetOne(goqu.C("id").Eq(gamma.ID))
+	require.NoError(t, err)
+	require.False(t, b.IsMarked)
+}
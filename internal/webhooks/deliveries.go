@@ -0,0 +1,124 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package webhooks
+
+import (
+	"errors"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+
+	"codeberg.org/readeck/readeck/internal/db"
+	"codeberg.org/readeck/readeck/pkg/base58"
+)
+
+const (
+	// DeliveryTable is the webhook delivery table name in database.
+	DeliveryTable = "webhook_delivery"
+
+	// DeliveryRetention is the time a delivery stays in the log.
+	DeliveryRetention = time.Hour * 24 * 30
+)
+
+var (
+	// Deliveries is the webhook delivery manager.
+	Deliveries = DeliveryManager{}
+
+	// ErrDeliveryNotFound is returned when a delivery record was not found.
+	ErrDeliveryNotFound = errors.New("not found")
+)
+
+// Delivery is a webhook call. Its payload is built when the event
+// occurs, so a retry sends the same content.
+type Delivery struct {
+	ID         int       `db:"id" goqu:"skipinsert,skipupdate"`
+	UID        string    `db:"uid"`
+	WebhookID  int       `db:"webhook_id"`
+	Created    time.Time `db:"created" goqu:"skipupdate"`
+	Updated    time.Time `db:"updated"`
+	Event      string    `db:"event"`
+	Payload    string    `db:"payload"`
+	Attempts   int       `db:"attempts"`
+	StatusCode int       `db:"status_code"`
+	Error      string    `db:"error"`
+}
+
+// DeliveryManager is a query helper for webhook delivery entries.
+type DeliveryManager struct{}
+
+// Query returns a prepared goqu SelectDataset that can be extended later.
+func (m *DeliveryManager) Query() *goqu.SelectDataset {
+	return db.Q().From(goqu.T(DeliveryTable).As("d")).Prepared(true)
+}
+
+// GetOne executes the a select query and returns the first result or an error
+// when there's no result.
+func (m *DeliveryManager) GetOne(expressions ...goqu.Expression) (*Delivery, error) {
+	var d Delivery
+	found, err := m.Query().Where(expressions...).ScanStruct(&d)
+
+	switch {
+	case err != nil:
+		return nil, err
+	case !found:
+		return nil, ErrDeliveryNotFound
+	}
+
+	return &d, nil
+}
+
+// Create inserts a new delivery in the database.
+func (m *DeliveryManager) Create(d *Delivery) error {
+	if d.WebhookID == 0 {
+		return errors.New("no webhook")
+	}
+
+	d.Created = time.Now()
+	d.Updated = d.Created
+	d.UID = base58.NewUUID()
+
+	ds := db.Q().Insert(DeliveryTable).
+		Rows(d).
+		Prepared(true)
+
+	id, err := db.InsertWithID(ds, "id")
+	if err != nil {
+		return err
+	}
+
+	d.ID = id
+	return nil
+}
+
+// Purge removes the deliveries created before the given time.
+func (m *DeliveryManager) Purge(before time.Time) (int64, error) {
+	res, err := db.Q().Delete(DeliveryTable).Prepared(true).
+		Where(goqu.C("created").Lt(before)).
+		Executor().Exec()
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+// Update updates some delivery values.
+func (d *Delivery) Update(v interface{}) error {
+	if d.ID == 0 {
+		return errors.New("no ID")
+	}
+
+	_, err := db.Q().Update(DeliveryTable).Prepared(true).
+		Set(v).
+		Where(goqu.C("id").Eq(d.ID)).
+		Executor().Exec()
+
+	return err
+}
+
+// IsSuccess returns true when the delivery received a 2xx response.
+func (d *Delivery) IsSuccess() bool {
+	return d.StatusCode >= 200 && d.StatusCode < 300
+}
@@ -0,0 +1,285 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package webhooks
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+
+	"codeberg.org/readeck/readeck/configs"
+	"codeberg.org/readeck/readeck/internal/bookmarks"
+	"codeberg.org/readeck/readeck/internal/bus"
+	"codeberg.org/readeck/readeck/pkg/extract"
+	"codeberg.org/readeck/readeck/pkg/superbus"
+)
+
+var (
+	// DeliverTask sends a webhook delivery.
+	DeliverTask superbus.Task
+
+	// PurgeDeliveriesTask removes the old deliveries from the log.
+	PurgeDeliveriesTask superbus.Task
+
+	deliverRetryPolicy = superbus.RetryPolicy{
+		MaxAttempts: 5,
+		Backoff:     time.Minute,
+		MaxBackoff:  time.Hour,
+		Retryable:   isRetryable,
+	}
+
+	encodeBookmark BookmarkEncoder = func(_ *url.URL, b *bookmarks.Bookmark) any {
+		return map[string]string{"id": b.UID}
+	}
+)
+
+// BookmarkEncoder returns the representation of a bookmark in a payload.
+// base is the root URL of the instance, as seen by the webhook's owner.
+type BookmarkEncoder func(base *url.URL, b *bookmarks.Bookmark) any
+
+// SetBookmarkEncoder sets the function that encodes a bookmark in
+// the payloads. Until it's set, a payload only contains the bookmark ID.
+func SetBookmarkEncoder(f BookmarkEncoder) {
+	encodeBookmark = f
+}
+
+// Payload is the JSON content sent on every delivery.
+type Payload struct {
+	Event        string    `json:"event"`
+	Created      time.Time `json:"created"`
+	Bookmark     any       `json:"bookmark"`
+	AnnotationID string    `json:"annotation_id,omitempty"`
+}
+
+// statusError is returned when the receiver responds with
+// an unsuccessful status.
+type statusError int
+
+func (e statusError) Error() string {
+	return fmt.Sprintf("unexpected status %d", int(e))
+}
+
+func init() {
+	bookmarks.OnEvent(trigger)
+
+	bus.OnReady(func() {
+		DeliverTask = bus.Tasks().NewTask(
+			"webhook.deliver",
+			superbus.WithTaskQueue(bus.QueueMaintenance),
+			superbus.WithUnmarshall(func(data []byte) interface{} {
+				var res int
+				err := json.Unmarshal(data, &res)
+				if err != nil {
+					panic(err)
+				}
+				return res
+			}),
+			superbus.WithFallibleTaskHandler(deliverHandler),
+			superbus.WithTaskRetry(deliverRetryPolicy),
+			superbus.WithTaskFailure(deliverFailure),
+		)
+
+		PurgeDeliveriesTask = bus.Tasks().NewTask(
+			"webhook.purge",
+			superbus.WithTaskQueue(bus.QueueMaintenance),
+			superbus.WithTaskSchedule(superbus.Every(24*time.Hour)),
+			superbus.WithFallibleTaskHandler(purgeDeliveriesHandler),
+		)
+	})
+}
+
+// Sign returns the signature of a payload, as sent in
+// the X-Readeck-Signature-256 header.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// trigger creates a delivery for every enabled webhook of the bookmark's
+// owner that subscribes to the event, and launches its task.
+func trigger(e bookmarks.Event) {
+	if bus.Tasks() == nil || e.Bookmark.UserID == nil {
+		return
+	}
+
+	var items []*Webhook
+	err := Webhooks.Query().
+		Where(
+			goqu.C("user_id").Eq(*e.Bookmark.UserID),
+			goqu.C("is_enabled").Eq(true),
+		).
+		ScanStructs(&items)
+	if err != nil {
+		slog.Error("loading webhooks", slog.Any("err", err))
+		return
+	}
+
+	for _, w := range items {
+		if !w.HasEvent(e.Name) {
+			continue
+		}
+
+		logger := slog.With(
+			slog.String("webhook", w.UID),
+			slog.String("event", e.Name),
+		)
+
+		base, err := url.Parse(w.BaseURL)
+		if err != nil {
+			logger.Error("invalid base URL", slog.Any("err", err))
+			continue
+		}
+
+		payload, err := json.Marshal(Payload{
+			Event:        e.Name,
+			Created:      time.Now().UTC(),
+			Bookmark:     encodeBookmark(base, e.Bookmark),
+			AnnotationID: e.AnnotationID,
+		})
+		if err != nil {
+			logger.Error("encoding payload", slog.Any("err", err))
+			continue
+		}
+
+		d := &Delivery{
+			WebhookID: w.ID,
+			Event:     e.Name,
+			Payload:   string(payload),
+		}
+		if err := Deliveries.Create(d); err != nil {
+			logger.Error("creating delivery", slog.Any("err", err))
+			continue
+		}
+
+		if err := DeliverTask.Run(d.ID, d.ID); err != nil {
+			logger.Error("launching delivery", slog.Any("err", err))
+		}
+	}
+}
+
+// Send posts the delivery's payload to the webhook URL and records the
+// result. It returns an error when the receiver didn't respond
+// with a 2xx status.
+func (d *Delivery) Send(w *Webhook) error {
+	status, err := d.post(w)
+
+	d.Attempts++
+	d.StatusCode = status
+	d.Error = ""
+	if err != nil {
+		d.Error = err.Error()
+	}
+
+	if uerr := d.Update(map[string]interface{}{
+		"updated":     time.Now(),
+		"attempts":    d.Attempts,
+		"status_code": d.StatusCode,
+		"error":       d.Error,
+	}); uerr != nil {
+		return uerr
+	}
+
+	return err
+}
+
+func (d *Delivery) post(w *Webhook) (int, error) {
+	body := []byte(d.Payload)
+	req, err := http.NewRequest(http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Readeck/"+configs.Version())
+	req.Header.Set("X-Readeck-Event", d.Event)
+	req.Header.Set("X-Readeck-Delivery", d.UID)
+	req.Header.Set("X-Readeck-Signature-256", Sign(w.Secret, body))
+
+	rsp, err := newClient().Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer rsp.Body.Close() //nolint:errcheck
+
+	if rsp.StatusCode < 200 || rsp.StatusCode >= 300 {
+		return rsp.StatusCode, statusError(rsp.StatusCode)
+	}
+	return rsp.StatusCode, nil
+}
+
+// newClient returns the HTTP client that sends the deliveries. It can't
+// reach the addresses the extractor can't reach either.
+func newClient() *http.Client {
+	client := extract.NewClient()
+	extract.SetClientDeniedIPs(client, configs.ExtractorDeniedIPs())
+	return client
+}
+
+// isRetryable returns false when the receiver rejected the request,
+// unless it asked us to come back later.
+func isRetryable(err error) bool {
+	var status statusError
+	if !errors.As(err, &status) {
+		return true
+	}
+
+	switch {
+	case status == http.StatusRequestTimeout, status == http.StatusTooManyRequests:
+		return true
+	case status >= 400 && status < 500:
+		return false
+	}
+	return true
+}
+
+func deliverHandler(data interface{}) error {
+	id := data.(int)
+
+	d, err := Deliveries.GetOne(goqu.C("id").Eq(id))
+	if errors.Is(err, ErrDeliveryNotFound) {
+		// The webhook was removed in the meantime.
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	w, err := Webhooks.GetOne(goqu.C("id").Eq(d.WebhookID))
+	if err != nil {
+		return err
+	}
+	if !w.IsEnabled {
+		return nil
+	}
+
+	return d.Send(w)
+}
+
+func deliverFailure(data interface{}, err error) {
+	slog.Warn("webhook delivery failed",
+		slog.Int("id", data.(int)),
+		slog.Any("err", err),
+	)
+}
+
+func purgeDeliveriesHandler(_ interface{}) error {
+	n, err := Deliveries.Purge(time.Now().Add(-DeliveryRetention))
+	if err != nil {
+		return err
+	}
+	slog.Debug("webhook deliveries purged", slog.Int64("count", n))
+	return nil
+}
@@ -0,0 +1,168 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+// Package webhooks contains the models and functions to manage
+// user webhooks and send their deliveries.
+package webhooks
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+
+	"codeberg.org/readeck/readeck/internal/bookmarks"
+	"codeberg.org/readeck/readeck/internal/db"
+	"codeberg.org/readeck/readeck/internal/db/types"
+	"codeberg.org/readeck/readeck/pkg/base58"
+)
+
+const (
+	// TableName is the webhook table name in database.
+	TableName = "webhook"
+)
+
+var (
+	// Webhooks is the webhook manager.
+	Webhooks = Manager{}
+
+	// ErrNotFound is returned when a webhook record was not found.
+	ErrNotFound = errors.New("not found")
+
+	// Events is the list of events a webhook can subscribe to.
+	Events = []string{
+		bookmarks.EventBookmarkCreated,
+		bookmarks.EventBookmarkLoaded,
+		bookmarks.EventBookmarkError,
+		bookmarks.EventBookmarkArchived,
+		bookmarks.EventBookmarkMarked,
+		bookmarks.EventBookmarkDeleted,
+		bookmarks.EventAnnotationCreated,
+		bookmarks.EventAnnotationDeleted,
+	}
+)
+
+// Webhook is a webhook record in database.
+type Webhook struct {
+	ID        int           `db:"id" goqu:"skipinsert,skipupdate"`
+	UID       string        `db:"uid"`
+	UserID    *int          `db:"user_id"`
+	Created   time.Time     `db:"created" goqu:"skipupdate"`
+	Updated   time.Time     `db:"updated"`
+	IsEnabled bool          `db:"is_enabled"`
+	Name      string        `db:"name"`
+	URL       string        `db:"url"`
+	Secret    string        `db:"secret"`
+	Events    types.Strings `db:"events"`
+	BaseURL   string        `db:"base_url"`
+}
+
+// Manager is a query helper for webhook entries.
+type Manager struct{}
+
+// Query returns a prepared goqu SelectDataset that can be extended later.
+func (m *Manager) Query() *goqu.SelectDataset {
+	return db.Q().From(goqu.T(TableName).As("w")).Prepared(true)
+}
+
+// GetOne executes the a select query and returns the first result or an error
+// when there's no result.
+func (m *Manager) GetOne(expressions ...goqu.Expression) (*Webhook, error) {
+	var w Webhook
+	found, err := m.Query().Where(expressions...).ScanStruct(&w)
+
+	switch {
+	case err != nil:
+		return nil, err
+	case !found:
+		return nil, ErrNotFound
+	}
+
+	return &w, nil
+}
+
+// Create inserts a new webhook in the database. A secret
+// is generated when the webhook doesn't have one.
+func (m *Manager) Create(w *Webhook) error {
+	if w.UserID == nil {
+		return errors.New("no webhook user")
+	}
+	if strings.TrimSpace(w.URL) == "" {
+		return errors.New("no URL")
+	}
+
+	w.Created = time.Now()
+	w.Updated = w.Created
+	w.UID = base58.NewUUID()
+	if w.Secret == "" {
+		w.Secret = NewSecret()
+	}
+	if w.Events == nil {
+		w.Events = types.Strings{}
+	}
+
+	ds := db.Q().Insert(TableName).
+		Rows(w).
+		Prepared(true)
+
+	id, err := db.InsertWithID(ds, "id")
+	if err != nil {
+		return err
+	}
+
+	w.ID = id
+	return nil
+}
+
+// Update updates some webhook values.
+func (w *Webhook) Update(v interface{}) error {
+	if w.ID == 0 {
+		return errors.New("no ID")
+	}
+
+	switch v := v.(type) {
+	case map[string]interface{}:
+		v["updated"] = time.Now()
+	case *Webhook:
+		v.Updated = time.Now()
+	}
+
+	_, err := db.Q().Update(TableName).Prepared(true).
+		Set(v).
+		Where(goqu.C("id").Eq(w.ID)).
+		Executor().Exec()
+
+	return err
+}
+
+// Save updates all the webhook values.
+func (w *Webhook) Save() error {
+	return w.Update(w)
+}
+
+// Delete removes a webhook from the database. Its deliveries
+// are removed with it.
+func (w *Webhook) Delete() error {
+	_, err := db.Q().Delete(TableName).Prepared(true).
+		Where(goqu.C("id").Eq(w.ID)).
+		Executor().Exec()
+
+	return err
+}
+
+// HasEvent returns true when the webhook subscribes to the given event.
+func (w *Webhook) HasEvent(name string) bool {
+	return slices.Contains(w.Events, name)
+}
+
+// NewSecret returns a new random signing secret.
+func NewSecret() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
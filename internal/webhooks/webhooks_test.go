@@ -0,0 +1,144 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package webhooks_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/stretchr/testify/require"
+
+	"codeberg.org/readeck/readeck/configs"
+	. "codeberg.org/readeck/readeck/internal/testing" //revive:disable:dot-imports
+	"codeberg.org/readeck/readeck/internal/webhooks"
+)
+
+func TestSign(t *testing.T) {
+	require.Equal(t,
+		"sha256=c834808ab8e8ee19b433e676b0a585c2f49b8e5b368983133174dec0fb0bc6eb",
+		webhooks.Sign("secret", []byte(`{"event":"bookmark.created"}`)),
+	)
+}
+
+// newTestApp returns a test application that can send
+// deliveries to a local test server.
+func newTestApp(t *testing.T) *TestApp {
+	denied := configs.Config.Extractor.DeniedIPs
+	configs.Config.Extractor.DeniedIPs = nil
+	t.Cleanup(func() {
+		configs.Config.Extractor.DeniedIPs = denied
+		configs.InitConfiguration()
+	})
+	return NewTestApp(t)
+}
+
+func TestDeliverySend(t *testing.T) {
+	app := newTestApp(t)
+	defer func() {
+		app.Close(t)
+	}()
+
+	status := http.StatusOK
+	var received *http.Request
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	w := &webhooks.Webhook{
+		UserID:    &app.Users["user"].User.ID,
+		IsEnabled: true,
+		URL:       srv.URL,
+	}
+	require.NoError(t, webhooks.Webhooks.Create(w))
+	require.NotEmpty(t, w.Secret)
+
+	d := &webhooks.Delivery{
+		WebhookID: w.ID,
+		Event:     "bookmark.created",
+		Payload:   `{"event":"bookmark.created"}`,
+	}
+	require.NoError(t, webhooks.Deliveries.Create(d))
+
+	t.Run("success", func(t *testing.T) {
+		require.NoError(t, d.Send(w))
+
+		require.Equal(t, http.MethodPost, received.Method)
+		require.Equal(t, "application/json", received.Header.Get("Content-Type"))
+		require.Equal(t, "bookmark.created", received.Header.Get("X-Readeck-Event"))
+		require.Equal(t, d.UID, received.Header.Get("X-Readeck-Delivery"))
+		require.Equal(t, webhooks.Sign(w.Secret, body), received.Header.Get("X-Readeck-Signature-256"))
+		require.Equal(t, d.Payload, string(body))
+
+		saved, err := webhooks.Deliveries.GetOne(goqu.C("id").Eq(d.ID))
+		require.NoError(t, err)
+		require.Equal(t, 1, saved.Attempts)
+		require.Equal(t, http.StatusOK, saved.StatusCode)
+		require.Empty(t, saved.Error)
+		require.True(t, saved.IsSuccess())
+	})
+
+	t.Run("error", func(t *testing.T) {
+		status = http.StatusBadGateway
+		require.EqualError(t, d.Send(w), "unexpected status 502")
+
+		saved, err := webhooks.Deliveries.GetOne(goqu.C("id").Eq(d.ID))
+		require.NoError(t, err)
+		require.Equal(t, 2, saved.Attempts)
+		require.Equal(t, http.StatusBadGateway, saved.StatusCode)
+		require.Equal(t, "unexpected status 502", saved.Error)
+		require.False(t, saved.IsSuccess())
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		require.NoError(t, w.Delete())
+
+		_, err := webhooks.Deliveries.GetOne(goqu.C("id").Eq(d.ID))
+		require.ErrorIs(t, err, webhooks.ErrDeliveryNotFound)
+	})
+}
+
+func TestDeliveryDeniedIP(t *testing.T) {
+	app := NewTestApp(t)
+	defer func() {
+		app.Close(t)
+	}()
+
+	called := false
+	srv := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		called = true
+	}))
+	defer srv.Close()
+
+	w := &webhooks.Webhook{
+		UserID:    &app.Users["user"].User.ID,
+		IsEnabled: true,
+		URL:       srv.URL,
+	}
+	require.NoError(t, webhooks.Webhooks.Create(w))
+
+	d := &webhooks.Delivery{
+		WebhookID: w.ID,
+		Event:     "bookmark.created",
+		Payload:   `{"event":"bookmark.created"}`,
+	}
+	require.NoError(t, webhooks.Deliveries.Create(d))
+
+	// 127.0.0.1 is in the default denied IP list
+	err := d.Send(w)
+	require.ErrorContains(t, err, "ip 127.0.0.1 is blocked")
+	require.False(t, called)
+
+	saved, err := webhooks.Deliveries.GetOne(goqu.C("id").Eq(d.ID))
+	require.NoError(t, err)
+	require.False(t, saved.IsSuccess())
+	require.Contains(t, saved.Error, "is blocked")
+}
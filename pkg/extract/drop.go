@@ -135,7 +135,7 @@ func (d *Drop) Load(client *http.Client) error {
 	d.Site = d.URL.Hostname()
 
 	if rsp.StatusCode/100 != 2 {
-		return fmt.Errorf("Invalid status code (%d)", rsp.StatusCode)
+		return HTTPStatusError{StatusCode: rsp.StatusCode}
 	}
 
 	switch {
@@ -23,6 +23,7 @@ import (
 	"net/url"
 	"strconv"
 	"strings"
+	"syscall"
 
 	"github.com/go-shiori/dom"
 
@@ -178,6 +179,63 @@ func (e Error) Error() string {
 	return strings.Join(s, ", ")
 }
 
+// Unwrap returns the error list, so [errors.Is] and [errors.As]
+// can match any of its errors.
+func (e Error) Unwrap() []error {
+	return e
+}
+
+// HTTPStatusError is returned when a resource's response
+// has a non 2xx status code.
+type HTTPStatusError struct {
+	StatusCode int
+}
+
+func (e HTTPStatusError) Error() string {
+	return fmt.Sprintf("Invalid status code (%d)", e.StatusCode)
+}
+
+// IsRetryable returns true when an error, or any error of an [Error] list,
+// is likely to be transient. That is a network failure, a timeout, or
+// a response with a 408, 429 or 5xx status code.
+func IsRetryable(err error) bool {
+	// errors.As stops on the first match of a list, which can be
+	// a permanent error while another one is transient.
+	var errs Error
+	if errors.As(err, &errs) {
+		for _, x := range errs {
+			if IsRetryable(x) {
+				return true
+			}
+		}
+		return false
+	}
+
+	var se HTTPStatusError
+	if errors.As(err, &se) {
+		return se.StatusCode == http.StatusRequestTimeout ||
+			se.StatusCode == http.StatusTooManyRequests ||
+			se.StatusCode >= 500
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return dnsErr.IsTimeout || dnsErr.IsTemporary
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return true
+	}
+
+	return errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNRESET)
+}
+
 // URLList hold a list of URLs.
 type URLList map[string]bool
 
@@ -208,6 +266,7 @@ type Extractor struct {
 	logger          *slog.Logger
 	processors      ProcessList
 	errors          Error
+	loadErr         error
 	drops           []*Drop
 	uniqueID        string
 	cachedResources map[string]*cachedResource
@@ -266,9 +325,7 @@ func SetLogger(logger *slog.Logger, args ...any) func(e *Extractor) {
 // by the extraction client.
 func SetDeniedIPs(netList []*net.IPNet) func(e *Extractor) {
 	return func(e *Extractor) {
-		if t, ok := e.client.Transport.(*Transport); ok {
-			t.deniedIPs = netList
-		}
+		SetClientDeniedIPs(e.client, netList)
 	}
 }
 
@@ -317,6 +374,12 @@ func (e *Extractor) Errors() Error {
 	return e.errors
 }
 
+// LoadError returns the error that prevented loading the main
+// document, if any.
+func (e *Extractor) LoadError() error {
+	return e.loadErr
+}
+
 // AddError add a new error to the extractor's error list.
 func (e *Extractor) AddError(err error) {
 	e.errors = append(e.errors, err)
@@ -411,6 +474,9 @@ func (e *Extractor) Run() {
 
 		err := d.Load(e.client)
 		if err != nil {
+			if i == 0 {
+				e.loadErr = err
+			}
 			m.Log().Error("cannot load resource", slog.Any("err", err))
 			return
 		}
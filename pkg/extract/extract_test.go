@@ -7,6 +7,9 @@ package extract
 import (
 	"context"
 	"errors"
+	"fmt"
+	"io"
+	"net"
 	"strconv"
 	"testing"
 
@@ -19,6 +22,33 @@ func TestErrors(t *testing.T) {
 	require.Equal(t, "err1, err2", errlist.Error())
 }
 
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected bool
+	}{
+		{errors.New("err"), false},
+		{HTTPStatusError{404}, false},
+		{HTTPStatusError{429}, true},
+		{HTTPStatusError{503}, true},
+		{fmt.Errorf("get: %w", io.ErrUnexpectedEOF), true},
+		{&net.OpError{Op: "dial", Err: errors.New("connection refused")}, true},
+		{&net.DNSError{Err: "no such host", IsNotFound: true}, false},
+		{&net.DNSError{Err: "timeout", IsTimeout: true}, true},
+		{Error{errors.New("err"), HTTPStatusError{502}}, true},
+		{Error{errors.New("err"), HTTPStatusError{403}}, false},
+		{Error{HTTPStatusError{404}, HTTPStatusError{503}}, true},
+		{Error{HTTPStatusError{503}, HTTPStatusError{404}}, true},
+		{Error{HTTPStatusError{404}, HTTPStatusError{403}}, false},
+	}
+
+	for i, test := range tests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			require.Equal(t, test.expected, IsRetryable(test.err))
+		})
+	}
+}
+
 func TestURLList(t *testing.T) {
 	assert := require.New(t)
 	list := URLList{}
@@ -81,6 +111,7 @@ func TestExtractorRun(t *testing.T) {
 	defer httpmock.DeactivateAndReset()
 
 	httpmock.RegisterResponder("GET", "/404", httpmock.NewJsonResponderOrPanic(404, ""))
+	httpmock.RegisterResponder("GET", "/503", httpmock.NewJsonResponderOrPanic(503, ""))
 	httpmock.RegisterResponder("GET", "/page1", newHTMLResponder(200, "html/ex1.html"))
 	httpmock.RegisterResponder("GET", `=~^/loop/\d+`, newHTMLResponder(200, "html/ex1.html"))
 
@@ -189,6 +220,31 @@ func TestExtractorRun(t *testing.T) {
 		ex.Run()
 		assert.Len(ex.Errors(), 1)
 		assert.Equal("cannot load resource", ex.Errors().Error())
+		assert.False(IsRetryable(ex.LoadError()))
+	})
+
+	t.Run("retryable load error", func(t *testing.T) {
+		assert := require.New(t)
+		ex, _ := New("http://example.net/503", nil)
+		ex.Run()
+		assert.Len(ex.Errors(), 1)
+		assert.True(IsRetryable(ex.LoadError()))
+	})
+
+	t.Run("secondary load error", func(t *testing.T) {
+		assert := require.New(t)
+		ex, _ := New("http://example.net/page1", nil)
+		ex.AddProcessors(func(m *ProcessMessage, next Processor) Processor {
+			if m.Step() == StepBody && m.Position() == 0 {
+				m.Extractor.AddDrop(mustParse("http://example.net/503"))
+			}
+			return next
+		})
+		ex.Run()
+		assert.Len(ex.Errors(), 1)
+		assert.True(IsRetryable(ex.Errors()))
+		assert.NoError(ex.LoadError())
+		assert.NotEmpty(ex.Drop().Body)
 	})
 
 	t.Run("process body", func(t *testing.T) {
@@ -198,6 +198,14 @@ func NewClient() *http.Client {
 	}
 }
 
+// SetClientDeniedIPs sets a list of ip or cidr that cannot be reached
+// by a given client.
+func SetClientDeniedIPs(client *http.Client, netList []*net.IPNet) {
+	if t, ok := client.Transport.(*Transport); ok {
+		t.deniedIPs = netList
+	}
+}
+
 // SetHeader sets a header on a given client.
 func SetHeader(client *http.Client, name, value string) {
 	if t, ok := client.Transport.(*Transport); ok {
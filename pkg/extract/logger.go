@@ -6,7 +6,6 @@ package extract
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"log/slog"
 	"strings"
@@ -70,7 +69,7 @@ func (h *logRecorder) Handle(ctx context.Context, r slog.Record) error {
 	h.extractor.Logs = append(h.extractor.Logs, strings.TrimSpace(b.String()))
 
 	if r.Level >= slog.LevelError {
-		h.extractor.errors = append(h.extractor.errors, errors.New(r.Message))
+		h.extractor.errors = append(h.extractor.errors, newLogError(r))
 	}
 
 	if h.Handler.Enabled(ctx, r.Level) {
@@ -108,3 +107,30 @@ func (h *logRecorder) renderAttr(w *strings.Builder, prefix string, attr slog.At
 	}
 	fmt.Fprintf(w, `%s%s="%v" `, prefix, attr.Key, attr.Value)
 }
+
+// logError is an error recorded from a log entry. Its message
+// is the log's message and it wraps the "err" attribute, if any.
+type logError struct {
+	msg string
+	err error
+}
+
+func newLogError(r slog.Record) error {
+	e := &logError{msg: r.Message}
+	r.Attrs(func(a slog.Attr) bool {
+		if err, ok := a.Value.Any().(error); ok && a.Key == "err" {
+			e.err = err
+			return false
+		}
+		return true
+	})
+	return e
+}
+
+func (e *logError) Error() string {
+	return e.msg
+}
+
+func (e *logError) Unwrap() error {
+	return e.err
+}
@@ -0,0 +1,322 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+// Package feed provides a parser for RSS 2.0, RSS 1.0, Atom and JSON Feed
+// documents. It only keeps what's needed to follow a feed's entries.
+package feed
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html/charset"
+)
+
+// ErrUnknownFormat is returned when a document is not a known feed format.
+var ErrUnknownFormat = errors.New("unknown feed format")
+
+// Feed is a parsed feed.
+type Feed struct {
+	Title   string
+	SiteURL string
+	Items   []Item
+}
+
+// Item is a feed entry.
+type Item struct {
+	// ID is the entry's unique identifier. It's the entry's URL
+	// when the feed doesn't provide one.
+	ID        string
+	URL       string
+	Title     string
+	Summary   string
+	Authors   []string
+	Published time.Time
+}
+
+// Parse reads a feed document.
+func Parse(r io.Reader) (*Feed, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	data = bytes.TrimSpace(data)
+
+	var res *Feed
+	if bytes.HasPrefix(data, []byte("{")) {
+		res, err = parseJSON(data)
+	} else {
+		res, err = parseXML(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for i, x := range res.Items {
+		res.Items[i].Title = strings.TrimSpace(x.Title)
+		res.Items[i].URL = strings.TrimSpace(x.URL)
+		res.Items[i].ID = strings.TrimSpace(x.ID)
+		if res.Items[i].ID == "" {
+			res.Items[i].ID = res.Items[i].URL
+		}
+	}
+	res.Title = strings.TrimSpace(res.Title)
+	res.SiteURL = strings.TrimSpace(res.SiteURL)
+
+	return res, nil
+}
+
+type rssItem struct {
+	Title string   `xml:"title"`
+	Links []string `xml:"link"`
+	GUID  struct {
+		Value       string `xml:",chardata"`
+		IsPermaLink string `xml:"isPermaLink,attr"`
+	} `xml:"guid"`
+	About       string   `xml:"http://www.w3.org/1999/02/22-rdf-syntax-ns# about,attr"`
+	Description string   `xml:"description"`
+	PubDate     string   `xml:"pubDate"`
+	Date        string   `xml:"http://purl.org/dc/elements/1.1/ date"`
+	Author      string   `xml:"author"`
+	Creators    []string `xml:"http://purl.org/dc/elements/1.1/ creator"`
+}
+
+type rssChannel struct {
+	Title string    `xml:"title"`
+	Links []string  `xml:"link"`
+	Items []rssItem `xml:"item"`
+}
+
+type rssDocument struct {
+	Channel rssChannel `xml:"channel"`
+	// RSS 1.0 items are siblings of the channel.
+	Items []rssItem `xml:"item"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+}
+
+type atomEntry struct {
+	ID        string     `xml:"id"`
+	Title     string     `xml:"title"`
+	Links     []atomLink `xml:"link"`
+	Published string     `xml:"published"`
+	Updated   string     `xml:"updated"`
+	Summary   string     `xml:"summary"`
+	Content   string     `xml:"content"`
+	Authors   []struct {
+		Name string `xml:"name"`
+	} `xml:"author"`
+}
+
+type atomDocument struct {
+	Title   string      `xml:"title"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type jsonDocument struct {
+	Version     string `json:"version"`
+	Title       string `json:"title"`
+	HomePageURL string `json:"home_page_url"`
+	Items       []struct {
+		ID            any          `json:"id"`
+		URL           string       `json:"url"`
+		ExternalURL   string       `json:"external_url"`
+		Title         string       `json:"title"`
+		Summary       string       `json:"summary"`
+		ContentText   string       `json:"content_text"`
+		DatePublished string       `json:"date_published"`
+		Author        *jsonAuthor  `json:"author"`
+		Authors       []jsonAuthor `json:"authors"`
+	} `json:"items"`
+}
+
+type jsonAuthor struct {
+	Name string `json:"name"`
+}
+
+func parseXML(data []byte) (*Feed, error) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	dec.CharsetReader = charset.NewReaderLabel
+	dec.Strict = false
+
+	// Find the root element
+	var root xml.StartElement
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return nil, ErrUnknownFormat
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrUnknownFormat, err)
+		}
+		if x, ok := tok.(xml.StartElement); ok {
+			root = x
+			break
+		}
+	}
+
+	switch root.Name.Local {
+	case "rss", "RDF":
+		doc := rssDocument{}
+		if err := dec.DecodeElement(&doc, &root); err != nil {
+			return nil, err
+		}
+		res := &Feed{
+			Title:   doc.Channel.Title,
+			SiteURL: firstValue(doc.Channel.Links),
+		}
+		for _, x := range append(doc.Channel.Items, doc.Items...) {
+			res.Items = append(res.Items, x.item())
+		}
+		return res, nil
+	case "feed":
+		doc := atomDocument{}
+		if err := dec.DecodeElement(&doc, &root); err != nil {
+			return nil, err
+		}
+		res := &Feed{
+			Title:   doc.Title,
+			SiteURL: atomAlternate(doc.Links),
+		}
+		for _, x := range doc.Entries {
+			res.Items = append(res.Items, x.item())
+		}
+		return res, nil
+	}
+
+	return nil, ErrUnknownFormat
+}
+
+func parseJSON(data []byte) (*Feed, error) {
+	doc := jsonDocument{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrUnknownFormat, err)
+	}
+	if !strings.HasPrefix(doc.Version, "https://jsonfeed.org/version/") {
+		return nil, ErrUnknownFormat
+	}
+
+	res := &Feed{
+		Title:   doc.Title,
+		SiteURL: doc.HomePageURL,
+	}
+	for _, x := range doc.Items {
+		item := Item{
+			URL:       firstValue([]string{x.URL, x.ExternalURL}),
+			Title:     x.Title,
+			Summary:   firstValue([]string{x.Summary, x.ContentText}),
+			Published: parseTime(x.DatePublished),
+		}
+		if x.ID != nil {
+			item.ID = fmt.Sprint(x.ID)
+		}
+		if x.Author != nil && x.Author.Name != "" {
+			item.Authors = append(item.Authors, x.Author.Name)
+		}
+		for _, a := range x.Authors {
+			if a.Name != "" {
+				item.Authors = append(item.Authors, a.Name)
+			}
+		}
+		res.Items = append(res.Items, item)
+	}
+
+	return res, nil
+}
+
+func (x rssItem) item() Item {
+	res := Item{
+		ID:        firstValue([]string{x.GUID.Value, x.About}),
+		URL:       firstValue(x.Links),
+		Title:     x.Title,
+		Summary:   x.Description,
+		Published: parseTime(firstValue([]string{x.PubDate, x.Date})),
+	}
+	if res.URL == "" && x.GUID.IsPermaLink != "false" && strings.HasPrefix(x.GUID.Value, "http") {
+		res.URL = x.GUID.Value
+	}
+	for _, a := range append([]string{x.Author}, x.Creators...) {
+		if a = strings.TrimSpace(a); a != "" {
+			res.Authors = append(res.Authors, a)
+		}
+	}
+	return res
+}
+
+func (x atomEntry) item() Item {
+	res := Item{
+		ID:        x.ID,
+		URL:       atomAlternate(x.Links),
+		Title:     x.Title,
+		Summary:   firstValue([]string{x.Summary, x.Content}),
+		Published: parseTime(firstValue([]string{x.Published, x.Updated})),
+	}
+	for _, a := range x.Authors {
+		if a.Name = strings.TrimSpace(a.Name); a.Name != "" {
+			res.Authors = append(res.Authors, a.Name)
+		}
+	}
+	return res
+}
+
+// atomAlternate returns the first alternate link's URL.
+func atomAlternate(links []atomLink) string {
+	for _, l := range links {
+		if l.Rel == "" || l.Rel == "alternate" {
+			return l.Href
+		}
+	}
+	return ""
+}
+
+func firstValue(values []string) string {
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+var timeLayouts = []string{
+	time.RFC3339,
+	time.RFC1123Z,
+	time.RFC1123,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"Mon, 2 Jan 2006 15:04 -0700",
+	"2 Jan 2006 15:04:05 -0700",
+	"2 Jan 2006 15:04:05 MST",
+	time.RFC822Z,
+	time.RFC822,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	time.DateOnly,
+}
+
+// parseTime returns the time of a date string, in any of the formats
+// found in feeds. It returns a zero time when the date can't be read.
+func parseTime(s string) time.Time {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}
+	}
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
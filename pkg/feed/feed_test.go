@@ -0,0 +1,198 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package feed_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"codeberg.org/readeck/readeck/pkg/feed"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		expected *feed.Feed
+	}{
+		{
+			"rss 2.0",
+			`<?xml version="1.0" encoding="UTF-8"?>
+			<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom" xmlns:dc="http://purl.org/dc/elements/1.1/">
+			<channel>
+				<title>Example</title>
+				<atom:link href="https://example.net/feed.xml" rel="self" />
+				<link>https://example.net/</link>
+				<item>
+					<title> First post </title>
+					<link>https://example.net/1</link>
+					<guid isPermaLink="false">post-1</guid>
+					<description>Some &lt;b&gt;text&lt;/b&gt;</description>
+					<pubDate>Tue, 04 Mar 2025 10:30:00 +0100</pubDate>
+					<dc:creator>Alice</dc:creator>
+				</item>
+				<item>
+					<title>Second post</title>
+					<guid>https://example.net/2</guid>
+					<pubDate>Wed, 5 Mar 2025 08:00:00 GMT</pubDate>
+				</item>
+			</channel>
+			</rss>`,
+			&feed.Feed{
+				Title:   "Example",
+				SiteURL: "https://example.net/",
+				Items: []feed.Item{
+					{
+						ID:        "post-1",
+						URL:       "https://example.net/1",
+						Title:     "First post",
+						Summary:   "Some <b>text</b>",
+						Authors:   []string{"Alice"},
+						Published: time.Date(2025, 3, 4, 10, 30, 0, 0, time.FixedZone("", 3600)),
+					},
+					{
+						ID:        "https://example.net/2",
+						URL:       "https://example.net/2",
+						Title:     "Second post",
+						Published: time.Date(2025, 3, 5, 8, 0, 0, 0, time.UTC),
+					},
+				},
+			},
+		},
+		{
+			"rss 1.0",
+			`<?xml version="1.0" encoding="ISO-8859-1"?>
+			<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns="http://purl.org/rss/1.0/"
+			 xmlns:dc="http://purl.org/dc/elements/1.1/">
+			<channel rdf:about="https://example.org/">
+				<title>Caf` + "\xe9" + `</title>
+				<link>https://example.org/</link>
+			</channel>
+			<item rdf:about="https://example.org/a">
+				<title>A</title>
+				<link>https://example.org/a</link>
+				<dc:date>2025-01-02T03:04:05Z</dc:date>
+			</item>
+			</rdf:RDF>`,
+			&feed.Feed{
+				Title:   "Café",
+				SiteURL: "https://example.org/",
+				Items: []feed.Item{
+					{
+						ID:        "https://example.org/a",
+						URL:       "https://example.org/a",
+						Title:     "A",
+						Published: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+					},
+				},
+			},
+		},
+		{
+			"atom",
+			`<?xml version="1.0" encoding="utf-8"?>
+			<feed xmlns="http://www.w3.org/2005/Atom">
+				<title>Atom example</title>
+				<link rel="self" href="https://example.com/atom.xml"/>
+				<link href="https://example.com/"/>
+				<entry>
+					<id>urn:uuid:1225c695-cfb8-4ebb-aaaa-80da344efa6a</id>
+					<title>An entry</title>
+					<link rel="alternate" type="text/html" href="https://example.com/entry"/>
+					<link rel="enclosure" href="https://example.com/file.mp3"/>
+					<updated>2025-02-01T12:00:00Z</updated>
+					<summary>A summary</summary>
+					<author><name>Bob</name></author>
+				</entry>
+			</feed>`,
+			&feed.Feed{
+				Title:   "Atom example",
+				SiteURL: "https://example.com/",
+				Items: []feed.Item{
+					{
+						ID:        "urn:uuid:1225c695-cfb8-4ebb-aaaa-80da344efa6a",
+						URL:       "https://example.com/entry",
+						Title:     "An entry",
+						Summary:   "A summary",
+						Authors:   []string{"Bob"},
+						Published: time.Date(2025, 2, 1, 12, 0, 0, 0, time.UTC),
+					},
+				},
+			},
+		},
+		{
+			"json feed",
+			`{
+				"version": "https://jsonfeed.org/version/1.1",
+				"title": "JSON example",
+				"home_page_url": "https://example.com/",
+				"items": [
+					{
+						"id": 1,
+						"url": "https://example.com/1",
+						"title": "One",
+						"content_text": "Some text",
+						"date_published": "2025-02-01T12:00:00+01:00",
+						"authors": [{"name": "Carol"}]
+					},
+					{
+						"id": "2",
+						"external_url": "https://example.org/2"
+					}
+				]
+			}`,
+			&feed.Feed{
+				Title:   "JSON example",
+				SiteURL: "https://example.com/",
+				Items: []feed.Item{
+					{
+						ID:        "1",
+						URL:       "https://example.com/1",
+						Title:     "One",
+						Summary:   "Some text",
+						Authors:   []string{"Carol"},
+						Published: time.Date(2025, 2, 1, 12, 0, 0, 0, time.FixedZone("", 3600)),
+					},
+					{
+						ID:  "2",
+						URL: "https://example.org/2",
+					},
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res, err := feed.Parse(strings.NewReader(test.data))
+			require.NoError(t, err)
+			require.Equal(t, test.expected.Title, res.Title)
+			require.Equal(t, test.expected.SiteURL, res.SiteURL)
+			require.Len(t, res.Items, len(test.expected.Items))
+			for i, x := range test.expected.Items {
+				require.Equal(t, x.ID, res.Items[i].ID)
+				require.Equal(t, x.URL, res.Items[i].URL)
+				require.Equal(t, x.Title, res.Items[i].Title)
+				require.Equal(t, x.Summary, res.Items[i].Summary)
+				require.Equal(t, x.Authors, res.Items[i].Authors)
+				require.True(t, x.Published.Equal(res.Items[i].Published), res.Items[i].Published)
+			}
+		})
+	}
+
+	t.Run("errors", func(t *testing.T) {
+		for _, data := range []string{
+			"",
+			"<html><body></body></html>",
+			`{"title": "not a feed"}`,
+			"not xml",
+		} {
+			_, err := feed.Parse(strings.NewReader(data))
+			require.ErrorIs(t, err, feed.ErrUnknownFormat, data)
+		}
+	})
+}
@@ -366,6 +366,21 @@ func (f IntegerField) Choices() ValueChoices[int] {
 	return GetChoices[int](f)
 }
 
+/* Float field
+   --------------------------------------------------------------- */
+
+// FloatField is a field that holds a [float64] value.
+type FloatField struct {
+	*BaseField[float64]
+}
+
+// NewFloatField returns a FloatField instance.
+func NewFloatField(name string, options ...any) *FloatField {
+	return &FloatField{
+		NewBaseField(name, DecodeFloat, options...),
+	}
+}
+
 /* Datetime field
    --------------------------------------------------------------- */
 
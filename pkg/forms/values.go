@@ -219,6 +219,44 @@ var DecodeInt = NewValueDecoder(
 	strconv.Itoa,
 )
 
+// DecodeFloat is a [Value] decoder for floats.
+var DecodeFloat = NewValueDecoder(
+	func(data any) Value[float64] {
+		value := NewValue[float64]()
+
+		if data == nil {
+			value.F |= IsNil
+			return value
+		}
+
+		switch V := data.(type) {
+		case float64:
+			value.set(V)
+		case int:
+			value.set(float64(V))
+		}
+
+		return value
+	},
+	func(text string) Value[float64] {
+		value := NewValue[float64]()
+
+		if len(text) == 0 {
+			value.F |= IsNil
+		}
+		v, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return value
+		}
+		value.set(v)
+
+		return value
+	},
+	func(v float64) string {
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	},
+)
+
 // DecodeTime is a [Value] decoder for [time.Time] values.
 var DecodeTime = NewValueDecoder(
 	func(data any) Value[time.Time] {
@@ -185,6 +185,42 @@ func TestDecoder(t *testing.T) {
 		}))
 	})
 
+	t.Run("float", func(t *testing.T) {
+		t.Run("any", runAnyDecoder(forms.DecodeFloat, []anyValueTest[float64]{
+			{nil, forms.Value[float64]{
+				F: forms.IsNil | forms.IsEmpty,
+			}},
+			{10, forms.Value[float64]{
+				V: 10,
+				F: forms.IsOk,
+			}},
+			{float64(0.25), forms.Value[float64]{
+				V: 0.25,
+				F: forms.IsOk,
+			}},
+			{"abc", forms.Value[float64]{
+				F: forms.IsEmpty,
+			}},
+		}))
+
+		t.Run("text", runTextDecoder(forms.DecodeFloat, []textValueTest[float64]{
+			{"", forms.Value[float64]{
+				F: forms.IsNil | forms.IsEmpty,
+			}},
+			{"0.5", forms.Value[float64]{
+				V: 0.5,
+				F: forms.IsOk,
+			}},
+			{"-5", forms.Value[float64]{
+				V: -5,
+				F: forms.IsOk,
+			}},
+			{"abc", forms.Value[float64]{
+				F: forms.IsEmpty,
+			}},
+		}))
+	})
+
 	t.Run("time.Time", func(t *testing.T) {
 		d1, _ := time.Parse(time.DateOnly, "2020-01-30")
 		d2, _ := time.Parse(time.DateTime, "2020-01-30 14:24:06")
@@ -0,0 +1,223 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+// Package mailmsg reads email messages, their content and their inline parts.
+package mailmsg
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html/charset"
+)
+
+// maxDepth is the maximum nesting level of multipart bodies.
+const maxDepth = 10
+
+// ErrNoContent is returned when a message has no text or HTML content.
+var ErrNoContent = errors.New("message has no content")
+
+var wordDecoder = &mime.WordDecoder{
+	CharsetReader: charset.NewReaderLabel,
+}
+
+// Part is an inline part of a message, usually an image
+// referenced by the HTML content.
+type Part struct {
+	ContentID   string
+	ContentType string
+	Data        []byte
+}
+
+// Message is an email message.
+type Message struct {
+	MessageID  string
+	Subject    string
+	From       *mail.Address
+	Date       time.Time
+	Recipients []string
+
+	// HTML is the HTML content of the message, in UTF-8. It's built from the
+	// text content when the message has no HTML content.
+	HTML string
+
+	// Inline contains the parts of the message with a Content-ID.
+	Inline []Part
+
+	text string
+}
+
+// Parse reads an email message.
+func Parse(r io.Reader) (*Message, error) {
+	msg, err := mail.ReadMessage(r)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &Message{
+		MessageID: strings.Trim(strings.TrimSpace(msg.Header.Get("Message-Id")), "<>"),
+		Subject:   decodeHeader(msg.Header.Get("Subject")),
+	}
+
+	parser := &mail.AddressParser{WordDecoder: wordDecoder}
+	if from, err := parser.ParseList(msg.Header.Get("From")); err == nil && len(from) > 0 {
+		res.From = from[0]
+	}
+	if date, err := msg.Header.Date(); err == nil {
+		res.Date = date
+	}
+
+	// The envelope recipients come first, they're the most reliable.
+	for _, name := range []string{"Delivered-To", "X-Original-To", "To", "Cc"} {
+		for _, value := range msg.Header[name] {
+			list, err := parser.ParseList(value)
+			if err != nil {
+				continue
+			}
+			for _, x := range list {
+				res.Recipients = append(res.Recipients, strings.ToLower(x.Address))
+			}
+		}
+	}
+
+	if err = res.readPart(textproto.MIMEHeader(msg.Header), msg.Body, 0); err != nil {
+		return nil, err
+	}
+
+	if res.HTML == "" && res.text != "" {
+		res.HTML = textToHTML(res.text)
+	}
+	if res.HTML == "" {
+		return nil, ErrNoContent
+	}
+
+	return res, nil
+}
+
+// SenderName returns the sender's name or, when it's empty,
+// the sender's address.
+func (m *Message) SenderName() string {
+	if m.From == nil {
+		return ""
+	}
+	if m.From.Name != "" {
+		return m.From.Name
+	}
+	return m.From.Address
+}
+
+func (m *Message) readPart(header textproto.MIMEHeader, body io.Reader, depth int) error {
+	if depth > maxDepth {
+		return errors.New("too many nested parts")
+	}
+
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		mediaType, params = "text/plain", map[string]string{}
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			p, err := mr.NextRawPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err := m.readPart(p.Header, p, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+
+	data, err := io.ReadAll(decodeTransfer(header.Get("Content-Transfer-Encoding"), body))
+	if err != nil {
+		return err
+	}
+
+	disposition, _, _ := mime.ParseMediaType(header.Get("Content-Disposition"))
+	isAttachment := disposition == "attachment"
+
+	switch {
+	case mediaType == "text/html" && !isAttachment && m.HTML == "":
+		m.HTML, err = decodeCharset(data, params["charset"])
+		return err
+	case mediaType == "text/plain" && !isAttachment && m.text == "":
+		m.text, err = decodeCharset(data, params["charset"])
+		return err
+	}
+
+	if cid := strings.Trim(strings.TrimSpace(header.Get("Content-Id")), "<>"); cid != "" {
+		m.Inline = append(m.Inline, Part{
+			ContentID:   cid,
+			ContentType: mediaType,
+			Data:        data,
+		})
+	}
+
+	return nil
+}
+
+func decodeHeader(value string) string {
+	res, err := wordDecoder.DecodeHeader(value)
+	if err != nil {
+		return strings.TrimSpace(value)
+	}
+	return strings.TrimSpace(res)
+}
+
+func decodeTransfer(encoding string, r io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, r)
+	case "quoted-printable":
+		return quotedprintable.NewReader(r)
+	}
+	return r
+}
+
+func decodeCharset(data []byte, label string) (string, error) {
+	if label == "" || strings.EqualFold(label, "utf-8") || strings.EqualFold(label, "us-ascii") {
+		return string(data), nil
+	}
+
+	r, err := charset.NewReaderLabel(label, bytes.NewReader(data))
+	if err != nil {
+		// Unknown charset, keep the content as is.
+		return string(data), nil //nolint:nilerr
+	}
+	res, err := io.ReadAll(r)
+	return string(res), err
+}
+
+// textToHTML converts a text content to HTML paragraphs.
+func textToHTML(text string) string {
+	buf := new(strings.Builder)
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	for _, p := range strings.Split(text, "\n\n") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		lines := strings.Split(p, "\n")
+		for i, x := range lines {
+			lines[i] = html.EscapeString(x)
+		}
+		fmt.Fprintf(buf, "<p>%s</p>\n", strings.Join(lines, "<br>\n"))
+	}
+	return buf.String()
+}
@@ -0,0 +1,96 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package mailmsg_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"codeberg.org/readeck/readeck/pkg/mailmsg"
+)
+
+func TestParse(t *testing.T) {
+	t.Run("multipart", func(t *testing.T) {
+		msg, err := mailmsg.Parse(strings.NewReader(`Message-ID: <abc@example.net>
+Date: Tue, 04 Mar 2025 10:30:00 +0100
+From: =?UTF-8?Q?The_Weekly_=E2=80=94_News?= <news@example.net>
+To: Alice <readeck+Secret@example.org>
+Delivered-To: readeck+secret@example.org
+Subject: =?ISO-8859-1?Q?Caf=E9?= news
+MIME-Version: 1.0
+Content-Type: multipart/related; boundary="rel"
+
+--rel
+Content-Type: multipart/alternative; boundary="alt"
+
+--alt
+Content-Type: text/plain; charset=utf-8
+
+Plain text
+--alt
+Content-Type: text/html; charset=iso-8859-1
+Content-Transfer-Encoding: quoted-printable
+
+<p>Caf=E9 <img src=3D"cid:img1@example.net"></p>
+--alt--
+
+--rel
+Content-Type: image/png
+Content-Transfer-Encoding: base64
+Content-ID: <img1@example.net>
+Content-Disposition: inline
+
+iVBORw0KGgo=
+--rel
+Content-Type: application/pdf
+Content-Disposition: attachment; filename="file.pdf"
+
+%PDF
+--rel--
+`))
+		require.NoError(t, err)
+		require.Equal(t, "abc@example.net", msg.MessageID)
+		require.Equal(t, "Café news", msg.Subject)
+		require.Equal(t, "The Weekly — News", msg.SenderName())
+		require.Equal(t, "news@example.net", msg.From.Address)
+		require.Equal(t, time.Date(2025, 3, 4, 9, 30, 0, 0, time.UTC), msg.Date.UTC())
+		require.Equal(t, []string{"readeck+secret@example.org", "readeck+secret@example.org"}, msg.Recipients)
+		require.Equal(t, `<p>Café <img src="cid:img1@example.net"></p>`, msg.HTML)
+		require.Equal(t, []mailmsg.Part{{
+			ContentID:   "img1@example.net",
+			ContentType: "image/png",
+			Data:        []byte("\x89PNG\r\n\x1a\n"),
+		}}, msg.Inline)
+	})
+
+	t.Run("text", func(t *testing.T) {
+		msg, err := mailmsg.Parse(strings.NewReader(`From: news@example.net
+To: readeck@example.org
+Subject: Hello
+
+First <line>
+second line
+
+Last paragraph
+`))
+		require.NoError(t, err)
+		require.Equal(t, "news@example.net", msg.SenderName())
+		require.Equal(t, "<p>First &lt;line&gt;<br>\nsecond line</p>\n<p>Last paragraph</p>\n", msg.HTML)
+		require.Empty(t, msg.Inline)
+	})
+
+	t.Run("no content", func(t *testing.T) {
+		_, err := mailmsg.Parse(strings.NewReader("From: news@example.net\nSubject: Hello\n\n"))
+		require.ErrorIs(t, err, mailmsg.ErrNoContent)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := mailmsg.Parse(strings.NewReader("not a message"))
+		require.Error(t, err)
+	})
+}
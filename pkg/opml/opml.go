@@ -0,0 +1,150 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+// Package opml provides an OPML reader and writer for feed subscription lists.
+// See http://opml.org/spec2.opml
+package opml
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html/charset"
+)
+
+// MimeType is the OPML mime type.
+const MimeType = "text/x-opml"
+
+// Document is an OPML document.
+type Document struct {
+	XMLName xml.Name `xml:"opml"`
+	Version string   `xml:"version,attr"`
+	Head    Head     `xml:"head"`
+	Body    Body     `xml:"body"`
+}
+
+// Head is the document's head.
+type Head struct {
+	Title       string `xml:"title,omitempty"`
+	DateCreated string `xml:"dateCreated,omitempty"`
+}
+
+// Body is the document's body.
+type Body struct {
+	Outlines []Outline `xml:"outline"`
+}
+
+// Outline is an outline element. It's a subscription when it has an
+// XMLURL value and a folder when it contains other outlines.
+type Outline struct {
+	Text     string    `xml:"text,attr"`
+	Title    string    `xml:"title,attr,omitempty"`
+	Type     string    `xml:"type,attr,omitempty"`
+	XMLURL   string    `xml:"xmlUrl,attr,omitempty"`
+	HTMLURL  string    `xml:"htmlUrl,attr,omitempty"`
+	Category string    `xml:"category,attr,omitempty"`
+	Outlines []Outline `xml:"outline"`
+}
+
+// Subscription is a feed subscription found in a document.
+type Subscription struct {
+	Title   string
+	XMLURL  string
+	HTMLURL string
+	// Categories contains the subscription's categories and the
+	// names of the folders it belongs to.
+	Categories []string
+}
+
+// New returns a new, empty, document.
+func New(title string, created time.Time) *Document {
+	return &Document{
+		Version: "2.0",
+		Head: Head{
+			Title:       title,
+			DateCreated: created.UTC().Format(time.RFC1123),
+		},
+	}
+}
+
+// Parse reads an OPML document.
+func Parse(r io.Reader) (*Document, error) {
+	dec := xml.NewDecoder(r)
+	dec.CharsetReader = charset.NewReaderLabel
+	dec.Strict = false
+
+	res := &Document{}
+	if err := dec.Decode(res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Add adds a subscription to the document.
+func (d *Document) Add(s Subscription) {
+	d.Body.Outlines = append(d.Body.Outlines, Outline{
+		Text:     s.Title,
+		Title:    s.Title,
+		Type:     "rss",
+		XMLURL:   s.XMLURL,
+		HTMLURL:  s.HTMLURL,
+		Category: strings.Join(s.Categories, ","),
+	})
+}
+
+// Subscriptions returns all the subscriptions in the document,
+// including the ones in folders.
+func (d *Document) Subscriptions() []Subscription {
+	res := []Subscription{}
+
+	var walk func(outlines []Outline, folders []string)
+	walk = func(outlines []Outline, folders []string) {
+		for _, o := range outlines {
+			if o.XMLURL != "" {
+				s := Subscription{
+					Title:      strings.TrimSpace(o.Title),
+					XMLURL:     strings.TrimSpace(o.XMLURL),
+					HTMLURL:    strings.TrimSpace(o.HTMLURL),
+					Categories: append([]string{}, folders...),
+				}
+				if s.Title == "" {
+					s.Title = strings.TrimSpace(o.Text)
+				}
+				for _, c := range strings.Split(o.Category, ",") {
+					if c = strings.Trim(strings.TrimSpace(c), "/"); c != "" {
+						s.Categories = append(s.Categories, c)
+					}
+				}
+				res = append(res, s)
+			}
+
+			if len(o.Outlines) > 0 {
+				name := strings.TrimSpace(o.Title)
+				if name == "" {
+					name = strings.TrimSpace(o.Text)
+				}
+				next := folders
+				if name != "" && o.XMLURL == "" {
+					next = append(append([]string{}, folders...), name)
+				}
+				walk(o.Outlines, next)
+			}
+		}
+	}
+	walk(d.Body.Outlines, []string{})
+
+	return res
+}
+
+// Write writes the document to w.
+func (d *Document) Write(w io.Writer) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	return enc.Encode(d)
+}
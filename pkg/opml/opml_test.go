@@ -0,0 +1,82 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package opml_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"codeberg.org/readeck/readeck/pkg/opml"
+)
+
+func TestParse(t *testing.T) {
+	doc, err := opml.Parse(strings.NewReader(`<?xml version="1.0" encoding="UTF-8"?>
+	<opml version="2.0">
+		<head><title>Subscriptions</title></head>
+		<body>
+			<outline text="Example" type="rss" xmlUrl="https://example.net/feed.xml" htmlUrl="https://example.net/" />
+			<outline text="Tech">
+				<outline text="Blog" title="Some blog" type="rss" xmlUrl=" https://example.org/atom.xml " category="/news,/tech/go" />
+				<outline text="Empty folder" />
+			</outline>
+			<outline text="Not a feed" />
+		</body>
+	</opml>`))
+	require.NoError(t, err)
+	require.Equal(t, "Subscriptions", doc.Head.Title)
+
+	require.Equal(t, []opml.Subscription{
+		{
+			Title:      "Example",
+			XMLURL:     "https://example.net/feed.xml",
+			HTMLURL:    "https://example.net/",
+			Categories: []string{},
+		},
+		{
+			Title:      "Some blog",
+			XMLURL:     "https://example.org/atom.xml",
+			Categories: []string{"Tech", "news", "tech/go"},
+		},
+	}, doc.Subscriptions())
+
+	_, err = opml.Parse(strings.NewReader("not opml"))
+	require.Error(t, err)
+}
+
+func TestWrite(t *testing.T) {
+	doc := opml.New("Feeds", time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC))
+	doc.Add(opml.Subscription{
+		Title:      "Example & co",
+		XMLURL:     "https://example.net/feed.xml",
+		HTMLURL:    "https://example.net/",
+		Categories: []string{"a", "b"},
+	})
+
+	buf := new(bytes.Buffer)
+	require.NoError(t, doc.Write(buf))
+	require.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>
+<opml version="2.0">
+  <head>
+    <title>Feeds</title>
+    <dateCreated>Sat, 01 Mar 2025 10:00:00 UTC</dateCreated>
+  </head>
+  <body>
+    <outline text="Example &amp; co" title="Example &amp; co" type="rss" xmlUrl="https://example.net/feed.xml" htmlUrl="https://example.net/" category="a,b"></outline>
+  </body>
+</opml>`, buf.String())
+
+	res, err := opml.Parse(buf)
+	require.NoError(t, err)
+	require.Equal(t, []opml.Subscription{{
+		Title:      "Example & co",
+		XMLURL:     "https://example.net/feed.xml",
+		HTMLURL:    "https://example.net/",
+		Categories: []string{"a", "b"},
+	}}, res.Subscriptions())
+}
@@ -0,0 +1,77 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+// Package partialmd5 implements the partial MD5 digest KOReader uses
+// to identify a document. Only a few 1KiB windows, at growing offsets,
+// are part of the digest so it stays fast on large files.
+package partialmd5
+
+import (
+	"crypto/md5" //nolint:gosec
+	"encoding/hex"
+	"hash"
+	"io"
+)
+
+const (
+	// windowSize is the size of each hashed window.
+	windowSize = 1024
+
+	// windowCount is the maximum number of hashed windows.
+	windowCount = 12
+)
+
+// offsets are the start of each window: 0, 1024, 4096, 16384...
+var offsets = func() (res [windowCount]int64) {
+	for i := 1; i < windowCount; i++ {
+		res[i] = windowSize << (2 * (i - 1))
+	}
+	return
+}()
+
+// Writer is an [io.Writer] computing the partial MD5 digest
+// of everything written to it.
+type Writer struct {
+	h   hash.Hash
+	pos int64
+}
+
+// New returns a new [Writer].
+func New() *Writer {
+	return &Writer{h: md5.New()} //nolint:gosec
+}
+
+// Write implements [io.Writer]. It never fails.
+func (w *Writer) Write(p []byte) (int, error) {
+	start := w.pos
+	end := start + int64(len(p))
+
+	for _, o := range offsets {
+		if o >= end {
+			break
+		}
+		s := max(o, start)
+		e := min(o+windowSize, end)
+		if s < e {
+			w.h.Write(p[s-start : e-start])
+		}
+	}
+
+	w.pos = end
+	return len(p), nil
+}
+
+// Sum returns the hexadecimal digest of the data written so far.
+func (w *Writer) Sum() string {
+	return hex.EncodeToString(w.h.Sum(nil))
+}
+
+// Sum returns the partial MD5 digest of r's content.
+func Sum(r io.Reader) (string, error) {
+	w := New()
+	if _, err := io.Copy(w, r); err != nil {
+		return "", err
+	}
+	return w.Sum(), nil
+}
@@ -0,0 +1,67 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package partialmd5_test
+
+import (
+	"bytes"
+	"crypto/md5" //nolint:gosec
+	"encoding/hex"
+	"io"
+	"math/rand/v2"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"codeberg.org/readeck/readeck/pkg/partialmd5"
+)
+
+// reference is a port of KOReader's util.partialMD5 function.
+func reference(r io.ReadSeeker) string {
+	h := md5.New() //nolint:gosec
+	buf := make([]byte, 1024)
+	for i := -1; i <= 10; i++ {
+		offset := int64(0)
+		if i >= 0 {
+			offset = 1024 << (2 * i)
+		}
+		if _, err := r.Seek(offset, io.SeekStart); err != nil {
+			break
+		}
+		n, _ := io.ReadFull(r, buf)
+		if n == 0 {
+			break
+		}
+		h.Write(buf[:n])
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func TestPartialMD5(t *testing.T) {
+	rnd := rand.New(rand.NewPCG(1, 2)) //nolint:gosec
+
+	for _, size := range []int{0, 10, 1024, 1500, 4096, 5000, 20000, 300000, 1200000} {
+		t.Run(strconv.Itoa(size), func(t *testing.T) {
+			data := make([]byte, size)
+			for i := range data {
+				data[i] = byte(rnd.IntN(256))
+			}
+			expected := reference(bytes.NewReader(data))
+
+			res, err := partialmd5.Sum(bytes.NewReader(data))
+			require.NoError(t, err)
+			require.Equal(t, expected, res)
+
+			// Small and uneven writes
+			w := partialmd5.New()
+			for p := data; len(p) > 0; {
+				n := min(len(p), 1+rnd.IntN(3000))
+				w.Write(p[:n]) //nolint:errcheck
+				p = p[n:]
+			}
+			require.Equal(t, expected, w.Sum())
+		})
+	}
+}
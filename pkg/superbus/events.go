@@ -12,6 +12,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/doug-martin/goqu/v9"
 	"github.com/go-redis/redis/v8"
 )
 
@@ -160,3 +161,121 @@ func (m *RedisEventManager) Push(name string, value []byte) error {
 func (m *RedisEventManager) On(name string, f EventHandler) {
 	m.handlers[name] = f
 }
+
+// DBEventManager is an event manager using a database table as a message queue.
+// Several processes can share the same table, an event is only received once.
+type DBEventManager struct {
+	db        *goqu.Database
+	wg        *sync.WaitGroup
+	stop      chan struct{}
+	handlers  map[string]EventHandler
+	tableName string
+	interval  time.Duration
+}
+
+// NewDBEventManager creates a DBEventManager instance. The table must
+// provide the id, created, name and value columns.
+func NewDBEventManager(db *goqu.Database, tableName string) *DBEventManager {
+	return &DBEventManager{
+		db:        db,
+		wg:        &sync.WaitGroup{},
+		stop:      make(chan struct{}),
+		handlers:  make(map[string]EventHandler),
+		tableName: tableName,
+		interval:  time.Second * 1,
+	}
+}
+
+// Listen listens for new events.
+func (m *DBEventManager) Listen() {
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		for {
+			select {
+			case <-m.stop:
+				return
+			default:
+				n, err := m.poll()
+				if err != nil {
+					slog.Error("loading event", slog.Any("err", err))
+				}
+				if n > 0 {
+					// There might be more events waiting
+					continue
+				}
+
+				select {
+				case <-m.stop:
+					return
+				case <-time.After(m.interval):
+				}
+			}
+		}
+	}()
+}
+
+// poll fetches the pending events and dispatches them. It returns
+// the number of events it handled.
+func (m *DBEventManager) poll() (int, error) {
+	type dbEvent struct {
+		ID    int    `db:"id"`
+		Name  string `db:"name"`
+		Value []byte `db:"value"`
+	}
+
+	var events []dbEvent
+	err := m.db.From(m.tableName).Prepared(true).
+		Select("id", "name", "value").
+		Order(goqu.C("id").Asc()).
+		Limit(10).
+		ScanStructs(&events)
+	if err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for _, e := range events {
+		// Another process could have received this event already.
+		// Only the one that deletes it can dispatch it.
+		res, err := m.db.Delete(m.tableName).Prepared(true).
+			Where(goqu.C("id").Eq(e.ID)).
+			Executor().Exec()
+		if err != nil {
+			return n, err
+		}
+		if c, _ := res.RowsAffected(); c == 0 {
+			continue
+		}
+
+		n++
+		if f, ok := m.handlers[e.Name]; ok {
+			f(Event{Name: e.Name, Value: e.Value})
+		}
+	}
+
+	return n, nil
+}
+
+// Stop stops the event listener.
+func (m *DBEventManager) Stop() {
+	m.stop <- struct{}{}
+	m.wg.Wait()
+}
+
+// Push sends an event to the event table.
+func (m *DBEventManager) Push(name string, value []byte) error {
+	_, err := m.db.Insert(m.tableName).Prepared(true).
+		Rows(goqu.Record{
+			"created": time.Now(),
+			"name":    name,
+			"value":   value,
+		}).
+		Executor().Exec()
+	return err
+}
+
+// On registers a event handler for a given event.
+func (m *DBEventManager) On(name string, f EventHandler) {
+	m.handlers[name] = f
+}
@@ -0,0 +1,62 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package superbus
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"codeberg.org/readeck/readeck/internal/db"
+)
+
+func TestDBEventManager(t *testing.T) {
+	clearTables(t)
+
+	// Two processes share the same table
+	var mu sync.Mutex
+	received := map[string][]int{}
+
+	managers := make([]*DBEventManager, 2)
+	for i := range managers {
+		m := NewDBEventManager(db.Q(), "bus_event")
+		m.interval = time.Millisecond * 10
+		m.On("test", func(e Event) {
+			mu.Lock()
+			defer mu.Unlock()
+			received[string(e.Value)] = append(received[string(e.Value)], i)
+		})
+		m.On("other", func(Event) {})
+		managers[i] = m
+	}
+
+	const n = 50
+	for i := range n {
+		require.NoError(t, managers[i%2].Push("test", []byte(strconv.Itoa(i))))
+	}
+	require.NoError(t, managers[0].Push("other", []byte("x")))
+
+	for _, m := range managers {
+		m.Listen()
+	}
+
+	require.Eventually(t, func() bool {
+		count, err := db.Q().From("bus_event").Count()
+		return err == nil && count == 0
+	}, 5*time.Second, 10*time.Millisecond)
+
+	for _, m := range managers {
+		m.Stop()
+	}
+
+	// Every event was received exactly once
+	require.Len(t, received, n)
+	for i := range n {
+		require.Len(t, received[strconv.Itoa(i)], 1, "event %d", i)
+	}
+}
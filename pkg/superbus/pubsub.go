@@ -0,0 +1,326 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package superbus
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/go-redis/redis/v8"
+)
+
+// PubSub describes a message broadcaster. Unlike an EventManager, where an
+// event is received only once, a message is sent to every subscriber of
+// a channel, in every process sharing the same backend.
+type PubSub interface {
+	// Publish sends a message to a channel
+	Publish(channel string, value []byte) error
+	// Subscribe returns a new subscription to a channel
+	Subscribe(channel string) *Subscription
+}
+
+// Subscription receives the messages of a channel. A slow subscriber
+// loses the messages that don't fit in its buffer.
+type Subscription struct {
+	C       <-chan []byte
+	ch      chan []byte
+	channel string
+	hub     *hub
+	once    sync.Once
+}
+
+// Close removes the subscription. Its channel is closed.
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		s.hub.unsubscribe(s)
+	})
+}
+
+// hub dispatches messages to the subscriptions of the current process.
+type hub struct {
+	sync.Mutex
+	subs map[string]map[*Subscription]struct{}
+}
+
+func newHub() *hub {
+	return &hub{subs: map[string]map[*Subscription]struct{}{}}
+}
+
+func (h *hub) subscribe(channel string) *Subscription {
+	h.Lock()
+	defer h.Unlock()
+
+	ch := make(chan []byte, 32)
+	s := &Subscription{C: ch, ch: ch, channel: channel, hub: h}
+	if _, ok := h.subs[channel]; !ok {
+		h.subs[channel] = map[*Subscription]struct{}{}
+	}
+	h.subs[channel][s] = struct{}{}
+
+	return s
+}
+
+func (h *hub) unsubscribe(s *Subscription) {
+	h.Lock()
+	defer h.Unlock()
+
+	delete(h.subs[s.channel], s)
+	if len(h.subs[s.channel]) == 0 {
+		delete(h.subs, s.channel)
+	}
+	close(s.ch)
+}
+
+func (h *hub) dispatch(channel string, value []byte) {
+	h.Lock()
+	defer h.Unlock()
+
+	for s := range h.subs[channel] {
+		select {
+		case s.ch <- value:
+		default:
+			slog.Warn("subscription buffer is full", slog.String("channel", channel))
+		}
+	}
+}
+
+func (h *hub) empty() bool {
+	h.Lock()
+	defer h.Unlock()
+	return len(h.subs) == 0
+}
+
+func (h *hub) has(channel string) bool {
+	h.Lock()
+	defer h.Unlock()
+	return len(h.subs[channel]) > 0
+}
+
+func (h *hub) channels() []string {
+	h.Lock()
+	defer h.Unlock()
+	res := make([]string, 0, len(h.subs))
+	for channel := range h.subs {
+		res = append(res, channel)
+	}
+	return res
+}
+
+// MemPubSub is an in memory PubSub for a single process.
+type MemPubSub struct {
+	hub *hub
+}
+
+// NewMemPubSub creates a MemPubSub instance.
+func NewMemPubSub() *MemPubSub {
+	return &MemPubSub{hub: newHub()}
+}
+
+// Publish sends a message to a channel.
+func (p *MemPubSub) Publish(channel string, value []byte) error {
+	p.hub.dispatch(channel, value)
+	return nil
+}
+
+// Subscribe returns a new subscription to a channel.
+func (p *MemPubSub) Subscribe(channel string) *Subscription {
+	return p.hub.subscribe(channel)
+}
+
+// RedisPubSub is a PubSub using redis channels.
+type RedisPubSub struct {
+	rdc    *redis.Client
+	prefix string
+	hub    *hub
+	once   sync.Once
+}
+
+// NewRedisPubSub creates a RedisPubSub instance. Its channels
+// are prefixed with the given prefix.
+func NewRedisPubSub(rdc *redis.Client, prefix string) *RedisPubSub {
+	return &RedisPubSub{
+		rdc:    rdc,
+		prefix: prefix + ":",
+		hub:    newHub(),
+	}
+}
+
+// Publish sends a message to a channel.
+func (p *RedisPubSub) Publish(channel string, value []byte) error {
+	return p.rdc.Publish(context.Background(), p.prefix+channel, value).Err()
+}
+
+// Subscribe returns a new subscription to a channel. The process starts
+// receiving messages on its first subscription.
+func (p *RedisPubSub) Subscribe(channel string) *Subscription {
+	p.once.Do(func() {
+		ps := p.rdc.PSubscribe(context.Background(), p.prefix+"*")
+		go func() {
+			for msg := range ps.Channel() {
+				p.hub.dispatch(strings.TrimPrefix(msg.Channel, p.prefix), []byte(msg.Payload))
+			}
+		}()
+	})
+
+	return p.hub.subscribe(channel)
+}
+
+// DBPubSub is a PubSub using a database table. Every process
+// reads the new messages and old messages are removed.
+//
+// A process with subscribers regularly records their presence in the
+// table, so the messages of a channel without any subscriber
+// are not stored.
+type DBPubSub struct {
+	db        *goqu.Database
+	tableName string
+	interval  time.Duration
+	presence  time.Duration
+	retention time.Duration
+	hub       *hub
+	once      sync.Once
+}
+
+// dbPresencePrefix is the channel prefix of the presence records.
+const dbPresencePrefix = "_presence:"
+
+// NewDBPubSub creates a DBPubSub instance. The table must
+// provide the id, created, channel and value columns.
+func NewDBPubSub(db *goqu.Database, tableName string) *DBPubSub {
+	return &DBPubSub{
+		db:        db,
+		tableName: tableName,
+		interval:  time.Second * 1,
+		presence:  time.Second * 10,
+		retention: time.Minute * 5,
+		hub:       newHub(),
+	}
+}
+
+// Publish sends a message to a channel. Nothing is sent when
+// the channel has no subscriber.
+func (p *DBPubSub) Publish(channel string, value []byte) error {
+	if !p.hub.has(channel) {
+		count, err := p.db.From(p.tableName).Prepared(true).
+			Where(
+				goqu.C("channel").Eq(dbPresencePrefix+channel),
+				goqu.C("created").Gt(time.Now().Add(-p.presence*3)),
+			).
+			Count()
+		if err != nil || count == 0 {
+			return err
+		}
+	}
+
+	return p.insert(channel, value)
+}
+
+// Subscribe returns a new subscription to a channel. The process starts
+// polling the table on its first subscription.
+func (p *DBPubSub) Subscribe(channel string) *Subscription {
+	p.once.Do(func() {
+		go p.listen()
+	})
+
+	// Other processes can send messages right away.
+	if err := p.insert(dbPresencePrefix+channel, nil); err != nil {
+		slog.Error("recording subscription", slog.Any("err", err))
+	}
+
+	return p.hub.subscribe(channel)
+}
+
+// insert adds a message to the table.
+func (p *DBPubSub) insert(channel string, value []byte) error {
+	if value == nil {
+		value = []byte{}
+	}
+	_, err := p.db.Insert(p.tableName).Prepared(true).
+		Rows(goqu.Record{
+			"created": time.Now(),
+			"channel": channel,
+			"value":   value,
+		}).
+		Executor().Exec()
+	return err
+}
+
+// listen polls the new messages and dispatches them.
+func (p *DBPubSub) listen() {
+	lastID := -1
+	lastPresence := time.Now()
+
+	for range time.Tick(p.interval) {
+		// Old messages are removed, whether there are subscribers or not.
+		_, err := p.db.Delete(p.tableName).Prepared(true).
+			Where(goqu.C("created").Lt(time.Now().Add(-p.retention))).
+			Executor().Exec()
+		if err != nil {
+			slog.Error("removing messages", slog.Any("err", err))
+		}
+
+		// Nobody's listening, the next subscriber only needs
+		// the messages sent after its arrival.
+		if p.hub.empty() {
+			lastID = -1
+			continue
+		}
+
+		if time.Since(lastPresence) > p.presence {
+			lastPresence = time.Now()
+			for _, channel := range p.hub.channels() {
+				if err := p.insert(dbPresencePrefix+channel, nil); err != nil {
+					slog.Error("recording subscription", slog.Any("err", err))
+				}
+			}
+		}
+
+		if lastID, err = p.poll(lastID); err != nil {
+			slog.Error("loading messages", slog.Any("err", err))
+		}
+	}
+}
+
+// poll dispatches the messages received after lastID and returns
+// the last message ID.
+func (p *DBPubSub) poll(lastID int) (int, error) {
+	if lastID < 0 {
+		var id *int
+		_, err := p.db.From(p.tableName).Prepared(true).
+			Select(goqu.MAX("id")).
+			ScanVal(&id)
+		if err != nil || id == nil {
+			return 0, err
+		}
+		return *id, nil
+	}
+
+	type dbMessage struct {
+		ID      int    `db:"id"`
+		Channel string `db:"channel"`
+		Value   []byte `db:"value"`
+	}
+
+	var messages []dbMessage
+	err := p.db.From(p.tableName).Prepared(true).
+		Select("id", "channel", "value").
+		Where(goqu.C("id").Gt(lastID)).
+		Order(goqu.C("id").Asc()).
+		ScanStructs(&messages)
+	if err != nil {
+		return lastID, err
+	}
+
+	for _, m := range messages {
+		p.hub.dispatch(m.Channel, m.Value)
+		lastID = m.ID
+	}
+
+	return lastID, nil
+}
@@ -0,0 +1,68 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package superbus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/stretchr/testify/require"
+
+	"codeberg.org/readeck/readeck/internal/db"
+)
+
+func TestDBPubSub(t *testing.T) {
+	clearTables(t)
+
+	// Two processes share the same table
+	newPubSub := func() *DBPubSub {
+		p := NewDBPubSub(db.Q(), "bus_message")
+		p.interval = time.Millisecond * 10
+		return p
+	}
+	p1, p2 := newPubSub(), newPubSub()
+
+	countMessages := func(channel string) int64 {
+		count, err := db.Q().From("bus_message").Where(goqu.C("channel").Eq(channel)).Count()
+		require.NoError(t, err)
+		return count
+	}
+
+	// Without subscribers, nothing is stored
+	require.NoError(t, p2.Publish("test", []byte("lost")))
+	require.Equal(t, int64(0), countMessages("test"))
+
+	sub := p1.Subscribe("test")
+	defer sub.Close()
+
+	// Wait for the first poll, so the subscriber only receives new messages
+	time.Sleep(p1.interval * 5)
+
+	require.NoError(t, p2.Publish("test", []byte("hello")))
+	require.NoError(t, p2.Publish("other", []byte("lost")))
+	require.Equal(t, int64(1), countMessages("test"))
+	require.Equal(t, int64(0), countMessages("other"))
+
+	select {
+	case msg := <-sub.C:
+		require.Equal(t, "hello", string(msg))
+	case <-time.After(time.Second * 5):
+		t.Fatal("no message received")
+	}
+
+	// Old messages are removed, even without subscribers
+	sub.Close()
+	_, err := db.Q().Insert("bus_message").Prepared(true).Rows(goqu.Record{
+		"created": time.Now().Add(-p1.retention - time.Minute),
+		"channel": "test",
+		"value":   []byte("old"),
+	}).Executor().Exec()
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		return countMessages("test") == 1
+	}, time.Second*5, p1.interval)
+}
@@ -0,0 +1,152 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package superbus
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+)
+
+// DefaultQueue is the queue of tasks that don't set one.
+const DefaultQueue = "default"
+
+type (
+	// QueueStatus contains the state of a queue.
+	QueueStatus struct {
+		Name     string `json:"name"`
+		Workers  int    `json:"workers"`
+		Priority int    `json:"priority"`
+		Pending  int    `json:"pending"`
+	}
+
+	// taskQueue is a named queue with its own worker pool.
+	taskQueue struct {
+		name     string
+		workers  int
+		priority int
+		items    []func()
+		// eligible contains the queues a worker of this queue takes
+		// operations from, by descending priority.
+		eligible []*taskQueue
+	}
+
+	// dispatcher holds the queues and hands their operations
+	// over to the workers.
+	dispatcher struct {
+		sync.Mutex
+		cond   *sync.Cond
+		queues map[string]*taskQueue
+		closed bool
+	}
+)
+
+func newDispatcher() *dispatcher {
+	d := &dispatcher{queues: map[string]*taskQueue{}}
+	d.cond = sync.NewCond(d)
+	d.setQueue(DefaultQueue, 1, 0)
+	return d
+}
+
+// setQueue adds or updates a queue.
+func (d *dispatcher) setQueue(name string, workers, priority int) {
+	q, ok := d.queues[name]
+	if !ok {
+		q = &taskQueue{name: name}
+		d.queues[name] = q
+	}
+	q.workers = max(1, workers)
+	q.priority = priority
+}
+
+// get returns the queue with the given name, or the default queue.
+func (d *dispatcher) get(name string) *taskQueue {
+	if q, ok := d.queues[name]; ok {
+		return q
+	}
+	return d.queues[DefaultQueue]
+}
+
+// prepare computes, for every queue, the list of queues its
+// workers take operations from: the queue itself and every queue
+// with a higher priority. This way, an idle worker always runs the
+// most urgent operation while a busy queue can't use the workers
+// of a more important one.
+func (d *dispatcher) prepare() {
+	all := make([]*taskQueue, 0, len(d.queues))
+	for _, q := range d.queues {
+		all = append(all, q)
+	}
+	slices.SortFunc(all, func(a, b *taskQueue) int {
+		return cmp.Or(cmp.Compare(b.priority, a.priority), cmp.Compare(a.name, b.name))
+	})
+
+	for _, q := range all {
+		q.eligible = slices.DeleteFunc(slices.Clone(all), func(x *taskQueue) bool {
+			return x != q && x.priority <= q.priority
+		})
+	}
+}
+
+// push adds an operation to a queue.
+func (d *dispatcher) push(q *taskQueue, f func()) {
+	d.Lock()
+	defer d.Unlock()
+	q.items = append(q.items, f)
+	d.cond.Broadcast()
+}
+
+// next waits for an operation a worker of the given queue can run.
+// It returns false when the dispatcher is closed and there's
+// nothing left to run.
+func (d *dispatcher) next(q *taskQueue) (func(), bool) {
+	d.Lock()
+	defer d.Unlock()
+
+	for {
+		for _, x := range q.eligible {
+			if len(x.items) > 0 {
+				f := x.items[0]
+				x.items[0] = nil
+				x.items = x.items[1:]
+				return f, true
+			}
+		}
+
+		if d.closed {
+			return nil, false
+		}
+		d.cond.Wait()
+	}
+}
+
+// close lets the workers exit once the queues are empty.
+func (d *dispatcher) close() {
+	d.Lock()
+	defer d.Unlock()
+	d.closed = true
+	d.cond.Broadcast()
+}
+
+// status returns the state of every queue, by descending priority.
+func (d *dispatcher) status() []QueueStatus {
+	d.Lock()
+	defer d.Unlock()
+
+	res := make([]QueueStatus, 0, len(d.queues))
+	for _, q := range d.queues {
+		res = append(res, QueueStatus{
+			Name:     q.name,
+			Workers:  q.workers,
+			Priority: q.priority,
+			Pending:  len(q.items),
+		})
+	}
+	slices.SortFunc(res, func(a, b QueueStatus) int {
+		return cmp.Or(cmp.Compare(b.Priority, a.Priority), cmp.Compare(a.Name, b.Name))
+	})
+
+	return res
+}
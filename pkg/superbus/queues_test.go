@@ -0,0 +1,66 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package superbus
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDispatcher(t *testing.T) {
+	d := newDispatcher()
+	d.setQueue("interactive", 2, 20)
+	d.setQueue("maintenance", 1, 10)
+	d.setQueue("import", 1, 0)
+	d.prepare()
+
+	var res []string
+	push := func(queue, name string) {
+		d.push(d.get(queue), func() { res = append(res, name) })
+	}
+	run := func(queue string) bool {
+		f, ok := d.next(d.get(queue))
+		if ok {
+			f()
+		}
+		return ok
+	}
+
+	push("import", "import-1")
+	push("import", "import-2")
+	push("maintenance", "maintenance-1")
+	push("interactive", "interactive-1")
+	push("unknown", "default-1")
+
+	require.Equal(t, []QueueStatus{
+		{Name: "interactive", Workers: 2, Priority: 20, Pending: 1},
+		{Name: "maintenance", Workers: 1, Priority: 10, Pending: 1},
+		{Name: "default", Workers: 1, Priority: 0, Pending: 1},
+		{Name: "import", Workers: 1, Priority: 0, Pending: 2},
+	}, d.status())
+
+	// An import worker runs the higher priority operations first
+	require.True(t, run("import"))
+	require.True(t, run("import"))
+	require.True(t, run("import"))
+	require.Equal(t, []string{"interactive-1", "maintenance-1", "import-1"}, res)
+
+	// An interactive worker never runs an import operation
+	push("interactive", "interactive-2")
+	require.True(t, run("interactive"))
+	require.Equal(t, "interactive-2", res[len(res)-1])
+
+	// The default queue doesn't take the import queue's operations
+	require.True(t, run(DefaultQueue))
+	require.Equal(t, "default-1", res[len(res)-1])
+
+	// Once closed, the remaining operations still run
+	d.close()
+	require.True(t, run("import"))
+	require.Equal(t, "import-2", res[len(res)-1])
+	require.False(t, run("import"))
+	require.False(t, run("interactive"))
+}
@@ -0,0 +1,215 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package superbus
+
+import (
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule describes when a recurring task runs.
+type Schedule interface {
+	// Next returns the next activation time after t.
+	Next(t time.Time) time.Time
+	String() string
+}
+
+type intervalSchedule time.Duration
+
+// Every returns a schedule that runs at a fixed interval. The activation
+// times are aligned on the interval, so every process computes the same ones.
+func Every(d time.Duration) Schedule {
+	return intervalSchedule(max(d, time.Second))
+}
+
+func (s intervalSchedule) Next(t time.Time) time.Time {
+	d := time.Duration(s)
+	return t.Truncate(d).Add(d)
+}
+
+func (s intervalSchedule) String() string {
+	return "@every " + time.Duration(s).String()
+}
+
+// cronSchedule is a schedule defined by a cron expression. Each field
+// is a bit set of the matching values.
+type cronSchedule struct {
+	expr   string
+	minute uint64
+	hour   uint64
+	dom    uint64
+	month  uint64
+	dow    uint64
+	// anyDay is true when either the day of month or the day of week
+	// is a wildcard. Otherwise, a day matches when any of them does.
+	anyDay bool
+}
+
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+type cronBounds struct {
+	min, max int
+}
+
+var cronFields = [5]cronBounds{
+	{0, 59}, // minute
+	{0, 23}, // hour
+	{1, 31}, // day of month
+	{1, 12}, // month
+	{0, 7},  // day of week (0 and 7 are sunday)
+}
+
+// ParseSchedule returns a Schedule from a standard 5 fields cron expression
+// ("minute hour day-of-month month day-of-week"), one of the @hourly, @daily,
+// @weekly, @monthly or @yearly macros or an interval like "@every 10m".
+func ParseSchedule(expr string) (Schedule, error) {
+	expr = strings.TrimSpace(expr)
+	if v, ok := strings.CutPrefix(expr, "@every "); ok {
+		d, err := time.ParseDuration(strings.TrimSpace(v))
+		if err != nil {
+			return nil, fmt.Errorf("invalid interval %q: %w", v, err)
+		}
+		if d <= 0 {
+			return nil, fmt.Errorf("invalid interval %q", v)
+		}
+		return Every(d), nil
+	}
+
+	spec := expr
+	if v, ok := cronMacros[expr]; ok {
+		spec = v
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("invalid cron expression %q: expected %d fields", expr, len(cronFields))
+	}
+
+	var values [5]uint64
+	for i, f := range fields {
+		v, err := parseCronField(f, cronFields[i])
+		if err != nil {
+			return nil, fmt.Errorf("invalid cron expression %q: %w", expr, err)
+		}
+		values[i] = v
+	}
+
+	// Sunday can be 0 or 7
+	if values[4]&(1<<7) != 0 {
+		values[4] |= 1
+	}
+
+	return &cronSchedule{
+		expr:   expr,
+		minute: values[0],
+		hour:   values[1],
+		dom:    values[2],
+		month:  values[3],
+		dow:    values[4],
+		anyDay: strings.HasPrefix(fields[2], "*") || strings.HasPrefix(fields[4], "*"),
+	}, nil
+}
+
+// parseCronField parses one cron field, made of a comma separated
+// list of "*", "n" or "n-m" ranges, with an optional "/step".
+func parseCronField(field string, b cronBounds) (uint64, error) {
+	var res uint64
+	for _, part := range strings.Split(field, ",") {
+		rng, stepStr, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepStr); err != nil || step < 1 {
+				return 0, fmt.Errorf("invalid step %q", part)
+			}
+		}
+
+		start, end := b.min, b.max
+		if rng != "*" {
+			from, to, isRange := strings.Cut(rng, "-")
+			var err error
+			if start, err = strconv.Atoi(from); err != nil {
+				return 0, fmt.Errorf("invalid value %q", part)
+			}
+			end = start
+			if isRange {
+				if end, err = strconv.Atoi(to); err != nil {
+					return 0, fmt.Errorf("invalid value %q", part)
+				}
+			} else if hasStep {
+				end = b.max
+			}
+		}
+
+		if start < b.min || end > b.max || start > end {
+			return 0, fmt.Errorf("value out of range %q", part)
+		}
+
+		for i := start; i <= end; i += step {
+			res |= 1 << uint(i)
+		}
+	}
+
+	return res, nil
+}
+
+func (s *cronSchedule) String() string {
+	return s.expr
+}
+
+func (s *cronSchedule) matchDay(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.anyDay {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// Next returns the next activation time after t. It returns
+// a zero time when there is none in the next 5 years.
+func (s *cronSchedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		switch {
+		case s.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !s.matchDay(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case s.hour&(1<<uint(t.Hour())) == 0:
+			t = nextHour(t)
+		case s.minute&(1<<uint(t.Minute())) == 0:
+			// Skip directly to the next matching minute of this hour, if any.
+			next := s.minute >> uint(t.Minute())
+			if next == 0 {
+				t = nextHour(t)
+			} else {
+				t = t.Add(time.Minute * time.Duration(bits.TrailingZeros64(next)))
+			}
+		default:
+			return t
+		}
+	}
+
+	return time.Time{}
+}
+
+// nextHour returns the beginning of the hour following t, in t's location.
+func nextHour(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+}
@@ -0,0 +1,73 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package superbus_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"codeberg.org/readeck/readeck/pkg/superbus"
+)
+
+func TestParseSchedule(t *testing.T) {
+	ref := time.Date(2025, 3, 14, 10, 17, 42, 0, time.UTC)
+
+	tests := []struct {
+		expr     string
+		expected []string
+	}{
+		{"@every 15m", []string{"2025-03-14T10:30:00Z", "2025-03-14T10:45:00Z"}},
+		{"@hourly", []string{"2025-03-14T11:00:00Z", "2025-03-14T12:00:00Z"}},
+		{"@daily", []string{"2025-03-15T00:00:00Z", "2025-03-16T00:00:00Z"}},
+		{"@weekly", []string{"2025-03-16T00:00:00Z", "2025-03-23T00:00:00Z"}},
+		{"@monthly", []string{"2025-04-01T00:00:00Z", "2025-05-01T00:00:00Z"}},
+		{"@yearly", []string{"2026-01-01T00:00:00Z", "2027-01-01T00:00:00Z"}},
+		{"*/20 * * * *", []string{"2025-03-14T10:20:00Z", "2025-03-14T10:40:00Z", "2025-03-14T11:00:00Z"}},
+		{"5,50 9-10 * * *", []string{"2025-03-14T10:50:00Z", "2025-03-15T09:05:00Z"}},
+		{"30 3 * * 1-5", []string{"2025-03-17T03:30:00Z", "2025-03-18T03:30:00Z"}},
+		{"0 0 * * 7", []string{"2025-03-16T00:00:00Z"}},
+		{"0 12 31 * *", []string{"2025-03-31T12:00:00Z", "2025-05-31T12:00:00Z"}},
+		{"0 0 13 * 5", []string{"2025-03-21T00:00:00Z", "2025-03-28T00:00:00Z", "2025-04-04T00:00:00Z"}},
+		{"0 0 29 2 *", []string{"2028-02-29T00:00:00Z"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			s, err := superbus.ParseSchedule(test.expr)
+			require.NoError(t, err)
+
+			next := ref
+			for _, x := range test.expected {
+				next = s.Next(next)
+				require.Equal(t, x, next.Format(time.RFC3339))
+			}
+		})
+	}
+}
+
+func TestParseScheduleErrors(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"@every",
+		"@every 0s",
+		"@every abc",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"a * * * *",
+	} {
+		t.Run(expr, func(t *testing.T) {
+			_, err := superbus.ParseSchedule(expr)
+			require.Error(t, err)
+		})
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/doug-martin/goqu/v9"
 	"github.com/go-redis/redis/v8"
 )
 
@@ -18,6 +19,20 @@ type Store interface {
 	Get(string) string
 	Set(string, string, time.Duration) error
 	Del(string) error
+	Pop(string) (string, error)
+}
+
+// Locker is a Store that can set a key only when it doesn't exist.
+// The TaskManager uses it to make sure only one process launches
+// a scheduled task.
+type Locker interface {
+	SetNX(key, value string, expiration time.Duration) (bool, error)
+}
+
+// KeyLister is a Store that can list its keys. The TaskManager uses it
+// to resume the tasks that were pending when it stopped.
+type KeyLister interface {
+	Keys(prefix string) ([]string, error)
 }
 
 // RedisStore implements KvStore with redis.
@@ -63,6 +78,32 @@ func (s *RedisStore) Del(key string) error {
 	return err
 }
 
+// Pop removes the given key and returns its value. Returns an empty
+// string when the value does not exist or when another client
+// removed it first.
+func (s *RedisStore) Pop(key string) (string, error) {
+	ctx := context.Background()
+	var res *redis.StringCmd
+	_, err := s.rdb.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		res = p.Get(ctx, s.key(key))
+		p.Del(ctx, s.key(key))
+		return nil
+	})
+	if err == redis.Nil {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return res.Val(), nil
+}
+
+// SetNX sets the value for the given key, only when the key
+// doesn't exist. It returns true when the value was set.
+func (s *RedisStore) SetNX(key, value string, expiration time.Duration) (bool, error) {
+	return s.rdb.SetNX(context.Background(), s.key(key), value, expiration).Result()
+}
+
 // MemStore is a KvStore implementation using a simple in memory map.
 type MemStore struct {
 	sync.RWMutex
@@ -109,9 +150,194 @@ func (s *MemStore) Del(key string) error {
 	return nil
 }
 
+// Pop removes the given key and returns its value. Returns an empty
+// string when the value does not exist.
+func (s *MemStore) Pop(key string) (string, error) {
+	s.Lock()
+	defer s.Unlock()
+	res := s.data[key]
+	delete(s.data, key)
+	return res, nil
+}
+
+// SetNX sets the value for the given key, only when the key
+// doesn't exist. It returns true when the value was set.
+func (s *MemStore) SetNX(key, value string, expiration time.Duration) (bool, error) {
+	s.Lock()
+	defer s.Unlock()
+	if _, ok := s.data[key]; ok {
+		return false, nil
+	}
+	s.data[key] = value
+
+	if expiration > 0 {
+		time.AfterFunc(expiration, func() {
+			s.Lock()
+			defer s.Unlock()
+			delete(s.data, key)
+		})
+	}
+
+	return true, nil
+}
+
 // Clear deletes everything in the memory store.
 func (s *MemStore) Clear() {
 	s.Lock()
 	defer s.Unlock()
 	s.data = make(map[string]string)
 }
+
+// DBStore is a KvStore implementation using a database table.
+type DBStore struct {
+	db        *goqu.Database
+	tableName string
+}
+
+// NewDBStore returns a DBStore instance. The table must provide
+// the key, value and expires columns.
+func NewDBStore(db *goqu.Database, tableName string) *DBStore {
+	return &DBStore{
+		db:        db,
+		tableName: tableName,
+	}
+}
+
+// notExpired returns the condition of a non expired key.
+func (s *DBStore) notExpired() goqu.Expression {
+	return goqu.Or(
+		goqu.C("expires").Eq(0),
+		goqu.C("expires").Gt(time.Now().Unix()),
+	)
+}
+
+// Get returns a value for the given key. Returns an empty string when the
+// value does not exist.
+func (s *DBStore) Get(key string) string {
+	var res string
+	_, err := s.db.From(s.tableName).Prepared(true).
+		Select("value").
+		Where(goqu.C("key").Eq(key), s.notExpired()).
+		ScanVal(&res)
+	if err != nil {
+		return ""
+	}
+
+	return res
+}
+
+// Set insert or replace the value for the given key.
+func (s *DBStore) Set(key, value string, expiration time.Duration) error {
+	var expires int64
+	if expiration > 0 {
+		expires = time.Now().Add(expiration).Unix()
+	}
+
+	// Remove expired keys while we're at it.
+	_, err := s.db.Delete(s.tableName).Prepared(true).
+		Where(
+			goqu.C("expires").Gt(0),
+			goqu.C("expires").Lte(time.Now().Unix()),
+		).
+		Executor().Exec()
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Insert(s.tableName).Prepared(true).
+		Rows(goqu.Record{
+			"key":     key,
+			"value":   value,
+			"expires": expires,
+		}).
+		OnConflict(goqu.DoUpdate("key", goqu.Record{
+			"value":   goqu.I("excluded.value"),
+			"expires": goqu.I("excluded.expires"),
+		})).
+		Executor().Exec()
+	return err
+}
+
+// Del removes the given key.
+func (s *DBStore) Del(key string) error {
+	_, err := s.db.Delete(s.tableName).Prepared(true).
+		Where(goqu.C("key").Eq(key)).
+		Executor().Exec()
+	return err
+}
+
+// Pop removes the given key and returns its value. Returns an empty
+// string when the value does not exist or when another client
+// removed it first.
+func (s *DBStore) Pop(key string) (string, error) {
+	res := s.Get(key)
+	if res == "" {
+		return "", nil
+	}
+
+	// Only the client that removes the row gets the value.
+	r, err := s.db.Delete(s.tableName).Prepared(true).
+		Where(goqu.C("key").Eq(key), goqu.C("value").Eq(res)).
+		Executor().Exec()
+	if err != nil {
+		return "", err
+	}
+	if n, err := r.RowsAffected(); err != nil || n != 1 {
+		return "", err
+	}
+	return res, nil
+}
+
+// SetNX sets the value for the given key, only when the key
+// doesn't exist. It returns true when the value was set.
+func (s *DBStore) SetNX(key, value string, expiration time.Duration) (bool, error) {
+	var expires int64
+	if expiration > 0 {
+		expires = time.Now().Add(expiration).Unix()
+	}
+
+	// An expired key doesn't exist.
+	_, err := s.db.Delete(s.tableName).Prepared(true).
+		Where(
+			goqu.C("key").Eq(key),
+			goqu.C("expires").Gt(0),
+			goqu.C("expires").Lte(time.Now().Unix()),
+		).
+		Executor().Exec()
+	if err != nil {
+		return false, err
+	}
+
+	res, err := s.db.Insert(s.tableName).Prepared(true).
+		Rows(goqu.Record{
+			"key":     key,
+			"value":   value,
+			"expires": expires,
+		}).
+		OnConflict(goqu.DoNothing()).
+		Executor().Exec()
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// Keys returns all the non expired keys starting with prefix.
+func (s *DBStore) Keys(prefix string) ([]string, error) {
+	res := []string{}
+	err := s.db.From(s.tableName).Prepared(true).
+		Select("key").
+		Where(
+			goqu.C("key").Like(prefix+"%"),
+			s.notExpired(),
+		).
+		Order(goqu.C("key").Asc()).
+		ScanVals(&res)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
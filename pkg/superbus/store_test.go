@@ -0,0 +1,177 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package superbus
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/stretchr/testify/require"
+
+	"codeberg.org/readeck/readeck/internal/db"
+)
+
+// TestMain opens a database with the tables used by the database
+// backed store, event manager and pubsub.
+func TestMain(m *testing.M) {
+	os.Exit(func() int {
+		tmpDir, err := os.MkdirTemp("", "superbus")
+		if err != nil {
+			panic(err)
+		}
+		defer os.RemoveAll(tmpDir) //nolint:errcheck
+
+		if err = db.Open("sqlite3:" + filepath.Join(tmpDir, "bus.db")); err != nil {
+			panic(err)
+		}
+		defer db.Close() //nolint:errcheck
+
+		_, err = db.Q().Exec(`
+			CREATE TABLE bus_event (
+				id      integer  PRIMARY KEY AUTOINCREMENT,
+				created datetime NOT NULL,
+				name    text     NOT NULL,
+				value   blob     NOT NULL
+			);
+			CREATE TABLE bus_store (
+				key     text     PRIMARY KEY,
+				value   text     NOT NULL,
+				expires integer  NOT NULL DEFAULT 0
+			);
+			CREATE TABLE bus_message (
+				id      integer  PRIMARY KEY AUTOINCREMENT,
+				created datetime NOT NULL,
+				channel text     NOT NULL,
+				value   blob     NOT NULL
+			);
+		`)
+		if err != nil {
+			panic(err)
+		}
+
+		return m.Run()
+	}())
+}
+
+// clearTables empties the bus tables.
+func clearTables(t *testing.T) {
+	for _, name := range []string{"bus_event", "bus_store", "bus_message"} {
+		_, err := db.Q().Delete(name).Executor().Exec()
+		require.NoError(t, err)
+	}
+}
+
+func TestDBStore(t *testing.T) {
+	clearTables(t)
+	s := NewDBStore(db.Q(), "bus_store")
+
+	t.Run("get set del", func(t *testing.T) {
+		require.Equal(t, "", s.Get("a"))
+
+		require.NoError(t, s.Set("a", "1", 0))
+		require.Equal(t, "1", s.Get("a"))
+
+		require.NoError(t, s.Set("a", "2", time.Hour))
+		require.Equal(t, "2", s.Get("a"))
+
+		require.NoError(t, s.Del("a"))
+		require.Equal(t, "", s.Get("a"))
+	})
+
+	t.Run("expiration", func(t *testing.T) {
+		_, err := db.Q().Insert("bus_store").Rows(goqu.Record{
+			"key": "expired", "value": "x", "expires": time.Now().Add(-time.Minute).Unix(),
+		}).Executor().Exec()
+		require.NoError(t, err)
+
+		require.Equal(t, "", s.Get("expired"))
+
+		// Setting any key removes the expired ones
+		require.NoError(t, s.Set("b", "1", 0))
+		count, err := db.Q().From("bus_store").Where(goqu.C("key").Eq("expired")).Count()
+		require.NoError(t, err)
+		require.Equal(t, int64(0), count)
+	})
+
+	t.Run("setnx", func(t *testing.T) {
+		ok, err := s.SetNX("lock", "1", time.Hour)
+		require.NoError(t, err)
+		require.True(t, ok)
+
+		ok, err = s.SetNX("lock", "2", time.Hour)
+		require.NoError(t, err)
+		require.False(t, ok)
+		require.Equal(t, "1", s.Get("lock"))
+
+		// An expired key can be set again
+		_, err = db.Q().Update("bus_store").
+			Set(goqu.Record{"expires": time.Now().Add(-time.Minute).Unix()}).
+			Where(goqu.C("key").Eq("lock")).
+			Executor().Exec()
+		require.NoError(t, err)
+
+		ok, err = s.SetNX("lock", "3", 0)
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, "3", s.Get("lock"))
+	})
+
+	t.Run("pop", func(t *testing.T) {
+		require.NoError(t, s.Set("code", "1", time.Hour))
+
+		v, err := s.Pop("code")
+		require.NoError(t, err)
+		require.Equal(t, "1", v)
+		require.Equal(t, "", s.Get("code"))
+
+		// The value can only be taken once
+		v, err = s.Pop("code")
+		require.NoError(t, err)
+		require.Equal(t, "", v)
+
+		// Even by concurrent clients
+		require.NoError(t, s.Set("code", "2", time.Hour))
+		var wg sync.WaitGroup
+		var taken atomic.Int32
+		for range 8 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if v, err := s.Pop("code"); err == nil && v == "2" {
+					taken.Add(1)
+				}
+			}()
+		}
+		wg.Wait()
+		require.Equal(t, int32(1), taken.Load())
+	})
+
+	t.Run("keys", func(t *testing.T) {
+		clearTables(t)
+		for i := range 3 {
+			require.NoError(t, s.Set(fmt.Sprintf("tasks:op:%d", i), "x", 0))
+		}
+		require.NoError(t, s.Set("tasks-other", "x", 0))
+		require.NoError(t, s.Set("scheduler:tasks:op", "x", 0))
+		_, err := db.Q().Insert("bus_store").Rows(goqu.Record{
+			"key": "tasks:op:9", "value": "x", "expires": time.Now().Add(-time.Minute).Unix(),
+		}).Executor().Exec()
+		require.NoError(t, err)
+
+		keys, err := s.Keys("tasks:")
+		require.NoError(t, err)
+		require.Equal(t, []string{"tasks:op:0", "tasks:op:1", "tasks:op:2"}, keys)
+
+		keys, err = s.Keys("nope:")
+		require.NoError(t, err)
+		require.Empty(t, keys)
+	})
+}
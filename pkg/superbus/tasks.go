@@ -6,10 +6,12 @@
 package superbus
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"log/slog"
+	"strings"
 	"sync"
 	"time"
 
@@ -25,26 +27,81 @@ type (
 
 	// Payload is the stored content of a task.
 	Payload struct {
-		ID    uuid.UUID `json:"id"`
-		Delay int       `json:"delay"`
-		Data  []byte    `json:"data"`
+		ID      uuid.UUID `json:"id"`
+		Delay   int       `json:"delay"`
+		Data    []byte    `json:"data"`
+		Running bool      `json:"running,omitempty"`
+		Attempt int       `json:"attempt,omitempty"`
+		// Owner is the ID of the task manager that took the operation.
+		Owner string `json:"owner,omitempty"`
+		// Lease is the time until which the operation belongs to its
+		// owner, or waits for its event when it has no owner.
+		Lease time.Time `json:"lease,omitzero"`
 	}
 
-	// TaskHandler is the function called on a task.
-	TaskHandler func(*Operation, *Payload)
+	// TaskHandler is the function called on a task. When it returns an error,
+	// the operation is retried or considered as failed.
+	TaskHandler func(*Operation, *Payload) error
+
+	// FailureHandler is the function called when an operation failed
+	// and won't be retried.
+	FailureHandler func(*Operation, *Payload, error)
+
+	// RetryPolicy defines how a failing task is retried.
+	RetryPolicy struct {
+		// MaxAttempts is the maximum number of runs, including the first one.
+		MaxAttempts int
+		// Backoff is the delay before the first retry. It doubles on each attempt.
+		Backoff time.Duration
+		// MaxBackoff caps the delay between two attempts.
+		MaxBackoff time.Duration
+		// Retryable tells whether an error is worth a new attempt.
+		// When it's nil, every error is.
+		Retryable func(error) bool
+	}
 
 	// TaskManager is the task manager.
 	TaskManager struct {
 		sync.Mutex
 
+		id          string
 		em          EventManager
 		store       Store
 		handlers    map[string]TaskHandler
-		queue       chan func()
-		numWorkers  int
+		policies    map[string]RetryPolicy
+		failures    map[string]FailureHandler
+		deadLetter  FailureHandler
+		queues      *dispatcher
+		taskQueues  map[string]string
 		workerGroup *sync.WaitGroup
 		timerGroup  *sync.WaitGroup
 		keyPrefix   string
+		payloadTTL  time.Duration
+		leaseTTL    time.Duration
+		claims      map[string]uuid.UUID
+		schedules   []*scheduledTask
+		schedStop   chan struct{}
+		schedGroup  *sync.WaitGroup
+		resumeStop  chan struct{}
+		resumeGroup *sync.WaitGroup
+	}
+
+	// ScheduleStatus contains the state of a scheduled task.
+	ScheduleStatus struct {
+		Name      string        `json:"name"`
+		Schedule  string        `json:"schedule"`
+		LastRun   time.Time     `json:"last_run"`
+		Duration  time.Duration `json:"duration"`
+		LastError string        `json:"last_error"`
+		NextRun   time.Time     `json:"next_run"`
+	}
+
+	// scheduledTask is a task that runs on a schedule.
+	scheduledTask struct {
+		sync.Mutex
+		task     Task
+		schedule Schedule
+		next     time.Time
 	}
 
 	// TaskManagerOption is a function that sets TaskManager option upon creation.
@@ -57,23 +114,35 @@ type (
 	Task struct {
 		tm             *TaskManager
 		name           string
+		queue          string
 		delay          int
+		schedule       Schedule
+		retry          RetryPolicy
 		unmarshallData func(data []byte) interface{}
-		taskHandler    func(data interface{})
+		taskHandler    func(data interface{}) error
+		failureHandler func(data interface{}, err error)
 	}
 )
 
 // NewTaskManager creates a new TaskManager instance.
 func NewTaskManager(m EventManager, s Store, options ...TaskManagerOption) *TaskManager {
 	tm := &TaskManager{
+		id:          uuid.NewString(),
 		em:          m,
 		store:       s,
 		handlers:    make(map[string]TaskHandler),
-		queue:       make(chan func()),
-		numWorkers:  1,
+		policies:    make(map[string]RetryPolicy),
+		failures:    make(map[string]FailureHandler),
+		queues:      newDispatcher(),
+		taskQueues:  make(map[string]string),
 		workerGroup: &sync.WaitGroup{},
 		timerGroup:  &sync.WaitGroup{},
+		schedGroup:  &sync.WaitGroup{},
+		resumeGroup: &sync.WaitGroup{},
 		keyPrefix:   "tasks",
+		payloadTTL:  time.Second * 30,
+		leaseTTL:    time.Minute,
+		claims:      make(map[string]uuid.UUID),
 	}
 
 	for _, o := range options {
@@ -88,7 +157,16 @@ func NewTaskManager(m EventManager, s Store, options ...TaskManagerOption) *Task
 // WithNumWorkers set the number of worker processes that handle operations.
 func WithNumWorkers(m int) TaskManagerOption {
 	return func(tm *TaskManager) {
-		tm.numWorkers = m
+		tm.queues.setQueue(DefaultQueue, m, 0)
+	}
+}
+
+// WithQueue adds a named queue with its own number of workers. When
+// idle, the workers of a queue run the operations of any queue
+// with a higher priority.
+func WithQueue(name string, workers int, priority int) TaskManagerOption {
+	return func(tm *TaskManager) {
+		tm.queues.setQueue(name, workers, priority)
 	}
 }
 
@@ -99,10 +177,51 @@ func WithOperationPrefix(p string) TaskManagerOption {
 	}
 }
 
+// WithPayloadTTL sets how long a task payload is kept in the store
+// after its delay. When the task manager stops before the task runs,
+// it's the time it has to resume it.
+func WithPayloadTTL(d time.Duration) TaskManagerOption {
+	return func(tm *TaskManager) {
+		tm.payloadTTL = d
+	}
+}
+
+// WithInstanceID sets the ID the task manager uses to claim operations.
+// A process that keeps the same ID across restarts resumes its pending
+// operations without waiting for their lease to expire.
+func WithInstanceID(id string) TaskManagerOption {
+	return func(tm *TaskManager) {
+		tm.id = id
+	}
+}
+
+// WithLeaseTTL sets how long an operation stays with the task manager
+// that took it, without news from it. The lease of a running operation
+// is renewed until it ends. It's also the interval at which the task
+// manager looks for operations whose lease expired.
+func WithLeaseTTL(d time.Duration) TaskManagerOption {
+	return func(tm *TaskManager) {
+		tm.leaseTTL = d
+	}
+}
+
+// WithDeadLetter sets a function that receives every operation
+// that failed for good.
+func WithDeadLetter(f FailureHandler) TaskManagerOption {
+	return func(tm *TaskManager) {
+		tm.deadLetter = f
+	}
+}
+
 // onTask is the task's event handler.
 func (tm *TaskManager) onTask(e Event) {
 	var op Operation
-	if err := json.Unmarshal(e.Value, &op); err != nil {
+
+	// Keep numeric IDs as they were sent, so the operation key
+	// doesn't end up with a float representation.
+	dec := json.NewDecoder(bytes.NewReader(e.Value))
+	dec.UseNumber()
+	if err := dec.Decode(&op); err != nil {
 		slog.Error("", slog.Any("err", err))
 		return
 	}
@@ -129,6 +248,13 @@ func (tm *TaskManager) onTask(e Event) {
 		return
 	}
 
+	// Take the operation. When it's already taken, the event is
+	// a duplicate, sent by a process that resumed it.
+	if !tm.claim(&op, &p0) {
+		l.Debug("operation already claimed")
+		return
+	}
+
 	// Enqueue the task to the queue
 	tm.timerGroup.Add(1)
 	time.AfterFunc(time.Second*time.Duration(p0.Delay), func() {
@@ -138,32 +264,221 @@ func (tm *TaskManager) onTask(e Event) {
 		// If the payload is gone, the task was canceled
 		p1, err := tm.getPayload(&op)
 		if err != nil {
+			tm.release(&op, p0.ID)
 			l.Error("", slog.Any("err", err))
 			return
 		}
 
 		// Check the original and current payload IDs. When they
 		// don't match, the timer is running for a previous task and
-		// we don't need it anymore.
-		if p0.ID != p1.ID {
+		// we don't need it anymore. The same goes when another
+		// process took the operation over.
+		if p0.ID != p1.ID || p1.Owner != tm.id {
+			tm.release(&op, p0.ID)
 			l.Error("not matching payloads")
 			return
 		}
 
-		// Push the worker to the queue.
-		tm.queue <- func() {
-			defer func() {
-				if r := recover(); r != nil {
-					l.Error("task error", slog.Any("err", err))
+		// Mark the payload as running, for as long as we renew its lease.
+		p1.Running = true
+		p1.Lease = time.Now().Add(tm.leaseTTL)
+		if err := tm.setPayload(&op, &p1); err != nil {
+			l.Error("updating payload", slog.Any("err", err))
+		}
+
+		// Push the worker to the task's queue.
+		tm.queues.push(tm.queues.get(tm.queueName(op.Name)), func() {
+			defer tm.release(&op, p1.ID)
+
+			stop := tm.keepLease(&op, p1.ID)
+			err := tm.run(f, &op, &p1)
+			stop()
+
+			if err != nil {
+				tm.fail(&op, &p1, err)
+				return
+			}
+
+			// The payload can be removed when we're done.
+			if err := tm.delPayload(&op); err != nil {
+				l.Error("removing payload", slog.Any("err", err))
+			}
+		})
+	})
+}
+
+// claim takes an operation for this task manager. It returns false
+// when another task manager holds a valid lease on the operation, or
+// when this one already handles it.
+func (tm *TaskManager) claim(op *Operation, p *Payload) bool {
+	if p.Owner != "" && p.Owner != tm.id && time.Now().Before(p.Lease) {
+		return false
+	}
+
+	key := tm.getOperationKey(op.Name, op.ID)
+	tm.Lock()
+	if id, ok := tm.claims[key]; ok && id == p.ID {
+		tm.Unlock()
+		return false
+	}
+	tm.claims[key] = p.ID
+	tm.Unlock()
+
+	p.Owner = tm.id
+	p.Lease = time.Now().Add(time.Second*time.Duration(p.Delay) + tm.leaseTTL)
+	if err := tm.setPayload(op, p); err != nil {
+		slog.Error("claiming operation", slog.Any("operation", *op), slog.Any("err", err))
+	}
+	return true
+}
+
+// isClaimed returns true when this task manager handles the operation.
+func (tm *TaskManager) isClaimed(op *Operation, id uuid.UUID) bool {
+	tm.Lock()
+	defer tm.Unlock()
+	claimed, ok := tm.claims[tm.getOperationKey(op.Name, op.ID)]
+	return ok && claimed == id
+}
+
+// release forgets an operation this task manager handled.
+func (tm *TaskManager) release(op *Operation, id uuid.UUID) {
+	key := tm.getOperationKey(op.Name, op.ID)
+	tm.Lock()
+	defer tm.Unlock()
+	if tm.claims[key] == id {
+		delete(tm.claims, key)
+	}
+}
+
+// keepLease renews the lease of a running operation until
+// the returned function is called.
+func (tm *TaskManager) keepLease(op *Operation, id uuid.UUID) func() {
+	stop := make(chan struct{})
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(tm.leaseTTL / 3)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				// Stop when the operation was canceled or replaced.
+				p, err := tm.getPayload(op)
+				if err != nil || p.ID != id {
+					return
 				}
-				// The payload can be removed when we're done.
-				if err := tm.delPayload(&op); err != nil {
-					l.Error("removing payload", slog.Any("err", err))
+				p.Lease = time.Now().Add(tm.leaseTTL)
+				if err := tm.setPayload(op, &p); err != nil {
+					slog.Error("renewing lease", slog.Any("operation", *op), slog.Any("err", err))
 				}
-			}()
-			f(&op, &p1)
+			}
 		}
-	})
+	}()
+
+	return func() {
+		close(stop)
+		<-done
+	}
+}
+
+// queueName returns the name of the queue the task runs in.
+func (tm *TaskManager) queueName(name string) string {
+	tm.Lock()
+	defer tm.Unlock()
+	return tm.taskQueues[name]
+}
+
+// Queues returns the status of every queue.
+func (tm *TaskManager) Queues() []QueueStatus {
+	return tm.queues.status()
+}
+
+// run calls the task handler and turns a panic into an error.
+func (tm *TaskManager) run(f TaskHandler, op *Operation, p *Payload) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+
+	return f(op, p)
+}
+
+// fail handles a failed operation. Depending on the task's retry policy,
+// it's launched again later or passed to the failure handlers.
+func (tm *TaskManager) fail(op *Operation, p *Payload, err error) {
+	l := slog.With(
+		slog.Any("operation", *op),
+		slog.Any("err", err),
+	)
+
+	p.Attempt++
+	policy := tm.policies[op.Name]
+	if policy.shouldRetry(p.Attempt, err) {
+		delay := policy.delay(p.Attempt)
+		l.Warn("task failed, retrying",
+			slog.Int("attempt", p.Attempt),
+			slog.Duration("delay", delay),
+		)
+		rerr := tm.relaunch(op, p, delay)
+		if rerr == nil {
+			return
+		}
+		l.Error("retrying task", slog.Any("err", rerr))
+	}
+
+	l.Error("task failed", slog.Int("attempts", p.Attempt))
+	if err := tm.delPayload(op); err != nil {
+		l.Error("removing payload", slog.Any("err", err))
+	}
+
+	if f, ok := tm.failures[op.Name]; ok {
+		f(op, p, err)
+	}
+	if tm.deadLetter != nil {
+		tm.deadLetter(op, p, err)
+	}
+}
+
+// relaunch stores a new payload for the operation and sends
+// the task event again.
+func (tm *TaskManager) relaunch(op *Operation, p *Payload, delay time.Duration) error {
+	p.ID = uuid.New()
+	p.Delay = max(1, int(delay.Round(time.Second)/time.Second))
+	p.Running = false
+	p.Owner = ""
+	p.Lease = time.Now().Add(time.Second*time.Duration(p.Delay) + tm.leaseTTL)
+	if err := tm.setPayload(op, p); err != nil {
+		return err
+	}
+
+	e, _ := json.Marshal(op)
+	return tm.em.Push("task", e)
+}
+
+// shouldRetry returns true when a new attempt can be made after
+// the given number of failed attempts.
+func (p RetryPolicy) shouldRetry(attempt int, err error) bool {
+	if attempt >= p.MaxAttempts {
+		return false
+	}
+	return p.Retryable == nil || p.Retryable(err)
+}
+
+// delay returns the delay before the next attempt.
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	d := p.Backoff
+	for i := 1; i < attempt; i++ {
+		d *= 2
+		if p.MaxBackoff > 0 && d >= p.MaxBackoff {
+			return p.MaxBackoff
+		}
+	}
+	return d
 }
 
 // getOperationKey returns the store key for an operation.
@@ -182,27 +497,261 @@ func (tm *TaskManager) getPayload(op *Operation) (payload Payload, err error) {
 	return
 }
 
+// setPayload saves the operation's payload in the store.
+func (tm *TaskManager) setPayload(op *Operation, payload *Payload) error {
+	p, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return tm.store.Set(
+		tm.getOperationKey(op.Name, op.ID), string(p),
+		time.Second*time.Duration(payload.Delay)+tm.payloadTTL,
+	)
+}
+
 // delPayload removes the operation's payload from the store.
 func (tm *TaskManager) delPayload(t *Operation) error {
 	return tm.store.Del(tm.getOperationKey(t.Name, t.ID))
 }
 
+// resume sends the task event again for the payloads left in the store
+// by this task manager, and for the ones whose lease expired, running
+// or not. The operations this task manager handles are left alone.
+// It only works with a store that can list its keys.
+func (tm *TaskManager) resume() {
+	kl, ok := tm.store.(KeyLister)
+	if !ok {
+		return
+	}
+
+	keys, err := kl.Keys(tm.keyPrefix + ":")
+	if err != nil {
+		slog.Error("listing tasks", slog.Any("err", err))
+		return
+	}
+
+	now := time.Now()
+	for _, k := range keys {
+		name, id, ok := strings.Cut(strings.TrimPrefix(k, tm.keyPrefix+":"), ":")
+		if !ok {
+			continue
+		}
+		op := Operation{Name: name, ID: id}
+		p, err := tm.getPayload(&op)
+		if err != nil {
+			continue
+		}
+
+		// Another process handles the operation, or its event
+		// is still on its way.
+		if p.Owner != tm.id && now.Before(p.Lease) {
+			continue
+		}
+		if tm.isClaimed(&op, p.ID) {
+			continue
+		}
+		if p.Running {
+			slog.Warn("reclaiming stale task", slog.Any("operation", op), slog.String("owner", p.Owner))
+		}
+
+		// Release the operation, so any process can take it
+		// when it receives the event.
+		p.Running = false
+		p.Owner = ""
+		p.Lease = now.Add(time.Second*time.Duration(p.Delay) + tm.leaseTTL)
+		if err := tm.setPayload(&op, &p); err != nil {
+			slog.Error("resuming task", slog.Any("err", err))
+			continue
+		}
+
+		e, _ := json.Marshal(op)
+		if err := tm.em.Push("task", e); err != nil {
+			slog.Error("resuming task", slog.Any("err", err))
+			continue
+		}
+		slog.Info("task resumed", slog.Any("operation", op))
+	}
+}
+
+// runResume resumes the operations whose lease expired, on every lease
+// period. A process that stopped while it held operations has a new ID
+// when it starts again, so its operations are only resumed once their
+// lease expired.
+func (tm *TaskManager) runResume() {
+	defer tm.resumeGroup.Done()
+
+	ticker := time.NewTicker(tm.leaseTTL)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-tm.resumeStop:
+			return
+		case <-ticker.C:
+			tm.resume()
+		}
+	}
+}
+
+// scheduleKey returns the store key of a scheduled task.
+func (tm *TaskManager) scheduleKey(name string) string {
+	return fmt.Sprintf("scheduler:%s:%s", tm.keyPrefix, name)
+}
+
+// runScheduler launches the scheduled tasks when they're due.
+func (tm *TaskManager) runScheduler() {
+	defer tm.schedGroup.Done()
+
+	now := time.Now()
+	for _, s := range tm.schedules {
+		s.setNext(s.schedule.Next(now))
+	}
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-tm.schedStop:
+			return
+		case now := <-ticker.C:
+			for _, s := range tm.schedules {
+				at := s.getNext()
+				if at.IsZero() || now.Before(at) {
+					continue
+				}
+				s.setNext(s.schedule.Next(now))
+				tm.launchScheduled(s, at)
+			}
+		}
+	}
+}
+
+// getNext returns the next activation time of a scheduled task.
+// It's zero when the scheduler doesn't run.
+func (s *scheduledTask) getNext() time.Time {
+	s.Lock()
+	defer s.Unlock()
+	return s.next
+}
+
+// setNext sets the next activation time of a scheduled task.
+func (s *scheduledTask) setNext(t time.Time) {
+	s.Lock()
+	defer s.Unlock()
+	s.next = t
+}
+
+// launchScheduled launches a scheduled task for the given activation time.
+// When several processes share the same store, only the first one
+// to get a lock on this activation launches the task.
+func (tm *TaskManager) launchScheduled(s *scheduledTask, at time.Time) {
+	l := slog.With(
+		slog.String("name", s.task.name),
+		slog.Time("at", at),
+	)
+
+	if lk, ok := tm.store.(Locker); ok {
+		key := fmt.Sprintf("%s:%d", tm.scheduleKey(s.task.name), at.Unix())
+		acquired, err := lk.SetNX(key, "1", time.Minute*10)
+		if err != nil {
+			l.Error("scheduler lock", slog.Any("err", err))
+			return
+		}
+		if !acquired {
+			l.Debug("scheduled task launched by another process")
+			return
+		}
+	}
+
+	if err := s.task.Run(at.Unix(), nil); err != nil {
+		l.Error("launching scheduled task", slog.Any("err", err))
+	}
+}
+
+// setScheduleStatus saves the last run information of a scheduled task.
+func (tm *TaskManager) setScheduleStatus(name string, start time.Time, err error) {
+	st := ScheduleStatus{
+		Name:     name,
+		LastRun:  start,
+		Duration: time.Since(start),
+	}
+	if err != nil {
+		st.LastError = err.Error()
+	}
+
+	data, _ := json.Marshal(st)
+	if err := tm.store.Set(tm.scheduleKey(name), string(data), 0); err != nil {
+		slog.Error("saving schedule status", slog.String("name", name), slog.Any("err", err))
+	}
+}
+
+// Schedules returns the status of every scheduled task.
+func (tm *TaskManager) Schedules() []ScheduleStatus {
+	now := time.Now()
+	res := make([]ScheduleStatus, len(tm.schedules))
+	for i, s := range tm.schedules {
+		if data := tm.store.Get(tm.scheduleKey(s.task.name)); data != "" {
+			_ = json.Unmarshal([]byte(data), &res[i])
+		}
+		res[i].Name = s.task.name
+		res[i].Schedule = s.schedule.String()
+		res[i].NextRun = s.getNext()
+		if res[i].NextRun.IsZero() {
+			res[i].NextRun = s.schedule.Next(now)
+		}
+	}
+
+	return res
+}
+
 // Start starts the events listener and the process workers.
+// Pending tasks are resumed when the store supports it, at start and
+// then whenever their lease expires.
 func (tm *TaskManager) Start() {
+	tm.resume()
+	if _, ok := tm.store.(KeyLister); ok {
+		tm.resumeStop = make(chan struct{})
+		tm.resumeGroup.Add(1)
+		go tm.runResume()
+	}
 	go tm.em.Listen()
-	for i := 0; i < tm.numWorkers; i++ {
-		tm.workerGroup.Add(1)
-		go func(_ int) {
-			defer tm.workerGroup.Done()
-			for task := range tm.queue {
-				task()
-			}
-		}(i)
+	tm.queues.prepare()
+	for _, q := range tm.queues.queues {
+		for i := 0; i < q.workers; i++ {
+			tm.workerGroup.Add(1)
+			go func() {
+				defer tm.workerGroup.Done()
+				for {
+					task, ok := tm.queues.next(q)
+					if !ok {
+						return
+					}
+					task()
+				}
+			}()
+		}
+	}
+
+	if len(tm.schedules) > 0 {
+		tm.schedStop = make(chan struct{})
+		tm.schedGroup.Add(1)
+		go tm.runScheduler()
 	}
 }
 
 // Stop stops the event listener and wait for running tasks to finish.
 func (tm *TaskManager) Stop() {
+	// Stop the scheduler
+	if tm.schedStop != nil {
+		close(tm.schedStop)
+		tm.schedGroup.Wait()
+	}
+
+	// Stop looking for expired operations
+	if tm.resumeStop != nil {
+		close(tm.resumeStop)
+		tm.resumeGroup.Wait()
+	}
+
 	// Stop the event bus (can't receive any new event)
 	tm.em.Stop()
 
@@ -210,7 +759,7 @@ func (tm *TaskManager) Stop() {
 	tm.timerGroup.Wait()
 
 	// Stop the worker group
-	close(tm.queue)
+	tm.queues.close()
 	tm.workerGroup.Wait()
 }
 
@@ -225,18 +774,14 @@ func (tm *TaskManager) Launch(name string, id interface{}, delay int, data inter
 	payload := Payload{
 		ID:    uuid.New(),
 		Delay: delay,
+		Lease: time.Now().Add(time.Second*time.Duration(delay) + tm.leaseTTL),
 	}
 	var err error
 	if payload.Data, err = json.Marshal(data); err != nil {
 		return err
 	}
 
-	p, err := json.Marshal(payload)
-	if err != nil {
-		return err
-	}
-	err = tm.store.Set(tm.getOperationKey(name, id), string(p), time.Duration(delay)+time.Second*30)
-	if err != nil {
+	if err = tm.setPayload(&t, &payload); err != nil {
 		return err
 	}
 
@@ -264,24 +809,91 @@ func (tm *TaskManager) NewTask(name string, options ...TaskOption) Task {
 	for _, o := range options {
 		o(&t)
 	}
-	tm.Register(t.name, func(_ *Operation, p *Payload) {
-		var data interface{} = p.Data
-		if t.unmarshallData != nil {
-			data = t.unmarshallData(p.Data)
+	tm.Register(t.name, func(_ *Operation, p *Payload) error {
+		if t.schedule == nil {
+			return t.taskHandler(t.decode(p))
 		}
-		t.taskHandler(data)
+
+		start := time.Now()
+		err := t.taskHandler(t.decode(p))
+		tm.setScheduleStatus(t.name, start, err)
+		return err
 	})
 
+	tm.Lock()
+	defer tm.Unlock()
+	tm.policies[t.name] = t.retry
+	tm.taskQueues[t.name] = t.queue
+	if t.schedule != nil {
+		tm.schedules = append(tm.schedules, &scheduledTask{task: t, schedule: t.schedule})
+	}
+	if t.failureHandler != nil {
+		tm.failures[t.name] = func(_ *Operation, p *Payload, err error) {
+			t.failureHandler(t.decode(p), err)
+		}
+	}
+
 	return t
 }
 
+// decode returns the payload's data, unmarshalled when
+// the task provides a function to do so.
+func (t Task) decode(p *Payload) interface{} {
+	var data interface{} = p.Data
+	if t.unmarshallData != nil {
+		data = t.unmarshallData(p.Data)
+	}
+	return data
+}
+
 // WithTaskHandler adds the given handler to the task.
 func WithTaskHandler(f func(data interface{})) TaskOption {
+	return func(t *Task) {
+		t.taskHandler = func(data interface{}) error {
+			f(data)
+			return nil
+		}
+	}
+}
+
+// WithFallibleTaskHandler adds the given handler to the task. An error
+// returned by the handler applies the task's retry policy.
+func WithFallibleTaskHandler(f func(data interface{}) error) TaskOption {
 	return func(t *Task) {
 		t.taskHandler = f
 	}
 }
 
+// WithTaskSchedule makes the task run on the given schedule, on top
+// of any explicit launch.
+func WithTaskSchedule(s Schedule) TaskOption {
+	return func(t *Task) {
+		t.schedule = s
+	}
+}
+
+// WithTaskQueue sets the queue the task runs in.
+func WithTaskQueue(name string) TaskOption {
+	return func(t *Task) {
+		t.queue = name
+	}
+}
+
+// WithTaskRetry sets the task's retry policy.
+func WithTaskRetry(p RetryPolicy) TaskOption {
+	return func(t *Task) {
+		t.retry = p
+	}
+}
+
+// WithTaskFailure adds a handler that is called when the task
+// failed and won't be retried.
+func WithTaskFailure(f func(data interface{}, err error)) TaskOption {
+	return func(t *Task) {
+		t.failureHandler = f
+	}
+}
+
 // WithUnmarshall registers a function that is responsible for payload decoding.
 func WithUnmarshall(f func(data []byte) interface{}) TaskOption {
 	return func(t *Task) {
@@ -0,0 +1,371 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package superbus
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"codeberg.org/readeck/readeck/internal/db"
+)
+
+// testEventManager records the events it receives. The test sends
+// them to the task managers.
+type testEventManager struct {
+	sync.Mutex
+	events []Event
+}
+
+func (m *testEventManager) Listen()                 {}
+func (m *testEventManager) Stop()                   {}
+func (m *testEventManager) On(string, EventHandler) {}
+
+func (m *testEventManager) Push(name string, value []byte) error {
+	m.Lock()
+	defer m.Unlock()
+	m.events = append(m.events, Event{Name: name, Value: value})
+	return nil
+}
+
+func (m *testEventManager) operations() []string {
+	m.Lock()
+	defer m.Unlock()
+	res := []string{}
+	for _, e := range m.events {
+		var op Operation
+		_ = json.Unmarshal(e.Value, &op)
+		res = append(res, op.ID.(string))
+	}
+	slices.Sort(res)
+	return res
+}
+
+func TestTaskResume(t *testing.T) {
+	clearTables(t)
+	store := NewDBStore(db.Q(), "bus_store")
+	em := &testEventManager{}
+	tm := NewTaskManager(em, store, WithInstanceID("a"), WithPayloadTTL(time.Hour))
+	tm.Register("op", func(*Operation, *Payload) error { return nil })
+
+	now := time.Now()
+	payloads := map[string]Payload{
+		// Launched, its event is on its way
+		"1": {Lease: now.Add(time.Minute)},
+		// Taken by another process
+		"2": {Owner: "b", Lease: now.Add(time.Minute)},
+		// Left by this process before a restart
+		"3": {Owner: "a", Lease: now.Add(time.Minute)},
+		// Running in a process that died
+		"4": {Owner: "b", Running: true, Lease: now.Add(-time.Second)},
+		// Never taken
+		"5": {Lease: now.Add(-time.Second)},
+		// Stored without a lease
+		"6": {},
+	}
+	for id, p := range payloads {
+		p.ID = uuid.New()
+		require.NoError(t, tm.setPayload(&Operation{Name: "op", ID: id}, &p))
+	}
+
+	tm.resume()
+	require.Equal(t, []string{"3", "4", "5", "6"}, em.operations())
+
+	// The stale payload is released
+	p, err := tm.getPayload(&Operation{Name: "op", ID: "4"})
+	require.NoError(t, err)
+	require.False(t, p.Running)
+	require.Equal(t, "", p.Owner)
+	require.True(t, p.Lease.After(now))
+
+	// The others are left alone
+	p, err = tm.getPayload(&Operation{Name: "op", ID: "2"})
+	require.NoError(t, err)
+	require.Equal(t, "b", p.Owner)
+}
+
+func TestTaskClaim(t *testing.T) {
+	store := NewDBStore(db.Q(), "bus_store")
+
+	newManager := func(id string, f TaskHandler) (*TaskManager, *testEventManager) {
+		em := &testEventManager{}
+		tm := NewTaskManager(em, store,
+			WithInstanceID(id),
+			WithNumWorkers(1),
+			WithLeaseTTL(time.Millisecond*60),
+		)
+		tm.Register("op", f)
+		return tm, em
+	}
+
+	t.Run("duplicate events", func(t *testing.T) {
+		clearTables(t)
+
+		var runs atomic.Int32
+		handler := func(*Operation, *Payload) error {
+			runs.Add(1)
+			return nil
+		}
+
+		tmA, emA := newManager("a", handler)
+		tmB, _ := newManager("b", handler)
+		tmA.Start()
+		tmB.Start()
+
+		require.NoError(t, tmA.Launch("op", 1, 0, nil))
+		e := emA.events[0]
+
+		// The same event reaches both processes, and twice the first one.
+		tmA.onTask(e)
+		tmA.onTask(e)
+		tmB.onTask(e)
+
+		tmA.Stop()
+		tmB.Stop()
+		require.Equal(t, int32(1), runs.Load())
+		require.Equal(t, "", store.Get("tasks:op:1"))
+	})
+
+	t.Run("expired lease", func(t *testing.T) {
+		clearTables(t)
+
+		var owner atomic.Value
+		tmA, _ := newManager("a", func(_ *Operation, p *Payload) error {
+			owner.Store(p.Owner)
+			return nil
+		})
+		tmA.Start()
+
+		op := &Operation{Name: "op", ID: "1"}
+		require.NoError(t, tmA.setPayload(op, &Payload{
+			ID:    uuid.New(),
+			Owner: "b",
+			Lease: time.Now().Add(-time.Second),
+		}))
+		e, _ := json.Marshal(op)
+		tmA.onTask(Event{Name: "task", Value: e})
+
+		tmA.Stop()
+		require.Equal(t, "a", owner.Load())
+	})
+
+	t.Run("restart", func(t *testing.T) {
+		clearTables(t)
+
+		var runs atomic.Int32
+		handler := func(*Operation, *Payload) error {
+			runs.Add(1)
+			return nil
+		}
+
+		// The process stopped after it took the operation,
+		// and starts again with a new ID.
+		tmA, emA := newManager("a", handler)
+		require.NoError(t, tmA.Launch("op", "1", 0, nil))
+		op := &Operation{Name: "op", ID: "1"}
+		p, err := tmA.getPayload(op)
+		require.NoError(t, err)
+		p.Owner = "a"
+		p.Lease = time.Now().Add(time.Millisecond * 100)
+		require.NoError(t, tmA.setPayload(op, &p))
+		emA.events = nil
+
+		tmB, emB := newManager("b", handler)
+		tmB.Start()
+		defer tmB.Stop()
+
+		// The lease is still valid on start
+		require.Empty(t, emB.operations())
+
+		// The operation is resumed once its lease expired
+		require.Eventually(t, func() bool {
+			return len(emB.operations()) > 0
+		}, time.Second, time.Millisecond*10)
+		require.Equal(t, []string{"1"}, emB.operations())
+
+		emB.Lock()
+		e := emB.events[0]
+		emB.Unlock()
+		tmB.onTask(e)
+		require.Eventually(t, func() bool {
+			return runs.Load() == 1 && store.Get("tasks:op:1") == ""
+		}, time.Second, time.Millisecond*10)
+	})
+
+	t.Run("lease renewal", func(t *testing.T) {
+		clearTables(t)
+
+		op := &Operation{Name: "op", ID: "1"}
+		var leases []time.Time
+		var tmA *TaskManager
+		tmA, emA := newManager("a", func(*Operation, *Payload) error {
+			for range 4 {
+				time.Sleep(time.Millisecond * 50)
+				p, err := tmA.getPayload(op)
+				if err == nil {
+					leases = append(leases, p.Lease)
+				}
+			}
+			return nil
+		})
+		tmA.Start()
+
+		require.NoError(t, tmA.Launch("op", "1", 0, nil))
+		tmA.onTask(emA.events[0])
+		tmA.Stop()
+
+		// The lease was renewed while the task was running, and the
+		// payload is gone once it's done.
+		require.Len(t, leases, 4)
+		for i := 1; i < len(leases); i++ {
+			require.True(t, leases[i].After(leases[i-1]))
+		}
+		require.Equal(t, "", store.Get("tasks:op:1"))
+	})
+}
+
+func TestRetryPolicy(t *testing.T) {
+	errTemporary := errors.New("temporary")
+	errPermanent := errors.New("permanent")
+
+	policy := RetryPolicy{
+		MaxAttempts: 5,
+		Backoff:     time.Second * 10,
+		MaxBackoff:  time.Minute,
+		Retryable: func(err error) bool {
+			return errors.Is(err, errTemporary)
+		},
+	}
+
+	t.Run("delay", func(t *testing.T) {
+		tests := []struct {
+			policy   RetryPolicy
+			attempt  int
+			expected time.Duration
+		}{
+			{policy, 1, time.Second * 10},
+			{policy, 2, time.Second * 20},
+			{policy, 3, time.Second * 40},
+			{policy, 4, time.Minute},
+			{policy, 10, time.Minute},
+			{RetryPolicy{Backoff: time.Second}, 8, time.Second * 128},
+			{RetryPolicy{}, 3, 0},
+		}
+
+		for _, test := range tests {
+			t.Run(strconv.Itoa(test.attempt), func(t *testing.T) {
+				require.Equal(t, test.expected, test.policy.delay(test.attempt))
+			})
+		}
+	})
+
+	t.Run("should retry", func(t *testing.T) {
+		tests := []struct {
+			policy   RetryPolicy
+			attempt  int
+			err      error
+			expected bool
+		}{
+			{policy, 1, errTemporary, true},
+			{policy, 4, errTemporary, true},
+			{policy, 5, errTemporary, false},
+			{policy, 1, errPermanent, false},
+			{RetryPolicy{MaxAttempts: 2}, 1, errPermanent, true},
+			{RetryPolicy{}, 1, errTemporary, false},
+		}
+
+		for i, test := range tests {
+			t.Run(strconv.Itoa(i), func(t *testing.T) {
+				require.Equal(t, test.expected, test.policy.shouldRetry(test.attempt, test.err))
+			})
+		}
+	})
+
+	t.Run("dead letter", func(t *testing.T) {
+		var failures, deadLetters []int
+		em := &testEventManager{}
+		tm := NewTaskManager(em, NewMemStore(),
+			WithInstanceID("a"),
+			WithDeadLetter(func(_ *Operation, p *Payload, _ error) {
+				deadLetters = append(deadLetters, p.Attempt)
+			}),
+		)
+		tm.NewTask("op",
+			WithFallibleTaskHandler(func(any) error { return nil }),
+			WithTaskRetry(policy),
+			WithTaskFailure(func(_ any, _ error) {
+				failures = append(failures, len(em.events))
+			}),
+		)
+
+		op := &Operation{Name: "op", ID: "1"}
+		p := &Payload{ID: uuid.New(), Owner: "a", Running: true}
+		require.NoError(t, tm.setPayload(op, p))
+
+		// Each failure stores a new payload and sends the event again,
+		// after a growing delay.
+		for i, delay := range []int{10, 20, 40, 60} {
+			id := p.ID
+			tm.fail(op, p, errTemporary)
+
+			require.Len(t, em.events, i+1)
+			stored, err := tm.getPayload(op)
+			require.NoError(t, err)
+			require.NotEqual(t, id, stored.ID)
+			require.Equal(t, i+1, stored.Attempt)
+			require.Equal(t, delay, stored.Delay)
+			require.False(t, stored.Running)
+			require.Equal(t, "", stored.Owner)
+			require.Empty(t, failures)
+			require.Empty(t, deadLetters)
+		}
+
+		// The last attempt goes to the failure handlers
+		tm.fail(op, p, errTemporary)
+		require.Len(t, em.events, 4)
+		require.Equal(t, []int{4}, failures)
+		require.Equal(t, []int{5}, deadLetters)
+		require.Equal(t, "", tm.store.Get(tm.getOperationKey("op", "1")))
+
+		// An error that can't be retried fails right away
+		p = &Payload{ID: uuid.New()}
+		require.NoError(t, tm.setPayload(op, p))
+		tm.fail(op, p, errPermanent)
+		require.Len(t, em.events, 4)
+		require.Equal(t, []int{5, 1}, deadLetters)
+		require.Equal(t, "", tm.store.Get(tm.getOperationKey("op", "1")))
+	})
+}
+
+func TestSchedules(t *testing.T) {
+	var runs atomic.Int32
+	tm := NewTaskManager(NewEagerEventManager(), NewMemStore(), WithNumWorkers(1))
+	tm.NewTask("op",
+		WithTaskSchedule(Every(time.Second)),
+		WithTaskHandler(func(any) { runs.Add(1) }),
+	)
+	tm.Start()
+
+	// The status is read while the scheduler updates it.
+	deadline := time.Now().Add(time.Millisecond * 1500)
+	for time.Now().Before(deadline) {
+		st := tm.Schedules()
+		require.Len(t, st, 1)
+		require.Equal(t, "op", st[0].Name)
+		require.False(t, st[0].NextRun.IsZero())
+		time.Sleep(time.Millisecond * 5)
+	}
+
+	tm.Stop()
+	require.GreaterOrEqual(t, runs.Load(), int32(1))
+}
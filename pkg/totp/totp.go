@@ -0,0 +1,103 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+// Package totp implements the time-based one-time passwords of RFC 6238,
+// with the parameters authenticator applications support: HMAC-SHA1,
+// 6 digits and a 30 seconds period.
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1" //nolint:gosec
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// Period is the validity period of a code.
+	Period = 30
+
+	// Digits is the number of digits of a code.
+	Digits = 6
+
+	// secretSize is the size of a new secret (160 bits, as advised
+	// by RFC 4226).
+	secretSize = 20
+)
+
+var b32 = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// NewSecret returns a new random secret.
+func NewSecret() []byte {
+	b := make([]byte, secretSize)
+	rand.Read(b)
+	return b
+}
+
+// EncodeSecret returns the base32 representation of a secret,
+// as users type it in authenticator applications.
+func EncodeSecret(secret []byte) string {
+	return b32.EncodeToString(secret)
+}
+
+// Step returns the time step of a given time.
+func Step(t time.Time) int64 {
+	return t.Unix() / Period
+}
+
+// Code returns the code of a time step.
+func Code(secret []byte, step int64) string {
+	msg := make([]byte, 8)
+	binary.BigEndian.PutUint64(msg, uint64(step))
+
+	mac := hmac.New(sha1.New, secret)
+	mac.Write(msg)
+	sum := mac.Sum(nil)
+
+	// Dynamic truncation (RFC 4226, section 5.3)
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:]) & 0x7fffffff
+
+	return fmt.Sprintf("%0*d", Digits, value%1_000_000)
+}
+
+// Validate checks a code at a given time, accepting the previous
+// and next time steps for clock drift. It returns the matching step,
+// that callers must keep to refuse the same code, or an older one,
+// later.
+func Validate(secret []byte, code string, t time.Time) (int64, bool) {
+	code = strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+	if len(code) != Digits {
+		return 0, false
+	}
+
+	step := Step(t)
+	for _, s := range []int64{step, step - 1, step + 1} {
+		if subtle.ConstantTimeCompare([]byte(Code(secret, s)), []byte(code)) == 1 {
+			return s, true
+		}
+	}
+	return 0, false
+}
+
+// URL returns the "otpauth" URL of a secret, for a QR code that
+// authenticator applications can read.
+func URL(issuer, account string, secret []byte) string {
+	u := url.URL{
+		Scheme: "otpauth",
+		Host:   "totp",
+		Path:   "/" + issuer + ":" + account,
+	}
+	u.RawQuery = url.Values{
+		"secret": {EncodeSecret(secret)},
+		"issuer": {issuer},
+	}.Encode()
+	return u.String()
+}
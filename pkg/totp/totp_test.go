@@ -0,0 +1,68 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package totp_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"codeberg.org/readeck/readeck/pkg/totp"
+)
+
+// rfcSecret is the SHA1 secret of RFC 6238, appendix B.
+var rfcSecret = []byte("12345678901234567890")
+
+func TestCode(t *testing.T) {
+	// RFC 6238 test vectors, truncated to 6 digits.
+	tests := []struct {
+		t        int64
+		expected string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, test := range tests {
+		t.Run(time.Unix(test.t, 0).UTC().String(), func(t *testing.T) {
+			require.Equal(t, test.expected, totp.Code(rfcSecret, totp.Step(time.Unix(test.t, 0))))
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+
+	step, ok := totp.Validate(rfcSecret, "050471", now)
+	require.True(t, ok)
+	require.Equal(t, totp.Step(now), step)
+
+	// Clock drift
+	step, ok = totp.Validate(rfcSecret, "050 471", now.Add(30*time.Second))
+	require.True(t, ok)
+	require.Equal(t, totp.Step(now), step)
+
+	_, ok = totp.Validate(rfcSecret, "050471", now.Add(90*time.Second))
+	require.False(t, ok)
+
+	_, ok = totp.Validate(rfcSecret, "000000", now)
+	require.False(t, ok)
+
+	_, ok = totp.Validate(rfcSecret, "", now)
+	require.False(t, ok)
+}
+
+func TestURL(t *testing.T) {
+	require.Equal(t,
+		"otpauth://totp/Readeck:alice?issuer=Readeck&secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ",
+		totp.URL("Readeck", "alice", rfcSecret),
+	)
+	require.Len(t, totp.NewSecret(), 20)
+}